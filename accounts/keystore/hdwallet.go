@@ -0,0 +1,292 @@
+package keystore
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/DogeProtocol/dp"
+	"github.com/DogeProtocol/dp/accounts"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+)
+
+// HDWalletScheme is the protocol scheme prefixing the URLs of the wallets derived
+// from a mnemonic. The path of the URL is the address of the account at the default
+// derivation path, which identifies the wallet without revealing its seed.
+const HDWalletScheme = "hd"
+
+// hdWallet is a wallet of the accounts derived from the seed of a mnemonic. The
+// derived keys are kept in memory while the wallet is open, accounts are stored
+// in the keystore only when imported with ImportMnemonic.
+type hdWallet struct {
+	url      accounts.URL
+	keystore *KeyStore
+
+	seed     []byte                                            // BIP-39 seed, nil once closed
+	accounts []accounts.Account                                // Accounts pinned by Derive
+	paths    map[common.Address]accounts.DerivationPath        // Derivation paths of the pinned accounts
+	keys     map[common.Address]*signaturealgorithm.PrivateKey // Keys of the pinned accounts
+
+	mu sync.RWMutex
+}
+
+// deriveKey derives the key of the account at path from the BIP-39 seed of a wallet.
+func deriveKey(seed []byte, path accounts.DerivationPath) (*Key, error) {
+	accountSeed, err := accounts.DeriveAccountSeed(seed, path)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := cryptobase.SigAlg.GenerateKeyFromSeed(accountSeed)
+	if err != nil {
+		return nil, err
+	}
+	return newKeyFromOQS(privateKey), nil
+}
+
+// NewHDWallet opens a wallet of the accounts derived from mnemonic and its optional
+// seed passphrase, and adds it to the wallets of the keystore until it is closed.
+// The wallet holds no accounts until they are derived. If the wallet of mnemonic
+// is already open, it is returned instead.
+func (ks *KeyStore) NewHDWallet(mnemonic string, seedPassphrase string) (accounts.Wallet, error) {
+	seed, err := accounts.SeedFromMnemonic(mnemonic, seedPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(seed, accounts.DefaultBaseDerivationPath)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key.PrivateKey)
+
+	wallet := &hdWallet{
+		url:      accounts.URL{Scheme: HDWalletScheme, Path: key.Address.Hex()},
+		keystore: ks,
+		seed:     seed,
+		paths:    make(map[common.Address]accounts.DerivationPath),
+		keys:     make(map[common.Address]*signaturealgorithm.PrivateKey),
+	}
+
+	ks.mu.Lock()
+	n := sort.Search(len(ks.hdWallets), func(i int) bool { return ks.hdWallets[i].url.Cmp(wallet.url) >= 0 })
+	if n < len(ks.hdWallets) && ks.hdWallets[n].url == wallet.url {
+		existing := ks.hdWallets[n]
+		ks.mu.Unlock()
+		zeroBytes(seed)
+		return existing, nil
+	}
+	ks.hdWallets = append(ks.hdWallets[:n], append([]*hdWallet{wallet}, ks.hdWallets[n:]...)...)
+	ks.mu.Unlock()
+
+	ks.updateFeed.Send(accounts.WalletEvent{Wallet: wallet, Kind: accounts.WalletArrived})
+	return wallet, nil
+}
+
+// dropHDWallet removes a closed wallet from the wallets of the keystore.
+func (ks *KeyStore) dropHDWallet(wallet *hdWallet) {
+	ks.mu.Lock()
+	for i, w := range ks.hdWallets {
+		if w == wallet {
+			ks.hdWallets = append(ks.hdWallets[:i], ks.hdWallets[i+1:]...)
+			break
+		}
+	}
+	ks.mu.Unlock()
+
+	ks.updateFeed.Send(accounts.WalletEvent{Wallet: wallet, Kind: accounts.WalletDropped})
+}
+
+// ImportMnemonic derives the account at path from mnemonic and its optional seed
+// passphrase, and stores its key into the key directory, encrypted with passphrase.
+func (ks *KeyStore) ImportMnemonic(mnemonic string, seedPassphrase string, path accounts.DerivationPath, passphrase string) (accounts.Account, error) {
+	seed, err := accounts.SeedFromMnemonic(mnemonic, seedPassphrase)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	key, err := deriveKey(seed, path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	defer zeroKey(key.PrivateKey)
+
+	ks.importMu.Lock()
+	defer ks.importMu.Unlock()
+
+	if ks.cache.hasAddress(key.Address) {
+		return accounts.Account{
+			Address: key.Address,
+		}, ErrAccountAlreadyExists
+	}
+	return ks.importKey(key, passphrase)
+}
+
+func (w *hdWallet) URL() accounts.URL {
+	return w.url
+}
+
+func (w *hdWallet) Status() (string, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.seed == nil {
+		return "Closed", nil
+	}
+	return "Open", nil
+}
+
+// Open is a noop, the wallet is open from its creation until it is closed.
+func (w *hdWallet) Open(passphrase string) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.seed == nil {
+		return accounts.ErrWalletClosed
+	}
+	return accounts.ErrWalletAlreadyOpen
+}
+
+// Close zeroes the seed and the derived keys of the wallet, and removes it from
+// the wallets of the keystore.
+func (w *hdWallet) Close() error {
+	w.mu.Lock()
+	if w.seed == nil {
+		w.mu.Unlock()
+		return nil
+	}
+	zeroBytes(w.seed)
+	w.seed = nil
+	for addr, key := range w.keys {
+		zeroKey(key)
+		delete(w.keys, addr)
+	}
+	w.mu.Unlock()
+
+	w.keystore.dropHDWallet(w)
+	return nil
+}
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+func (w *hdWallet) Accounts() []accounts.Account {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	cpy := make([]accounts.Account, len(w.accounts))
+	copy(cpy, w.accounts)
+	return cpy
+}
+
+func (w *hdWallet) Contains(account accounts.Account) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	_, ok := w.paths[account.Address]
+	return ok && (account.URL == (accounts.URL{}) || account.URL == w.url)
+}
+
+// Derive derives the account at path. If pin is set, the account is added to the
+// accounts of the wallet, which it can then sign with.
+func (w *hdWallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.seed == nil {
+		return accounts.Account{}, accounts.ErrWalletClosed
+	}
+	key, err := deriveKey(w.seed, path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	account := accounts.Account{Address: key.Address, URL: w.url}
+	if pin == false {
+		zeroKey(key.PrivateKey)
+		return account, nil
+	}
+	if _, ok := w.paths[key.Address]; ok {
+		zeroKey(key.PrivateKey)
+		return account, nil
+	}
+	w.accounts = append(w.accounts, account)
+	w.paths[key.Address] = append(accounts.DerivationPath{}, path...)
+	w.keys[key.Address] = key.PrivateKey
+	return account, nil
+}
+
+// SelfDerive is not supported, the hybrid keys are too costly to derive to look
+// for used accounts in the background.
+func (w *hdWallet) SelfDerive(bases []accounts.DerivationPath, chain dp.ChainStateReader) {
+}
+
+func (w *hdWallet) key(account accounts.Account) (*signaturealgorithm.PrivateKey, error) {
+	if w.seed == nil {
+		return nil, accounts.ErrWalletClosed
+	}
+	key, ok := w.keys[account.Address]
+	if ok == false || (account.URL != (accounts.URL{}) && account.URL != w.url) {
+		return nil, accounts.ErrUnknownAccount
+	}
+	return key, nil
+}
+
+func (w *hdWallet) signHash(account accounts.Account, hash []byte) ([]byte, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	key, err := w.key(account)
+	if err != nil {
+		return nil, err
+	}
+	return cryptobase.SigAlg.Sign(hash, key)
+}
+
+func (w *hdWallet) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	return w.signHash(account, crypto.Keccak256(data))
+}
+
+func (w *hdWallet) SignDataWithContext(account accounts.Account, mimeType string, data []byte, context []byte) ([]byte, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	key, err := w.key(account)
+	if err != nil {
+		return nil, err
+	}
+	return cryptobase.SigAlg.SignWithContext(crypto.Keccak256(data), key, context)
+}
+
+// SignDataWithPassphrase signs as SignData, the keys of the wallet are not
+// protected by a passphrase.
+func (w *hdWallet) SignDataWithPassphrase(account accounts.Account, passphrase, mimeType string, data []byte) ([]byte, error) {
+	return w.SignData(account, mimeType, data)
+}
+
+func (w *hdWallet) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	return w.signHash(account, accounts.TextHash(text))
+}
+
+func (w *hdWallet) SignTextWithPassphrase(account accounts.Account, passphrase string, text []byte) ([]byte, error) {
+	return w.SignText(account, text)
+}
+
+func (w *hdWallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	key, err := w.key(account)
+	if err != nil {
+		return nil, err
+	}
+	signer := types.LatestSignerForChainID(chainID)
+	return types.SignTx(tx, signer, key)
+}
+
+func (w *hdWallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return w.SignTx(account, tx, chainID)
+}
@@ -0,0 +1,174 @@
+package keystore
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/DogeProtocol/dp/accounts"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/crypto/hybrideds"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func newTestHDWallet(t *testing.T, ks *KeyStore) accounts.Wallet {
+	wallet, err := ks.NewHDWallet(testMnemonic, "")
+	if err == hybrideds.ErrSeedKeygenUnsupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("failed to create wallet: %v", err)
+	}
+	return wallet
+}
+
+func TestHDWalletDerive(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	wallet := newTestHDWallet(t, ks)
+	defer wallet.Close()
+
+	first, err := wallet.Derive(accounts.DefaultBaseDerivationPath, false)
+	if err != nil {
+		t.Fatalf("failed to derive account: %v", err)
+	}
+	if wallet.URL().Path != first.Address.Hex() {
+		t.Errorf("wallet URL mismatch: have %v, want path %v", wallet.URL(), first.Address.Hex())
+	}
+	if len(wallet.Accounts()) != 0 || wallet.Contains(first) {
+		t.Fatalf("unpinned account added to the wallet")
+	}
+
+	second, err := wallet.Derive(accounts.DerivationPath{0x80000000 + 44, 0x80000000 + 60, 0x80000000 + 0, 0, 1}, true)
+	if err != nil {
+		t.Fatalf("failed to derive account: %v", err)
+	}
+	if second.Address == first.Address {
+		t.Fatalf("accounts at different paths share an address")
+	}
+	again, err := wallet.Derive(accounts.DerivationPath{0x80000000 + 44, 0x80000000 + 60, 0x80000000 + 0, 0, 1}, true)
+	if err != nil || again != second {
+		t.Fatalf("derivation is not deterministic: have %v, want %v (err %v)", again, second, err)
+	}
+	if accs := wallet.Accounts(); len(accs) != 1 || accs[0] != second {
+		t.Fatalf("pinned accounts mismatch: %v", accs)
+	}
+
+	hash := crypto.Keccak256([]byte("hd wallet"))
+	signature, err := wallet.SignData(second, "", []byte("hd wallet"))
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	pubKey, err := cryptobase.SigAlg.PublicKeyFromSignature(hash, signature)
+	if err != nil {
+		t.Fatalf("failed to recover public key: %v", err)
+	}
+	if signer, _ := cryptobase.SigAlg.PublicKeyToAddress(pubKey); signer != second.Address {
+		t.Errorf("signer mismatch: have %x, want %x", signer, second.Address)
+	}
+	if _, err := wallet.SignData(first, "", []byte("hd wallet")); err != accounts.ErrUnknownAccount {
+		t.Errorf("error mismatch signing with an unpinned account: have %v, want %v", err, accounts.ErrUnknownAccount)
+	}
+
+	wallet.Close()
+	if _, err := wallet.SignData(second, "", []byte("hd wallet")); err != accounts.ErrWalletClosed {
+		t.Errorf("error mismatch signing with a closed wallet: have %v, want %v", err, accounts.ErrWalletClosed)
+	}
+	if _, err := wallet.Derive(accounts.DefaultBaseDerivationPath, false); err != accounts.ErrWalletClosed {
+		t.Errorf("error mismatch deriving from a closed wallet: have %v, want %v", err, accounts.ErrWalletClosed)
+	}
+}
+
+func TestImportMnemonic(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	wallet := newTestHDWallet(t, ks)
+	defer wallet.Close()
+	want, err := wallet.Derive(accounts.DefaultBaseDerivationPath, false)
+	if err != nil {
+		t.Fatalf("failed to derive account: %v", err)
+	}
+
+	account, err := ks.ImportMnemonic(testMnemonic, "", accounts.DefaultBaseDerivationPath, "pass")
+	if err != nil {
+		t.Fatalf("failed to import mnemonic: %v", err)
+	}
+	if account.Address != want.Address {
+		t.Errorf("address mismatch: have %x, want %x", account.Address, want.Address)
+	}
+	if ks.HasAddress(account.Address) == false {
+		t.Errorf("imported account not in the keystore")
+	}
+	if _, err := ks.ImportMnemonic(testMnemonic, "", accounts.DefaultBaseDerivationPath, "pass"); err != ErrAccountAlreadyExists {
+		t.Errorf("error mismatch importing twice: have %v, want %v", err, ErrAccountAlreadyExists)
+	}
+	if _, err := ks.ImportMnemonic(testMnemonic, "other", accounts.DefaultBaseDerivationPath, "pass"); err != nil {
+		t.Errorf("failed to import mnemonic with another seed passphrase: %v", err)
+	}
+	if _, err := ks.ImportMnemonic("abandon about", "", accounts.DefaultBaseDerivationPath, "pass"); err != accounts.ErrInvalidMnemonic {
+		t.Errorf("error mismatch importing an invalid mnemonic: have %v, want %v", err, accounts.ErrInvalidMnemonic)
+	}
+}
+
+// Tests that the wallets derived from mnemonics are wallets of the keystore, and
+// of the account manager, until they are closed.
+func TestHDWalletBackend(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	updates := make(chan accounts.WalletEvent, 4)
+	sub := ks.Subscribe(updates)
+	defer sub.Unsubscribe()
+	am := accounts.NewManager(&accounts.Config{}, ks)
+	defer am.Close()
+
+	if _, err := ks.NewAccount("pass"); err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	wallet := newTestHDWallet(t, ks)
+	defer wallet.Close()
+
+	waitEvent := func(kind accounts.WalletEventType) {
+		t.Helper()
+		for {
+			select {
+			case event := <-updates:
+				if event.Wallet == wallet {
+					if event.Kind != kind {
+						t.Fatalf("event mismatch: have %v, want %v", event.Kind, kind)
+					}
+					return
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("wallet event %v not received", kind)
+			}
+		}
+	}
+	waitEvent(accounts.WalletArrived)
+
+	if wallets := ks.Wallets(); len(wallets) != 2 || wallets[0] != wallet {
+		t.Fatalf("keystore wallets mismatch: %v", wallets)
+	}
+	if again, err := ks.NewHDWallet(testMnemonic, ""); err != nil || again != wallet {
+		t.Fatalf("opened the wallet twice: %v (err %v)", again, err)
+	}
+	for i := 0; ; i++ {
+		if found, err := am.Wallet(wallet.URL().String()); err == nil && found == wallet {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("wallet not found by the account manager")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	wallet.Close()
+	waitEvent(accounts.WalletDropped)
+	if wallets := ks.Wallets(); len(wallets) != 1 || wallets[0] == wallet {
+		t.Fatalf("closed wallet still in the keystore wallets: %v", wallets)
+	}
+}
@@ -35,7 +35,12 @@ import (
 )
 
 const (
-	version = 3
+	version   = 3
+	versionV4 = 4
+
+	// keySigAlgVersion is the version of the serialization of the private keys of
+	// the signature algorithms, recorded in version 4 key files
+	keySigAlgVersion = 1
 )
 
 type Key struct {
@@ -70,6 +75,22 @@ type encryptedKeyJSONV3 struct {
 	Version int        `json:"version"`
 }
 
+// encryptedKeyJSONV4 is a key file that records the signature algorithm of its
+// key, which is encrypted with an AEAD cipher that also authenticates the rest
+// of the key file.
+type encryptedKeyJSONV4 struct {
+	Address string     `json:"address"`
+	Crypto  CryptoJSON `json:"crypto"`
+	Id      string     `json:"id"`
+	Version int        `json:"version"`
+	SigAlg  sigAlgJSON `json:"sigalg"`
+}
+
+type sigAlgJSON struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
 type encryptedKeyJSONV1 struct {
 	Address string     `json:"address"`
 	Crypto  CryptoJSON `json:"crypto"`
@@ -21,10 +21,14 @@
 package keystore
 
 import (
+	"bytes"
 	crand "crypto/rand"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/DogeProtocol/dp/crypto/cryptobase"
 	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+	"io/ioutil"
 	"math/big"
 	"os"
 	"path/filepath"
@@ -47,6 +51,10 @@ var (
 	// ErrAccountAlreadyExists is returned if an account attempted to import is
 	// already present in the keystore.
 	ErrAccountAlreadyExists = errors.New("account already exists")
+
+	// ErrKeyUpToDate is returned if a key file attempted to upgrade is already of
+	// the latest version.
+	ErrKeyUpToDate = errors.New("key file already of the latest version")
 )
 
 // KeyStoreType is the reflect type of a keystore backend.
@@ -66,6 +74,7 @@ type KeyStore struct {
 	unlocked map[common.Address]*unlocked // Currently unlocked account (decrypted private keys)
 
 	wallets     []accounts.Wallet       // Wallet wrappers around the individual key files
+	hdWallets   []*hdWallet             // Wallets derived from mnemonics, sorted by URL until closed
 	updateFeed  event.Feed              // Event feed to notify wallet additions/removals
 	updateScope event.SubscriptionScope // Subscription scope tracking current live listeners
 	updating    bool                    // Whether the event notification loop is running
@@ -120,7 +129,7 @@ func (ks *KeyStore) init(keydir string) {
 }
 
 // Wallets implements accounts.Backend, returning all single-key wallets from the
-// keystore directory.
+// keystore directory and the open wallets derived from mnemonics.
 func (ks *KeyStore) Wallets() []accounts.Wallet {
 	// Make sure the list of wallets is in sync with the account cache
 	ks.refreshWallets()
@@ -128,9 +137,12 @@ func (ks *KeyStore) Wallets() []accounts.Wallet {
 	ks.mu.RLock()
 	defer ks.mu.RUnlock()
 
-	cpy := make([]accounts.Wallet, len(ks.wallets))
-	copy(cpy, ks.wallets)
-	return cpy
+	// The HD wallet scheme sorts before the keystore scheme
+	cpy := make([]accounts.Wallet, 0, len(ks.hdWallets)+len(ks.wallets))
+	for _, wallet := range ks.hdWallets {
+		cpy = append(cpy, wallet)
+	}
+	return append(cpy, ks.wallets...)
 }
 
 // refreshWallets retrieves the current account list and based on that does any
@@ -518,6 +530,75 @@ func (ks *KeyStore) ImportWalletKey(keyJSON []byte, passphrase string) (accounts
 	return a, nil
 }
 
+// Upgrade rewrites the key file of an account of a previous version as a version 4
+// key file, encrypted with the same passphrase, with the Argon2id KDF if argon2id is
+// set and with scrypt otherwise. The key file is only replaced once the key decrypted
+// from the new key file is checked to be the same as the one of the previous file.
+func (ks *KeyStore) Upgrade(a accounts.Account, passphrase string, argon2id bool) error {
+	store, ok := ks.storage.(*keyStorePassphrase)
+	if ok == false {
+		return accounts.ErrNotSupported
+	}
+	a, err := ks.Find(a)
+	if err != nil {
+		return err
+	}
+	keyJSON, err := ioutil.ReadFile(a.URL.Path)
+	if err != nil {
+		return err
+	}
+	var header struct {
+		Version interface{} `json:"version"`
+	}
+	if err := json.Unmarshal(keyJSON, &header); err != nil {
+		return err
+	}
+	if v, ok := header.Version.(float64); ok && v == versionV4 && argon2id == false {
+		return ErrKeyUpToDate
+	}
+
+	key, err := DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return err
+	}
+	defer zeroKey(key.PrivateKey)
+	if key.Address != a.Address {
+		return fmt.Errorf("key content mismatch: have account %x, want %x", key.Address, a.Address)
+	}
+
+	var newKeyJSON []byte
+	if argon2id {
+		if store.scryptN < StandardScryptN {
+			newKeyJSON, err = EncryptKeyArgon2id(key, passphrase, LightArgon2idT, LightArgon2idM, LightArgon2idP)
+		} else {
+			newKeyJSON, err = EncryptKeyArgon2id(key, passphrase, StandardArgon2idT, StandardArgon2idM, StandardArgon2idP)
+		}
+	} else {
+		newKeyJSON, err = EncryptKeyV4(key, passphrase, store.scryptN, store.scryptP)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Make sure that the new key file round-trips to the same key
+	newKey, err := DecryptKey(newKeyJSON, passphrase)
+	if err != nil {
+		return err
+	}
+	defer zeroKey(newKey.PrivateKey)
+	if newKey.Address != key.Address || newKey.Id != key.Id ||
+		bytes.Equal(newKey.PrivateKey.PriData, key.PrivateKey.PriData) == false ||
+		bytes.Equal(newKey.PrivateKey.PubData, key.PrivateKey.PubData) == false {
+		return fmt.Errorf("upgraded key file of account %x does not match the key", a.Address)
+	}
+
+	tmpName, err := writeTemporaryKeyFile(a.URL.Path, newKeyJSON)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpName, a.URL.Path)
+}
+
 // zeroKey zeroes a private key in memory.
 func zeroKey(k *signaturealgorithm.PrivateKey) {
 	cryptobase.SigAlg.Zeroize(k)
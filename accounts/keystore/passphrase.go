@@ -28,6 +28,7 @@ package keystore
 import (
 	"bytes"
 	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
@@ -42,13 +43,17 @@ import (
 	"github.com/DogeProtocol/dp/accounts"
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
 	"github.com/google/uuid"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/crypto/scrypt"
 )
 
 const (
-	keyHeaderKDF = "scrypt"
+	keyHeaderKDF         = "scrypt"
+	keyHeaderKDFArgon2id = "argon2id"
+	keyCipherAEAD        = "aes-256-gcm"
 
 	// StandardScryptN is the N parameter of Scrypt encryption algorithm, using 256MB
 	// memory and taking approximately 1s CPU time on a modern processor.
@@ -68,6 +73,20 @@ const (
 
 	scryptR     = 8
 	scryptDKLen = 32
+
+	// StandardArgon2idT, StandardArgon2idM and StandardArgon2idP are the passes, the
+	// memory in KiB and the threads of the Argon2id KDF, using 256MB memory.
+	StandardArgon2idT = 3
+	StandardArgon2idM = 256 * 1024
+	StandardArgon2idP = 4
+
+	// LightArgon2idT, LightArgon2idM and LightArgon2idP are the passes, the memory
+	// in KiB and the threads of the Argon2id KDF, using 4MB memory.
+	LightArgon2idT = 3
+	LightArgon2idM = 4 * 1024
+	LightArgon2idP = 4
+
+	argon2idDKLen = 32
 )
 
 type keyStorePassphrase struct {
@@ -202,6 +221,100 @@ func EncryptKey(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
 	return json.Marshal(encryptedKeyJSONV3)
 }
 
+// EncryptKeyV4 encrypts a key into a version 4 key file, with the scrypt KDF of
+// the specified parameters.
+func EncryptKeyV4(key *Key, auth string, scryptN, scryptP int) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		panic("reading from crypto/rand failed: " + err.Error())
+	}
+	derivedKey, err := scrypt.Key([]byte(auth), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+
+	scryptParamsJSON := make(map[string]interface{}, 5)
+	scryptParamsJSON["n"] = scryptN
+	scryptParamsJSON["r"] = scryptR
+	scryptParamsJSON["p"] = scryptP
+	scryptParamsJSON["dklen"] = scryptDKLen
+	scryptParamsJSON["salt"] = hex.EncodeToString(salt)
+	return encryptKeyV4(key, derivedKey, keyHeaderKDF, scryptParamsJSON)
+}
+
+// EncryptKeyArgon2id encrypts a key into a version 4 key file, with the Argon2id
+// KDF of argonT passes over argonM KiB of memory with argonP threads.
+func EncryptKeyArgon2id(key *Key, auth string, argonT, argonM uint32, argonP uint8) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		panic("reading from crypto/rand failed: " + err.Error())
+	}
+	derivedKey := argon2.IDKey([]byte(auth), salt, argonT, argonM, argonP, argon2idDKLen)
+
+	argonParamsJSON := make(map[string]interface{}, 5)
+	argonParamsJSON["t"] = argonT
+	argonParamsJSON["m"] = argonM
+	argonParamsJSON["p"] = argonP
+	argonParamsJSON["dklen"] = argon2idDKLen
+	argonParamsJSON["salt"] = hex.EncodeToString(salt)
+	return encryptKeyV4(key, derivedKey, keyHeaderKDFArgon2id, argonParamsJSON)
+}
+
+func encryptKeyV4(key *Key, derivedKey []byte, kdf string, kdfParams map[string]interface{}) ([]byte, error) {
+	keyBytes, err := cryptobase.SigAlg.SerializePrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyJSON := encryptedKeyJSONV4{
+		Address: hex.EncodeToString(key.Address[:]),
+		Id:      key.Id.String(),
+		Version: versionV4,
+		SigAlg: sigAlgJSON{
+			Name:    cryptobase.SigAlg.SignatureName(),
+			Version: keySigAlgVersion,
+		},
+	}
+
+	aead, err := newKeyAEAD(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		panic("reading from crypto/rand failed: " + err.Error())
+	}
+	cipherText := aead.Seal(nil, nonce, keyBytes, keyJSON.additionalData())
+
+	keyJSON.Crypto = CryptoJSON{
+		Cipher:     keyCipherAEAD,
+		CipherText: hex.EncodeToString(cipherText),
+		CipherParams: cipherparamsJSON{
+			IV: hex.EncodeToString(nonce),
+		},
+		KDF:       kdf,
+		KDFParams: kdfParams,
+	}
+	return json.Marshal(keyJSON)
+}
+
+// additionalData returns the fields of the key file that are authenticated along
+// with the encrypted key, so that they can not be swapped.
+func (k *encryptedKeyJSONV4) additionalData() []byte {
+	return []byte(fmt.Sprintf("%d:%s:%s:%s:%d", k.Version, k.Address, k.Id, k.SigAlg.Name, k.SigAlg.Version))
+}
+
+func newKeyAEAD(derivedKey []byte) (cipher.AEAD, error) {
+	if len(derivedKey) < 32 {
+		return nil, fmt.Errorf("invalid derived key length: %d", len(derivedKey))
+	}
+	block, err := aes.NewCipher(derivedKey[:32])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
 // DecryptKey decrypts a key from a json blob, returning the private key itself.
 func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 	// Parse the json into a simple map to fetch the key version
@@ -213,6 +326,7 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 	var (
 		keyBytes, keyId []byte
 		err             error
+		sigAlg          signaturealgorithm.SignatureAlgorithm = cryptobase.SigAlg
 	)
 	if version, ok := m["version"].(string); ok && version == "1" {
 		k := new(encryptedKeyJSONV1)
@@ -220,6 +334,15 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 			return nil, err
 		}
 		keyBytes, keyId, err = decryptKeyV1(k, auth)
+	} else if version, ok := m["version"].(float64); ok && version == versionV4 {
+		k := new(encryptedKeyJSONV4)
+		if err := json.Unmarshal(keyjson, k); err != nil {
+			return nil, err
+		}
+		if sigAlg, err = cryptobase.SigAlgByName(k.SigAlg.Name); err != nil {
+			return nil, fmt.Errorf("signature algorithm not supported: %v", k.SigAlg.Name)
+		}
+		keyBytes, keyId, err = decryptKeyV4(k, auth)
 	} else {
 		k := new(encryptedKeyJSONV3)
 		if err := json.Unmarshal(keyjson, k); err != nil {
@@ -231,7 +354,7 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 	if err != nil {
 		return nil, err
 	}
-	key, err := cryptobase.SigAlg.DeserializePrivateKey(keyBytes)
+	key, err := sigAlg.DeserializePrivateKey(keyBytes)
 	if err != nil {
 		return nil, err
 	}
@@ -240,7 +363,7 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 		return nil, err
 	}
 
-	pubKeyAddress, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
+	pubKeyAddress, err := sigAlg.PublicKeyToAddress(&key.PublicKey)
 	if err != nil {
 		return nil, err
 	}
@@ -303,6 +426,45 @@ func decryptKeyV3(keyProtected *encryptedKeyJSONV3, auth string) (keyBytes []byt
 	return plainText, keyId, err
 }
 
+func decryptKeyV4(keyProtected *encryptedKeyJSONV4, auth string) (keyBytes []byte, keyId []byte, err error) {
+	if keyProtected.SigAlg.Version != keySigAlgVersion {
+		return nil, nil, fmt.Errorf("signature algorithm version not supported: %v", keyProtected.SigAlg.Version)
+	}
+	if keyProtected.Crypto.Cipher != keyCipherAEAD {
+		return nil, nil, fmt.Errorf("cipher not supported: %v", keyProtected.Crypto.Cipher)
+	}
+	keyUUID, err := uuid.Parse(keyProtected.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyId = keyUUID[:]
+
+	nonce, err := hex.DecodeString(keyProtected.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, nil, err
+	}
+	cipherText, err := hex.DecodeString(keyProtected.Crypto.CipherText)
+	if err != nil {
+		return nil, nil, err
+	}
+	derivedKey, err := getKDFKey(keyProtected.Crypto, auth)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := newKeyAEAD(derivedKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, nil, fmt.Errorf("invalid nonce length: %d", len(nonce))
+	}
+	plainText, err := aead.Open(nil, nonce, cipherText, keyProtected.additionalData())
+	if err != nil {
+		return nil, nil, ErrDecrypt
+	}
+	return plainText, keyId, nil
+}
+
 func decryptKeyV1(keyProtected *encryptedKeyJSONV1, auth string) (keyBytes []byte, keyId []byte, err error) {
 	keyUUID, err := uuid.Parse(keyProtected.Id)
 	if err != nil {
@@ -348,6 +510,10 @@ func getKDFKey(cryptoJSON CryptoJSON, auth string) ([]byte, error) {
 		return nil, err
 	}
 	dkLen := ensureInt(cryptoJSON.KDFParams["dklen"])
+	// All ciphers use the first 32 bytes of the derived key
+	if dkLen < 32 {
+		return nil, fmt.Errorf("invalid derived key length: %d", dkLen)
+	}
 
 	if cryptoJSON.KDF == keyHeaderKDF {
 		n := ensureInt(cryptoJSON.KDFParams["n"])
@@ -355,6 +521,15 @@ func getKDFKey(cryptoJSON CryptoJSON, auth string) ([]byte, error) {
 		p := ensureInt(cryptoJSON.KDFParams["p"])
 		return scrypt.Key(authArray, salt, n, r, p, dkLen)
 
+	} else if cryptoJSON.KDF == keyHeaderKDFArgon2id {
+		t := ensureInt(cryptoJSON.KDFParams["t"])
+		m := ensureInt(cryptoJSON.KDFParams["m"])
+		p := ensureInt(cryptoJSON.KDFParams["p"])
+		if t < 1 || m < 1 || p < 1 || p > 255 {
+			return nil, fmt.Errorf("invalid Argon2id parameters: t=%d m=%d p=%d dklen=%d", t, m, p, dkLen)
+		}
+		return argon2.IDKey(authArray, salt, uint32(t), uint32(m), uint8(p), uint32(dkLen)), nil
+
 	} else if cryptoJSON.KDF == "pbkdf2" {
 		c := ensureInt(cryptoJSON.KDFParams["c"])
 		prf := cryptoJSON.KDFParams["prf"].(string)
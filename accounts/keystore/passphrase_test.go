@@ -17,10 +17,17 @@
 package keystore
 
 import (
-	"github.com/DogeProtocol/dp/accounts"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
 	"os"
 	"strconv"
 	"testing"
+
+	"github.com/DogeProtocol/dp/accounts"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	wasmkeystore "github.com/DogeProtocol/dp/wasm/accounts/keystore"
 )
 
 const (
@@ -50,3 +57,226 @@ func TestKeyEncryptDecrypt(t *testing.T) {
 	}
 
 }
+
+// encryptKeyV3 encrypts a key into a version 3 key file, as written before the
+// version 4 key files.
+func encryptKeyV3(t *testing.T, key *Key, auth string) []byte {
+	keyBytes, err := cryptobase.SigAlg.SerializePrivateKey(key.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cryptoStruct, err := EncryptDataV3(keyBytes, []byte(auth), veryLightScryptN, veryLightScryptP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyJSON, err := json.Marshal(encryptedKeyJSONV3{hex.EncodeToString(key.Address[:]), cryptoStruct, key.Id.String(), version})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyJSON
+}
+
+func newTestKey(t *testing.T) *Key {
+	privateKey, err := cryptobase.SigAlg.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newKeyFromOQS(privateKey)
+}
+
+func checkDecryptedKey(t *testing.T, keyJSON []byte, auth string, want *Key) {
+	key, err := DecryptKey(keyJSON, auth)
+	if err != nil {
+		t.Fatalf("failed to decrypt key: %v", err)
+	}
+	if key.Address != want.Address || key.Id != want.Id || bytes.Equal(key.PrivateKey.PriData, want.PrivateKey.PriData) == false {
+		t.Fatalf("decrypted key mismatch: have %x, want %x", key.Address, want.Address)
+	}
+}
+
+// Tests that version 4 key files record the signature algorithm and authenticate
+// the fields of the key file.
+func TestKeyV4EncryptDecrypt(t *testing.T) {
+	key := newTestKey(t)
+
+	scryptJSON, err := EncryptKeyV4(key, "pass", veryLightScryptN, veryLightScryptP)
+	if err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	argonJSON, err := EncryptKeyArgon2id(key, "pass", 1, 64, 1)
+	if err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	for _, keyJSON := range [][]byte{scryptJSON, argonJSON} {
+		var k encryptedKeyJSONV4
+		if err := json.Unmarshal(keyJSON, &k); err != nil {
+			t.Fatal(err)
+		}
+		if k.Version != versionV4 || k.SigAlg.Name != cryptobase.SigAlg.SignatureName() || k.Crypto.Cipher != keyCipherAEAD {
+			t.Fatalf("key file header mismatch: %+v", k)
+		}
+		checkDecryptedKey(t, keyJSON, "pass", key)
+
+		if _, err := DecryptKey(keyJSON, "wrong"); err != ErrDecrypt {
+			t.Errorf("error mismatch with the wrong password: have %v, want %v", err, ErrDecrypt)
+		}
+		k.Id = "c2a4e4ae-4e0f-4ec2-b7a2-2c4b1f4bd2c1"
+		tampered, _ := json.Marshal(k)
+		if _, err := DecryptKey(tampered, "pass"); err != ErrDecrypt {
+			t.Errorf("error mismatch with a tampered key file: have %v, want %v", err, ErrDecrypt)
+		}
+		k.SigAlg.Name = "unknown"
+		tampered, _ = json.Marshal(k)
+		if _, err := DecryptKey(tampered, "pass"); err == nil {
+			t.Errorf("decrypted a key of an unknown signature algorithm")
+		}
+	}
+
+	// Version 3 key files are still decrypted
+	checkDecryptedKey(t, encryptKeyV3(t, key, "pass"), "pass", key)
+}
+
+// Tests that keys are encrypted into version 3 key files unless version 4 is asked for,
+// so that they can still be read by the tools that only read version 3.
+func TestKeyEncryptDefaultVersion(t *testing.T) {
+	key := newTestKey(t)
+
+	keyJSON, err := EncryptKey(key, "pass", veryLightScryptN, veryLightScryptP)
+	if err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	var k encryptedKeyJSONV3
+	if err := json.Unmarshal(keyJSON, &k); err != nil || k.Version != version || k.Crypto.Cipher != "aes-256-ctr" {
+		t.Fatalf("key file not of version 3: %s", keyJSON)
+	}
+	checkDecryptedKey(t, keyJSON, "pass", key)
+
+	// New accounts and accounts of which the password is updated have version 3 key files
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+	account, err := ks.NewAccount("pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Update(account, "pass", "new"); err != nil {
+		t.Fatal(err)
+	}
+	keyJSON, err = ioutil.ReadFile(account.URL.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(keyJSON, &k); err != nil || k.Version != version {
+		t.Fatalf("key file not of version 3: %s", keyJSON)
+	}
+}
+
+// Tests that a key file with a derived key shorter than the cipher key is
+// rejected instead of crashing.
+func TestKeyShortDerivedKey(t *testing.T) {
+	key := newTestKey(t)
+
+	v4JSON, err := EncryptKeyV4(key, "pass", veryLightScryptN, veryLightScryptP)
+	if err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	for _, keyJSON := range [][]byte{v4JSON, encryptKeyV3(t, key, "pass")} {
+		m := make(map[string]interface{})
+		if err := json.Unmarshal(keyJSON, &m); err != nil {
+			t.Fatal(err)
+		}
+		m["crypto"].(map[string]interface{})["kdfparams"].(map[string]interface{})["dklen"] = 16
+		tampered, _ := json.Marshal(m)
+		if _, err := DecryptKey(tampered, "pass"); err == nil {
+			t.Errorf("decrypted a key file with a 16 byte derived key")
+		}
+	}
+	if _, err := newKeyAEAD(make([]byte, 16)); err == nil {
+		t.Errorf("created a cipher from a 16 byte derived key")
+	}
+}
+
+// Tests that the version 4 key files can be read by the wasm keystore.
+func TestKeyV4DecryptWasm(t *testing.T) {
+	key := newTestKey(t)
+	keyBytes, err := cryptobase.SigAlg.SerializePrivateKey(key.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cryptobase.SigAlg.SignatureName() != "dilithium-ed25519-sphincs" || len(keyBytes) != 64+2560+1312+128 {
+		t.Skip("the wasm keystore only reads hybrid keys")
+	}
+
+	scryptJSON, err := EncryptKeyV4(key, "pass", veryLightScryptN, veryLightScryptP)
+	if err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	argonJSON, err := EncryptKeyArgon2id(key, "pass", 1, 64, 1)
+	if err != nil {
+		t.Fatalf("failed to encrypt key: %v", err)
+	}
+	for _, keyJSON := range [][]byte{scryptJSON, argonJSON} {
+		wasmKey, err := wasmkeystore.DecryptKey(keyJSON, "pass")
+		if err != nil {
+			t.Fatalf("failed to decrypt key: %v", err)
+		}
+		if wasmKey.Address != key.Address || wasmKey.Id != key.Id || bytes.Equal(wasmKey.PrivateKey.PriData, keyBytes) == false {
+			t.Fatalf("decrypted key mismatch: have %x, want %x", wasmKey.Address, key.Address)
+		}
+		if _, err := wasmkeystore.DecryptKey(keyJSON, "wrong"); err != wasmkeystore.ErrDecrypt {
+			t.Errorf("error mismatch with the wrong password: have %v, want %v", err, wasmkeystore.ErrDecrypt)
+		}
+	}
+}
+
+func TestKeyUpgrade(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	account, err := ks.NewAccount("pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, key, err := ks.getDecryptedKey(account, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v3JSON := encryptKeyV3(t, key, "pass")
+	if err := ioutil.WriteFile(account.URL.Path, v3JSON, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ks.Upgrade(account, "wrong", false); err != ErrDecrypt {
+		t.Fatalf("error mismatch with the wrong password: have %v, want %v", err, ErrDecrypt)
+	}
+	if keyJSON, _ := ioutil.ReadFile(account.URL.Path); bytes.Equal(keyJSON, v3JSON) == false {
+		t.Fatalf("key file changed by a failed upgrade")
+	}
+
+	if err := ks.Upgrade(account, "pass", false); err != nil {
+		t.Fatalf("failed to upgrade: %v", err)
+	}
+	keyJSON, err := ioutil.ReadFile(account.URL.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var k encryptedKeyJSONV4
+	if err := json.Unmarshal(keyJSON, &k); err != nil || k.Version != versionV4 || k.Crypto.KDF != keyHeaderKDF {
+		t.Fatalf("key file not upgraded to version 4 with scrypt: %s", keyJSON)
+	}
+	checkDecryptedKey(t, keyJSON, "pass", key)
+	if err := ks.Upgrade(account, "pass", false); err != ErrKeyUpToDate {
+		t.Fatalf("error mismatch upgrading a version 4 key file: have %v, want %v", err, ErrKeyUpToDate)
+	}
+
+	if err := ks.Upgrade(account, "pass", true); err != nil {
+		t.Fatalf("failed to upgrade to Argon2id: %v", err)
+	}
+	keyJSON, _ = ioutil.ReadFile(account.URL.Path)
+	if err := json.Unmarshal(keyJSON, &k); err != nil || k.Crypto.KDF != keyHeaderKDFArgon2id {
+		t.Fatalf("key file not upgraded to Argon2id: %s", keyJSON)
+	}
+	checkDecryptedKey(t, keyJSON, "pass", key)
+	if err := ks.Unlock(account, "pass"); err != nil {
+		t.Fatalf("failed to unlock the upgraded account: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package accounts
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// DefaultMnemonicBits is the entropy of the mnemonics of new wallets, which makes
+// for 24 words.
+const DefaultMnemonicBits = 256
+
+// masterSeedKey is the HMAC key that the master key of a wallet is derived with
+// from its BIP-39 seed, in the manner of SLIP-10.
+var masterSeedKey = []byte("dp hybrid seed")
+
+var (
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+	ErrInvalidSeed     = errors.New("invalid seed length")
+)
+
+// NewMnemonic returns a new BIP-39 mnemonic with the given bits of entropy, a
+// multiple of 32 from 128 to 256.
+func NewMnemonic(bits int) (string, error) {
+	entropy, err := bip39.NewEntropy(bits)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// SeedFromMnemonic returns the BIP-39 seed of a mnemonic and its optional
+// passphrase, checking the words and the checksum of the mnemonic.
+func SeedFromMnemonic(mnemonic string, passphrase string) ([]byte, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, ErrInvalidMnemonic
+	}
+	return seed, nil
+}
+
+// DeriveAccountSeed derives the 32 byte seed of the account at path from the
+// BIP-39 seed of a wallet. The hybrid keys have no public derivation, so every
+// component of the path is derived as hardened, following the SLIP-10 private
+// key derivation. The account seed is then expanded into a keypair by the
+// signature algorithm.
+func DeriveAccountSeed(seed []byte, path DerivationPath) ([common.HashLength]byte, error) {
+	var accountSeed [common.HashLength]byte
+	if len(seed) < 16 || len(seed) > 64 {
+		return accountSeed, ErrInvalidSeed
+	}
+
+	mac := hmac.New(sha512.New, masterSeedKey)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+	key, chainCode := sum[:32], sum[32:]
+
+	for _, component := range path {
+		data := make([]byte, 1+32+4)
+		copy(data[1:], key)
+		binary.BigEndian.PutUint32(data[33:], component|0x80000000)
+
+		mac = hmac.New(sha512.New, chainCode)
+		mac.Write(data)
+		sum = mac.Sum(nil)
+		key, chainCode = sum[:32], sum[32:]
+	}
+	copy(accountSeed[:], key)
+	return accountSeed, nil
+}
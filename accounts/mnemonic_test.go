@@ -0,0 +1,83 @@
+package accounts
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+// Tests the BIP-39 seed of a mnemonic against the reference test vector.
+func TestSeedFromMnemonic(t *testing.T) {
+	seed, err := SeedFromMnemonic(testMnemonic, "TREZOR")
+	if err != nil {
+		t.Fatalf("failed to derive seed: %v", err)
+	}
+	want := "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"
+	if hex.EncodeToString(seed) != want {
+		t.Errorf("seed mismatch: have %x, want %s", seed, want)
+	}
+
+	invalid := []string{
+		"",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon dpcoin",
+	}
+	for i, mnemonic := range invalid {
+		if _, err := SeedFromMnemonic(mnemonic, ""); err != ErrInvalidMnemonic {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, ErrInvalidMnemonic)
+		}
+	}
+}
+
+func TestNewMnemonic(t *testing.T) {
+	mnemonic, err := NewMnemonic(DefaultMnemonicBits)
+	if err != nil {
+		t.Fatalf("failed to create mnemonic: %v", err)
+	}
+	if words := len(strings.Fields(mnemonic)); words != 24 {
+		t.Errorf("word count mismatch: have %d, want 24", words)
+	}
+	if _, err := SeedFromMnemonic(mnemonic, ""); err != nil {
+		t.Errorf("new mnemonic is invalid: %v", err)
+	}
+	if _, err := NewMnemonic(100); err == nil {
+		t.Errorf("mnemonic created with invalid entropy")
+	}
+}
+
+// Tests the account seeds derived at a few paths from the seed of the reference
+// mnemonic, which fix the derivation of the accounts of existing wallets.
+func TestDeriveAccountSeed(t *testing.T) {
+	seed, err := SeedFromMnemonic(testMnemonic, "TREZOR")
+	if err != nil {
+		t.Fatalf("failed to derive seed: %v", err)
+	}
+	tests := []struct {
+		path string
+		seed string
+	}{
+		{"m/44'/60'/0'/0/0", "488128b78e04860a2e1d932df5f1ba5b662d49163940ccd41f19cd21242e841d"},
+		{"m/44'/60'/0'/0/1", "84f1cb34d6140ca446eaa96bb28193a4d29e4b613a12f15305de9f0d77ddee67"},
+		{"m/44'/60'/1'/0/0", "c3657d663be41d7e69aa04caf0399370266b05b076a3a4e0c2de1cd28e741e6a"},
+		// Every component is derived as hardened
+		{"m/44'/60'/0'/0'/0'", "488128b78e04860a2e1d932df5f1ba5b662d49163940ccd41f19cd21242e841d"},
+	}
+	for _, test := range tests {
+		path, err := ParseDerivationPath(test.path)
+		if err != nil {
+			t.Fatalf("%s: failed to parse path: %v", test.path, err)
+		}
+		accountSeed, err := DeriveAccountSeed(seed, path)
+		if err != nil {
+			t.Fatalf("%s: failed to derive account seed: %v", test.path, err)
+		}
+		if hex.EncodeToString(accountSeed[:]) != test.seed {
+			t.Errorf("%s: account seed mismatch: have %x, want %s", test.path, accountSeed, test.seed)
+		}
+	}
+	if _, err := DeriveAccountSeed(seed[:8], DefaultBaseDerivationPath); err != ErrInvalidSeed {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrInvalidSeed)
+	}
+}
@@ -15,12 +15,37 @@ import (
 	"sync"
 )
 
+const (
+	blockBackupName = "blockbackup.db"
+	txnBackupName   = "txnbackup.db"
+)
+
+// firstBlockKey is the key of the number of the first block in the block store. It is
+// shorter than the hashes that all other keys are.
+var firstBlockKey = []byte("backupFirstBlock")
+
 type BackupManager struct {
 	backupDir     string
 	txBackupLock  sync.Mutex
 	blkBackupLock sync.Mutex
 	blockdb       *ethdb.Database
 	txndb         *ethdb.Database
+
+	// firstBlock and headBlock are the numbers of the first and the highest block in the
+	// block store, guarded by blkBackupLock. hasBlocks is false if the store is empty.
+	firstBlock uint64
+	headBlock  uint64
+	hasBlocks  bool
+
+	segmentDir  string
+	segmentLock sync.RWMutex
+	segments    []*SegmentManifest
+
+	config  Config
+	shipped map[Sink]map[string]bool
+	lastErr string
+	quit    chan struct{}
+	wg      sync.WaitGroup
 }
 
 var singleInstance *BackupManager
@@ -31,7 +56,10 @@ func GetInstance() *BackupManager {
 
 var instanceLock sync.Mutex
 
-func NewBackupManager(backupDir string) (*BackupManager, error) {
+// NewBackupManager opens the backup stores in backupDir and makes them the ones that
+// processed blocks and transactions are backed up to. If config enables the rotation or
+// the retention, they run periodically until the manager is closed.
+func NewBackupManager(backupDir string, config *Config) (*BackupManager, error) {
 	instanceLock.Lock()
 	defer instanceLock.Unlock()
 
@@ -40,11 +68,17 @@ func NewBackupManager(backupDir string) (*BackupManager, error) {
 	}
 
 	bm := &BackupManager{}
+	if config != nil {
+		bm.config = *config
+	}
 
 	err := bm.Initialize(backupDir)
 	if err != nil {
 		return nil, err
 	}
+	if config != nil {
+		bm.start(*config)
+	}
 
 	singleInstance = bm
 	return bm, nil
@@ -53,17 +87,22 @@ func NewBackupManager(backupDir string) (*BackupManager, error) {
 func (b *BackupManager) Initialize(backupDir string) error {
 	log.Debug("Initialize backup", "backupDir", backupDir)
 
-	blockdbFilePath := filepath.Join(backupDir, "blockbackup.db")
+	return b.open(backupDir, false)
+}
+
+func (b *BackupManager) open(backupDir string, readonly bool) error {
+	blockdbFilePath := filepath.Join(backupDir, blockBackupName)
 	var blkdb ethdb.Database
-	blkdb, err := rawdb.NewLevelDBDatabase(blockdbFilePath, 32, 0, "", false)
+	blkdb, err := rawdb.NewLevelDBDatabase(blockdbFilePath, 32, 0, "", readonly)
 	if err != nil {
 		return err
 	}
 
-	txndbFilePath := filepath.Join(backupDir, "txnbackup.db")
+	txndbFilePath := filepath.Join(backupDir, txnBackupName)
 	var txndb ethdb.Database
-	txndb, err = rawdb.NewLevelDBDatabase(txndbFilePath, 64, 0, "", false)
+	txndb, err = rawdb.NewLevelDBDatabase(txndbFilePath, 64, 0, "", readonly)
 	if err != nil {
+		blkdb.Close()
 		return err
 	}
 
@@ -71,10 +110,42 @@ func (b *BackupManager) Initialize(backupDir string) error {
 	b.blockdb = &blkdb
 	b.txndb = &txndb
 
+	b.segmentDir = filepath.Join(backupDir, segmentDirName)
+	b.segments, err = loadSegments(b.segmentDir, b.config.trustedSigners(), readonly == false)
+	if err != nil {
+		blkdb.Close()
+		txndb.Close()
+		return err
+	}
+
+	return b.loadBlockRange()
+}
+
+// loadBlockRange finds the first and the highest block in the block store. Stores that
+// were written before the first block was recorded start at block 1.
+func (b *BackupManager) loadBlockRange() error {
+	db := *b.blockdb
+	first := uint64(1)
+	if enc, err := db.Get(firstBlockKey); err == nil && len(enc) == 8 {
+		first = binary.LittleEndian.Uint64(enc)
+	}
+	if b.HasBlockNumber(first) == false {
+		return nil
+	}
+	head, err := b.lastStoredBlockNumber(first)
+	if err != nil {
+		return err
+	}
+	b.firstBlock, b.headBlock, b.hasBlocks = first, head, true
 	return nil
 }
 
+// BackupTransaction backs up a transaction of the transaction pool.
 func (b *BackupManager) BackupTransaction(tx *types.Transaction) error {
+	return b.backupTransaction(tx, false)
+}
+
+func (b *BackupManager) backupTransaction(tx *types.Transaction, mined bool) error {
 	b.txBackupLock.Lock()
 	defer b.txBackupLock.Unlock()
 
@@ -96,6 +167,11 @@ func (b *BackupManager) BackupTransaction(tx *types.Transaction) error {
 		return err
 	}
 
+	err = indexTransaction(db, tx, mined)
+	if err != nil {
+		return err
+	}
+
 	log.Trace("BackupTransaction", "tx", tx.Hash())
 	return nil
 }
@@ -105,7 +181,7 @@ func (b *BackupManager) BackupBlock(blk *types.Block) error {
 	defer b.blkBackupLock.Unlock()
 
 	for _, tx := range blk.Transactions() {
-		err := b.BackupTransaction(tx)
+		err := b.backupTransaction(tx, true)
 		if err != nil {
 			return err
 		}
@@ -130,18 +206,36 @@ func (b *BackupManager) BackupBlock(blk *types.Block) error {
 	}
 
 	//Mapping from block number to hash
-	blkNumberBytes := make([]byte, 8)
-	binary.LittleEndian.PutUint64(blkNumberBytes, blk.NumberU64())
-	blkNumberHash := crypto.Keccak256(blkNumberBytes)
-	err = db.Put(blkNumberHash, blk.Hash().Bytes())
+	err = db.Put(blockNumberKey(blk.NumberU64()), blk.Hash().Bytes())
 	if err != nil {
 		return err
 	}
 
+	number := blk.NumberU64()
+	if b.hasBlocks == false || number < b.firstBlock {
+		firstBytes := make([]byte, 8)
+		binary.LittleEndian.PutUint64(firstBytes, number)
+		err = db.Put(firstBlockKey, firstBytes)
+		if err != nil {
+			return err
+		}
+		b.firstBlock = number
+	}
+	if b.hasBlocks == false || number > b.headBlock {
+		b.headBlock = number
+	}
+	b.hasBlocks = true
+
 	log.Trace("BackupBlock", "number", blk.Number(), "hash", blk.Hash())
 	return nil
 }
 
+func blockNumberKey(number uint64) []byte {
+	blkNumberBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(blkNumberBytes, number)
+	return crypto.Keccak256(blkNumberBytes)
+}
+
 func (b *BackupManager) BlockExists(hash common.Hash) error {
 	b.blkBackupLock.Lock()
 	defer b.blkBackupLock.Unlock()
@@ -172,12 +266,8 @@ func (b *BackupManager) GetBlockHash(number uint64) (common.Hash, error) {
 	b.blkBackupLock.Lock()
 	defer b.blkBackupLock.Unlock()
 
-	blkNumberBytes := make([]byte, 8)
-	binary.LittleEndian.PutUint64(blkNumberBytes, number)
-	blkNumberHash := crypto.Keccak256(blkNumberBytes)
-
 	db := *b.blockdb
-	blockHashBytes, err := db.Get(blkNumberHash)
+	blockHashBytes, err := db.Get(blockNumberKey(number))
 	if err != nil {
 		return common.ZERO_HASH, err
 	}
@@ -203,12 +293,17 @@ func (b *BackupManager) TrsansactionExists(hash common.Hash) error {
 }
 
 func (b *BackupManager) Close() error {
+	b.stop()
+
 	b.blkBackupLock.Lock()
 	defer b.blkBackupLock.Unlock()
 
 	b.txBackupLock.Lock()
 	defer b.txBackupLock.Unlock()
 
+	if b.blockdb == nil {
+		return nil
+	}
 	blkdb := *b.blockdb
 	err := blkdb.Close()
 	if err != nil {
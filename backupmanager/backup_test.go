@@ -1,13 +1,19 @@
 package backupmanager
 
 import (
+	"bytes"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
 	"github.com/DogeProtocol/dp/trie"
 	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 // Returns a random hash
@@ -35,7 +41,7 @@ func largeNumber(megabytes int) *big.Int {
 func TestBackup(t *testing.T) {
 	tmpdir := t.TempDir()
 
-	bm, err := NewBackupManager(tmpdir)
+	bm, err := NewBackupManager(tmpdir, nil)
 	if err != nil {
 		fmt.Println("err", err)
 		t.Fatalf("failed NewBackupManager")
@@ -144,3 +150,542 @@ func TestBackup(t *testing.T) {
 		t.Fatalf("failed 4")
 	}
 }
+
+// backupChain backs up a chain of count blocks with one transaction each, starting at
+// block 1.
+func backupChain(t *testing.T, bm *BackupManager, count int) []*types.Block {
+	parent := randHash()
+	blocks := make([]*types.Block, 0)
+	for i := 1; i <= count; i++ {
+		header := &types.Header{
+			ParentHash: parent,
+			Root:       randHash(),
+			Number:     big.NewInt(int64(i)),
+			Difficulty: big.NewInt(1),
+			Time:       uint64(i),
+			Extra:      []byte{},
+		}
+		to := randAddress()
+		txs := []*types.Transaction{types.NewTx(types.NewDefaultFeeTransactionSimple(uint64(i), &to, big.NewInt(100), 21000, nil))}
+		block := types.NewBlock(header, txs, nil, trie.NewStackTrie(nil))
+		err := bm.BackupBlock(block)
+		if err != nil {
+			t.Fatalf("failed BackupBlock %v", err)
+		}
+		blocks = append(blocks, block)
+		parent = block.Hash()
+	}
+	return blocks
+}
+
+func TestVerifyChain(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	bm := &BackupManager{}
+	err := bm.Initialize(tmpdir)
+	if err != nil {
+		t.Fatalf("failed Initialize %v", err)
+	}
+	blocks := backupChain(t, bm, 100)
+	err = bm.Close()
+	if err != nil {
+		t.Fatalf("failed Close %v", err)
+	}
+
+	bm, err = OpenBackup(tmpdir, nil)
+	if err != nil {
+		t.Fatalf("failed OpenBackup %v", err)
+	}
+
+	last, err := bm.LastBlockNumber(1)
+	if err != nil {
+		t.Fatalf("failed LastBlockNumber %v", err)
+	}
+	if last != 100 {
+		t.Fatalf("last block mismatch %d", last)
+	}
+	last, err = bm.LastBlockNumber(37)
+	if err != nil || last != 100 {
+		t.Fatalf("last block mismatch %d %v", last, err)
+	}
+	_, err = bm.LastBlockNumber(101)
+	if err != ErrBlockNotFound {
+		t.Fatalf("expected ErrBlockNotFound, got %v", err)
+	}
+
+	verified := 0
+	err = bm.VerifyChain(1, last, func(block *types.Block) {
+		if block.Hash() != blocks[verified].Hash() {
+			t.Fatalf("block %d mismatch", block.NumberU64())
+		}
+		verified++
+	})
+	if err != nil {
+		t.Fatalf("failed VerifyChain %v", err)
+	}
+	if verified != 100 {
+		t.Fatalf("verified %d blocks", verified)
+	}
+
+	txs := 0
+	err = bm.ForEachTransaction(func(tx *types.Transaction) error {
+		txs++
+		return nil
+	})
+	if err != nil || txs != 100 {
+		t.Fatalf("transaction count mismatch %d %v", txs, err)
+	}
+
+	err = bm.VerifyChain(1, 101, nil)
+	if err == nil {
+		t.Fatalf("expected missing block error")
+	}
+	err = bm.Close()
+	if err != nil {
+		t.Fatalf("failed Close %v", err)
+	}
+
+	// Replace block 50 with a block that is not the child of block 49
+	bm = &BackupManager{}
+	err = bm.Initialize(tmpdir)
+	if err != nil {
+		t.Fatalf("failed Initialize %v", err)
+	}
+	header := blocks[49].Header()
+	header.ParentHash = randHash()
+	err = bm.BackupBlock(types.NewBlockWithHeader(header).WithBody(blocks[49].Transactions()))
+	if err != nil {
+		t.Fatalf("failed BackupBlock %v", err)
+	}
+	err = bm.VerifyChain(1, 100, nil)
+	if err == nil {
+		t.Fatalf("expected parent mismatch error")
+	}
+	err = bm.VerifyChain(51, 100, nil)
+	if err != nil {
+		t.Fatalf("failed VerifyChain %v", err)
+	}
+	err = bm.Close()
+	if err != nil {
+		t.Fatalf("failed Close %v", err)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	tmpdir := t.TempDir()
+	sinkdir := filepath.Join(t.TempDir(), "sink")
+
+	key, err := cryptobase.SigAlg.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed GenerateKey %v", err)
+	}
+	signer, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed PublicKeyToAddress %v", err)
+	}
+	trusted := []common.Address{randAddress(), signer}
+	sink, err := NewLocalSink(sinkdir)
+	if err != nil {
+		t.Fatalf("failed NewLocalSink %v", err)
+	}
+
+	bm := &BackupManager{}
+	err = bm.Initialize(tmpdir)
+	if err != nil {
+		t.Fatalf("failed Initialize %v", err)
+	}
+	blocks := backupChain(t, bm, 300)
+
+	bm.start(Config{
+		RetentionBlocks:  50,
+		SegmentBlocks:    100,
+		RotationInterval: time.Hour,
+		SigningKey:       key,
+		Sinks:            []Sink{sink},
+	})
+	bm.rotate()
+	if bm.lastErr != "" {
+		t.Fatalf("failed rotate %v", bm.lastErr)
+	}
+
+	// Blocks 201 to 300 are within segmentConfirmations of the head
+	segments := bm.Segments()
+	if len(segments) != 2 || segments[0].FirstBlock != 1 || segments[1].LastBlock != 200 {
+		t.Fatalf("unexpected segments %v", segments)
+	}
+	if segments[1].FirstHash != blocks[100].Hash() || segments[1].LastHash != blocks[199].Hash() {
+		t.Fatalf("segment hash mismatch")
+	}
+	for _, segment := range segments {
+		if has, err := sink.Has(segment); err != nil || has == false {
+			t.Fatalf("segment %s not shipped %v", segment.Name(), err)
+		}
+	}
+
+	// The sealed blocks are out of the retention, the others are not sealed yet
+	if bm.HasBlockNumber(200) || bm.HasBlockNumber(201) == false {
+		t.Fatalf("unexpected retention")
+	}
+	first, err := bm.FirstBlockNumber()
+	if err != nil || first != 1 {
+		t.Fatalf("first block mismatch %d %v", first, err)
+	}
+	last, err := bm.LastBlockNumber(first)
+	if err != nil || last != 300 {
+		t.Fatalf("last block mismatch %d %v", last, err)
+	}
+	block, err := bm.GetBlockByNumber(150)
+	if err != nil || block.Hash() != blocks[149].Hash() {
+		t.Fatalf("failed GetBlockByNumber from segment %v", err)
+	}
+	err = bm.VerifyChain(1, 300, nil)
+	if err != nil {
+		t.Fatalf("failed VerifyChain %v", err)
+	}
+	txs := 0
+	bm.ForEachTransaction(func(tx *types.Transaction) error {
+		txs++
+		return nil
+	})
+	if txs != 100 {
+		t.Fatalf("transactions of pruned blocks not removed, %d left", txs)
+	}
+	err = bm.Close()
+	if err != nil {
+		t.Fatalf("failed Close %v", err)
+	}
+
+	// The segments are verified when the backup is opened again, if they are signed by a
+	// trusted signer
+	_, err = OpenBackup(tmpdir, []common.Address{randAddress()})
+	if errors.Is(err, ErrSegmentUntrustedSigner) == false {
+		t.Fatalf("expected ErrSegmentUntrustedSigner, got %v", err)
+	}
+	bm, err = OpenBackup(tmpdir, trusted)
+	if err != nil {
+		t.Fatalf("failed OpenBackup %v", err)
+	}
+	first, err = bm.FirstBlockNumber()
+	if err != nil || first != 1 {
+		t.Fatalf("first block mismatch %d %v", first, err)
+	}
+	bm.Close()
+
+	// The sink can be opened as a backup of its own
+	bm, err = OpenBackup(sinkdir, trusted)
+	if err != nil {
+		t.Fatalf("failed OpenBackup of sink %v", err)
+	}
+	last, err = bm.LastBlockNumber(1)
+	if err != nil || last != 200 {
+		t.Fatalf("last block mismatch %d %v", last, err)
+	}
+	err = bm.VerifyChain(1, 200, nil)
+	if err != nil {
+		t.Fatalf("failed VerifyChain of sink %v", err)
+	}
+	bm.Close()
+
+	// A tampered segment is rejected
+	path := filepath.Join(sinkdir, segments[1].SegmentFileName())
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed ReadFile %v", err)
+	}
+	data[len(data)/2] ^= 0xff
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatalf("failed WriteFile %v", err)
+	}
+	_, err = OpenBackup(sinkdir, trusted)
+	if errors.Is(err, ErrSegmentContentMismatch) == false {
+		t.Fatalf("expected ErrSegmentContentMismatch, got %v", err)
+	}
+
+	// So is a manifest that is not signed by its signer
+	segments[0].Signer = randAddress()
+	if errors.Is(segments[0].VerifySignature(), ErrSegmentInvalidSignature) == false {
+		t.Fatalf("expected ErrSegmentInvalidSignature")
+	}
+}
+
+func TestTransactionsByAddress(t *testing.T) {
+	tmpdir := t.TempDir()
+	bm := &BackupManager{}
+	err := bm.Initialize(tmpdir)
+	if err != nil {
+		t.Fatalf("failed Initialize %v", err)
+	}
+	defer bm.Close()
+
+	key, err := cryptobase.SigAlg.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed GenerateKey %v", err)
+	}
+	from, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed PublicKeyToAddress %v", err)
+	}
+	to := randAddress()
+	signer := types.NewLondonSignerDefaultChain()
+	txs := make([]*types.Transaction, 0)
+	for i := 0; i < 3; i++ {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), to, big.NewInt(100), 21000, big.NewInt(1), nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed SignTx %v", err)
+		}
+		if err := bm.BackupTransaction(tx); err != nil {
+			t.Fatalf("failed BackupTransaction %v", err)
+		}
+		txs = append(txs, tx)
+	}
+
+	tx, firstSeen, err := bm.GetTransaction(txs[1].Hash())
+	if err != nil {
+		t.Fatalf("failed GetTransaction %v", err)
+	}
+	if tx.Hash() != txs[1].Hash() || firstSeen == 0 {
+		t.Fatalf("GetTransaction returned %s first seen at %d", tx.Hash(), firstSeen)
+	}
+	if _, _, err := bm.GetTransaction(randHash()); err != ErrTransactionNotFound {
+		t.Fatalf("GetTransaction of a missing transaction returned %v", err)
+	}
+
+	for _, address := range []common.Address{from, to} {
+		found, cursor, err := bm.TransactionsByAddress(address, nil, 10)
+		if err != nil {
+			t.Fatalf("failed TransactionsByAddress %v", err)
+		}
+		if len(found) != len(txs) || cursor != nil {
+			t.Fatalf("TransactionsByAddress %s returned %d transactions and cursor %x, want %d", address, len(found), cursor, len(txs))
+		}
+	}
+	found, _, err := bm.TransactionsByAddress(randAddress(), nil, 10)
+	if err != nil || len(found) != 0 {
+		t.Fatalf("TransactionsByAddress of an unknown address returned %d transactions, %v", len(found), err)
+	}
+
+	// The transactions are listed the most recent first, by page
+	var listed []*types.Transaction
+	var cursor []byte
+	for pages := 0; ; pages++ {
+		if pages == len(txs) {
+			t.Fatalf("TransactionsByAddress did not end")
+		}
+		found, cursor, err = bm.TransactionsByAddress(to, cursor, 2)
+		if err != nil {
+			t.Fatalf("failed TransactionsByAddress %v", err)
+		}
+		if len(found) == 0 || len(found) > 2 {
+			t.Fatalf("TransactionsByAddress returned %d transactions", len(found))
+		}
+		for _, tx := range found {
+			if tx.FirstSeen == 0 {
+				t.Fatalf("transaction %s has no first seen time", tx.Transaction.Hash())
+			}
+			listed = append(listed, tx.Transaction)
+		}
+		if cursor == nil {
+			break
+		}
+	}
+	if len(listed) != len(txs) {
+		t.Fatalf("listed %d transactions, want %d", len(listed), len(txs))
+	}
+	for i, tx := range listed {
+		if want := txs[len(txs)-1-i]; tx.Hash() != want.Hash() {
+			t.Fatalf("transaction %d is %s, want %s", i, tx.Hash(), want.Hash())
+		}
+	}
+	if _, _, err := bm.TransactionsByAddress(to, []byte{1, 2, 3}, 2); err != ErrInvalidCursor {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	// A transaction that replaces another is found by its nonce
+	replacement, err := types.SignTx(types.NewTransaction(1, randAddress(), big.NewInt(100), 21000, big.NewInt(2), nil), signer, key)
+	if err != nil {
+		t.Fatalf("failed SignTx %v", err)
+	}
+	if err := bm.BackupTransaction(replacement); err != nil {
+		t.Fatalf("failed BackupTransaction %v", err)
+	}
+	sameNonce, err := bm.TransactionsByNonce(from, 1)
+	if err != nil {
+		t.Fatalf("failed TransactionsByNonce %v", err)
+	}
+	if len(sameNonce) != 2 {
+		t.Fatalf("TransactionsByNonce returned %d transactions, want 2", len(sameNonce))
+	}
+	for _, tx := range sameNonce {
+		if tx.Hash() != txs[1].Hash() && tx.Hash() != replacement.Hash() {
+			t.Fatalf("TransactionsByNonce returned %s", tx.Hash())
+		}
+	}
+	if sameNonce, err := bm.TransactionsByNonce(to, 1); err != nil || len(sameNonce) != 0 {
+		t.Fatalf("TransactionsByNonce of a recipient returned %d transactions, %v", len(sameNonce), err)
+	}
+
+	// The transactions of pruned blocks are removed from the index
+	blocks := backupChain(t, bm, 2)
+	blockTo := *blocks[0].Transactions()[0].To()
+	if err := bm.pruneBlocks(1, 1); err != nil {
+		t.Fatalf("failed pruneBlocks %v", err)
+	}
+	found, _, err = bm.TransactionsByAddress(blockTo, nil, 10)
+	if err != nil || len(found) != 0 {
+		t.Fatalf("TransactionsByAddress of a pruned transaction returned %d transactions, %v", len(found), err)
+	}
+	it := (*bm.txndb).NewIterator(append(common.CopyBytes(addressIndexPrefix), blockTo.Bytes()...), nil)
+	defer it.Release()
+	if it.Next() {
+		t.Fatalf("address index key %x was not pruned", it.Key())
+	}
+}
+
+func TestSegmentVerificationCache(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	key, err := cryptobase.SigAlg.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed GenerateKey %v", err)
+	}
+	bm := &BackupManager{config: Config{SigningKey: key}}
+	err = bm.Initialize(tmpdir)
+	if err != nil {
+		t.Fatalf("failed Initialize %v", err)
+	}
+	backupChain(t, bm, 200)
+	bm.config.SegmentBlocks = 100
+	if err := bm.sealSegments(); err != nil {
+		t.Fatalf("failed sealSegments %v", err)
+	}
+	segments := bm.Segments()
+	if len(segments) != 1 {
+		t.Fatalf("unexpected segments %v", segments)
+	}
+	if err := bm.Close(); err != nil {
+		t.Fatalf("failed Close %v", err)
+	}
+	segmentDir := filepath.Join(tmpdir, segmentDirName)
+	if _, ok := readSegmentVerifications(segmentDir)[segments[0].Name()]; ok == false {
+		t.Fatalf("verification of the sealed segment not cached")
+	}
+
+	// A segment that is changed without changing its size or modification time is not
+	// hashed again when the backup is opened, but is by VerifySegments
+	path := filepath.Join(segmentDir, segments[0].SegmentFileName())
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed Stat %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed ReadFile %v", err)
+	}
+	data[len(data)/2] ^= 0xff
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed WriteFile %v", err)
+	}
+	if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+		t.Fatalf("failed Chtimes %v", err)
+	}
+	bm = &BackupManager{config: Config{SigningKey: key}}
+	if err := bm.Initialize(tmpdir); err != nil {
+		t.Fatalf("failed Initialize with a cached verification %v", err)
+	}
+	if err := bm.VerifySegments(); errors.Is(err, ErrSegmentContentMismatch) == false {
+		t.Fatalf("expected ErrSegmentContentMismatch, got %v", err)
+	}
+	bm.Close()
+
+	// Once the modification time changes, the segment is hashed again
+	if err := os.Chtimes(path, info.ModTime().Add(time.Second), info.ModTime().Add(time.Second)); err != nil {
+		t.Fatalf("failed Chtimes %v", err)
+	}
+	bm = &BackupManager{config: Config{SigningKey: key}}
+	if err := bm.Initialize(tmpdir); errors.Is(err, ErrSegmentContentMismatch) == false {
+		t.Fatalf("expected ErrSegmentContentMismatch, got %v", err)
+	}
+}
+
+func TestPrunePendingTransactions(t *testing.T) {
+	tmpdir := t.TempDir()
+	bm := &BackupManager{}
+	err := bm.Initialize(tmpdir)
+	if err != nil {
+		t.Fatalf("failed Initialize %v", err)
+	}
+	defer bm.Close()
+
+	key, err := cryptobase.SigAlg.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed GenerateKey %v", err)
+	}
+	to := randAddress()
+	signer := types.NewLondonSignerDefaultChain()
+	var txs []*types.Transaction
+	for i := 0; i < 2; i++ {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), to, big.NewInt(100), 21000, big.NewInt(1), nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed SignTx %v", err)
+		}
+		if err := bm.BackupTransaction(tx); err != nil {
+			t.Fatalf("failed BackupTransaction %v", err)
+		}
+		txs = append(txs, tx)
+	}
+
+	// The first transaction is mined
+	header := &types.Header{Number: big.NewInt(1), ParentHash: randHash(), Time: 1337}
+	block := types.NewBlock(header, txs[:1], nil, trie.NewStackTrie(nil))
+	if err := bm.BackupBlock(block); err != nil {
+		t.Fatalf("failed BackupBlock %v", err)
+	}
+
+	if err := bm.prunePendingTransactions(uint64(time.Now().Unix()) + 1); err != nil {
+		t.Fatalf("failed prunePendingTransactions %v", err)
+	}
+	if _, _, err := bm.GetTransaction(txs[0].Hash()); err != nil {
+		t.Fatalf("mined transaction was pruned %v", err)
+	}
+	if _, _, err := bm.GetTransaction(txs[1].Hash()); err != ErrTransactionNotFound {
+		t.Fatalf("expected the transaction that was not mined to be pruned, got %v", err)
+	}
+	found, _, err := bm.TransactionsByAddress(to, nil, 10)
+	if err != nil || len(found) != 1 || found[0].Transaction.Hash() != txs[0].Hash() {
+		t.Fatalf("TransactionsByAddress returned %d transactions, %v", len(found), err)
+	}
+	it := (*bm.txndb).NewIterator(txPendingPrefix, nil)
+	defer it.Release()
+	for it.Next() {
+		if len(it.Key()) == len(txPendingPrefix)+8+common.HashLength {
+			t.Fatalf("pending key %x was not pruned", it.Key())
+		}
+	}
+	from, err := types.Sender(signer, txs[0])
+	if err != nil {
+		t.Fatalf("failed Sender %v", err)
+	}
+	nonceIt := (*bm.txndb).NewIterator(txNoncePrefix, nil)
+	defer nonceIt.Release()
+	for nonceIt.Next() {
+		if bytes.Equal(nonceIt.Key(), txNonceKey(from, 0, txs[0].Hash())) == false {
+			t.Fatalf("nonce key %x was not pruned", nonceIt.Key())
+		}
+	}
+
+	// Transactions backed up after the cutoff are kept
+	tx, err := types.SignTx(types.NewTransaction(2, to, big.NewInt(100), 21000, big.NewInt(1), nil), signer, key)
+	if err != nil {
+		t.Fatalf("failed SignTx %v", err)
+	}
+	if err := bm.BackupTransaction(tx); err != nil {
+		t.Fatalf("failed BackupTransaction %v", err)
+	}
+	if err := bm.prunePendingTransactions(uint64(time.Now().Add(-time.Hour).Unix())); err != nil {
+		t.Fatalf("failed prunePendingTransactions %v", err)
+	}
+	if _, _, err := bm.GetTransaction(tx.Hash()); err != nil {
+		t.Fatalf("recent transaction was pruned %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package backupmanager
+
+import (
+	"time"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+)
+
+const (
+	// DefaultRotationInterval is the interval at which blocks are sealed into segments,
+	// segments are shipped to the sinks and the retention is applied.
+	DefaultRotationInterval = time.Minute
+
+	// segmentConfirmations is the number of most recent blocks that are never sealed into
+	// a segment, since they can still be replaced by a reorg.
+	segmentConfirmations = 64
+)
+
+// Config is the configuration of the retention and the rotation of a backup.
+type Config struct {
+	// RetentionBlocks is the number of most recent blocks kept in the backup stores;
+	// 0 keeps all of them.
+	RetentionBlocks uint64
+
+	// RetentionAge is the maximum age of the blocks kept in the backup stores, by their
+	// block time; 0 keeps all of them.
+	RetentionAge time.Duration
+
+	// SegmentBlocks is the number of blocks sealed into each segment; 0 disables the
+	// rotation. When it is enabled, blocks are only removed from the backup stores
+	// once they are sealed into a segment.
+	SegmentBlocks uint64
+
+	// RotationInterval is the interval at which the rotation and the retention run.
+	RotationInterval time.Duration
+
+	// SigningKey is the key that segment manifests are signed with. Rotation is
+	// disabled without it.
+	SigningKey *signaturealgorithm.PrivateKey
+
+	// TrustedSigners are the signers whose segments are loaded, besides the signer of
+	// SigningKey. Segments signed by anyone else are refused.
+	TrustedSigners []common.Address
+
+	// Sinks are the sinks that sealed segments are shipped to.
+	Sinks []Sink
+}
+
+func (c *Config) rotationEnabled() bool {
+	return c.SegmentBlocks > 0 && c.SigningKey != nil
+}
+
+func (c *Config) retentionEnabled() bool {
+	return c.RetentionBlocks > 0 || c.RetentionAge > 0
+}
+
+// trustedSigners returns the signer of SigningKey and the TrustedSigners.
+func (c *Config) trustedSigners() []common.Address {
+	signers := make([]common.Address, 0, len(c.TrustedSigners)+1)
+	if c.SigningKey != nil {
+		if signer, err := cryptobase.SigAlg.PublicKeyToAddress(&c.SigningKey.PublicKey); err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	return append(signers, c.TrustedSigners...)
+}
@@ -0,0 +1,261 @@
+package backupmanager
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/ethdb"
+	"github.com/DogeProtocol/dp/rlp"
+)
+
+var (
+	// addressIndexPrefix + address + inverted first seen time (uint64 big endian) +
+	// inverted nonce (uint64 big endian) + transaction hash -> nothing. The inverted time
+	// and nonce list the transactions of an address the most recently backed up first.
+	// The keys are longer than the hashes that transactions are stored under.
+	addressIndexPrefix = []byte("a")
+
+	// txNoncePrefix + sender + nonce (uint64 big endian) + transaction hash -> nothing,
+	// to find the transactions that replace each other.
+	txNoncePrefix = []byte("n")
+
+	// txFirstSeenPrefix + transaction hash -> the time the transaction was first backed up
+	txFirstSeenPrefix = []byte("s")
+
+	// txPendingPrefix + first seen time (uint64 big endian) + transaction hash -> nothing,
+	// for the transactions that are not in a backed up block. The transactions that are
+	// never mined are pruned from these, in the order they were backed up.
+	txPendingPrefix = []byte("p")
+)
+
+// addressIndexCursorLength is the length of a pagination cursor, the position of a
+// transaction in the address index after the address.
+const addressIndexCursorLength = 8 + 8 + common.HashLength
+
+var ErrTransactionNotFound = errors.New("transaction not found in backup")
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// AddressTransaction is a backed up transaction of an address, along with the time it
+// was first backed up.
+type AddressTransaction struct {
+	Transaction *types.Transaction
+	FirstSeen   uint64
+}
+
+func addressIndexKey(address common.Address, firstSeen uint64, tx *types.Transaction) []byte {
+	key := make([]byte, 0, len(addressIndexPrefix)+common.AddressLength+addressIndexCursorLength)
+	key = append(key, addressIndexPrefix...)
+	key = append(key, address.Bytes()...)
+	return append(key, addressIndexCursor(firstSeen, tx.Nonce(), tx.Hash())...)
+}
+
+// addressIndexCursor encodes the position of a transaction in the address index. The
+// time and the nonce are inverted so that the most recent transactions come first.
+func addressIndexCursor(firstSeen uint64, nonce uint64, hash common.Hash) []byte {
+	cursor := make([]byte, 16, addressIndexCursorLength)
+	binary.BigEndian.PutUint64(cursor, math.MaxUint64-firstSeen)
+	binary.BigEndian.PutUint64(cursor[8:], math.MaxUint64-nonce)
+	return append(cursor, hash.Bytes()...)
+}
+
+func txNonceKey(sender common.Address, nonce uint64, hash common.Hash) []byte {
+	key := make([]byte, len(txNoncePrefix)+common.AddressLength+8, len(txNoncePrefix)+common.AddressLength+8+common.HashLength)
+	copy(key, txNoncePrefix)
+	copy(key[len(txNoncePrefix):], sender.Bytes())
+	binary.BigEndian.PutUint64(key[len(txNoncePrefix)+common.AddressLength:], nonce)
+	return append(key, hash.Bytes()...)
+}
+
+func txFirstSeenKey(hash common.Hash) []byte {
+	return append(common.CopyBytes(txFirstSeenPrefix), hash.Bytes()...)
+}
+
+func txPendingKey(firstSeen uint64, hash common.Hash) []byte {
+	key := make([]byte, len(txPendingPrefix)+8, len(txPendingPrefix)+8+common.HashLength)
+	copy(key, txPendingPrefix)
+	binary.BigEndian.PutUint64(key[len(txPendingPrefix):], firstSeen)
+	return append(key, hash.Bytes()...)
+}
+
+// transactionAddresses returns the sender and the recipient of a transaction that are
+// indexed. The sender is cached in the transaction once the pool has validated it.
+func transactionAddresses(tx *types.Transaction) []common.Address {
+	var addresses []common.Address
+	if from, ok := transactionSender(tx); ok {
+		addresses = append(addresses, from)
+	}
+	if tx.To() != nil && (len(addresses) == 0 || addresses[0] != *tx.To()) {
+		addresses = append(addresses, *tx.To())
+	}
+	return addresses
+}
+
+// transactionSender returns the sender of a transaction, if it can be recovered.
+func transactionSender(tx *types.Transaction) (common.Address, bool) {
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	return from, err == nil
+}
+
+// readFirstSeen returns the time a transaction was first backed up, if it is indexed.
+func readFirstSeen(db ethdb.KeyValueReader, hash common.Hash) (uint64, bool) {
+	enc, err := db.Get(txFirstSeenKey(hash))
+	if err != nil || len(enc) != 8 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint64(enc), true
+}
+
+// indexTransaction adds the transaction to the address and nonce indexes, keeping the
+// time it was first backed up if it is already indexed. Transactions that are not mined
+// are kept pending until their block is backed up.
+func indexTransaction(db ethdb.Database, tx *types.Transaction, mined bool) error {
+	hash := tx.Hash()
+	if firstSeen, ok := readFirstSeen(db, hash); ok {
+		if mined {
+			return db.Delete(txPendingKey(firstSeen, hash))
+		}
+		return nil
+	}
+
+	now := uint64(time.Now().Unix())
+	firstSeen := make([]byte, 8)
+	binary.LittleEndian.PutUint64(firstSeen, now)
+
+	batch := db.NewBatch()
+	if err := batch.Put(txFirstSeenKey(hash), firstSeen); err != nil {
+		return err
+	}
+	if mined == false {
+		if err := batch.Put(txPendingKey(now, hash), nil); err != nil {
+			return err
+		}
+	}
+	for _, address := range transactionAddresses(tx) {
+		if err := batch.Put(addressIndexKey(address, now, tx), nil); err != nil {
+			return err
+		}
+	}
+	if from, ok := transactionSender(tx); ok {
+		if err := batch.Put(txNonceKey(from, tx.Nonce(), hash), nil); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// GetTransaction returns the backed up transaction with the given hash, along with the
+// time it was first backed up, which is 0 for transactions backed up before the address
+// index was added.
+func (b *BackupManager) GetTransaction(hash common.Hash) (*types.Transaction, uint64, error) {
+	b.txBackupLock.Lock()
+	defer b.txBackupLock.Unlock()
+
+	if b.txndb == nil {
+		return nil, 0, ErrTransactionNotFound
+	}
+	db := *b.txndb
+	tx, err := readTransaction(db, hash)
+	if err != nil {
+		return nil, 0, err
+	}
+	firstSeen, _ := readFirstSeen(db, hash)
+	return tx, firstSeen, nil
+}
+
+// readTransaction reads and decodes a backed up transaction.
+func readTransaction(db ethdb.KeyValueReader, hash common.Hash) (*types.Transaction, error) {
+	if has, err := db.Has(hash.Bytes()); err != nil {
+		return nil, err
+	} else if has == false {
+		return nil, ErrTransactionNotFound
+	}
+	txBytes, err := db.Get(hash.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(txBytes, tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// TransactionsByAddress returns up to limit backed up transactions sent from or to an
+// address, the most recently backed up first. Listing starts at the given cursor if it
+// is not empty, and the returned cursor is empty when there are no more transactions.
+// Only the transactions that are returned are read from the store.
+func (b *BackupManager) TransactionsByAddress(address common.Address, cursor []byte, limit int) ([]*AddressTransaction, []byte, error) {
+	if len(cursor) != 0 && len(cursor) != addressIndexCursorLength {
+		return nil, nil, ErrInvalidCursor
+	}
+
+	b.txBackupLock.Lock()
+	defer b.txBackupLock.Unlock()
+
+	if b.txndb == nil {
+		return nil, nil, nil
+	}
+	db := *b.txndb
+	prefix := append(common.CopyBytes(addressIndexPrefix), address.Bytes()...)
+	it := db.NewIterator(prefix, cursor)
+	defer it.Release()
+
+	txs := make([]*AddressTransaction, 0)
+	for it.Next() {
+		position := it.Key()[len(prefix):]
+		if len(position) != addressIndexCursorLength {
+			continue
+		}
+		if len(txs) == limit {
+			return txs, common.CopyBytes(position), nil
+		}
+		tx, err := readTransaction(db, common.BytesToHash(position[16:]))
+		if err == ErrTransactionNotFound {
+			// Removed by the retention
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		txs = append(txs, &AddressTransaction{
+			Transaction: tx,
+			FirstSeen:   math.MaxUint64 - binary.BigEndian.Uint64(position[:8]),
+		})
+	}
+	return txs, nil, it.Error()
+}
+
+// TransactionsByNonce returns the backed up transactions sent from an address with the
+// given nonce.
+func (b *BackupManager) TransactionsByNonce(sender common.Address, nonce uint64) ([]*types.Transaction, error) {
+	b.txBackupLock.Lock()
+	defer b.txBackupLock.Unlock()
+
+	if b.txndb == nil {
+		return nil, nil
+	}
+	db := *b.txndb
+	prefix := txNonceKey(sender, nonce, common.Hash{})[:len(txNoncePrefix)+common.AddressLength+8]
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	var txs []*types.Transaction
+	for it.Next() {
+		if len(it.Key()) != len(prefix)+common.HashLength {
+			continue
+		}
+		tx, err := readTransaction(db, common.BytesToHash(it.Key()[len(prefix):]))
+		if err == ErrTransactionNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, tx)
+	}
+	return txs, it.Error()
+}
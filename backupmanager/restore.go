@@ -0,0 +1,303 @@
+package backupmanager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/rlp"
+	"github.com/DogeProtocol/dp/trie"
+)
+
+var ErrBackupNotFound = errors.New("backup not found")
+var ErrBlockNotFound = errors.New("block not found in backup")
+
+// OpenBackup opens the backup stores in backupDir for reading. Unlike NewBackupManager,
+// the stores are not written to and do not become the ones that processed blocks and
+// transactions are backed up to, so that a backup can be read by an offline command.
+// backupDir can also be a directory that segments were shipped to, without stores. Only
+// segments signed by one of the trusted signers are loaded.
+func OpenBackup(backupDir string, trustedSigners []common.Address) (*BackupManager, error) {
+	log.Debug("Open backup", "backupDir", backupDir)
+
+	var missing []string
+	for _, name := range []string{blockBackupName, txnBackupName} {
+		if _, err := os.Stat(filepath.Join(backupDir, name)); os.IsNotExist(err) {
+			missing = append(missing, filepath.Join(backupDir, name))
+		}
+	}
+	if len(missing) == 1 {
+		return nil, fmt.Errorf("%w: %s", ErrBackupNotFound, missing[0])
+	}
+	if len(missing) == 2 {
+		segments, err := loadSegments(backupDir, trustedSigners, false)
+		if err != nil {
+			return nil, err
+		}
+		if len(segments) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrBackupNotFound, missing[0])
+		}
+		return &BackupManager{backupDir: backupDir, segmentDir: backupDir, segments: segments}, nil
+	}
+
+	bm := &BackupManager{config: Config{TrustedSigners: trustedSigners}}
+	err := bm.open(backupDir, true)
+	if err != nil {
+		return nil, err
+	}
+	return bm, nil
+}
+
+// HasBlockNumber returns whether a block with the given number was backed up.
+func (b *BackupManager) HasBlockNumber(number uint64) bool {
+	b.blkBackupLock.Lock()
+	defer b.blkBackupLock.Unlock()
+
+	if b.blockdb == nil {
+		return false
+	}
+	db := *b.blockdb
+	has, err := db.Has(blockNumberKey(number))
+	return err == nil && has
+}
+
+// FirstBlockNumber returns the number of the first block of the backup, in its segments
+// or in its block store.
+func (b *BackupManager) FirstBlockNumber() (uint64, error) {
+	b.segmentLock.RLock()
+	if len(b.segments) > 0 {
+		first := b.segments[0].FirstBlock
+		b.segmentLock.RUnlock()
+		return first, nil
+	}
+	b.segmentLock.RUnlock()
+
+	b.blkBackupLock.Lock()
+	defer b.blkBackupLock.Unlock()
+
+	if b.hasBlocks == false {
+		return 0, ErrBlockNotFound
+	}
+	return b.firstBlock, nil
+}
+
+// GetBlockByNumber returns the last block with the given number that was backed up, from
+// the block store or else from the segment that contains it.
+func (b *BackupManager) GetBlockByNumber(number uint64) (*types.Block, error) {
+	if b.HasBlockNumber(number) == false {
+		var found *types.Block
+		err := b.forEachSegmentBlock(number, number, func(block *types.Block) error {
+			found = block
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return found, nil
+	}
+	hash, err := b.GetBlockHash(number)
+	if err != nil {
+		return nil, err
+	}
+	return b.GetBlock(hash)
+}
+
+// LastBlockNumber returns the number of the last block of the run of consecutive blocks
+// in the backup that starts at first, through its segments and then its block store.
+// Blocks are backed up in order as they are processed, so the end of the run in the
+// block store is found by a binary search; a gap within the run is reported by
+// VerifyChain.
+func (b *BackupManager) LastBlockNumber(first uint64) (uint64, error) {
+	number := first
+	for segment := b.segmentOf(number); segment != nil; segment = b.segmentOf(number) {
+		number = segment.LastBlock + 1
+	}
+	if b.HasBlockNumber(number) == false {
+		if number == first {
+			return 0, ErrBlockNotFound
+		}
+		return number - 1, nil
+	}
+	return b.lastStoredBlockNumber(number)
+}
+
+func (b *BackupManager) lastStoredBlockNumber(first uint64) (uint64, error) {
+	if b.HasBlockNumber(first) == false {
+		return 0, ErrBlockNotFound
+	}
+
+	// Find a missing block by doubling the step, then search between the two
+	present, step := first, uint64(1)
+	for b.HasBlockNumber(present + step) {
+		present += step
+		step *= 2
+	}
+	missing := present + step
+	for missing-present > 1 {
+		mid := present + (missing-present)/2
+		if b.HasBlockNumber(mid) {
+			present = mid
+		} else {
+			missing = mid
+		}
+	}
+	return present, nil
+}
+
+// VerifyBlock returns the block in the block store with the given number, after checking
+// that it is stored under its own hash and that its transactions match its header.
+func (b *BackupManager) VerifyBlock(number uint64) (*types.Block, error) {
+	if b.HasBlockNumber(number) == false {
+		return nil, ErrBlockNotFound
+	}
+	hash, err := b.GetBlockHash(number)
+	if err != nil {
+		return nil, err
+	}
+	block, err := b.GetBlock(hash)
+	if err != nil {
+		return nil, fmt.Errorf("block %d %s: %v", number, hash, err)
+	}
+	if block.NumberU64() != number {
+		return nil, fmt.Errorf("block %s is stored as block %d, but its number is %d", hash, number, block.NumberU64())
+	}
+	if block.Hash() != hash {
+		return nil, fmt.Errorf("block %d is stored as %s, but its hash is %s", number, hash, block.Hash())
+	}
+	if err := verifyTransactions(block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func verifyTransactions(block *types.Block) error {
+	if txHash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); txHash != block.TxHash() {
+		return fmt.Errorf("block %d %s: transactions hash is %s, the header has %s", block.NumberU64(), block.Hash(), txHash, block.TxHash())
+	}
+	return nil
+}
+
+// errStopIteration stops reading a segment once the blocks needed are read.
+var errStopIteration = errors.New("stop iteration")
+
+// forEachSegmentBlock calls fn with the blocks from first to last of the segment that
+// contains first, and stops at the first error returned by fn.
+func (b *BackupManager) forEachSegmentBlock(first uint64, last uint64, fn func(block *types.Block) error) error {
+	segment := b.segmentOf(first)
+	if segment == nil {
+		return ErrBlockNotFound
+	}
+	if last > segment.LastBlock {
+		last = segment.LastBlock
+	}
+	next := first
+	err := readSegmentBlocks(filepath.Join(b.segmentDir, segment.SegmentFileName()), func(block *types.Block) error {
+		if block.NumberU64() < next {
+			return nil
+		}
+		if block.NumberU64() != next {
+			return fmt.Errorf("segment %s: block %d follows block %d", segment.Name(), block.NumberU64(), next-1)
+		}
+		if err := verifyTransactions(block); err != nil {
+			return err
+		}
+		if err := fn(block); err != nil {
+			return err
+		}
+		if next == last {
+			return errStopIteration
+		}
+		next++
+		return nil
+	})
+	if err == errStopIteration {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("segment %s: block %d: %w", segment.Name(), next, ErrBlockNotFound)
+}
+
+// ForEachBlock calls fn with the blocks of the backup from first to last in order, from
+// the block store or else from the segments, and stops at the first error returned by fn.
+func (b *BackupManager) ForEachBlock(first uint64, last uint64, fn func(block *types.Block) error) error {
+	for number := first; number <= last; {
+		if b.HasBlockNumber(number) == false {
+			if segment := b.segmentOf(number); segment != nil {
+				end := segment.LastBlock
+				if end > last {
+					end = last
+				}
+				if err := b.forEachSegmentBlock(number, end, fn); err != nil {
+					return err
+				}
+				number = end + 1
+				continue
+			}
+		}
+		block, err := b.VerifyBlock(number)
+		if err == ErrBlockNotFound {
+			return fmt.Errorf("block %d: %w", number, err)
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(block); err != nil {
+			return err
+		}
+		number++
+	}
+	return nil
+}
+
+// VerifyChain verifies the blocks in the backup from first to last and checks that each
+// of them is the child of the previous one. fn, if not nil, is called with every block
+// once it is verified.
+func (b *BackupManager) VerifyChain(first uint64, last uint64, fn func(block *types.Block)) error {
+	var parent common.Hash
+	return b.ForEachBlock(first, last, func(block *types.Block) error {
+		number := block.NumberU64()
+		if number > first && block.ParentHash() != parent {
+			return fmt.Errorf("block %d %s: parent is %s, but block %d is %s", number, block.Hash(), block.ParentHash(), number-1, parent)
+		}
+		parent = block.Hash()
+		if fn != nil {
+			fn(block)
+		}
+		return nil
+	})
+}
+
+// ForEachTransaction calls fn with every transaction in the backup, in no particular
+// order, and stops at the first error returned by fn.
+func (b *BackupManager) ForEachTransaction(fn func(tx *types.Transaction) error) error {
+	b.txBackupLock.Lock()
+	defer b.txBackupLock.Unlock()
+
+	if b.txndb == nil {
+		return nil
+	}
+	db := *b.txndb
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+
+	for it.Next() {
+		// Skip the keys of the address index
+		if len(it.Key()) != common.HashLength {
+			continue
+		}
+		tx := new(types.Transaction)
+		if err := rlp.DecodeBytes(it.Value(), tx); err != nil {
+			return fmt.Errorf("transaction %x: %v", it.Key(), err)
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
@@ -0,0 +1,328 @@
+package backupmanager
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/ethdb"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/rlp"
+)
+
+// pruneBatchSize is the number of blocks removed from the backup stores per write.
+const pruneBatchSize = 1000
+
+// start runs the rotation and the retention periodically, if the config enables them.
+func (b *BackupManager) start(config Config) {
+	b.config = config
+	if b.config.RotationInterval == 0 {
+		b.config.RotationInterval = DefaultRotationInterval
+	}
+	if b.config.SegmentBlocks > 0 && b.config.SigningKey == nil {
+		log.Warn("Backup rotation is disabled, no key to sign segments with")
+	}
+	if b.config.rotationEnabled() == false && b.config.retentionEnabled() == false && len(b.config.Sinks) == 0 {
+		return
+	}
+	b.shipped = make(map[Sink]map[string]bool)
+	for _, sink := range b.config.Sinks {
+		b.shipped[sink] = make(map[string]bool)
+	}
+	b.quit = make(chan struct{})
+
+	log.Info("Backup rotation started", "segmentBlocks", b.config.SegmentBlocks, "retentionBlocks", b.config.RetentionBlocks,
+		"retentionAge", b.config.RetentionAge, "sinks", len(b.config.Sinks))
+
+	b.wg.Add(1)
+	go b.loop()
+}
+
+func (b *BackupManager) stop() {
+	if b.quit == nil {
+		return
+	}
+	close(b.quit)
+	b.wg.Wait()
+	b.quit = nil
+}
+
+func (b *BackupManager) loop() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(b.config.RotationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.rotate()
+		case <-b.quit:
+			return
+		}
+	}
+}
+
+// rotate seals the blocks that are old enough into segments, ships the segments to the
+// sinks and removes the blocks outside of the retention from the backup stores. Errors
+// are logged once, and the next rotation tries again.
+func (b *BackupManager) rotate() {
+	err := b.sealSegments()
+	if err == nil {
+		err = b.shipSegments()
+	}
+	if err == nil {
+		err = b.applyRetention()
+	}
+	if err != nil {
+		if err.Error() != b.lastErr {
+			log.Warn("Backup rotation failed", "err", err)
+		}
+		b.lastErr = err.Error()
+		return
+	}
+	b.lastErr = ""
+}
+
+// sealSegments seals every range of SegmentBlocks blocks after the last segment that is
+// older than segmentConfirmations blocks.
+func (b *BackupManager) sealSegments() error {
+	if b.config.rotationEnabled() == false {
+		return nil
+	}
+	b.blkBackupLock.Lock()
+	first, head, hasBlocks := b.firstBlock, b.headBlock, b.hasBlocks
+	b.blkBackupLock.Unlock()
+	if hasBlocks == false {
+		return nil
+	}
+
+	next := first
+	if sealed, ok := b.lastSealedBlock(); ok {
+		next = sealed + 1
+	}
+	for next+b.config.SegmentBlocks-1+segmentConfirmations <= head {
+		select {
+		case <-b.quit:
+			return nil
+		default:
+		}
+		if _, err := b.sealSegment(next, next+b.config.SegmentBlocks-1); err != nil {
+			return err
+		}
+		next += b.config.SegmentBlocks
+	}
+	return nil
+}
+
+// shipSegments puts the segments that a sink does not have yet into it.
+func (b *BackupManager) shipSegments() error {
+	for _, sink := range b.config.Sinks {
+		for _, segment := range b.Segments() {
+			if b.shipped[sink][segment.Name()] {
+				continue
+			}
+			has, err := sink.Has(segment)
+			if err != nil {
+				return err
+			}
+			if has == false {
+				fh, err := os.Open(filepath.Join(b.segmentDir, segment.SegmentFileName()))
+				if err != nil {
+					return err
+				}
+				err = sink.Put(segment, fh)
+				fh.Close()
+				if err != nil {
+					return err
+				}
+				log.Info("Shipped backup segment", "sink", sink.Name(), "first", segment.FirstBlock, "last", segment.LastBlock)
+			}
+			b.shipped[sink][segment.Name()] = true
+		}
+	}
+	return nil
+}
+
+// applyRetention removes the blocks outside of the retention from the backup stores,
+// along with their transactions. When the rotation is enabled, only blocks that are
+// sealed into a segment are removed.
+func (b *BackupManager) applyRetention() error {
+	if b.config.retentionEnabled() == false {
+		return nil
+	}
+	b.blkBackupLock.Lock()
+	first, head, hasBlocks := b.firstBlock, b.headBlock, b.hasBlocks
+	b.blkBackupLock.Unlock()
+	if hasBlocks == false {
+		return nil
+	}
+
+	limit := head
+	if b.config.rotationEnabled() {
+		sealed, ok := b.lastSealedBlock()
+		if ok == false {
+			return nil
+		}
+		if sealed < limit {
+			limit = sealed
+		}
+	}
+	cutoff := uint64(time.Now().Add(-b.config.RetentionAge).Unix())
+
+	number, pruneFrom := first, first
+	for number <= limit {
+		block, err := b.VerifyBlock(number)
+		if err != nil && err != ErrBlockNotFound {
+			return err
+		}
+		if err == nil {
+			expired := b.config.RetentionBlocks > 0 && number+b.config.RetentionBlocks <= head
+			if b.config.RetentionAge > 0 && block.Time() < cutoff {
+				expired = true
+			}
+			if expired == false {
+				break
+			}
+		}
+		number++
+		if number-pruneFrom == pruneBatchSize {
+			if err := b.pruneBlocks(pruneFrom, number-1); err != nil {
+				return err
+			}
+			pruneFrom = number
+		}
+	}
+	if number > pruneFrom {
+		if err := b.pruneBlocks(pruneFrom, number-1); err != nil {
+			return err
+		}
+	}
+	if number > first {
+		log.Debug("Applied backup retention", "pruned", number-first, "first", number)
+	}
+
+	// The transactions that were never mined are removed once they are older than the
+	// first block that is kept
+	var pendingCutoff uint64
+	if block, err := b.VerifyBlock(number); err == nil {
+		pendingCutoff = block.Time()
+	} else if b.config.RetentionAge > 0 {
+		pendingCutoff = cutoff
+	}
+	if pendingCutoff > 0 {
+		return b.prunePendingTransactions(pendingCutoff)
+	}
+	return nil
+}
+
+// pruneBlocks removes the blocks from first to last and their transactions from the
+// backup stores, and moves the first block of the block store past them.
+func (b *BackupManager) pruneBlocks(first uint64, last uint64) error {
+	b.blkBackupLock.Lock()
+	defer b.blkBackupLock.Unlock()
+	b.txBackupLock.Lock()
+	defer b.txBackupLock.Unlock()
+
+	blkdb, txndb := *b.blockdb, *b.txndb
+	blkBatch, txnBatch := blkdb.NewBatch(), txndb.NewBatch()
+	for number := first; number <= last; number++ {
+		numberKey := blockNumberKey(number)
+		hashBytes, err := blkdb.Get(numberKey)
+		if err != nil {
+			continue
+		}
+		if blockBytes, err := blkdb.Get(hashBytes); err == nil {
+			if block, err := types.DecodeBlockFromRLP(blockBytes); err == nil {
+				for _, tx := range block.Transactions() {
+					deleteTransaction(txndb, txnBatch, tx)
+				}
+			}
+		}
+		blkBatch.Delete(hashBytes)
+		blkBatch.Delete(numberKey)
+	}
+	firstBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(firstBytes, last+1)
+	blkBatch.Put(firstBlockKey, firstBytes)
+
+	if err := txnBatch.Write(); err != nil {
+		return err
+	}
+	if err := blkBatch.Write(); err != nil {
+		return err
+	}
+	b.firstBlock = last + 1
+	if b.firstBlock > b.headBlock {
+		b.hasBlocks = false
+	}
+	return nil
+}
+
+// prunePendingTransactions removes the transactions that were first backed up before
+// cutoff and are not in a backed up block, along with their keys in the address index.
+func (b *BackupManager) prunePendingTransactions(cutoff uint64) error {
+	b.txBackupLock.Lock()
+	defer b.txBackupLock.Unlock()
+
+	txndb := *b.txndb
+	it := txndb.NewIterator(txPendingPrefix, nil)
+	defer it.Release()
+
+	batch := txndb.NewBatch()
+	pruned := 0
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(txPendingPrefix)+8+common.HashLength {
+			continue
+		}
+		if binary.BigEndian.Uint64(key[len(txPendingPrefix):]) >= cutoff {
+			break
+		}
+		hash := common.BytesToHash(key[len(txPendingPrefix)+8:])
+		batch.Delete(common.CopyBytes(key))
+		if txBytes, err := txndb.Get(hash.Bytes()); err == nil {
+			tx := new(types.Transaction)
+			if err := rlp.DecodeBytes(txBytes, tx); err == nil {
+				deleteTransaction(txndb, batch, tx)
+			}
+		}
+		pruned++
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	if pruned > 0 {
+		log.Debug("Pruned backed up transactions that were not mined", "pruned", pruned)
+	}
+	return nil
+}
+
+// deleteTransaction adds the removal of a transaction and of its keys in the address
+// and nonce indexes to the batch.
+func deleteTransaction(txndb ethdb.Database, batch ethdb.Batch, tx *types.Transaction) {
+	hash := tx.Hash()
+	batch.Delete(hash.Bytes())
+	if firstSeen, ok := readFirstSeen(txndb, hash); ok {
+		for _, address := range transactionAddresses(tx) {
+			batch.Delete(addressIndexKey(address, firstSeen, tx))
+		}
+		batch.Delete(txPendingKey(firstSeen, hash))
+	}
+	if from, ok := transactionSender(tx); ok {
+		batch.Delete(txNonceKey(from, tx.Nonce(), hash))
+	}
+	batch.Delete(txFirstSeenKey(hash))
+}
@@ -0,0 +1,475 @@
+package backupmanager
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/crypto/hashingalgorithm"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/rlp"
+)
+
+const (
+	segmentDirName         = "backupsegments"
+	segmentFileSuffix      = ".rlp"
+	segmentManifestSuffix  = ".json"
+	segmentManifestVersion = 1
+
+	// segmentVerifiedFileName is the name of the file that caches the verification of the
+	// segment files. It does not end in segmentManifestSuffix.
+	segmentVerifiedFileName = "segments.verified"
+)
+
+var ErrSegmentContentMismatch = errors.New("segment content does not match its manifest")
+var ErrSegmentInvalidSignature = errors.New("segment manifest signature is invalid")
+var ErrSegmentUntrustedSigner = errors.New("segment is not signed by a trusted signer")
+
+// SegmentManifest describes a segment, an immutable file with a range of consecutive
+// blocks that were sealed out of the backup stores. The segment file is a stream of RLP
+// encoded blocks, in the format of the export command, so it can also be imported with
+// the import command. The manifest is signed by the node that sealed the segment.
+type SegmentManifest struct {
+	Version     int            `json:"version"`
+	FirstBlock  uint64         `json:"firstBlock"`
+	LastBlock   uint64         `json:"lastBlock"`
+	FirstHash   common.Hash    `json:"firstHash"`
+	LastHash    common.Hash    `json:"lastHash"`
+	Size        uint64         `json:"size"`
+	ContentHash common.Hash    `json:"contentHash"`
+	Created     uint64         `json:"created"`
+	Signer      common.Address `json:"signer"`
+	PublicKey   hexutil.Bytes  `json:"publicKey"`
+	Signature   hexutil.Bytes  `json:"signature"`
+}
+
+// Name returns the name of the segment, from which the names of its files are derived.
+// Names sort in the order of the blocks.
+func (m *SegmentManifest) Name() string {
+	return fmt.Sprintf("%012d-%012d", m.FirstBlock, m.LastBlock)
+}
+
+// SegmentFileName returns the name of the file with the blocks of the segment.
+func (m *SegmentManifest) SegmentFileName() string {
+	return m.Name() + segmentFileSuffix
+}
+
+// ManifestFileName returns the name of the file with the manifest of the segment.
+func (m *SegmentManifest) ManifestFileName() string {
+	return m.Name() + segmentManifestSuffix
+}
+
+// Contains returns whether the block with the given number is in the segment.
+func (m *SegmentManifest) Contains(number uint64) bool {
+	return number >= m.FirstBlock && number <= m.LastBlock
+}
+
+// signingHash returns the hash of the fields of the manifest that are signed.
+func (m *SegmentManifest) signingHash() []byte {
+	enc, _ := rlp.EncodeToBytes([]interface{}{
+		uint64(m.Version),
+		m.FirstBlock,
+		m.LastBlock,
+		m.FirstHash,
+		m.LastHash,
+		m.Size,
+		m.ContentHash,
+		m.Created,
+	})
+	return crypto.Keccak256(enc)
+}
+
+func (m *SegmentManifest) sign(key *signaturealgorithm.PrivateKey) error {
+	signer, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+	sig, err := cryptobase.SigAlg.Sign(m.signingHash(), key)
+	if err != nil {
+		return err
+	}
+	m.Signer = signer
+	m.PublicKey = common.CopyBytes(key.PublicKey.PubData)
+	m.Signature = sig
+	return nil
+}
+
+// VerifySignature checks that the manifest is signed by its public key, and that the
+// public key is the one of its signer. Whether the signer is trusted is up to the caller.
+func (m *SegmentManifest) VerifySignature() error {
+	if len(m.PublicKey) == 0 || len(m.Signature) == 0 {
+		return ErrSegmentInvalidSignature
+	}
+	signer, err := cryptobase.SigAlg.PublicKeyToAddress(&signaturealgorithm.PublicKey{PubData: m.PublicKey})
+	if err != nil || signer != m.Signer {
+		return ErrSegmentInvalidSignature
+	}
+	if cryptobase.SigAlg.Verify(m.PublicKey, m.signingHash(), m.Signature) == false {
+		return ErrSegmentInvalidSignature
+	}
+	return nil
+}
+
+// VerifyContent checks that the segment read from r has the size and the content hash
+// of the manifest.
+func (m *SegmentManifest) VerifyContent(r io.Reader) error {
+	hasher := hashingalgorithm.NewHashState()
+	size, err := io.Copy(hasher, r)
+	if err != nil {
+		return err
+	}
+	if uint64(size) != m.Size || common.BytesToHash(hasher.Sum(nil)) != m.ContentHash {
+		return fmt.Errorf("%w: segment %s", ErrSegmentContentMismatch, m.Name())
+	}
+	return nil
+}
+
+// Verify checks that the manifest is signed by one of the trusted signers, and the
+// content of the segment file in dir.
+func (m *SegmentManifest) Verify(dir string, trustedSigners []common.Address) error {
+	if err := m.verifySigner(trustedSigners); err != nil {
+		return err
+	}
+	return m.verifyFile(dir)
+}
+
+// verifyFile checks the content of the segment file in dir.
+func (m *SegmentManifest) verifyFile(dir string) error {
+	fh, err := os.Open(filepath.Join(dir, m.SegmentFileName()))
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	return m.VerifyContent(fh)
+}
+
+// verifySigner checks that the manifest is signed by one of the trusted signers.
+func (m *SegmentManifest) verifySigner(trustedSigners []common.Address) error {
+	trusted := false
+	for _, signer := range trustedSigners {
+		if signer == m.Signer {
+			trusted = true
+			break
+		}
+	}
+	if trusted == false {
+		return fmt.Errorf("segment %s: %w: %s", m.Name(), ErrSegmentUntrustedSigner, m.Signer.Hex())
+	}
+	if err := m.VerifySignature(); err != nil {
+		return fmt.Errorf("segment %s: %w", m.Name(), err)
+	}
+	return nil
+}
+
+// segmentVerification is the cached verification of the content of a segment file. It
+// holds as long as the file keeps its size and modification time.
+type segmentVerification struct {
+	ContentHash common.Hash `json:"contentHash"`
+	Size        uint64      `json:"size"`
+	ModTime     int64       `json:"modTime"`
+}
+
+func readSegmentVerifications(dir string) map[string]segmentVerification {
+	verified := make(map[string]segmentVerification)
+	data, err := os.ReadFile(filepath.Join(dir, segmentVerifiedFileName))
+	if err != nil {
+		return verified
+	}
+	if err := json.Unmarshal(data, &verified); err != nil {
+		log.Warn("Ignoring the cached verification of the backup segments", "dir", dir, "err", err)
+		return make(map[string]segmentVerification)
+	}
+	return verified
+}
+
+func writeSegmentVerifications(dir string, verified map[string]segmentVerification) error {
+	return writeFileAtomic(filepath.Join(dir, segmentVerifiedFileName), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(verified)
+	})
+}
+
+// newSegmentVerification returns the verification of a segment file whose content
+// matches the manifest.
+func newSegmentVerification(dir string, m *SegmentManifest) (segmentVerification, error) {
+	info, err := os.Stat(filepath.Join(dir, m.SegmentFileName()))
+	if err != nil {
+		return segmentVerification{}, err
+	}
+	return segmentVerification{ContentHash: m.ContentHash, Size: uint64(info.Size()), ModTime: info.ModTime().UnixNano()}, nil
+}
+
+// WriteTo writes the manifest as indented JSON.
+func (m *SegmentManifest) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+func readManifest(path string) (*SegmentManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m SegmentManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("segment manifest %s: %v", path, err)
+	}
+	if m.Version != segmentManifestVersion {
+		return nil, fmt.Errorf("segment manifest %s: unsupported version %d", path, m.Version)
+	}
+	return &m, nil
+}
+
+// writeFileAtomic writes a file through a temporary file that is renamed once it is
+// complete, so that a file is never seen partially written.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	tmp := path + ".tmp"
+	fh, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	buffered := bufio.NewWriter(fh)
+	err = write(buffered)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err == nil {
+		err = fh.Sync()
+	}
+	if closeErr := fh.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadSegments reads the manifests of the segments in dir and verifies the segments,
+// which have to be signed by one of the trusted signers. The content of a segment file
+// is only hashed if it changed since it was last verified; if writeCache is set, the
+// verifications are cached in dir. The segments are returned in the order of their
+// blocks, and must be consecutive.
+func loadSegments(dir string, trustedSigners []common.Address, writeCache bool) ([]*SegmentManifest, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	verified := readSegmentVerifications(dir)
+	changed := false
+	var segments []*SegmentManifest
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), segmentManifestSuffix) == false {
+			continue
+		}
+		m, err := readManifest(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if m.ManifestFileName() != entry.Name() {
+			return nil, fmt.Errorf("segment manifest %s is for blocks %d to %d", entry.Name(), m.FirstBlock, m.LastBlock)
+		}
+		if err := m.verifySigner(trustedSigners); err != nil {
+			return nil, err
+		}
+		cached, ok := verified[m.Name()]
+		current, err := newSegmentVerification(dir, m)
+		if err != nil {
+			return nil, err
+		}
+		if ok == false || cached != current || current.Size != m.Size {
+			if err := m.verifyFile(dir); err != nil {
+				return nil, err
+			}
+			verified[m.Name()] = current
+			changed = true
+		}
+		segments = append(segments, m)
+	}
+	if writeCache && changed {
+		if err := writeSegmentVerifications(dir, verified); err != nil {
+			log.Warn("Failed to cache the verification of the backup segments", "dir", dir, "err", err)
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].FirstBlock < segments[j].FirstBlock
+	})
+	for i := 1; i < len(segments); i++ {
+		if segments[i].FirstBlock != segments[i-1].LastBlock+1 {
+			return nil, fmt.Errorf("segment %s does not follow segment %s", segments[i].Name(), segments[i-1].Name())
+		}
+	}
+	log.Debug("Loaded backup segments", "dir", dir, "segments", len(segments))
+	return segments, nil
+}
+
+// readSegmentBlocks calls fn with the blocks of a segment file in order, and stops at the
+// first error returned by fn.
+func readSegmentBlocks(path string, fn func(block *types.Block) error) error {
+	fh, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	stream := rlp.NewStream(bufio.NewReader(fh), 0)
+	for {
+		block := new(types.Block)
+		if err := stream.Decode(block); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("segment %s: %v", filepath.Base(path), err)
+		}
+		if err := fn(block); err != nil {
+			return err
+		}
+	}
+}
+
+// sealSegment writes the blocks from first to last into a new segment, signs its
+// manifest and adds it to the segments of the backup.
+func (b *BackupManager) sealSegment(first uint64, last uint64) (*SegmentManifest, error) {
+	if err := os.MkdirAll(b.segmentDir, 0755); err != nil {
+		return nil, err
+	}
+	m := &SegmentManifest{
+		Version:    segmentManifestVersion,
+		FirstBlock: first,
+		LastBlock:  last,
+		Created:    uint64(time.Now().Unix()),
+	}
+
+	hasher := hashingalgorithm.NewHashState()
+	var parent common.Hash
+	err := writeFileAtomic(filepath.Join(b.segmentDir, m.SegmentFileName()), func(w io.Writer) error {
+		counter := &countingWriter{w: io.MultiWriter(w, hasher)}
+		for number := first; number <= last; number++ {
+			block, err := b.VerifyBlock(number)
+			if err != nil {
+				return err
+			}
+			if number > first && block.ParentHash() != parent {
+				return fmt.Errorf("block %d %s: parent is %s, but block %d is %s", number, block.Hash(), block.ParentHash(), number-1, parent)
+			}
+			parent = block.Hash()
+			if number == first {
+				m.FirstHash = block.Hash()
+			}
+			m.LastHash = block.Hash()
+			if err := rlp.Encode(counter, block); err != nil {
+				return err
+			}
+		}
+		m.Size = counter.n
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.ContentHash = common.BytesToHash(hasher.Sum(nil))
+	if err := m.sign(b.config.SigningKey); err != nil {
+		return nil, err
+	}
+	err = writeFileAtomic(filepath.Join(b.segmentDir, m.ManifestFileName()), func(w io.Writer) error {
+		_, err := m.WriteTo(w)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The segment was hashed as it was written, so it is not hashed again when the
+	// backup is opened
+	if current, err := newSegmentVerification(b.segmentDir, m); err == nil {
+		verified := readSegmentVerifications(b.segmentDir)
+		verified[m.Name()] = current
+		if err := writeSegmentVerifications(b.segmentDir, verified); err != nil {
+			log.Warn("Failed to cache the verification of the backup segment", "segment", m.Name(), "err", err)
+		}
+	}
+
+	b.segmentLock.Lock()
+	b.segments = append(b.segments, m)
+	b.segmentLock.Unlock()
+
+	log.Info("Sealed backup segment", "first", first, "last", last, "size", common.StorageSize(m.Size))
+	return m, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n uint64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += uint64(n)
+	return n, err
+}
+
+// VerifySegments verifies the content of every segment of the backup, whether or not its
+// verification is cached. The signers were checked when the backup was opened.
+func (b *BackupManager) VerifySegments() error {
+	for _, segment := range b.Segments() {
+		if err := segment.verifyFile(b.segmentDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Segments returns the manifests of the segments of the backup, in the order of their blocks.
+func (b *BackupManager) Segments() []*SegmentManifest {
+	b.segmentLock.RLock()
+	defer b.segmentLock.RUnlock()
+
+	segments := make([]*SegmentManifest, len(b.segments))
+	copy(segments, b.segments)
+	return segments
+}
+
+// segmentOf returns the segment that contains the block with the given number, or nil.
+func (b *BackupManager) segmentOf(number uint64) *SegmentManifest {
+	b.segmentLock.RLock()
+	defer b.segmentLock.RUnlock()
+
+	i := sort.Search(len(b.segments), func(i int) bool {
+		return b.segments[i].LastBlock >= number
+	})
+	if i < len(b.segments) && b.segments[i].Contains(number) {
+		return b.segments[i]
+	}
+	return nil
+}
+
+// lastSealedBlock returns the number of the last block sealed into a segment, and
+// whether there is any segment.
+func (b *BackupManager) lastSealedBlock() (uint64, bool) {
+	b.segmentLock.RLock()
+	defer b.segmentLock.RUnlock()
+
+	if len(b.segments) == 0 {
+		return 0, false
+	}
+	return b.segments[len(b.segments)-1].LastBlock, true
+}
@@ -0,0 +1,80 @@
+package backupmanager
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Sink receives the sealed segments of a backup, to keep them somewhere else than the
+// node. A segment that fails to be shipped is shipped again at the next rotation.
+type Sink interface {
+	// Name returns a name of the sink for the logs.
+	Name() string
+
+	// Has returns whether the sink already has the segment.
+	Has(manifest *SegmentManifest) (bool, error)
+
+	// Put stores a segment, read from segment, along with its manifest.
+	Put(manifest *SegmentManifest, segment io.Reader) error
+}
+
+// LocalSink is a sink that copies segments into a local directory, such as a mounted
+// network or removable drive. The directory has the same layout as the segment directory
+// of a backup.
+type LocalSink struct {
+	dir string
+}
+
+// NewLocalSink creates a sink that copies segments into dir, which is created if needed.
+func NewLocalSink(dir string) (*LocalSink, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &LocalSink{dir: dir}, nil
+}
+
+func (s *LocalSink) Name() string {
+	return s.dir
+}
+
+func (s *LocalSink) Has(manifest *SegmentManifest) (bool, error) {
+	if _, err := os.Stat(filepath.Join(s.dir, manifest.ManifestFileName())); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	info, err := os.Stat(filepath.Join(s.dir, manifest.SegmentFileName()))
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return uint64(info.Size()) == manifest.Size, nil
+}
+
+// Put copies the segment, then writes its manifest, so that a manifest in the directory
+// always describes a complete segment.
+func (s *LocalSink) Put(manifest *SegmentManifest, segment io.Reader) error {
+	err := writeFileAtomic(filepath.Join(s.dir, manifest.SegmentFileName()), func(w io.Writer) error {
+		_, err := io.Copy(w, segment)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fh, err := os.Open(filepath.Join(s.dir, manifest.SegmentFileName()))
+	if err != nil {
+		return err
+	}
+	err = manifest.VerifyContent(fh)
+	fh.Close()
+	if err != nil {
+		os.Remove(filepath.Join(s.dir, manifest.SegmentFileName()))
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.dir, manifest.ManifestFileName()), func(w io.Writer) error {
+		_, err := manifest.WriteTo(w)
+		return err
+	})
+}
@@ -30,6 +30,11 @@ import (
 )
 
 var (
+	argon2idFlag = cli.BoolFlag{
+		Name:  "argon2id",
+		Usage: "Encrypt the upgraded key files with the Argon2id KDF instead of scrypt",
+	}
+
 	walletCommand = cli.Command{
 		Name:      "wallet",
 		Usage:     "Manage Ethereum presale wallets",
@@ -153,6 +158,37 @@ For non-interactive use the password can be specified with the --password flag:
 
 Since only one password can be given, only format update can be performed,
 changing your password is only possible interactively.
+`,
+			},
+			{
+				Name:      "upgrade",
+				Usage:     "Upgrade the key files of existing accounts to the latest format",
+				Action:    utils.MigrateFlags(accountUpgrade),
+				ArgsUsage: "<address>",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+					argon2idFlag,
+				},
+				Description: `
+    dp account upgrade [options] <address>
+
+Rewrites the key files of the accounts in place in the version 4 format, which
+records the signature algorithm of the key and encrypts it with an AEAD cipher.
+You are prompted for the password of each account, the upgraded key file keeps
+the same password.
+
+New accounts are created with version 3 key files, which every release can read,
+and updating the password of an account writes a version 3 key file again. The
+version 4 format is only used once the key file is upgraded.
+
+The key file is only replaced after the key decrypted from the upgraded file is
+checked to be the same as the one of the previous file.
+
+With the --argon2id flag the key files are encrypted with the Argon2id KDF instead
+of scrypt, which also re-encrypts key files that are already of version 4.
 `,
 			},
 			{
@@ -320,6 +356,34 @@ func accountUpdate(ctx *cli.Context) error {
 	return nil
 }
 
+// accountUpgrade rewrites the key files of accounts of a previous format in the
+// latest format.
+func accountUpgrade(ctx *cli.Context) error {
+	if len(ctx.Args()) == 0 {
+		utils.Fatalf("No accounts specified to upgrade")
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	passwords := utils.MakePasswordList(ctx)
+	for i, addr := range ctx.Args() {
+		account, password, err := unlockAccount(ks, addr, i, passwords)
+		if err != nil {
+			utils.Fatalf("Could not unlock account to upgrade the key file: %v", err)
+		}
+		err = ks.Upgrade(account, password, ctx.Bool(argon2idFlag.Name))
+		if err == keystore.ErrKeyUpToDate {
+			fmt.Printf("Key file of account {%x} is already of the latest version\n", account.Address)
+			continue
+		}
+		if err != nil {
+			utils.Fatalf("Could not upgrade the key file: %v", err)
+		}
+		fmt.Printf("Upgraded key file of account {%x}\n", account.Address)
+	}
+	return nil
+}
+
 func importWallet(ctx *cli.Context) error {
 	keyfile := ctx.Args().First()
 	if len(keyfile) == 0 {
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/DogeProtocol/dp/backupmanager"
+	"github.com/DogeProtocol/dp/cmd/utils"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	backupDirFlag = cli.StringFlag{
+		Name:  "backup.dir",
+		Usage: "Directory of the block and transaction backups (default = the instance directory in the data directory)",
+	}
+	backupTxPoolFlag = cli.BoolFlag{
+		Name:  "backup.txpool",
+		Usage: "Write the backed up transactions that are not mined to the transaction journal, to re-inject them into the transaction pool",
+	}
+
+	backupCommand = cli.Command{
+		Name:      "backup",
+		Usage:     "Inspect, verify and restore the block and transaction backups",
+		ArgsUsage: "",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+Nodes started with backups enabled back up every processed block and every transaction
+added to the transaction pool, and can seal older blocks into signed segments. These
+commands read the backups, and rebuild a chain database from them. --backup.dir can also
+be a directory that segments were shipped to. Only the segments signed by the node key of
+the data directory, or by one of --backup.trustedsigners, are read.`,
+		Subcommands: []cli.Command{
+			backupListCmd,
+			backupVerifyCmd,
+			backupRestoreCmd,
+		},
+	}
+	backupListCmd = cli.Command{
+		Action:    utils.MigrateFlags(backupList),
+		Name:      "list",
+		Usage:     "Show the range of backed up blocks, and list the blocks in a range",
+		ArgsUsage: "[<first> [<last>]]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			backupDirFlag,
+			utils.BackupTrustedSignersFlag,
+		},
+		Description: `
+The list command shows the range of consecutive blocks in the backup, the number of
+backed up transactions and the segments with their signer. If a range is given, the
+blocks in it are listed with their hash, number of transactions and time.`,
+	}
+	backupVerifyCmd = cli.Command{
+		Action:    utils.MigrateFlags(backupVerify),
+		Name:      "verify",
+		Usage:     "Verify the backed up blocks",
+		ArgsUsage: "[<first> [<last>]]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			backupDirFlag,
+			utils.BackupTrustedSignersFlag,
+		},
+		Description: `
+The verify command walks the backed up blocks by number, from first (default the first
+backed up block) to last (default the last backed up block), and checks that every block
+is stored under its hash, that its transactions match its header and that it is the
+child of the previous block. The signatures of the segments are verified when the backup
+is opened, and the content of every segment is hashed again.`,
+	}
+	backupRestoreCmd = cli.Command{
+		Action:    utils.MigrateFlags(backupRestore),
+		Name:      "restore",
+		Usage:     "Rebuild the chain database from the backup",
+		ArgsUsage: "[<last>]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+			utils.GCModeFlag,
+			utils.SnapshotFlag,
+			utils.CacheDatabaseFlag,
+			utils.CacheGCFlag,
+			utils.TxPoolJournalFlag,
+			backupDirFlag,
+			backupTxPoolFlag,
+			utils.BackupTrustedSignersFlag,
+		},
+		Description: `
+The restore command re-imports the backed up blocks, up to last (default the last backed
+up block), into the chain database of the data directory, which is usually a fresh one
+initialized with the genesis of the backed up chain by the init command. Blocks that are already present are skipped, so that an interrupted restore can be run
+again. Every block is executed and verified as it is imported.
+
+With --backup.txpool, the backed up transactions that can still be executed on top of the
+restored chain are appended to the transaction journal. The node adds them to its
+transaction pool as local transactions when it starts, unless --txpool.nolocals is set.`,
+	}
+)
+
+// openBackup opens the backup in the directory given by --backup.dir, or in the instance
+// directory of the node. The segments signed by the node key are trusted.
+func openBackup(ctx *cli.Context) *backupmanager.BackupManager {
+	stack, cfg := makeConfigNode(ctx)
+	backupDir := ctx.String(backupDirFlag.Name)
+	if backupDir == "" {
+		backupDir = stack.InstanceDir()
+	}
+	trustedSigners := utils.MakeBackupTrustedSigners(&cfg.Node, true)
+	stack.Close()
+
+	backup, err := backupmanager.OpenBackup(backupDir, trustedSigners)
+	if err != nil {
+		utils.Fatalf("Failed to open backup: %v", err)
+	}
+	return backup
+}
+
+// parseBackupRange parses the optional first and last block arguments. last defaults to
+// the last block of the backup.
+func parseBackupRange(ctx *cli.Context, backup *backupmanager.BackupManager) (uint64, uint64, error) {
+	if ctx.NArg() > 2 {
+		return 0, 0, fmt.Errorf("Max 2 arguments: %v", ctx.Command.ArgsUsage)
+	}
+	first, err := backup.FirstBlockNumber()
+	if err != nil {
+		return 0, 0, err
+	}
+	if ctx.NArg() >= 1 {
+		n, err := strconv.ParseUint(ctx.Args().Get(0), 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid first block: %v", err)
+		}
+		first = n
+	}
+	if ctx.NArg() >= 2 {
+		last, err := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid last block: %v", err)
+		}
+		if last < first {
+			return 0, 0, fmt.Errorf("last block %d is before first block %d", last, first)
+		}
+		return first, last, nil
+	}
+	last, err := backup.LastBlockNumber(first)
+	if err != nil {
+		return 0, 0, fmt.Errorf("block %d: %v", first, err)
+	}
+	return first, last, nil
+}
+
+func backupList(ctx *cli.Context) error {
+	backup := openBackup(ctx)
+	defer backup.Close()
+
+	if ctx.NArg() > 0 {
+		first, last, err := parseBackupRange(ctx, backup)
+		if err != nil {
+			return err
+		}
+		for number := first; number <= last; number++ {
+			block, err := backup.GetBlockByNumber(number)
+			if err != nil {
+				return fmt.Errorf("block %d: %v", number, err)
+			}
+			fmt.Printf("%d %s txs=%d time=%s\n", number, block.Hash().Hex(), len(block.Transactions()),
+				time.Unix(int64(block.Time()), 0).UTC().Format(time.RFC3339))
+		}
+		return nil
+	}
+
+	txs := 0
+	if err := backup.ForEachTransaction(func(tx *types.Transaction) error {
+		txs++
+		return nil
+	}); err != nil {
+		return err
+	}
+	first, err := backup.FirstBlockNumber()
+	if err == nil {
+		var last uint64
+		if last, err = backup.LastBlockNumber(first); err == nil {
+			fmt.Printf("Blocks:       %d - %d\n", first, last)
+			if block, err := backup.GetBlockByNumber(last); err == nil {
+				fmt.Printf("Last block:   %s\n", block.Hash().Hex())
+			}
+		}
+	}
+	switch {
+	case errors.Is(err, backupmanager.ErrBlockNotFound):
+		fmt.Println("Blocks:       none")
+	case err != nil:
+		return err
+	}
+	fmt.Printf("Transactions: %d\n", txs)
+
+	segments := backup.Segments()
+	fmt.Printf("Segments:     %d\n", len(segments))
+	for _, segment := range segments {
+		fmt.Printf("  %d - %d size=%s signer=%s created=%s\n", segment.FirstBlock, segment.LastBlock, common.StorageSize(segment.Size),
+			segment.Signer.Hex(), time.Unix(int64(segment.Created), 0).UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+func backupVerify(ctx *cli.Context) error {
+	backup := openBackup(ctx)
+	defer backup.Close()
+
+	first, last, err := parseBackupRange(ctx, backup)
+	if err != nil {
+		return err
+	}
+	log.Info("Verifying backup", "first", first, "last", last)
+
+	if err := backup.VerifySegments(); err != nil {
+		return fmt.Errorf("backup verification failed: %v", err)
+	}
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	err = backup.VerifyChain(first, last, func(block *types.Block) {
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying backup", "number", block.NumberU64(), "last", last, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("backup verification failed: %v", err)
+	}
+	log.Info("Verified backup", "first", first, "last", last, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func backupRestore(ctx *cli.Context) error {
+	if ctx.NArg() > 1 {
+		return fmt.Errorf("Max 1 argument: %v", ctx.Command.ArgsUsage)
+	}
+	backup := openBackup(ctx)
+	defer backup.Close()
+
+	stack, cfg := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+	defer chain.Stop()
+
+	first, err := backup.FirstBlockNumber()
+	if err != nil {
+		return err
+	}
+	var last uint64
+	if ctx.NArg() == 1 {
+		if last, err = strconv.ParseUint(ctx.Args().Get(0), 10, 64); err != nil {
+			return fmt.Errorf("invalid last block: %v", err)
+		}
+	} else if last, err = backup.LastBlockNumber(first); err != nil {
+		return err
+	}
+	// Resume after the blocks restored by a previous run, or continue a chain that
+	// ends right before the backup
+	head := chain.CurrentBlock().NumberU64()
+	if head+1 < first {
+		return fmt.Errorf("the backup starts at block %d, the chain must contain block %d but ends at block %d", first, first-1, head)
+	}
+	first = head + 1
+	if first <= last {
+		block, err := backup.GetBlockByNumber(first)
+		if err != nil {
+			return fmt.Errorf("block %d: %v", first, err)
+		}
+		if parent := chain.CurrentBlock().Hash(); block.ParentHash() != parent {
+			return fmt.Errorf("backup is of another chain: block %d is %s, block %d has parent %s", head, parent, first, block.ParentHash())
+		}
+	}
+
+	start := time.Now()
+	if first <= last {
+		if err := utils.RestoreChain(chain, backup, first, last); err != nil {
+			return err
+		}
+	}
+	restored := chain.CurrentBlock()
+	log.Info("Restored blockchain", "number", restored.NumberU64(), "hash", restored.Hash(), "elapsed", common.PrettyDuration(time.Since(start)))
+
+	if ctx.Bool(backupTxPoolFlag.Name) {
+		if cfg.Eth.TxPool.Journal == "" {
+			return fmt.Errorf("the transaction journal is disabled")
+		}
+		if _, err := utils.RestoreTransactions(chain, backup, stack.ResolvePath(cfg.Eth.TxPool.Journal)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -149,6 +149,11 @@ var (
 		configFileFlag,
 		utils.CatalystFlag,
 		utils.EnableBackupsFlag,
+		utils.BackupRetentionBlocksFlag,
+		utils.BackupRetentionAgeFlag,
+		utils.BackupSegmentBlocksFlag,
+		utils.BackupSinkDirFlag,
+		utils.BackupTrustedSignersFlag,
 		utils.RebroadcastCountFlag,
 		utils.ProfPortFlag,
 	}
@@ -216,6 +221,8 @@ func init() {
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
+		// See signercmd.go:
+		signerCommand,
 		// See consolecmd.go:
 		consoleCommand,
 		attachCommand,
@@ -229,6 +236,8 @@ func init() {
 		dumpConfigCommand,
 		// see dbcmd.go
 		dbCommand,
+		// See backupcmd.go
+		backupCommand,
 		// See cmd/utils/flags_legacy.go
 		utils.ShowDeprecated,
 		// See snapshot.go
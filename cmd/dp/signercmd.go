@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/DogeProtocol/dp/accounts/keystore"
+	"github.com/DogeProtocol/dp/cmd/utils"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/consensus/proofofstake"
+	"github.com/DogeProtocol/dp/core/rawdb"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/node"
+	"github.com/DogeProtocol/dp/rpc"
+	"github.com/DogeProtocol/dp/signer/core"
+	"github.com/DogeProtocol/dp/signer/rules"
+	"github.com/DogeProtocol/dp/signer/storage"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	signerSlashingDBFlag = cli.StringFlag{
+		Name:  "signer.slashingdb",
+		Usage: "Directory of the slashing protection database (default = inside the datadir)",
+	}
+	signerIPCPathFlag = cli.StringFlag{
+		Name:  "signer.ipcpath",
+		Usage: "Filename for the IPC socket/pipe of the signer within the datadir (explicit paths escape it)",
+		Value: "signer.ipc",
+	}
+	signerHTTPFlag = cli.StringFlag{
+		Name:  "signer.http",
+		Usage: "Listening address of the HTTP endpoint of the signer, e.g. 127.0.0.1:8550 (disabled by default)",
+	}
+	signerRulesFlag = cli.StringFlag{
+		Name:  "signer.rules",
+		Usage: "JavaScript file with the rules to approve signing requests automatically",
+	}
+	signerAuditLogFlag = cli.StringFlag{
+		Name:  "signer.auditlog",
+		Usage: "File to log all signing requests and responses to",
+	}
+
+	signerCommand = cli.Command{
+		Name:     "signer",
+		Usage:    "Run an external signer for validator consensus packets",
+		Action:   utils.MigrateFlags(runSigner),
+		Category: "ACCOUNT COMMANDS",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.KeyStoreDirFlag,
+			utils.LightKDFFlag,
+			utils.UnlockedAccountFlag,
+			utils.PasswordFileFlag,
+			signerSlashingDBFlag,
+			signerIPCPathFlag,
+			signerHTTPFlag,
+			signerRulesFlag,
+			signerAuditLogFlag,
+		},
+		Description: `
+    dp signer --unlock <validator> --signer.rules rules.js
+
+Runs a signer that holds the validator key outside of the node process. A node
+started with --signer <endpoint> sends the consensus packets of the validator
+to the signer, over IPC or HTTP, instead of signing them itself.
+
+Every packet is checked against a slashing protection database before it is
+signed, and a packet that conflicts with one already signed for the same parent
+hash and round is refused. The database must be kept along with the key; a
+signer started with an empty database could sign a conflicting packet.
+
+Signing requests are approved on the console, unless they are approved by the
+ApproveSignData function of the rules file, for example:
+
+    function ApproveSignData(req) {
+        if (req.content_type == "application/x-proofofstake-header") {
+            return "Approve"
+        }
+    }
+
+Only consensus packets are signed; the signer cannot be used to sign transactions.`,
+	}
+)
+
+// consensusSignerAPI only exposes the consensus signing of the signer API, so that the
+// validator key cannot be used for anything else.
+type consensusSignerAPI struct {
+	api core.ExternalAPI
+}
+
+func (s *consensusSignerAPI) SignConsensusData(ctx context.Context, addr common.MixedcaseAddress, data hexutil.Bytes, signContext *hexutil.Bytes) (hexutil.Bytes, error) {
+	return s.api.SignConsensusData(ctx, addr, data, signContext)
+}
+
+func (s *consensusSignerAPI) Version(ctx context.Context) (string, error) {
+	return s.api.Version(ctx)
+}
+
+func runSigner(ctx *cli.Context) error {
+	dataDir := ctx.GlobalString(utils.DataDirFlag.Name)
+	keystoreDir := filepath.Join(dataDir, "keystore")
+	if ctx.GlobalIsSet(utils.KeyStoreDirFlag.Name) {
+		keystoreDir = ctx.GlobalString(utils.KeyStoreDirFlag.Name)
+	}
+	am := core.StartClefAccountManager(keystoreDir, true, ctx.GlobalBool(utils.LightKDFFlag.Name), "")
+	defer am.Close()
+
+	slashingDBDir := ctx.GlobalString(signerSlashingDBFlag.Name)
+	if slashingDBDir == "" {
+		slashingDBDir = filepath.Join(dataDir, "signer", "slashingprotection")
+	}
+	db, err := rawdb.NewLevelDBDatabase(slashingDBDir, 16, 16, "signer/slashingprotection/", false)
+	if err != nil {
+		utils.Fatalf("Could not open slashing protection database: %v", err)
+	}
+	slashingProtection := proofofstake.NewSlashingProtectionDB(db)
+	defer slashingProtection.Close()
+
+	var ui core.UIClientAPI = core.NewCommandlineUI()
+	if rulesFile := ctx.GlobalString(signerRulesFlag.Name); rulesFile != "" {
+		ruleJS, err := ioutil.ReadFile(rulesFile)
+		if err != nil {
+			utils.Fatalf("Could not read rules file: %v", err)
+		}
+		ruleEngine, err := rules.NewRuleEvaluator(ui, storage.NewEphemeralStorage())
+		if err != nil {
+			utils.Fatalf("Could not create rule engine: %v", err)
+		}
+		if err = ruleEngine.Init(string(ruleJS)); err != nil {
+			utils.Fatalf("Could not load rules: %v", err)
+		}
+		ui = ruleEngine
+	}
+
+	// Transactions are not signed, so no transaction validator is needed
+	signerAPI := core.NewSignerAPI(am, 0, true, ui, nil, false, &storage.NoStorage{})
+	signerAPI.SetSlashingProtection(slashingProtection)
+
+	if unlock := ctx.GlobalString(utils.UnlockedAccountFlag.Name); unlock != "" {
+		ks := am.Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+		unlockAccount(ks, unlock, 0, utils.MakePasswordList(ctx))
+	}
+
+	var api core.ExternalAPI = signerAPI
+	if auditLog := ctx.GlobalString(signerAuditLogFlag.Name); auditLog != "" {
+		api, err = core.NewAuditLogger(auditLog, signerAPI)
+		if err != nil {
+			utils.Fatalf("Could not open audit log: %v", err)
+		}
+	}
+	apis := []rpc.API{
+		{
+			Namespace: "account",
+			Version:   "1.0",
+			Service:   &consensusSignerAPI{api: api},
+			Public:    true,
+		},
+	}
+
+	ipcPath := ctx.GlobalString(signerIPCPathFlag.Name)
+	if filepath.Base(ipcPath) == ipcPath {
+		ipcPath = filepath.Join(dataDir, ipcPath)
+	}
+	listener, _, err := rpc.StartIPCEndpoint(ipcPath, apis)
+	if err != nil {
+		utils.Fatalf("Could not start IPC endpoint: %v", err)
+	}
+	defer listener.Close()
+	extapiURL := ipcPath
+
+	if httpAddr := ctx.GlobalString(signerHTTPFlag.Name); httpAddr != "" {
+		srv := rpc.NewServer()
+		for _, api := range apis {
+			if err := srv.RegisterName(api.Namespace, api.Service); err != nil {
+				utils.Fatalf("Could not register API: %v", err)
+			}
+		}
+		httpListener, err := net.Listen("tcp", httpAddr)
+		if err != nil {
+			utils.Fatalf("Could not start HTTP endpoint: %v", err)
+		}
+		httpServer := &http.Server{Handler: node.NewHTTPHandlerStack(srv, []string{"localhost"}, []string{"localhost"})}
+		go httpServer.Serve(httpListener)
+		defer httpServer.Close()
+		extapiURL = fmt.Sprintf("http://%v", httpListener.Addr())
+	}
+
+	log.Info("Signer started", "ipc", ipcPath, "extapi", extapiURL, "slashingdb", slashingDBDir)
+	ui.OnSignerStartup(core.StartupInfo{
+		Info: map[string]interface{}{
+			"extapi_version": core.ExternalAPIVersion,
+			"extapi_ipc":     ipcPath,
+			"extapi_http":    ctx.GlobalString(signerHTTPFlag.Name),
+		},
+	})
+
+	abortChan := make(chan os.Signal, 1)
+	signal.Notify(abortChan, os.Interrupt, syscall.SIGTERM)
+	sig := <-abortChan
+	log.Info("Exiting signer", "signal", sig)
+
+	return nil
+}
@@ -223,6 +223,11 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			cli.HelpFlag,
 			utils.CatalystFlag,
 			utils.EnableBackupsFlag,
+			utils.BackupRetentionBlocksFlag,
+			utils.BackupRetentionAgeFlag,
+			utils.BackupSegmentBlocksFlag,
+			utils.BackupSinkDirFlag,
+			utils.BackupTrustedSignersFlag,
 			utils.RebroadcastCountFlag,
 			utils.ProfPortFlag,
 		},
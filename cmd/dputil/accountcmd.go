@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	balanceCommand = cli.Command{
+		Action:    withConfig(balance),
+		Name:      "balance",
+		Usage:     "Show the balance of an account",
+		ArgsUsage: "<address>",
+		Category:  "ACCOUNT COMMANDS",
+		Flags:     commandFlags(outputFlag),
+		Description: `
+Shows the balance of an account, from the node given with --rpcurl, or else from
+the public read API along with the nonce of the account.`,
+	}
+	sendCommand = cli.Command{
+		Action:    withConfig(sendTxn),
+		Name:      "send",
+		Usage:     "Send coins",
+		ArgsUsage: "<from address> <to address> <quantity>",
+		Category:  "ACCOUNT COMMANDS",
+		Flags:     commandFlags(outputFlag, passwordFileFlag),
+		Description: `
+Sends a quantity of coins from an account, whose key file is looked up in --keydir,
+to another.`,
+	}
+	txnCommand = cli.Command{
+		Action:      withConfig(getTxn),
+		Name:        "txn",
+		Usage:       "Show a transaction",
+		ArgsUsage:   "<hash>",
+		Category:    "ACCOUNT COMMANDS",
+		Flags:       commandFlags(outputFlag),
+		Description: `Shows a transaction, as JSON.`,
+	}
+)
+
+// AccountBalance is the output of the balance command.
+type AccountBalance struct {
+	Address string `json:"address"`
+	Coins   string `json:"coins"`
+	Wei     string `json:"wei"`
+	Nonce   string `json:"nonce,omitempty"`
+}
+
+func balance(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 1); err != nil {
+		return err
+	}
+	addr, err := addressArg(ctx, 0, "account")
+	if err != nil {
+		return err
+	}
+
+	result := &AccountBalance{Address: addr.Hex()}
+	if len(cfg.RPCURL) == 0 {
+		result.Coins, result.Wei, result.Nonce, err = requestGetBalance(addr.Hex())
+	} else {
+		result.Coins, result.Wei, err = getBalance(addr.Hex())
+	}
+	if err != nil {
+		return err
+	}
+
+	return printResult(result, func() {
+		if len(result.Nonce) == 0 {
+			fmt.Println("Address", result.Address, "coins", result.Coins, "wei", result.Wei)
+		} else {
+			fmt.Println("Address", result.Address, "coins", result.Coins, "wei", result.Wei, "nonce", result.Nonce)
+		}
+	})
+}
+
+func sendTxn(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 3); err != nil {
+		return err
+	}
+	from, err := addressArg(ctx, 0, "from")
+	if err != nil {
+		return err
+	}
+	to, err := addressArg(ctx, 1, "to")
+	if err != nil {
+		return err
+	}
+	quantity, err := amountArg(ctx, 2)
+	if err != nil {
+		return err
+	}
+	if err := requireRPC(); err != nil {
+		return err
+	}
+
+	key, _, err := unlockKey(ctx, from, "sender", passwordFileFlag)
+	if err != nil {
+		return err
+	}
+	hash, err := send(key, to.Hex(), quantity)
+	if err != nil {
+		return err
+	}
+	return printTransaction(fmt.Sprintf("Sent %s coins from %s to %s.", quantity, from.Hex(), to.Hex()), hash)
+}
+
+func getTxn(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 1); err != nil {
+		return err
+	}
+	if err := requireRPC(); err != nil {
+		return err
+	}
+
+	txnJson, err := GetTransaction(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	json, err := Prettify(txnJson)
+	if err != nil {
+		return err
+	}
+	fmt.Println(json)
+	return nil
+}
+
+func Prettify(str string) (string, error) {
+	var prettyJSON bytes.Buffer
+	if err := json.Indent(&prettyJSON, []byte(str), "", "    "); err != nil {
+		return "", err
+	}
+	return prettyJSON.String(), nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"unicode"
+
+	"github.com/DogeProtocol/dp/cmd/utils"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/naoina/toml"
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+var (
+	configFileFlag = cli.StringFlag{
+		Name:  "config",
+		Usage: "TOML configuration file",
+	}
+	rpcURLFlag = cli.StringFlag{
+		Name:   "rpcurl",
+		Usage:  "URL of the node to connect to",
+		EnvVar: "DP_RAW_URL",
+	}
+	keyDirFlag = cli.StringFlag{
+		Name:   "keydir",
+		Usage:  "Directory of the key files, looked up by address",
+		EnvVar: "DP_KEY_FILE_DIR",
+	}
+	keyFileFlag = cli.StringFlag{
+		Name:   "keyfile",
+		Usage:  "Key file to use, instead of looking it up in --keydir",
+		EnvVar: "DP_KEY_FILE",
+	}
+	genesisFlag = cli.StringFlag{
+		Name:   "genesis",
+		Usage:  "Genesis file of the chain of the node, for its fork schedule (default: mainnet)",
+		EnvVar: "DP_GENESIS_FILE",
+	}
+	outputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: `Output format, "text" or "json"`,
+	}
+
+	// connectionFlags are the flags of all commands that can also be set in the
+	// configuration file.
+	connectionFlags = []cli.Flag{
+		configFileFlag,
+		rpcURLFlag,
+		keyDirFlag,
+		keyFileFlag,
+		genesisFlag,
+	}
+)
+
+var errNoKeyDir = errors.New("no key file, set --keyfile or --keydir, or KeyFile or KeyDir in the configuration file")
+var errNoRPCURL = errors.New("no node to connect to, set --rpcurl, or RPCURL in the configuration file")
+
+// dputilConfig is the configuration of dputil. It is read from the file given with
+// --config, and the command line flags and their environment variables override it.
+type dputilConfig struct {
+	RPCURL  string
+	KeyDir  string
+	KeyFile string
+	Output  string
+
+	// GenesisFile is the genesis file of the chain of the node, whose fork schedule
+	// tells when the staking contract was upgraded. The mainnet schedule is used if it
+	// is not set.
+	GenesisFile string
+}
+
+// cfg is the configuration of the running command, loaded by loadConfig.
+var cfg = dputilConfig{
+	Output: outputText,
+}
+
+// chainConfig is the chain config of the genesis file of the configuration, if any,
+// loaded by loadConfig.
+var chainConfig *params.ChainConfig
+
+// These settings ensure that TOML keys use the same names as Go struct fields.
+var tomlSettings = toml.Config{
+	NormFieldName: func(rt reflect.Type, key string) string {
+		return key
+	},
+	FieldToKey: func(rt reflect.Type, field string) string {
+		return field
+	},
+	MissingField: func(rt reflect.Type, field string) error {
+		var link string
+		if unicode.IsUpper(rune(rt.Name()[0])) && rt.PkgPath() != "main" {
+			link = fmt.Sprintf(", see https://godoc.org/%s#%s for available fields", rt.PkgPath(), rt.Name())
+		}
+		return fmt.Errorf("field '%s' is not defined in %s%s", field, rt.String(), link)
+	},
+}
+
+func loadConfigFile(file string, config *dputilConfig) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = tomlSettings.NewDecoder(bufio.NewReader(f)).Decode(config)
+	// Add file name to errors that have a line number.
+	if _, ok := err.(*toml.LineError); ok {
+		err = errors.New(file + ", " + err.Error())
+	}
+	return err
+}
+
+// loadConfig loads the configuration file, if any, into cfg and applies the flags
+// that are set on top of it.
+func loadConfig(ctx *cli.Context) error {
+	if file := ctx.GlobalString(configFileFlag.Name); file != "" {
+		if err := loadConfigFile(file, &cfg); err != nil {
+			return err
+		}
+	}
+	if ctx.GlobalIsSet(rpcURLFlag.Name) {
+		cfg.RPCURL = ctx.GlobalString(rpcURLFlag.Name)
+	}
+	if ctx.GlobalIsSet(keyDirFlag.Name) {
+		cfg.KeyDir = ctx.GlobalString(keyDirFlag.Name)
+	}
+	if ctx.GlobalIsSet(keyFileFlag.Name) {
+		cfg.KeyFile = ctx.GlobalString(keyFileFlag.Name)
+	}
+	if ctx.GlobalIsSet(genesisFlag.Name) {
+		cfg.GenesisFile = ctx.GlobalString(genesisFlag.Name)
+	}
+	if ctx.GlobalIsSet(outputFlag.Name) {
+		cfg.Output = ctx.GlobalString(outputFlag.Name)
+	}
+	if cfg.Output != outputText && cfg.Output != outputJSON {
+		return fmt.Errorf("invalid output format %q, use %q or %q", cfg.Output, outputText, outputJSON)
+	}
+	chainConfig = nil
+	if cfg.GenesisFile != "" {
+		config, err := loadGenesisChainConfig(cfg.GenesisFile)
+		if err != nil {
+			return err
+		}
+		chainConfig = config
+	}
+	return nil
+}
+
+// loadGenesisChainConfig reads the chain config of a genesis file and checks its
+// proof-of-stake fork schedule.
+func loadGenesisChainConfig(file string) (*params.ChainConfig, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var genesis struct {
+		Config *params.ChainConfig `json:"config"`
+	}
+	if err := json.Unmarshal(data, &genesis); err != nil {
+		return nil, fmt.Errorf("invalid genesis file %s: %v", file, err)
+	}
+	if genesis.Config == nil || genesis.Config.ProofOfStake == nil {
+		return nil, fmt.Errorf("genesis file %s has no proofofstake config", file)
+	}
+	if err := genesis.Config.ProofOfStake.CheckForkSchedule(); err != nil {
+		return nil, fmt.Errorf("genesis file %s: %v", file, err)
+	}
+	return genesis.Config, nil
+}
+
+// forkSchedule returns the proof-of-stake fork schedule of the chain of the node.
+func forkSchedule() *params.ProofOfStakeForkSchedule {
+	if chainConfig == nil {
+		return params.DefaultProofOfStakeForkSchedule
+	}
+	return chainConfig.ProofOfStakeForkSchedule()
+}
+
+// withConfig wraps the action of a command so that it runs with the configuration
+// loaded. The flags of the command can be given before or after its name.
+func withConfig(action func(ctx *cli.Context) error) func(*cli.Context) error {
+	return utils.MigrateFlags(func(ctx *cli.Context) error {
+		if err := loadConfig(ctx); err != nil {
+			return cli.NewExitError(err.Error(), exitCodeUsage)
+		}
+		return action(ctx)
+	})
+}
+
+var dumpConfigCommand = cli.Command{
+	Action:      withConfig(dumpConfig),
+	Name:        "dumpconfig",
+	Usage:       "Show configuration values",
+	ArgsUsage:   "",
+	Flags:       commandFlags(outputFlag),
+	Description: `The dumpconfig command shows the configuration values, from the configuration file and the flags.`,
+}
+
+func dumpConfig(ctx *cli.Context) error {
+	out, err := tomlSettings.Marshal(&cfg)
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(out)
+	return nil
+}
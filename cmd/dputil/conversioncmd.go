@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/console/prompt"
+	"github.com/DogeProtocol/dp/conversionutil"
+	"github.com/DogeProtocol/dp/crypto/crosssign"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+	"github.com/DogeProtocol/dp/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	getConversionMessageCommand = cli.Command{
+		Action:    withConfig(GetConversionMessage),
+		Name:      "getconversionmessage",
+		Usage:     "Show the message to sign to convert tokens to coins",
+		ArgsUsage: "<eth address>",
+		Category:  "CONVERSION COMMANDS",
+		Flags:     commandFlags(passwordFileFlag),
+		Description: `
+Shows the message that the ETH address holding the tokens signs, to convert them to
+coins of the quantum wallet given with --keyfile.`,
+	}
+	getCoinsForTokensCommand = cli.Command{
+		Action:    withConfig(ConvertToCoins),
+		Name:      "getcoinsfortokens",
+		Usage:     "Convert tokens to coins",
+		ArgsUsage: "<eth address> <eth signature>",
+		Category:  "CONVERSION COMMANDS",
+		Flags:     commandFlags(passwordFileFlag),
+		Description: `
+Requests the conversion of the tokens of an ETH address to coins of the quantum
+wallet given with --keyfile, with the signature of the message shown by the
+getconversionmessage command. It asks for several confirmations.`,
+	}
+)
+
+// conversionKey decrypts the key of the quantum wallet given with --keyfile.
+func conversionKey(ctx *cli.Context) (*signaturealgorithm.PrivateKey, common.Address, error) {
+	if len(cfg.KeyFile) == 0 {
+		return nil, common.Address{}, errors.New("no quantum wallet, set --keyfile or KeyFile in the configuration file")
+	}
+
+	fmt.Println(fmt.Sprintf("Quantum wallet address %s", cfg.KeyFile))
+	accPwd, err := getPassword(ctx, passwordFileFlag, "Enter the quantum wallet password : ")
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+
+	key, err := GetKeyFromFile(cfg.KeyFile, accPwd)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+
+	qAddr, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	return key, qAddr, nil
+}
+
+func GetConversionMessage(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 1); err != nil {
+		return err
+	}
+
+	ethAddress := ctx.Args().Get(0)
+	if common.IsLegacyEthereumHexAddress(ethAddress) == false {
+		return usageError(ctx, "invalid eth address %s", ethAddress)
+	}
+
+	_, qAddr, err := conversionKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	quantumAddress := qAddr.Hex()
+
+	message := strings.Replace(crosssign.ConversionMessageTemplate, "[ETH_ADDRESS]", strings.ToLower(ethAddress), 1)
+	message = strings.Replace(message, "[QUANTUM_ADDRESS]", strings.ToLower(quantumAddress), 1)
+
+	fmt.Println("Message is: ")
+	fmt.Println(message)
+
+	return nil
+}
+
+func ConvertToCoins(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 2); err != nil {
+		return err
+	}
+
+	ethAddress := ctx.Args().Get(0)
+	if common.IsLegacyEthereumHexAddress(ethAddress) == false {
+		return usageError(ctx, "invalid eth address %s", ethAddress)
+	}
+
+	_, ok := conversionutil.SnapshotMap[strings.ToLower(ethAddress)]
+
+	if ok == false {
+		log.Trace("IsGasExemptTxn address not in snapshot", "ethAddress", ethAddress)
+		return errors.New("unidentified eth address")
+	}
+
+	ethConfirm, err := prompt.Stdin.PromptConfirm(fmt.Sprintf("Do you confirm that your ETH ADDRESS having the Dogep tokens is %s ?", ethAddress))
+	if err != nil {
+		return err
+	}
+	if ethConfirm != true {
+		return errNotConfirmed
+	}
+	fmt.Println()
+
+	ethSignature := ctx.Args().Get(1)
+
+	key, qAddr, err := conversionKey(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Println()
+
+	backupConfirm, err := prompt.Stdin.PromptConfirm(fmt.Sprintf("Do you confirm that you have backed up your quantum wallet located at %s ?", cfg.KeyFile))
+	if err != nil {
+		return err
+	}
+	if backupConfirm != true {
+		return errNotConfirmed
+	}
+	fmt.Println()
+
+	passwordConfirm, err := prompt.Stdin.PromptConfirm(fmt.Sprintf("Do you understand that the wallet password will always be required to use the quantum wallet at %s?", cfg.KeyFile))
+	if err != nil {
+		return err
+	}
+	if passwordConfirm != true {
+		return errNotConfirmed
+	}
+	fmt.Println()
+
+	quantumAddress := qAddr.Hex()
+
+	time.Sleep(500 * time.Millisecond)
+
+	fmt.Println()
+	quantumConfirm, err := prompt.Stdin.PromptConfirm(fmt.Sprintf("Do you confirm that you want the coins deposited to QUANTUM ADDRESS %s ?", quantumAddress))
+	if err != nil {
+		return err
+	}
+	if quantumConfirm != true {
+		return errNotConfirmed
+	}
+	fmt.Println()
+
+	crossSignDetails := &crosssign.ConversionSignDetails{
+		EthAddress:        strings.ToLower(ethAddress),
+		EthereumSignature: ethSignature,
+		QuantumAddress:    strings.ToLower(quantumAddress),
+	}
+
+	_, err = crosssign.VerifyConversion(crossSignDetails)
+	if err != nil {
+		fmt.Println("An error occurred while verifying the ethereum signature.")
+		return err
+	}
+
+	time.Sleep(3000 * time.Millisecond)
+	fmt.Println("Final confirmation!!!")
+	time.Sleep(3000 * time.Millisecond)
+	fmt.Println("Verify your message...")
+	time.Sleep(3000 * time.Millisecond)
+
+	message := strings.Replace(crosssign.ConversionMessageTemplate, "[ETH_ADDRESS]", strings.ToLower(ethAddress), 1)
+	message = strings.Replace(message, "[QUANTUM_ADDRESS]", strings.ToLower(quantumAddress), 1)
+
+	finalConfirm, err := prompt.Stdin.PromptConfirm(fmt.Sprintf("%s", message))
+	if err != nil {
+		return err
+	}
+	if finalConfirm != true {
+		return errNotConfirmed
+	}
+
+	if len(cfg.RPCURL) == 0 {
+		return requestConvertCoins(ethAddress, ethSignature, key)
+	} else {
+		return convertCoins(ethAddress, ethSignature, key)
+	}
+}
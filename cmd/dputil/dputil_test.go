@@ -1 +1,225 @@
 package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/ethclient"
+	"github.com/DogeProtocol/dp/internal/cmdtest"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/rpc"
+	"github.com/docker/docker/pkg/reexec"
+)
+
+type testDputil struct {
+	*cmdtest.TestCmd
+}
+
+// runDputil spawns dputil with the given command line args.
+func runDputil(t *testing.T, args ...string) *testDputil {
+	tt := new(testDputil)
+	tt.TestCmd = cmdtest.NewTestCmd(t, tt)
+	tt.Run("dputil-test", args...)
+	return tt
+}
+
+func TestMain(m *testing.M) {
+	// Run the app if we've been exec'd as "dputil-test" in runDputil.
+	reexec.Register("dputil-test", func() {
+		main()
+		os.Exit(0)
+	})
+	// check if we have been reexec'd
+	if reexec.Init() {
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// testNodeService serves the eth methods that dputil calls on the node.
+type testNodeService struct {
+	head    uint64
+	balance *big.Int
+}
+
+func (s *testNodeService) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(s.head)
+}
+
+func (s *testNodeService) GetBalance(address common.Address, block string) *hexutil.Big {
+	return (*hexutil.Big)(s.balance)
+}
+
+// newTestNode starts an HTTP RPC server that serves service, and returns its URL.
+func newTestNode(t *testing.T, service *testNodeService) string {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", service); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(func() {
+		httpServer.Close()
+		server.Stop()
+	})
+	return httpServer.URL
+}
+
+func writeTestFile(t *testing.T, name string, content string) string {
+	file := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestUsageErrors(t *testing.T) {
+	address := common.BytesToAddress([]byte{1}).Hex()
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	noProofOfStake := writeTestFile(t, "genesis.json", `{"config": {"chainId": 123123}}`)
+
+	tests := []struct {
+		args   []string
+		help   bool
+		stderr string
+	}{
+		{[]string{"balance"}, true, "wrong number of arguments, expected 1, got 0"},
+		{[]string{"balance", address, address}, true, "wrong number of arguments, expected 1, got 2"},
+		{[]string{"balance", "0xzz"}, true, "invalid account address 0xzz"},
+		{[]string{"send", address, address, "abc"}, true, "invalid amount abc"},
+		{[]string{"send", address, "0x1", "1"}, true, "invalid to address 0x1"},
+		{[]string{"--output", "xml", "dumpconfig"}, false, `invalid output format "xml"`},
+		{[]string{"dumpconfig", "--output", "xml"}, false, `invalid output format "xml"`},
+		{[]string{"--config", missing, "dumpconfig"}, false, "missing.json"},
+		{[]string{"--genesis", missing, "dumpconfig"}, false, "missing.json"},
+		{[]string{"--genesis", noProofOfStake, "dumpconfig"}, false, "has no proofofstake config"},
+	}
+	for _, test := range tests {
+		dputil := runDputil(t, test.args...)
+		if test.help {
+			dputil.ExpectRegexp(`(?s)OPTIONS:.*`)
+		}
+		dputil.WaitExit()
+		if status := dputil.ExitStatus(); status != exitCodeUsage {
+			t.Errorf("%v: exit status %d, want %d", test.args, status, exitCodeUsage)
+		}
+		if stderr := dputil.StderrText(); strings.Contains(stderr, test.stderr) == false {
+			t.Errorf("%v: stderr %q does not contain %q", test.args, stderr, test.stderr)
+		}
+	}
+}
+
+func TestCommandError(t *testing.T) {
+	// The node is no longer listening
+	closed := httptest.NewServer(nil)
+	url := closed.URL
+	closed.Close()
+
+	dputil := runDputil(t, "balance", "--rpcurl", url, common.BytesToAddress([]byte{1}).Hex())
+	dputil.ExpectExit()
+	if status := dputil.ExitStatus(); status != exitCodeError {
+		t.Errorf("exit status %d, want %d", status, exitCodeError)
+	}
+	if stderr := dputil.StderrText(); strings.Contains(stderr, "Error:") == false {
+		t.Errorf("stderr %q does not contain the error", stderr)
+	}
+}
+
+func TestOutputJSON(t *testing.T) {
+	url := newTestNode(t, &testNodeService{balance: new(big.Int).Mul(big.NewInt(2), big.NewInt(1e18))})
+	address := common.BytesToAddress([]byte{1}).Hex()
+
+	dputil := runDputil(t, "--output", "json", "balance", "--rpcurl", url, address)
+	_, matches := dputil.ExpectRegexp(`(?s)^(\{.*\})\n$`)
+	dputil.ExpectExit()
+	if status := dputil.ExitStatus(); status != 0 {
+		t.Fatalf("exit status %d, stderr %s", status, dputil.StderrText())
+	}
+	var result AccountBalance
+	if err := json.Unmarshal([]byte(matches[1]), &result); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	if result.Address != address || result.Coins != "2" || result.Wei != "2000000000000000000" || result.Nonce != "" {
+		t.Fatalf("unexpected balance %+v", result)
+	}
+
+	// The same balance for humans, with the flag after the command
+	dputil = runDputil(t, "balance", "--rpcurl", url, "--output", "text", address)
+	dputil.Expect("Address " + address + " coins 2 wei 2000000000000000000\n")
+	dputil.ExpectExit()
+}
+
+func TestDumpConfig(t *testing.T) {
+	config := writeTestFile(t, "config.toml", `RPCURL = "http://localhost:8545"
+KeyDir = "keys"
+Output = "json"
+`)
+	dputil := runDputil(t, "--config", config, "dumpconfig", "--keydir", "other")
+	dputil.Expect(`
+RPCURL = "http://localhost:8545"
+KeyDir = "other"
+KeyFile = ""
+Output = "json"
+GenesisFile = ""
+`)
+	dputil.ExpectExit()
+
+	dputil = runDputil(t, "--config", writeTestFile(t, "bad.toml", "Unknown = 1\n"), "dumpconfig")
+	dputil.WaitExit()
+	if status := dputil.ExitStatus(); status != exitCodeUsage {
+		t.Errorf("exit status %d, want %d", status, exitCodeUsage)
+	}
+}
+
+func TestStakingContractV2Schedule(t *testing.T) {
+	defer func() { chainConfig = nil }()
+
+	service := &testNodeService{}
+	client, err := ethclient.Dial(newTestNode(t, service))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	check := func(head uint64, want bool) {
+		t.Helper()
+		service.head = head
+		v2, err := isStakingContractV2(client)
+		if err != nil {
+			t.Fatalf("isStakingContractV2 failed: %v", err)
+		}
+		if v2 != want {
+			t.Fatalf("staking contract v2 at block %d is %v, want %v", head, v2, want)
+		}
+	}
+
+	// The mainnet schedule is used without a genesis file
+	mainnetBlock := params.DefaultProofOfStakeForkSchedule.StakingContractV2Block
+	check(0, false)
+	check(mainnetBlock-1, false)
+	check(mainnetBlock, true)
+
+	// The schedule of the genesis file of the chain
+	genesis := writeTestFile(t, "genesis.json", `{"config": {"chainId": 123123, "proofofstake": {
+		"stakingContractV2Block": 10, "consensusContextStartBlock": 10, "contextBasedStartBlock": 70000}}}`)
+	config, err := loadGenesisChainConfig(genesis)
+	if err != nil {
+		t.Fatalf("loadGenesisChainConfig failed: %v", err)
+	}
+	chainConfig = config
+	check(9, false)
+	check(10, true)
+	check(mainnetBlock-1, true)
+
+	invalid := writeTestFile(t, "invalid.json", `{"config": {"chainId": 123123, "proofofstake": {"stakingContractV2Block": 0}}}`)
+	if _, err := loadGenesisChainConfig(invalid); err == nil {
+		t.Fatalf("expected an invalid fork schedule to be rejected")
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/crypto/crosssign"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	genesisSignCommand = cli.Command{
+		Action:    withConfig(GenesisSign),
+		Name:      "genesis-sign",
+		Usage:     "Cross sign a genesis validator",
+		ArgsUsage: "<eth address> <depositor address> <validator address> <amount>",
+		Category:  "GENESIS COMMANDS",
+		Flags:     commandFlags(passwordFileFlag, validatorPasswordFileFlag),
+		Description: `
+Signs the genesis validator message with the depositor and the validator keys, looked
+up in --keydir, and writes it to cross-sign-<depositor address>.json.`,
+	}
+	genesisVerifyCommand = cli.Command{
+		Action:      withConfig(GenesisVerify),
+		Name:        "genesis-verify",
+		Usage:       "Verify a genesis validator cross sign file",
+		ArgsUsage:   "<json file>",
+		Category:    "GENESIS COMMANDS",
+		Flags:       commandFlags(),
+		Description: `Verifies a file written by the genesis-sign command.`,
+	}
+)
+
+func GenesisSign(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 4); err != nil {
+		return err
+	}
+
+	ethAddr := ctx.Args().Get(0)
+	amount := ctx.Args().Get(3)
+
+	if common.IsLegacyEthereumHexAddress(ethAddr) == false {
+		return usageError(ctx, "invalid eth address %s", ethAddr)
+	}
+	depositorAddr, err := addressArg(ctx, 1, "depositor")
+	if err != nil {
+		return err
+	}
+	validatorAddr, err := addressArg(ctx, 2, "validator")
+	if err != nil {
+		return err
+	}
+	if _, err := amountArg(ctx, 3); err != nil {
+		return err
+	}
+
+	depKey, _, err := unlockKey(ctx, depositorAddr, "depositor", passwordFileFlag)
+	if err != nil {
+		return err
+	}
+	valKey, _, err := unlockKey(ctx, validatorAddr, "validator", validatorPasswordFileFlag)
+	if err != nil {
+		return err
+	}
+
+	details, err := crosssign.SignGenesis(depKey, valKey, ethAddr, amount)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Signed the genesis validator message!")
+
+	marshalled, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	fileName := "cross-sign-" + ctx.Args().Get(1) + ".json"
+	err = ioutil.WriteFile(fileName, marshalled, 0644)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Successfully created cross-sign file", fileName)
+	return nil
+}
+
+func GenesisVerify(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 1); err != nil {
+		return err
+	}
+
+	jsonFile := ctx.Args().First()
+
+	jsonBytes, err := ioutil.ReadFile(jsonFile)
+	if err != nil {
+		return fmt.Errorf("error opening json file %s: %v", jsonFile, err)
+	}
+
+	details := crosssign.GenesisCrossSignDetails{}
+	err = json.Unmarshal(jsonBytes, &details)
+	if err != nil {
+		return fmt.Errorf("error reading json %s: %v", jsonFile, err)
+	}
+
+	_, err = crosssign.VerifyGenesis(&details)
+	if err != nil {
+		return fmt.Errorf("verify failed: %v", err)
+	}
+
+	fmt.Println("Verify succeeded!")
+	return nil
+}
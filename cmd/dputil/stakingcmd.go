@@ -0,0 +1,498 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var gasLimitFlag = cli.Uint64Flag{
+	Name:   "gaslimit",
+	Usage:  "Gas limit of the transaction",
+	Value:  DEFAULT_GAS_LIMIT,
+	EnvVar: "GAS_LIMIT",
+}
+
+var (
+	stakingDepositCommand = cli.Command{
+		Action:    withConfig(deposit),
+		Name:      "stakingdeposit",
+		Usage:     "Deposit coins to become a validator",
+		ArgsUsage: "<depositor address> <validator address> <amount>",
+		Category:  "STAKING COMMANDS",
+		Flags:     commandFlags(outputFlag, passwordFileFlag, validatorPasswordFileFlag, yesFlag),
+		Description: `
+Deposits an amount of coins from the depositor account, for the validator account.
+The key files of both accounts are looked up in --keydir. The password of the
+depositor key is read from --passwordfile and the one of the validator key from
+--validator.passwordfile, or else they are prompted for.`,
+	}
+	stakingBalanceCommand = cli.Command{
+		Action:      withConfig(depositorBalance),
+		Name:        "stakingbalance",
+		Usage:       "Show the staking balance of a depositor",
+		ArgsUsage:   "<depositor address>",
+		Category:    "STAKING COMMANDS",
+		Flags:       commandFlags(outputFlag),
+		Description: `Shows the coins deposited by a depositor.`,
+	}
+	listValidatorsCommand = cli.Command{
+		Action:      withConfig(validators),
+		Name:        "listvalidators",
+		Usage:       "List the validators",
+		ArgsUsage:   "",
+		Category:    "STAKING COMMANDS",
+		Flags:       commandFlags(outputFlag),
+		Description: `Lists the validators with their depositors, balances, rewards and slashings.`,
+	}
+	blockRewardsCommand = cli.Command{
+		Action:      withConfig(depositorBlockRewards),
+		Name:        "blockrewards",
+		Usage:       "Show the block rewards of a depositor",
+		ArgsUsage:   "<depositor address>",
+		Category:    "STAKING COMMANDS",
+		Flags:       commandFlags(outputFlag),
+		Description: `Shows the block rewards earned by a depositor.`,
+	}
+	getStakingDetailsCommand = cli.Command{
+		Action:      withConfig(stakingDetails),
+		Name:        "getstakingdetails",
+		Usage:       "Show the staking details of a validator",
+		ArgsUsage:   "<validator address>",
+		Category:    "STAKING COMMANDS",
+		Flags:       commandFlags(outputFlag),
+		Description: `Shows the staking details of a validator. It fails if the address is not a validator.`,
+	}
+	initiateWithdrawalRewardsCommand = cli.Command{
+		Action:    withConfig(initiateWithdrawalRewards),
+		Name:      "initiatewithdrawalrewards",
+		Usage:     "Initiate the withdrawal of the block rewards of a depositor",
+		ArgsUsage: "<depositor address>",
+		Category:  "STAKING COMMANDS",
+		Flags:     commandFlags(outputFlag, passwordFileFlag, yesFlag),
+		Description: `
+Initiates the withdrawal of the block rewards of a depositor, less its slashings.
+Complete it with the completewithdrawalrewards command.`,
+	}
+	initiatePartialWithdrawalCommand = cli.Command{
+		Action:    withConfig(initiatePartialWithdrawalCmd),
+		Name:      "initiatepartialwithdrawal",
+		Usage:     "Initiate the withdrawal of an amount of the deposit of a depositor",
+		ArgsUsage: "<depositor address> <amount>",
+		Category:  "STAKING COMMANDS",
+		Flags:     commandFlags(outputFlag, passwordFileFlag, yesFlag),
+		Description: `
+Initiates the withdrawal of an amount of coins deposited by a depositor. Complete it
+with the completepartialwithdrawal command.`,
+	}
+	completePartialWithdrawalCommand = cli.Command{
+		Action:      withConfig(completePartialWithdrawalCmd),
+		Name:        "completepartialwithdrawal",
+		Aliases:     []string{"completewithdrawalrewards"},
+		Usage:       "Complete a withdrawal of a depositor",
+		ArgsUsage:   "<depositor address>",
+		Category:    "STAKING COMMANDS",
+		Flags:       commandFlags(outputFlag, passwordFileFlag, yesFlag),
+		Description: `Completes a withdrawal initiated by initiatepartialwithdrawal or initiatewithdrawalrewards.`,
+	}
+	increaseDepositCommand = cli.Command{
+		Action:      withConfig(increaseDepositCmd),
+		Name:        "increasedeposit",
+		Usage:       "Increase the deposit of a depositor",
+		ArgsUsage:   "<depositor address> <additional amount>",
+		Category:    "STAKING COMMANDS",
+		Flags:       commandFlags(outputFlag, passwordFileFlag, yesFlag),
+		Description: `Deposits an additional amount of coins from a depositor.`,
+	}
+	changeValidatorCommand = cli.Command{
+		Action:    withConfig(changeValidatorCmd),
+		Name:      "changevalidator",
+		Usage:     "Change the validator of a depositor",
+		ArgsUsage: "<depositor address> <new validator address>",
+		Category:  "STAKING COMMANDS",
+		Flags:     commandFlags(outputFlag, passwordFileFlag, validatorPasswordFileFlag, yesFlag),
+		Description: `
+Changes the validator of a depositor. The key files of both accounts are looked up in
+--keydir.`,
+	}
+	pauseValidationCommand = cli.Command{
+		Action:      withConfig(pauseValidationCmd),
+		Name:        "pausevalidation",
+		Usage:       "Pause the validation of the validator of a depositor",
+		ArgsUsage:   "<depositor address>",
+		Category:    "STAKING COMMANDS",
+		Flags:       commandFlags(outputFlag, passwordFileFlag, yesFlag),
+		Description: `Pauses the validation of the validator of a depositor.`,
+	}
+	resumeValidationCommand = cli.Command{
+		Action:      withConfig(resumeValidationCmd),
+		Name:        "resumevalidation",
+		Usage:       "Resume the validation of the validator of a depositor",
+		ArgsUsage:   "<depositor address>",
+		Category:    "STAKING COMMANDS",
+		Flags:       commandFlags(outputFlag, passwordFileFlag, yesFlag),
+		Description: `Resumes the validation of the validator of a depositor.`,
+	}
+	initiateWithdrawalCommand = cli.Command{
+		Action:      withConfig(initiateWithdrawalCmd),
+		Name:        "initiatewithdrawal",
+		Usage:       "Initiate the withdrawal of the whole deposit of a depositor",
+		ArgsUsage:   "<depositor address>",
+		Category:    "STAKING COMMANDS",
+		Flags:       commandFlags(outputFlag, passwordFileFlag, yesFlag),
+		Description: `Initiates the withdrawal of the whole deposit of a depositor, before the staking contract upgrade.`,
+	}
+	completeWithdrawalCommand = cli.Command{
+		Action:      withConfig(completeWithdrawalCmd),
+		Name:        "completewithdrawal",
+		Usage:       "Complete the withdrawal of the whole deposit of a depositor",
+		ArgsUsage:   "<depositor address>",
+		Category:    "STAKING COMMANDS",
+		Flags:       commandFlags(outputFlag, passwordFileFlag, yesFlag, gasLimitFlag),
+		Description: `Completes a withdrawal initiated by initiatewithdrawal.`,
+	}
+)
+
+// DepositorAmount is the output of the stakingbalance and blockrewards commands.
+type DepositorAmount struct {
+	Depositor common.Address `json:"depositor"`
+	Coins     string         `json:"coins"`
+	Wei       string         `json:"wei"`
+}
+
+func newDepositorAmount(depositor common.Address, amount *big.Int) *DepositorAmount {
+	return &DepositorAmount{
+		Depositor: depositor,
+		Coins:     weiToEther(amount).String(),
+		Wei:       amount.String(),
+	}
+}
+
+// depositorArg checks that a node to connect to is configured and returns the first
+// argument of the command, the address of a depositor.
+func depositorArg(ctx *cli.Context) (common.Address, error) {
+	depositor, err := addressArg(ctx, 0, "depositor")
+	if err != nil {
+		return common.Address{}, err
+	}
+	return depositor, requireRPC()
+}
+
+// depositorKey unlocks the key of the depositor given as the first argument of the command.
+func depositorKey(ctx *cli.Context) (*signaturealgorithm.PrivateKey, error) {
+	depositor, err := depositorArg(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return unlockStakingKey(ctx, depositor, "depositor", passwordFileFlag)
+}
+
+func deposit(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 3); err != nil {
+		return err
+	}
+	validator, err := addressArg(ctx, 1, "validator")
+	if err != nil {
+		return err
+	}
+	amount, err := amountArg(ctx, 2)
+	if err != nil {
+		return err
+	}
+	depKey, err := depositorKey(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := unlockStakingKey(ctx, validator, "validator", validatorPasswordFileFlag); err != nil {
+		return err
+	}
+
+	hash, err := newDeposit(validator.Hex(), amount, depKey)
+	if err != nil {
+		return err
+	}
+	return printTransaction("Your request to deposit has been added to the queue for processing. Please check your account balance after 10 minutes.", hash)
+}
+
+func depositorBalance(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 1); err != nil {
+		return err
+	}
+	depositor, err := depositorArg(ctx)
+	if err != nil {
+		return err
+	}
+
+	balance, err := getBalanceOfDepositor(depositor.Hex())
+	if err != nil {
+		return err
+	}
+	result := newDepositorAmount(depositor, balance)
+	return printResult(result, func() {
+		fmt.Println("StakingBalance", "Address", result.Depositor.Hex(), "coins", result.Coins, "wei", result.Wei)
+	})
+}
+
+func depositorBlockRewards(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 1); err != nil {
+		return err
+	}
+	depositor, err := depositorArg(ctx)
+	if err != nil {
+		return err
+	}
+
+	rewards, err := getDepositorBlockRewards(depositor.Hex())
+	if err != nil {
+		return err
+	}
+	result := newDepositorAmount(depositor, rewards)
+	return printResult(result, func() {
+		fmt.Println("BlockRewards", "Depositor", result.Depositor.Hex(), "coins", result.Coins, "wei", result.Wei)
+	})
+}
+
+func validators(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 0); err != nil {
+		return err
+	}
+	if err := requireRPC(); err != nil {
+		return err
+	}
+
+	result, err := listValidators()
+	if err != nil {
+		return err
+	}
+	return printResult(result, func() {
+		for _, validatorDetails := range result.Validators {
+			balance, _ := hexutil.DecodeBig(validatorDetails.Balance)
+			netBalance, _ := hexutil.DecodeBig(validatorDetails.NetBalance)
+			blockRewards, _ := hexutil.DecodeBig(validatorDetails.BlockRewards)
+			slashing, _ := hexutil.DecodeBig(validatorDetails.Slashings)
+
+			fmt.Println("Depositor ", validatorDetails.Depositor, "Validator ", validatorDetails.Validator, "Balance coins", weiToEther(balance).String(),
+				"NetBalance coins", weiToEther(netBalance).String(), "Block Rewards coins", weiToEther(blockRewards).String(), "Slashing Coins", weiToEther(slashing).String())
+		}
+		total, _ := hexutil.DecodeBig(result.TotalDepositedBalance)
+		fmt.Println("Total validators", len(result.Validators), "totalDepositedBalance", weiToEther(total).String())
+	})
+}
+
+func stakingDetails(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 1); err != nil {
+		return err
+	}
+	validator, err := addressArg(ctx, 0, "validator")
+	if err != nil {
+		return err
+	}
+	if err := requireRPC(); err != nil {
+		return err
+	}
+
+	details, err := getStakingDetails(validator)
+	if err != nil {
+		return err
+	}
+	return printResult(details, func() {
+		fmt.Println("Depositor ", details.Depositor, " Validator ", details.Validator)
+		fmt.Println("Last NiL Block ", details.LastNilBlock.ToInt().String(), " Nil Block Count ", details.NilBlockCount.ToInt().String())
+		fmt.Println("Withdrawal Block ", details.WithdrawalBlock.ToInt().String())
+		fmt.Println("Withdrawal coins ", weiToEther(details.WithdrawalAmount.ToInt()).String())
+		fmt.Println("Slashing coins", weiToEther(details.Slashings.ToInt()).String())
+		fmt.Println("Rewards coins ", weiToEther(details.BlockRewards.ToInt()).String())
+		fmt.Println("Staking Balance coins ", weiToEther(details.Balance.ToInt()).String())
+		fmt.Println("Net Balance coins ", weiToEther(details.NetBalance.ToInt()).String())
+		fmt.Println("Validation paused ", details.IsValidationPaused)
+	})
+}
+
+func initiateWithdrawalRewards(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 1); err != nil {
+		return err
+	}
+	depositor, err := depositorArg(ctx)
+	if err != nil {
+		return err
+	}
+	depKey, err := unlockStakingKey(ctx, depositor, "depositor", passwordFileFlag)
+	if err != nil {
+		return err
+	}
+
+	depositorReward, err := getDepositorBlockRewards(depositor.Hex())
+	if err != nil {
+		return errors.New("depositor reward " + err.Error())
+	}
+	depositorSlashings, err := getDepositorSlashings(depositor.Hex())
+	if err != nil {
+		return errors.New("depositor slashings " + err.Error())
+	}
+	if depositorReward.Sign() == 0 || depositorSlashings.Cmp(depositorReward) >= 0 {
+		return errors.New("there are no rewards available to withdraw")
+	}
+
+	amount := new(big.Int).Sub(weiToEther(depositorReward), weiToEther(depositorSlashings))
+	if amount.Sign() <= 0 {
+		return errors.New("invalid depositor amount")
+	}
+	err = confirm(ctx, fmt.Sprintf("The following amount will be withdrawn. Please confirm if you are ok : %d?", amount))
+	if err != nil {
+		return err
+	}
+
+	hash, err := initiatePartialWithdrawal(depKey, amount.String())
+	if err != nil {
+		return err
+	}
+	return printTransaction("Your request to initiate rewards withdrawal has been added to the queue for processing.", hash)
+}
+
+func initiatePartialWithdrawalCmd(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 2); err != nil {
+		return err
+	}
+	amount, err := amountArg(ctx, 1)
+	if err != nil {
+		return err
+	}
+	depKey, err := depositorKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	hash, err := initiatePartialWithdrawal(depKey, amount)
+	if err != nil {
+		return err
+	}
+	return printTransaction("Your request to initiate the withdrawal has been added to the queue for processing.", hash)
+}
+
+func completePartialWithdrawalCmd(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 1); err != nil {
+		return err
+	}
+	depKey, err := depositorKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	hash, err := completePartialWithdrawal(depKey)
+	if err != nil {
+		return err
+	}
+	return printTransaction("Your request to complete the withdrawal has been added to the queue for processing.", hash)
+}
+
+func increaseDepositCmd(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 2); err != nil {
+		return err
+	}
+	amount, err := amountArg(ctx, 1)
+	if err != nil {
+		return err
+	}
+	depKey, err := depositorKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	hash, err := increaseDeposit(depKey, amount)
+	if err != nil {
+		return err
+	}
+	return printTransaction("Your request to increase the deposit has been added to the queue for processing.", hash)
+}
+
+func changeValidatorCmd(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 2); err != nil {
+		return err
+	}
+	validator, err := addressArg(ctx, 1, "validator")
+	if err != nil {
+		return err
+	}
+	depKey, err := depositorKey(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := unlockStakingKey(ctx, validator, "validator", validatorPasswordFileFlag); err != nil {
+		return err
+	}
+
+	hash, err := changeValidator(depKey, validator)
+	if err != nil {
+		return err
+	}
+	return printTransaction("Your request to change the validator has been added to the queue for processing.", hash)
+}
+
+func pauseValidationCmd(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 1); err != nil {
+		return err
+	}
+	depKey, err := depositorKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	hash, err := pauseValidation(depKey)
+	if err != nil {
+		return err
+	}
+	return printTransaction("Your request to pause validation has been added to the queue for processing.", hash)
+}
+
+func resumeValidationCmd(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 1); err != nil {
+		return err
+	}
+	depKey, err := depositorKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	hash, err := resumeValidation(depKey)
+	if err != nil {
+		return err
+	}
+	return printTransaction("Your request to resume validation has been added to the queue for processing.", hash)
+}
+
+func initiateWithdrawalCmd(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 1); err != nil {
+		return err
+	}
+	depKey, err := depositorKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	hash, err := initiateWithdrawal(depKey)
+	if err != nil {
+		return err
+	}
+	return printTransaction("Your request to initiate withdrawal has been added to the queue for processing.", hash)
+}
+
+func completeWithdrawalCmd(ctx *cli.Context) error {
+	if err := checkArgs(ctx, 1); err != nil {
+		return err
+	}
+	depKey, err := depositorKey(ctx)
+	if err != nil {
+		return err
+	}
+
+	hash, err := completeWithdrawal(depKey, ctx.Uint64(gasLimitFlag.Name))
+	if err != nil {
+		return err
+	}
+	return printTransaction("Your request to complete withdrawal has been added to the queue for processing.", hash)
+}
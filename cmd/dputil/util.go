@@ -11,7 +11,6 @@ import (
 	"github.com/DogeProtocol/dp/accounts/keystore"
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/common/hexutil"
-	"github.com/DogeProtocol/dp/consensus/proofofstake"
 	"github.com/DogeProtocol/dp/core/types"
 	"github.com/DogeProtocol/dp/crypto/cryptobase"
 	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
@@ -27,12 +26,10 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 )
 
-const GAS_LIMIT_ENV = "GAS_LIMIT"
 const DEFAULT_GAS_LIMIT = uint64(210000)
 
 type KeyStore struct {
@@ -65,7 +62,7 @@ func etherToWeiFloat(eth *big.Float) *big.Int {
 }
 
 func getBalance(address string) (ethBalance string, weiBalance string, err error) {
-	client, err := ethclient.Dial(rawURL)
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
 		return "", "", err
 	}
@@ -76,6 +73,16 @@ func getBalance(address string) (ethBalance string, weiBalance string, err error
 	return weiToEther(balance).String(), balance.String(), nil
 }
 
+// isStakingContractV2 returns whether the staking contract has been upgraded to v2 as of
+// the latest block of the node. The upgrade happens while finalizing the fork block itself.
+func isStakingContractV2(client *ethclient.Client) (bool, error) {
+	blockNumber, err := client.BlockNumber(context.Background())
+	if err != nil {
+		return false, err
+	}
+	return blockNumber >= forkSchedule().StakingContractV2Block, nil
+}
+
 func requestGetBalance(address string) (ethBalance string, weiBalance string, nonce string, err error) {
 	request, err := http.NewRequest("GET", READ_API_URL+"/api/accounts/"+address+"/balance", nil)
 	if err != nil {
@@ -119,14 +126,13 @@ func requestGetBalance(address string) (ethBalance string, weiBalance string, no
 }
 
 func findAllAddresses() ([]string, error) {
-	keyfileDir := os.Getenv("DP_KEY_FILE_DIR")
+	keyfileDir := cfg.KeyDir
 	if len(keyfileDir) == 0 {
-		return nil, errors.New("Both DP_KEY_FILE and DP_KEY_FILE_DIR environment variables not set")
+		return nil, errNoKeyDir
 	}
 
 	files, err := ioutil.ReadDir(keyfileDir)
 	if err != nil {
-		log.Fatal(err)
 		return nil, err
 	}
 
@@ -147,19 +153,18 @@ func findAllAddresses() ([]string, error) {
 }
 
 func findKeyFile(keyAddress string) (string, error) {
-	keyfile := os.Getenv("DP_KEY_FILE")
+	keyfile := cfg.KeyFile
 	if len(keyfile) > 0 {
 		return keyfile, nil
 	}
 
-	keyfileDir := os.Getenv("DP_KEY_FILE_DIR")
+	keyfileDir := cfg.KeyDir
 	if len(keyfileDir) == 0 {
-		return "", errors.New("Both DP_KEY_FILE and DP_KEY_FILE_DIR environment variables not set")
+		return "", errNoKeyDir
 	}
 
 	files, err := ioutil.ReadDir(keyfileDir)
 	if err != nil {
-		log.Fatal(err)
 		return "", err
 	}
 
@@ -173,7 +178,7 @@ func findKeyFile(keyAddress string) (string, error) {
 		}
 	}
 
-	return "", errors.New("could not find key file")
+	return "", fmt.Errorf("could not find the key file of %s in %s", keyAddress, keyfileDir)
 }
 
 type ConnectionContext struct {
@@ -197,7 +202,7 @@ func GetKeyFromFile(keyFile string, accPwd string) (*signaturealgorithm.PrivateK
 	return key.PrivateKey, nil
 }
 
-func GetConnectionContext(from string) (*ConnectionContext, error) {
+func GetConnectionContext(from string, password string) (*ConnectionContext, error) {
 	keyFile, err := findKeyFile(from)
 	if err != nil {
 		return nil, err
@@ -208,13 +213,12 @@ func GetConnectionContext(from string) (*ConnectionContext, error) {
 		return nil, err
 	}
 
-	password := os.Getenv("DP_ACC_PWD")
 	key, err := keystore.DecryptKey(secretKey, password)
 	if err != nil {
 		return nil, err
 	}
 
-	client, err := ethclient.Dial(rawURL)
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
 		return nil, err
 	}
@@ -273,74 +277,56 @@ func sendVia(connectionContext *ConnectionContext, to string, quantity string, n
 	return signedTx.Hash().Hex(), nonce, nil
 }
 
-func send(from string, to string, quantity string) (string, error) {
-	keyFile, err := findKeyFile(from)
-	if err != nil {
-		return "", err
-	}
-
-	fmt.Println("keyFile", keyFile)
-	secretKey, err := ReadDataFile(keyFile)
-	if err != nil {
-		return "", err
-	}
-	password := os.Getenv("DP_ACC_PWD")
-	key, err := keystore.DecryptKey(secretKey, password)
+func send(key *signaturealgorithm.PrivateKey, to string, quantity string) (common.Hash, error) {
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
-		return "", err
+		return common.Hash{}, err
 	}
 
-	client, err := ethclient.Dial(rawURL)
+	fromAddress, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
 	if err != nil {
-		return "", err
+		return common.Hash{}, err
 	}
-
-	fromAddress := common.HexToAddress(from)
 	toAddress := common.HexToAddress(to)
 
 	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
 	if err != nil {
-		return "", err
+		return common.Hash{}, err
 	}
 	chainID, err := client.NetworkID(context.Background())
 	if err != nil {
-		return "", err
+		return common.Hash{}, err
 	}
 	gasLimit := uint64(21000)
 
 	v, err := ParseBigFloat(quantity)
 	if err != nil {
-		return "", err
+		return common.Hash{}, err
 	}
 
 	value := etherToWeiFloat(v)
 
 	var data []byte
 	tx := types.NewDefaultFeeTransaction(chainID, nonce, &toAddress, value, gasLimit, types.GAS_TIER_DEFAULT, data)
-	fmt.Println("chainID", chainID)
 
-	signedTx, err := types.SignTx(tx, types.NewLondonSigner(chainID), key.PrivateKey)
+	signedTx, err := types.SignTx(tx, types.NewLondonSigner(chainID), key)
 	if err != nil {
-		fmt.Println("signedTx err", err)
-		return "", err
+		return common.Hash{}, err
 	}
-	fmt.Println("signedTx ok")
 	err = client.SendTransaction(context.Background(), signedTx)
 	if err != nil {
-		return "", err
+		return common.Hash{}, err
 	}
 
-	fmt.Println("Sent Transaction", "from", fromAddress, "to", toAddress, "quantity", quantity, "Transaction", signedTx.Hash().Hex())
-	return signedTx.Hash().Hex(), nil
+	return signedTx.Hash(), nil
 }
 
 func GetTransaction(txnHash string) (string, error) {
-	client, err := ethclient.Dial(rawURL)
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
 		return "", err
 	}
 	hash := common.HexToHash(txnHash)
-	fmt.Println("hash", hash)
 	return client.RawTransactionByHash(context.Background(), hash)
 }
 
@@ -358,18 +344,14 @@ func ReadDataFile(filename string) ([]byte, error) {
 	jsonFile, err := os.Open(filename)
 	// if we os.Open returns an error then handle it
 	if err != nil {
-		log.Println(err.Error())
 		return nil, err
 	}
 
-	fmt.Println("Successfully Opened ", filename)
 	// defer the closing of our jsonFile so that we can parse it later on
 	defer jsonFile.Close()
 
 	// read our opened xmlFile as a byte array.
-	byteValue, _ := ioutil.ReadAll(jsonFile)
-
-	return byteValue, nil
+	return ioutil.ReadAll(jsonFile)
 }
 
 func (ks *KeyStore) CreateNewKeys(password string) accounts.Account {
@@ -407,7 +389,7 @@ func SetUpKeyStore() *KeyStore {
 }
 
 func convertCoins(ethAddress string, ethSignature string, key *signaturealgorithm.PrivateKey) error {
-	client, err := ethclient.Dial(rawURL)
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
 		return err
 	}
@@ -545,29 +527,29 @@ func requestConvertCoins(ethAddress string, ethSignature string, key *signaturea
 	return nil
 }
 
-func newDeposit(validatorAddress string, depositAmount string, key *signaturealgorithm.PrivateKey) error {
+func newDeposit(validatorAddress string, depositAmount string, key *signaturealgorithm.PrivateKey) (common.Hash, error) {
 
-	client, err := ethclient.Dial(rawURL)
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	fromAddress, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
 
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 	txnOpts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(123123))
 
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	txnOpts.From = fromAddress
@@ -577,65 +559,59 @@ func newDeposit(validatorAddress string, depositAmount string, key *signaturealg
 	val, _ := ParseBigFloat(depositAmount)
 	txnOpts.Value = etherToWeiFloat(val)
 
-	blockNumber, err := client.BlockNumber(context.Background())
+	stakingV2, err := isStakingContractV2(client)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	var tx *types.Transaction
-	if blockNumber < proofofstake.STAKING_CONTRACT_V2_CUTOFF_BLOCK {
+	if stakingV2 == false {
 		contract, err := stakingv1.NewStaking(contractAddress, client)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 
 		tx, err = contract.NewDeposit(txnOpts, common.HexToAddress(validatorAddress))
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 	} else {
 		contract, err := stakingv2.NewStaking(contractAddress, client)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 
 		tx, err = contract.NewDeposit(txnOpts, common.HexToAddress(validatorAddress))
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 	}
 
-	fmt.Println("Your request to deposit has been added to the queue for processing. Please check your account balance after 10 minutes.")
-	fmt.Println("The transaction hash for tracking this request is: ", tx.Hash())
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
-
-	return nil
+	return tx.Hash(), nil
 }
 
-func initiateWithdrawal(key *signaturealgorithm.PrivateKey) error {
+func initiateWithdrawal(key *signaturealgorithm.PrivateKey) (common.Hash, error) {
 
-	client, err := ethclient.Dial(rawURL)
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	fromAddress, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 	txnOpts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(123123))
 
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	txnOpts.From = fromAddress
@@ -646,116 +622,91 @@ func initiateWithdrawal(key *signaturealgorithm.PrivateKey) error {
 	txnOpts.Value = etherToWeiFloat(val)
 
 	var tx *types.Transaction
-	var blockNumber uint64
-	if blockNumber < proofofstake.STAKING_CONTRACT_V2_CUTOFF_BLOCK {
+	stakingV2, err := isStakingContractV2(client)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if stakingV2 == false {
 		contract, err := stakingv1.NewStaking(contractAddress, client)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 
 		tx, err = contract.InitiateWithdrawal(txnOpts)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 	} else {
-		fmt.Println("Operation not supported. Use partial withdraw instead")
-		return nil
+		return common.Hash{}, errors.New("initiating a full withdrawal is not supported by the staking contract, initiate a partial withdrawal instead")
 	}
 
-	fmt.Println("Your request to initiate withdrawal has been added to the queue for processing.")
-	fmt.Println("The transaction hash for tracking this request is: ", tx.Hash())
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
-
-	return nil
+	return tx.Hash(), nil
 }
 
-func getGasLimit() (uint64, error) {
-	gasLimitEnv := os.Getenv(GAS_LIMIT_ENV)
-	if len(gasLimitEnv) > 0 {
-		gasLimit, err := strconv.ParseUint(gasLimitEnv, 10, 64)
-		if err != nil {
-			fmt.Println("Error parsing gas limit, err")
-			return gasLimit, err
-		}
-		fmt.Println("Using gas limit passed using environment variable", gasLimit)
-		return gasLimit, nil
-	} else {
-		return DEFAULT_GAS_LIMIT, nil
-	}
-}
+func completeWithdrawal(key *signaturealgorithm.PrivateKey, gasLimit uint64) (common.Hash, error) {
 
-func completeWithdrawal(key *signaturealgorithm.PrivateKey) error {
-
-	client, err := ethclient.Dial(rawURL)
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	fromAddress, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
 
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 	txnOpts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(123123))
 
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	txnOpts.From = fromAddress
 	txnOpts.Nonce = big.NewInt(int64(nonce))
-	txnOpts.GasLimit, err = getGasLimit()
-	if err != nil {
-		return err
-	}
+	txnOpts.GasLimit = gasLimit
 
 	val, _ := ParseBigFloat("0")
 	txnOpts.Value = etherToWeiFloat(val)
 
 	var tx *types.Transaction
-	var blockNumber uint64
-	if blockNumber < proofofstake.STAKING_CONTRACT_V2_CUTOFF_BLOCK {
+	stakingV2, err := isStakingContractV2(client)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if stakingV2 == false {
 		contract, err := stakingv1.NewStaking(contractAddress, client)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 
 		tx, err = contract.CompleteWithdrawal(txnOpts)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 	} else {
 		contract, err := stakingv2.NewStaking(contractAddress, client)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 
 		tx, err = contract.CompleteWithdrawal(txnOpts)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 	}
 
-	fmt.Println("Your request to complete withdrawal has been added to the queue for processing.")
-	fmt.Println("The transaction hash for tracking this request is: ", tx.Hash())
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
-
-	return nil
+	return tx.Hash(), nil
 }
 
 func getBalanceOfDepositor(dep string) (*big.Int, error) {
-	client, err := ethclient.Dial(rawURL)
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
 		return nil, err
 	}
@@ -763,8 +714,11 @@ func getBalanceOfDepositor(dep string) (*big.Int, error) {
 	contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 
 	var depositorBalance *big.Int
-	var blockNumber uint64
-	if blockNumber < proofofstake.STAKING_CONTRACT_V2_CUTOFF_BLOCK {
+	stakingV2, err := isStakingContractV2(client)
+	if err != nil {
+		return nil, err
+	}
+	if stakingV2 == false {
 
 		instance, err := stakingv1.NewStaking(contractAddress, client)
 		if err != nil {
@@ -774,7 +728,7 @@ func getBalanceOfDepositor(dep string) (*big.Int, error) {
 		depositor := common.HexToAddress(dep)
 		depositorBalance, err = instance.GetBalanceOfDepositor(nil, depositor)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 	} else {
 		instance, err := stakingv2.NewStaking(contractAddress, client)
@@ -785,28 +739,25 @@ func getBalanceOfDepositor(dep string) (*big.Int, error) {
 		depositor := common.HexToAddress(dep)
 		depositorBalance, err = instance.GetBalanceOfDepositor(nil, depositor)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 	}
 
-	fmt.Println("StakingBalance", "Address", dep, "coins", weiToEther(depositorBalance).String(), "wei", depositorBalance)
-
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
-
 	return depositorBalance, nil
 }
 
 func getNetBalanceOfDepositor(dep string) (*big.Int, error) {
-	client, err := ethclient.Dial(rawURL)
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
 		return nil, err
 	}
 
 	var depositorBalance *big.Int
-	var blockNumber uint64
-	if blockNumber < proofofstake.STAKING_CONTRACT_V2_CUTOFF_BLOCK {
+	stakingV2, err := isStakingContractV2(client)
+	if err != nil {
+		return nil, err
+	}
+	if stakingV2 == false {
 		contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 		instance, err := stakingv1.NewStaking(contractAddress, client)
 		if err != nil {
@@ -816,7 +767,7 @@ func getNetBalanceOfDepositor(dep string) (*big.Int, error) {
 		depositor := common.HexToAddress(dep)
 		depositorBalance, err = instance.GetNetBalanceOfDepositor(nil, depositor)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 	} else {
 		contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
@@ -828,29 +779,26 @@ func getNetBalanceOfDepositor(dep string) (*big.Int, error) {
 		depositor := common.HexToAddress(dep)
 		depositorBalance, err = instance.GetNetBalanceOfDepositor(nil, depositor)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 	}
 
-	fmt.Println("StakingNetBalance", "Address", dep, "coins", weiToEther(depositorBalance).String(), "wei", depositorBalance)
-
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
-
 	return depositorBalance, nil
 }
 
 func getDepositorOfValidator(val string) (common.Address, error) {
-	client, err := ethclient.Dial(rawURL)
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
 		return common.ZERO_ADDRESS, err
 	}
 
 	var depositor common.Address
 	var validator common.Address
-	var blockNumber uint64
-	if blockNumber < proofofstake.STAKING_CONTRACT_V2_CUTOFF_BLOCK {
+	stakingV2, err := isStakingContractV2(client)
+	if err != nil {
+		return common.ZERO_ADDRESS, err
+	}
+	if stakingV2 == false {
 		contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 		instance, err := stakingv1.NewStaking(contractAddress, client)
 		if err != nil {
@@ -860,7 +808,7 @@ func getDepositorOfValidator(val string) (common.Address, error) {
 		validator = common.HexToAddress(val)
 		depositor, err = instance.GetDepositorOfValidator(nil, validator)
 		if err != nil {
-			log.Fatal(err)
+			return common.ZERO_ADDRESS, err
 		}
 	} else {
 		contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
@@ -872,27 +820,25 @@ func getDepositorOfValidator(val string) (common.Address, error) {
 		validator = common.HexToAddress(val)
 		depositor, err = instance.GetDepositorOfValidator(nil, validator)
 		if err != nil {
-			log.Fatal(err)
+			return common.ZERO_ADDRESS, err
 		}
 	}
 
-	fmt.Println("Depositor", depositor, "validator", validator)
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
-
-	return depositor, err
+	return depositor, nil
 }
 
 func getDepositorBlockRewards(dep string) (*big.Int, error) {
-	client, err := ethclient.Dial(rawURL)
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
 		return nil, err
 	}
 
 	var depositorBalance *big.Int
-	var blockNumber uint64
-	if blockNumber < proofofstake.STAKING_CONTRACT_V2_CUTOFF_BLOCK {
+	stakingV2, err := isStakingContractV2(client)
+	if err != nil {
+		return nil, err
+	}
+	if stakingV2 == false {
 		contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 		instance, err := stakingv1.NewStaking(contractAddress, client)
 		if err != nil {
@@ -902,7 +848,7 @@ func getDepositorBlockRewards(dep string) (*big.Int, error) {
 		depositor := common.HexToAddress(dep)
 		depositorBalance, err = instance.GetDepositorRewards(nil, depositor)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 	} else {
 		contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
@@ -914,27 +860,25 @@ func getDepositorBlockRewards(dep string) (*big.Int, error) {
 		depositor := common.HexToAddress(dep)
 		depositorBalance, err = instance.GetDepositorRewards(nil, depositor)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 	}
 
-	fmt.Println("BlockRewards", "Depositor", dep, "coins", weiToEther(depositorBalance).String(), "wei", depositorBalance)
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
-
 	return depositorBalance, nil
 }
 
 func getDepositorSlashings(dep string) (*big.Int, error) {
-	client, err := ethclient.Dial(rawURL)
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
 		return nil, err
 	}
 
 	var depositorSlashing *big.Int
-	var blockNumber uint64
-	if blockNumber < proofofstake.STAKING_CONTRACT_V2_CUTOFF_BLOCK {
+	stakingV2, err := isStakingContractV2(client)
+	if err != nil {
+		return nil, err
+	}
+	if stakingV2 == false {
 		contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 		instance, err := stakingv1.NewStaking(contractAddress, client)
 		if err != nil {
@@ -944,7 +888,7 @@ func getDepositorSlashings(dep string) (*big.Int, error) {
 		depositor := common.HexToAddress(dep)
 		depositorSlashing, err = instance.GetDepositorSlashings(nil, depositor)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 	} else {
 		contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
@@ -956,15 +900,10 @@ func getDepositorSlashings(dep string) (*big.Int, error) {
 		depositor := common.HexToAddress(dep)
 		depositorSlashing, err = instance.GetDepositorSlashings(nil, depositor)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 	}
 
-	fmt.Println("Slashing", "Depositor", dep, "coins", weiToEther(depositorSlashing).String(), "wei", depositorSlashing)
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
-
 	return depositorSlashing, nil
 }
 
@@ -982,137 +921,120 @@ type ValidatorDetails struct {
 	NilBlockCount      string         `json:"nilBlockCount" gencodec:"required"`
 }
 
-func listValidators() error {
-	if len(rawURL) == 0 {
-		return errors.New("DP_RAW_URL environment variable not specified")
-	}
+// ValidatorList is the output of the listvalidators command.
+type ValidatorList struct {
+	Validators            []*ValidatorDetails `json:"validators"`
+	TotalDepositedBalance string              `json:"totalDepositedBalance"`
+}
 
-	client, err := ethclient.Dial(rawURL)
+func listValidators() (*ValidatorList, error) {
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 	var validatorList []common.Address
 
-	blockNumber, err := client.BlockNumber(context.Background())
+	stakingV2, err := isStakingContractV2(client)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if blockNumber < proofofstake.STAKING_CONTRACT_V2_CUTOFF_BLOCK {
+	if stakingV2 == false {
 		instance, err := stakingv1.NewStaking(contractAddress, client)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		validatorList, err = instance.ListValidators(nil)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	} else {
 		instance, err := stakingv2.NewStaking(contractAddress, client)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		validatorList, err = instance.ListValidators(nil)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	totalDepositedBalance := big.NewInt(int64(0))
-
-	var validatorDetails *ValidatorDetails
-	var validatorDetailsList []*ValidatorDetails
+	validatorDetailsList := make([]*ValidatorDetails, 0, len(validatorList))
 
 	for i := 0; i < len(validatorList); i++ {
 		depositor, err := getDepositorOfValidator(validatorList[i].String())
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		if depositor.IsEqualTo(common.HexToAddress("0x0000000000000000000000000000000000000000000000000000000000000000")) {
+		if depositor.IsEqualTo(common.ZERO_ADDRESS) {
 			continue
 		}
 
 		balanceVal, err := getBalanceOfDepositor(depositor.String())
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		netBalance, err := getNetBalanceOfDepositor(depositor.String())
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		blockrewards, err := getDepositorBlockRewards(depositor.String())
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		blockslashing, err := getDepositorSlashings(depositor.String())
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		validatorDetails = &ValidatorDetails{
+		validatorDetailsList = append(validatorDetailsList, &ValidatorDetails{
 			Depositor:    depositor,
 			Validator:    validatorList[i],
 			Balance:      hexutil.EncodeBig(balanceVal),
 			NetBalance:   hexutil.EncodeBig(netBalance),
 			BlockRewards: hexutil.EncodeBig(blockrewards),
 			Slashings:    hexutil.EncodeBig(blockslashing),
-		}
-		validatorDetailsList = append(validatorDetailsList, validatorDetails)
+		})
 
 		totalDepositedBalance = totalDepositedBalance.Add(totalDepositedBalance, balanceVal)
-
-	}
-
-	for i := 0; i < len(validatorDetailsList); i++ {
-		validatorDetails := validatorDetailsList[i]
-
-		balance, _ := hexutil.DecodeBig(validatorDetails.Balance)
-		netBalance, _ := hexutil.DecodeBig(validatorDetails.NetBalance)
-		blockRewards, _ := hexutil.DecodeBig(validatorDetails.BlockRewards)
-		slashing, _ := hexutil.DecodeBig(validatorDetails.Slashings)
-
-		fmt.Println("Depositor ", validatorDetails.Depositor, "Validator ", validatorDetails.Validator, "Balance coins", weiToEther(balance).String(),
-			"NetBalance coins", weiToEther(netBalance).String(), "Block Rewards coins", weiToEther(blockRewards).String(), "Slashing Coins", weiToEther(slashing).String())
 	}
 
-	fmt.Println("Total validators", len(validatorDetailsList), "totalDepositedBalance", weiToEther(totalDepositedBalance).String())
-
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
-
-	return nil
+	return &ValidatorList{
+		Validators:            validatorDetailsList,
+		TotalDepositedBalance: hexutil.EncodeBig(totalDepositedBalance),
+	}, nil
 }
 
-func initiatePartialWithdrawal(key *signaturealgorithm.PrivateKey, amount string) error {
-	client, err := ethclient.Dial(rawURL)
+func initiatePartialWithdrawal(key *signaturealgorithm.PrivateKey, amount string) (common.Hash, error) {
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	fromAddress, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 	txnOpts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(123123))
 
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	txnOpts.From = fromAddress
@@ -1124,52 +1046,46 @@ func initiatePartialWithdrawal(key *signaturealgorithm.PrivateKey, amount string
 
 	contract, err := stakingv2.NewStaking(contractAddress, client)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	amountFlt, err := ParseBigFloat(amount)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 	amountWei := etherToWeiFloat(amountFlt)
 
 	tx, err := contract.InitiatePartialWithdrawal(txnOpts, amountWei)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
-	fmt.Println("Your request to initiate rewards withdrawal has been added to the queue for processing.")
-	fmt.Println("The transaction hash for tracking this request is: ", tx.Hash())
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
-
-	return nil
+	return tx.Hash(), nil
 }
 
-func completePartialWithdrawal(key *signaturealgorithm.PrivateKey) error {
+func completePartialWithdrawal(key *signaturealgorithm.PrivateKey) (common.Hash, error) {
 
-	client, err := ethclient.Dial(rawURL)
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	fromAddress, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
 
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 	txnOpts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(123123))
 
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	txnOpts.From = fromAddress
@@ -1182,44 +1098,38 @@ func completePartialWithdrawal(key *signaturealgorithm.PrivateKey) error {
 	var tx *types.Transaction
 	contract, err := stakingv2.NewStaking(contractAddress, client)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	tx, err = contract.CompletePartialWithdrawal(txnOpts)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
-	fmt.Println("Your request to complete rewards withdrawal has been added to the queue for processing.")
-	fmt.Println("The transaction hash for tracking this request is: ", tx.Hash())
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
-
-	return nil
+	return tx.Hash(), nil
 }
 
-func increaseDeposit(key *signaturealgorithm.PrivateKey, additionalAmount string) error {
-	client, err := ethclient.Dial(rawURL)
+func increaseDeposit(key *signaturealgorithm.PrivateKey, additionalAmount string) (common.Hash, error) {
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	fromAddress, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 	txnOpts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(123123))
 
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	txnOpts.From = fromAddress
@@ -1231,44 +1141,38 @@ func increaseDeposit(key *signaturealgorithm.PrivateKey, additionalAmount string
 
 	contract, err := stakingv2.NewStaking(contractAddress, client)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	tx, err := contract.IncreaseDeposit(txnOpts)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
-	fmt.Println("Your request to initiate rewards withdrawal has been added to the queue for processing.")
-	fmt.Println("The transaction hash for tracking this request is: ", tx.Hash())
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
-
-	return nil
+	return tx.Hash(), nil
 }
 
-func changeValidator(key *signaturealgorithm.PrivateKey, newValidatorAddress common.Address) error {
-	client, err := ethclient.Dial(rawURL)
+func changeValidator(key *signaturealgorithm.PrivateKey, newValidatorAddress common.Address) (common.Hash, error) {
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	fromAddress, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 	txnOpts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(123123))
 
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	txnOpts.From = fromAddress
@@ -1280,94 +1184,100 @@ func changeValidator(key *signaturealgorithm.PrivateKey, newValidatorAddress com
 
 	contract, err := stakingv2.NewStaking(contractAddress, client)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	tx, err := contract.ChangeValidator(txnOpts, newValidatorAddress)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
-	fmt.Println("Your request to change the validator has been added to the queue for processing.")
-	fmt.Println("The transaction hash for tracking this request is: ", tx.Hash())
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
-
-	return nil
+	return tx.Hash(), nil
 }
 
-func getStakingDetails(validatorAddress common.Address) error {
-	if len(rawURL) == 0 {
-		return errors.New("DP_RAW_URL environment variable not specified")
-	}
+// StakingDetails is the output of the getstakingdetails command.
+type StakingDetails struct {
+	Depositor          common.Address `json:"depositor"`
+	Validator          common.Address `json:"validator"`
+	Balance            *hexutil.Big   `json:"balance"`
+	NetBalance         *hexutil.Big   `json:"netBalance"`
+	BlockRewards       *hexutil.Big   `json:"blockRewards"`
+	Slashings          *hexutil.Big   `json:"slashings"`
+	IsValidationPaused bool           `json:"isValidationPaused"`
+	WithdrawalBlock    *hexutil.Big   `json:"withdrawalBlock"`
+	WithdrawalAmount   *hexutil.Big   `json:"withdrawalAmount"`
+	LastNilBlock       *hexutil.Big   `json:"lastNilBlock"`
+	NilBlockCount      *hexutil.Big   `json:"nilBlockCount"`
+}
 
-	client, err := ethclient.Dial(rawURL)
+func getStakingDetails(validatorAddress common.Address) (*StakingDetails, error) {
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 
-	blockNumber, err := client.BlockNumber(context.Background())
+	stakingV2, err := isStakingContractV2(client)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if blockNumber < proofofstake.STAKING_CONTRACT_V2_CUTOFF_BLOCK {
-		fmt.Println(nil)
-	} else {
-		instance, err := stakingv2.NewStaking(contractAddress, client)
-		if err != nil {
-			return err
-		}
-
-		stakingDetails, err := instance.GetStakingDetails(nil, validatorAddress)
-
-		if stakingDetails.Depositor.IsEqualTo(common.ZERO_ADDRESS) {
-			return nil
-		}
-
-		fmt.Println("Depositor ", stakingDetails.Depositor, " Validator ", stakingDetails.Validator)
-		fmt.Println("Last NiL Block ", stakingDetails.LastNilBlockNumber.String(), " Nil Block Count ", stakingDetails.NilBlockCount.String())
-		fmt.Println("Withdrawal Block ", stakingDetails.WithdrawalBlock.String())
-		fmt.Println("Withdrawal coins ", weiToEther(stakingDetails.WithdrawalAmount).String())
-		fmt.Println("Slashing coins", weiToEther(stakingDetails.Slashings).String())
-		fmt.Println("Rewards coins ", weiToEther(stakingDetails.BlockRewards).String())
-		fmt.Println("Staking Balance coins ", weiToEther(stakingDetails.Balance).String())
-		fmt.Println("Net Balance coins ", weiToEther(stakingDetails.NetBalance).String())
+	if stakingV2 == false {
+		return nil, errors.New("staking details are not available before the staking contract upgrade")
 	}
 
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
+	instance, err := stakingv2.NewStaking(contractAddress, client)
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	stakingDetails, err := instance.GetStakingDetails(nil, validatorAddress)
+	if err != nil {
+		return nil, err
+	}
+	if stakingDetails.Depositor.IsEqualTo(common.ZERO_ADDRESS) {
+		return nil, fmt.Errorf("%s is not a validator", validatorAddress)
+	}
+
+	return &StakingDetails{
+		Depositor:          stakingDetails.Depositor,
+		Validator:          stakingDetails.Validator,
+		Balance:            (*hexutil.Big)(stakingDetails.Balance),
+		NetBalance:         (*hexutil.Big)(stakingDetails.NetBalance),
+		BlockRewards:       (*hexutil.Big)(stakingDetails.BlockRewards),
+		Slashings:          (*hexutil.Big)(stakingDetails.Slashings),
+		IsValidationPaused: stakingDetails.IsValidationPaused,
+		WithdrawalBlock:    (*hexutil.Big)(stakingDetails.WithdrawalBlock),
+		WithdrawalAmount:   (*hexutil.Big)(stakingDetails.WithdrawalAmount),
+		LastNilBlock:       (*hexutil.Big)(stakingDetails.LastNilBlockNumber),
+		NilBlockCount:      (*hexutil.Big)(stakingDetails.NilBlockCount),
+	}, nil
 }
 
-func pauseValidation(key *signaturealgorithm.PrivateKey) error {
+func pauseValidation(key *signaturealgorithm.PrivateKey) (common.Hash, error) {
 
-	client, err := ethclient.Dial(rawURL)
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	fromAddress, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
 
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 	txnOpts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(123123))
 
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	txnOpts.From = fromAddress
@@ -1378,61 +1288,58 @@ func pauseValidation(key *signaturealgorithm.PrivateKey) error {
 	txnOpts.Value = etherToWeiFloat(val)
 
 	var tx *types.Transaction
-	var blockNumber uint64
-	if blockNumber < proofofstake.STAKING_CONTRACT_V2_CUTOFF_BLOCK {
+	stakingV2, err := isStakingContractV2(client)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if stakingV2 == false {
 		contract, err := stakingv1.NewStaking(contractAddress, client)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 
 		tx, err = contract.PauseValidation(txnOpts)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 	} else {
 		contract, err := stakingv2.NewStaking(contractAddress, client)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 
 		tx, err = contract.PauseValidation(txnOpts)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 	}
 
-	fmt.Println("Your request to pause validation has been added to the queue for processing.")
-	fmt.Println("The transaction hash for tracking this request is: ", tx.Hash())
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
-
-	return nil
+	return tx.Hash(), nil
 }
 
-func resumeValidation(key *signaturealgorithm.PrivateKey) error {
+func resumeValidation(key *signaturealgorithm.PrivateKey) (common.Hash, error) {
 
-	client, err := ethclient.Dial(rawURL)
+	client, err := ethclient.Dial(cfg.RPCURL)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	fromAddress, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
 
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	contractAddress := common.HexToAddress(staking.STAKING_CONTRACT)
 	txnOpts, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(123123))
 
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	txnOpts.From = fromAddress
@@ -1443,34 +1350,31 @@ func resumeValidation(key *signaturealgorithm.PrivateKey) error {
 	txnOpts.Value = etherToWeiFloat(val)
 
 	var tx *types.Transaction
-	var blockNumber uint64
-	if blockNumber < proofofstake.STAKING_CONTRACT_V2_CUTOFF_BLOCK {
+	stakingV2, err := isStakingContractV2(client)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if stakingV2 == false {
 		contract, err := stakingv1.NewStaking(contractAddress, client)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 
 		tx, err = contract.ResumeValidation(txnOpts)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 	} else {
 		contract, err := stakingv2.NewStaking(contractAddress, client)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 
-		tx, err = contract.PauseValidation(txnOpts)
+		tx, err = contract.ResumeValidation(txnOpts)
 		if err != nil {
-			return err
+			return common.Hash{}, err
 		}
 	}
 
-	fmt.Println("Your request to resume validation has been added to the queue for processing.")
-	fmt.Println("The transaction hash for tracking this request is: ", tx.Hash())
-	fmt.Println()
-
-	time.Sleep(1000 * time.Millisecond)
-
-	return nil
+	return tx.Hash(), nil
 }
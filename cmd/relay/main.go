@@ -18,10 +18,16 @@ import (
 	"net"
 	"net/http"
 	"os"
-	qcreadapi "github.com/DogeProtocol/dp/relay/qcreadapi"
-	qcwriteapi "github.com/DogeProtocol/dp/relay/qcwriteapi"
 	"strconv"
 	"strings"
+
+	"github.com/DogeProtocol/dp/ethdb/leveldb"
+	"github.com/DogeProtocol/dp/metrics"
+	"github.com/DogeProtocol/dp/metrics/prometheus"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/relay"
+	qcreadapi "github.com/DogeProtocol/dp/relay/qcreadapi"
+	qcwriteapi "github.com/DogeProtocol/dp/relay/qcwriteapi"
 )
 
 type Config struct {
@@ -29,9 +35,20 @@ type Config struct {
 	Ip		string `json:"ip"`
 	Port	string `json:"port"`
 	DpUrl   string `json:"dpurl"`
+	DpUrls  []string `json:"dpurls"`
+	DpStreamUrls []string `json:"dpStreamUrls"`
 	CorsAllowedOrigins    string `json:"corsAllowedOrigins"`
 	EnableAuth bool `json:"enableAuth"`
 	ApiKeys string `json:"apiKeys"`
+	IndexDir string `json:"indexDir"`
+	ApiKeysFile string `json:"apiKeysFile"`
+	RateLimit relay.RateLimit `json:"rateLimit"`
+	IpRateLimit relay.RateLimit `json:"ipRateLimit"`
+	EndpointRateLimits map[string]relay.RateLimit `json:"endpointRateLimits"`
+	EndpointQuotas map[string]relay.Quota `json:"endpointQuotas"`
+	TrustForwardedFor bool `json:"trustForwardedFor"`
+	TrustedProxies []string `json:"trustedProxies"`
+	SignatureSchemes params.SignatureSchemes `json:"signatureSchemes"`
 }
 
 type Configs struct {
@@ -57,14 +74,27 @@ func main() {
 		return
 	}
 
+	// Metrics are collected only if they are served, and have to be enabled before any
+	// of them is created
+	for _, config := range configs {
+		if strings.EqualFold(config.Api, "metrics") {
+			metrics.Enabled = true
+		}
+	}
+
+	// Configs with the same upstream nodes share a pool, so that the read and write apis
+	// keep a single connection to each node
+	pools := make(map[string]*relay.UpstreamPool)
+
 	for _, config := range configs{
 		api := config.Api
 		ip := config.Ip
 		port := config.Port
-		dpUrl := config.DpUrl
+		dpUrls := config.DpUrls
+		if len(config.DpUrl) > 0 {
+			dpUrls = append([]string{config.DpUrl}, dpUrls...)
+		}
 		corsAllowedOrigins := config.CorsAllowedOrigins
-		enableAuth := config.EnableAuth
-		apiKeys := config.ApiKeys
 
 		if net.ParseIP(ip) == nil {
 			fmt.Println("Check configuration ip value ", ip)
@@ -76,12 +106,80 @@ func main() {
 			return
 		}
 
+		if strings.EqualFold(api ,"metrics") {
+			go metricsApi(ip, port)
+			continue
+		}
+
+		if len(dpUrls) == 0 {
+			fmt.Println("Check configuration dpurl value")
+			return
+		}
+
+		access, err := relay.NewAccessControl(relay.AccessConfig{
+			EnableAuth:        config.EnableAuth,
+			ApiKeys:           config.ApiKeys,
+			ApiKeysFile:       config.ApiKeysFile,
+			KeyLimit:          config.RateLimit,
+			IpLimit:           config.IpRateLimit,
+			EndpointLimits:    config.EndpointRateLimits,
+			EndpointQuotas:    config.EndpointQuotas,
+			TrustForwardedFor: config.TrustForwardedFor,
+			TrustedProxies:    config.TrustedProxies,
+		})
+		if err != nil {
+			fmt.Println("Check configuration access values ", config.ApiKeysFile, err.Error())
+			return
+		}
+
+		key := strings.Join(dpUrls, ",")
+		pool, ok := pools[key]
+		if !ok {
+			pool, err = relay.NewUpstreamPool(dpUrls)
+			if err != nil {
+				fmt.Println("Check configuration dpurl value ", key, err.Error())
+				return
+			}
+			pools[key] = pool
+		}
+
 		if strings.EqualFold(api ,"read") {
-			go qcReadApi(ip, port, dpUrl, corsAllowedOrigins,enableAuth,apiKeys)
+			var addressIndex *relay.AddressIndex
+			if len(config.IndexDir) > 0 {
+				db, err := leveldb.New(config.IndexDir, 16, 16, "relay/addressindex/", false)
+				if err != nil {
+					fmt.Println("Check configuration indexDir value ", config.IndexDir, err.Error())
+					return
+				}
+				addressIndex = relay.NewAddressIndex(db, pool)
+				addressIndex.Start()
+			}
+			// The streams follow the nodes over WebSocket or IPC if given, as subscriptions
+			// are not supported over HTTP
+			streamPool := pool
+			if len(config.DpStreamUrls) > 0 {
+				streamKey := strings.Join(config.DpStreamUrls, ",")
+				streamPool, ok = pools[streamKey]
+				if !ok {
+					streamPool, err = relay.NewUpstreamPool(config.DpStreamUrls)
+					if err != nil {
+						fmt.Println("Check configuration dpStreamUrls value ", streamKey, err.Error())
+						return
+					}
+					pools[streamKey] = streamPool
+				}
+			}
+			streams := relay.NewStreamHub(streamPool)
+			streams.Start()
+			go qcReadApi(ip, port, pool, addressIndex, streams, corsAllowedOrigins, access)
 		}
 
 		if strings.EqualFold(api ,"write") {
-			go qcWriteApi(ip, port, dpUrl, corsAllowedOrigins,enableAuth,apiKeys)
+			if err := config.SignatureSchemes.Check(); err != nil {
+				fmt.Println("Check configuration signatureSchemes value ", err.Error())
+				return
+			}
+			go qcWriteApi(ip, port, pool, config.SignatureSchemes, corsAllowedOrigins, access)
 		}
 	}
 
@@ -89,24 +187,29 @@ func main() {
 	<-make(chan int)
 }
 
-func qcReadApi(ip string, port string, dpUrl string, corsAllowedOrigins string, enableAuth bool, apiKeys string) {
-	ReadApiAPIService := qcreadapi.NewReadApiAPIService(dpUrl)
-	ReadApiAPIController := qcreadapi.NewReadApiAPIController(ReadApiAPIService, corsAllowedOrigins, enableAuth, apiKeys)
+func qcReadApi(ip string, port string, upstreams *relay.UpstreamPool, addressIndex *relay.AddressIndex, streams *relay.StreamHub, corsAllowedOrigins string, access *relay.AccessControl) {
+	ReadApiAPIService := qcreadapi.NewReadApiAPIService(upstreams, addressIndex, streams)
+	ReadApiAPIController := qcreadapi.NewReadApiAPIController(ReadApiAPIService, corsAllowedOrigins, access)
 	readRouter := qcreadapi.NewRouter(ReadApiAPIController)
 
-	fmt.Println("Read api server is listening on : ", ip + ":" + port, "dpUrl" + ":" + dpUrl, "corsAllowedOrigins" + ":" + corsAllowedOrigins)
+	fmt.Println("Read api server is listening on : ", ip + ":" + port, "dpUrls" + ":" + strings.Join(upstreams.Urls(), ","), "corsAllowedOrigins" + ":" + corsAllowedOrigins)
 	http.ListenAndServe(ip + ":" + port, readRouter)
 }
 
-func qcWriteApi(ip string, port string, dpUrl string, corsAllowedOrigins string, enableAuth bool, apiKeys string) {
-	WriteApiAPIService := qcwriteapi.NewWriteApiAPIService(dpUrl)
-	WriteApiAPIController := qcwriteapi.NewWriteApiAPIController(WriteApiAPIService, corsAllowedOrigins, enableAuth, apiKeys)
+func qcWriteApi(ip string, port string, upstreams *relay.UpstreamPool, signatureSchemes params.SignatureSchemes, corsAllowedOrigins string, access *relay.AccessControl) {
+	WriteApiAPIService := qcwriteapi.NewWriteApiAPIService(upstreams, signatureSchemes)
+	WriteApiAPIController := qcwriteapi.NewWriteApiAPIController(WriteApiAPIService, corsAllowedOrigins, access)
 	writeRouter := qcwriteapi.NewRouter(WriteApiAPIController)
 
-	fmt.Println("Write api server is listening on : ", ip + ":" + port, "dpUrl" + ":" + dpUrl, "corsAllowedOrigins" + ":" + corsAllowedOrigins)
+	fmt.Println("Write api server is listening on : ", ip + ":" + port, "dpUrls" + ":" + strings.Join(upstreams.Urls(), ","), "corsAllowedOrigins" + ":" + corsAllowedOrigins)
 	http.ListenAndServe(ip + ":" + port,  writeRouter)
 }
 
+func metricsApi(ip string, port string) {
+	fmt.Println("Metrics server is listening on : ", ip + ":" + port)
+	http.ListenAndServe(ip + ":" + port, prometheus.Handler(metrics.DefaultRegistry))
+}
+
 func readConfigJsonDataFile(filename string)  ([]Config, error) {
 	if _, err := os.Stat(filename); err != nil {
 		return nil, errors.New("File not found " + filename)
@@ -34,6 +34,7 @@ import (
 	"github.com/DogeProtocol/dp/core/rawdb"
 	"github.com/DogeProtocol/dp/core/types"
 	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
 	"github.com/DogeProtocol/dp/eth/ethconfig"
 	"github.com/DogeProtocol/dp/ethdb"
 	"github.com/DogeProtocol/dp/internal/debug"
@@ -69,7 +70,7 @@ func Fatalf(format string, args ...interface{}) {
 
 func StartNode(ctx *cli.Context, stack *node.Node) {
 	if stack.Config().EnableBackups {
-		_, err := backupmanager.NewBackupManager(stack.InstanceDir())
+		_, err := backupmanager.NewBackupManager(stack.InstanceDir(), makeBackupConfig(stack.Config()))
 		if err != nil {
 			Fatalf("Error starting protocol stack (backup manager initialize failed: %v", err)
 		}
@@ -115,6 +116,56 @@ func StartNode(ctx *cli.Context, stack *node.Node) {
 	}()
 }
 
+// makeBackupConfig creates the retention and rotation config of the backup manager.
+// Segments are signed with the node key.
+func makeBackupConfig(cfg *node.Config) *backupmanager.Config {
+	config := &backupmanager.Config{
+		RetentionBlocks: cfg.BackupRetentionBlocks,
+		RetentionAge:    cfg.BackupRetentionAge,
+		SegmentBlocks:   cfg.BackupSegmentBlocks,
+		TrustedSigners:  MakeBackupTrustedSigners(cfg, false),
+	}
+	// The node key is loaded even if no segments are sealed, so that the segments sealed
+	// before are trusted
+	key, err := cfg.NodeKey()
+	if err != nil {
+		Fatalf("Failed to load the key to sign backup segments with: %v", err)
+	}
+	config.SigningKey = key
+	if cfg.BackupSinkDir != "" {
+		sink, err := backupmanager.NewLocalSink(cfg.BackupSinkDir)
+		if err != nil {
+			Fatalf("Failed to create backup sink: %v", err)
+		}
+		config.Sinks = append(config.Sinks, sink)
+	}
+	return config
+}
+
+// MakeBackupTrustedSigners returns the addresses whose backup segments are trusted. If
+// withNodeKey is set, the address of the node key is included.
+func MakeBackupTrustedSigners(cfg *node.Config, withNodeKey bool) []common.Address {
+	var signers []common.Address
+	if withNodeKey {
+		key, err := cfg.NodeKey()
+		if err != nil {
+			Fatalf("Failed to load the node key: %v", err)
+		}
+		signer, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
+		if err != nil {
+			Fatalf("Failed to derive the address of the node key: %v", err)
+		}
+		signers = append(signers, signer)
+	}
+	for _, signer := range cfg.BackupTrustedSigners {
+		if common.IsHexAddress(signer) == false {
+			Fatalf("Invalid backup trusted signer: %s", signer)
+		}
+		signers = append(signers, common.HexToAddress(signer))
+	}
+	return signers
+}
+
 func monitorFreeDiskSpace(sigc chan os.Signal, path string, freeDiskSpaceCritical uint64) {
 	for {
 		freeSpace, err := getFreeDiskSpace(path)
@@ -234,6 +285,117 @@ func missingBlocks(chain *core.BlockChain, blocks []*types.Block) []*types.Block
 	return nil
 }
 
+// RestoreChain imports the blocks from first to last of a backup into the chain, in
+// batches, skipping the ones that are already present.
+func RestoreChain(chain *core.BlockChain, backup *backupmanager.BackupManager, first uint64, last uint64) error {
+	// Watch for Ctrl-C while the restore is running.
+	// If a signal is received, the restore will stop at the next batch.
+	interrupt := make(chan os.Signal, 1)
+	stop := make(chan struct{})
+	signal.Notify(interrupt, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	defer close(interrupt)
+	go func() {
+		if _, ok := <-interrupt; ok {
+			log.Info("Interrupted during restore, stopping at next batch")
+		}
+		close(stop)
+	}()
+	checkInterrupt := func() bool {
+		select {
+		case <-stop:
+			return true
+		default:
+			return false
+		}
+	}
+
+	log.Info("Restoring blockchain from backup", "first", first, "last", last)
+
+	// don't restore the genesis block
+	if first == 0 {
+		first = 1
+	}
+	blocks := make(types.Blocks, 0, importBatchSize)
+	batch := 0
+	importBatch := func() error {
+		// Import the batch.
+		if checkInterrupt() {
+			return fmt.Errorf("interrupted")
+		}
+		defer func() {
+			blocks = blocks[:0]
+			batch++
+		}()
+		missing := missingBlocks(chain, blocks)
+		if len(missing) == 0 {
+			log.Info("Skipping batch as all blocks present", "batch", batch, "first", blocks[0].Hash(), "last", blocks[len(blocks)-1].Hash())
+			return nil
+		}
+		if n, err := chain.InsertChain(missing); err != nil {
+			return fmt.Errorf("invalid block %d: %v", missing[n].NumberU64(), err)
+		}
+		log.Info("Restored batch", "batch", batch, "number", blocks[len(blocks)-1].NumberU64())
+		return nil
+	}
+	err := backup.ForEachBlock(first, last, func(block *types.Block) error {
+		if blocks = append(blocks, block); len(blocks) == importBatchSize {
+			return importBatch()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(blocks) > 0 {
+		return importBatch()
+	}
+	return nil
+}
+
+// RestoreTransactions appends the transactions of a backup that can still be executed on
+// top of the chain head to the transaction journal at journalPath. The node adds them to
+// its transaction pool as local transactions when it starts. The number of transactions
+// written is returned.
+func RestoreTransactions(chain *core.BlockChain, backup *backupmanager.BackupManager, journalPath string) (int, error) {
+	statedb, err := chain.State()
+	if err != nil {
+		return 0, err
+	}
+	signer := types.LatestSigner(chain.Config())
+
+	fh, err := os.OpenFile(journalPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer fh.Close()
+
+	restored, skipped := 0, 0
+	err = backup.ForEachTransaction(func(tx *types.Transaction) error {
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			log.Debug("Skipping backed up transaction with invalid signature", "hash", tx.Hash(), "err", err)
+			skipped++
+			return nil
+		}
+		// Mined transactions, and the ones replaced by a mined transaction, are stale
+		if statedb.GetNonce(from) > tx.Nonce() {
+			skipped++
+			return nil
+		}
+		if err := rlp.Encode(fh, tx); err != nil {
+			return err
+		}
+		restored++
+		return nil
+	})
+	if err != nil {
+		return restored, err
+	}
+	log.Info("Restored backed up transactions", "journal", journalPath, "transactions", restored, "skipped", skipped)
+	return restored, nil
+}
+
 // ExportChain exports a blockchain into the specified file, truncating any data
 // already present in the file.
 func ExportChain(blockchain *core.BlockChain, fn string) error {
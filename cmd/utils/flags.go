@@ -739,6 +739,26 @@ var (
 		Name:  "enablebackup",
 		Usage: "Whether to enable backups og blocks, transactions etc.",
 	}
+	BackupRetentionBlocksFlag = cli.Uint64Flag{
+		Name:  "backup.retention.blocks",
+		Usage: "Number of most recent blocks kept in the backup stores (0 = keep all)",
+	}
+	BackupRetentionAgeFlag = cli.DurationFlag{
+		Name:  "backup.retention.age",
+		Usage: "Maximum age of the blocks kept in the backup stores (0 = keep all)",
+	}
+	BackupSegmentBlocksFlag = cli.Uint64Flag{
+		Name:  "backup.segment.blocks",
+		Usage: "Number of blocks per signed backup segment; blocks are only removed from the backup stores once sealed (0 = disabled)",
+	}
+	BackupSinkDirFlag = DirectoryFlag{
+		Name:  "backup.sink.dir",
+		Usage: "Directory that backup segments are copied to",
+	}
+	BackupTrustedSignersFlag = cli.StringFlag{
+		Name:  "backup.trustedsigners",
+		Usage: "Comma separated addresses whose backup segments are trusted, besides the node key",
+	}
 
 	RebroadcastCountFlag = cli.IntFlag{
 		Name:  "rebroadcastcount",
@@ -1224,6 +1244,21 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(EnableBackupsFlag.Name) {
 		cfg.EnableBackups = ctx.GlobalBool(EnableBackupsFlag.Name)
 	}
+	if ctx.GlobalIsSet(BackupRetentionBlocksFlag.Name) {
+		cfg.BackupRetentionBlocks = ctx.GlobalUint64(BackupRetentionBlocksFlag.Name)
+	}
+	if ctx.GlobalIsSet(BackupRetentionAgeFlag.Name) {
+		cfg.BackupRetentionAge = ctx.GlobalDuration(BackupRetentionAgeFlag.Name)
+	}
+	if ctx.GlobalIsSet(BackupSegmentBlocksFlag.Name) {
+		cfg.BackupSegmentBlocks = ctx.GlobalUint64(BackupSegmentBlocksFlag.Name)
+	}
+	if ctx.GlobalIsSet(BackupSinkDirFlag.Name) {
+		cfg.BackupSinkDir = ctx.GlobalString(BackupSinkDirFlag.Name)
+	}
+	if ctx.GlobalIsSet(BackupTrustedSignersFlag.Name) {
+		cfg.BackupTrustedSigners = SplitAndTrim(ctx.GlobalString(BackupTrustedSignersFlag.Name))
+	}
 
 	if ctx.GlobalIsSet(RebroadcastCountFlag.Name) {
 		cfg.RebroadcastCount = ctx.GlobalInt(RebroadcastCountFlag.Name)
@@ -5,10 +5,9 @@ import (
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/core/types"
 	"github.com/DogeProtocol/dp/crypto"
-	"github.com/DogeProtocol/dp/crypto/cryptobase"
-	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
 	"github.com/DogeProtocol/dp/eth/protocols/eth"
 	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/params"
 	"github.com/DogeProtocol/dp/rlp"
 	"math/big"
 	"strconv"
@@ -24,10 +23,21 @@ type PacketMap struct {
 }
 
 func ParseConsensusPackets(parentHash common.Hash, consensusPackets *[]eth.ConsensusPacket, filteredValidatorDepositMap map[common.Address]*big.Int,
-	blockNumber uint64, validatorDetailsMap *map[common.Address]*ValidatorDetailsV2, consensusContext common.Hash) (packetRoundMap map[byte]*PacketMap, err error) {
+	blockNumber uint64, validatorDetailsMap *map[common.Address]*ValidatorDetailsV2, consensusContext common.Hash, schedule *params.ProofOfStakeForkSchedule) (packetRoundMap map[byte]*PacketMap, err error) {
 	packetRoundMap = make(map[byte]*PacketMap)
 
 	packets := *consensusPackets
+
+	// The signatures are verified concurrently up front, and are then served from the
+	// signature cache in the order of the packets
+	verifyPackets := make([]*eth.ConsensusPacket, 0, len(packets))
+	for i := range packets {
+		if packets[i].ParentHash.IsEqualTo(parentHash) {
+			verifyPackets = append(verifyPackets, &packets[i])
+		}
+	}
+	verifyPacketSignatures(verifyPackets)
+
 	for index, packet := range packets {
 		if packet.ParentHash.IsEqualTo(parentHash) == false {
 			return nil, errors.New("unexpected parenthash")
@@ -37,11 +47,6 @@ func ParseConsensusPackets(parentHash common.Hash, consensusPackets *[]eth.Conse
 			return nil, errors.New("invalid consensus packet, nil data")
 		}
 
-		dataToVerify := append(packet.ParentHash.Bytes(), packet.ConsensusData...)
-		digestHash := crypto.Keccak256(dataToVerify)
-		var pubKey *signaturealgorithm.PublicKey
-		var err error
-
 		var startIndex int
 		if packet.ConsensusData[0] >= MinConsensusNetworkProtocolVersion {
 			startIndex = 2
@@ -50,28 +55,12 @@ func ParseConsensusPackets(parentHash common.Hash, consensusPackets *[]eth.Conse
 		}
 
 		packetType := ConsensusPacketType(packet.ConsensusData[startIndex-1])
-		if packetType == CONSENSUS_PACKET_TYPE_PROPOSE_BLOCK && len(packet.Signature) != cryptobase.SigAlg.SignatureWithPublicKeyLength() { //for verify, it is ok not to check the blockNumber for full
-			pubKey, err = cryptobase.SigAlg.PublicKeyFromSignatureWithContext(digestHash, packet.Signature, FULL_SIGN_CONTEXT)
-			if err != nil {
-				return nil, InvalidPacketErr
-			}
-
-			if cryptobase.SigAlg.VerifyWithContext(pubKey.PubData, digestHash, packet.Signature, []byte{crypto.DILITHIUM_ED25519_SPHINCS_FULL_ID}) == false {
-				return nil, InvalidPacketErr
-			}
-		} else {
-			pubKey, err = cryptobase.SigAlg.PublicKeyFromSignature(digestHash, packet.Signature)
-			if err != nil {
-				return nil, err
-			}
-			if cryptobase.SigAlg.Verify(pubKey.PubData, digestHash, packet.Signature) == false {
-				return nil, InvalidPacketErr
-			}
+		if err := checkPacketSignatureScheme(&packet, blockNumber, schedule); err != nil {
+			return nil, err
 		}
-
-		validator, err := cryptobase.SigAlg.PublicKeyToAddress(pubKey)
+		//for verify, it is ok not to check the blockNumber for full
+		validator, err := recoverPacketSigner(&packet, packetType)
 		if err != nil {
-			log.Trace("invalid 3", "err", err)
 			return nil, err
 		}
 
@@ -92,7 +81,7 @@ func ParseConsensusPackets(parentHash common.Hash, consensusPackets *[]eth.Conse
 				return nil, errors.New("invalid round d")
 			}
 
-			blockProposer, err := getBlockProposer(parentHash, &filteredValidatorDepositMap, details.Round, validatorDetailsMap, blockNumber, consensusContext)
+			blockProposer, err := getBlockProposer(parentHash, &filteredValidatorDepositMap, details.Round, validatorDetailsMap, blockNumber, consensusContext, schedule)
 			if err != nil {
 				return nil, err
 			}
@@ -354,29 +343,42 @@ func ValidatePackets(parentHash common.Hash, round byte, packetMap *PacketMap, v
 		return errors.New("precommit low deposit")
 	}
 
+	commitDepositValue, _, err := getCommitDepositValues(commitHash, packetMap, valMap)
+	if err != nil {
+		return err
+	}
+
+	if commitDepositValue.Cmp(minDepositRequired) < 0 {
+		return errors.New("precommit low deposit")
+	}
+
+	return nil
+}
+
+// getCommitDepositValues returns the deposit of the validators with a commit packet in the
+// packet map, and the deposit of the ones among them that committed to the commit hash.
+func getCommitDepositValues(commitHash common.Hash, packetMap *PacketMap, valMap map[common.Address]*big.Int) (*big.Int, *big.Int, error) {
 	commitDepositValue := big.NewInt(0)
+	matchingDepositValue := big.NewInt(0)
 	for v, commitDetails := range packetMap.commitDetailsMap {
 		depositValue, ok := valMap[v]
 		if ok == false {
-			return errors.New("unrecognized validator")
+			return nil, nil, errors.New("unrecognized validator")
 		}
 
-		if commitDetails.CommitHash.IsEqualTo(commitHash) == false {
-			errors.New("invalid commithash")
+		if commitDetails.CommitHash.IsEqualTo(commitHash) {
+			matchingDepositValue = common.SafeAddBigInt(matchingDepositValue, depositValue)
 		}
 
 		commitDepositValue = common.SafeAddBigInt(commitDepositValue, depositValue)
 	}
 
-	if commitDepositValue.Cmp(minDepositRequired) < 0 {
-		return errors.New("precommit low deposit")
-	}
-
-	return nil
+	return commitDepositValue, matchingDepositValue, nil
 }
 
 func ValidateBlockConsensusDataInner(txns []common.Hash, parentHash common.Hash, blockConsensusData *BlockConsensusData, blockAdditionalConsensusData *BlockAdditionalConsensusData,
-	validatorDepositMap *map[common.Address]*big.Int, blockNumber uint64, valDetailsMap *map[common.Address]*ValidatorDetailsV2, consensusContext common.Hash) error {
+	validatorDepositMap *map[common.Address]*big.Int, blockNumber uint64, valDetailsMap *map[common.Address]*ValidatorDetailsV2, consensusContext common.Hash, window *EquivocationWindow,
+	schedule *params.ProofOfStakeForkSchedule) error {
 	if blockConsensusData.Round < 1 {
 		return errors.New("ValidateBlockConsensusData round min")
 	}
@@ -422,7 +424,12 @@ func ValidateBlockConsensusDataInner(txns []common.Hash, parentHash common.Hash,
 		filteredValidatorDepositMap[v] = valMap[v]
 	}
 
-	if blockNumber >= BLOCK_PROPOSER_NIL_BLOCK_START_BLOCK {
+	err = ValidateEquivocationEvidence(window, blockConsensusData.Evidence, filteredValidatorDepositMap, blockNumber, schedule)
+	if err != nil {
+		return err
+	}
+
+	if blockNumber >= schedule.BlockProposerNilBlockStartBlock {
 		for valAddr, valDetails := range *valDetailsMap {
 			if valDetails.IsValidationPaused {
 				delete(*valDetailsMap, valAddr)
@@ -437,7 +444,7 @@ func ValidateBlockConsensusDataInner(txns []common.Hash, parentHash common.Hash,
 
 	roundBlockValidators := make(map[byte]common.Address)
 	for r := byte(1); r <= blockConsensusData.Round; r++ {
-		roundBlockValidators[r], err = getBlockProposer(parentHash, &filteredValidatorDepositMap, r, valDetailsMap, blockNumber, consensusContext)
+		roundBlockValidators[r], err = getBlockProposer(parentHash, &filteredValidatorDepositMap, r, valDetailsMap, blockNumber, consensusContext, schedule)
 		if err != nil {
 			return err
 		}
@@ -448,7 +455,7 @@ func ValidateBlockConsensusDataInner(txns []common.Hash, parentHash common.Hash,
 		return errors.New("nil ConsensusPackets")
 	}
 
-	packetRoundMap, err := ParseConsensusPackets(parentHash, &blockAdditionalConsensusData.ConsensusPackets, filteredValidatorDepositMap, blockNumber, valDetailsMap, consensusContext)
+	packetRoundMap, err := ParseConsensusPackets(parentHash, &blockAdditionalConsensusData.ConsensusPackets, filteredValidatorDepositMap, blockNumber, valDetailsMap, consensusContext, schedule)
 	if err != nil {
 		return err
 	}
@@ -565,8 +572,8 @@ func ValidateBlockConsensusDataInner(txns []common.Hash, parentHash common.Hash,
 
 // In this function, absolute time cannot be validated, since this function can get called at a different time, for example when new node is created and is reading old blocks
 // Hence only basic checks are allowed
-func ValidateBlockProposalTime(blockNumber uint64, proposedTime uint64) bool {
-	if blockNumber == 1 || blockNumber%BLOCK_PERIOD_TIME_CHANGE == 0 || blockNumber >= BLOCK_TIME_ORIG_START_BLOCK {
+func ValidateBlockProposalTime(blockNumber uint64, proposedTime uint64, schedule *params.ProofOfStakeForkSchedule) bool {
+	if blockNumber == 1 || blockNumber%BLOCK_PERIOD_TIME_CHANGE == 0 || blockNumber >= schedule.BlockTimeOrigStartBlock {
 		if proposedTime == 0 {
 			return true
 		}
@@ -585,7 +592,8 @@ func ValidateBlockProposalTime(blockNumber uint64, proposedTime uint64) bool {
 }
 
 func ValidateBlockConsensusData(block *types.Block, validatorDepositMap *map[common.Address]*big.Int,
-	valDetailsMap *map[common.Address]*ValidatorDetailsV2, getBlockConsensusContext GetBlockConsensusContextFn, getValidatorsFn GetValidatorsFn) error {
+	valDetailsMap *map[common.Address]*ValidatorDetailsV2, getBlockConsensusContext GetBlockConsensusContextFn, getValidatorsFn GetValidatorsFn, window *EquivocationWindow,
+	schedule *params.ProofOfStakeForkSchedule) error {
 	header := block.Header()
 
 	if header.ConsensusData == nil || header.UnhashedConsensusData == nil {
@@ -623,32 +631,42 @@ func ValidateBlockConsensusData(block *types.Block, validatorDepositMap *map[com
 		txnList = make([]common.Hash, 0)
 	}
 
-	if ValidateBlockProposalTime(block.Number().Uint64(), blockConsensusData.BlockTime) == false {
+	if ValidateBlockProposalTime(block.Number().Uint64(), blockConsensusData.BlockTime, schedule) == false {
 		log.Warn("ValidateBlockProposalTime failed", "blockNumber", block.Number().Uint64(), "proposedTime", blockConsensusData.BlockTime)
 		return errors.New("ValidateBlockProposalTime failed")
 	}
 
-	//Consensus Context
+	consensusContext, err := GetBlockConsensusContextHash(header.Number.Uint64(), header.ParentHash, getBlockConsensusContext, getValidatorsFn, schedule)
+	if err != nil {
+		return err
+	}
+
+	return ValidateBlockConsensusDataInner(txnList, header.ParentHash, blockConsensusData, blockAdditionalConsensusData, validatorDepositMap, header.Number.Uint64(), valDetailsMap, consensusContext, window, schedule)
+}
+
+// GetBlockConsensusContextHash returns the consensus context that the block proposers of a
+// block are selected with. It is empty for blocks before the context based fork.
+func GetBlockConsensusContextHash(blockNumber uint64, parentHash common.Hash, getBlockConsensusContext GetBlockConsensusContextFn,
+	getValidatorsFn GetValidatorsFn, schedule *params.ProofOfStakeForkSchedule) (common.Hash, error) {
 	var consensusContext common.Hash
-	blockNumber := header.Number.Uint64()
-	if blockNumber >= CONTEXT_BASED_START_BLOCK {
-		validators, err := getValidatorsFn(header.ParentHash)
+	if blockNumber >= schedule.ContextBasedStartBlock {
+		validators, err := getValidatorsFn(parentHash)
 		if err != nil {
-			return err
+			return consensusContext, err
 		}
 
 		preFilterValidatorCount := len(validators)
 
-		contextKey, err := GetBlockConsensusContextKeyForBlock(blockNumber)
+		contextKey, err := GetBlockConsensusContextKeyForBlock(blockNumber, schedule)
 		if err != nil {
-			return err
+			return consensusContext, err
 		}
-		blockContext, err := getBlockConsensusContext(contextKey, header.ParentHash)
+		blockContext, err := getBlockConsensusContext(contextKey, parentHash)
 		if err != nil {
-			return err
+			return consensusContext, err
 		}
 		consensusContext = crypto.Keccak256Hash(blockContext[:], []byte(strconv.Itoa(preFilterValidatorCount)))
 	}
 
-	return ValidateBlockConsensusDataInner(txnList, header.ParentHash, blockConsensusData, blockAdditionalConsensusData, validatorDepositMap, header.Number.Uint64(), valDetailsMap, consensusContext)
+	return consensusContext, nil
 }
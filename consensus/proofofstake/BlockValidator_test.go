@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/params"
 	"github.com/DogeProtocol/dp/rlp"
 	"github.com/DogeProtocol/dp/trie"
 	"math/big"
@@ -101,7 +102,7 @@ func BlockNilTest(blockConsensusData *BlockConsensusData, blockAdditionalConsens
 
 	block := types.NewBlock(header, txs[:], receipts, trie.NewStackTrie(nil))
 	valMap := make(map[common.Address]*big.Int)
-	err := ValidateBlockConsensusData(block, &valMap, nil, DummyGetBlockConsensusContext, nil)
+	err := ValidateBlockConsensusData(block, &valMap, nil, DummyGetBlockConsensusContext, nil, NewEquivocationWindow(header.ParentHash), params.DefaultProofOfStakeForkSchedule)
 	if err == nil || strings.Compare(err.Error(), expectedError) != 0 {
 		debug.PrintStack()
 		t.Fatalf("BlockNilTest failed")
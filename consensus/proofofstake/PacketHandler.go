@@ -5,11 +5,10 @@ import (
 	"errors"
 	"github.com/DogeProtocol/dp/accounts"
 	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/consensus/proofofstake/finality"
 	"github.com/DogeProtocol/dp/crypto"
-	"github.com/DogeProtocol/dp/crypto/cryptobase"
-	"github.com/DogeProtocol/dp/crypto/hybrideds"
-	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
 	"github.com/DogeProtocol/dp/eth/protocols/eth"
+	"github.com/DogeProtocol/dp/ethdb"
 	"github.com/DogeProtocol/dp/handler"
 	"github.com/DogeProtocol/dp/log"
 	"github.com/DogeProtocol/dp/node"
@@ -75,6 +74,12 @@ type ConsensusHandler struct {
 	latestBlockMutex  sync.RWMutex
 
 	peerHandler *PeerHandler
+
+	schedule *params.ProofOfStakeForkSchedule
+	journal  *voteJournal
+
+	clock          Clock
+	synchronousP2P bool
 }
 
 type PacketStats struct {
@@ -90,6 +95,8 @@ type BlockConsensusData struct {
 	Round                 byte
 	SelectedTransactions  []common.Hash `json:"selectedTransactions" gencodec:"required"` //this will be a super-set of transactions that actually got executed
 	BlockTime             uint64        `json:"blockTime" gencodec:"required"`
+
+	Evidence []EquivocationEvidence `json:"evidence" rlp:"optional"`
 }
 
 type BlockAdditionalConsensusData struct {
@@ -102,7 +109,7 @@ var BLOCK_TIMEOUT_MS = int64(60000)
 var FULL_BLOCK_TIMEOUT_MS = int64(90000)
 var ACK_BLOCK_TIMEOUT_MS = 300000 //relative to start of block locally
 var BLOCK_CLEANUP_TIME_MS = int64(900000)
-var MAX_ROUND = byte(2)
+var MAX_ROUND = finality.MaxRound
 var BROADCAST_RESEND_DELAY = int64(10000)
 var BROADCAST_CLEANUP_DELAY = int64(1800000)
 var CONSENSUS_DATA_REQUEST_RESEND_DELAY = int64(30000)
@@ -114,7 +121,7 @@ var STALE_BLOCK_WARN_TIME = int64(1800 * 1000)
 var BLOCK_PROPOSER_OFFLINE_NIL_BLOCK_MULTIPLIER = uint64(2)
 var BLOCK_PROPOSER_OFFLINE_MAX_DELAY_BLOCK_COUNT = uint64(1024)
 
-var MIN_VALIDATORS int = 3
+var MIN_VALIDATORS int = finality.MinValidators
 
 type BlockRoundState byte
 type VoteType byte
@@ -122,7 +129,7 @@ type ConsensusPacketType byte
 type RequestConsensusDataType byte
 type NewRoundReason byte
 
-var InvalidPacketErr = errors.New("invalid packet")
+var InvalidPacketErr = finality.InvalidPacketErr
 var OutOfOrderPackerErr = errors.New("packet received out of order")
 var UnknownParentHashErr = errors.New("unknown parent hash")
 
@@ -157,7 +164,7 @@ const (
 )
 
 const (
-	MAX_VALIDATORS int = 128
+	MAX_VALIDATORS int = finality.MaxValidators
 )
 
 const (
@@ -165,6 +172,7 @@ const (
 	NEW_ROUND_REASON_WAIT_ACK_BLOCK_PROPOSAL_TIMEOUT      NewRoundReason = 2
 	NEW_ROUND_REASON_WAIT_ACK_BLOCK_PROPOSAL_HIGHER_ROUND NewRoundReason = 3
 	NEW_ROUND_REASON_WAIT_PRECOMMIT_TIMEOUT               NewRoundReason = 4
+	NEW_ROUND_REASON_RESUMED                              NewRoundReason = 5
 )
 
 const (
@@ -178,9 +186,9 @@ var (
 )
 
 var (
-	MIN_VALIDATOR_DEPOSIT                               *big.Int       = params.EtherToWei(big.NewInt(5000000))
-	MIN_BLOCK_DEPOSIT                                   *big.Int       = params.EtherToWei(big.NewInt(500000000000))
-	MIN_BLOCK_TRANSACTION_WEIGHTED_PROPOSALS_PERCENTAGE *big.Int       = big.NewInt(70)
+	MIN_VALIDATOR_DEPOSIT                               *big.Int       = finality.MinValidatorDeposit
+	MIN_BLOCK_DEPOSIT                                   *big.Int       = finality.MinBlockDeposit
+	MIN_BLOCK_TRANSACTION_WEIGHTED_PROPOSALS_PERCENTAGE *big.Int       = big.NewInt(finality.CommitPercentage)
 	ZERO_HASH                                           common.Hash    = common.BytesToHash([]byte{0})
 	ZERO_ADDRESS                                        common.Address = common.BytesToAddress([]byte{0})
 )
@@ -240,6 +248,9 @@ type BlockStateDetails struct {
 	highestProposalRoundSeen          byte
 	consensusContext                  common.Hash
 
+	votes         map[voteKey]*eth.ConsensusPacket
+	equivocations map[common.Address]*EquivocationEvidence
+
 	//stats
 	proposalTime    int64
 	ackProposalTime int64
@@ -353,13 +364,42 @@ func NewConsensusPacketHandler() *ConsensusHandler {
 		blockStateDetailsMap: make(map[common.Hash]*BlockStateDetails),
 		outOfOrderPacketsMap: make(map[common.Hash]map[common.Hash]*OutOfOrderPacket),
 		timeStatMap:          timeStatMap,
+		schedule:             params.DefaultProofOfStakeForkSchedule,
+		clock:                systemClock{},
 	}
 
 	cph.peerHandler = NewPeerHandler(isConsensusRelay, cph.GetLatestBlockNumber)
+	cph.peerHandler.SetForkSchedule(cph.schedule)
 
 	return cph
 }
 
+// SetForkSchedule sets the proof-of-stake activation blocks the handler runs with,
+// replacing the mainnet schedule it is created with.
+func (cph *ConsensusHandler) SetForkSchedule(schedule *params.ProofOfStakeForkSchedule) {
+	cph.schedule = schedule
+	cph.peerHandler.SetForkSchedule(schedule)
+}
+
+// SetVoteJournal sets the database the handler journals its own consensus packets in.
+// Without it the packets are only kept in memory.
+func (cph *ConsensusHandler) SetVoteJournal(db ethdb.KeyValueStore) {
+	cph.journal = newVoteJournal(db)
+}
+
+// SetClock replaces the system clock that the handler takes the time from, for its
+// timeouts as well as the proposed block times.
+func (cph *ConsensusHandler) SetClock(clock Clock) {
+	cph.clock = clock
+}
+
+// SetSynchronousP2P makes the handler hand broadcasts and consensus data requests to
+// the P2PHandler on the calling goroutine instead of a new one. The P2PHandler must then
+// not call back into the handler before returning.
+func (cph *ConsensusHandler) SetSynchronousP2P(synchronous bool) {
+	cph.synchronousP2P = synchronous
+}
+
 func (cph *ConsensusHandler) SetValidatorsFunction(getValidatorsFn GetValidatorsFn) {
 	cph.getValidatorsFn = getValidatorsFn
 }
@@ -386,12 +426,12 @@ func (cph *ConsensusHandler) isValidator(parentHash common.Hash) (bool, error) {
 }
 
 func getBlockProposer(parentHash common.Hash, filteredValidatorDepositMap *map[common.Address]*big.Int, round byte,
-	validatorDetailsMap *map[common.Address]*ValidatorDetailsV2, blockNumber uint64, contextHash common.Hash) (common.Address, error) {
-	if blockNumber >= CONTEXT_BASED_START_BLOCK {
+	validatorDetailsMap *map[common.Address]*ValidatorDetailsV2, blockNumber uint64, contextHash common.Hash, schedule *params.ProofOfStakeForkSchedule) (common.Address, error) {
+	if blockNumber >= schedule.ContextBasedStartBlock {
 		return getBlockProposerV2(contextHash, validatorDetailsMap, round, blockNumber) //passing contextHash instead of parentHash
 	}
 
-	if blockNumber >= BLOCK_PROPOSER_NIL_BLOCK_START_BLOCK {
+	if blockNumber >= schedule.BlockProposerNilBlockStartBlock {
 		return getBlockProposerV2(parentHash, validatorDetailsMap, round, blockNumber)
 	}
 	var proposer common.Address
@@ -481,102 +521,20 @@ func getBlockProposerV2(contextHash common.Hash, validatorMap *map[common.Addres
 }
 
 func filterValidators(parentHash common.Hash, valDepMap *map[common.Address]*big.Int) (filteredValidators map[common.Address]bool, filteredDepositValue *big.Int, blockMinWeightedProposalsRequired *big.Int, err error) {
-	validatorsDepositMap := *valDepMap
-
-	totalDepositValue := big.NewInt(0)
-	valCount := 0
-	for val, depositValue := range validatorsDepositMap { //todo: this should be based on netBalance
-		if depositValue.Cmp(MIN_VALIDATOR_DEPOSIT) == -1 {
-			log.Trace("Skipping validator with low balance", "val", val, "depositValue", depositValue)
-			delete(validatorsDepositMap, val)
-			continue
-		}
-		totalDepositValue = common.SafeAddBigInt(totalDepositValue, depositValue)
-		valCount = valCount + 1
-	}
-
-	if valCount < MIN_VALIDATORS {
-		return nil, nil, nil, errors.New("number of validators less than minimum")
-	}
-
-	if totalDepositValue.Cmp(MIN_BLOCK_DEPOSIT) == -1 {
-		return nil, nil, nil, errors.New("min block deposit not met")
-	}
-
-	filteredValidators = make(map[common.Address]bool)
-
-	if len(validatorsDepositMap) <= MAX_VALIDATORS {
-		for validator := range validatorsDepositMap {
-			filteredValidators[validator] = true
-		}
-	} else {
-		rng, err := cryptobase.DRNG.InitializeWithSeed(parentHash)
-		if err != nil {
-			return nil, nil, nil, err
-		}
-
-		zero := big.NewInt(0)
-		byteMax := big.NewInt(255)
-		depositValueSoFar := big.NewInt(0)
-
-		validatorList := make([]common.Address, len(validatorsDepositMap))
-		ctr := 0
-		for validator, _ := range validatorsDepositMap {
-			validatorList[ctr] = validator
-			ctr = ctr + 1
-		}
-
-		sort.Slice(validatorList, func(i, j int) bool {
-			vi := crypto.Keccak256Hash(parentHash.Bytes(), validatorList[i].Bytes()).Bytes()
-			vj := crypto.Keccak256Hash(parentHash.Bytes(), validatorList[j].Bytes()).Bytes()
-			return bytes.Compare(vi, vj) == -1
-		})
-
-		for _, validator := range validatorList {
-			depositValue := validatorsDepositMap[validator]
-			randByte := big.NewInt(int64(rng.NextByte()))
-
-			//normalize depositValue to byte-max value since random generator only returns bytes
-			normalizedDepositValue := common.SafeDivBigInt(common.SafeMulBigInt(byteMax, depositValue), totalDepositValue)
-			if normalizedDepositValue.Cmp(zero) < 0 || normalizedDepositValue.Cmp(byteMax) > 0 {
-				return nil, nil, nil, errors.New("invalid normalizedDepositValue")
-			}
-
-			if normalizedDepositValue.Cmp(randByte) >= 0 {
-				filteredValidators[validator] = true
-				depositValueSoFar = common.SafeAddBigInt(depositValueSoFar, depositValue)
-			}
-		}
-
-		if len(filteredValidators) < MAX_VALIDATORS || MIN_BLOCK_DEPOSIT.Cmp(depositValueSoFar) > 0 {
-			for _, validator := range validatorList {
-				_, ok := filteredValidators[validator]
-				if ok == false {
-					//this needs optimization, since validators first in the list get the benefit
-					filteredValidators[validator] = true
-					depositValue := validatorsDepositMap[validator]
-					depositValueSoFar = common.SafeAddBigInt(depositValueSoFar, depositValue)
-					if len(filteredValidators) == MAX_VALIDATORS && MIN_BLOCK_DEPOSIT.Cmp(depositValueSoFar) <= 0 {
-						break
-					}
-				}
-			}
-		}
-	}
-
-	filteredDepositValue = big.NewInt(0)
-	for val, _ := range filteredValidators {
-		depositValue := validatorsDepositMap[val]
-		filteredDepositValue = common.SafeAddBigInt(filteredDepositValue, depositValue)
-	}
+	return finality.FilterValidators(parentHash, valDepMap, consensusParams())
+}
 
-	if filteredDepositValue.Cmp(MIN_BLOCK_DEPOSIT) == -1 {
-		return nil, nil, nil, errors.New("min block deposit not met for filteredDepositValue")
+// consensusParams returns the consensus parameters that the finality certificates of
+// the blocks are verified with.
+func consensusParams() *finality.Params {
+	return &finality.Params{
+		MinValidators:       MIN_VALIDATORS,
+		MaxValidators:       MAX_VALIDATORS,
+		MaxRound:            MAX_ROUND,
+		MinValidatorDeposit: MIN_VALIDATOR_DEPOSIT,
+		MinBlockDeposit:     MIN_BLOCK_DEPOSIT,
+		CommitPercentage:    MIN_BLOCK_TRANSACTION_WEIGHTED_PROPOSALS_PERCENTAGE,
 	}
-
-	blockMinWeightedProposalsRequired = common.SafeRelativePercentageBigInt(filteredDepositValue, MIN_BLOCK_TRANSACTION_WEIGHTED_PROPOSALS_PERCENTAGE)
-
-	return filteredValidators, filteredDepositValue, blockMinWeightedProposalsRequired, nil
 }
 
 func (cph *ConsensusHandler) initializeBlockStateIfRequired(parentHash common.Hash, blockNumber uint64) error {
@@ -589,13 +547,15 @@ func (cph *ConsensusHandler) initializeBlockStateIfRequired(parentHash common.Ha
 	cph.blockStateDetailsMap[parentHash] = &BlockStateDetails{
 		blockRoundMap:                make(map[byte]*BlockRoundDetails),
 		filteredValidatorsDepositMap: make(map[common.Address]*big.Int),
-		initTime:                     time.Now(),
+		initTime:                     cph.clock.Now(),
 		parentHash:                   parentHash,
 		highestProposalRoundSeen:     0,
 		blockNumber:                  blockNumber,
+		votes:                        make(map[voteKey]*eth.ConsensusPacket),
+		equivocations:                make(map[common.Address]*EquivocationEvidence),
 	}
 	blockStateDetails := cph.blockStateDetailsMap[parentHash]
-	cph.lastRequestConsensusDataTime = time.Now()
+	cph.lastRequestConsensusDataTime = cph.clock.Now()
 
 	validators, err := cph.getValidatorsFn(parentHash)
 	if err != nil {
@@ -613,7 +573,7 @@ func (cph *ConsensusHandler) initializeBlockStateIfRequired(parentHash common.Ha
 		return err
 	}
 
-	if blockNumber >= BLOCK_PROPOSER_NIL_BLOCK_START_BLOCK {
+	if blockNumber >= cph.schedule.BlockProposerNilBlockStartBlock {
 		validatorDetailsMap, err := cph.listValidatorsFn(parentHash)
 		if err != nil {
 			log.Error("listValidatorsFn", "err", err)
@@ -651,8 +611,8 @@ func (cph *ConsensusHandler) initializeBlockStateIfRequired(parentHash common.Ha
 	cph.currentParentHash = parentHash
 
 	//Consensus Context
-	if blockNumber >= CONTEXT_BASED_START_BLOCK {
-		contextKey, err := GetBlockConsensusContextKeyForBlock(blockNumber)
+	if blockNumber >= cph.schedule.ContextBasedStartBlock {
+		contextKey, err := GetBlockConsensusContextKeyForBlock(blockNumber, cph.schedule)
 		if err != nil {
 			return err
 		}
@@ -672,6 +632,12 @@ func (cph *ConsensusHandler) initializeBlockStateIfRequired(parentHash common.Ha
 		return errors.New("min block deposit not met")
 	}
 
+	err = cph.replayVoteJournal(parentHash)
+	if err != nil {
+		delete(cph.blockStateDetailsMap, parentHash)
+		return err
+	}
+
 	cph.peerHandler.SetCurrentParentHash(parentHash, blockNumber)
 
 	return cph.SaveHash(parentHash)
@@ -689,20 +655,21 @@ func (cph *ConsensusHandler) initializeNewBlockRound(newRoundReason NewRoundReas
 		selfProposed:          false,
 		selfAckd:              false,
 		selfPrecommited:       false,
-		initTime:              time.Now(),
+		initTime:              cph.clock.Now(),
 		proposalAckPackets:    make(map[common.Address]*eth.ConsensusPacket),
 		precommitPackets:      make(map[common.Address]*eth.ConsensusPacket),
 		commitPackets:         make(map[common.Address]*eth.ConsensusPacket),
 		selfKnownTransactions: make(map[common.Hash]bool),
 		newRoundReason:        newRoundReason,
 	}
+	markNewRound(newRoundReason)
 
 	if blockRoundDetails.Round > 1 {
 		log.Trace("initializeNewBlockRound", "currentRound", blockStateDetails.currentRound, "Address", cph.account.Address)
 	}
 
 	proposer, err := getBlockProposer(cph.currentParentHash, &blockStateDetails.filteredValidatorsDepositMap, blockRoundDetails.Round,
-		blockStateDetails.validatorDetailsMap, blockStateDetails.blockNumber, blockStateDetails.consensusContext)
+		blockStateDetails.validatorDetailsMap, blockStateDetails.blockNumber, blockStateDetails.consensusContext, cph.schedule)
 	if err != nil {
 		return err
 	}
@@ -717,7 +684,7 @@ func (cph *ConsensusHandler) initializeNewBlockRound(newRoundReason NewRoundReas
 }
 
 func (cph *ConsensusHandler) isBlockProposer(parentHash common.Hash, filteredValidatorDepositMap *map[common.Address]*big.Int, round byte, blockStateDetails *BlockStateDetails) (bool, error) {
-	blockProposer, err := getBlockProposer(parentHash, filteredValidatorDepositMap, round, blockStateDetails.validatorDetailsMap, blockStateDetails.blockNumber, blockStateDetails.consensusContext)
+	blockProposer, err := getBlockProposer(parentHash, filteredValidatorDepositMap, round, blockStateDetails.validatorDetailsMap, blockStateDetails.blockNumber, blockStateDetails.consensusContext, cph.schedule)
 
 	if err != nil {
 		log.Trace("isBlockProposer", "err", err)
@@ -728,6 +695,13 @@ func (cph *ConsensusHandler) isBlockProposer(parentHash common.Hash, filteredVal
 
 func (cph *ConsensusHandler) HandleConsensusPacket(packet *eth.ConsensusPacket, fromPeerId string) error {
 	log.Debug("HandleConsensusPacket", "ParentHash", packet.ParentHash, "fromPeerId", fromPeerId)
+
+	// Verify the signature before taking the lock, so that the packets of different peers
+	// are verified concurrently, processPacket then finds it in the signature cache
+	if cph.signFn != nil && packet != nil {
+		verifyPacketSignatures([]*eth.ConsensusPacket{packet})
+	}
+
 	cph.outerPacketLock.Lock()
 	defer cph.outerPacketLock.Unlock()
 
@@ -740,7 +714,7 @@ func (cph *ConsensusHandler) HandleConsensusPacket(packet *eth.ConsensusPacket,
 		return nil
 	}
 
-	if cph.initialized == false || HasExceededTimeThreshold(cph.initTime, STARTUP_DELAY_MS) == false {
+	if cph.initialized == false || cph.hasExceededTimeThreshold(cph.initTime, STARTUP_DELAY_MS) == false {
 		log.Trace("received consensus packet, but consensus is not ready yet")
 		cph.peerHandler.HandleConsensusPacket(packet, fromPeerId)
 		return nil
@@ -748,6 +722,7 @@ func (cph *ConsensusHandler) HandleConsensusPacket(packet *eth.ConsensusPacket,
 
 	cph.LogIncomingPacketStats()
 	err := cph.processPacket(packet, fromPeerId)
+	markPacketProcessed(packet, err)
 	if errors.Is(err, OutOfOrderPackerErr) {
 		pkt := eth.NewConsensusPacket(packet)
 		packetMap, ok := cph.outOfOrderPacketsMap[packet.ParentHash]
@@ -760,11 +735,12 @@ func (cph *ConsensusHandler) HandleConsensusPacket(packet *eth.ConsensusPacket,
 			return nil
 		}
 		oooPacket := &OutOfOrderPacket{
-			ReceivedTime: time.Now(),
+			ReceivedTime: cph.clock.Now(),
 			Packet:       &pkt,
 		}
 		packetMap[packet.ParentHash] = oooPacket
 		cph.outOfOrderPacketsMap[packet.ParentHash] = packetMap
+		cph.updateOutOfOrderGauge()
 
 		return nil
 	}
@@ -786,15 +762,15 @@ func (cph *ConsensusHandler) HandleConsensusPacket(packet *eth.ConsensusPacket,
 	return nil
 }
 
-func shouldSignFull(blockNumber uint64) bool {
-	if blockNumber >= FULL_SIGN_PROPOSAL_CUTOFF_BLOCK && blockNumber%FULL_SIGN_PROPOSAL_FREQUENCY_BLOCKS == 0 {
+func shouldSignFull(blockNumber uint64, schedule *params.ProofOfStakeForkSchedule) bool {
+	if blockNumber >= schedule.FullSignProposalCutoffBlock && blockNumber%FULL_SIGN_PROPOSAL_FREQUENCY_BLOCKS == 0 {
 		return true
 	}
 	return false
 }
 
 func (cph *ConsensusHandler) processPacket(packet *eth.ConsensusPacket, fromPeerId string) error {
-	if packet == nil || packet.ConsensusData == nil || len(packet.ConsensusData) < 1 || packet.Signature == nil || len(packet.Signature) < hybrideds.CRYPTO_SIGNATURE_BYTES {
+	if packet == nil || packet.ConsensusData == nil || len(packet.ConsensusData) < 1 || packet.Signature == nil || len(packet.Signature) == 0 {
 		log.Debug("processPacket nil")
 		return errors.New("nil packet")
 	}
@@ -808,41 +784,23 @@ func (cph *ConsensusHandler) processPacket(packet *eth.ConsensusPacket, fromPeer
 
 	packetType := ConsensusPacketType(packet.ConsensusData[startIndex-1])
 
-	dataToVerify := append(packet.ParentHash.Bytes(), packet.ConsensusData...)
-	digestHash := crypto.Keccak256(dataToVerify)
-	var pubKey *signaturealgorithm.PublicKey
-	var err error
-
-	if packetType == CONSENSUS_PACKET_TYPE_PROPOSE_BLOCK && len(packet.Signature) != cryptobase.SigAlg.SignatureWithPublicKeyLength() { //for verify, it is ok not to check the blockNumber for full
-		pubKey, err = cryptobase.SigAlg.PublicKeyFromSignatureWithContext(digestHash, packet.Signature, FULL_SIGN_CONTEXT)
-		if err != nil {
-			log.Debug("processPacket invalid 1")
-			return InvalidPacketErr
-		}
-
-		if cryptobase.SigAlg.VerifyWithContext(pubKey.PubData, digestHash, packet.Signature, FULL_SIGN_CONTEXT) == false {
-			return InvalidPacketErr
-		}
-	} else {
-		pubKey, err = cryptobase.SigAlg.PublicKeyFromSignature(digestHash, packet.Signature)
-		if err != nil {
-			log.Debug("processPacket invalid 2")
-			return InvalidPacketErr
-		}
-
-		if cryptobase.SigAlg.Verify(pubKey.PubData, digestHash, packet.Signature) == false {
-			log.Debug("processPacket invalid 3")
-			return InvalidPacketErr
-		}
+	// The packets are for the block after the latest one
+	if err := checkPacketSignatureScheme(packet, cph.GetLatestBlockNumber()+1, cph.schedule); err != nil {
+		log.Debug("processPacket invalid signature scheme", "err", err)
+		return InvalidPacketErr
 	}
-
-	validator, err := cryptobase.SigAlg.PublicKeyToAddress(pubKey)
+	//for verify, it is ok not to check the blockNumber for full
+	validator, err := recoverPacketSigner(packet, packetType)
 	if err != nil {
-		log.Debug("processPacket invalid 4")
+		log.Debug("processPacket invalid", "err", err)
 		return InvalidPacketErr
 	}
 
 	log.Trace("processPacket", "validator", validator, "packetType", packetType)
+	if packetType <= CONSENSUS_PACKET_TYPE_COMMIT_BLOCK {
+		cph.detectEquivocation(validator, packetType, packet)
+	}
+
 	if packetType == CONSENSUS_PACKET_TYPE_PROPOSE_BLOCK {
 		return cph.handleProposeBlockPacket(validator, packet, false)
 	} else if packetType == CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL {
@@ -851,7 +809,7 @@ func (cph *ConsensusHandler) processPacket(packet *eth.ConsensusPacket, fromPeer
 		return cph.handlePrecommitPacket(validator, packet, false)
 	} else if packetType == CONSENSUS_PACKET_TYPE_COMMIT_BLOCK {
 		return cph.handleCommitPacket(validator, packet, false)
-	} else if cph.GetLatestBlockNumber() >= PACKET_PROTOCOL_START_BLOCK && packetType >= CONSENSUS_PACKET_TYPE_CAPABILITY {
+	} else if cph.GetLatestBlockNumber() >= cph.schedule.PacketProtocolStartBlock && packetType >= CONSENSUS_PACKET_TYPE_CAPABILITY {
 		return nil
 	}
 
@@ -892,6 +850,7 @@ func (cph *ConsensusHandler) processOutOfOrderPackets(parentHash common.Hash) er
 			cph.outOfOrderPacketsMap[parentHash] = packetMap
 		}
 	}
+	cph.updateOutOfOrderGauge()
 
 	return nil
 }
@@ -914,7 +873,7 @@ func (cph *ConsensusHandler) getBlockRoundState(parentHash common.Hash, round by
 	return roundDetails.state, roundDetails.blockVoteType, len(roundDetails.validatorProposalAcks), nil
 }
 
-func (cph *ConsensusHandler) getBlockConsensusData(parentHash common.Hash) (blockConsensusData *BlockConsensusData, blockAdditionalConsensusData *BlockAdditionalConsensusData, err error) {
+func (cph *ConsensusHandler) getBlockConsensusData(parentHash common.Hash, window *EquivocationWindow) (blockConsensusData *BlockConsensusData, blockAdditionalConsensusData *BlockAdditionalConsensusData, err error) {
 	cph.outerPacketLock.Lock()
 	defer cph.outerPacketLock.Unlock()
 
@@ -963,6 +922,10 @@ func (cph *ConsensusHandler) getBlockConsensusData(parentHash common.Hash) (bloc
 
 	blockConsensusData.PrecommitHash.CopyFrom(blockRoundDetails.precommitHash)
 
+	if blockStateDetails.blockNumber >= cph.schedule.EquivocationSlashStartBlock {
+		blockConsensusData.Evidence = cph.getEquivocationEvidence(blockStateDetails, window)
+	}
+
 	blockAdditionalConsensusData = &BlockAdditionalConsensusData{
 		InitTime: uint64(blockStateDetails.initTime.UnixNano() / int64(time.Millisecond)),
 	}
@@ -989,7 +952,7 @@ func (cph *ConsensusHandler) getBlockConsensusData(parentHash common.Hash) (bloc
 		}
 
 		roundProposer, err := getBlockProposer(parentHash, &blockStateDetails.filteredValidatorsDepositMap, r,
-			blockStateDetails.validatorDetailsMap, blockStateDetails.blockNumber, blockStateDetails.consensusContext)
+			blockStateDetails.validatorDetailsMap, blockStateDetails.blockNumber, blockStateDetails.consensusContext, cph.schedule)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -1021,10 +984,10 @@ func (cph *ConsensusHandler) getBlockConsensusData(parentHash common.Hash) (bloc
 
 	if blockConsensusData.VoteType == VOTE_TYPE_NIL {
 		err = ValidateBlockConsensusDataInner(nil, parentHash, blockConsensusData, blockAdditionalConsensusData,
-			&blockStateDetails.filteredValidatorsDepositMap, blockStateDetails.blockNumber, blockStateDetails.validatorDetailsMap, blockStateDetails.consensusContext)
+			&blockStateDetails.filteredValidatorsDepositMap, blockStateDetails.blockNumber, blockStateDetails.validatorDetailsMap, blockStateDetails.consensusContext, window, cph.schedule)
 	} else {
 		err = ValidateBlockConsensusDataInner(blockRoundDetails.proposalTxns, parentHash, blockConsensusData, blockAdditionalConsensusData,
-			&blockStateDetails.filteredValidatorsDepositMap, blockStateDetails.blockNumber, blockStateDetails.validatorDetailsMap, blockStateDetails.consensusContext)
+			&blockStateDetails.filteredValidatorsDepositMap, blockStateDetails.blockNumber, blockStateDetails.validatorDetailsMap, blockStateDetails.consensusContext, window, cph.schedule)
 	}
 
 	if err != nil {
@@ -1104,23 +1067,7 @@ func (cph *ConsensusHandler) getBlockVote(parentHash common.Hash) (VoteType, err
 }
 
 func GetCombinedTxnHash(parentHash common.Hash, round byte, txns []common.Hash) common.Hash {
-	var txnList []common.Hash
-	txnList = make([]common.Hash, len(txns))
-	for i := 0; i < len(txns); i++ {
-		txnList[i].CopyFrom(txns[i])
-	}
-
-	sort.Slice(txnList, func(i, j int) bool {
-		return bytes.Compare(txnList[i].Bytes(), txnList[j].Bytes()) == -1
-	})
-
-	var data []byte
-	data = make([]byte, 0)
-	for _, txn := range txnList {
-		data = append(data, txn.Bytes()...)
-	}
-
-	hash := crypto.Keccak256Hash(data, parentHash.Bytes(), []byte{round})
+	hash := finality.CombinedTxnHash(parentHash, round, txns)
 	log.Trace("GetCombinedTxnHash", "parentHash", parentHash, "round", round, "txn count", len(txns), "hash", hash)
 	return hash
 }
@@ -1176,7 +1123,7 @@ func (cph *ConsensusHandler) handleProposeBlockPacket(validator common.Address,
 		return errors.New("invalid proposer")
 	}
 
-	if ValidateBlockProposalTimeConsensus(blockStateDetails.blockNumber, proposalDetails.BlockTime) == false {
+	if validateBlockProposalTimeConsensus(cph.clock.Now(), blockStateDetails.blockNumber, proposalDetails.BlockTime, cph.schedule) == false {
 		return errors.New("block time validation failed, skipping packet")
 	}
 
@@ -1228,14 +1175,14 @@ func (cph *ConsensusHandler) handleProposeBlockPacket(validator common.Address,
 			}
 		} else {
 			blockRoundDetails.state = BLOCK_STATE_WAITING_FOR_PROPOSAL_ACKS
-			blockStateDetails.proposalTime = Elapsed(blockStateDetails.initTime)
+			blockStateDetails.proposalTime = cph.elapsed(blockStateDetails.initTime)
 		}
 	} else {
-		blockStateDetails.proposalTime = Elapsed(blockStateDetails.initTime)
+		blockStateDetails.proposalTime = cph.elapsed(blockStateDetails.initTime)
 		blockRoundDetails.state = BLOCK_STATE_WAITING_FOR_PROPOSAL_ACKS
 		blockRoundDetails.selfProposed = true
 		blockRoundDetails.selfProposalPacket = packet
-		blockRoundDetails.selfProposedTime = time.Now()
+		blockRoundDetails.selfProposedTime = cph.clock.Now()
 	}
 
 	pkt := eth.NewConsensusPacket(packet)
@@ -1336,23 +1283,11 @@ func (cph *ConsensusHandler) handleAckBlockProposalPacket(validator common.Addre
 }
 
 func parsePacket(packet *eth.ConsensusPacket) (byte, common.Address, error) {
-	dataToVerify := append(packet.ParentHash.Bytes(), packet.ConsensusData...)
-	digestHash := crypto.Keccak256(dataToVerify)
-	pubKey, err := cryptobase.SigAlg.PublicKeyFromSignature(digestHash, packet.Signature)
+	validator, err := verifyPacketSignature(packet)
 	if err != nil {
 		log.Trace("invalid 1", "err", err)
 		return 0, ZERO_ADDRESS, err
 	}
-	if cryptobase.SigAlg.Verify(pubKey.PubData, digestHash, packet.Signature) == false {
-		log.Trace("invalid 2")
-		return 0, ZERO_ADDRESS, InvalidPacketErr
-	}
-
-	validator, err := cryptobase.SigAlg.PublicKeyToAddress(pubKey)
-	if err != nil {
-		log.Trace("invalid 3", "err", err)
-		return 0, ZERO_ADDRESS, err
-	}
 
 	var startIndex int
 	if packet.ConsensusData[0] >= MinConsensusNetworkProtocolVersion {
@@ -1523,7 +1458,7 @@ func (cph *ConsensusHandler) shouldMoveToNextRoundPrecommit(parentHash common.Ha
 	blockStateDetails := cph.blockStateDetailsMap[parentHash]
 	blockRoundDetails := blockStateDetails.blockRoundMap[blockStateDetails.currentRound]
 
-	if HasExceededTimeThreshold(blockRoundDetails.precommitInitTime, int64(ACK_BLOCK_TIMEOUT_MS*int(blockRoundDetails.Round))) == false {
+	if cph.hasExceededTimeThreshold(blockRoundDetails.precommitInitTime, int64(ACK_BLOCK_TIMEOUT_MS*int(blockRoundDetails.Round))) == false {
 		log.Trace("shouldMoveToNextRoundPrecommit time not met", "blockRoundDetails.precommitInitTime", blockRoundDetails.precommitInitTime)
 		return false, nil
 	}
@@ -1682,7 +1617,7 @@ func (cph *ConsensusHandler) handlePrecommitPacket(validator common.Address, pac
 
 		log.Debug("handlePrecommitPacket", "totalVotesDepositCount", totalVotesDepositCount, "blockMinWeightedProposalsRequired", blockStateDetails.blockMinWeightedProposalsRequired)
 		if totalVotesDepositCount.Cmp(blockStateDetails.blockMinWeightedProposalsRequired) >= 0 {
-			blockStateDetails.precommitTime = Elapsed(blockStateDetails.initTime)
+			blockStateDetails.precommitTime = cph.elapsed(blockStateDetails.initTime)
 			blockRoundDetails.state = BLOCK_STATE_WAITING_FOR_COMMITS
 		}
 	}
@@ -1786,10 +1721,10 @@ func (cph *ConsensusHandler) handleCommitPacket(validator common.Address, packet
 				cph.totalTransactions = cph.totalTransactions + txnCountInBlock
 				if txnCountInBlock > cph.maxTransactionsInBlock {
 					cph.maxTransactionsInBlock = txnCountInBlock
-					cph.maxTransactionsBlockTime = Elapsed(blockStateDetails.initTime)
+					cph.maxTransactionsBlockTime = cph.elapsed(blockStateDetails.initTime)
 				}
 			}
-			blockStateDetails.commitTime = Elapsed(blockStateDetails.initTime)
+			blockStateDetails.commitTime = cph.elapsed(blockStateDetails.initTime)
 
 			//stats
 			cph.timeStatMap[GetTimeStatBucket(PROPOSAL_KEY_PREFIX, blockStateDetails.proposalTime)]++
@@ -1797,6 +1732,7 @@ func (cph *ConsensusHandler) handleCommitPacket(validator common.Address, packet
 			cph.timeStatMap[GetTimeStatBucket(PRECOMMIT_KEY_PREFIX, blockStateDetails.precommitTime-blockStateDetails.ackProposalTime)]++
 			cph.timeStatMap[GetTimeStatBucket(COMMIT_KEY_PREFIX, blockStateDetails.commitTime-blockStateDetails.precommitTime)]++
 			cph.timeStatMap[GetTimeStatBucket(TOTAL_KEY_PREFIX, blockStateDetails.commitTime)]++
+			markBlockCommitted(blockStateDetails, blockRoundDetails.blockVoteType)
 
 			log.Debug("BlockStats", "maxTxnsInBlock", cph.maxTransactionsInBlock, "totalTxns", cph.totalTransactions, "okBlocks", cph.okVoteBlocks, "nilBlocks", cph.nilVoteBlocks)
 			for statKey, statVal := range cph.timeStatMap {
@@ -1823,26 +1759,35 @@ func (cph *ConsensusHandler) handleCommitPacket(validator common.Address, packet
 }
 
 func HasExceededTimeThreshold(startTime time.Time, thresholdMs int64) bool {
-	end := time.Now().UnixNano() / int64(time.Millisecond)
-	start := startTime.UnixNano() / int64(time.Millisecond)
-	diff := end - start
-	if diff >= thresholdMs {
-		return true
-	} else {
-		return false
-	}
+	return Elapsed(startTime) >= thresholdMs
 }
 
 func Elapsed(startTime time.Time) int64 {
-	end := time.Now().UnixNano() / int64(time.Millisecond)
+	return elapsedSince(time.Now(), startTime)
+}
+
+func (cph *ConsensusHandler) elapsed(startTime time.Time) int64 {
+	return elapsedSince(cph.clock.Now(), startTime)
+}
+
+func (cph *ConsensusHandler) hasExceededTimeThreshold(startTime time.Time, thresholdMs int64) bool {
+	return cph.elapsed(startTime) >= thresholdMs
+}
+
+func elapsedSince(now time.Time, startTime time.Time) int64 {
+	end := now.UnixNano() / int64(time.Millisecond)
 	start := startTime.UnixNano() / int64(time.Millisecond)
 	diff := end - start
 	return diff
 }
 
-func GetProposalTime(blockNumber uint64) uint64 {
-	if blockNumber == 1 || blockNumber%BLOCK_PERIOD_TIME_CHANGE == 0 || blockNumber >= BLOCK_TIME_ORIG_START_BLOCK {
-		blockTime := uint64(time.Now().UTC().Unix())
+func GetProposalTime(blockNumber uint64, schedule *params.ProofOfStakeForkSchedule) uint64 {
+	return getProposalTime(time.Now(), blockNumber, schedule)
+}
+
+func getProposalTime(now time.Time, blockNumber uint64, schedule *params.ProofOfStakeForkSchedule) uint64 {
+	if blockNumber == 1 || blockNumber%BLOCK_PERIOD_TIME_CHANGE == 0 || blockNumber >= schedule.BlockTimeOrigStartBlock {
+		blockTime := uint64(now.UTC().Unix())
 		if blockTime%60 != 0 {
 			blockTime = blockTime - (blockTime % 60)
 		}
@@ -1853,8 +1798,12 @@ func GetProposalTime(blockNumber uint64) uint64 {
 	}
 }
 
-func ValidateBlockProposalTimeConsensus(blockNumber uint64, proposedTime uint64) bool {
-	if blockNumber == 1 || blockNumber%BLOCK_PERIOD_TIME_CHANGE == 0 || blockNumber >= BLOCK_TIME_ORIG_START_BLOCK {
+func ValidateBlockProposalTimeConsensus(blockNumber uint64, proposedTime uint64, schedule *params.ProofOfStakeForkSchedule) bool {
+	return validateBlockProposalTimeConsensus(time.Now(), blockNumber, proposedTime, schedule)
+}
+
+func validateBlockProposalTimeConsensus(now time.Time, blockNumber uint64, proposedTime uint64, schedule *params.ProofOfStakeForkSchedule) bool {
+	if blockNumber == 1 || blockNumber%BLOCK_PERIOD_TIME_CHANGE == 0 || blockNumber >= schedule.BlockTimeOrigStartBlock {
 		if proposedTime == 0 {
 			return false
 		}
@@ -1863,7 +1812,7 @@ func ValidateBlockProposalTimeConsensus(blockNumber uint64, proposedTime uint64)
 		if tm.Second() != 0 || tm.Nanosecond() != 0 { //No granularity at anything other than minute level allowed, to reduce ability to manipulate blockHash
 			return false
 		}
-		currTimeVal := time.Now().UTC().Unix() //Note that packet may have arrived late. So, these comparisions are approximate.
+		currTimeVal := now.UTC().Unix() //Note that packet may have arrived late. So, these comparisions are approximate.
 		if currTimeVal%60 != 0 {
 			currTimeVal = currTimeVal - (currTimeVal % 60)
 		}
@@ -1909,7 +1858,7 @@ func (cph *ConsensusHandler) proposeBlock(parentHash common.Hash, txns []common.
 	} else {
 		proposalDetails.Txns = make([]common.Hash, 0)
 	}
-	proposalDetails.BlockTime = GetProposalTime(blockNumber)
+	proposalDetails.BlockTime = getProposalTime(cph.clock.Now(), blockNumber, cph.schedule)
 
 	log.Trace("ProposeBlock with txns", "count", len(proposalDetails.Txns))
 
@@ -1921,14 +1870,14 @@ func (cph *ConsensusHandler) proposeBlock(parentHash common.Hash, txns []common.
 
 	var dataToSend []byte
 
-	if cph.GetLatestBlockNumber() >= PACKET_PROTOCOL_START_BLOCK {
+	if cph.GetLatestBlockNumber() >= cph.schedule.PacketProtocolStartBlock {
 		dataToSend = append([]byte{ConsensusNetworkProtocolVersion}, append([]byte{byte(CONSENSUS_PACKET_TYPE_PROPOSE_BLOCK)}, data...)...)
 	} else {
 		dataToSend = append([]byte{byte(CONSENSUS_PACKET_TYPE_PROPOSE_BLOCK)}, data...)
 	}
 
-	fullSignNeeded := shouldSignFull(blockNumber)
-	packet, err = cph.createConsensusPacket(parentHash, dataToSend, fullSignNeeded)
+	fullSignNeeded := shouldSignFull(blockNumber, cph.schedule)
+	packet, err = cph.createVotePacket(parentHash, proposalDetails.Round, CONSENSUS_PACKET_TYPE_PROPOSE_BLOCK, dataToSend, fullSignNeeded)
 	if err != nil {
 		return err
 	}
@@ -1986,13 +1935,13 @@ func (cph *ConsensusHandler) ackBlockProposalTimeout(parentHash common.Hash) err
 
 		var dataToSend []byte
 
-		if cph.GetLatestBlockNumber() >= PACKET_PROTOCOL_START_BLOCK {
+		if cph.GetLatestBlockNumber() >= cph.schedule.PacketProtocolStartBlock {
 			dataToSend = append([]byte{ConsensusNetworkProtocolVersion}, append([]byte{byte(CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL)}, data...)...)
 		} else {
 			dataToSend = append([]byte{byte(CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL)}, data...)
 		}
 
-		packet, err := cph.createConsensusPacket(parentHash, dataToSend, false)
+		packet, err := cph.createVotePacket(parentHash, proposalAckDetails.Round, CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL, dataToSend, false)
 		if err != nil {
 			return err
 		}
@@ -2042,11 +1991,11 @@ func (cph *ConsensusHandler) ackBlockProposalTimeout(parentHash common.Hash) err
 		//do nothing
 	} else if nilVotesDepositCount.Cmp(blockStateDetails.blockMinWeightedProposalsRequired) >= 0 { //handle timeout differently?
 		blockRoundDetails.state = BLOCK_STATE_WAITING_FOR_PRECOMMITS
-		blockRoundDetails.precommitInitTime = time.Now()
+		blockRoundDetails.precommitInitTime = cph.clock.Now()
 		blockRoundDetails.blockVoteType = VOTE_TYPE_NIL
 		blockRoundDetails.precommitHash.CopyFrom(getNilVotePreCommitHash(parentHash, blockStateDetails.currentRound))
 	} else {
-		if HasExceededTimeThreshold(blockRoundDetails.initTime, int64(ACK_BLOCK_TIMEOUT_MS*int(blockRoundDetails.Round))) {
+		if cph.hasExceededTimeThreshold(blockRoundDetails.initTime, int64(ACK_BLOCK_TIMEOUT_MS*int(blockRoundDetails.Round))) {
 			if totalVotesDepositCount.Cmp(blockStateDetails.totalBlockDepositValue) >= 0 ||
 				totalVotesDepositCount.Cmp(blockStateDetails.blockMinWeightedProposalsRequired) >= 0 {
 				blockStateDetails.blockRoundMap[blockStateDetails.currentRound] = blockRoundDetails
@@ -2170,12 +2119,12 @@ func (cph *ConsensusHandler) ackBlockProposal(parentHash common.Hash) error {
 
 		var dataToSend []byte
 
-		if cph.GetLatestBlockNumber() >= PACKET_PROTOCOL_START_BLOCK {
+		if cph.GetLatestBlockNumber() >= cph.schedule.PacketProtocolStartBlock {
 			dataToSend = append([]byte{ConsensusNetworkProtocolVersion}, append([]byte{byte(CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL)}, data...)...)
 		} else {
 			dataToSend = append([]byte{byte(CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL)}, data...)
 		}
-		packet, err := cph.createConsensusPacket(parentHash, dataToSend, false)
+		packet, err := cph.createVotePacket(parentHash, proposalAckDetails.Round, CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL, dataToSend, false)
 		if err != nil {
 			return err
 		}
@@ -2221,20 +2170,20 @@ func (cph *ConsensusHandler) ackBlockProposal(parentHash common.Hash) error {
 
 	if okVotesDepositCount.Cmp(blockStateDetails.blockMinWeightedProposalsRequired) >= 0 && blockRoundDetails.selfAckProposalVoteType == VOTE_TYPE_OK { //For ok votes, vote type should match
 		blockRoundDetails.state = BLOCK_STATE_WAITING_FOR_PRECOMMITS
-		blockRoundDetails.precommitInitTime = time.Now()
+		blockRoundDetails.precommitInitTime = cph.clock.Now()
 		blockRoundDetails.precommitHash.CopyFrom(getOkVotePreCommitHash(parentHash, blockRoundDetails.proposalHash, blockStateDetails.currentRound))
 		blockRoundDetails.blockVoteType = VOTE_TYPE_OK
 		log.Trace("blockVoteType a1", "parentHash", parentHash)
-		blockStateDetails.ackProposalTime = Elapsed(blockStateDetails.initTime)
+		blockStateDetails.ackProposalTime = cph.elapsed(blockStateDetails.initTime)
 	} else if nilVotesDepositCount.Cmp(blockStateDetails.blockMinWeightedProposalsRequired) >= 0 { //handle timeout differently? for nil votes, it is ok to accept NIL vote even if self vote is OK
 		blockRoundDetails.state = BLOCK_STATE_WAITING_FOR_PRECOMMITS
-		blockRoundDetails.precommitInitTime = time.Now()
+		blockRoundDetails.precommitInitTime = cph.clock.Now()
 		blockRoundDetails.precommitHash.CopyFrom(getNilVotePreCommitHash(parentHash, blockStateDetails.currentRound))
 		log.Trace("blockVoteType a2", "parentHash", parentHash)
 		blockRoundDetails.blockVoteType = VOTE_TYPE_NIL
 	} else {
 		if totalVotesDepositCount.Cmp(blockStateDetails.totalBlockDepositValue) >= 0 ||
-			totalVotesDepositCount.Cmp(blockStateDetails.blockMinWeightedProposalsRequired) >= 0 && HasExceededTimeThreshold(blockRoundDetails.initTime, int64(ACK_BLOCK_TIMEOUT_MS*int(blockRoundDetails.Round))) {
+			totalVotesDepositCount.Cmp(blockStateDetails.blockMinWeightedProposalsRequired) >= 0 && cph.hasExceededTimeThreshold(blockRoundDetails.initTime, int64(ACK_BLOCK_TIMEOUT_MS*int(blockRoundDetails.Round))) {
 			blockStateDetails.blockRoundMap[blockStateDetails.currentRound] = blockRoundDetails
 			cph.blockStateDetailsMap[parentHash] = blockStateDetails
 			err := cph.initializeNewBlockRound(NEW_ROUND_REASON_WAIT_ACK_BLOCK_PROPOSAL_TIMEOUT)
@@ -2303,13 +2252,13 @@ func (cph *ConsensusHandler) precommitBlock(parentHash common.Hash) error {
 
 	var dataToSend []byte
 
-	if cph.GetLatestBlockNumber() >= PACKET_PROTOCOL_START_BLOCK {
+	if cph.GetLatestBlockNumber() >= cph.schedule.PacketProtocolStartBlock {
 		dataToSend = append([]byte{ConsensusNetworkProtocolVersion}, append([]byte{byte(CONSENSUS_PACKET_TYPE_PRECOMMIT_BLOCK)}, data...)...)
 	} else {
 		dataToSend = append([]byte{byte(CONSENSUS_PACKET_TYPE_PRECOMMIT_BLOCK)}, data...)
 	}
 
-	packet, err := cph.createConsensusPacket(parentHash, dataToSend, false)
+	packet, err := cph.createVotePacket(parentHash, precommit.Round, CONSENSUS_PACKET_TYPE_PRECOMMIT_BLOCK, dataToSend, false)
 	if err != nil {
 		return err
 	}
@@ -2357,13 +2306,13 @@ func (cph *ConsensusHandler) commitBlock(parentHash common.Hash) error {
 
 	var dataToSend []byte
 
-	if cph.GetLatestBlockNumber() >= PACKET_PROTOCOL_START_BLOCK {
+	if cph.GetLatestBlockNumber() >= cph.schedule.PacketProtocolStartBlock {
 		dataToSend = append([]byte{ConsensusNetworkProtocolVersion}, append([]byte{byte(CONSENSUS_PACKET_TYPE_COMMIT_BLOCK)}, data...)...)
 	} else {
 		dataToSend = append([]byte{byte(CONSENSUS_PACKET_TYPE_COMMIT_BLOCK)}, data...)
 	}
 
-	packet, err := cph.createConsensusPacket(parentHash, dataToSend, false)
+	packet, err := cph.createVotePacket(parentHash, commitDetails.Round, CONSENSUS_PACKET_TYPE_COMMIT_BLOCK, dataToSend, false)
 	if err != nil {
 		return err
 	}
@@ -2473,7 +2422,7 @@ func (cph *ConsensusHandler) HandleConsensus(parentHash common.Hash, txns []comm
 			return errors.New("Waiting for previous block to mine")
 		}
 
-		cph.initTime = time.Now()
+		cph.initTime = cph.clock.Now()
 		cph.initialized = true
 		cph.packetHashLastSentMap = make(map[common.Hash]time.Time)
 		cph.packetStats = PacketStats{}
@@ -2482,16 +2431,16 @@ func (cph *ConsensusHandler) HandleConsensus(parentHash common.Hash, txns []comm
 	}
 
 	if cph.lastBlockNumber == blockNumber {
-		if Elapsed(cph.lastBlockNumberChangeTime) >= STALE_BLOCK_WARN_TIME && rndVal == 1 {
+		if cph.elapsed(cph.lastBlockNumberChangeTime) >= STALE_BLOCK_WARN_TIME && rndVal == 1 {
 			log.Warn("Stale Block. Please check your connection.", "blockNumber", blockNumber, "lastBlockChangeTime", cph.lastBlockNumberChangeTime)
 		}
 	} else {
 		cph.lastBlockNumber = blockNumber
-		cph.lastBlockNumberChangeTime = time.Now()
+		cph.lastBlockNumberChangeTime = cph.clock.Now()
 	}
 
-	if HasExceededTimeThreshold(cph.initTime, STARTUP_DELAY_MS) == false && rndVal == 1 {
-		log.Info("Waiting to startup...", "elapsed ms", Elapsed(cph.initTime), "pending txn count", len(txns), "STARTUP_DELAY_MS", STARTUP_DELAY_MS)
+	if cph.hasExceededTimeThreshold(cph.initTime, STARTUP_DELAY_MS) == false && rndVal == 1 {
+		log.Info("Waiting to startup...", "elapsed ms", cph.elapsed(cph.initTime), "pending txn count", len(txns), "STARTUP_DELAY_MS", STARTUP_DELAY_MS)
 		return errors.New("starting up")
 	}
 
@@ -2544,12 +2493,12 @@ func (cph *ConsensusHandler) HandleConsensus(parentHash common.Hash, txns []comm
 			cph.proposeBlock(parentHash, txns, blockNumber)
 		} else {
 			var timeoutMs int64
-			if shouldSignFull(blockNumber) {
+			if shouldSignFull(blockNumber, cph.schedule) {
 				timeoutMs = FULL_BLOCK_TIMEOUT_MS
 			} else {
 				timeoutMs = BLOCK_TIMEOUT_MS
 			}
-			if HasExceededTimeThreshold(blockRoundDetails.initTime, timeoutMs*int64(blockRoundDetails.Round)) {
+			if cph.hasExceededTimeThreshold(blockRoundDetails.initTime, timeoutMs*int64(blockRoundDetails.Round)) {
 				cph.ackBlockProposalTimeout(parentHash)
 			} else {
 				cph.requestConsensusData(blockStateDetails)
@@ -2621,7 +2570,7 @@ func (cph *ConsensusHandler) createConsensusPacket(parentHash common.Hash, data
 
 func (cph *ConsensusHandler) cleanupBroadcast() {
 	for k, v := range cph.packetHashLastSentMap {
-		elapsed := Elapsed(v)
+		elapsed := cph.elapsed(v)
 		if elapsed >= BROADCAST_CLEANUP_DELAY {
 			delete(cph.packetHashLastSentMap, k)
 		}
@@ -2636,7 +2585,7 @@ func (cph *ConsensusHandler) broadCast(packet *eth.ConsensusPacket) error {
 		return errors.New("packet is nil")
 	}
 
-	if cph.latestBlockNumber >= PACKET_PROTOCOL_START_BLOCK {
+	if cph.latestBlockNumber >= cph.schedule.PacketProtocolStartBlock {
 		sendCount := cph.peerHandler.BroadcastLocalPacket(packet)
 		if sendCount > 8 {
 			return nil
@@ -2658,13 +2607,14 @@ func (cph *ConsensusHandler) broadCast(packet *eth.ConsensusPacket) error {
 	packetType := ConsensusPacketType(packet.ConsensusData[startIndex-1])
 	lastSent, ok := cph.packetHashLastSentMap[hash]
 	if ok == false {
-		cph.packetHashLastSentMap[hash] = time.Now()
+		cph.packetHashLastSentMap[hash] = cph.clock.Now()
 		log.Trace("Broadcasting packet", "hash", hash, "packetType", packetType)
 	} else {
-		elapsed := Elapsed(lastSent)
+		elapsed := cph.elapsed(lastSent)
 		if elapsed > BROADCAST_RESEND_DELAY {
-			cph.packetHashLastSentMap[hash] = time.Now()
+			cph.packetHashLastSentMap[hash] = cph.clock.Now()
 			log.Trace("Rebroadcasting packet", "hash", hash, "packetType", packetType)
+			packetResendCounter.Inc(1)
 		} else {
 			log.Trace("Skipping broadcasting packet", "hash", hash, "packetType", packetType)
 			return nil
@@ -2672,7 +2622,11 @@ func (cph *ConsensusHandler) broadCast(packet *eth.ConsensusPacket) error {
 	}
 
 	cph.cleanupBroadcast()
-	go cph.p2pHandler.BroadcastConsensusData(packet)
+	if cph.synchronousP2P {
+		cph.p2pHandler.BroadcastConsensusData(packet)
+	} else {
+		go cph.p2pHandler.BroadcastConsensusData(packet)
+	}
 
 	return nil
 }
@@ -2747,12 +2701,12 @@ func (cph *ConsensusHandler) requestConsensusData(blockStateDetails *BlockStateD
 
 	lastSent, ok := cph.packetHashLastSentMap[hash]
 	if ok == false {
-		cph.packetHashLastSentMap[hash] = time.Now()
+		cph.packetHashLastSentMap[hash] = cph.clock.Now()
 		log.Trace("requestConsensusData packet", "hash", hash)
 	} else {
-		elapsed := Elapsed(lastSent)
+		elapsed := cph.elapsed(lastSent)
 		if elapsed > BROADCAST_RESEND_DELAY*3 {
-			cph.packetHashLastSentMap[hash] = time.Now()
+			cph.packetHashLastSentMap[hash] = cph.clock.Now()
 			log.Trace("requestConsensusData packet", "hash", hash)
 		} else {
 			log.Trace("Skipping requestConsensusData packet", "hash", hash)
@@ -2760,16 +2714,16 @@ func (cph *ConsensusHandler) requestConsensusData(blockStateDetails *BlockStateD
 		}
 	}
 
-	elapsed := Elapsed(blockStateDetails.initTime)
+	elapsed := cph.elapsed(blockStateDetails.initTime)
 	if elapsed < BLOCK_TIMEOUT_MS {
 		return nil
 	}
 
-	elapsed = Elapsed(cph.lastRequestConsensusDataTime)
+	elapsed = cph.elapsed(cph.lastRequestConsensusDataTime)
 	if elapsed < CONSENSUS_DATA_REQUEST_RESEND_DELAY {
 		return nil
 	}
-	cph.lastRequestConsensusDataTime = time.Now()
+	cph.lastRequestConsensusDataTime = cph.clock.Now()
 
 	log.Trace("requestConsensusData 1")
 	requestPacketDetails, err := cph.getRequestConsensusDataPacket(blockStateDetails)
@@ -2788,7 +2742,11 @@ func (cph *ConsensusHandler) requestConsensusData(blockStateDetails *BlockStateD
 	copy(packet.RequestData, data)
 	packet.ParentHash = blockStateDetails.parentHash
 
-	go cph.p2pHandler.RequestConsensusData(&packet)
+	if cph.synchronousP2P {
+		cph.p2pHandler.RequestConsensusData(&packet)
+	} else {
+		go cph.p2pHandler.RequestConsensusData(&packet)
+	}
 
 	return nil
 }
@@ -2799,8 +2757,9 @@ func (cph *ConsensusHandler) cleanupBlockState() {
 			continue
 		}
 
-		if Elapsed(blockStateDetails.initTime) >= BLOCK_CLEANUP_TIME_MS {
+		if cph.elapsed(blockStateDetails.initTime) >= BLOCK_CLEANUP_TIME_MS {
 			delete(cph.blockStateDetailsMap, key)
+			cph.journal.delete(key)
 		}
 	}
 }
@@ -2817,7 +2776,7 @@ func (cph *ConsensusHandler) HandleRequestConsensusDataPacket(packet *eth.Reques
 		return nil, errors.New("invalid request consensus data packet")
 	}
 
-	if cph.initialized == false || HasExceededTimeThreshold(cph.initTime, STARTUP_DELAY_MS) == false {
+	if cph.initialized == false || cph.hasExceededTimeThreshold(cph.initTime, STARTUP_DELAY_MS) == false {
 		return nil, errors.New("received request for consensus packet, but consensus is not ready yet")
 	}
 
@@ -2882,15 +2841,15 @@ func getNilVoteProposalHash(parentHash common.Hash, round byte) common.Hash {
 }
 
 func getCommitHash(precommitHash common.Hash) common.Hash {
-	return crypto.Keccak256Hash(precommitHash.Bytes())
+	return finality.CommitHash(precommitHash)
 }
 
 func getOkVotePreCommitHash(parentHash common.Hash, proposalHash common.Hash, round byte) common.Hash {
-	return crypto.Keccak256Hash(parentHash.Bytes(), proposalHash.Bytes(), []byte{round}, []byte{byte(VOTE_TYPE_OK)})
+	return finality.OkVotePreCommitHash(parentHash, proposalHash, round)
 }
 
 func getNilVotePreCommitHash(parentHash common.Hash, round byte) common.Hash {
-	return crypto.Keccak256Hash(parentHash.Bytes(), []byte("precommit"), ZERO_HASH.Bytes(), []byte{round}, []byte{byte(VOTE_TYPE_NIL)})
+	return finality.NilVotePreCommitHash(parentHash, round)
 }
 
 func (cph *ConsensusHandler) LogIncomingPacketStats() {
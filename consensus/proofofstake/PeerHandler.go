@@ -7,6 +7,7 @@ import (
 	"github.com/DogeProtocol/dp/eth/protocols/eth"
 	"github.com/DogeProtocol/dp/handler"
 	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/params"
 	"github.com/DogeProtocol/dp/rlp"
 	"sync"
 )
@@ -46,6 +47,8 @@ type PeerHandler struct {
 	currentParentHash  common.Hash
 	currentBlockNumber uint64
 
+	schedule *params.ProofOfStakeForkSchedule
+
 	totalBlocks                   int64
 	packetsReceivedTotal          int64
 	packetsReceivedFromRelayTotal int64
@@ -83,9 +86,14 @@ func NewPeerHandler(isConsensusRelay bool, getLatestBlockNumberFn GetLatestBlock
 		consensusRelayMap:      make(map[string]bool),
 		syncPeerMap:            make(map[string]bool),
 		packetSyncMap:          make(map[common.Hash]*PacketSyncDetails),
+		schedule:               params.DefaultProofOfStakeForkSchedule,
 	}
 }
 
+func (p *PeerHandler) SetForkSchedule(schedule *params.ProofOfStakeForkSchedule) {
+	p.schedule = schedule
+}
+
 func (p *PeerHandler) SetP2PHandler(handler *handler.P2PHandler, localPeerId string) {
 	p.p2pHandler = handler
 	p.localPeerId = localPeerId
@@ -256,7 +264,7 @@ func (p *PeerHandler) CreateConsensusPacket(data []byte) (*eth.ConsensusPacket,
 
 func (p *PeerHandler) SendCapabilityPacket(peerList []string) error {
 	log.Debug("PeerHandler SendCapabilityPacket", "peer count", len(peerList))
-	if p.p2pHandler == nil || p.isConsensusRelay == false || p.getLatestBlockNumberFn() < PACKET_PROTOCOL_START_BLOCK {
+	if p.p2pHandler == nil || p.isConsensusRelay == false || p.getLatestBlockNumberFn() < p.schedule.PacketProtocolStartBlock {
 		return nil
 	}
 
@@ -307,7 +315,7 @@ func (p *PeerHandler) ConnectAvailableConsensusRelay() {
 
 func (p *PeerHandler) SendRequestConsensusSyncPacket(peerId string) error {
 	log.Trace("PeerHandler SendRequestConsensusSyncPacket", "peerId", peerId)
-	if p.p2pHandler == nil || p.getLatestBlockNumberFn() < PACKET_PROTOCOL_START_BLOCK {
+	if p.p2pHandler == nil || p.getLatestBlockNumberFn() < p.schedule.PacketProtocolStartBlock {
 		log.Debug("PeerHandler SendRequestConsensusSyncPacket return", "peerId", peerId)
 		return nil
 	}
@@ -406,6 +414,8 @@ func (p *PeerHandler) BroadcastToConsensusRelays(packet *eth.ConsensusPacket, fr
 	p.packetsSentToRelaysCurrentParentHash = p.packetsSentToRelaysCurrentParentHash + int64(len(sendList))
 	if fromPeerId == p.localPeerId {
 		p.localPacketsSentToRelaysCurrentParentHash = p.localPacketsSentToRelaysCurrentParentHash + int64(len(sendList))
+	} else {
+		relayRebroadcastCounter.Inc(int64(len(sendList)))
 	}
 
 	go p.p2pHandler.SendConsensusPacket(sendList, packet)
@@ -469,6 +479,9 @@ func (p *PeerHandler) BroadcastToSyncPeers(packet *eth.ConsensusPacket, fromPeer
 		"packetHash", packet.Hash(), "parentHash", packet.ParentHash)
 
 	p.packetsSentCurrentParentHash = p.packetsSentCurrentParentHash + int64(len(sendPeerList))
+	if fromPeerId != p.localPeerId {
+		syncRebroadcastCounter.Inc(int64(len(sendPeerList)))
+	}
 	go p.p2pHandler.SendConsensusPacket(sendPeerList, packet)
 
 	return len(sendPeerList)
@@ -532,9 +545,9 @@ func (p *PeerHandler) SetCurrentParentHash(parentHash common.Hash, currentBlockN
 	}
 
 	if p.isConsensusRelay {
-		if currentBlockNumber == PACKET_PROTOCOL_START_BLOCK { //Special case, to trigger on-going connections
+		if currentBlockNumber == p.schedule.PacketProtocolStartBlock { //Special case, to trigger on-going connections
 			go p.SendCapabilityToAllPeers()
-		} else if currentBlockNumber > PACKET_PROTOCOL_START_BLOCK {
+		} else if currentBlockNumber > p.schedule.PacketProtocolStartBlock {
 			if len(p.peerMap) > len(p.syncPeerMap) && currentBlockNumber%128 == 0 {
 				go p.SendCapabilityToDeltaPeers()
 			}
@@ -106,7 +106,7 @@ func (api *API) GetStakingDetailsByValidatorAddress(validator common.Address, bl
 		return nil, errUnknownBlock
 	}
 
-	if blockNumber < STAKING_CONTRACT_V2_CUTOFF_BLOCK {
+	if blockNumber < api.proofofstake.schedule.StakingContractV2Block {
 		return api.proofofstake.GetStakingDetailsByValidatorAddress(validator, header.Hash())
 	} else {
 		validatorDetailsV2, err := api.proofofstake.GetStakingDetailsByValidatorAddressV2(validator, header.Hash())
@@ -154,7 +154,7 @@ func (api *API) GetStakingDetailsByDepositorAddress(depositor common.Address, bl
 		return nil, err
 	}
 
-	if blockNumber < STAKING_CONTRACT_V2_CUTOFF_BLOCK {
+	if blockNumber < api.proofofstake.schedule.StakingContractV2Block {
 		return api.proofofstake.GetStakingDetailsByValidatorAddress(validator, header.Hash())
 	} else {
 		validatorDetailsV2, err := api.proofofstake.GetStakingDetailsByValidatorAddressV2(validator, header.Hash())
@@ -284,7 +284,7 @@ func (api *API) GetBlockConsensusData(blockNumberHex string) (*ConsensusData, er
 	}
 
 	if blockConsensusData.VoteType == VOTE_TYPE_OK {
-		blockRewards := GetReward(header.Number)
+		blockRewards := GetReward(header.Number, api.proofofstake.schedule)
 		consensusData.BlockProposerRewards = hexutil.EncodeBig(blockRewards)
 	} else {
 		consensusData.BlockProposerRewards = hexutil.EncodeUint64(0)
@@ -314,6 +314,176 @@ func (api *API) GetBlockConsensusData(blockNumberHex string) (*ConsensusData, er
 	return consensusData, err
 }
 
+type RewardSchedule struct {
+	FromBlock        string          `json:"fromBlock"     gencodec:"required"`
+	ToBlock          string          `json:"toBlock"     gencodec:"required"`
+	RewardStartBlock string          `json:"rewardStartBlock"     gencodec:"required"`
+	PeriodBlocks     string          `json:"periodBlocks"     gencodec:"required"`
+	Periods          []*RewardPeriod `json:"periods"     gencodec:"required"`
+	TotalEmission    string          `json:"totalEmission"     gencodec:"required"`
+}
+
+// GetRewardSchedule returns the block proposer reward per block and per halving period,
+// and the cumulative emission between two blocks (both inclusive). The blocks may be in
+// the future. The emission assumes every block is rewarded, blocks that ended in a nil
+// vote do not pay a reward. An empty fromBlockHex starts at the genesis block and an
+// empty toBlockHex ends at the current block.
+func (api *API) GetRewardSchedule(fromBlockHex string, toBlockHex string) (*RewardSchedule, error) {
+	var fromBlock, toBlock uint64
+	var err error
+	if len(fromBlockHex) > 0 {
+		fromBlock, err = hexutil.DecodeUint64(fromBlockHex)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(toBlockHex) == 0 {
+		toBlock = api.chain.CurrentHeader().Number.Uint64()
+	} else {
+		toBlock, err = hexutil.DecodeUint64(toBlockHex)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if fromBlock > toBlock {
+		return nil, errors.New("fromBlock is after toBlock")
+	}
+
+	schedule := api.proofofstake.schedule
+	periods, total := GetRewardPeriods(fromBlock, toBlock, schedule)
+
+	return &RewardSchedule{
+		FromBlock:        hexutil.EncodeUint64(fromBlock),
+		ToBlock:          hexutil.EncodeUint64(toBlock),
+		RewardStartBlock: hexutil.EncodeUint64(schedule.RewardStartBlock),
+		PeriodBlocks:     hexutil.EncodeBig(rewardPeriodBlocks),
+		Periods:          periods,
+		TotalEmission:    hexutil.EncodeBig(total),
+	}, nil
+}
+
+type ValidatorPerformance struct {
+	Validator          common.Address `json:"validator"     gencodec:"required"`
+	FromBlock          string         `json:"fromBlock"     gencodec:"required"`
+	ToBlock            string         `json:"toBlock"     gencodec:"required"`
+	ProposalsMade      string         `json:"proposalsMade"     gencodec:"required"`
+	ProposalsMissed    string         `json:"proposalsMissed"     gencodec:"required"`
+	NilBlocksCaused    string         `json:"nilBlocksCaused"     gencodec:"required"`
+	RoundsParticipated string         `json:"roundsParticipated"     gencodec:"required"`
+	Slashings          string         `json:"slashings"     gencodec:"required"`
+	SlashedAmount      string         `json:"slashedAmount"     gencodec:"required"`
+	RewardsEarned      string         `json:"rewardsEarned"     gencodec:"required"`
+}
+
+// GetValidatorPerformance returns the track record of a validator between two blocks (both
+// inclusive): the proposals it made and missed, the nil blocks it caused by being offline
+// as the first round proposer, the rounds it signed consensus packets in, and the slashings
+// and block proposer rewards applied to it. An empty fromBlockHex starts at the genesis
+// block and an empty toBlockHex ends at the current block. Ranges that are not indexed yet
+// are limited in size.
+func (api *API) GetValidatorPerformance(validator common.Address, fromBlockHex string, toBlockHex string) (*ValidatorPerformance, error) {
+	var fromBlock, toBlock uint64
+	var err error
+	currentBlock := api.chain.CurrentHeader().Number.Uint64()
+	if len(fromBlockHex) > 0 {
+		fromBlock, err = hexutil.DecodeUint64(fromBlockHex)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(toBlockHex) == 0 {
+		toBlock = currentBlock
+	} else {
+		toBlock, err = hexutil.DecodeUint64(toBlockHex)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if fromBlock > toBlock {
+		return nil, errors.New("fromBlock is after toBlock")
+	}
+	if toBlock > currentBlock {
+		return nil, errUnknownBlock
+	}
+
+	var sections uint64
+	if api.proofofstake.performanceIndexer != nil {
+		sections, _, _ = api.proofofstake.performanceIndexer.Sections()
+	}
+	performance, err := getValidatorPerformance(api.proofofstake.db, performanceSectionSize, sections, api.chain.GetHeaderByNumber,
+		validator, fromBlock, toBlock, api.proofofstake.schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ValidatorPerformance{
+		Validator:          validator,
+		FromBlock:          hexutil.EncodeUint64(fromBlock),
+		ToBlock:            hexutil.EncodeUint64(toBlock),
+		ProposalsMade:      hexutil.EncodeUint64(performance.ProposalsMade),
+		ProposalsMissed:    hexutil.EncodeUint64(performance.ProposalsMissed),
+		NilBlocksCaused:    hexutil.EncodeUint64(performance.NilBlocksCaused),
+		RoundsParticipated: hexutil.EncodeUint64(performance.RoundsParticipated),
+		Slashings:          hexutil.EncodeUint64(performance.Slashings),
+		SlashedAmount:      hexutil.EncodeBig(performance.SlashedAmount),
+		RewardsEarned:      hexutil.EncodeBig(performance.RewardsEarned),
+	}, nil
+}
+
+type FinalityCertificateResult struct {
+	BlockNumber string        `json:"blockNumber"     gencodec:"required"`
+	BlockHash   common.Hash   `json:"blockHash"     gencodec:"required"`
+	Certificate hexutil.Bytes `json:"certificate"     gencodec:"required"`
+}
+
+// GetFinalityCertificate returns the RLP encoded finality certificate of a committed block:
+// the block header, the commit packets of its final round, the validators and deposits at
+// its parent and the consensus context. The certificate can be verified with the
+// consensus/proofofstake/finality package, without trusting this node. An empty
+// blockNumberHex returns the certificate of the current block.
+func (api *API) GetFinalityCertificate(blockNumberHex string) (*FinalityCertificateResult, error) {
+	var blockNumber uint64
+	var err error
+	if len(blockNumberHex) == 0 {
+		blockNumber = api.chain.CurrentHeader().Number.Uint64()
+	} else {
+		blockNumber, err = hexutil.DecodeUint64(blockNumberHex)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	header := api.chain.GetHeaderByNumber(blockNumber)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+
+	validators, err := api.proofofstake.GetValidators(header.ParentHash)
+	if err != nil {
+		return nil, err
+	}
+	consensusContext, err := GetBlockConsensusContextHash(blockNumber, header.ParentHash, api.proofofstake.GetConsensusContext,
+		api.proofofstake.GetValidators, api.proofofstake.schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	certificate, err := NewFinalityCertificate(header, validators, consensusContext)
+	if err != nil {
+		return nil, err
+	}
+	data, err := rlp.EncodeToBytes(certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FinalityCertificateResult{
+		BlockNumber: hexutil.EncodeUint64(blockNumber),
+		BlockHash:   header.Hash(),
+		Certificate: data,
+	}, nil
+}
+
 type ConversionDetails struct {
 	EthAddress     common.Address `json:"ethAddress"     gencodec:"required"`
 	QuantumAddress common.Address `json:"quantumAddress"     gencodec:"required"`
@@ -501,7 +671,7 @@ func (api *API) GetBlockConsensusContext(blockNumber uint64) ([32]byte, error) {
 	currentheader := api.chain.CurrentHeader()
 
 	var context [32]byte
-	key, err := GetConsensusContextKey(blockNumber)
+	key, err := GetConsensusContextKey(blockNumber, api.proofofstake.schedule)
 	if err != nil {
 		return context, err
 	}
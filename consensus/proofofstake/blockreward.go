@@ -1,13 +1,11 @@
 package proofofstake
 
 import (
-	"fmt"
 	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
 	"github.com/DogeProtocol/dp/core/state"
 	"github.com/DogeProtocol/dp/params"
-	"math"
 	"math/big"
-	"strings"
 )
 
 var (
@@ -19,53 +17,173 @@ var (
 	blockSecond = 6
 	blockYearly = big.NewInt(int64((((60 * 60) * 24) / blockSecond) * 365))
 
-	rewardStartBlock = big.NewInt(int64(rewardStartBlockNumber))
+	// rewardPeriodBlocks is the number of blocks after which the block reward halves.
+	rewardPeriodBlocks = new(big.Int).Mul(blockYearly, percentageChangeYear)
+
+	weiPerEther = big.NewInt(params.Ether)
+
+	// The block reward of period n is
+	//
+	//   totalCoin * percentageDefault / percentageDivided^(n+1) / percentageDivided / 100 / blockYearly
+	//
+	// Mainnet computed this in float64. All steps except the division by blockYearly only
+	// scale by powers of two, so they are exact and the single rounding step can be done
+	// once, for the reward of the first period. rewardMantissa * 2^-rewardShift is that
+	// float64 value, the reward of period n is the same value shifted by n more bits.
+	rewardMantissa, rewardShift = float64Quo(
+		new(big.Int).Mul(totalCoin, percentageDefault),
+		new(big.Int).Mul(new(big.Int).Mul(percentageDivided, percentageDivided), new(big.Int).Mul(big.NewInt(100), blockYearly)))
 )
 
-func GetReward(blockNumber *big.Int) *big.Int {
-
-	blockReward := big.NewInt(0)
+// GetReward returns the block proposer reward in wei for the given block number.
+func GetReward(blockNumber *big.Int, schedule *params.ProofOfStakeForkSchedule) *big.Int {
+	period, ok := GetRewardPeriod(blockNumber, schedule)
+	if !ok {
+		return big.NewInt(0)
+	}
+	return GetPeriodReward(period)
+}
 
-	if rewardStartBlock.Int64() <= blockNumber.Int64() {
-		//Step 0
-		block := common.SafeSubBigInt(blockNumber, rewardStartBlock)
-		s := common.SafeDivBigInt(block, blockYearly)
-		s0 := common.SafeDivBigInt(s, percentageChangeYear)
+// GetRewardPeriod returns the halving period the block number belongs to. It returns
+// false if the block is before the reward start block.
+func GetRewardPeriod(blockNumber *big.Int, schedule *params.ProofOfStakeForkSchedule) (uint64, bool) {
+	rewardStartBlock := new(big.Int).SetUint64(schedule.RewardStartBlock)
+	if blockNumber.Cmp(rewardStartBlock) < 0 {
+		return 0, false
+	}
+	period := common.SafeDivBigInt(common.SafeSubBigInt(blockNumber, rewardStartBlock), rewardPeriodBlocks)
+	return period.Uint64(), true
+}
 
-		//Step 1
-		s1 := common.SafeAddBigInt(s0, big.NewInt(1))
+// GetPeriodReward returns the per block reward in wei of the given halving period.
+//
+// The value matches the original float64 formula bit for bit: the per block reward in
+// ether rounded to a float64, truncated to its integer part plus its fraction printed
+// with 18 decimals (round half to even). If the fraction rounds up to a whole ether the
+// carry is lost, as it was before.
+func GetPeriodReward(period uint64) *big.Int {
+	shift := uint64(rewardShift) + period
+
+	scaled := new(big.Int).Mul(rewardMantissa, weiPerEther)
+	if uint64(scaled.BitLen()) < shift {
+		// Less than half a wei
+		return big.NewInt(0)
+	}
+	whole := new(big.Int).Rsh(rewardMantissa, uint(shift))
+	whole.Mul(whole, weiPerEther)
 
-		//Step 2
-		s2 := MathPow(int(percentageDivided.Int64()), int(s1.Int64()))
+	fraction := roundShift(scaled, uint(shift))
+	fraction.Mod(fraction, weiPerEther)
 
-		//Step 3
-		s3 := (float64(percentageDefault.Int64()) / s2) / float64(percentageDivided.Int64())
+	return whole.Add(whole, fraction)
+}
 
-		//Step 4 (1 Year Reward)
-		totalReward := (float64(totalCoin.Int64()) * s3) / 100
+// RewardPeriod is the block reward of one halving period within a block range.
+type RewardPeriod struct {
+	Period      uint64 `json:"period"     gencodec:"required"`
+	StartBlock  string `json:"startBlock"     gencodec:"required"`
+	EndBlock    string `json:"endBlock"     gencodec:"required"`
+	BlockReward string `json:"blockReward"     gencodec:"required"`
+	Emission    string `json:"emission"     gencodec:"required"`
+}
 
-		//Step 5 (Block reward)
-		perBlock := big.NewFloat(totalReward / float64(blockYearly.Int64()))
-		blockReward = etherToWeiFloat(perBlock)
+// GetRewardPeriods splits the inclusive block range into the halving periods it
+// covers and returns the per block reward and the emission of each of them, along
+// with the total emission of the range. Blocks before the reward start block are
+// left out. Once the reward has dropped to zero the rest of the range is returned
+// as a single zero reward entry.
+func GetRewardPeriods(fromBlock uint64, toBlock uint64, schedule *params.ProofOfStakeForkSchedule) ([]*RewardPeriod, *big.Int) {
+	periods := make([]*RewardPeriod, 0)
+	total := big.NewInt(0)
+
+	start := fromBlock
+	if start < schedule.RewardStartBlock {
+		start = schedule.RewardStartBlock
+	}
+	if start > toBlock {
+		return periods, total
+	}
 
+	periodBlocks := rewardPeriodBlocks.Uint64()
+	period := (start - schedule.RewardStartBlock) / periodBlocks
+	for {
+		reward := GetPeriodReward(period)
+		end := toBlock
+		if reward.Sign() > 0 {
+			if periodEnd := schedule.RewardStartBlock + (period+1)*periodBlocks - 1; periodEnd < end {
+				end = periodEnd
+			}
+		}
+
+		emission := new(big.Int).Mul(reward, new(big.Int).SetUint64(end-start+1))
+		total.Add(total, emission)
+		periods = append(periods, &RewardPeriod{
+			Period:      period,
+			StartBlock:  hexutil.EncodeUint64(start),
+			EndBlock:    hexutil.EncodeUint64(end),
+			BlockReward: hexutil.EncodeBig(reward),
+			Emission:    hexutil.EncodeBig(emission),
+		})
+
+		if end == toBlock {
+			break
+		}
+		start = end + 1
+		period++
 	}
 
-	return blockReward
+	return periods, total
 }
 
-// MathPow calculates n to the mth power with the math.Pow() function
-func MathPow(n, m int) float64 {
-	return math.Pow(float64(n), float64(m))
+// float64Quo returns the 53 bit mantissa and the shift of num / den rounded to
+// the nearest float64 (half to even), so that the result is mant * 2^-shift. The
+// quotient is assumed to be a normal float64 with an integer part below 2^53.
+func float64Quo(num, den *big.Int) (*big.Int, int) {
+	shift := 53 - (num.BitLen() - den.BitLen())
+	var q, r *big.Int
+	for {
+		n, d := new(big.Int).Set(num), new(big.Int).Set(den)
+		if shift >= 0 {
+			n.Lsh(n, uint(shift))
+		} else {
+			d.Lsh(d, uint(-shift))
+		}
+		q, r = new(big.Int).QuoRem(n, d, new(big.Int))
+		switch {
+		case q.BitLen() > 53:
+			shift--
+			continue
+		case q.BitLen() < 53:
+			shift++
+			continue
+		}
+		if c := new(big.Int).Lsh(r, 1).Cmp(d); c > 0 || (c == 0 && q.Bit(0) == 1) {
+			q.Add(q, common.Big1)
+		}
+		break
+	}
+	if q.BitLen() > 53 {
+		q.Rsh(q, 1)
+		shift--
+	}
+	return q, shift
 }
 
-func etherToWeiFloat(eth *big.Float) *big.Int {
-	truncInt, _ := eth.Int(nil)
-	truncInt = new(big.Int).Mul(truncInt, big.NewInt(params.Ether))
-	fracStr := strings.Split(fmt.Sprintf("%.18f", eth), ".")[1]
-	fracStr += strings.Repeat("0", 18-len(fracStr))
-	fracInt, _ := new(big.Int).SetString(fracStr, 10)
-	wei := new(big.Int).Add(truncInt, fracInt)
-	return wei
+// roundShift returns x / 2^shift rounded to the nearest integer, half to even.
+func roundShift(x *big.Int, shift uint) *big.Int {
+	if shift == 0 {
+		return new(big.Int).Set(x)
+	}
+	q := new(big.Int).Rsh(x, shift)
+	if x.Bit(int(shift-1)) == 1 {
+		rest := new(big.Int).Lsh(q, shift)
+		rest.Sub(x, rest)
+		half := new(big.Int).Lsh(common.Big1, shift-1)
+		if rest.Cmp(half) > 0 || q.Bit(0) == 1 {
+			q.Add(q, common.Big1)
+		}
+	}
+	return q
 }
 
 func (c *ProofOfStake) accumulateBalance(state *state.StateDB, amount *big.Int, addr common.Address) error {
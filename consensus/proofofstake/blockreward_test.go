@@ -3,9 +3,13 @@ package proofofstake
 import (
 	"fmt"
 	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
 	"github.com/DogeProtocol/dp/params"
 	"github.com/stretchr/testify/assert"
+	"math"
 	"math/big"
+	"math/rand"
+	"strings"
 	"testing"
 )
 
@@ -26,6 +30,8 @@ var blockRewardTotal = []float64{951293.759512938, 475646.879756469, 237823.4398
 
 var blockStartRang = int64(1497600)
 
+var rewardStartBlock = new(big.Int).SetUint64(params.DefaultProofOfStakeForkSchedule.RewardStartBlock)
+
 var blockEndRange = []int64{22521600, 43545600, 64569600, 85593600, 106617600, 127641600, 148665600, 169689600, 190713600, 211737600, 232761600, 253785600,
 	274809600, 295833600, 316857600, 337881600, 358905600, 379929600, 400953600, 421977600, 443001600, 464025600, 485049600, 506073600,
 	527097600, 548121600, 569145600, 590169600, 611193600, 632217600, 653241600, 674265600, 695289600, 716313600, 737337600, 758361600,
@@ -39,10 +45,10 @@ func TestRewardGenerateYearly(t *testing.T) {
 	for i := 1; i <= 350; i++ {
 		blockNumber := rewardStartBlock.Int64() + (blockYearly.Int64() * int64(i))
 		startBlockNumber := big.NewInt(blockNumber - blockYearly.Int64())
-		startReward := new(big.Int).Set(GetReward(startBlockNumber))
+		startReward := new(big.Int).Set(GetReward(startBlockNumber, params.DefaultProofOfStakeForkSchedule))
 
 		endBlockNumber := big.NewInt(blockNumber - 1)
-		endReward := new(big.Int).Set(GetReward(endBlockNumber))
+		endReward := new(big.Int).Set(GetReward(endBlockNumber, params.DefaultProofOfStakeForkSchedule))
 
 		fmt.Println("Year : ", i,
 			" Block Range : ", startBlockNumber, " - ", endBlockNumber,
@@ -56,7 +62,7 @@ func TestRewardGenerateBlocks1(t *testing.T) {
 	incrementBlock := big.NewInt(1)
 
 	for startBlockNumber.Int64() <= endBlockNumber.Int64() {
-		reward := new(big.Int).Set(GetReward(startBlockNumber))
+		reward := new(big.Int).Set(GetReward(startBlockNumber, params.DefaultProofOfStakeForkSchedule))
 		fmt.Println("Block Number : ", startBlockNumber, " reward : ", reward)
 		startBlockNumber = common.SafeAddBigInt(startBlockNumber, incrementBlock)
 	}
@@ -68,7 +74,7 @@ func TestRewardGenerateBlocks(t *testing.T) {
 	incrementBlock := big.NewInt(1)
 
 	for startBlockNumber.Int64() <= endBlockNumber.Int64() {
-		reward := new(big.Int).Set(GetReward(startBlockNumber))
+		reward := new(big.Int).Set(GetReward(startBlockNumber, params.DefaultProofOfStakeForkSchedule))
 		fmt.Println("Block Number : ", startBlockNumber, " reward : ", reward)
 		startBlockNumber = common.SafeAddBigInt(startBlockNumber, incrementBlock)
 	}
@@ -78,14 +84,14 @@ func TestRewardVerifyYearly(t *testing.T) {
 	for i := 1; i <= 12; i++ {
 		blockNumber := rewardStartBlock.Int64() - 1 + (blockYearly.Int64() * int64(i))
 		startBlockNumber := big.NewInt(blockNumber - blockYearly.Int64())
-		startReward := new(big.Int).Set(GetReward(startBlockNumber))
+		startReward := new(big.Int).Set(GetReward(startBlockNumber, params.DefaultProofOfStakeForkSchedule))
 
 		r1 := params.WeiToEther(getTestReward(startBlockNumber))
 		r2 := params.WeiToEther(startReward)
 		assert.Equal(t, r1, r2)
 
 		endBlockNumber := big.NewInt(blockNumber - 1)
-		endReward := new(big.Int).Set(GetReward(endBlockNumber))
+		endReward := new(big.Int).Set(GetReward(endBlockNumber, params.DefaultProofOfStakeForkSchedule))
 
 		r1 = params.WeiToEther(getTestReward(endBlockNumber))
 		r2 = params.WeiToEther(endReward)
@@ -94,12 +100,12 @@ func TestRewardVerifyYearly(t *testing.T) {
 }
 
 func TestRewardVerifyBlocks(t *testing.T) {
-	startBlockNumber := big.NewInt(int64(rewardStartBlockNumber) - 1000)
-	endBlockNumber := big.NewInt(int64(rewardStartBlockNumber - 500))
+	startBlockNumber := big.NewInt(rewardStartBlock.Int64() - 1000)
+	endBlockNumber := big.NewInt(rewardStartBlock.Int64() - 500)
 	incrementBlock := big.NewInt(1)
 
 	for startBlockNumber.Int64() <= endBlockNumber.Int64() {
-		reward := new(big.Int).Set(GetReward(startBlockNumber))
+		reward := new(big.Int).Set(GetReward(startBlockNumber, params.DefaultProofOfStakeForkSchedule))
 		r1 := params.WeiToEther(getTestReward(startBlockNumber))
 		r2 := params.WeiToEther(reward)
 		assert.Equal(t, r1, r2)
@@ -123,3 +129,130 @@ func getTestReward(blockNumber *big.Int) *big.Int {
 	}
 	return reward
 }
+
+// getRewardFloat is the float64 block reward formula mainnet used before GetReward
+// was made exact. It is kept as the reference for the historical values.
+func getRewardFloat(blockNumber *big.Int) *big.Int {
+	blockReward := big.NewInt(0)
+
+	if rewardStartBlock.Int64() <= blockNumber.Int64() {
+		//Step 0
+		block := common.SafeSubBigInt(blockNumber, rewardStartBlock)
+		s := common.SafeDivBigInt(block, blockYearly)
+		s0 := common.SafeDivBigInt(s, percentageChangeYear)
+
+		//Step 1
+		s1 := common.SafeAddBigInt(s0, big.NewInt(1))
+
+		//Step 2
+		s2 := math.Pow(float64(percentageDivided.Int64()), float64(s1.Int64()))
+
+		//Step 3
+		s3 := (float64(percentageDefault.Int64()) / s2) / float64(percentageDivided.Int64())
+
+		//Step 4 (1 Year Reward)
+		totalReward := (float64(totalCoin.Int64()) * s3) / 100
+
+		//Step 5 (Block reward)
+		perBlock := big.NewFloat(totalReward / float64(blockYearly.Int64()))
+		blockReward = etherToWeiFloat(perBlock)
+	}
+
+	return blockReward
+}
+
+func etherToWeiFloat(eth *big.Float) *big.Int {
+	truncInt, _ := eth.Int(nil)
+	truncInt = new(big.Int).Mul(truncInt, big.NewInt(params.Ether))
+	fracStr := strings.Split(fmt.Sprintf("%.18f", eth), ".")[1]
+	fracStr += strings.Repeat("0", 18-len(fracStr))
+	fracInt, _ := new(big.Int).SetString(fracStr, 10)
+	wei := new(big.Int).Add(truncInt, fracInt)
+	return wei
+}
+
+func TestRewardMatchesFloat(t *testing.T) {
+	schedule := params.DefaultProofOfStakeForkSchedule
+	periodBlocks := rewardPeriodBlocks.Int64()
+
+	heights := []int64{0, 1, rewardStartBlock.Int64() - 1}
+	for i := int64(0); i <= 1100; i++ {
+		start := rewardStartBlock.Int64() + i*periodBlocks
+		heights = append(heights, start, start+1, start+periodBlocks/2, start+periodBlocks-1)
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		heights = append(heights, r.Int63n(200*periodBlocks))
+	}
+
+	for _, h := range heights {
+		blockNumber := big.NewInt(h)
+		want := getRewardFloat(blockNumber)
+		have := GetReward(blockNumber, schedule)
+		if have.Cmp(want) != 0 {
+			t.Fatalf("block %d: reward mismatch, have %v want %v", h, have, want)
+		}
+	}
+}
+
+func TestRewardValues(t *testing.T) {
+	tests := []struct {
+		blockNumber int64
+		reward      string
+	}{
+		{0, "0"},
+		{rewardStartBlock.Int64() - 1, "0"},
+		{rewardStartBlock.Int64(), "951293759512937627732754"},
+		{rewardStartBlock.Int64() + rewardPeriodBlocks.Int64() - 1, "951293759512937627732754"},
+		{rewardStartBlock.Int64() + rewardPeriodBlocks.Int64(), "475646879756468813866377"},
+		{rewardStartBlock.Int64() + 10*rewardPeriodBlocks.Int64(), "928997812024353152083"},
+		{rewardStartBlock.Int64() + 200*rewardPeriodBlocks.Int64(), "0"},
+	}
+	for _, test := range tests {
+		reward := GetReward(big.NewInt(test.blockNumber), params.DefaultProofOfStakeForkSchedule)
+		if reward.String() != test.reward {
+			t.Errorf("block %d: reward %v, want %v", test.blockNumber, reward, test.reward)
+		}
+	}
+}
+
+func TestRewardPeriods(t *testing.T) {
+	schedule := params.DefaultProofOfStakeForkSchedule
+	periodBlocks := rewardPeriodBlocks.Uint64()
+	from := schedule.RewardStartBlock - 10
+	to := schedule.RewardStartBlock + 2*periodBlocks + 5
+
+	periods, total := GetRewardPeriods(from, to, schedule)
+	if len(periods) != 3 {
+		t.Fatalf("expected 3 periods, got %d", len(periods))
+	}
+
+	want := big.NewInt(0)
+	for i := uint64(0); i < 3; i++ {
+		blocks := periodBlocks
+		if i == 2 {
+			blocks = 6
+		}
+		want.Add(want, new(big.Int).Mul(GetPeriodReward(i), new(big.Int).SetUint64(blocks)))
+	}
+	if total.Cmp(want) != 0 {
+		t.Fatalf("total emission %v, want %v", total, want)
+	}
+	if periods[0].StartBlock != hexutil.EncodeUint64(schedule.RewardStartBlock) {
+		t.Fatalf("unexpected start block %v", periods[0].StartBlock)
+	}
+	if periods[2].EndBlock != hexutil.EncodeUint64(to) {
+		t.Fatalf("unexpected end block %v", periods[2].EndBlock)
+	}
+
+	// Far in the future the reward is zero and the remaining range is a single entry
+	periods, _ = GetRewardPeriods(schedule.RewardStartBlock+150*periodBlocks, math.MaxUint64, schedule)
+	if len(periods) != 1 || periods[0].BlockReward != "0x0" {
+		t.Fatalf("unexpected zero reward periods %v", periods)
+	}
+
+	periods, total = GetRewardPeriods(0, schedule.RewardStartBlock-1, schedule)
+	if len(periods) != 0 || total.Sign() != 0 {
+		t.Fatalf("expected no reward before the reward start block")
+	}
+}
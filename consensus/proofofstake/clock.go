@@ -0,0 +1,16 @@
+package proofofstake
+
+import "time"
+
+// Clock is the time source of the consensus handler. Handlers run on the system clock
+// unless another clock is set, which lets a simulation drive many handlers through their
+// timeouts on a virtual clock instead of waiting for them.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/DogeProtocol/dp/core/types"
 	"github.com/DogeProtocol/dp/internal/ethapi"
 	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/params"
 	"github.com/DogeProtocol/dp/rpc"
 	"github.com/DogeProtocol/dp/systemcontracts/consensuscontext"
 	"math"
@@ -134,10 +135,10 @@ func (p *ProofOfStake) GetConsensusContext(key string, blockHash common.Hash) ([
 	return out, nil
 }
 
-func GetConsensusContextKey(blockNumber uint64) (string, error) {
+func GetConsensusContextKey(blockNumber uint64, schedule *params.ProofOfStakeForkSchedule) (string, error) {
 	var key string
-	if blockNumber <= CONSENSUS_CONTEXT_START_BLOCK {
-		return key, errors.New("GetBlockConsensusContextFn blockNumber below CONSENSUS_CONTEXT_START_BLOCK")
+	if blockNumber <= schedule.ConsensusContextStartBlock {
+		return key, errors.New("GetBlockConsensusContextFn blockNumber below ConsensusContextStartBlock")
 	}
 
 	//bc for block context
@@ -146,15 +147,15 @@ func GetConsensusContextKey(blockNumber uint64) (string, error) {
 	return key, nil
 }
 
-func GetBlockConsensusContextKeyForBlock(currrentBlockNumber uint64) (string, error) {
+func GetBlockConsensusContextKeyForBlock(currrentBlockNumber uint64, schedule *params.ProofOfStakeForkSchedule) (string, error) {
 	var key string
-	if currrentBlockNumber < CONTEXT_BASED_START_BLOCK {
-		return key, errors.New("GetBlockConsensusContextFn blockNumber below CONTEXT_BASED_START_BLOCK")
+	if currrentBlockNumber < schedule.ContextBasedStartBlock {
+		return key, errors.New("GetBlockConsensusContextFn blockNumber below ContextBasedStartBlock")
 	}
 
-	if currrentBlockNumber > CONSENSUS_CONTEXT_START_BLOCK+CONSENSUS_CONTEXT_MAX_BLOCK_COUNT {
-		return GetConsensusContextKey(currrentBlockNumber - CONSENSUS_CONTEXT_MAX_BLOCK_COUNT)
+	if currrentBlockNumber > schedule.ConsensusContextStartBlock+CONSENSUS_CONTEXT_MAX_BLOCK_COUNT {
+		return GetConsensusContextKey(currrentBlockNumber-CONSENSUS_CONTEXT_MAX_BLOCK_COUNT, schedule)
 	} else {
-		return GetConsensusContextKey(currrentBlockNumber - CONTEXT_BASED_BLOCK_THRESHOLD)
+		return GetConsensusContextKey(currrentBlockNumber-CONTEXT_BASED_BLOCK_THRESHOLD, schedule)
 	}
 }
@@ -0,0 +1,379 @@
+package proofofstake
+
+import (
+	"bytes"
+	"errors"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/consensus"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/eth/protocols/eth"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/rlp"
+	"math/big"
+	"sort"
+)
+
+var InvalidEvidenceErr = errors.New("invalid equivocation evidence")
+
+// EQUIVOCATION_EVIDENCE_WINDOW is how many recent parent hashes a block may include
+// equivocation evidence for, so that conflicting packets that arrive after the block of
+// their parent hash was committed can still be slashed.
+var EQUIVOCATION_EVIDENCE_WINDOW = uint64(16)
+
+// EquivocationEvidence proves that a validator signed two different packets of the same
+// type for the same parent hash and round. Both packets share the parent hash, so it is
+// carried once along with the data and signature of each packet. The packets are ordered by their data, so that
+// a conflicting pair has a single encoding.
+type EquivocationEvidence struct {
+	ParentHash      common.Hash `json:"parentHash" gencodec:"required"`
+	FirstData       []byte      `json:"firstData" gencodec:"required"`
+	FirstSignature  []byte      `json:"firstSignature" gencodec:"required"`
+	SecondData      []byte      `json:"secondData" gencodec:"required"`
+	SecondSignature []byte      `json:"secondSignature" gencodec:"required"`
+}
+
+// EquivocationWindow is the set of recent parent hashes that the evidence of a block may
+// be for, along with the validators already slashed for each of them by the ancestors of
+// the block.
+type EquivocationWindow struct {
+	parents map[common.Hash]bool
+	slashed map[common.Hash]map[common.Address]bool
+}
+
+type voteKey struct {
+	validator  common.Address
+	round      byte
+	packetType ConsensusPacketType
+}
+
+// NewEquivocationEvidence creates the evidence of two conflicting packets of the same validator.
+func NewEquivocationEvidence(a *eth.ConsensusPacket, b *eth.ConsensusPacket) *EquivocationEvidence {
+	if bytes.Compare(a.ConsensusData, b.ConsensusData) > 0 {
+		a, b = b, a
+	}
+	return &EquivocationEvidence{
+		ParentHash:      a.ParentHash,
+		FirstData:       common.CopyBytes(a.ConsensusData),
+		FirstSignature:  common.CopyBytes(a.Signature),
+		SecondData:      common.CopyBytes(b.ConsensusData),
+		SecondSignature: common.CopyBytes(b.Signature),
+	}
+}
+
+// Packets returns the two conflicting signed packets of the evidence.
+func (e *EquivocationEvidence) Packets() (*eth.ConsensusPacket, *eth.ConsensusPacket) {
+	first := &eth.ConsensusPacket{ParentHash: e.ParentHash, ConsensusData: e.FirstData, Signature: e.FirstSignature}
+	second := &eth.ConsensusPacket{ParentHash: e.ParentHash, ConsensusData: e.SecondData, Signature: e.SecondSignature}
+	return first, second
+}
+
+// Verify checks the signatures of both packets and that they conflict, and returns the
+// validator that signed them.
+func (e *EquivocationEvidence) Verify() (common.Address, error) {
+	if len(e.FirstData) == 0 || len(e.SecondData) == 0 || bytes.Compare(e.FirstData, e.SecondData) >= 0 {
+		return ZERO_ADDRESS, InvalidEvidenceErr
+	}
+
+	first, second := e.Packets()
+	firstType, firstRound, err := decodePacketRound(first.ConsensusData)
+	if err != nil {
+		return ZERO_ADDRESS, err
+	}
+	secondType, secondRound, err := decodePacketRound(second.ConsensusData)
+	if err != nil {
+		return ZERO_ADDRESS, err
+	}
+	if firstType != secondType || firstRound != secondRound || firstRound < 1 || firstRound > MAX_ROUND {
+		return ZERO_ADDRESS, InvalidEvidenceErr
+	}
+
+	firstSigner, err := recoverPacketSigner(first, firstType)
+	if err != nil {
+		return ZERO_ADDRESS, err
+	}
+	secondSigner, err := recoverPacketSigner(second, secondType)
+	if err != nil {
+		return ZERO_ADDRESS, err
+	}
+	if firstSigner.IsEqualTo(secondSigner) == false {
+		return ZERO_ADDRESS, InvalidEvidenceErr
+	}
+
+	return firstSigner, nil
+}
+
+// validatedSigner returns the validator that signed the evidence of a block that was
+// already validated on import, without verifying the signatures again.
+func (e *EquivocationEvidence) validatedSigner() (common.Address, error) {
+	first, _ := e.Packets()
+	return validatedPacketSigner(first)
+}
+
+// decodePacketRound returns the type and the round of a proposal, ack, precommit or commit packet.
+func decodePacketRound(consensusData []byte) (ConsensusPacketType, byte, error) {
+	if len(consensusData) == 0 {
+		return 0, 0, InvalidPacketErr
+	}
+	var startIndex int
+	if consensusData[0] >= MinConsensusNetworkProtocolVersion {
+		startIndex = 2
+	} else {
+		startIndex = 1
+	}
+	if len(consensusData) <= startIndex {
+		return 0, 0, InvalidPacketErr
+	}
+
+	packetType := ConsensusPacketType(consensusData[startIndex-1])
+	var round byte
+	var err error
+	switch packetType {
+	case CONSENSUS_PACKET_TYPE_PROPOSE_BLOCK:
+		details := ProposalDetails{}
+		err = rlp.DecodeBytes(consensusData[startIndex:], &details)
+		round = details.Round
+	case CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL:
+		details := ProposalAckDetails{}
+		err = rlp.DecodeBytes(consensusData[startIndex:], &details)
+		round = details.Round
+	case CONSENSUS_PACKET_TYPE_PRECOMMIT_BLOCK:
+		details := PreCommitDetails{}
+		err = rlp.DecodeBytes(consensusData[startIndex:], &details)
+		round = details.Round
+	case CONSENSUS_PACKET_TYPE_COMMIT_BLOCK:
+		details := CommitDetails{}
+		err = rlp.DecodeBytes(consensusData[startIndex:], &details)
+		round = details.Round
+	default:
+		return 0, 0, InvalidPacketErr
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return packetType, round, nil
+}
+
+func recoverPacketSigner(packet *eth.ConsensusPacket, packetType ConsensusPacketType) (common.Address, error) {
+	if isFullSignedProposal(packet, packetType) == false {
+		validator, err := verifyPacketSignature(packet)
+		if err != nil {
+			return ZERO_ADDRESS, InvalidPacketErr
+		}
+		return validator, nil
+	}
+
+	sigAlg, err := cryptobase.SigAlgFromCombinedSignature(packet.Signature)
+	if err != nil {
+		return ZERO_ADDRESS, InvalidPacketErr
+	}
+	dataToVerify := append(packet.ParentHash.Bytes(), packet.ConsensusData...)
+	digestHash := crypto.Keccak256(dataToVerify)
+	pubKey, err := sigAlg.PublicKeyFromSignatureWithContext(digestHash, packet.Signature, FULL_SIGN_CONTEXT)
+	if err != nil {
+		return ZERO_ADDRESS, InvalidPacketErr
+	}
+	if sigAlg.VerifyWithContext(pubKey.PubData, digestHash, packet.Signature, FULL_SIGN_CONTEXT) == false {
+		return ZERO_ADDRESS, InvalidPacketErr
+	}
+
+	return sigAlg.PublicKeyToAddress(pubKey)
+}
+
+// NewEquivocationWindow returns the window of a block that only accepts evidence for the
+// parent hash of the block.
+func NewEquivocationWindow(parentHash common.Hash) *EquivocationWindow {
+	return &EquivocationWindow{
+		parents: map[common.Hash]bool{parentHash: true},
+		slashed: make(map[common.Hash]map[common.Address]bool),
+	}
+}
+
+// getEquivocationWindow returns the window of the block with the given parent hash and
+// number, from the last EQUIVOCATION_EVIDENCE_WINDOW parent hashes of the chain.
+func getEquivocationWindow(chain consensus.ChainHeaderReader, parentHash common.Hash, number uint64, schedule *params.ProofOfStakeForkSchedule) (*EquivocationWindow, error) {
+	window := NewEquivocationWindow(parentHash)
+	if number < schedule.EquivocationSlashStartBlock {
+		return window, nil
+	}
+
+	hash := parentHash
+	for i := uint64(1); i < EQUIVOCATION_EVIDENCE_WINDOW && i < number; i++ {
+		header := chain.GetHeader(hash, number-i)
+		if header == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		err := window.addAncestor(header, schedule)
+		if err != nil {
+			return nil, err
+		}
+		hash = header.ParentHash
+	}
+
+	return window, nil
+}
+
+// addAncestor adds the parent hash of an ancestor of the block to the window, and records
+// the validators slashed by the evidence of the ancestor.
+func (w *EquivocationWindow) addAncestor(header *types.Header, schedule *params.ProofOfStakeForkSchedule) error {
+	w.parents[header.ParentHash] = true
+	if header.Number.Uint64() < schedule.EquivocationSlashStartBlock {
+		return nil
+	}
+
+	blockConsensusData := &BlockConsensusData{}
+	err := rlp.DecodeBytes(header.ConsensusData, blockConsensusData)
+	if err != nil {
+		return err
+	}
+	for i := range blockConsensusData.Evidence {
+		validator, err := blockConsensusData.Evidence[i].validatedSigner()
+		if err != nil {
+			return err
+		}
+		parentHash := blockConsensusData.Evidence[i].ParentHash
+		if w.slashed[parentHash] == nil {
+			w.slashed[parentHash] = make(map[common.Address]bool)
+		}
+		w.slashed[parentHash][validator] = true
+	}
+
+	return nil
+}
+
+// contains returns whether the block may include evidence for parentHash.
+func (w *EquivocationWindow) contains(parentHash common.Hash) bool {
+	return w.parents[parentHash]
+}
+
+// isSlashed returns whether an ancestor of the block already included evidence of the
+// validator for parentHash.
+func (w *EquivocationWindow) isSlashed(parentHash common.Hash, validator common.Address) bool {
+	return w.slashed[parentHash][validator]
+}
+
+// ValidateEquivocationEvidence checks the evidence included in a block. Evidence is only
+// accepted for the parent hashes in the window of the block, and only if no ancestor
+// already included evidence of the validator for the same parent hash, so that an
+// equivocation is slashed at most once. Each validator is slashed at most once per block,
+// and only once the equivocation slash fork is active.
+func ValidateEquivocationEvidence(window *EquivocationWindow, evidence []EquivocationEvidence, filteredValidatorDepositMap map[common.Address]*big.Int,
+	blockNumber uint64, schedule *params.ProofOfStakeForkSchedule) error {
+	if len(evidence) == 0 {
+		return nil
+	}
+	if blockNumber < schedule.EquivocationSlashStartBlock {
+		return errors.New("equivocation evidence before fork")
+	}
+	if len(evidence) > len(filteredValidatorDepositMap) {
+		return errors.New("too much equivocation evidence")
+	}
+
+	seen := make(map[common.Address]bool)
+	for i := range evidence {
+		if window.contains(evidence[i].ParentHash) == false {
+			return errors.New("equivocation evidence parent hash not in window")
+		}
+		validator, err := evidence[i].Verify()
+		if err != nil {
+			return err
+		}
+		if _, ok := filteredValidatorDepositMap[validator]; ok == false {
+			return errors.New("equivocation evidence validator not part of block")
+		}
+		if seen[validator] {
+			return errors.New("duplicate equivocation evidence")
+		}
+		if window.isSlashed(evidence[i].ParentHash, validator) {
+			return errors.New("equivocation already slashed")
+		}
+		seen[validator] = true
+	}
+
+	return nil
+}
+
+// detectEquivocation remembers the first packet of each validator per round and packet
+// type of the block, and retains evidence when the validator signs a different packet
+// for the same slot. Only the first evidence per validator is kept.
+func (cph *ConsensusHandler) detectEquivocation(validator common.Address, packetType ConsensusPacketType, packet *eth.ConsensusPacket) {
+	cph.innerPacketLock.Lock()
+	defer cph.innerPacketLock.Unlock()
+
+	blockStateDetails, ok := cph.blockStateDetailsMap[packet.ParentHash]
+	if ok == false {
+		return
+	}
+	if _, ok = blockStateDetails.filteredValidatorsDepositMap[validator]; ok == false {
+		return
+	}
+
+	_, round, err := decodePacketRound(packet.ConsensusData)
+	if err != nil || round < 1 || round > MAX_ROUND {
+		return
+	}
+
+	key := voteKey{validator: validator, round: round, packetType: packetType}
+	seen, ok := blockStateDetails.votes[key]
+	if ok == false {
+		pkt := eth.NewConsensusPacket(packet)
+		blockStateDetails.votes[key] = &pkt
+		return
+	}
+	if bytes.Equal(seen.ConsensusData, packet.ConsensusData) {
+		return
+	}
+	if _, ok = blockStateDetails.equivocations[validator]; ok {
+		return
+	}
+
+	log.Warn("Validator equivocation detected", "validator", validator, "parentHash", packet.ParentHash, "round", round, "packetType", packetType)
+	blockStateDetails.equivocations[validator] = NewEquivocationEvidence(seen, packet)
+}
+
+// getEquivocationEvidence returns the evidence retained for the parent hashes of the
+// window that was not slashed yet, for the validators of the block ordered by validator.
+// A validator that equivocated for several parent hashes is slashed for the oldest one
+// first, since it leaves the window first.
+func (cph *ConsensusHandler) getEquivocationEvidence(blockStateDetails *BlockStateDetails, window *EquivocationWindow) []EquivocationEvidence {
+	selected := make(map[common.Address]*EquivocationEvidence)
+	selectedBlockNumber := make(map[common.Address]uint64)
+	for parentHash, details := range cph.blockStateDetailsMap {
+		if window.contains(parentHash) == false {
+			continue
+		}
+		for validator, evidence := range details.equivocations {
+			if _, ok := blockStateDetails.filteredValidatorsDepositMap[validator]; ok == false {
+				continue
+			}
+			if window.isSlashed(parentHash, validator) {
+				continue
+			}
+			if blockNumber, ok := selectedBlockNumber[validator]; ok && blockNumber <= details.blockNumber {
+				continue
+			}
+			selected[validator] = evidence
+			selectedBlockNumber[validator] = details.blockNumber
+		}
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+
+	validators := make([]common.Address, 0, len(selected))
+	for validator := range selected {
+		validators = append(validators, validator)
+	}
+	sort.Slice(validators, func(i, j int) bool {
+		return bytes.Compare(validators[i].Bytes(), validators[j].Bytes()) < 0
+	})
+
+	evidence := make([]EquivocationEvidence, len(validators))
+	for i, validator := range validators {
+		evidence[i] = *selected[validator]
+	}
+	return evidence
+}
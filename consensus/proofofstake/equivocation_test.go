@@ -0,0 +1,273 @@
+package proofofstake
+
+import (
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/consensus"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/eth/protocols/eth"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/rlp"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func testOkAckData(t *testing.T, round byte, proposalHash common.Hash) []byte {
+	data, err := rlp.EncodeToBytes(&ProposalAckDetails{ProposalAckVoteType: VOTE_TYPE_OK, Round: round, ProposalHash: proposalHash})
+	if err != nil {
+		t.Fatalf("failed to encode ack: %v", err)
+	}
+	return append([]byte{byte(CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL)}, data...)
+}
+
+func testSignPacket(t *testing.T, handler *ConsensusHandler, parentHash common.Hash, data []byte) *eth.ConsensusPacket {
+	packet, err := handler.createConsensusPacket(parentHash, data, false)
+	if err != nil {
+		t.Fatalf("createConsensusPacket failed: %v", err)
+	}
+	return packet
+}
+
+func TestEquivocationEvidence_Verify(t *testing.T) {
+	handler, other := testJournalHandlers()
+	parentHash := common.BytesToHash([]byte{1})
+
+	nilAck := testSignPacket(t, handler, parentHash, testNilAckData(t, parentHash, 1))
+	okAck := testSignPacket(t, handler, parentHash, testOkAckData(t, 1, common.BytesToHash([]byte{2})))
+
+	evidence := NewEquivocationEvidence(nilAck, okAck)
+	validator, err := evidence.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if validator != handler.account.Address {
+		t.Fatalf("expected validator %v, got %v", handler.account.Address, validator)
+	}
+	if !reflect.DeepEqual(evidence, NewEquivocationEvidence(okAck, nilAck)) {
+		t.Fatalf("expected the evidence not to depend on the packet order")
+	}
+
+	enc, err := rlp.EncodeToBytes(evidence)
+	if err != nil {
+		t.Fatalf("failed to encode evidence: %v", err)
+	}
+	decoded := &EquivocationEvidence{}
+	if err = rlp.DecodeBytes(enc, decoded); err != nil {
+		t.Fatalf("failed to decode evidence: %v", err)
+	}
+	if _, err = decoded.Verify(); err != nil {
+		t.Fatalf("Verify of decoded evidence failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		first *eth.ConsensusPacket
+		other *eth.ConsensusPacket
+	}{
+		{"same packet", nilAck, nilAck},
+		{"different rounds", nilAck, testSignPacket(t, handler, parentHash, testOkAckData(t, 2, common.BytesToHash([]byte{2})))},
+		{"different signers", nilAck, testSignPacket(t, other, parentHash, testOkAckData(t, 1, common.BytesToHash([]byte{2})))},
+	}
+	for _, test := range tests {
+		if _, err := NewEquivocationEvidence(test.first, test.other).Verify(); err == nil {
+			t.Fatalf("%s: expected Verify to fail", test.name)
+		}
+	}
+
+	tampered := NewEquivocationEvidence(nilAck, okAck)
+	tampered.SecondSignature = common.CopyBytes(tampered.SecondSignature)
+	tampered.SecondSignature[len(tampered.SecondSignature)-1] ^= 0xff
+	if _, err := tampered.Verify(); err == nil {
+		t.Fatalf("expected Verify of a tampered signature to fail")
+	}
+}
+
+func TestDetectEquivocation(t *testing.T) {
+	handler, other := testJournalHandlers()
+	parentHash := common.BytesToHash([]byte{1})
+	if err := handler.initializeBlockStateIfRequired(parentHash, TEST_CONSENSUS_BLOCK_NUMBER); err != nil {
+		t.Fatalf("initializeBlockStateIfRequired failed: %v", err)
+	}
+	blockStateDetails := handler.blockStateDetailsMap[parentHash]
+	window := NewEquivocationWindow(parentHash)
+
+	nilAck := testSignPacket(t, other, parentHash, testNilAckData(t, parentHash, 1))
+	handler.detectEquivocation(other.account.Address, CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL, nilAck)
+	handler.detectEquivocation(other.account.Address, CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL, nilAck)
+	if evidence := handler.getEquivocationEvidence(blockStateDetails, window); len(evidence) != 0 {
+		t.Fatalf("expected no evidence for a repeated packet, got %d", len(evidence))
+	}
+
+	okAck := testSignPacket(t, other, parentHash, testOkAckData(t, 1, common.BytesToHash([]byte{2})))
+	handler.detectEquivocation(other.account.Address, CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL, okAck)
+	evidence := handler.getEquivocationEvidence(blockStateDetails, window)
+	if len(evidence) != 1 {
+		t.Fatalf("expected one evidence, got %d", len(evidence))
+	}
+
+	schedule := *params.DefaultProofOfStakeForkSchedule
+	schedule.EquivocationSlashStartBlock = 0
+	err := ValidateEquivocationEvidence(window, evidence, blockStateDetails.filteredValidatorsDepositMap, TEST_CONSENSUS_BLOCK_NUMBER, &schedule)
+	if err != nil {
+		t.Fatalf("ValidateEquivocationEvidence failed: %v", err)
+	}
+	err = ValidateEquivocationEvidence(window, evidence, blockStateDetails.filteredValidatorsDepositMap, TEST_CONSENSUS_BLOCK_NUMBER, params.DefaultProofOfStakeForkSchedule)
+	if err == nil {
+		t.Fatalf("expected evidence to be rejected before the fork")
+	}
+	err = ValidateEquivocationEvidence(NewEquivocationWindow(common.BytesToHash([]byte{3})), evidence, blockStateDetails.filteredValidatorsDepositMap, TEST_CONSENSUS_BLOCK_NUMBER, &schedule)
+	if err == nil {
+		t.Fatalf("expected evidence of a parent hash outside the window to be rejected")
+	}
+	err = ValidateEquivocationEvidence(window, append(evidence, evidence[0]), blockStateDetails.filteredValidatorsDepositMap, TEST_CONSENSUS_BLOCK_NUMBER, &schedule)
+	if err == nil {
+		t.Fatalf("expected duplicate evidence to be rejected")
+	}
+
+	// The evidence of the previous parent hash is still included in the next block
+	nextParentHash := common.BytesToHash([]byte{4})
+	if err := handler.initializeBlockStateIfRequired(nextParentHash, TEST_CONSENSUS_BLOCK_NUMBER+1); err != nil {
+		t.Fatalf("initializeBlockStateIfRequired failed: %v", err)
+	}
+	nextWindow := NewEquivocationWindow(nextParentHash)
+	if evidence := handler.getEquivocationEvidence(handler.blockStateDetailsMap[nextParentHash], nextWindow); len(evidence) != 0 {
+		t.Fatalf("expected no evidence outside the window, got %d", len(evidence))
+	}
+	nextWindow.parents[parentHash] = true
+	if next := handler.getEquivocationEvidence(handler.blockStateDetailsMap[nextParentHash], nextWindow); !reflect.DeepEqual(next, evidence) {
+		t.Fatalf("expected the evidence of the previous parent hash, got %d", len(next))
+	}
+	nextWindow.slashed[parentHash] = map[common.Address]bool{other.account.Address: true}
+	if evidence := handler.getEquivocationEvidence(handler.blockStateDetailsMap[nextParentHash], nextWindow); len(evidence) != 0 {
+		t.Fatalf("expected no evidence once slashed, got %d", len(evidence))
+	}
+}
+
+// testHeaderChain is a chain of headers that equivocation windows are read from.
+type testHeaderChain struct {
+	headers []*types.Header
+}
+
+func (c *testHeaderChain) Config() *params.ChainConfig { return nil }
+
+func (c *testHeaderChain) CurrentHeader() *types.Header { return c.headers[len(c.headers)-1] }
+
+func (c *testHeaderChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header := c.GetHeaderByNumber(number)
+	if header == nil || header.Hash() != hash {
+		return nil
+	}
+	return header
+}
+
+func (c *testHeaderChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(c.headers)) {
+		return nil
+	}
+	return c.headers[number]
+}
+
+func (c *testHeaderChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, header := range c.headers {
+		if header.Hash() == hash {
+			return header
+		}
+	}
+	return nil
+}
+
+func TestEquivocationWindow(t *testing.T) {
+	handler, other := testJournalHandlers()
+	schedule := *params.DefaultProofOfStakeForkSchedule
+	schedule.EquivocationSlashStartBlock = 1
+	validators := map[common.Address]*big.Int{handler.account.Address: big.NewInt(1), other.account.Address: big.NewInt(1)}
+
+	evidenceFor := func(signer *ConsensusHandler, parentHash common.Hash) EquivocationEvidence {
+		return *NewEquivocationEvidence(testSignPacket(t, signer, parentHash, testNilAckData(t, parentHash, 1)),
+			testSignPacket(t, signer, parentHash, testOkAckData(t, 1, common.BytesToHash([]byte{2}))))
+	}
+
+	// Block 18 slashes the other validator for the parent hash of block 17
+	chain := &testHeaderChain{}
+	for number := int64(0); number <= 20; number++ {
+		data := &BlockConsensusData{VoteType: VOTE_TYPE_NIL, Round: 1}
+		if number == 18 {
+			data.Evidence = []EquivocationEvidence{evidenceFor(other, chain.headers[16].Hash())}
+		}
+		header := testPerformanceHeader(t, number, data, nil)
+		if number > 0 {
+			header.ParentHash = chain.headers[number-1].Hash()
+		}
+		chain.headers = append(chain.headers, header)
+	}
+	parentHash := chain.headers[20].Hash()
+	oldest := chain.headers[21-EQUIVOCATION_EVIDENCE_WINDOW].Hash()
+
+	window, err := getEquivocationWindow(chain, parentHash, 21, &schedule)
+	if err != nil {
+		t.Fatalf("getEquivocationWindow failed: %v", err)
+	}
+	tests := []struct {
+		name     string
+		evidence []EquivocationEvidence
+		valid    bool
+	}{
+		{"parent", []EquivocationEvidence{evidenceFor(other, parentHash)}, true},
+		{"oldest parent", []EquivocationEvidence{evidenceFor(other, oldest)}, true},
+		{"parent before the window", []EquivocationEvidence{evidenceFor(other, chain.headers[20-EQUIVOCATION_EVIDENCE_WINDOW].Hash())}, false},
+		{"already slashed", []EquivocationEvidence{evidenceFor(other, chain.headers[16].Hash())}, false},
+		{"already slashed validator of another parent", []EquivocationEvidence{evidenceFor(other, chain.headers[15].Hash())}, true},
+		{"other validator of a slashed parent", []EquivocationEvidence{evidenceFor(handler, chain.headers[16].Hash())}, true},
+		{"two parents of a validator", []EquivocationEvidence{evidenceFor(other, parentHash), evidenceFor(other, oldest)}, false},
+	}
+	for _, test := range tests {
+		err := ValidateEquivocationEvidence(window, test.evidence, validators, 21, &schedule)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: err %v, valid %v", test.name, err, test.valid)
+		}
+	}
+
+	// Before the fork the ancestors are not read
+	schedule.EquivocationSlashStartBlock = 22
+	if window, err = getEquivocationWindow(chain, parentHash, 21, &schedule); err != nil || len(window.parents) != 1 || window.contains(parentHash) == false {
+		t.Fatalf("unexpected window before the fork: %v %v", window, err)
+	}
+
+	schedule.EquivocationSlashStartBlock = 1
+	chain.headers = chain.headers[15:]
+	if _, err = getEquivocationWindow(chain, parentHash, 21, &schedule); err != consensus.ErrUnknownAncestor {
+		t.Fatalf("expected ErrUnknownAncestor, got %v", err)
+	}
+}
+
+func TestBlockConsensusData_EvidenceEncoding(t *testing.T) {
+	data := &BlockConsensusData{
+		VoteType:              VOTE_TYPE_NIL,
+		SlashedBlockProposers: make([]common.Address, 0),
+		Round:                 1,
+		SelectedTransactions:  make([]common.Hash, 0),
+	}
+	enc, err := rlp.EncodeToBytes(data)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+
+	// Blocks without evidence keep the encoding from before the evidence field was added
+	type legacyBlockConsensusData struct {
+		BlockProposer         common.Address
+		VoteType              VoteType
+		ProposalHash          common.Hash
+		PrecommitHash         common.Hash
+		SlashedBlockProposers []common.Address
+		Round                 byte
+		SelectedTransactions  []common.Hash
+		BlockTime             uint64
+	}
+	legacy, err := rlp.EncodeToBytes(&legacyBlockConsensusData{VoteType: VOTE_TYPE_NIL, Round: 1})
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	if !reflect.DeepEqual(enc, legacy) {
+		t.Fatalf("encoding mismatch:\nhave %x\nwant %x", enc, legacy)
+	}
+}
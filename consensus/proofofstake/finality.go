@@ -0,0 +1,49 @@
+package proofofstake
+
+import (
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/consensus/proofofstake/finality"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/eth/protocols/eth"
+	"github.com/DogeProtocol/dp/rlp"
+	"math/big"
+)
+
+// NewFinalityCertificate creates the finality certificate of a committed block from its
+// header and the validators and deposits at its parent. The certificate is verified with
+// the finality package.
+func NewFinalityCertificate(header *types.Header, validatorDepositMap map[common.Address]*big.Int, consensusContext common.Hash) (*finality.Certificate, error) {
+	if header.Number == nil || header.Number.Sign() <= 0 || len(header.ConsensusData) == 0 || len(header.UnhashedConsensusData) == 0 {
+		return nil, finality.InvalidCertificateErr
+	}
+
+	blockConsensusData := &BlockConsensusData{}
+	err := rlp.DecodeBytes(header.ConsensusData, blockConsensusData)
+	if err != nil {
+		return nil, err
+	}
+	blockAdditionalConsensusData := &BlockAdditionalConsensusData{}
+	err = rlp.DecodeBytes(header.UnhashedConsensusData, blockAdditionalConsensusData)
+	if err != nil {
+		return nil, err
+	}
+
+	commitPackets := make([]finality.Packet, 0)
+	for i := range blockAdditionalConsensusData.ConsensusPackets {
+		packet := &blockAdditionalConsensusData.ConsensusPackets[i]
+		packetType, round, err := decodePacketRound(packet.ConsensusData)
+		if err != nil {
+			return nil, err
+		}
+		if packetType == CONSENSUS_PACKET_TYPE_COMMIT_BLOCK && round == blockConsensusData.Round {
+			copied := eth.NewConsensusPacket(packet)
+			commitPackets = append(commitPackets, finality.Packet{
+				ParentHash:    copied.ParentHash,
+				Signature:     copied.Signature,
+				ConsensusData: copied.ConsensusData,
+			})
+		}
+	}
+
+	return finality.NewCertificate(header, commitPackets, validatorDepositMap, consensusContext), nil
+}
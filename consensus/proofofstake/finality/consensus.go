@@ -0,0 +1,245 @@
+package finality
+
+import (
+	"bytes"
+	"errors"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/rlp"
+	"math/big"
+	"sort"
+)
+
+// The consensus primitives below are shared with the proofofstake engine, so that a
+// certificate is verified exactly as the engine validates the block.
+
+const (
+	// CommitPacketType is the type of the commit packets of a round.
+	CommitPacketType = byte(3)
+	// MinPacketVersion is the first protocol version that prefixes the packet type of
+	// the consensus data with the version.
+	MinPacketVersion = byte(5)
+
+	VoteTypeOk  = byte(1)
+	VoteTypeNil = byte(2)
+)
+
+// The consensus parameters of the network. They are defined here only, the proofofstake
+// engine is configured with them too.
+const (
+	MinValidators    = 3
+	MaxValidators    = 128
+	MaxRound         = byte(2)
+	CommitPercentage = 70 // Percentage of the deposit of the validators required for consensus
+)
+
+var (
+	MinValidatorDeposit = params.EtherToWei(big.NewInt(5000000))
+	MinBlockDeposit     = params.EtherToWei(big.NewInt(500000000000))
+)
+
+var zeroHash = common.BytesToHash([]byte{0})
+
+// Params are the consensus parameters that the validators of a block are selected,
+// and its commits are counted, with.
+type Params struct {
+	MinValidators       int
+	MaxValidators       int
+	MaxRound            byte
+	MinValidatorDeposit *big.Int
+	MinBlockDeposit     *big.Int
+	CommitPercentage    *big.Int // Percentage of the deposit of the validators required for consensus
+}
+
+// DefaultParams are the consensus parameters of the network.
+var DefaultParams = Params{
+	MinValidators:       MinValidators,
+	MaxValidators:       MaxValidators,
+	MaxRound:            MaxRound,
+	MinValidatorDeposit: MinValidatorDeposit,
+	MinBlockDeposit:     MinBlockDeposit,
+	CommitPercentage:    big.NewInt(CommitPercentage),
+}
+
+// BlockConsensusData is the consensus data in the header of a block. Only the
+// fields up to the block time are read, the evidence is kept undecoded.
+type BlockConsensusData struct {
+	BlockProposer         common.Address
+	VoteType              byte
+	ProposalHash          common.Hash
+	PrecommitHash         common.Hash
+	SlashedBlockProposers []common.Address
+	Round                 byte
+	SelectedTransactions  []common.Hash
+	BlockTime             uint64
+
+	Evidence []rlp.RawValue `rlp:"optional"`
+}
+
+type commitDetails struct {
+	CommitHash common.Hash
+	Round      byte
+}
+
+// CombinedTxnHash is the proposal hash of the transactions proposed for the block
+// with parentHash in round.
+func CombinedTxnHash(parentHash common.Hash, round byte, txns []common.Hash) common.Hash {
+	var txnList []common.Hash
+	txnList = make([]common.Hash, len(txns))
+	for i := 0; i < len(txns); i++ {
+		txnList[i].CopyFrom(txns[i])
+	}
+
+	sort.Slice(txnList, func(i, j int) bool {
+		return bytes.Compare(txnList[i].Bytes(), txnList[j].Bytes()) == -1
+	})
+
+	var data []byte
+	data = make([]byte, 0)
+	for _, txn := range txnList {
+		data = append(data, txn.Bytes()...)
+	}
+
+	return crypto.Keccak256Hash(data, parentHash.Bytes(), []byte{round})
+}
+
+// CommitHash is the hash that the commit packets of precommitHash are signed for.
+func CommitHash(precommitHash common.Hash) common.Hash {
+	return crypto.Keccak256Hash(precommitHash.Bytes())
+}
+
+// OkVotePreCommitHash is the precommit hash of a proposal that was voted for.
+func OkVotePreCommitHash(parentHash common.Hash, proposalHash common.Hash, round byte) common.Hash {
+	return crypto.Keccak256Hash(parentHash.Bytes(), proposalHash.Bytes(), []byte{round}, []byte{VoteTypeOk})
+}
+
+// NilVotePreCommitHash is the precommit hash of a round that was voted nil.
+func NilVotePreCommitHash(parentHash common.Hash, round byte) common.Hash {
+	return crypto.Keccak256Hash(parentHash.Bytes(), []byte("precommit"), zeroHash.Bytes(), []byte{round}, []byte{VoteTypeNil})
+}
+
+// RecoverPacketSigner verifies the signature of a packet that is signed without a
+// signing context, and returns the validator that signed it.
+func RecoverPacketSigner(packet *Packet) (common.Address, error) {
+	sigAlg, err := cryptobase.SigAlgFromCombinedSignature(packet.Signature)
+	if err != nil {
+		return common.Address{}, InvalidPacketErr
+	}
+	dataToVerify := append(packet.ParentHash.Bytes(), packet.ConsensusData...)
+	digestHash := crypto.Keccak256(dataToVerify)
+	pubKey, err := sigAlg.PublicKeyFromSignature(digestHash, packet.Signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if sigAlg.Verify(pubKey.PubData, digestHash, packet.Signature) == false {
+		return common.Address{}, InvalidPacketErr
+	}
+
+	return sigAlg.PublicKeyToAddress(pubKey)
+}
+
+// FilterValidators selects the validators of the block with parentHash from the
+// validators and their deposits, and returns them along with their deposit and the
+// deposit required for consensus. Validators below the minimum deposit are removed
+// from valDepMap.
+func FilterValidators(parentHash common.Hash, valDepMap *map[common.Address]*big.Int, p *Params) (filteredValidators map[common.Address]bool, filteredDepositValue *big.Int, blockMinWeightedProposalsRequired *big.Int, err error) {
+	validatorsDepositMap := *valDepMap
+
+	totalDepositValue := big.NewInt(0)
+	valCount := 0
+	for val, depositValue := range validatorsDepositMap { //todo: this should be based on netBalance
+		if depositValue.Cmp(p.MinValidatorDeposit) == -1 {
+			log.Trace("Skipping validator with low balance", "val", val, "depositValue", depositValue)
+			delete(validatorsDepositMap, val)
+			continue
+		}
+		totalDepositValue = common.SafeAddBigInt(totalDepositValue, depositValue)
+		valCount = valCount + 1
+	}
+
+	if valCount < p.MinValidators {
+		return nil, nil, nil, errors.New("number of validators less than minimum")
+	}
+
+	if totalDepositValue.Cmp(p.MinBlockDeposit) == -1 {
+		return nil, nil, nil, errors.New("min block deposit not met")
+	}
+
+	filteredValidators = make(map[common.Address]bool)
+
+	if len(validatorsDepositMap) <= p.MaxValidators {
+		for validator := range validatorsDepositMap {
+			filteredValidators[validator] = true
+		}
+	} else {
+		rng, err := cryptobase.DRNG.InitializeWithSeed(parentHash)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		zero := big.NewInt(0)
+		byteMax := big.NewInt(255)
+		depositValueSoFar := big.NewInt(0)
+
+		validatorList := make([]common.Address, len(validatorsDepositMap))
+		ctr := 0
+		for validator, _ := range validatorsDepositMap {
+			validatorList[ctr] = validator
+			ctr = ctr + 1
+		}
+
+		sort.Slice(validatorList, func(i, j int) bool {
+			vi := crypto.Keccak256Hash(parentHash.Bytes(), validatorList[i].Bytes()).Bytes()
+			vj := crypto.Keccak256Hash(parentHash.Bytes(), validatorList[j].Bytes()).Bytes()
+			return bytes.Compare(vi, vj) == -1
+		})
+
+		for _, validator := range validatorList {
+			depositValue := validatorsDepositMap[validator]
+			randByte := big.NewInt(int64(rng.NextByte()))
+
+			//normalize depositValue to byte-max value since random generator only returns bytes
+			normalizedDepositValue := common.SafeDivBigInt(common.SafeMulBigInt(byteMax, depositValue), totalDepositValue)
+			if normalizedDepositValue.Cmp(zero) < 0 || normalizedDepositValue.Cmp(byteMax) > 0 {
+				return nil, nil, nil, errors.New("invalid normalizedDepositValue")
+			}
+
+			if normalizedDepositValue.Cmp(randByte) >= 0 {
+				filteredValidators[validator] = true
+				depositValueSoFar = common.SafeAddBigInt(depositValueSoFar, depositValue)
+			}
+		}
+
+		if len(filteredValidators) < p.MaxValidators || p.MinBlockDeposit.Cmp(depositValueSoFar) > 0 {
+			for _, validator := range validatorList {
+				_, ok := filteredValidators[validator]
+				if ok == false {
+					//this needs optimization, since validators first in the list get the benefit
+					filteredValidators[validator] = true
+					depositValue := validatorsDepositMap[validator]
+					depositValueSoFar = common.SafeAddBigInt(depositValueSoFar, depositValue)
+					if len(filteredValidators) == p.MaxValidators && p.MinBlockDeposit.Cmp(depositValueSoFar) <= 0 {
+						break
+					}
+				}
+			}
+		}
+	}
+
+	filteredDepositValue = big.NewInt(0)
+	for val, _ := range filteredValidators {
+		depositValue := validatorsDepositMap[val]
+		filteredDepositValue = common.SafeAddBigInt(filteredDepositValue, depositValue)
+	}
+
+	if filteredDepositValue.Cmp(p.MinBlockDeposit) == -1 {
+		return nil, nil, nil, errors.New("min block deposit not met for filteredDepositValue")
+	}
+
+	blockMinWeightedProposalsRequired = common.SafeRelativePercentageBigInt(filteredDepositValue, p.CommitPercentage)
+
+	return filteredValidators, filteredDepositValue, blockMinWeightedProposalsRequired, nil
+}
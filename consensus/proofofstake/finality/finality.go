@@ -0,0 +1,243 @@
+// Package finality verifies the finality certificates returned by
+// proofofstake_getFinalityCertificate, without access to a node or a database.
+//
+// A certificate proves that the validators holding the deposit required for consensus
+// signed the commit of a block. It is only as trustworthy as the validator set it is
+// verified against, so the validator set must come from a trusted source, for example a
+// previously verified block in the same validator epoch. The signature schemes that the
+// commits may be signed with are those of the fork schedule of the chain.
+package finality
+
+import (
+	"bytes"
+	"errors"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/rlp"
+	"math/big"
+	"sort"
+)
+
+var InvalidCertificateErr = errors.New("invalid finality certificate")
+var InsufficientCommitDepositErr = errors.New("finality certificate commits below the required deposit")
+var ValidatorSetMismatchErr = errors.New("certificate validator set does not match the trusted validator set")
+var InvalidPacketErr = errors.New("invalid packet")
+var InvalidSignatureSchemeErr = errors.New("packet signature scheme not valid at the block")
+
+// Packet is a signed consensus packet, encoded as the consensus packets of the eth protocol.
+type Packet struct {
+	ParentHash    common.Hash `json:"parentHash"    gencodec:"required"`
+	Signature     []byte      `json:"signature"     gencodec:"required"`
+	ConsensusData []byte      `json:"consensusData" gencodec:"required"`
+}
+
+// Validator is a validator and its deposit at the parent of the certified block.
+type Validator struct {
+	Address common.Address `json:"address" gencodec:"required"`
+	Deposit *big.Int       `json:"deposit" gencodec:"required"`
+}
+
+// Certificate is a self-contained proof that a block was committed by the validators.
+// The header binds the block hash to the precommit hash that the commit packets of the
+// final round are signed for. The unhashed consensus data of the header is left out,
+// since only the commit packets are needed.
+type Certificate struct {
+	Header           *types.Header `json:"header" gencodec:"required"`
+	CommitPackets    []Packet      `json:"commitPackets" gencodec:"required"`
+	Validators       []Validator   `json:"validators" gencodec:"required"`
+	ConsensusContext common.Hash   `json:"consensusContext" gencodec:"required"`
+}
+
+// NewCertificate returns the certificate of header, with its commit packets and the
+// validators and deposits at its parent sorted by address.
+func NewCertificate(header *types.Header, commitPackets []Packet, validatorDepositMap map[common.Address]*big.Int, consensusContext common.Hash) *Certificate {
+	certificateHeader := types.CopyHeader(header)
+	certificateHeader.UnhashedConsensusData = nil
+
+	certificate := &Certificate{
+		Header:           certificateHeader,
+		CommitPackets:    commitPackets,
+		Validators:       make([]Validator, 0, len(validatorDepositMap)),
+		ConsensusContext: consensusContext,
+	}
+	for validator, deposit := range validatorDepositMap {
+		certificate.Validators = append(certificate.Validators, Validator{Address: validator, Deposit: new(big.Int).Set(deposit)})
+	}
+	sort.Slice(certificate.Validators, func(i, j int) bool {
+		return bytes.Compare(certificate.Validators[i].Address.Bytes(), certificate.Validators[j].Address.Bytes()) < 0
+	})
+	return certificate
+}
+
+// ValidatorDepositMap returns the validators of the certificate and their deposits.
+func (c *Certificate) ValidatorDepositMap() map[common.Address]*big.Int {
+	validatorDepositMap := make(map[common.Address]*big.Int)
+	for _, validator := range c.Validators {
+		validatorDepositMap[validator.Address] = new(big.Int).Set(validator.Deposit)
+	}
+	return validatorDepositMap
+}
+
+// Decode decodes an RLP encoded finality certificate.
+func Decode(data []byte) (*Certificate, error) {
+	certificate := &Certificate{}
+	if err := rlp.DecodeBytes(data, certificate); err != nil {
+		return nil, err
+	}
+	return certificate, nil
+}
+
+// Verify checks that the certificate was issued for the trusted validator set and that the
+// block was committed by it, with the signature schemes of schedule, and returns the hash
+// and number of the final block.
+func Verify(certificate *Certificate, trustedValidators map[common.Address]*big.Int, schedule *params.ProofOfStakeForkSchedule) (common.Hash, uint64, error) {
+	if certificate.Header == nil || certificate.Header.Number == nil {
+		return common.Hash{}, 0, InvalidCertificateErr
+	}
+	if len(certificate.Validators) != len(trustedValidators) {
+		return common.Hash{}, 0, ValidatorSetMismatchErr
+	}
+	seen := make(map[common.Address]bool)
+	for _, validator := range certificate.Validators {
+		deposit, ok := trustedValidators[validator.Address]
+		if ok == false || seen[validator.Address] || validator.Deposit == nil || deposit.Cmp(validator.Deposit) != 0 {
+			return common.Hash{}, 0, ValidatorSetMismatchErr
+		}
+		seen[validator.Address] = true
+	}
+
+	if err := VerifyCommits(certificate, trustedValidators, schedule, &DefaultParams); err != nil {
+		return common.Hash{}, 0, err
+	}
+
+	return certificate.Header.Hash(), certificate.Header.Number.Uint64(), nil
+}
+
+// VerifyEncoded decodes and verifies an RLP encoded finality certificate.
+func VerifyEncoded(data []byte, trustedValidators map[common.Address]*big.Int, schedule *params.ProofOfStakeForkSchedule) (common.Hash, uint64, error) {
+	certificate, err := Decode(data)
+	if err != nil {
+		return common.Hash{}, 0, err
+	}
+	return Verify(certificate, trustedValidators, schedule)
+}
+
+// VerifyCommits checks that the block of the certificate was committed by the given
+// validators, by verifying the signatures of the commit packets and that the validators
+// that committed to the precommit hash of the block hold the deposit required for
+// consensus. The validators of the certificate itself are not trusted.
+func VerifyCommits(certificate *Certificate, validatorDepositMap map[common.Address]*big.Int, schedule *params.ProofOfStakeForkSchedule, p *Params) error {
+	header := certificate.Header
+	if header == nil || header.Number == nil || header.Number.Sign() <= 0 || len(certificate.CommitPackets) == 0 {
+		return InvalidCertificateErr
+	}
+	parentHash := header.ParentHash
+
+	blockConsensusData := &BlockConsensusData{}
+	err := rlp.DecodeBytes(header.ConsensusData, blockConsensusData)
+	if err != nil {
+		return err
+	}
+	round := blockConsensusData.Round
+	if round < 1 || round > p.MaxRound {
+		return InvalidCertificateErr
+	}
+
+	var precommitHash common.Hash
+	if blockConsensusData.VoteType == VoteTypeOk {
+		proposalHash := CombinedTxnHash(parentHash, round, blockConsensusData.SelectedTransactions)
+		if blockConsensusData.ProposalHash.IsEqualTo(proposalHash) == false {
+			return InvalidCertificateErr
+		}
+		precommitHash = OkVotePreCommitHash(parentHash, proposalHash, round)
+	} else if blockConsensusData.VoteType == VoteTypeNil {
+		precommitHash = NilVotePreCommitHash(parentHash, round)
+	} else {
+		return InvalidCertificateErr
+	}
+	if blockConsensusData.PrecommitHash.IsEqualTo(precommitHash) == false {
+		return InvalidCertificateErr
+	}
+
+	valMap := make(map[common.Address]*big.Int)
+	for validator, deposit := range validatorDepositMap {
+		valMap[validator] = deposit
+	}
+	filteredValidators, _, minDepositRequired, err := FilterValidators(parentHash, &valMap, p)
+	if err != nil {
+		return err
+	}
+
+	commitHash := CommitHash(precommitHash)
+	committed := make(map[common.Address]bool)
+	commitDepositValue := big.NewInt(0)
+	for i := range certificate.CommitPackets {
+		packet := &certificate.CommitPackets[i]
+		if packet.ParentHash.IsEqualTo(parentHash) == false {
+			return InvalidCertificateErr
+		}
+		details, err := decodeCommit(packet.ConsensusData)
+		if err != nil {
+			return err
+		}
+		if details.Round != round {
+			return InvalidCertificateErr
+		}
+		if err := CheckSignatureScheme(packet.Signature, header.Number.Uint64(), schedule); err != nil {
+			return err
+		}
+		validator, err := RecoverPacketSigner(packet)
+		if err != nil {
+			return err
+		}
+		if filteredValidators[validator] == false {
+			return errors.New("validator not part of block")
+		}
+		if committed[validator] {
+			return errors.New("duplicate commit packet")
+		}
+		committed[validator] = true
+		if details.CommitHash.IsEqualTo(commitHash) {
+			commitDepositValue = common.SafeAddBigInt(commitDepositValue, valMap[validator])
+		}
+	}
+	if commitDepositValue.Cmp(minDepositRequired) < 0 {
+		return InsufficientCommitDepositErr
+	}
+
+	return nil
+}
+
+// CheckSignatureScheme checks that a packet signature is signed with a signature
+// scheme that is valid at blockNumber.
+func CheckSignatureScheme(signature []byte, blockNumber uint64, schedule *params.ProofOfStakeForkSchedule) error {
+	sigAlg, err := cryptobase.SigAlgFromCombinedSignature(signature)
+	if err != nil {
+		return InvalidPacketErr
+	}
+	if schedule.SignatureSchemes.IsValid(sigAlg.SignatureName(), new(big.Int).SetUint64(blockNumber)) == false {
+		return InvalidSignatureSchemeErr
+	}
+	return nil
+}
+
+// decodeCommit decodes the consensus data of a commit packet.
+func decodeCommit(consensusData []byte) (*commitDetails, error) {
+	if len(consensusData) == 0 {
+		return nil, InvalidPacketErr
+	}
+	startIndex := 1
+	if consensusData[0] >= MinPacketVersion {
+		startIndex = 2
+	}
+	if len(consensusData) <= startIndex || consensusData[startIndex-1] != CommitPacketType {
+		return nil, InvalidCertificateErr
+	}
+	details := &commitDetails{}
+	if err := rlp.DecodeBytes(consensusData[startIndex:], details); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
@@ -0,0 +1,77 @@
+package finality
+
+import (
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/rlp"
+	"math/big"
+	"testing"
+)
+
+func TestVerify_ValidatorSetMismatch(t *testing.T) {
+	a, b := common.BytesToAddress([]byte{1}), common.BytesToAddress([]byte{2})
+	certificate := &Certificate{
+		Header:        &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(1)},
+		CommitPackets: make([]Packet, 0),
+		Validators: []Validator{
+			{Address: a, Deposit: big.NewInt(10)},
+			{Address: b, Deposit: big.NewInt(10)},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		trusted map[common.Address]*big.Int
+	}{
+		{"missing validator", map[common.Address]*big.Int{a: big.NewInt(10)}},
+		{"different deposit", map[common.Address]*big.Int{a: big.NewInt(10), b: big.NewInt(11)}},
+		{"different validator", map[common.Address]*big.Int{a: big.NewInt(10), common.BytesToAddress([]byte{3}): big.NewInt(10)}},
+	}
+	for _, test := range tests {
+		if _, _, err := Verify(certificate, test.trusted, params.DefaultProofOfStakeForkSchedule); err != ValidatorSetMismatchErr {
+			t.Fatalf("%s: expected %v, got %v", test.name, ValidatorSetMismatchErr, err)
+		}
+	}
+
+	// A matching validator set still requires the commits
+	trusted := map[common.Address]*big.Int{a: big.NewInt(10), b: big.NewInt(10)}
+	if _, _, err := Verify(certificate, trusted, params.DefaultProofOfStakeForkSchedule); err == nil {
+		t.Fatalf("expected a certificate without commits to fail")
+	}
+
+	enc, err := rlp.EncodeToBytes(certificate)
+	if err != nil {
+		t.Fatalf("failed to encode certificate: %v", err)
+	}
+	if _, _, err = VerifyEncoded(enc, map[common.Address]*big.Int{a: big.NewInt(10)}, params.DefaultProofOfStakeForkSchedule); err != ValidatorSetMismatchErr {
+		t.Fatalf("expected %v, got %v", ValidatorSetMismatchErr, err)
+	}
+	if _, err = Decode(enc[:len(enc)-1]); err == nil {
+		t.Fatalf("expected decoding a truncated certificate to fail")
+	}
+}
+
+func TestDecodeCommit(t *testing.T) {
+	commit, err := rlp.EncodeToBytes(&commitDetails{CommitHash: common.BytesToHash([]byte{1}), Round: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range [][]byte{
+		append([]byte{CommitPacketType}, commit...),
+		append([]byte{MinPacketVersion, CommitPacketType}, commit...),
+	} {
+		details, err := decodeCommit(data)
+		if err != nil {
+			t.Fatalf("failed to decode commit: %v", err)
+		}
+		if details.Round != 2 || details.CommitHash != common.BytesToHash([]byte{1}) {
+			t.Fatalf("commit mismatch: %+v", details)
+		}
+	}
+	for _, data := range [][]byte{nil, {MinPacketVersion}, append([]byte{MinPacketVersion, CommitPacketType - 1}, commit...)} {
+		if _, err := decodeCommit(data); err == nil {
+			t.Fatalf("decoded an invalid commit %x", data)
+		}
+	}
+}
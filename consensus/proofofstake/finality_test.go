@@ -0,0 +1,192 @@
+package proofofstake
+
+import (
+	"errors"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/consensus/proofofstake/finality"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/rlp"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testFinalityHeader returns the header of the first block committed by a simulation,
+// along with the validators that committed it.
+func testFinalityHeader(t *testing.T) (*types.Header, map[common.Address]*big.Int, *SimulationBlock) {
+	sim, err := NewSimulation(SimulationConfig{Validators: 4, Seed: 6, Blocks: 1, Latency: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewSimulation failed: %v", err)
+	}
+	result := sim.Run()
+	if len(result.Blocks) == 0 {
+		t.Fatalf("no block committed")
+	}
+	block := result.Blocks[0]
+
+	consensusData, err := rlp.EncodeToBytes(block.consensusData)
+	if err != nil {
+		t.Fatalf("failed to encode consensus data: %v", err)
+	}
+	unhashedConsensusData, err := rlp.EncodeToBytes(block.additionalConsensusData)
+	if err != nil {
+		t.Fatalf("failed to encode additional consensus data: %v", err)
+	}
+	header := &types.Header{
+		ParentHash:            block.ParentHash,
+		Number:                new(big.Int).SetUint64(block.Number),
+		Difficulty:            big.NewInt(1),
+		ConsensusData:         consensusData,
+		UnhashedConsensusData: unhashedConsensusData,
+	}
+	validators, _ := sim.getValidators(block.ParentHash)
+	return header, validators, block
+}
+
+func TestFinalityCertificate(t *testing.T) {
+	header, validators, block := testFinalityHeader(t)
+
+	certificate, err := NewFinalityCertificate(header, validators, common.Hash{})
+	if err != nil {
+		t.Fatalf("NewFinalityCertificate failed: %v", err)
+	}
+	if len(certificate.CommitPackets) == 0 || len(certificate.CommitPackets) >= len(block.additionalConsensusData.ConsensusPackets) {
+		t.Fatalf("expected only the commit packets, got %d of %d packets", len(certificate.CommitPackets), len(block.additionalConsensusData.ConsensusPackets))
+	}
+	if certificate.Header.Hash() != header.Hash() || certificate.Header.UnhashedConsensusData != nil {
+		t.Fatalf("expected the certificate header to keep the block hash without the unhashed consensus data")
+	}
+	if err = finality.VerifyCommits(certificate, validators, params.DefaultProofOfStakeForkSchedule, consensusParams()); err != nil {
+		t.Fatalf("VerifyCommits failed: %v", err)
+	}
+
+	enc, err := rlp.EncodeToBytes(certificate)
+	if err != nil {
+		t.Fatalf("failed to encode certificate: %v", err)
+	}
+	hash, number, err := finality.VerifyEncoded(enc, validators, params.DefaultProofOfStakeForkSchedule)
+	if err != nil {
+		t.Fatalf("VerifyEncoded failed: %v", err)
+	}
+	if hash != header.Hash() || number != header.Number.Uint64() {
+		t.Fatalf("block mismatch after decoding: have %x %d, want %x %d", hash, number, header.Hash(), header.Number)
+	}
+
+	// The commits are only valid with the signature schemes of the chain
+	schedule := *params.DefaultProofOfStakeForkSchedule
+	schedule.SignatureSchemes = params.SignatureSchemes{
+		{Name: params.DefaultSignatureScheme, Block: big.NewInt(0), RetireBlock: new(big.Int).Set(header.Number)},
+	}
+	if _, _, err = finality.Verify(certificate, validators, &schedule); err != finality.InvalidSignatureSchemeErr {
+		t.Fatalf("expected %v, got %v", finality.InvalidSignatureSchemeErr, err)
+	}
+}
+
+// Tests that the finality package selects validators and counts commits with the
+// consensus parameters and packet encoding of the engine.
+func TestFinalityConsensusParams(t *testing.T) {
+	if reflect.DeepEqual(consensusParams(), &finality.DefaultParams) == false {
+		t.Fatalf("consensus parameters mismatch: have %+v, want %+v", consensusParams(), finality.DefaultParams)
+	}
+	if byte(CONSENSUS_PACKET_TYPE_COMMIT_BLOCK) != finality.CommitPacketType || MinConsensusNetworkProtocolVersion != finality.MinPacketVersion ||
+		byte(VOTE_TYPE_OK) != finality.VoteTypeOk || byte(VOTE_TYPE_NIL) != finality.VoteTypeNil {
+		t.Fatalf("packet constants mismatch")
+	}
+
+	// The consensus data of the engine decodes as the consensus data of a certificate
+	data := &BlockConsensusData{
+		BlockProposer:        common.BytesToAddress([]byte{1}),
+		VoteType:             VOTE_TYPE_OK,
+		ProposalHash:         common.BytesToHash([]byte{2}),
+		PrecommitHash:        common.BytesToHash([]byte{3}),
+		Round:                2,
+		SelectedTransactions: []common.Hash{common.BytesToHash([]byte{4})},
+		BlockTime:            5,
+		Evidence:             []EquivocationEvidence{{}},
+	}
+	enc, err := rlp.EncodeToBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &finality.BlockConsensusData{}
+	if err := rlp.DecodeBytes(enc, decoded); err != nil {
+		t.Fatalf("failed to decode consensus data: %v", err)
+	}
+	if decoded.BlockProposer != data.BlockProposer || decoded.VoteType != byte(data.VoteType) || decoded.ProposalHash != data.ProposalHash ||
+		decoded.PrecommitHash != data.PrecommitHash || decoded.Round != data.Round || reflect.DeepEqual(decoded.SelectedTransactions, data.SelectedTransactions) == false ||
+		decoded.BlockTime != data.BlockTime || len(decoded.Evidence) != 1 {
+		t.Fatalf("consensus data mismatch: have %+v, want %+v", decoded, data)
+	}
+}
+
+func TestFinalityCertificate_Invalid(t *testing.T) {
+	header, validators, block := testFinalityHeader(t)
+
+	tests := []struct {
+		name   string
+		modify func(certificate *finality.Certificate) map[common.Address]*big.Int
+		err    error
+	}{
+		{
+			name: "commits below the required deposit",
+			modify: func(certificate *finality.Certificate) map[common.Address]*big.Int {
+				certificate.CommitPackets = certificate.CommitPackets[:1]
+				return validators
+			},
+			err: finality.InsufficientCommitDepositErr,
+		},
+		{
+			name: "other validator set",
+			modify: func(certificate *finality.Certificate) map[common.Address]*big.Int {
+				other := make(map[common.Address]*big.Int)
+				for i := 0; i < len(validators); i++ {
+					other[common.BytesToAddress([]byte{byte(i + 1)})] = validators[block.consensusData.BlockProposer]
+				}
+				return other
+			},
+		},
+		{
+			name: "tampered consensus data",
+			modify: func(certificate *finality.Certificate) map[common.Address]*big.Int {
+				data := *block.consensusData
+				data.SelectedTransactions = append([]common.Hash{common.BytesToHash([]byte{1})}, data.SelectedTransactions...)
+				certificate.Header.ConsensusData, _ = rlp.EncodeToBytes(&data)
+				return validators
+			},
+			err: finality.InvalidCertificateErr,
+		},
+		{
+			name: "packet other than a commit",
+			modify: func(certificate *finality.Certificate) map[common.Address]*big.Int {
+				for _, packet := range block.additionalConsensusData.ConsensusPackets {
+					if getPacketType(&packet) != CONSENSUS_PACKET_TYPE_COMMIT_BLOCK {
+						certificate.CommitPackets = append(certificate.CommitPackets, finality.Packet{
+							ParentHash:    packet.ParentHash,
+							Signature:     packet.Signature,
+							ConsensusData: packet.ConsensusData,
+						})
+						break
+					}
+				}
+				return validators
+			},
+			err: finality.InvalidCertificateErr,
+		},
+	}
+	for _, test := range tests {
+		certificate, err := NewFinalityCertificate(header, validators, common.Hash{})
+		if err != nil {
+			t.Fatalf("NewFinalityCertificate failed: %v", err)
+		}
+		trusted := test.modify(certificate)
+		err = finality.VerifyCommits(certificate, trusted, params.DefaultProofOfStakeForkSchedule, consensusParams())
+		if err == nil {
+			t.Fatalf("%s: expected VerifyCommits to fail", test.name)
+		}
+		if test.err != nil && errors.Is(err, test.err) == false {
+			t.Fatalf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package proofofstake
+
+import (
+	"bytes"
+	"errors"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/rawdb"
+	"github.com/DogeProtocol/dp/eth/protocols/eth"
+	"github.com/DogeProtocol/dp/ethdb"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/rlp"
+)
+
+var ConflictingVoteErr = errors.New("conflicting vote for the same parent hash and round")
+
+// VOTE_JOURNAL_KEEP_BLOCKS is how many blocks below the head block the journaled packets
+// are kept for when the journal is pruned at startup.
+var VOTE_JOURNAL_KEEP_BLOCKS = uint64(128)
+
+// voteJournal is a write-ahead journal of the consensus packets signed by the local
+// validator. Packets are journaled before they are handled or broadcast, so that after
+// a restart the handler resumes the round it was in and never signs two different
+// packets of the same type for the same parent hash and round.
+type voteJournal struct {
+	db ethdb.KeyValueStore
+}
+
+type journaledVote struct {
+	round      byte
+	packetType ConsensusPacketType
+	packet     *eth.ConsensusPacket
+}
+
+func newVoteJournal(db ethdb.KeyValueStore) *voteJournal {
+	if db == nil {
+		return nil
+	}
+	return &voteJournal{db: db}
+}
+
+func (j *voteJournal) get(parentHash common.Hash, round byte, packetType ConsensusPacketType) (*eth.ConsensusPacket, error) {
+	if j == nil {
+		return nil, nil
+	}
+	data := rawdb.ReadConsensusVote(j.db, parentHash, round, byte(packetType))
+	if len(data) == 0 {
+		return nil, nil
+	}
+	packet := &eth.ConsensusPacket{}
+	if err := rlp.DecodeBytes(data, packet); err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
+
+func (j *voteJournal) put(round byte, packetType ConsensusPacketType, packet *eth.ConsensusPacket) error {
+	if j == nil {
+		return nil
+	}
+	data, err := rlp.EncodeToBytes(packet)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteConsensusVote(j.db, packet.ParentHash, round, byte(packetType), data)
+	return nil
+}
+
+// votes returns the journaled packets of the parent hash, ordered by round and packet type.
+func (j *voteJournal) votes(parentHash common.Hash) ([]*journaledVote, error) {
+	if j == nil {
+		return nil, nil
+	}
+	entries := rawdb.ReadConsensusVotes(j.db, parentHash)
+	votes := make([]*journaledVote, 0, len(entries))
+	for _, entry := range entries {
+		packet := &eth.ConsensusPacket{}
+		if err := rlp.DecodeBytes(entry.Data, packet); err != nil {
+			return nil, err
+		}
+		votes = append(votes, &journaledVote{
+			round:      entry.Round,
+			packetType: ConsensusPacketType(entry.PacketType),
+			packet:     packet,
+		})
+	}
+	return votes, nil
+}
+
+func (j *voteJournal) delete(parentHash common.Hash) {
+	if j == nil {
+		return
+	}
+	rawdb.DeleteConsensusVotes(j.db, parentHash)
+}
+
+// prune deletes the journaled packets of parent hashes that are more than
+// VOTE_JOURNAL_KEEP_BLOCKS blocks below the head block, or that are not in the database.
+// Packets are otherwise only deleted when the handler cleans up the block state, so the
+// packets of the blocks that were pending when the node stopped would stay forever.
+func (j *voteJournal) prune() {
+	if j == nil {
+		return
+	}
+	headHash := rawdb.ReadHeadHeaderHash(j.db)
+	head := rawdb.ReadHeaderNumber(j.db, headHash)
+	if head == nil {
+		return
+	}
+
+	pruned := 0
+	for _, parentHash := range rawdb.ReadConsensusVoteParents(j.db) {
+		number := rawdb.ReadHeaderNumber(j.db, parentHash)
+		if number != nil && *number+VOTE_JOURNAL_KEEP_BLOCKS >= *head {
+			continue
+		}
+		rawdb.DeleteConsensusVotes(j.db, parentHash)
+		pruned++
+	}
+	if pruned > 0 {
+		log.Info("Pruned the vote journal", "parentHashes", pruned, "head", *head)
+	}
+}
+
+// createVotePacket signs a packet of the local validator and journals it. If a packet of
+// the same type was already signed for the parent hash and round, the journaled packet is
+// returned instead when the data matches, and ConflictingVoteErr when it does not.
+func (cph *ConsensusHandler) createVotePacket(parentHash common.Hash, round byte, packetType ConsensusPacketType, data []byte, fullSign bool) (*eth.ConsensusPacket, error) {
+	journaled, err := cph.journal.get(parentHash, round, packetType)
+	if err != nil {
+		return nil, err
+	}
+	if journaled != nil {
+		if bytes.Equal(journaled.ConsensusData, data) {
+			return journaled, nil
+		}
+		log.Warn("Refusing to sign a conflicting consensus packet", "parentHash", parentHash, "round", round, "packetType", packetType)
+		return nil, ConflictingVoteErr
+	}
+
+	packet, err := cph.createConsensusPacket(parentHash, data, fullSign)
+	if err != nil {
+		return nil, err
+	}
+	if err = cph.journal.put(round, packetType, packet); err != nil {
+		return nil, err
+	}
+
+	return packet, nil
+}
+
+// replayVoteJournal restores the rounds and the own proposal and proposal acks of the parent
+// hash from the journal after a restart. Precommits and commits depend on the votes of the
+// other validators, so they are not restored; once the round gets there again createVotePacket
+// hands out the journaled packets.
+func (cph *ConsensusHandler) replayVoteJournal(parentHash common.Hash) error {
+	votes, err := cph.journal.votes(parentHash)
+	if err != nil {
+		return err
+	}
+	if len(votes) == 0 {
+		return nil
+	}
+	log.Info("Resuming consensus from the vote journal", "parentHash", parentHash, "packets", len(votes))
+
+	blockStateDetails := cph.blockStateDetailsMap[parentHash]
+	for _, vote := range votes {
+		for blockStateDetails.currentRound < vote.round {
+			err = cph.initializeNewBlockRound(NEW_ROUND_REASON_RESUMED)
+			if err != nil {
+				return err
+			}
+		}
+		if vote.round != blockStateDetails.currentRound {
+			continue
+		}
+
+		switch vote.packetType {
+		case CONSENSUS_PACKET_TYPE_PROPOSE_BLOCK:
+			err = cph.handleProposeBlockPacket(cph.account.Address, vote.packet, true)
+			if err != nil {
+				log.Warn("replayVoteJournal proposal", "parentHash", parentHash, "round", vote.round, "err", err)
+			}
+		case CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL:
+			var startIndex int
+			if vote.packet.ConsensusData[0] >= MinConsensusNetworkProtocolVersion {
+				startIndex = 2
+			} else {
+				startIndex = 1
+			}
+			proposalAckDetails := &ProposalAckDetails{}
+			err = rlp.DecodeBytes(vote.packet.ConsensusData[startIndex:], proposalAckDetails)
+			if err != nil {
+				return err
+			}
+
+			blockRoundDetails := blockStateDetails.blockRoundMap[vote.round]
+			pkt := eth.NewConsensusPacket(vote.packet)
+			blockRoundDetails.proposalAckPackets[cph.account.Address] = &pkt
+			blockRoundDetails.validatorProposalAcks[cph.account.Address] = proposalAckDetails
+			blockRoundDetails.selfAckd = true
+			blockRoundDetails.selfAckPacket = vote.packet
+			blockRoundDetails.selfAckProposalVoteType = proposalAckDetails.ProposalAckVoteType
+			if proposalAckDetails.ProposalAckVoteType == VOTE_TYPE_NIL {
+				blockRoundDetails.blockVoteType = VOTE_TYPE_NIL
+			}
+		}
+	}
+
+	return nil
+}
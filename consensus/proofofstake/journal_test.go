@@ -0,0 +1,154 @@
+package proofofstake
+
+import (
+	"bytes"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/rawdb"
+	"github.com/DogeProtocol/dp/rlp"
+	"testing"
+)
+
+func testNilAckData(t *testing.T, parentHash common.Hash, round byte) []byte {
+	proposalAckDetails := &ProposalAckDetails{
+		ProposalAckVoteType: VOTE_TYPE_NIL,
+		Round:               round,
+	}
+	proposalAckDetails.ProposalHash.CopyFrom(getNilVoteProposalHash(parentHash, round))
+	data, err := rlp.EncodeToBytes(proposalAckDetails)
+	if err != nil {
+		t.Fatalf("failed to encode ack: %v", err)
+	}
+	return append([]byte{byte(CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL)}, data...)
+}
+
+func testJournalHandlers() (*ConsensusHandler, *ConsensusHandler) {
+	_, p2p, _ := Initialize(4)
+	var handlers []*ConsensusHandler
+	for _, h := range p2p.mockP2pHandlers {
+		handlers = append(handlers, h.consensusHandler)
+	}
+	return handlers[0], handlers[1]
+}
+
+func TestVoteJournal_ConflictingVote(t *testing.T) {
+	handler, _ := testJournalHandlers()
+	handler.SetVoteJournal(rawdb.NewMemoryDatabase())
+
+	parentHash := common.BytesToHash([]byte{1})
+	packet, err := handler.createVotePacket(parentHash, 1, CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL, testNilAckData(t, parentHash, 1), false)
+	if err != nil {
+		t.Fatalf("createVotePacket failed: %v", err)
+	}
+
+	again, err := handler.createVotePacket(parentHash, 1, CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL, testNilAckData(t, parentHash, 1), false)
+	if err != nil {
+		t.Fatalf("createVotePacket of the same data failed: %v", err)
+	}
+	if !bytes.Equal(again.Signature, packet.Signature) {
+		t.Fatalf("expected the journaled packet to be returned")
+	}
+
+	okAck := &ProposalAckDetails{ProposalAckVoteType: VOTE_TYPE_OK, Round: 1, ProposalHash: common.BytesToHash([]byte{2})}
+	data, _ := rlp.EncodeToBytes(okAck)
+	_, err = handler.createVotePacket(parentHash, 1, CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL, append([]byte{byte(CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL)}, data...), false)
+	if err != ConflictingVoteErr {
+		t.Fatalf("expected ConflictingVoteErr, got %v", err)
+	}
+
+	if _, err = handler.createVotePacket(parentHash, 2, CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL, testNilAckData(t, parentHash, 2), false); err != nil {
+		t.Fatalf("createVotePacket for the next round failed: %v", err)
+	}
+	if _, err = handler.createVotePacket(common.BytesToHash([]byte{3}), 1, CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL, testNilAckData(t, common.BytesToHash([]byte{3}), 1), false); err != nil {
+		t.Fatalf("createVotePacket for another parent hash failed: %v", err)
+	}
+}
+
+func TestVoteJournal_Replay(t *testing.T) {
+	handler, restarted := testJournalHandlers()
+	db := rawdb.NewMemoryDatabase()
+	handler.SetVoteJournal(db)
+
+	parentHash := common.BytesToHash([]byte{1})
+	var signatures [3][]byte
+	for round := byte(1); round <= 2; round++ {
+		packet, err := handler.createVotePacket(parentHash, round, CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL, testNilAckData(t, parentHash, round), false)
+		if err != nil {
+			t.Fatalf("createVotePacket failed: %v", err)
+		}
+		signatures[round] = packet.Signature
+	}
+
+	// Simulate a restart of the same validator with the same database
+	restarted.account = handler.account
+	restarted.SetVoteJournal(db)
+	if err := restarted.initializeBlockStateIfRequired(parentHash, TEST_CONSENSUS_BLOCK_NUMBER); err != nil {
+		t.Fatalf("initializeBlockStateIfRequired failed: %v", err)
+	}
+
+	blockStateDetails := restarted.blockStateDetailsMap[parentHash]
+	if blockStateDetails.currentRound != 2 {
+		t.Fatalf("expected to resume in round 2, got %d", blockStateDetails.currentRound)
+	}
+	for round := byte(1); round <= 2; round++ {
+		blockRoundDetails := blockStateDetails.blockRoundMap[round]
+		if !blockRoundDetails.selfAckd || blockRoundDetails.selfAckProposalVoteType != VOTE_TYPE_NIL {
+			t.Fatalf("round %d: expected the journaled ack to be restored", round)
+		}
+		if !bytes.Equal(blockRoundDetails.selfAckPacket.Signature, signatures[round]) {
+			t.Fatalf("round %d: restored ack does not match the journaled ack", round)
+		}
+	}
+
+	restarted.journal.delete(parentHash)
+	votes, _ := restarted.journal.votes(parentHash)
+	if len(votes) != 0 {
+		t.Fatalf("expected the journal to be empty, got %d packets", len(votes))
+	}
+}
+
+func TestVoteJournal_Prune(t *testing.T) {
+	handler, _ := testJournalHandlers()
+	db := rawdb.NewMemoryDatabase()
+	handler.SetVoteJournal(db)
+
+	head := uint64(1000)
+	parents := map[common.Hash]bool{} // parent hash -> kept
+	for _, number := range []uint64{0, head - VOTE_JOURNAL_KEEP_BLOCKS - 1, head - VOTE_JOURNAL_KEEP_BLOCKS, head} {
+		parentHash := common.BytesToHash([]byte{1, byte(number >> 8), byte(number)})
+		rawdb.WriteHeaderNumber(db, parentHash, number)
+		parents[parentHash] = number+VOTE_JOURNAL_KEEP_BLOCKS >= head
+	}
+	parents[common.BytesToHash([]byte{2})] = false // not in the database
+	for parentHash := range parents {
+		for round := byte(1); round <= 2; round++ {
+			if _, err := handler.createVotePacket(parentHash, round, CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL, testNilAckData(t, parentHash, round), false); err != nil {
+				t.Fatalf("createVotePacket failed: %v", err)
+			}
+		}
+	}
+	if journaled := rawdb.ReadConsensusVoteParents(db); len(journaled) != len(parents) {
+		t.Fatalf("expected %d journaled parent hashes, got %d", len(parents), len(journaled))
+	}
+
+	// Without a head block nothing is pruned
+	handler.journal.prune()
+	if journaled := rawdb.ReadConsensusVoteParents(db); len(journaled) != len(parents) {
+		t.Fatalf("expected %d journaled parent hashes without a head, got %d", len(parents), len(journaled))
+	}
+
+	headHash := common.BytesToHash([]byte{1, byte(head >> 8), byte(head)})
+	rawdb.WriteHeadHeaderHash(db, headHash)
+	handler.journal.prune()
+	for parentHash, kept := range parents {
+		votes, err := handler.journal.votes(parentHash)
+		if err != nil {
+			t.Fatalf("votes failed: %v", err)
+		}
+		if kept && len(votes) != 2 {
+			t.Fatalf("expected the packets of %v to be kept, got %d", parentHash, len(votes))
+		}
+		if kept == false && len(votes) != 0 {
+			t.Fatalf("expected the packets of %v to be pruned, got %d", parentHash, len(votes))
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package proofofstake
+
+import (
+	"errors"
+	"fmt"
+	"github.com/DogeProtocol/dp/eth/protocols/eth"
+	"github.com/DogeProtocol/dp/metrics"
+)
+
+var (
+	roundProposalHistogram    = metrics.NewRegisteredHistogram("consensus/round/proposal", nil, metrics.NewExpDecaySample(1028, 0.015))
+	roundAckProposalHistogram = metrics.NewRegisteredHistogram("consensus/round/ackproposal", nil, metrics.NewExpDecaySample(1028, 0.015))
+	roundPrecommitHistogram   = metrics.NewRegisteredHistogram("consensus/round/precommit", nil, metrics.NewExpDecaySample(1028, 0.015))
+	roundCommitHistogram      = metrics.NewRegisteredHistogram("consensus/round/commit", nil, metrics.NewExpDecaySample(1028, 0.015))
+	roundTotalHistogram       = metrics.NewRegisteredHistogram("consensus/round/total", nil, metrics.NewExpDecaySample(1028, 0.015))
+
+	okBlockCounter  = metrics.NewRegisteredCounter("consensus/blocks/ok", nil)
+	nilBlockCounter = metrics.NewRegisteredCounter("consensus/blocks/nil", nil)
+
+	packetInMeter       = metrics.NewRegisteredMeter("consensus/packets/in", nil)
+	packetRejectMeter   = metrics.NewRegisteredMeter("consensus/packets/rejected", nil)
+	outOfOrderGauge     = metrics.NewRegisteredGauge("consensus/packets/outoforder", nil)
+	packetResendCounter = metrics.NewRegisteredCounter("consensus/packets/resend", nil)
+
+	relayRebroadcastCounter = metrics.NewRegisteredCounter("consensus/peer/rebroadcast/relays", nil)
+	syncRebroadcastCounter  = metrics.NewRegisteredCounter("consensus/peer/rebroadcast/syncpeers", nil)
+
+	packetSignatureCacheHitMeter  = metrics.NewRegisteredMeter("consensus/sigcache/hit", nil)
+	packetSignatureCacheMissMeter = metrics.NewRegisteredMeter("consensus/sigcache/miss", nil)
+)
+
+func (t ConsensusPacketType) String() string {
+	switch t {
+	case CONSENSUS_PACKET_TYPE_PROPOSE_BLOCK:
+		return "proposal"
+	case CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL:
+		return "ackproposal"
+	case CONSENSUS_PACKET_TYPE_PRECOMMIT_BLOCK:
+		return "precommit"
+	case CONSENSUS_PACKET_TYPE_COMMIT_BLOCK:
+		return "commit"
+	case CONSENSUS_PACKET_TYPE_CAPABILITY:
+		return "capability"
+	case CONSENSUS_PACKET_TYPE_SYNC:
+		return "sync"
+	default:
+		return "unknown"
+	}
+}
+
+func (r NewRoundReason) String() string {
+	switch r {
+	case NEW_ROUND_REASON_START:
+		return "start"
+	case NEW_ROUND_REASON_WAIT_ACK_BLOCK_PROPOSAL_TIMEOUT:
+		return "ackproposaltimeout"
+	case NEW_ROUND_REASON_WAIT_ACK_BLOCK_PROPOSAL_HIGHER_ROUND:
+		return "higherround"
+	case NEW_ROUND_REASON_WAIT_PRECOMMIT_TIMEOUT:
+		return "precommittimeout"
+	case NEW_ROUND_REASON_RESUMED:
+		return "resumed"
+	default:
+		return "unknown"
+	}
+}
+
+// packetRejectReason returns the metric name of the error a packet was rejected with.
+func packetRejectReason(err error) string {
+	switch {
+	case errors.Is(err, InvalidPacketErr):
+		return "invalid"
+	case errors.Is(err, OutOfOrderPackerErr):
+		return "outoforder"
+	case errors.Is(err, UnknownParentHashErr):
+		return "unknownparent"
+	default:
+		return "other"
+	}
+}
+
+// markPacketProcessed counts a received packet by its type, and the rejected ones by
+// type and error.
+func markPacketProcessed(packet *eth.ConsensusPacket, err error) {
+	if metrics.Enabled == false {
+		return
+	}
+	packetType := ConsensusPacketType(255)
+	if len(packet.ConsensusData) > 1 || (len(packet.ConsensusData) == 1 && packet.ConsensusData[0] < MinConsensusNetworkProtocolVersion) {
+		packetType = getPacketType(packet)
+	}
+	packetInMeter.Mark(1)
+	metrics.GetOrRegisterMeter(fmt.Sprintf("consensus/packets/in/%s", packetType), nil).Mark(1)
+	if err != nil {
+		packetRejectMeter.Mark(1)
+		metrics.GetOrRegisterMeter(fmt.Sprintf("consensus/packets/rejected/%s/%s", packetType, packetRejectReason(err)), nil).Mark(1)
+	}
+}
+
+func markNewRound(newRoundReason NewRoundReason) {
+	if metrics.Enabled == false {
+		return
+	}
+	metrics.GetOrRegisterCounter(fmt.Sprintf("consensus/round/new/%s", newRoundReason), nil).Inc(1)
+}
+
+// markBlockCommitted records the time spent in each state of a committed block, both as
+// histograms and as the time buckets of GetTimeStatBucket.
+func markBlockCommitted(blockStateDetails *BlockStateDetails, voteType VoteType) {
+	if metrics.Enabled == false {
+		return
+	}
+	if voteType == VOTE_TYPE_OK {
+		okBlockCounter.Inc(1)
+	} else {
+		nilBlockCounter.Inc(1)
+	}
+
+	durations := []struct {
+		state     string
+		ms        int64
+		histogram metrics.Histogram
+	}{
+		{PROPOSAL_KEY_PREFIX, blockStateDetails.proposalTime, roundProposalHistogram},
+		{ACK_PROPOSAL_KEY_PREFIX, blockStateDetails.ackProposalTime - blockStateDetails.proposalTime, roundAckProposalHistogram},
+		{PRECOMMIT_KEY_PREFIX, blockStateDetails.precommitTime - blockStateDetails.ackProposalTime, roundPrecommitHistogram},
+		{COMMIT_KEY_PREFIX, blockStateDetails.commitTime - blockStateDetails.precommitTime, roundCommitHistogram},
+		{TOTAL_KEY_PREFIX, blockStateDetails.commitTime, roundTotalHistogram},
+	}
+	for _, duration := range durations {
+		duration.histogram.Update(duration.ms)
+		metrics.GetOrRegisterCounter("consensus/timestats/"+GetTimeStatBucket(duration.state, duration.ms), nil).Inc(1)
+	}
+}
+
+// updateOutOfOrderGauge sets the gauge to the number of queued out of order packets.
+func (cph *ConsensusHandler) updateOutOfOrderGauge() {
+	if metrics.Enabled == false {
+		return
+	}
+	count := 0
+	for _, packetMap := range cph.outOfOrderPacketsMap {
+		count = count + len(packetMap)
+	}
+	outOfOrderGauge.Update(int64(count))
+}
@@ -0,0 +1,62 @@
+package proofofstake
+
+import (
+	"fmt"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/eth/protocols/eth"
+	"github.com/DogeProtocol/dp/metrics"
+	"testing"
+)
+
+func TestPacketRejectReason(t *testing.T) {
+	tests := []struct {
+		err    error
+		reason string
+	}{
+		{InvalidPacketErr, "invalid"},
+		{fmt.Errorf("wrapped: %w", OutOfOrderPackerErr), "outoforder"},
+		{UnknownParentHashErr, "unknownparent"},
+		{InvalidEvidenceErr, "other"},
+	}
+	for _, test := range tests {
+		if reason := packetRejectReason(test.err); reason != test.reason {
+			t.Fatalf("%v: expected reason %s, got %s", test.err, test.reason, reason)
+		}
+	}
+}
+
+func TestPacketMetrics(t *testing.T) {
+	enabled := metrics.Enabled
+	metrics.Enabled = true
+	defer func() {
+		metrics.Enabled = enabled
+	}()
+
+	packet := &eth.ConsensusPacket{
+		ParentHash:    common.BytesToHash([]byte{1}),
+		ConsensusData: []byte{byte(CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL), 0xc0},
+	}
+	markPacketProcessed(packet, nil)
+	markPacketProcessed(packet, OutOfOrderPackerErr)
+	markPacketProcessed(&eth.ConsensusPacket{ConsensusData: []byte{MinConsensusNetworkProtocolVersion}}, InvalidPacketErr)
+	markNewRound(NEW_ROUND_REASON_WAIT_PRECOMMIT_TIMEOUT)
+
+	meters := map[string]int64{
+		"consensus/packets/in/ackproposal":                  2,
+		"consensus/packets/rejected/ackproposal/outoforder": 1,
+		"consensus/packets/rejected/unknown/invalid":        1,
+	}
+	for name, count := range meters {
+		meter, ok := metrics.DefaultRegistry.Get(name).(metrics.Meter)
+		if ok == false {
+			t.Fatalf("meter %s not registered", name)
+		}
+		if meter.Count() != count {
+			t.Fatalf("meter %s: expected %d, got %d", name, count, meter.Count())
+		}
+	}
+	counter, ok := metrics.DefaultRegistry.Get("consensus/round/new/precommittimeout").(metrics.Counter)
+	if ok == false || counter.Count() != 1 {
+		t.Fatalf("expected one new round after a precommit timeout")
+	}
+}
@@ -163,7 +163,7 @@ func getSigner(packet *eth.ConsensusPacket) (common.Address, error) {
 	}
 
 	packetType := ConsensusPacketType(packet.ConsensusData[startIndex])
-	if shouldSignFull(TEST_CONSENSUS_BLOCK_NUMBER) && packetType == CONSENSUS_PACKET_TYPE_PROPOSE_BLOCK && packet.ParentHash.IsEqualTo(getTestParentHash(TEST_CONSENSUS_BLOCK_NUMBER)) {
+	if shouldSignFull(TEST_CONSENSUS_BLOCK_NUMBER, params.DefaultProofOfStakeForkSchedule) && packetType == CONSENSUS_PACKET_TYPE_PROPOSE_BLOCK && packet.ParentHash.IsEqualTo(getTestParentHash(TEST_CONSENSUS_BLOCK_NUMBER)) {
 		pubKey, err := cryptobase.SigAlg.PublicKeyFromSignatureWithContext(digestHash, packet.Signature, FULL_SIGN_CONTEXT)
 		if err != nil {
 			return ZERO_ADDRESS, err
@@ -459,7 +459,7 @@ func ValidateBlockConsensusDataTest(parentHash common.Hash, p2p *MockP2PManager,
 			t.Fatalf("failed")
 		}
 
-		blockConsensusData, blockAdditionalConsensusData, err := handler.consensusHandler.getBlockConsensusData(parentHash)
+		blockConsensusData, blockAdditionalConsensusData, err := handler.consensusHandler.getBlockConsensusData(parentHash, NewEquivocationWindow(parentHash))
 		if err != nil {
 			fmt.Println("ValidateBlockConsensusData getBlockConsensusData", "err", err, "val", handler.validator)
 			t.Fatalf("failed")
@@ -504,7 +504,7 @@ func ValidateBlockConsensusDataTest(parentHash common.Hash, p2p *MockP2PManager,
 			t.Fatalf("failed")
 		}
 
-		err = ValidateBlockConsensusDataInner(txns, parentHash, blockConsensusData, blockAdditionalConsensusData, validatorMap, TEST_CONSENSUS_BLOCK_NUMBER, nil, common.ZERO_HASH)
+		err = ValidateBlockConsensusDataInner(txns, parentHash, blockConsensusData, blockAdditionalConsensusData, validatorMap, TEST_CONSENSUS_BLOCK_NUMBER, nil, common.ZERO_HASH, NewEquivocationWindow(parentHash), params.DefaultProofOfStakeForkSchedule)
 		if err != nil {
 			fmt.Println("ValidateBlockConsensusDataInner", err, handler.validator)
 			t.Fatalf("ValidateBlockConsensusDataInner failed")
@@ -607,7 +607,7 @@ func testPacketHandler_min_basic(t *testing.T) {
 	parentHash := common.BytesToHash([]byte{1})
 
 	startTime := time.Now().UnixNano() / int64(time.Millisecond)
-	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH)
+	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH, params.DefaultProofOfStakeForkSchedule)
 
 	skipped := false
 	c := 0
@@ -657,7 +657,7 @@ func testPacketHandler_extended_failure(t *testing.T, numKeys int, minPass int,
 	parentHash := common.BytesToHash([]byte{1})
 
 	startTime := time.Now().UnixNano() / int64(time.Millisecond)
-	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH)
+	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH, params.DefaultProofOfStakeForkSchedule)
 
 	for _, handler := range p2p.mockP2pHandlers {
 		h := handler
@@ -719,7 +719,7 @@ func testPacketHandler_block_proposer_timedout(t *testing.T) {
 	parentHash := common.BytesToHash([]byte{1})
 	c := 1
 	startTime := time.Now().UnixNano() / int64(time.Millisecond)
-	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH)
+	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH, params.DefaultProofOfStakeForkSchedule)
 
 	for _, handler := range p2p.mockP2pHandlers {
 		h := handler
@@ -762,7 +762,7 @@ func testPacketHandler_min_negative(t *testing.T, numKeys int, minPass int, unre
 	parentHash := common.BytesToHash([]byte{1})
 	c := 1
 	startTime := time.Now().UnixNano() / int64(time.Millisecond)
-	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH)
+	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH, params.DefaultProofOfStakeForkSchedule)
 	skipList := make(map[common.Address]bool)
 
 	for _, handler := range p2p.mockP2pHandlers {
@@ -801,7 +801,7 @@ func testPacketHandler_no_round2_then_round2(t *testing.T, numKeys int, minPass
 	parentHash := common.BytesToHash([]byte{1})
 	c := 1
 	startTime := time.Now().UnixNano() / int64(time.Millisecond)
-	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH)
+	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH, params.DefaultProofOfStakeForkSchedule)
 	skipCount := 0
 	unresponsiveValCount := 2
 	var valSkipList []common.Address
@@ -908,7 +908,7 @@ func TestPacketHandler_no_round2_then_round2(t *testing.T) {
 func testPacketHandler_bifurcated(t *testing.T) {
 	_, p2p, valMap := Initialize(4)
 	parentHash := common.BytesToHash([]byte{1})
-	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH)
+	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH, params.DefaultProofOfStakeForkSchedule)
 	c := 0
 	startTime := time.Now().UnixNano() / int64(time.Millisecond)
 
@@ -1315,7 +1315,7 @@ func testPacketHandler_split_increasing_txns_some_unresponsive(t *testing.T, num
 	_, p2p, valMap := Initialize(numKeys)
 
 	parentHash := common.BytesToHash([]byte{1})
-	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH)
+	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH, params.DefaultProofOfStakeForkSchedule)
 
 	j := 0
 	numTxns := 0
@@ -1468,7 +1468,7 @@ func testPacketHandler_packet_loss_txns_some_unresponsive(t *testing.T, numVal i
 	_, p2p, valMap := Initialize(numKeys)
 
 	parentHash := common.BytesToHash([]byte{1})
-	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH)
+	proposer, _ := getBlockProposer(parentHash, valMap, 1, nil, TEST_CONSENSUS_BLOCK_NUMBER, common.ZERO_HASH, params.DefaultProofOfStakeForkSchedule)
 
 	j := 0
 	numTxns := 0
@@ -1894,13 +1894,13 @@ func TestFilterValidators_positive_low_balance_negative(t *testing.T) {
 
 func TestBlockProposalTime(t *testing.T) {
 	for i := uint64(0); i < 1000000000; i += 256 {
-		if GetProposalTime(i) == 0 {
+		if GetProposalTime(i, params.DefaultProofOfStakeForkSchedule) == 0 {
 			fmt.Println(i)
 			t.Fatalf("failed 1")
 		}
 	}
 
-	t1 := GetProposalTime(256)
+	t1 := GetProposalTime(256, params.DefaultProofOfStakeForkSchedule)
 	tm := time.Unix(int64(t1), 0)
 	fmt.Println(tm)
 
@@ -1913,51 +1913,51 @@ func TestBlockProposalTime(t *testing.T) {
 		t.Fatalf("failed 3")
 	}
 
-	if GetProposalTime(1) == 0 {
+	if GetProposalTime(1, params.DefaultProofOfStakeForkSchedule) == 0 {
 		t.Fatalf("failed 4")
 	}
 }
 
 func TestValidateBlockProposalTime(t *testing.T) {
-	if ValidateBlockProposalTime(1, GetProposalTime(1)) == false {
+	if ValidateBlockProposalTime(1, GetProposalTime(1, params.DefaultProofOfStakeForkSchedule), params.DefaultProofOfStakeForkSchedule) == false {
 		t.Fatalf("failed 1")
 	}
 
-	if ValidateBlockProposalTime(256, GetProposalTime(256)) == false {
+	if ValidateBlockProposalTime(256, GetProposalTime(256, params.DefaultProofOfStakeForkSchedule), params.DefaultProofOfStakeForkSchedule) == false {
 		t.Fatalf("failed 2")
 	}
 
-	if ValidateBlockProposalTime(2, GetProposalTime(2)) == false {
+	if ValidateBlockProposalTime(2, GetProposalTime(2, params.DefaultProofOfStakeForkSchedule), params.DefaultProofOfStakeForkSchedule) == false {
 		t.Fatalf("failed 3")
 	}
 
-	if ValidateBlockProposalTime(1, GetProposalTime(1)+1) == true {
+	if ValidateBlockProposalTime(1, GetProposalTime(1, params.DefaultProofOfStakeForkSchedule)+1, params.DefaultProofOfStakeForkSchedule) == true {
 		t.Fatalf("failed 4")
 	}
 
-	if ValidateBlockProposalTime(BLOCK_TIME_ORIG_START_BLOCK, GetProposalTime(BLOCK_TIME_ORIG_START_BLOCK)) == false {
+	if ValidateBlockProposalTime(params.DefaultProofOfStakeForkSchedule.BlockTimeOrigStartBlock, GetProposalTime(params.DefaultProofOfStakeForkSchedule.BlockTimeOrigStartBlock, params.DefaultProofOfStakeForkSchedule), params.DefaultProofOfStakeForkSchedule) == false {
 		t.Fatalf("failed 5")
 	}
 }
 
 func TestValidateBlockProposalTimeConsensus(t *testing.T) {
-	if ValidateBlockProposalTimeConsensus(1, GetProposalTime(1)) == false {
+	if ValidateBlockProposalTimeConsensus(1, GetProposalTime(1, params.DefaultProofOfStakeForkSchedule), params.DefaultProofOfStakeForkSchedule) == false {
 		t.Fatalf("failed 1")
 	}
 
-	if ValidateBlockProposalTimeConsensus(256, GetProposalTime(256)) == false {
+	if ValidateBlockProposalTimeConsensus(256, GetProposalTime(256, params.DefaultProofOfStakeForkSchedule), params.DefaultProofOfStakeForkSchedule) == false {
 		t.Fatalf("failed 2")
 	}
 
-	if ValidateBlockProposalTimeConsensus(2, GetProposalTime(2)) == false {
+	if ValidateBlockProposalTimeConsensus(2, GetProposalTime(2, params.DefaultProofOfStakeForkSchedule), params.DefaultProofOfStakeForkSchedule) == false {
 		t.Fatalf("failed 3")
 	}
 
-	if ValidateBlockProposalTimeConsensus(1, GetProposalTime(2)) == true {
+	if ValidateBlockProposalTimeConsensus(1, GetProposalTime(2, params.DefaultProofOfStakeForkSchedule), params.DefaultProofOfStakeForkSchedule) == true {
 		t.Fatalf("failed 4")
 	}
 
-	if ValidateBlockProposalTimeConsensus(1, GetProposalTime(1)+1) == true {
+	if ValidateBlockProposalTimeConsensus(1, GetProposalTime(1, params.DefaultProofOfStakeForkSchedule)+1, params.DefaultProofOfStakeForkSchedule) == true {
 		t.Fatalf("failed 5")
 	}
 
@@ -1965,7 +1965,7 @@ func TestValidateBlockProposalTimeConsensus(t *testing.T) {
 	if tm%60 != 0 {
 		tm = tm - (tm % 60)
 	}
-	if ValidateBlockProposalTimeConsensus(1, uint64(tm)) == false {
+	if ValidateBlockProposalTimeConsensus(1, uint64(tm), params.DefaultProofOfStakeForkSchedule) == false {
 		t.Fatalf("failed 6")
 	}
 
@@ -1973,7 +1973,7 @@ func TestValidateBlockProposalTimeConsensus(t *testing.T) {
 	if tm%60 != 0 {
 		tm = tm - (tm % 60)
 	}
-	if ValidateBlockProposalTimeConsensus(1, uint64(tm)) == false {
+	if ValidateBlockProposalTimeConsensus(1, uint64(tm), params.DefaultProofOfStakeForkSchedule) == false {
 		t.Fatalf("failed 7")
 	}
 
@@ -1981,7 +1981,7 @@ func TestValidateBlockProposalTimeConsensus(t *testing.T) {
 	if tm%60 != 0 {
 		tm = tm - (tm % 60)
 	}
-	if ValidateBlockProposalTimeConsensus(1, uint64(tm)) == false {
+	if ValidateBlockProposalTimeConsensus(1, uint64(tm), params.DefaultProofOfStakeForkSchedule) == false {
 		t.Fatalf("failed 8")
 	}
 
@@ -1989,7 +1989,7 @@ func TestValidateBlockProposalTimeConsensus(t *testing.T) {
 	if tm%60 != 0 {
 		tm = tm - (tm % 60)
 	}
-	if ValidateBlockProposalTimeConsensus(1, uint64(tm)) == true {
+	if ValidateBlockProposalTimeConsensus(1, uint64(tm), params.DefaultProofOfStakeForkSchedule) == true {
 		t.Fatalf("failed 9")
 	}
 
@@ -1997,7 +1997,7 @@ func TestValidateBlockProposalTimeConsensus(t *testing.T) {
 	if tm%60 != 0 {
 		tm = tm - (tm % 60)
 	}
-	if ValidateBlockProposalTimeConsensus(1, uint64(tm)) == false {
+	if ValidateBlockProposalTimeConsensus(1, uint64(tm), params.DefaultProofOfStakeForkSchedule) == false {
 		t.Fatalf("failed 10")
 	}
 
@@ -2005,7 +2005,7 @@ func TestValidateBlockProposalTimeConsensus(t *testing.T) {
 	if tm%60 != 0 {
 		tm = tm - (tm % 60)
 	}
-	if ValidateBlockProposalTimeConsensus(1, uint64(tm)) == false {
+	if ValidateBlockProposalTimeConsensus(1, uint64(tm), params.DefaultProofOfStakeForkSchedule) == false {
 		t.Fatalf("failed 11")
 	}
 
@@ -2013,7 +2013,7 @@ func TestValidateBlockProposalTimeConsensus(t *testing.T) {
 	if tm%60 != 0 {
 		tm = tm - (tm % 60)
 	}
-	if ValidateBlockProposalTimeConsensus(1, uint64(tm)) == false {
+	if ValidateBlockProposalTimeConsensus(1, uint64(tm), params.DefaultProofOfStakeForkSchedule) == false {
 		t.Fatalf("failed 12")
 	}
 
@@ -2021,7 +2021,7 @@ func TestValidateBlockProposalTimeConsensus(t *testing.T) {
 	if tm%60 != 0 {
 		tm = tm - (tm % 60)
 	}
-	if ValidateBlockProposalTimeConsensus(1, uint64(tm)) == true {
+	if ValidateBlockProposalTimeConsensus(1, uint64(tm), params.DefaultProofOfStakeForkSchedule) == true {
 		t.Fatalf("failed 13")
 	}
 
@@ -2029,7 +2029,7 @@ func TestValidateBlockProposalTimeConsensus(t *testing.T) {
 	if tm%60 != 0 {
 		tm = tm - (tm % 60)
 	}
-	if ValidateBlockProposalTimeConsensus(BLOCK_TIME_ORIG_START_BLOCK, uint64(tm)) == false {
+	if ValidateBlockProposalTimeConsensus(params.DefaultProofOfStakeForkSchedule.BlockTimeOrigStartBlock, uint64(tm), params.DefaultProofOfStakeForkSchedule) == false {
 		t.Fatalf("failed 14")
 	}
 }
@@ -2132,20 +2132,20 @@ func Test_requestconsensuspacket_negative(t *testing.T) {
 }
 
 func Test_shouldSignFull(t *testing.T) {
-	for i := uint64(0); i < FULL_SIGN_PROPOSAL_CUTOFF_BLOCK; i++ {
-		if shouldSignFull(uint64(i)) == true {
+	for i := uint64(0); i < params.DefaultProofOfStakeForkSchedule.FullSignProposalCutoffBlock; i++ {
+		if shouldSignFull(uint64(i), params.DefaultProofOfStakeForkSchedule) == true {
 			t.Fatalf("failed 1")
 		}
 	}
 
-	for i := FULL_SIGN_PROPOSAL_CUTOFF_BLOCK; i < FULL_SIGN_PROPOSAL_CUTOFF_BLOCK*100; i += FULL_SIGN_PROPOSAL_FREQUENCY_BLOCKS {
-		if shouldSignFull(uint64(i)) == false {
+	for i := params.DefaultProofOfStakeForkSchedule.FullSignProposalCutoffBlock; i < params.DefaultProofOfStakeForkSchedule.FullSignProposalCutoffBlock*100; i += FULL_SIGN_PROPOSAL_FREQUENCY_BLOCKS {
+		if shouldSignFull(uint64(i), params.DefaultProofOfStakeForkSchedule) == false {
 			t.Fatalf("failed 2")
 		}
 	}
 
-	for i := FULL_SIGN_PROPOSAL_CUTOFF_BLOCK + 1; i < FULL_SIGN_PROPOSAL_CUTOFF_BLOCK+FULL_SIGN_PROPOSAL_FREQUENCY_BLOCKS-1; i++ {
-		if shouldSignFull(uint64(i)) == true {
+	for i := params.DefaultProofOfStakeForkSchedule.FullSignProposalCutoffBlock + 1; i < params.DefaultProofOfStakeForkSchedule.FullSignProposalCutoffBlock+FULL_SIGN_PROPOSAL_FREQUENCY_BLOCKS-1; i++ {
+		if shouldSignFull(uint64(i), params.DefaultProofOfStakeForkSchedule) == true {
 			t.Fatalf("failed 3")
 		}
 	}
@@ -2153,7 +2153,7 @@ func Test_shouldSignFull(t *testing.T) {
 
 func TestPacketHandler_basic_fullsign(t *testing.T) {
 	fmt.Println("TestPacketHandler_basic_fullsign starting")
-	TEST_CONSENSUS_BLOCK_NUMBER = FULL_SIGN_PROPOSAL_CUTOFF_BLOCK
+	TEST_CONSENSUS_BLOCK_NUMBER = params.DefaultProofOfStakeForkSchedule.FullSignProposalCutoffBlock
 	for i := 1; i <= TEST_ITERATIONS; i++ {
 		fmt.Println("iteration", i)
 		testPacketHandler_basic(4, t)
@@ -0,0 +1,314 @@
+package proofofstake
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core"
+	"github.com/DogeProtocol/dp/core/rawdb"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/ethdb"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/rlp"
+	"math/big"
+	"sort"
+	"time"
+)
+
+const (
+	// performanceSectionSize is the number of blocks of which the validator performance
+	// is aggregated into a single index section.
+	performanceSectionSize = 4096
+
+	// performanceConfirms is the number of confirmations before a section is indexed.
+	performanceConfirms = 16
+
+	// performanceThrottling is the time to wait between processing two consecutive
+	// index sections.
+	performanceThrottling = 100 * time.Millisecond
+
+	// maxPerformanceScanBlocks is the maximum number of blocks outside of the indexed
+	// sections that are decoded to answer a single performance request.
+	maxPerformanceScanBlocks = 2 * performanceSectionSize
+)
+
+var PerformanceRangeNotIndexedErr = errors.New("block range is not indexed yet, request a smaller range")
+
+// validatorPerformance is the consensus track record of a validator over a range of blocks.
+type validatorPerformance struct {
+	Validator          common.Address
+	ProposalsMade      uint64
+	ProposalsMissed    uint64
+	NilBlocksCaused    uint64
+	RoundsParticipated uint64
+	Slashings          uint64
+	SlashedAmount      *big.Int
+	RewardsEarned      *big.Int
+}
+
+func newValidatorPerformance(validator common.Address) *validatorPerformance {
+	return &validatorPerformance{
+		Validator:     validator,
+		SlashedAmount: big.NewInt(0),
+		RewardsEarned: big.NewInt(0),
+	}
+}
+
+func (p *validatorPerformance) add(other *validatorPerformance) {
+	if other == nil {
+		return
+	}
+	p.ProposalsMade = p.ProposalsMade + other.ProposalsMade
+	p.ProposalsMissed = p.ProposalsMissed + other.ProposalsMissed
+	p.NilBlocksCaused = p.NilBlocksCaused + other.NilBlocksCaused
+	p.RoundsParticipated = p.RoundsParticipated + other.RoundsParticipated
+	p.Slashings = p.Slashings + other.Slashings
+	p.SlashedAmount.Add(p.SlashedAmount, other.SlashedAmount)
+	p.RewardsEarned.Add(p.RewardsEarned, other.RewardsEarned)
+}
+
+// performanceTally is the performance of all validators that took part in a range of blocks.
+type performanceTally map[common.Address]*validatorPerformance
+
+func (t performanceTally) get(validator common.Address) *validatorPerformance {
+	performance, ok := t[validator]
+	if ok == false {
+		performance = newValidatorPerformance(validator)
+		t[validator] = performance
+	}
+	return performance
+}
+
+// processPerformanceHeader adds the outcome of the consensus of a block to the tally. The
+// slashings and rewards mirror the ones applied by Finalize. The header must be of the
+// canonical chain, its packets and evidence were verified when the block was imported
+// and their signers are read without verifying the signatures again.
+func processPerformanceHeader(tally performanceTally, header *types.Header, schedule *params.ProofOfStakeForkSchedule) error {
+	if len(header.ConsensusData) == 0 {
+		return nil
+	}
+	blockNumber := header.Number.Uint64()
+
+	blockConsensusData := &BlockConsensusData{}
+	err := rlp.DecodeBytes(header.ConsensusData, blockConsensusData)
+	if err != nil {
+		return err
+	}
+
+	if blockConsensusData.VoteType == VOTE_TYPE_OK {
+		performance := tally.get(blockConsensusData.BlockProposer)
+		performance.ProposalsMade = performance.ProposalsMade + 1
+		if blockNumber >= schedule.RewardStartBlock {
+			performance.RewardsEarned.Add(performance.RewardsEarned, GetReward(header.Number, schedule))
+		}
+	}
+
+	for _, proposer := range blockConsensusData.SlashedBlockProposers {
+		performance := tally.get(proposer)
+		performance.ProposalsMissed = performance.ProposalsMissed + 1
+		if blockConsensusData.Round == 1 {
+			if blockConsensusData.VoteType == VOTE_TYPE_NIL {
+				performance.NilBlocksCaused = performance.NilBlocksCaused + 1
+			}
+			if blockNumber >= schedule.SlashStartBlock {
+				performance.Slashings = performance.Slashings + 1
+				performance.SlashedAmount.Add(performance.SlashedAmount, slashAmount)
+			}
+		}
+	}
+
+	if blockNumber >= schedule.EquivocationSlashStartBlock {
+		for i := range blockConsensusData.Evidence {
+			validator, err := blockConsensusData.Evidence[i].validatedSigner()
+			if err != nil {
+				return err
+			}
+			performance := tally.get(validator)
+			performance.Slashings = performance.Slashings + 1
+			performance.SlashedAmount.Add(performance.SlashedAmount, schedule.EquivocationSlashAmount)
+		}
+	}
+
+	if len(header.UnhashedConsensusData) == 0 {
+		return nil
+	}
+	blockAdditionalConsensusData := &BlockAdditionalConsensusData{}
+	err = rlp.DecodeBytes(header.UnhashedConsensusData, blockAdditionalConsensusData)
+	if err != nil {
+		return err
+	}
+
+	type participation struct {
+		validator common.Address
+		round     byte
+	}
+	participated := make(map[participation]bool)
+	for i := range blockAdditionalConsensusData.ConsensusPackets {
+		packet := &blockAdditionalConsensusData.ConsensusPackets[i]
+		_, round, err := decodePacketRound(packet.ConsensusData)
+		if err != nil {
+			continue
+		}
+		validator, err := validatedPacketSigner(packet)
+		if err != nil {
+			continue
+		}
+		key := participation{validator: validator, round: round}
+		if participated[key] {
+			continue
+		}
+		participated[key] = true
+
+		performance := tally.get(validator)
+		performance.RoundsParticipated = performance.RoundsParticipated + 1
+	}
+
+	return nil
+}
+
+func encodePerformanceTally(tally performanceTally) ([]byte, error) {
+	list := make([]*validatorPerformance, 0, len(tally))
+	for _, performance := range tally {
+		list = append(list, performance)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(list[i].Validator.Bytes(), list[j].Validator.Bytes()) < 0
+	})
+	return rlp.EncodeToBytes(list)
+}
+
+func decodePerformanceTally(data []byte) (performanceTally, error) {
+	var list []*validatorPerformance
+	if err := rlp.DecodeBytes(data, &list); err != nil {
+		return nil, err
+	}
+	tally := make(performanceTally)
+	for _, performance := range list {
+		tally[performance.Validator] = performance
+	}
+	return tally, nil
+}
+
+// PerformanceIndexer implements a core.ChainIndexer, aggregating the consensus outcome
+// of each block into the performance of the validators per section.
+type PerformanceIndexer struct {
+	size     uint64                           // section size to aggregate the performance of
+	db       ethdb.Database                   // database instance to write index data into
+	schedule *params.ProofOfStakeForkSchedule // activation blocks of the slashing and reward forks
+	section  uint64                           // section number being processed currently
+	head     common.Hash                      // hash of the last header processed
+	tally    performanceTally                 // performance of the section being processed
+}
+
+// NewPerformanceIndexer returns a chain indexer that aggregates the validator performance
+// of the canonical chain.
+func NewPerformanceIndexer(db ethdb.Database, schedule *params.ProofOfStakeForkSchedule) *core.ChainIndexer {
+	backend := &PerformanceIndexer{
+		db:       db,
+		size:     performanceSectionSize,
+		schedule: schedule,
+	}
+	table := rawdb.NewTable(db, string(rawdb.ValidatorPerformanceIndexPrefix))
+
+	return core.NewChainIndexer(db, table, backend, performanceSectionSize, performanceConfirms, performanceThrottling, "validatorperformance")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new performance section.
+func (p *PerformanceIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	p.section, p.head, p.tally = section, common.Hash{}, make(performanceTally)
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the consensus outcome of a new
+// header to the section.
+func (p *PerformanceIndexer) Process(ctx context.Context, header *types.Header) error {
+	if err := processPerformanceHeader(p.tally, header, p.schedule); err != nil {
+		return err
+	}
+	p.head = header.Hash()
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing the performance of the section
+// into the database.
+func (p *PerformanceIndexer) Commit() error {
+	data, err := encodePerformanceTally(p.tally)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteValidatorPerformance(p.db, p.section, p.head, data)
+	return nil
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (p *PerformanceIndexer) Prune(threshold uint64) error {
+	return nil
+}
+
+// getValidatorPerformance returns the performance of the validator between two blocks
+// (both inclusive). Sections of the canonical chain that are indexed are read from the
+// database, the remaining blocks are decoded from their headers.
+func getValidatorPerformance(db ethdb.Database, size uint64, sections uint64, getHeaderByNumber func(uint64) *types.Header,
+	validator common.Address, fromBlock uint64, toBlock uint64, schedule *params.ProofOfStakeForkSchedule) (*validatorPerformance, error) {
+	if performanceScanBlocks(size, sections, fromBlock, toBlock) > maxPerformanceScanBlocks {
+		return nil, PerformanceRangeNotIndexedErr
+	}
+	performance := newValidatorPerformance(validator)
+
+	scanned := uint64(0)
+	for number := fromBlock; number <= toBlock; {
+		section := number / size
+		if number%size == 0 && section < sections && toBlock-number >= size-1 {
+			head := rawdb.ReadCanonicalHash(db, number+size-1)
+			if data := rawdb.ReadValidatorPerformance(db, section, head); len(data) > 0 {
+				tally, err := decodePerformanceTally(data)
+				if err != nil {
+					return nil, err
+				}
+				performance.add(tally[validator])
+				if toBlock-number == size-1 {
+					break
+				}
+				number = number + size
+				continue
+			}
+		}
+
+		scanned = scanned + 1
+		if scanned > maxPerformanceScanBlocks {
+			return nil, PerformanceRangeNotIndexedErr
+		}
+		header := getHeaderByNumber(number)
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		tally := make(performanceTally)
+		if err := processPerformanceHeader(tally, header, schedule); err != nil {
+			return nil, err
+		}
+		performance.add(tally[validator])
+		if number == toBlock {
+			break
+		}
+		number = number + 1
+	}
+
+	return performance, nil
+}
+
+// performanceScanBlocks returns the number of blocks between two blocks (both inclusive)
+// that are not covered by one of the indexed sections, and have to be decoded from their
+// headers.
+func performanceScanBlocks(size uint64, sections uint64, fromBlock uint64, toBlock uint64) uint64 {
+	firstSection := (fromBlock + size - 1) / size
+	lastSection := (toBlock + 1) / size
+	if lastSection > sections {
+		lastSection = sections
+	}
+	blocks := toBlock - fromBlock + 1
+	if lastSection <= firstSection {
+		return blocks
+	}
+	return blocks - (lastSection-firstSection)*size
+}
@@ -0,0 +1,219 @@
+package proofofstake
+
+import (
+	"context"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/core/rawdb"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/eth/protocols/eth"
+	"github.com/DogeProtocol/dp/params"
+	"github.com/DogeProtocol/dp/rlp"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func testPerformanceHeader(t *testing.T, number int64, data *BlockConsensusData, packets []*eth.ConsensusPacket) *types.Header {
+	if data.SlashedBlockProposers == nil {
+		data.SlashedBlockProposers = make([]common.Address, 0)
+	}
+	if data.SelectedTransactions == nil {
+		data.SelectedTransactions = make([]common.Hash, 0)
+	}
+	consensusData, err := rlp.EncodeToBytes(data)
+	if err != nil {
+		t.Fatalf("failed to encode consensus data: %v", err)
+	}
+	additionalData := &BlockAdditionalConsensusData{ConsensusPackets: make([]eth.ConsensusPacket, 0)}
+	for _, packet := range packets {
+		additionalData.ConsensusPackets = append(additionalData.ConsensusPackets, eth.NewConsensusPacket(packet))
+	}
+	unhashedConsensusData, err := rlp.EncodeToBytes(additionalData)
+	if err != nil {
+		t.Fatalf("failed to encode additional consensus data: %v", err)
+	}
+	return &types.Header{Number: big.NewInt(number), ConsensusData: consensusData, UnhashedConsensusData: unhashedConsensusData}
+}
+
+// testPerformanceChain returns a genesis header followed by an ok block of the first
+// validator, a nil block caused by the second validator, an ok block of the first
+// validator in round 2 and a block with equivocation evidence of the second validator.
+func testPerformanceChain(t *testing.T) ([]*types.Header, common.Address, common.Address) {
+	proposer, other := testJournalHandlers()
+	parentHash := common.BytesToHash([]byte{1})
+	proposalHash := common.BytesToHash([]byte{2})
+
+	headers := []*types.Header{{Number: big.NewInt(0)}}
+	headers = append(headers, testPerformanceHeader(t, 1, &BlockConsensusData{VoteType: VOTE_TYPE_OK, BlockProposer: proposer.account.Address, Round: 1},
+		[]*eth.ConsensusPacket{
+			testSignPacket(t, proposer, parentHash, testOkAckData(t, 1, proposalHash)),
+			testSignPacket(t, other, parentHash, testOkAckData(t, 1, proposalHash)),
+		}))
+	headers = append(headers, testPerformanceHeader(t, 2, &BlockConsensusData{VoteType: VOTE_TYPE_NIL, Round: 1,
+		SlashedBlockProposers: []common.Address{other.account.Address}},
+		[]*eth.ConsensusPacket{
+			testSignPacket(t, proposer, parentHash, testNilAckData(t, parentHash, 1)),
+		}))
+	headers = append(headers, testPerformanceHeader(t, 3, &BlockConsensusData{VoteType: VOTE_TYPE_OK, BlockProposer: proposer.account.Address, Round: 2,
+		SlashedBlockProposers: []common.Address{other.account.Address}},
+		[]*eth.ConsensusPacket{
+			testSignPacket(t, proposer, parentHash, testNilAckData(t, parentHash, 1)),
+			testSignPacket(t, proposer, parentHash, testOkAckData(t, 2, proposalHash)),
+		}))
+
+	evidence := NewEquivocationEvidence(testSignPacket(t, other, parentHash, testNilAckData(t, parentHash, 1)),
+		testSignPacket(t, other, parentHash, testOkAckData(t, 1, proposalHash)))
+	headers = append(headers, testPerformanceHeader(t, 4, &BlockConsensusData{VoteType: VOTE_TYPE_NIL, Round: MAX_ROUND,
+		Evidence: []EquivocationEvidence{*evidence}}, nil))
+
+	return headers, proposer.account.Address, other.account.Address
+}
+
+func testPerformanceSchedule() *params.ProofOfStakeForkSchedule {
+	schedule := *params.DefaultProofOfStakeForkSchedule
+	schedule.RewardStartBlock = 0
+	schedule.SlashStartBlock = 0
+	schedule.EquivocationSlashStartBlock = 0
+	return &schedule
+}
+
+func TestProcessPerformanceHeader(t *testing.T) {
+	headers, proposer, other := testPerformanceChain(t)
+	schedule := testPerformanceSchedule()
+
+	tally := make(performanceTally)
+	for _, header := range headers {
+		if err := processPerformanceHeader(tally, header, schedule); err != nil {
+			t.Fatalf("processPerformanceHeader of block %d failed: %v", header.Number, err)
+		}
+	}
+
+	rewards := new(big.Int).Add(GetReward(big.NewInt(1), schedule), GetReward(big.NewInt(3), schedule))
+	expected := &validatorPerformance{Validator: proposer, ProposalsMade: 2, RoundsParticipated: 4,
+		SlashedAmount: big.NewInt(0), RewardsEarned: rewards}
+	if !reflect.DeepEqual(tally[proposer], expected) {
+		t.Fatalf("proposer performance mismatch:\nhave %+v\nwant %+v", tally[proposer], expected)
+	}
+
+	slashed := new(big.Int).Add(slashAmount, schedule.EquivocationSlashAmount)
+	expected = &validatorPerformance{Validator: other, ProposalsMissed: 2, NilBlocksCaused: 1, RoundsParticipated: 1, Slashings: 2,
+		SlashedAmount: slashed, RewardsEarned: big.NewInt(0)}
+	if !reflect.DeepEqual(tally[other], expected) {
+		t.Fatalf("other performance mismatch:\nhave %+v\nwant %+v", tally[other], expected)
+	}
+
+	// Slashings before the forks are not applied by Finalize
+	tally = make(performanceTally)
+	for _, header := range headers {
+		if err := processPerformanceHeader(tally, header, params.DefaultProofOfStakeForkSchedule); err != nil {
+			t.Fatalf("processPerformanceHeader of block %d failed: %v", header.Number, err)
+		}
+	}
+	if tally[other].Slashings != 0 || tally[other].NilBlocksCaused != 1 {
+		t.Fatalf("expected no slashings before the forks, got %+v", tally[other])
+	}
+}
+
+func TestGetValidatorPerformance(t *testing.T) {
+	headers, proposer, other := testPerformanceChain(t)
+	schedule := testPerformanceSchedule()
+	db := rawdb.NewMemoryDatabase()
+	for _, header := range headers {
+		rawdb.WriteCanonicalHash(db, header.Hash(), header.Number.Uint64())
+	}
+
+	// Index the first section of two blocks
+	indexer := &PerformanceIndexer{db: db, size: 2, schedule: schedule}
+	if err := indexer.Reset(context.Background(), 0, common.Hash{}); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	for _, header := range headers[:2] {
+		if err := indexer.Process(context.Background(), header); err != nil {
+			t.Fatalf("Process failed: %v", err)
+		}
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	getHeaderByNumber := func(number uint64) *types.Header {
+		return headers[number]
+	}
+	indexedOnly := func(number uint64) *types.Header {
+		if number < 2 {
+			return nil
+		}
+		return headers[number]
+	}
+
+	for _, validator := range []common.Address{proposer, other} {
+		tally := make(performanceTally)
+		for _, header := range headers {
+			if err := processPerformanceHeader(tally, header, schedule); err != nil {
+				t.Fatalf("processPerformanceHeader failed: %v", err)
+			}
+		}
+
+		unindexed, err := getValidatorPerformance(db, 2, 0, getHeaderByNumber, validator, 0, 4, schedule)
+		if err != nil {
+			t.Fatalf("getValidatorPerformance without index failed: %v", err)
+		}
+		if !reflect.DeepEqual(unindexed, tally[validator]) {
+			t.Fatalf("performance mismatch:\nhave %+v\nwant %+v", unindexed, tally[validator])
+		}
+
+		indexed, err := getValidatorPerformance(db, 2, 1, indexedOnly, validator, 0, 4, schedule)
+		if err != nil {
+			t.Fatalf("getValidatorPerformance with index failed: %v", err)
+		}
+		if !reflect.DeepEqual(indexed, unindexed) {
+			t.Fatalf("indexed performance mismatch:\nhave %+v\nwant %+v", indexed, unindexed)
+		}
+	}
+
+	// A partially requested section is decoded from the headers
+	performance, err := getValidatorPerformance(db, 2, 1, getHeaderByNumber, proposer, 1, 1, schedule)
+	if err != nil {
+		t.Fatalf("getValidatorPerformance failed: %v", err)
+	}
+	if performance.ProposalsMade != 1 {
+		t.Fatalf("expected one proposal, got %d", performance.ProposalsMade)
+	}
+
+	// A section of a reorged chain is not used
+	rawdb.WriteCanonicalHash(db, common.BytesToHash([]byte{9}), 1)
+	if _, err = getValidatorPerformance(db, 2, 1, indexedOnly, proposer, 0, 4, schedule); err == nil {
+		t.Fatalf("expected the stale section to be skipped")
+	}
+}
+
+func TestPerformanceScanBlocks(t *testing.T) {
+	tests := []struct {
+		sections, fromBlock, toBlock uint64
+		want                         uint64
+	}{
+		{0, 0, 9, 10},
+		{5, 0, 9, 0},
+		{5, 1, 9, 1},
+		{2, 0, 9, 6},
+		{5, 3, 3, 1},
+		{5, 2, 3, 0},
+		{5, 1, 2, 2},
+	}
+	for _, test := range tests {
+		if have := performanceScanBlocks(2, test.sections, test.fromBlock, test.toBlock); have != test.want {
+			t.Errorf("sections %d, range %d-%d: have %d, want %d", test.sections, test.fromBlock, test.toBlock, have, test.want)
+		}
+	}
+
+	// A range that is not indexed is refused before any header is read
+	getHeaderByNumber := func(number uint64) *types.Header {
+		t.Fatalf("header %d read for a range that is not indexed", number)
+		return nil
+	}
+	_, err := getValidatorPerformance(rawdb.NewMemoryDatabase(), performanceSectionSize, 0, getHeaderByNumber, common.Address{},
+		0, maxPerformanceScanBlocks, testPerformanceSchedule())
+	if err != PerformanceRangeNotIndexedErr {
+		t.Fatalf("expected %v, got %v", PerformanceRangeNotIndexedErr, err)
+	}
+}
@@ -63,8 +63,8 @@ var (
 
 	epochLength = uint64(30000) // Default number of blocks after which to checkpoint and reset the pending votes
 
-	extraVanity = 32                                               // Fixed number of extra-data prefix bytes reserved for validator vanity
-	extraSeal   = cryptobase.SigAlg.SignatureWithPublicKeyLength() // Fixed number of extra-data suffix bytes reserved for validator seal
+	extraVanity = 32                                        // Fixed number of extra-data prefix bytes reserved for validator vanity
+	extraSeal   = sealLength(params.DefaultSignatureScheme) // Fixed number of extra-data suffix bytes reserved for validator seal
 
 	nonceAuthVote = hexutil.MustDecode("0xffffffffffffffff") // Magic nonce number to vote on adding a new validator
 	nonceDropVote = hexutil.MustDecode("0x0000000000000000") // Magic nonce number to vote on removing a validator.
@@ -74,23 +74,10 @@ var (
 
 	slashAmount = params.EtherToWei(big.NewInt(10))
 
-	rewardStartBlockNumber = uint64(277204)
-	slashStartBlockNumber  = uint64(1497600)
-
-	FULL_SIGN_PROPOSAL_CUTOFF_BLOCK     = uint64(421888)
 	FULL_SIGN_PROPOSAL_FREQUENCY_BLOCKS = uint64(4096)
 
-	STAKING_CONTRACT_V2_CUTOFF_BLOCK  = FULL_SIGN_PROPOSAL_CUTOFF_BLOCK
-	CONSENSUS_CONTEXT_START_BLOCK     = FULL_SIGN_PROPOSAL_CUTOFF_BLOCK
 	CONSENSUS_CONTEXT_MAX_BLOCK_COUNT = uint64(512000)
-
-	VALIDATOR_NIL_BLOCK_START_BLOCK      = STAKING_CONTRACT_V2_CUTOFF_BLOCK + 1
-	BLOCK_PROPOSER_NIL_BLOCK_START_BLOCK = VALIDATOR_NIL_BLOCK_START_BLOCK + 16
-
-	CONTEXT_BASED_START_BLOCK     = uint64(536000)
-	CONTEXT_BASED_BLOCK_THRESHOLD = uint64(64000)
-	BLOCK_TIME_ORIG_START_BLOCK   = uint64(CONTEXT_BASED_START_BLOCK + 1)
-	PACKET_PROTOCOL_START_BLOCK   = uint64(BLOCK_TIME_ORIG_START_BLOCK + 32)
+	CONTEXT_BASED_BLOCK_THRESHOLD     = params.ConsensusContextBasedBlockThreshold
 )
 
 // Various error messages to mark blocks invalid. These should be private to
@@ -175,8 +162,9 @@ type SignerTxFn func(accounts.Account, *types.Transaction, *big.Int) (*types.Tra
 // ProofOfStake is the proof-of-authority consensus engine proposed to support the
 // Ethereum testnet following the Ropsten attacks.
 type ProofOfStake struct {
-	chainConfig *params.ChainConfig        // Chain config
-	config      *params.ProofOfStakeConfig // Consensus engine configuration parameters
+	chainConfig *params.ChainConfig              // Chain config
+	config      *params.ProofOfStakeConfig       // Consensus engine configuration parameters
+	schedule    *params.ProofOfStakeForkSchedule // Activation blocks of the proof-of-stake forks
 	genesisHash common.Hash
 	db          ethdb.Database // Database to store and retrieve snapshot checkpoints
 
@@ -202,6 +190,11 @@ type ProofOfStake struct {
 
 	account    *accounts.Account
 	blockchain *core.BlockChain
+
+	performanceIndexer *core.ChainIndexer // Validator performance indexer operating during block imports
+
+	packetVerifierStarted    bool // Whether the engine started the packet signature verifier
+	packetSignatureCacheSize int  // Number of packet signature verifications kept by the verifier, the default if zero
 }
 
 // New creates a ProofOfStake proof-of-authority consensus engine with the initial
@@ -218,11 +211,17 @@ func New(chainConfig *params.ChainConfig, db ethdb.Database,
 	recents, _ := lru.NewARC(inmemorySnapshots)
 	signatures, _ := lru.NewARC(inmemorySignatures)
 
+	schedule := conf.ProofOfStakeForkSchedule()
+
 	packetHandler := NewConsensusPacketHandler()
+	packetHandler.SetForkSchedule(schedule)
+	packetHandler.SetVoteJournal(db)
+	packetHandler.journal.prune()
 
 	proofofstake := &ProofOfStake{
 		chainConfig:      chainConfig,
 		config:           conf.ProofOfStake,
+		schedule:         schedule,
 		genesisHash:      genesisHash,
 		db:               db,
 		ethAPI:           ethAPI,
@@ -252,6 +251,21 @@ func (c *ProofOfStake) SetP2PHandler(handler *handler.P2PHandler, localPeerId st
 
 func (c *ProofOfStake) SetBlockchain(blockchain *core.BlockChain) {
 	c.blockchain = blockchain
+
+	if c.performanceIndexer == nil {
+		c.performanceIndexer = NewPerformanceIndexer(c.db, c.schedule)
+		c.performanceIndexer.Start(blockchain)
+	}
+	if c.packetVerifierStarted == false {
+		startPacketVerifier(c.packetSignatureCacheSize)
+		c.packetVerifierStarted = true
+	}
+}
+
+// SetPacketSignatureCacheSize sets the number of packet signature verifications that
+// are kept, it has to be set before the blockchain.
+func (c *ProofOfStake) SetPacketSignatureCacheSize(size int) {
+	c.packetSignatureCacheSize = size
 }
 
 // Author implements consensus.Engine, returning the Ethereum address recovered
@@ -610,14 +624,19 @@ func (c *ProofOfStake) VerifyBlock(chain consensus.ChainHeaderReader, block *typ
 	}
 
 	var valDetailsMap map[common.Address]*ValidatorDetailsV2
-	if number >= BLOCK_PROPOSER_NIL_BLOCK_START_BLOCK {
+	if number >= c.schedule.BlockProposerNilBlockStartBlock {
 		valDetailsMap, err = c.ListValidatorsAsMap(header.ParentHash)
 		if err != nil {
 			return err
 		}
 	}
 
-	err = ValidateBlockConsensusData(block, &validatorDepositMap, &valDetailsMap, c.GetConsensusContext, c.GetValidators)
+	window, err := getEquivocationWindow(chain, header.ParentHash, number, c.schedule)
+	if err != nil {
+		return err
+	}
+
+	err = ValidateBlockConsensusData(block, &validatorDepositMap, &valDetailsMap, c.GetConsensusContext, c.GetValidators, window, c.schedule)
 	if err != nil {
 		log.Trace("ValidateBlockConsensusData", "err", err)
 	}
@@ -719,7 +738,7 @@ func (c *ProofOfStake) Finalize(chain consensus.ChainHeaderReader, header *types
 
 	//Block Slashing
 	//If Round = 1, then it means PROPOSER was likely offline, as opposed to Round = 2 which means validators were not able to get consensus on time
-	if blockConsensusData.Round == 1 && blockConsensusData.SlashedBlockProposers != nil && len(blockConsensusData.SlashedBlockProposers) > 0 && header.Number.Uint64() >= slashStartBlockNumber {
+	if blockConsensusData.Round == 1 && blockConsensusData.SlashedBlockProposers != nil && len(blockConsensusData.SlashedBlockProposers) > 0 && header.Number.Uint64() >= c.schedule.SlashStartBlock {
 		for _, val := range blockConsensusData.SlashedBlockProposers {
 			depositor, err := c.GetDepositorOfValidator(val, header.ParentHash)
 			if err != nil {
@@ -739,10 +758,34 @@ func (c *ProofOfStake) Finalize(chain consensus.ChainHeaderReader, header *types
 		}
 	}
 
+	//Equivocation Slashing
+	if len(blockConsensusData.Evidence) > 0 && header.Number.Uint64() >= c.schedule.EquivocationSlashStartBlock {
+		for i := range blockConsensusData.Evidence {
+			val, err := blockConsensusData.Evidence[i].Verify()
+			if err != nil {
+				return err
+			}
+			depositor, err := c.GetDepositorOfValidator(val, header.ParentHash)
+			if err != nil {
+				return err
+			}
+			slashTotal, err := c.AddDepositorSlashing(header.ParentHash, depositor, c.schedule.EquivocationSlashAmount, state, header)
+			if err != nil {
+				log.Trace("AddDepositorSlashing err", "err", err)
+				return err
+			}
+			log.Warn("Validator slashed for equivocation", "validator", val, "depositor", depositor, "slashAmount", c.schedule.EquivocationSlashAmount, "slashTotal", slashTotal)
+
+			if c.signFn != nil && val.IsEqualTo(c.validator) {
+				log.Warn("Your account got slashed for signing conflicting consensus packets!", "parentHash", header.ParentHash)
+			}
+		}
+	}
+
 	//Validator nil block
 	//If Round = 1, then it means PROPOSER was likely offline, as opposed to Round = 2 which means validators were not able to get consensus on time
 	if blockConsensusData.VoteType == VOTE_TYPE_NIL && blockConsensusData.Round == 1 && blockConsensusData.SlashedBlockProposers != nil &&
-		len(blockConsensusData.SlashedBlockProposers) > 0 && header.Number.Uint64() >= VALIDATOR_NIL_BLOCK_START_BLOCK {
+		len(blockConsensusData.SlashedBlockProposers) > 0 && header.Number.Uint64() >= c.schedule.ValidatorNilBlockStartBlock {
 		for _, val := range blockConsensusData.SlashedBlockProposers {
 			err = c.SetNilBlock(val, state, header)
 			if err != nil {
@@ -753,8 +796,8 @@ func (c *ProofOfStake) Finalize(chain consensus.ChainHeaderReader, header *types
 	}
 
 	//Block Rewards
-	if blockConsensusData.VoteType == VOTE_TYPE_OK && header.Number.Uint64() >= rewardStartBlockNumber {
-		blockProposerRewardAmount := GetReward(header.Number)
+	if blockConsensusData.VoteType == VOTE_TYPE_OK && header.Number.Uint64() >= c.schedule.RewardStartBlock {
+		blockProposerRewardAmount := GetReward(header.Number, c.schedule)
 
 		//Add same amount of reward to Staking Contract, so that it is available for withdrawal later on
 		err := c.accumulateBalance(state, blockProposerRewardAmount, common.HexToAddress(staking.GetStakingContract_Address_String()))
@@ -783,7 +826,7 @@ func (c *ProofOfStake) Finalize(chain consensus.ChainHeaderReader, header *types
 		}
 
 		//Validator nil block reset
-		if header.Number.Uint64() > VALIDATOR_NIL_BLOCK_START_BLOCK {
+		if header.Number.Uint64() > c.schedule.ValidatorNilBlockStartBlock {
 			err = c.ResetNilBlock(blockConsensusData.BlockProposer, state, header)
 			if err != nil {
 				log.Error("ResetNilBlock err", "err", err)
@@ -793,21 +836,21 @@ func (c *ProofOfStake) Finalize(chain consensus.ChainHeaderReader, header *types
 	}
 
 	//Staking V2
-	if header.Number.Uint64() == STAKING_CONTRACT_V2_CUTOFF_BLOCK {
-		log.Info("Setting stakingv2 contract code", "blockNumber", STAKING_CONTRACT_V2_CUTOFF_BLOCK)
+	if header.Number.Uint64() == c.schedule.StakingContractV2Block {
+		log.Info("Setting stakingv2 contract code", "blockNumber", c.schedule.StakingContractV2Block)
 		stakingContractCode := common.FromHex(stakingv2.STAKING_RUNTIME_BIN)
 		state.SetCode(staking.STAKING_CONTRACT_ADDRESS, stakingContractCode)
 	}
 
 	//Consensus Context
-	if header.Number.Uint64() == CONSENSUS_CONTEXT_START_BLOCK {
-		log.Info("Setting consensus context contract code", "blockNumber", CONSENSUS_CONTEXT_START_BLOCK)
+	if header.Number.Uint64() == c.schedule.ConsensusContextStartBlock {
+		log.Info("Setting consensus context contract code", "blockNumber", c.schedule.ConsensusContextStartBlock)
 		consensuscontextContractCode := common.FromHex(consensuscontext.CONSENSUS_CONTEXT_RUNTIME_BIN)
 		state.SetCode(consensuscontext.CONSENSUS_CONTEXT_CONTRACT_ADDRESS, consensuscontextContractCode)
 	}
 
-	if header.Number.Uint64() > CONSENSUS_CONTEXT_START_BLOCK {
-		key, err := GetConsensusContextKey(header.Number.Uint64())
+	if header.Number.Uint64() > c.schedule.ConsensusContextStartBlock {
+		key, err := GetConsensusContextKey(header.Number.Uint64(), c.schedule)
 		if err != nil {
 			log.Error("GetBlockConsensusContextFn err", "err", err)
 			return err
@@ -821,8 +864,8 @@ func (c *ProofOfStake) Finalize(chain consensus.ChainHeaderReader, header *types
 		}
 
 		//Remove the oldest key
-		if header.Number.Uint64() > (CONSENSUS_CONTEXT_START_BLOCK + CONSENSUS_CONTEXT_MAX_BLOCK_COUNT) {
-			oldKey, err := GetConsensusContextKey(header.Number.Uint64() - CONSENSUS_CONTEXT_MAX_BLOCK_COUNT)
+		if header.Number.Uint64() > (c.schedule.ConsensusContextStartBlock + CONSENSUS_CONTEXT_MAX_BLOCK_COUNT) {
+			oldKey, err := GetConsensusContextKey(header.Number.Uint64()-CONSENSUS_CONTEXT_MAX_BLOCK_COUNT, c.schedule)
 			if err != nil {
 				log.Error("GetBlockConsensusContextKey oldKey err", "err", err)
 				return err
@@ -838,7 +881,7 @@ func (c *ProofOfStake) Finalize(chain consensus.ChainHeaderReader, header *types
 
 	//Fix blocktime
 	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
-	if (header.Number.Uint64() == 1 || header.Number.Uint64()%BLOCK_PERIOD_TIME_CHANGE == 0 || header.Number.Uint64() >= BLOCK_TIME_ORIG_START_BLOCK) && blockConsensusData.VoteType == VOTE_TYPE_OK && parent.Time < blockConsensusData.BlockTime {
+	if (header.Number.Uint64() == 1 || header.Number.Uint64()%BLOCK_PERIOD_TIME_CHANGE == 0 || header.Number.Uint64() >= c.schedule.BlockTimeOrigStartBlock) && blockConsensusData.VoteType == VOTE_TYPE_OK && parent.Time < blockConsensusData.BlockTime {
 		header.Time = blockConsensusData.BlockTime
 	} else {
 		header.Time = parent.Time + c.config.Period
@@ -879,7 +922,13 @@ func (c *ProofOfStake) FinalizeAndAssembleWithConsensus(chain consensus.ChainHea
 		return nil, errors.New("Block state not yet BLOCK_STATE_WAITING_FOR_COMMITS")
 	}
 
-	blockConsensusData, blockAdditionalConsensusData, err := c.consensusHandler.getBlockConsensusData(header.ParentHash)
+	window, err := getEquivocationWindow(chain, header.ParentHash, number, c.schedule)
+	if err != nil {
+		log.Trace("getEquivocationWindow", "err", err)
+		return nil, err
+	}
+
+	blockConsensusData, blockAdditionalConsensusData, err := c.consensusHandler.getBlockConsensusData(header.ParentHash, window)
 	if err != nil {
 		log.Trace("getBlockConsensusData", "err", err)
 		return nil, err
@@ -963,8 +1012,16 @@ func (c *ProofOfStake) SealHash(header *types.Header) common.Hash {
 	return SealHash(header)
 }
 
-// Close implements consensus.Engine. It's a noop for proofofstake as there are no background threads.
+// Close implements consensus.Engine, stopping the validator performance indexer and
+// the packet signature verifier.
 func (c *ProofOfStake) Close() error {
+	if c.packetVerifierStarted {
+		stopPacketVerifier()
+		c.packetVerifierStarted = false
+	}
+	if c.performanceIndexer != nil {
+		return c.performanceIndexer.Close()
+	}
 	return nil
 }
 
@@ -983,6 +1040,17 @@ func (c *ProofOfStake) GetConsensusPacketHandler() *ConsensusHandler {
 	return c.consensusHandler
 }
 
+// sealLength returns the length of the seal of the headers, the length of a signature
+// with its public key of the scheme called name. The seal is part of the header format,
+// it does not change with the signature schemes valid at a block.
+func sealLength(name string) int {
+	sigAlg, err := cryptobase.SigAlgByName(name)
+	if err != nil {
+		panic("unknown seal signature scheme " + name)
+	}
+	return sigAlg.SignatureWithPublicKeyLength()
+}
+
 // SealHash returns the hash of a block prior to it being sealed.
 func SealHash(header *types.Header) (hash common.Hash) {
 	buff := new(bytes.Buffer)
@@ -1004,7 +1072,7 @@ func encodeSigHeader(w io.Writer, header *types.Header) {
 		header.GasLimit,
 		header.GasUsed,
 		header.Time,
-		header.Extra[:len(header.Extra)-cryptobase.SigAlg.SignatureWithPublicKeyLength()], // Yes, this will panic if extra is too short
+		header.Extra[:len(header.Extra)-extraSeal], // Yes, this will panic if extra is too short
 		header.MixDigest,
 		header.Nonce,
 	}
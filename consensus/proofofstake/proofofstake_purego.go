@@ -0,0 +1,10 @@
+//go:build !cgo || purego
+// +build !cgo purego
+
+package proofofstake
+
+// Consensus packets are signed with full hybrid signatures, which only libhybridpqc
+// verifies. The purego build tag is for tools that verify compact signatures, such
+// as transaction signatures; the consensus engine, and so the node, does not build
+// with it.
+var _ = proofOfStakeRequiresCgoWithoutPuregoTag
@@ -23,6 +23,7 @@ import (
 	"github.com/DogeProtocol/dp/core/types"
 	"github.com/DogeProtocol/dp/crypto/cryptobase"
 	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+	"github.com/DogeProtocol/dp/params"
 	"github.com/DogeProtocol/dp/systemcontracts/conversion"
 	"github.com/DogeProtocol/dp/systemcontracts/staking"
 	"math/big"
@@ -193,14 +194,14 @@ func TestPos_PackAddress(t *testing.T) {
 }
 
 func testGetBlockConsensusContextForBlock(t *testing.T, blockNumber uint64, expectedBlockNumber uint64) {
-	expectedKey, err := GetConsensusContextKey(expectedBlockNumber)
+	expectedKey, err := GetConsensusContextKey(expectedBlockNumber, params.DefaultProofOfStakeForkSchedule)
 	if err != nil {
 		fmt.Println("err", err)
 		t.Fatalf("failed 1")
 		return
 	}
 
-	key, err := GetBlockConsensusContextKeyForBlock(blockNumber)
+	key, err := GetBlockConsensusContextKeyForBlock(blockNumber, params.DefaultProofOfStakeForkSchedule)
 	if err != nil {
 		fmt.Println("err", err)
 		t.Fatalf("failed 2")
@@ -0,0 +1,121 @@
+package proofofstake
+
+import (
+	"context"
+	"errors"
+	"github.com/DogeProtocol/dp/accounts"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/rpc"
+	"time"
+)
+
+// remoteSignerTimeout is the maximum time to wait for the external signer to sign a
+// consensus packet, before the packet is given up on.
+const remoteSignerTimeout = 2 * time.Second
+
+var InvalidRemoteSignatureErr = errors.New("external signer returned a signature of a different account")
+
+// RemoteSigner signs consensus packets with an external signer, reached over IPC or
+// HTTP, that holds the validator key and keeps its own slashing protection database.
+// Its SignData and SignDataWithContext methods are passed to ProofOfStake.Authorize.
+type RemoteSigner struct {
+	endpoint string
+	client   *rpc.Client
+}
+
+// NewRemoteSigner connects to the external signer at the given endpoint.
+func NewRemoteSigner(endpoint string) (*RemoteSigner, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &RemoteSigner{
+		endpoint: endpoint,
+		client:   client,
+	}, nil
+}
+
+// SignData signs a consensus packet, see SignerFn.
+func (s *RemoteSigner) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	return s.sign(account, mimeType, data, nil)
+}
+
+// SignDataWithContext signs a consensus packet with the given signing context, see SignerFnWithContext.
+func (s *RemoteSigner) SignDataWithContext(account accounts.Account, mimeType string, data []byte, signContext []byte) ([]byte, error) {
+	return s.sign(account, mimeType, data, signContext)
+}
+
+func (s *RemoteSigner) sign(account accounts.Account, mimeType string, data []byte, signContext []byte) ([]byte, error) {
+	if mimeType != accounts.MimetypeProofOfStake {
+		return nil, errors.New("external signer only signs consensus packets")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), remoteSignerTimeout)
+	defer cancel()
+
+	var signature hexutil.Bytes
+	var err error
+	if signContext == nil {
+		err = s.client.CallContext(ctx, &signature, "account_signConsensusData", account.Address, hexutil.Bytes(data))
+	} else {
+		err = s.client.CallContext(ctx, &signature, "account_signConsensusData", account.Address, hexutil.Bytes(data), hexutil.Bytes(signContext))
+	}
+	if err != nil {
+		log.Debug("External signer failed to sign consensus packet", "endpoint", s.endpoint, "err", err)
+		return nil, err
+	}
+
+	// A misconfigured signer would otherwise only show up as packets rejected by the peers
+	err = verifyRemoteSignature(account.Address, data, signature, signContext)
+	if err != nil {
+		return nil, err
+	}
+
+	return signature, nil
+}
+
+func verifyRemoteSignature(address common.Address, data []byte, signature []byte, signContext []byte) error {
+	digestHash := crypto.Keccak256(data)
+	sigAlg, err := cryptobase.SigAlgFromCombinedSignature(signature)
+	if err != nil {
+		return InvalidRemoteSignatureErr
+	}
+	var pubKey *signaturealgorithm.PublicKey
+	if signContext == nil {
+		pubKey, err = sigAlg.PublicKeyFromSignature(digestHash, signature)
+		if err != nil {
+			return err
+		}
+		if sigAlg.Verify(pubKey.PubData, digestHash, signature) == false {
+			return InvalidRemoteSignatureErr
+		}
+	} else {
+		pubKey, err = sigAlg.PublicKeyFromSignatureWithContext(digestHash, signature, signContext)
+		if err != nil {
+			return err
+		}
+		if sigAlg.VerifyWithContext(pubKey.PubData, digestHash, signature, signContext) == false {
+			return InvalidRemoteSignatureErr
+		}
+	}
+
+	signer, err := sigAlg.PublicKeyToAddress(pubKey)
+	if err != nil {
+		return err
+	}
+	if signer.IsEqualTo(address) == false {
+		return InvalidRemoteSignatureErr
+	}
+
+	return nil
+}
+
+// Close closes the connection to the external signer.
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
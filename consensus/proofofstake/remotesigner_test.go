@@ -0,0 +1,89 @@
+package proofofstake
+
+import (
+	"context"
+	"github.com/DogeProtocol/dp/accounts"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+	"github.com/DogeProtocol/dp/ethdb/memorydb"
+	"github.com/DogeProtocol/dp/rpc"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testSignerService is a minimal external signer serving account_signConsensusData.
+type testSignerService struct {
+	key                *signaturealgorithm.PrivateKey
+	slashingProtection *SlashingProtectionDB
+}
+
+func (s *testSignerService) SignConsensusData(ctx context.Context, addr common.MixedcaseAddress, data hexutil.Bytes, signContext *hexutil.Bytes) (hexutil.Bytes, error) {
+	if err := s.slashingProtection.CheckAndRecord(addr.Address(), data); err != nil {
+		return nil, err
+	}
+	if signContext == nil {
+		return cryptobase.SigAlg.Sign(crypto.Keccak256(data), s.key)
+	}
+	return cryptobase.SigAlg.SignWithContext(crypto.Keccak256(data), s.key, *signContext)
+}
+
+func TestRemoteSigner(t *testing.T) {
+	key, err := cryptobase.SigAlg.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	account := accounts.Account{Address: cryptobase.SigAlg.PublicKeyToAddressNoError(&key.PublicKey)}
+
+	dir, err := ioutil.TempDir("", "remotesigner-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	endpoint := filepath.Join(dir, "signer.ipc")
+	listener, server, err := rpc.StartIPCEndpoint(endpoint, []rpc.API{
+		{
+			Namespace: "account",
+			Version:   "1.0",
+			Service:   &testSignerService{key: key, slashingProtection: NewSlashingProtectionDB(memorydb.New())},
+			Public:    true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("StartIPCEndpoint failed: %v", err)
+	}
+	defer server.Stop()
+	defer listener.Close()
+
+	signer, err := NewRemoteSigner(endpoint)
+	if err != nil {
+		t.Fatalf("NewRemoteSigner failed: %v", err)
+	}
+	defer signer.Close()
+
+	parentHash := common.BytesToHash([]byte{1})
+	okAck := append(parentHash.Bytes(), testOkAckData(t, 1, common.BytesToHash([]byte{2}))...)
+	if _, err = signer.SignData(account, accounts.MimetypeProofOfStake, okAck); err != nil {
+		t.Fatalf("SignData failed: %v", err)
+	}
+	if _, err = signer.SignData(account, accounts.MimetypeProofOfStake, okAck); err != nil {
+		t.Fatalf("expected resending a packet to be signed, got %v", err)
+	}
+
+	nilAck := append(parentHash.Bytes(), testNilAckData(t, parentHash, 1)...)
+	if _, err = signer.SignData(account, accounts.MimetypeProofOfStake, nilAck); err == nil || err.Error() != SlashingProtectionErr.Error() {
+		t.Fatalf("expected %v, got %v", SlashingProtectionErr, err)
+	}
+
+	other := accounts.Account{Address: common.BytesToAddress([]byte{3})}
+	if _, err = signer.SignData(other, accounts.MimetypeProofOfStake, okAck); err != InvalidRemoteSignatureErr {
+		t.Fatalf("expected %v, got %v", InvalidRemoteSignatureErr, err)
+	}
+	if _, err = signer.SignData(account, accounts.MimetypeTextPlain, okAck); err == nil {
+		t.Fatalf("expected signing data other than consensus packets to fail")
+	}
+}
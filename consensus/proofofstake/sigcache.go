@@ -0,0 +1,242 @@
+package proofofstake
+
+import (
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/consensus/proofofstake/finality"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/eth/protocols/eth"
+	"github.com/DogeProtocol/dp/params"
+	lru "github.com/hashicorp/golang-lru"
+	"runtime"
+	"sync"
+)
+
+var errInvalidPacketSignatureScheme = finality.InvalidSignatureSchemeErr
+
+// DefaultPacketSignatureCacheSize is the default number of packet signature
+// verifications that are kept. A block of a hundred validators carries a few hundred
+// packets per round.
+const DefaultPacketSignatureCacheSize = 16384
+
+// packetVerifier verifies the signatures of packets ahead of processing them and caches
+// the results. It is started by the consensus engines and stopped once the last of them
+// is closed, without it the signatures are verified when the packets are processed.
+var (
+	packetVerifier      *packetSignatureVerifier
+	packetVerifierUsers int
+	packetVerifierLock  sync.RWMutex // Protects the packet verifier from being stopped while in use
+)
+
+type packetSignature struct {
+	validator common.Address
+	err       error
+}
+
+// packetSignatureKey hashes the parent hash, consensus data and signature of a packet.
+// The consensus data is hashed on its own, since both it and the signature are of
+// variable length.
+func packetSignatureKey(packet *eth.ConsensusPacket) common.Hash {
+	return crypto.Keccak256Hash(packet.ParentHash.Bytes(), crypto.Keccak256(packet.ConsensusData), packet.Signature)
+}
+
+// isFullSignedProposal returns whether the packet is a proposal that is signed with the
+// full signature scheme. Those are verified with a signing context and are not cached.
+func isFullSignedProposal(packet *eth.ConsensusPacket, packetType ConsensusPacketType) bool {
+	if packetType != CONSENSUS_PACKET_TYPE_PROPOSE_BLOCK {
+		return false
+	}
+	id, err := cryptobase.SignatureID(packet.Signature)
+	return err == nil && id == crypto.DILITHIUM_ED25519_SPHINCS_FULL_ID
+}
+
+// checkPacketSignatureScheme checks that the packet is signed with a signature scheme
+// that is valid at blockNumber.
+func checkPacketSignatureScheme(packet *eth.ConsensusPacket, blockNumber uint64, schedule *params.ProofOfStakeForkSchedule) error {
+	return finality.CheckSignatureScheme(packet.Signature, blockNumber, schedule)
+}
+
+// verifyPacketSignature verifies the signature of a packet that is signed without a
+// signing context, and returns the validator that signed it. The result is served
+// from the signature cache of the packet verifier, if it is running.
+func verifyPacketSignature(packet *eth.ConsensusPacket) (common.Address, error) {
+	packetVerifierLock.RLock()
+	verifier := packetVerifier
+	packetVerifierLock.RUnlock()
+
+	if verifier == nil {
+		return recoverPacketSignature(packet)
+	}
+	return verifier.verifyPacket(packet)
+}
+
+// verifyPacket verifies the signature of a packet and returns the validator that
+// signed it. Both valid and invalid signatures are cached.
+func (v *packetSignatureVerifier) verifyPacket(packet *eth.ConsensusPacket) (common.Address, error) {
+	key := packetSignatureKey(packet)
+	if cached, ok := v.cache.Get(key); ok {
+		packetSignatureCacheHitMeter.Mark(1)
+		signature := cached.(*packetSignature)
+		return signature.validator, signature.err
+	}
+	packetSignatureCacheMissMeter.Mark(1)
+
+	signature := &packetSignature{}
+	signature.validator, signature.err = recoverPacketSignature(packet)
+	v.cache.Add(key, signature)
+
+	return signature.validator, signature.err
+}
+
+func recoverPacketSignature(packet *eth.ConsensusPacket) (common.Address, error) {
+	return finality.RecoverPacketSigner(&finality.Packet{ParentHash: packet.ParentHash, Signature: packet.Signature, ConsensusData: packet.ConsensusData})
+}
+
+// validatedPacketSigner returns the validator that signed a packet of a block that was
+// already validated on import. The public key is read from the combined signature, the
+// signature is not verified again.
+func validatedPacketSigner(packet *eth.ConsensusPacket) (common.Address, error) {
+	sigAlg, err := cryptobase.SigAlgFromCombinedSignature(packet.Signature)
+	if err != nil {
+		return ZERO_ADDRESS, InvalidPacketErr
+	}
+	_, pubKeyBytes, err := common.ExtractTwoParts(packet.Signature)
+	if err != nil {
+		return ZERO_ADDRESS, InvalidPacketErr
+	}
+	pubKey, err := sigAlg.DeserializePublicKey(pubKeyBytes)
+	if err != nil {
+		return ZERO_ADDRESS, InvalidPacketErr
+	}
+
+	return sigAlg.PublicKeyToAddress(pubKey)
+}
+
+// startPacketVerifier starts the packet verifier if it is not running yet, keeping up to
+// cacheSize signature verifications, and adds a user of it. The verifier is shared by
+// the engines, the cache size of the first one is used.
+func startPacketVerifier(cacheSize int) {
+	packetVerifierLock.Lock()
+	defer packetVerifierLock.Unlock()
+
+	if packetVerifier == nil {
+		packetVerifier = newPacketSignatureVerifier(runtime.NumCPU(), cacheSize)
+	}
+	packetVerifierUsers++
+}
+
+// stopPacketVerifier removes a user of the packet verifier, and stops it once it has
+// no users left.
+func stopPacketVerifier() {
+	packetVerifierLock.Lock()
+	defer packetVerifierLock.Unlock()
+
+	if packetVerifierUsers == 0 {
+		return
+	}
+	packetVerifierUsers--
+	if packetVerifierUsers == 0 {
+		packetVerifier.close()
+		packetVerifier = nil
+	}
+}
+
+// verifyPacketSignatures verifies the signatures of the packets on the packet verifier,
+// if it is running.
+func verifyPacketSignatures(packets []*eth.ConsensusPacket) {
+	packetVerifierLock.RLock()
+	defer packetVerifierLock.RUnlock()
+
+	if packetVerifier != nil {
+		packetVerifier.verify(packets)
+	}
+}
+
+// packetSignatureRequest is a request for verifying the signatures of a set of
+// packets, split between the workers of the verifier.
+type packetSignatureRequest struct {
+	packets []*eth.ConsensusPacket
+	inc     int
+	wg      *sync.WaitGroup
+}
+
+// packetSignatureVerifier verifies packet signatures concurrently on a fixed number of
+// workers and stores the results in the signature cache, so that they are served from
+// the cache when the packets are processed.
+type packetSignatureVerifier struct {
+	threads int
+	tasks   chan *packetSignatureRequest
+
+	// cache caches the result of verifying packet signatures, keyed by packetSignatureKey.
+	// The same packets are verified when they are received, when they are rebroadcast and
+	// when the block carrying them is validated.
+	cache *lru.Cache
+}
+
+// newPacketSignatureVerifier creates a new packet signature verifier that keeps up to
+// cacheSize signature verifications, and starts the given number of workers.
+func newPacketSignatureVerifier(threads int, cacheSize int) *packetSignatureVerifier {
+	if cacheSize <= 0 {
+		cacheSize = DefaultPacketSignatureCacheSize
+	}
+	cache, _ := lru.New(cacheSize)
+	v := &packetSignatureVerifier{
+		tasks:   make(chan *packetSignatureRequest, threads),
+		threads: threads,
+		cache:   cache,
+	}
+	for i := 0; i < threads; i++ {
+		go v.work()
+	}
+	return v
+}
+
+func (v *packetSignatureVerifier) work() {
+	for task := range v.tasks {
+		for i := 0; i < len(task.packets); i += task.inc {
+			v.preverify(task.packets[i])
+		}
+		task.wg.Done()
+	}
+}
+
+// preverify verifies the signature of a packet ahead of processing it. Malformed
+// packets and full signed proposals are left to the processing.
+func (v *packetSignatureVerifier) preverify(packet *eth.ConsensusPacket) {
+	if len(packet.ConsensusData) < 2 || len(packet.Signature) == 0 || isFullSignedProposal(packet, getPacketType(packet)) {
+		return
+	}
+	v.verifyPacket(packet)
+}
+
+// verify verifies the signatures of the packets and waits until all of them are in the
+// signature cache. Full signed proposals are skipped. A single packet is verified by
+// the caller, handing it to a worker would only add the wait for the worker.
+func (v *packetSignatureVerifier) verify(packets []*eth.ConsensusPacket) {
+	if len(packets) == 0 {
+		return
+	}
+	if len(packets) == 1 {
+		v.preverify(packets[0])
+		return
+	}
+	tasks := (len(packets) + 1) / 2
+	if tasks > v.threads {
+		tasks = v.threads
+	}
+	wg := &sync.WaitGroup{}
+	wg.Add(tasks)
+	for i := 0; i < tasks; i++ {
+		v.tasks <- &packetSignatureRequest{
+			packets: packets[i:],
+			inc:     tasks,
+			wg:      wg,
+		}
+	}
+	wg.Wait()
+}
+
+// close stops the workers of the verifier.
+func (v *packetSignatureVerifier) close() {
+	close(v.tasks)
+}
@@ -0,0 +1,223 @@
+package proofofstake
+
+import (
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/eth/protocols/eth"
+	"github.com/DogeProtocol/dp/rlp"
+	"math/big"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestVerifyPacketSignature(t *testing.T) {
+	handler, _ := testJournalHandlers()
+	parentHash := common.BytesToHash([]byte{1})
+	packet := testSignPacket(t, handler, parentHash, testNilAckData(t, parentHash, 1))
+
+	// Without the verifier the signature is verified but not cached
+	validator, err := verifyPacketSignature(packet)
+	if err != nil {
+		t.Fatalf("verifyPacketSignature failed: %v", err)
+	}
+	if validator != handler.account.Address {
+		t.Fatalf("expected validator %v, got %v", handler.account.Address, validator)
+	}
+
+	startPacketVerifier(DefaultPacketSignatureCacheSize)
+	defer stopPacketVerifier()
+	if validator, err = verifyPacketSignature(packet); err != nil || validator != handler.account.Address {
+		t.Fatalf("expected validator %v, got %v %v", handler.account.Address, validator, err)
+	}
+	if packetVerifier.cache.Contains(packetSignatureKey(packet)) == false {
+		t.Fatalf("expected the verified signature to be cached")
+	}
+	if cached, err := verifyPacketSignature(packet); err != nil || cached != validator {
+		t.Fatalf("expected the cached validator %v, got %v %v", validator, cached, err)
+	}
+
+	// A packet signed for other data must not be served from the cache
+	other := eth.NewConsensusPacket(packet)
+	other.ConsensusData = testOkAckData(t, 1, common.BytesToHash([]byte{2}))
+	if _, err = verifyPacketSignature(&other); err == nil {
+		t.Fatalf("expected a signature over other data to fail")
+	}
+	if _, err = verifyPacketSignature(&other); err == nil {
+		t.Fatalf("expected the cached verification of a signature over other data to fail")
+	}
+
+	// Moving bytes between the consensus data, parent hash and signature changes the key
+	shifted := &eth.ConsensusPacket{
+		ParentHash:    common.BytesToHash(append(packet.ConsensusData[1:], packet.ParentHash[:1]...)),
+		ConsensusData: packet.ConsensusData[:1],
+		Signature:     append(packet.ParentHash[1:], packet.Signature...),
+	}
+	if packetSignatureKey(shifted) == packetSignatureKey(packet) {
+		t.Fatalf("expected packets with different fields to have different keys")
+	}
+}
+
+func TestPacketSignatureVerifier(t *testing.T) {
+	handler, _ := testJournalHandlers()
+	verifier := newPacketSignatureVerifier(3, DefaultPacketSignatureCacheSize)
+	defer verifier.close()
+
+	parentHash := common.BytesToHash([]byte{3})
+	packets := make([]*eth.ConsensusPacket, 0)
+	for round := byte(1); round <= 7; round++ {
+		packets = append(packets, testSignPacket(t, handler, parentHash, testNilAckData(t, parentHash, round)))
+	}
+	packets = append(packets, &eth.ConsensusPacket{ParentHash: parentHash})
+	verifier.verify(packets)
+
+	for _, packet := range packets[:len(packets)-1] {
+		if verifier.cache.Contains(packetSignatureKey(packet)) == false {
+			t.Fatalf("expected the signature of every packet to be verified")
+		}
+	}
+
+	// A single packet is verified by the caller, even when no worker is free
+	single := newPacketSignatureVerifier(0, DefaultPacketSignatureCacheSize)
+	defer single.close()
+	single.verify(packets[:1])
+	if single.cache.Contains(packetSignatureKey(packets[0])) == false {
+		t.Fatalf("expected the signature of a single packet to be verified")
+	}
+}
+
+func TestPacketSignatureCacheSize(t *testing.T) {
+	handler, _ := testJournalHandlers()
+	verifier := newPacketSignatureVerifier(2, 2)
+	defer verifier.close()
+
+	parentHash := common.BytesToHash([]byte{5})
+	packets := make([]*eth.ConsensusPacket, 0)
+	for round := byte(1); round <= 3; round++ {
+		packets = append(packets, testSignPacket(t, handler, parentHash, testNilAckData(t, parentHash, round)))
+	}
+	verifier.verify(packets)
+	if verifier.cache.Len() != 2 {
+		t.Fatalf("expected the cache to keep 2 verifications, got %d", verifier.cache.Len())
+	}
+
+	// The verifier started by an engine is sized by the engine configuration
+	startPacketVerifier(1)
+	defer stopPacketVerifier()
+	verifyPacketSignatures(packets)
+	if packetVerifier.cache.Len() != 1 {
+		t.Fatalf("expected the cache to keep 1 verification, got %d", packetVerifier.cache.Len())
+	}
+}
+
+func TestStartStopPacketVerifier(t *testing.T) {
+	if packetVerifier != nil {
+		t.Fatalf("expected no packet verifier to run before an engine starts it")
+	}
+	handler, _ := testJournalHandlers()
+	parentHash := common.BytesToHash([]byte{4})
+	packet := testSignPacket(t, handler, parentHash, testNilAckData(t, parentHash, 1))
+
+	// Without the verifier the signatures are left to the processing of the packets
+	verifyPacketSignatures([]*eth.ConsensusPacket{packet})
+
+	startPacketVerifier(DefaultPacketSignatureCacheSize)
+	startPacketVerifier(DefaultPacketSignatureCacheSize)
+	stopPacketVerifier()
+	if packetVerifier == nil {
+		t.Fatalf("expected the verifier to run while it has a user")
+	}
+	if packetVerifier.cache.Contains(packetSignatureKey(packet)) {
+		t.Fatalf("expected the signature not to be verified without the verifier")
+	}
+	verifyPacketSignatures([]*eth.ConsensusPacket{packet})
+	if packetVerifier.cache.Contains(packetSignatureKey(packet)) == false {
+		t.Fatalf("expected the signature to be verified")
+	}
+	stopPacketVerifier()
+	if packetVerifier != nil {
+		t.Fatalf("expected the verifier to stop with its last user")
+	}
+	stopPacketVerifier()
+}
+
+// benchmarkParseConsensusPackets parses the packets of a block committed by a simulation
+// of the given number of validators.
+func benchmarkParseConsensusPackets(b *testing.B, validators int, threads int, cached bool) {
+	sim, err := NewSimulation(SimulationConfig{Validators: validators, Seed: 10, Blocks: 1, Latency: 50 * time.Millisecond})
+	if err != nil {
+		b.Fatalf("NewSimulation failed: %v", err)
+	}
+	result := sim.Run()
+	if len(result.Blocks) == 0 {
+		b.Fatalf("no block committed")
+	}
+	block := result.Blocks[0]
+	validatorDepositMap, _ := sim.getValidators(block.ParentHash)
+	filteredValidators, _, _, err := filterValidators(block.ParentHash, &validatorDepositMap)
+	if err != nil {
+		b.Fatalf("filterValidators failed: %v", err)
+	}
+	filteredValidatorDepositMap := make(map[common.Address]*big.Int)
+	for v := range filteredValidators {
+		filteredValidatorDepositMap[v] = validatorDepositMap[v]
+	}
+	// Decode the packets like a block received from the network
+	enc, err := rlp.EncodeToBytes(block.additionalConsensusData)
+	if err != nil {
+		b.Fatalf("failed to encode additional consensus data: %v", err)
+	}
+	additionalConsensusData := &BlockAdditionalConsensusData{}
+	if err = rlp.DecodeBytes(enc, additionalConsensusData); err != nil {
+		b.Fatalf("failed to decode additional consensus data: %v", err)
+	}
+
+	startPacketVerifier(DefaultPacketSignatureCacheSize)
+	defer stopPacketVerifier()
+	packetVerifierLock.Lock()
+	verifier := packetVerifier
+	packetVerifier = newPacketSignatureVerifier(threads, DefaultPacketSignatureCacheSize)
+	benchVerifier := packetVerifier
+	packetVerifierLock.Unlock()
+	defer func() {
+		packetVerifierLock.Lock()
+		packetVerifier.close()
+		packetVerifier = verifier
+		packetVerifierLock.Unlock()
+	}()
+
+	parse := func() {
+		_, err := ParseConsensusPackets(block.ParentHash, &additionalConsensusData.ConsensusPackets, filteredValidatorDepositMap, block.Number,
+			nil, common.ZERO_HASH, sim.schedule)
+		if err != nil {
+			b.Fatalf("ParseConsensusPackets failed: %v", err)
+		}
+	}
+	benchVerifier.cache.Purge()
+	parse()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if cached == false {
+			b.StopTimer()
+			benchVerifier.cache.Purge()
+			b.StartTimer()
+		}
+		parse()
+	}
+	b.ReportMetric(float64(len(additionalConsensusData.ConsensusPackets)), "packets/op")
+}
+
+// BenchmarkParseConsensusPackets compares the validation of the packets of a block when
+// every signature is verified in turn, as before the signature cache, when they are
+// verified concurrently, and when they were already verified as the packets were received.
+func BenchmarkParseConsensusPackets(b *testing.B) {
+	b.Run("sequential", func(b *testing.B) {
+		benchmarkParseConsensusPackets(b, 7, 1, false)
+	})
+	b.Run("parallel", func(b *testing.B) {
+		benchmarkParseConsensusPackets(b, 7, runtime.NumCPU(), false)
+	})
+	b.Run("cached", func(b *testing.B) {
+		benchmarkParseConsensusPackets(b, 7, runtime.NumCPU(), true)
+	})
+}
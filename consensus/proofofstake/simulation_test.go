@@ -0,0 +1,656 @@
+package proofofstake
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"github.com/DogeProtocol/dp/accounts"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+	"github.com/DogeProtocol/dp/eth/protocols/eth"
+	"github.com/DogeProtocol/dp/params"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// SimulationConfig describes a simulated network of validators. Every random choice of
+// the network (latency, packet loss) is derived from the seed and the packet, so that a
+// run is reproducible from the seed for a given set of validator keys.
+type SimulationConfig struct {
+	Validators int
+	Keys       []*signaturealgorithm.PrivateKey // Generated when not set
+	Seed       int64
+	Blocks     uint64        // Number of blocks to commit
+	MaxTime    time.Duration // Virtual time after which the run gives up
+
+	TickInterval time.Duration // How often each validator runs HandleConsensus
+	Latency      time.Duration // One way latency of every packet
+	Jitter       time.Duration // Random extra latency of every packet, up to this value
+	PacketLoss   int           // Percentage of packets that are dropped
+
+	Partitions []SimulationPartition
+
+	// OfflineProposers maps a block number to the number of its rounds whose proposers
+	// are offline while the block is decided. 1 takes the round 1 proposer offline, 2 the
+	// proposers of both rounds.
+	OfflineProposers map[uint64]byte
+}
+
+// SimulationPartition splits the validators into groups that cannot reach each other
+// between Start and End. Validators that are not listed form a group of their own.
+type SimulationPartition struct {
+	Start  time.Duration
+	End    time.Duration
+	Groups [][]int
+}
+
+// SimulationBlock is a block committed during a simulation.
+type SimulationBlock struct {
+	Number           uint64
+	ParentHash       common.Hash
+	Hash             common.Hash
+	Round            byte
+	VoteType         VoteType
+	Proposer         int   // Validator index, -1 for a nil block
+	SlashedProposers []int // Validator indexes
+	Offline          []int // Validators that were offline while the block was decided
+	CommittedAt      time.Duration
+
+	consensusData           *BlockConsensusData
+	additionalConsensusData *BlockAdditionalConsensusData
+}
+
+// SimulationResult is the outcome of a simulation.
+type SimulationResult struct {
+	Blocks     []*SimulationBlock
+	Violations []string // Safety violations, conflicting blocks committed for the same parent
+	Elapsed    time.Duration
+}
+
+type simEventKind byte
+
+const (
+	simEventBlock simEventKind = iota
+	simEventPacket
+	simEventRequest
+	simEventTick
+)
+
+type simEvent struct {
+	at      time.Duration
+	kind    simEventKind
+	to      int
+	from    int
+	key     common.Hash
+	packet  *eth.ConsensusPacket
+	request *eth.RequestConsensusDataPacket
+	block   *SimulationBlock
+}
+
+type simEventQueue []*simEvent
+
+func (q simEventQueue) Len() int { return len(q) }
+
+func (q simEventQueue) Less(i, j int) bool {
+	a, b := q[i], q[j]
+	if a.at != b.at {
+		return a.at < b.at
+	}
+	if a.kind != b.kind {
+		return a.kind < b.kind
+	}
+	if a.to != b.to {
+		return a.to < b.to
+	}
+	if a.from != b.from {
+		return a.from < b.from
+	}
+	return bytes.Compare(a.key.Bytes(), b.key.Bytes()) < 0
+}
+
+func (q simEventQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *simEventQueue) Push(x interface{}) { *q = append(*q, x.(*simEvent)) }
+
+func (q *simEventQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	ev := old[n-1]
+	*q = old[:n-1]
+	return ev
+}
+
+// simClock is the virtual clock shared by all validators of a simulation. It only moves
+// when the simulation moves on to the next event.
+type simClock struct {
+	now time.Time
+}
+
+func (c *simClock) Now() time.Time {
+	return c.now
+}
+
+type simNode struct {
+	index      int
+	address    common.Address
+	handler    *ConsensusHandler
+	parentHash common.Hash
+	number     uint64
+	committed  bool
+}
+
+type simP2PHandler struct {
+	sim   *Simulation
+	index int
+}
+
+func (p *simP2PHandler) SendConsensusPacket(peerList []string, packet *eth.ConsensusPacket) error {
+	return nil
+}
+
+func (p *simP2PHandler) BroadcastConsensusData(packet *eth.ConsensusPacket) error {
+	for to := range p.sim.nodes {
+		if to != p.index {
+			p.sim.sendPacket(p.index, to, packet)
+		}
+	}
+	return nil
+}
+
+func (p *simP2PHandler) RequestTransactions(txns []common.Hash) error {
+	return nil
+}
+
+func (p *simP2PHandler) RequestConsensusData(packet *eth.RequestConsensusDataPacket) error {
+	for to := range p.sim.nodes {
+		if to != p.index {
+			p.sim.sendRequest(p.index, to, packet)
+		}
+	}
+	return nil
+}
+
+func (p *simP2PHandler) GetLocalPeerId() string {
+	return "sim-" + strconv.Itoa(p.index)
+}
+
+// Simulation runs a set of ConsensusHandler instances over a simulated network on a
+// virtual clock. Everything runs on the calling goroutine, one event at a time.
+type Simulation struct {
+	config    SimulationConfig
+	clock     *simClock
+	origin    time.Time
+	now       time.Duration
+	queue     simEventQueue
+	nodes     []*simNode
+	deposits  map[common.Address]*big.Int
+	indexes   map[common.Address]int
+	keys      map[common.Address]*signaturealgorithm.PrivateKey
+	schedule  *params.ProofOfStakeForkSchedule
+	blocks    map[common.Hash]*SimulationBlock // By parent hash
+	chain     []*SimulationBlock
+	offline   map[int]bool
+	result    *SimulationResult
+	genesis   common.Hash
+	chainHead common.Hash
+}
+
+func NewSimulation(config SimulationConfig) (*Simulation, error) {
+	if config.TickInterval == 0 {
+		config.TickInterval = time.Second
+	}
+	if config.MaxTime == 0 {
+		config.MaxTime = time.Hour
+	}
+	if config.Keys == nil {
+		for i := 0; i < config.Validators; i++ {
+			key, err := cryptobase.SigAlg.GenerateKey()
+			if err != nil {
+				return nil, err
+			}
+			config.Keys = append(config.Keys, key)
+		}
+	}
+	if len(config.Keys) != config.Validators {
+		return nil, fmt.Errorf("expected %d keys, got %d", config.Validators, len(config.Keys))
+	}
+
+	sim := &Simulation{
+		config:   config,
+		origin:   time.Unix(1700000000, 0),
+		deposits: make(map[common.Address]*big.Int),
+		indexes:  make(map[common.Address]int),
+		keys:     make(map[common.Address]*signaturealgorithm.PrivateKey),
+		schedule: params.DefaultProofOfStakeForkSchedule,
+		blocks:   make(map[common.Hash]*SimulationBlock),
+		offline:  make(map[int]bool),
+		result:   &SimulationResult{},
+		genesis:  crypto.Keccak256Hash([]byte("simulation"), big.NewInt(config.Seed).Bytes()),
+	}
+	sim.clock = &simClock{now: sim.origin}
+	sim.chainHead = sim.genesis
+
+	// Validator indexes follow the address order, so that they do not depend on the
+	// order the keys were passed in.
+	addresses := make([]common.Address, 0, len(config.Keys))
+	for _, key := range config.Keys {
+		address, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		sim.keys[address] = key
+		sim.deposits[address] = params.EtherToWei(big.NewInt(500000000000))
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i].Bytes(), addresses[j].Bytes()) < 0
+	})
+
+	for i, address := range addresses {
+		handler := NewConsensusPacketHandler()
+		handler.account = accounts.Account{Address: address}
+		handler.signFn = sim.signData
+		handler.signFnWithContext = sim.signDataWithContext
+		handler.getValidatorsFn = sim.getValidators
+		handler.doesFinalizedTransactionExistFn = func(txnHash common.Hash) (bool, error) {
+			return false, nil
+		}
+		handler.getBlockConsensusContext = func(key string, blockHash common.Hash) ([32]byte, error) {
+			var blockContext [32]byte
+			copy(blockContext[:], key)
+			return blockContext, nil
+		}
+		handler.p2pHandler = &simP2PHandler{sim: sim, index: i}
+		handler.SetClock(sim.clock)
+		handler.SetSynchronousP2P(true)
+
+		sim.indexes[address] = i
+		sim.nodes = append(sim.nodes, &simNode{
+			index:      i,
+			address:    address,
+			handler:    handler,
+			parentHash: sim.genesis,
+			number:     1,
+		})
+	}
+
+	return sim, nil
+}
+
+func (s *Simulation) signData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	return cryptobase.SigAlg.Sign(crypto.Keccak256(data), s.keys[account.Address])
+}
+
+func (s *Simulation) signDataWithContext(account accounts.Account, mimeType string, data []byte, context []byte) ([]byte, error) {
+	return cryptobase.SigAlg.SignWithContext(crypto.Keccak256(data), s.keys[account.Address], context)
+}
+
+func (s *Simulation) getValidators(blockHash common.Hash) (map[common.Address]*big.Int, error) {
+	deposits := make(map[common.Address]*big.Int)
+	for address, deposit := range s.deposits {
+		deposits[address] = deposit
+	}
+	return deposits, nil
+}
+
+// Run runs the simulation until the configured number of blocks is committed by every
+// validator, or until the virtual time runs out.
+func (s *Simulation) Run() *SimulationResult {
+	restore := setSimulationTimeouts()
+	defer restore()
+
+	s.updateOfflineProposers()
+	for i := range s.nodes {
+		s.push(&simEvent{at: s.config.TickInterval * time.Duration(i) / time.Duration(len(s.nodes)), kind: simEventTick, to: i, from: i})
+	}
+
+	for s.queue.Len() > 0 && !s.done() {
+		ev := heap.Pop(&s.queue).(*simEvent)
+		if ev.at > s.config.MaxTime {
+			break
+		}
+		s.now = ev.at
+		s.clock.now = s.origin.Add(ev.at)
+
+		switch ev.kind {
+		case simEventTick:
+			s.tick(ev.to)
+		case simEventPacket:
+			if s.canDeliver(ev.from, ev.to) {
+				s.nodes[ev.to].handler.HandleConsensusPacket(ev.packet, "sim-"+strconv.Itoa(ev.from))
+				s.checkCommit(ev.to)
+			}
+		case simEventRequest:
+			if s.canDeliver(ev.from, ev.to) {
+				packets, err := s.nodes[ev.to].handler.HandleRequestConsensusDataPacket(ev.request)
+				if err == nil {
+					for _, packet := range packets {
+						s.sendPacket(ev.to, ev.from, packet)
+					}
+				}
+			}
+		case simEventBlock:
+			// A sealed block stays available after its sealer goes offline
+			if s.offline[ev.to] || !s.canReach(ev.from, ev.to) {
+				ev.at = s.now + s.config.TickInterval
+				s.push(ev)
+				continue
+			}
+			s.importBlock(ev.to, ev.block)
+		}
+	}
+
+	s.result.Elapsed = s.now
+	return s.result
+}
+
+// setSimulationTimeouts sets the timeouts of the handler to their defaults, which other
+// tests of the package change, and returns a function that restores them.
+func setSimulationTimeouts() func() {
+	startupDelay, blockTimeout, fullBlockTimeout, ackTimeout := STARTUP_DELAY_MS, BLOCK_TIMEOUT_MS, FULL_BLOCK_TIMEOUT_MS, ACK_BLOCK_TIMEOUT_MS
+	cleanupTime, maxRound, resendDelay, cleanupDelay := BLOCK_CLEANUP_TIME_MS, MAX_ROUND, BROADCAST_RESEND_DELAY, BROADCAST_CLEANUP_DELAY
+	requestDelay, skipHashCheck := CONSENSUS_DATA_REQUEST_RESEND_DELAY, SKIP_HASH_CHECK
+
+	STARTUP_DELAY_MS = int64(120000)
+	BLOCK_TIMEOUT_MS = int64(60000)
+	FULL_BLOCK_TIMEOUT_MS = int64(90000)
+	ACK_BLOCK_TIMEOUT_MS = 300000
+	BLOCK_CLEANUP_TIME_MS = int64(900000)
+	MAX_ROUND = byte(2)
+	BROADCAST_RESEND_DELAY = int64(10000)
+	BROADCAST_CLEANUP_DELAY = int64(1800000)
+	CONSENSUS_DATA_REQUEST_RESEND_DELAY = int64(30000)
+	SKIP_HASH_CHECK = true
+
+	return func() {
+		STARTUP_DELAY_MS, BLOCK_TIMEOUT_MS, FULL_BLOCK_TIMEOUT_MS, ACK_BLOCK_TIMEOUT_MS = startupDelay, blockTimeout, fullBlockTimeout, ackTimeout
+		BLOCK_CLEANUP_TIME_MS, MAX_ROUND, BROADCAST_RESEND_DELAY, BROADCAST_CLEANUP_DELAY = cleanupTime, maxRound, resendDelay, cleanupDelay
+		CONSENSUS_DATA_REQUEST_RESEND_DELAY, SKIP_HASH_CHECK = requestDelay, skipHashCheck
+	}
+}
+
+func (s *Simulation) done() bool {
+	for _, node := range s.nodes {
+		if node.number <= s.config.Blocks {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Simulation) push(ev *simEvent) {
+	heap.Push(&s.queue, ev)
+}
+
+func (s *Simulation) tick(index int) {
+	node := s.nodes[index]
+	next := s.now + s.config.TickInterval
+	if node.handler.initialized == false {
+		// The first call only starts the handler up. It randomly joins consensus before
+		// the startup delay is over, so skip the delay to keep the run deterministic.
+		node.handler.HandleConsensus(node.parentHash, s.transactions(node.number), node.number)
+		next = s.now + time.Duration(STARTUP_DELAY_MS)*time.Millisecond
+	} else if !s.offline[index] && node.number <= s.config.Blocks {
+		node.handler.HandleConsensus(node.parentHash, s.transactions(node.number), node.number)
+		s.checkCommit(index)
+	}
+	s.push(&simEvent{at: next, kind: simEventTick, to: index, from: index})
+}
+
+// transactions returns the pending transactions known to every validator at the block number.
+func (s *Simulation) transactions(number uint64) []common.Hash {
+	return []common.Hash{crypto.Keccak256Hash(big.NewInt(s.config.Seed).Bytes(), new(big.Int).SetUint64(number).Bytes())}
+}
+
+// random returns a number derived from the seed and the given values.
+func (s *Simulation) random(values ...[]byte) uint64 {
+	data := [][]byte{big.NewInt(s.config.Seed).Bytes()}
+	data = append(data, values...)
+	return binary.BigEndian.Uint64(crypto.Keccak256(data...)[:8])
+}
+
+func (s *Simulation) delay(from int, to int, key common.Hash) (time.Duration, bool) {
+	at := new(big.Int).SetInt64(int64(s.now)).Bytes()
+	ends := []byte{byte(from), byte(to)}
+	if s.config.PacketLoss > 0 && s.random(at, ends, key.Bytes(), []byte("loss"))%100 < uint64(s.config.PacketLoss) {
+		return 0, false
+	}
+	latency := s.config.Latency
+	if s.config.Jitter > 0 {
+		latency += time.Duration(s.random(at, ends, key.Bytes(), []byte("jitter")) % uint64(s.config.Jitter+1))
+	}
+	return latency, true
+}
+
+func (s *Simulation) sendPacket(from int, to int, packet *eth.ConsensusPacket) {
+	if !s.canDeliver(from, to) {
+		return
+	}
+	// The signature is left out of the key, as signing is not deterministic
+	key := crypto.Keccak256Hash(packet.ParentHash.Bytes(), packet.ConsensusData)
+	latency, ok := s.delay(from, to, key)
+	if !ok {
+		return
+	}
+	pkt := eth.NewConsensusPacket(packet)
+	s.push(&simEvent{at: s.now + latency, kind: simEventPacket, to: to, from: from, key: key, packet: &pkt})
+}
+
+func (s *Simulation) sendRequest(from int, to int, request *eth.RequestConsensusDataPacket) {
+	if !s.canDeliver(from, to) {
+		return
+	}
+	key := crypto.Keccak256Hash(request.ParentHash.Bytes(), request.RequestData)
+	latency, ok := s.delay(from, to, key)
+	if !ok {
+		return
+	}
+	req := &eth.RequestConsensusDataPacket{ParentHash: request.ParentHash, RequestData: common.CopyBytes(request.RequestData)}
+	s.push(&simEvent{at: s.now + latency, kind: simEventRequest, to: to, from: from, key: key, request: req})
+}
+
+func (s *Simulation) canDeliver(from int, to int) bool {
+	if s.offline[from] || s.offline[to] {
+		return false
+	}
+	return s.canReach(from, to)
+}
+
+// canReach returns whether the validators are on the same side of every active partition.
+func (s *Simulation) canReach(from int, to int) bool {
+	for _, partition := range s.config.Partitions {
+		if s.now < partition.Start || s.now >= partition.End {
+			continue
+		}
+		if partitionGroup(partition, from) != partitionGroup(partition, to) {
+			return false
+		}
+	}
+	return true
+}
+
+func partitionGroup(partition SimulationPartition, index int) int {
+	for g, group := range partition.Groups {
+		for _, i := range group {
+			if i == index {
+				return g
+			}
+		}
+	}
+	return -1
+}
+
+// updateOfflineProposers takes the configured proposers of the block at the head of the
+// chain offline, and brings everyone else back.
+func (s *Simulation) updateOfflineProposers() {
+	s.offline = make(map[int]bool)
+	number := uint64(len(s.chain)) + 1
+	for r := byte(1); r <= s.config.OfflineProposers[number]; r++ {
+		proposer, err := getBlockProposer(s.chainHead, &s.deposits, r, nil, number, common.ZERO_HASH, s.schedule)
+		if err != nil {
+			panic(err)
+		}
+		s.offline[s.indexes[proposer]] = true
+	}
+}
+
+func (s *Simulation) checkCommit(index int) {
+	node := s.nodes[index]
+	if node.committed {
+		return
+	}
+	state, _, err := node.handler.getBlockState(node.parentHash)
+	if err != nil || state != BLOCK_STATE_RECEIVED_COMMITS {
+		return
+	}
+	data, additionalData, err := node.handler.getBlockConsensusData(node.parentHash, NewEquivocationWindow(node.parentHash))
+	if err != nil {
+		return
+	}
+	node.committed = true
+
+	block, ok := s.blocks[node.parentHash]
+	if ok {
+		if !sameBlockConsensus(block.consensusData, data) {
+			s.result.Violations = append(s.result.Violations, fmt.Sprintf("validator %d committed a conflicting block %d at %v: %+v, sealed %+v",
+				index, node.number, s.now, data, block.consensusData))
+		}
+		return
+	}
+
+	block = &SimulationBlock{
+		Number:        node.number,
+		ParentHash:    node.parentHash,
+		Hash:          crypto.Keccak256Hash(node.parentHash.Bytes(), data.PrecommitHash.Bytes()),
+		Round:         data.Round,
+		VoteType:      data.VoteType,
+		Proposer:      -1,
+		CommittedAt:   s.now,
+		consensusData: data,
+	}
+	block.additionalConsensusData = additionalData
+	if data.VoteType == VOTE_TYPE_OK {
+		block.Proposer = s.indexes[data.BlockProposer]
+	}
+	for _, proposer := range data.SlashedBlockProposers {
+		block.SlashedProposers = append(block.SlashedProposers, s.indexes[proposer])
+	}
+	for i := range s.nodes {
+		if s.offline[i] {
+			block.Offline = append(block.Offline, i)
+		}
+	}
+	s.blocks[node.parentHash] = block
+	s.chain = append(s.chain, block)
+	s.chainHead = block.Hash
+	s.result.Blocks = append(s.result.Blocks, block)
+
+	s.updateOfflineProposers()
+	for to := range s.nodes {
+		s.push(&simEvent{at: s.now + s.config.Latency, kind: simEventBlock, to: to, from: index, key: block.Hash, block: block})
+	}
+}
+
+func sameBlockConsensus(a *BlockConsensusData, b *BlockConsensusData) bool {
+	return a.VoteType == b.VoteType && a.Round == b.Round && a.BlockProposer == b.BlockProposer &&
+		a.ProposalHash == b.ProposalHash && a.PrecommitHash == b.PrecommitHash &&
+		reflect.DeepEqual(a.SelectedTransactions, b.SelectedTransactions)
+}
+
+func (s *Simulation) importBlock(index int, block *SimulationBlock) {
+	node := s.nodes[index]
+	if block.Number < node.number {
+		return
+	}
+	node.parentHash = block.Hash
+	node.number = block.Number + 1
+	node.committed = false
+}
+
+func runSimulation(t *testing.T, config SimulationConfig) *SimulationResult {
+	sim, err := NewSimulation(config)
+	if err != nil {
+		t.Fatalf("NewSimulation failed: %v", err)
+	}
+	result := sim.Run()
+	for _, violation := range result.Violations {
+		t.Errorf("seed %d: %s", config.Seed, violation)
+	}
+	if uint64(len(result.Blocks)) < config.Blocks {
+		t.Fatalf("seed %d: committed %d of %d blocks in %v", config.Seed, len(result.Blocks), config.Blocks, result.Elapsed)
+	}
+	return result
+}
+
+func TestSimulation_Basic(t *testing.T) {
+	result := runSimulation(t, SimulationConfig{Validators: 4, Seed: 1, Blocks: 3, Latency: 50 * time.Millisecond})
+	for _, block := range result.Blocks {
+		if block.Round != 1 || block.VoteType != VOTE_TYPE_OK {
+			t.Fatalf("block %d: expected an ok block in round 1, got round %d vote type %d", block.Number, block.Round, block.VoteType)
+		}
+	}
+}
+
+func TestSimulation_OfflineProposer(t *testing.T) {
+	for _, validators := range []int{4, 7} {
+		result := runSimulation(t, SimulationConfig{Validators: validators, Seed: 2, Blocks: 3, Latency: 50 * time.Millisecond,
+			OfflineProposers: map[uint64]byte{2: 1}})
+
+		block := result.Blocks[1]
+		if block.VoteType != VOTE_TYPE_NIL {
+			t.Fatalf("%d validators: expected a nil block while the proposer is offline, got vote type %d", validators, block.VoteType)
+		}
+		if !reflect.DeepEqual(block.SlashedProposers, block.Offline) {
+			t.Fatalf("%d validators: expected the offline proposer %v to be slashed, got %v", validators, block.Offline, block.SlashedProposers)
+		}
+		if result.Blocks[2].VoteType != VOTE_TYPE_OK {
+			t.Fatalf("%d validators: expected an ok block once the proposer is back", validators)
+		}
+	}
+}
+
+func TestSimulation_Partition(t *testing.T) {
+	// Neither half reaches the votes required to decide the first round
+	result := runSimulation(t, SimulationConfig{Validators: 4, Seed: 3, Blocks: 2, Latency: 50 * time.Millisecond,
+		Partitions: []SimulationPartition{{Start: 0, End: 200 * time.Second, Groups: [][]int{{0, 1}, {2, 3}}}}})
+
+	if result.Blocks[0].Round != 2 {
+		t.Fatalf("expected the first block to be decided in round 2, got round %d", result.Blocks[0].Round)
+	}
+}
+
+func TestSimulation_PacketLoss(t *testing.T) {
+	runSimulation(t, SimulationConfig{Validators: 4, Seed: 4, Blocks: 3, Latency: 100 * time.Millisecond, Jitter: 2 * time.Second, PacketLoss: 20})
+}
+
+func TestSimulation_Deterministic(t *testing.T) {
+	config := SimulationConfig{Validators: 4, Seed: 5, Blocks: 3, Latency: 100 * time.Millisecond, Jitter: time.Second, PacketLoss: 10,
+		Partitions: []SimulationPartition{{Start: 0, End: 200 * time.Second, Groups: [][]int{{0, 1}, {2, 3}}}}}
+	for i := 0; i < config.Validators; i++ {
+		key, err := cryptobase.SigAlg.GenerateKey()
+		if err != nil {
+			t.Fatalf("GenerateKey failed: %v", err)
+		}
+		config.Keys = append(config.Keys, key)
+	}
+
+	first, second := runSimulation(t, config), runSimulation(t, config)
+	if first.Elapsed != second.Elapsed || len(first.Blocks) != len(second.Blocks) {
+		t.Fatalf("runs differ: %v and %v elapsed, %d and %d blocks", first.Elapsed, second.Elapsed, len(first.Blocks), len(second.Blocks))
+	}
+	for i := range first.Blocks {
+		a, b := *first.Blocks[i], *second.Blocks[i]
+		a.consensusData, b.consensusData = nil, nil
+		a.additionalConsensusData, b.additionalConsensusData = nil, nil
+		if !reflect.DeepEqual(a, b) {
+			t.Fatalf("block %d differs between runs:\n%+v\n%+v", a.Number, a, b)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package proofofstake
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/ethdb"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/rlp"
+	"sync"
+	"time"
+)
+
+const (
+	// slashingProtectionRetention is how long the signed packets are kept. The rounds of a
+	// parent hash are over long before, the packets of the parent hash a validator signed
+	// last are kept regardless, in case the chain is stalled.
+	slashingProtectionRetention = 7 * 24 * time.Hour
+
+	// slashingProtectionPruneInterval is how often the expired packets are pruned.
+	slashingProtectionPruneInterval = time.Hour
+)
+
+var (
+	SlashingProtectionErr = errors.New("refusing to sign a conflicting consensus packet")
+	NotConsensusPacketErr = errors.New("refusing to sign data that is not a consensus packet")
+)
+
+// slashingProtectionPrefix + validator + parent hash + packet type + round -> hash of the signed data + signing time
+var slashingProtectionPrefix = []byte("sp-")
+
+// SlashingProtectionDB records the consensus packets signed for each validator, so that a
+// signer never signs two different packets of the same type for the same parent hash and
+// round, which would be slashed as an equivocation.
+type SlashingProtectionDB struct {
+	db        ethdb.KeyValueStore
+	clock     Clock
+	lastPrune time.Time
+	lock      sync.Mutex
+}
+
+// NewSlashingProtectionDB creates a slashing protection database on top of the given store.
+// Expired packets are pruned with the first packet signed, and then every hour.
+func NewSlashingProtectionDB(db ethdb.KeyValueStore) *SlashingProtectionDB {
+	return &SlashingProtectionDB{
+		db:    db,
+		clock: systemClock{},
+	}
+}
+
+func slashingProtectionKey(validator common.Address, parentHash common.Hash, packetType ConsensusPacketType, round byte) []byte {
+	key := make([]byte, 0, len(slashingProtectionPrefix)+common.AddressLength+common.HashLength+2)
+	key = append(key, slashingProtectionPrefix...)
+	key = append(key, validator.Bytes()...)
+	key = append(key, parentHash.Bytes()...)
+	return append(key, byte(packetType), round)
+}
+
+// CheckAndRecord checks the data that a consensus packet signs, the parent hash followed
+// by the consensus data, against the packets already signed by the validator, and records
+// it before it is signed. Signing the same data again is allowed, so that packets can be
+// resent. Capability and sync packets, which have the zero parent hash, cannot be slashed
+// and are not recorded. Any other data is refused, so that the validator key cannot be
+// used to sign transactions or other messages.
+func (s *SlashingProtectionDB) CheckAndRecord(validator common.Address, data []byte) error {
+	if len(data) <= common.HashLength {
+		return InvalidPacketErr
+	}
+	if isTransactionPayload(data) {
+		log.Warn("Refusing to sign data that decodes as a transaction", "validator", validator)
+		return NotConsensusPacketErr
+	}
+	parentHash := common.BytesToHash(data[:common.HashLength])
+	consensusData := data[common.HashLength:]
+	if isPeerPacket(consensusData) {
+		if parentHash.IsEqualTo(ZERO_HASH) == false {
+			return NotConsensusPacketErr
+		}
+		return nil
+	}
+	packetType, round, err := decodePacketRound(consensusData)
+	if err != nil || parentHash.IsEqualTo(ZERO_HASH) {
+		log.Warn("Refusing to sign data that is not a consensus packet", "validator", validator, "parentHash", parentHash)
+		return NotConsensusPacketErr
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.clock.Now()
+	if now.Sub(s.lastPrune) >= slashingProtectionPruneInterval {
+		if err := s.prune(now); err != nil {
+			log.Warn("Failed to prune slashing protection database", "err", err)
+		}
+	}
+
+	key := slashingProtectionKey(validator, parentHash, packetType, round)
+	dataHash := crypto.Keccak256(data)
+	signed, err := s.db.Has(key)
+	if err != nil {
+		return err
+	}
+	if signed {
+		record, err := s.db.Get(key)
+		if err != nil {
+			return err
+		}
+		if len(record) >= common.HashLength && bytes.Equal(record[:common.HashLength], dataHash) {
+			return nil
+		}
+		log.Warn("Refusing to sign conflicting consensus packet", "validator", validator, "parentHash", parentHash,
+			"packetType", packetType, "round", round)
+		return SlashingProtectionErr
+	}
+
+	record := make([]byte, common.HashLength+8)
+	copy(record, dataHash)
+	binary.BigEndian.PutUint64(record[common.HashLength:], uint64(now.Unix()))
+	return s.db.Put(key, record)
+}
+
+// isPeerPacket returns whether the consensus data is a well formed capability or sync
+// packet, which the peer handler sends with the zero parent hash.
+func isPeerPacket(consensusData []byte) bool {
+	if len(consensusData) < 2 || consensusData[0] < MinConsensusNetworkProtocolVersion {
+		return false
+	}
+	switch ConsensusPacketType(consensusData[1]) {
+	case CONSENSUS_PACKET_TYPE_CAPABILITY:
+		return rlp.DecodeBytes(consensusData[2:], &CapabilityDetails{}) == nil
+	case CONSENSUS_PACKET_TYPE_SYNC:
+		return rlp.DecodeBytes(consensusData[2:], &RequestConsensusSyncDetails{}) == nil
+	}
+	return false
+}
+
+// isTransactionPayload returns whether the data is also the signing payload of a legacy
+// or typed transaction, a single RLP list optionally preceded by the transaction type.
+func isTransactionPayload(data []byte) bool {
+	if data[0] <= 0x7f {
+		data = data[1:]
+	}
+	kind, _, rest, err := rlp.Split(data)
+	return err == nil && kind == rlp.List && len(rest) == 0
+}
+
+// prune deletes the packets signed before the retention period, except those of the
+// parent hash that each validator signed last.
+func (s *SlashingProtectionDB) prune(now time.Time) error {
+	s.lastPrune = now
+	cutoff := uint64(now.Add(-slashingProtectionRetention).Unix())
+
+	type latestParent struct {
+		parentHash common.Hash
+		signedAt   uint64
+	}
+	latest := make(map[common.Address]latestParent)
+	var expired [][]byte
+
+	it := s.db.NewIterator(slashingProtectionPrefix, nil)
+	for it.Next() {
+		key, record := it.Key(), it.Value()
+		if len(key) != len(slashingProtectionPrefix)+common.AddressLength+common.HashLength+2 || len(record) != common.HashLength+8 {
+			continue
+		}
+		validator := common.BytesToAddress(key[len(slashingProtectionPrefix) : len(slashingProtectionPrefix)+common.AddressLength])
+		parentHash := common.BytesToHash(key[len(slashingProtectionPrefix)+common.AddressLength : len(key)-2])
+		signedAt := binary.BigEndian.Uint64(record[common.HashLength:])
+		if last, ok := latest[validator]; ok == false || signedAt > last.signedAt {
+			latest[validator] = latestParent{parentHash: parentHash, signedAt: signedAt}
+		}
+		if signedAt < cutoff {
+			expired = append(expired, common.CopyBytes(key))
+		}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+
+	batch := s.db.NewBatch()
+	for _, key := range expired {
+		validator := common.BytesToAddress(key[len(slashingProtectionPrefix) : len(slashingProtectionPrefix)+common.AddressLength])
+		parentHash := common.BytesToHash(key[len(slashingProtectionPrefix)+common.AddressLength : len(key)-2])
+		last := latest[validator]
+		if last.parentHash.IsEqualTo(parentHash) {
+			continue
+		}
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// Close closes the underlying store.
+func (s *SlashingProtectionDB) Close() error {
+	return s.db.Close()
+}
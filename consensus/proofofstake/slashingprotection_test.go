@@ -0,0 +1,119 @@
+package proofofstake
+
+import (
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/ethdb/memorydb"
+	"github.com/DogeProtocol/dp/rlp"
+	"testing"
+	"time"
+)
+
+func testPeerPacketData(t *testing.T, packetType ConsensusPacketType) []byte {
+	var data []byte
+	var err error
+	if packetType == CONSENSUS_PACKET_TYPE_CAPABILITY {
+		data, err = rlp.EncodeToBytes(&CapabilityDetails{IsConsensusRelay: true, PeerId: "peer"})
+	} else {
+		data, err = rlp.EncodeToBytes(&RequestConsensusSyncDetails{PeerId: "peer"})
+	}
+	if err != nil {
+		t.Fatalf("failed to encode peer packet: %v", err)
+	}
+	return append([]byte{ConsensusNetworkProtocolVersion, byte(packetType)}, data...)
+}
+
+func TestSlashingProtectionDB(t *testing.T) {
+	db := NewSlashingProtectionDB(memorydb.New())
+	validator := common.BytesToAddress([]byte{1})
+	parentHash := common.BytesToHash([]byte{2})
+	signData := func(data []byte) []byte {
+		return append(parentHash.Bytes(), data...)
+	}
+
+	okAck := signData(testOkAckData(t, 1, common.BytesToHash([]byte{3})))
+	if err := db.CheckAndRecord(validator, okAck); err != nil {
+		t.Fatalf("CheckAndRecord failed: %v", err)
+	}
+	if err := db.CheckAndRecord(validator, okAck); err != nil {
+		t.Fatalf("expected signing the same packet again to be allowed, got %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		validator common.Address
+		data      []byte
+		err       error
+	}{
+		{"conflicting ack", validator, signData(testNilAckData(t, parentHash, 1)), SlashingProtectionErr},
+		{"ack for another proposal", validator, signData(testOkAckData(t, 1, common.BytesToHash([]byte{4}))), SlashingProtectionErr},
+		{"ack of the next round", validator, signData(testOkAckData(t, 2, common.BytesToHash([]byte{4}))), nil},
+		{"ack of another validator", common.BytesToAddress([]byte{5}), signData(testNilAckData(t, parentHash, 1)), nil},
+		{"ack for another parent", validator, append(common.BytesToHash([]byte{6}).Bytes(), testNilAckData(t, parentHash, 1)...), nil},
+		{"capability packet", validator, append(ZERO_HASH.Bytes(), testPeerPacketData(t, CONSENSUS_PACKET_TYPE_CAPABILITY)...), nil},
+		{"sync packet", validator, append(ZERO_HASH.Bytes(), testPeerPacketData(t, CONSENSUS_PACKET_TYPE_SYNC)...), nil},
+		{"sync packet with a parent hash", validator, signData(testPeerPacketData(t, CONSENSUS_PACKET_TYPE_SYNC)), NotConsensusPacketErr},
+		{"malformed sync packet", validator, append(ZERO_HASH.Bytes(), ConsensusNetworkProtocolVersion, byte(CONSENSUS_PACKET_TYPE_SYNC), 0xc0), NotConsensusPacketErr},
+		{"ack with the zero parent hash", validator, append(ZERO_HASH.Bytes(), testOkAckData(t, 1, common.BytesToHash([]byte{3}))...), NotConsensusPacketErr},
+		{"unknown packet type", validator, signData([]byte{MinConsensusNetworkProtocolVersion, 4, 0xc0}), NotConsensusPacketErr},
+		{"arbitrary data", validator, signData([]byte("arbitrary data")), NotConsensusPacketErr},
+		{"data without consensus data", validator, parentHash.Bytes(), InvalidPacketErr},
+	}
+	for _, test := range tests {
+		if err := db.CheckAndRecord(test.validator, test.data); err != test.err {
+			t.Fatalf("%s: expected %v, got %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestSlashingProtectionDBRejectsTransactions(t *testing.T) {
+	db := NewSlashingProtectionDB(memorydb.New())
+	validator := common.BytesToAddress([]byte{1})
+
+	payload, err := rlp.EncodeToBytes([]interface{}{
+		uint64(1), uint64(0), uint64(21000), common.BytesToAddress([]byte{9}), uint64(1), []byte{}, uint64(0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		"legacy transaction": payload,
+		"typed transaction":  append([]byte{2}, payload...),
+	} {
+		if err := db.CheckAndRecord(validator, data); err != NotConsensusPacketErr {
+			t.Errorf("%s: expected %v, got %v", name, NotConsensusPacketErr, err)
+		}
+	}
+}
+
+func TestSlashingProtectionDBPrune(t *testing.T) {
+	clock := &simClock{now: time.Unix(1700000000, 0)}
+	store := memorydb.New()
+	db := NewSlashingProtectionDB(store)
+	db.clock = clock
+	validator := common.BytesToAddress([]byte{1})
+
+	oldParent := common.BytesToHash([]byte{2})
+	lastParent := common.BytesToHash([]byte{3})
+	oldAck := append(oldParent.Bytes(), testNilAckData(t, oldParent, 1)...)
+	lastAck := append(lastParent.Bytes(), testNilAckData(t, lastParent, 1)...)
+	if err := db.CheckAndRecord(validator, oldAck); err != nil {
+		t.Fatalf("CheckAndRecord failed: %v", err)
+	}
+	clock.now = clock.now.Add(time.Minute)
+	if err := db.CheckAndRecord(validator, lastAck); err != nil {
+		t.Fatalf("CheckAndRecord failed: %v", err)
+	}
+
+	// Both packets expire, but those of the last parent hash are kept
+	clock.now = clock.now.Add(slashingProtectionRetention + time.Hour)
+	if err := db.CheckAndRecord(common.BytesToAddress([]byte{4}), lastAck); err != nil {
+		t.Fatalf("CheckAndRecord failed: %v", err)
+	}
+	if has, _ := store.Has(slashingProtectionKey(validator, oldParent, CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL, 1)); has {
+		t.Fatal("expired packet was not pruned")
+	}
+	conflicting := append(lastParent.Bytes(), testOkAckData(t, 1, common.BytesToHash([]byte{5}))...)
+	if err := db.CheckAndRecord(validator, conflicting); err != SlashingProtectionErr {
+		t.Fatalf("expected the packets of the last parent hash to be kept, got %v", err)
+	}
+}
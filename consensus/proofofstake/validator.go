@@ -666,7 +666,7 @@ func (p *ProofOfStake) AddDepositorSlashing(blockHash common.Hash,
 func (p *ProofOfStake) GetStakingContractAbi() (abi.ABI, error) {
 	blockNumber := p.blockchain.CurrentBlock().NumberU64()
 
-	if blockNumber < STAKING_CONTRACT_V2_CUTOFF_BLOCK {
+	if blockNumber < p.schedule.StakingContractV2Block {
 		return staking.GetStakingContract_ABI()
 	} else {
 		return staking.GetStakingContractV2_ABI()
@@ -1043,7 +1043,7 @@ func (p *ProofOfStake) ListValidators(blockHash common.Hash, blockNumber uint64)
 	for _, val := range *out {
 		var validatorDetails *ValidatorDetails
 
-		if blockNumber < STAKING_CONTRACT_V2_CUTOFF_BLOCK {
+		if blockNumber < p.schedule.StakingContractV2Block {
 			validatorDetails, err = p.GetStakingDetailsByValidatorAddress(val, blockHash)
 			if err != nil {
 				return nil, err
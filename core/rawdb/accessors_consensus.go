@@ -0,0 +1,95 @@
+package rawdb
+
+import (
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/ethdb"
+	"github.com/DogeProtocol/dp/log"
+)
+
+// ConsensusVote is a journaled consensus packet signed by the local validator.
+type ConsensusVote struct {
+	Round      byte
+	PacketType byte
+	Data       []byte
+}
+
+// ReadConsensusVote retrieves the journaled consensus packet of the local validator
+// for the given parent hash, round and packet type.
+func ReadConsensusVote(db ethdb.KeyValueReader, parentHash common.Hash, round byte, packetType byte) []byte {
+	data, _ := db.Get(consensusVoteKey(parentHash, round, packetType))
+	return data
+}
+
+// WriteConsensusVote stores a consensus packet of the local validator in the journal.
+func WriteConsensusVote(db ethdb.KeyValueWriter, parentHash common.Hash, round byte, packetType byte, data []byte) {
+	if err := db.Put(consensusVoteKey(parentHash, round, packetType), data); err != nil {
+		log.Crit("Failed to store consensus vote", "err", err)
+	}
+}
+
+// ReadConsensusVotes retrieves all journaled consensus packets of the local validator
+// for the given parent hash, ordered by round and packet type.
+func ReadConsensusVotes(db ethdb.Iteratee, parentHash common.Hash) []*ConsensusVote {
+	prefix := append(append([]byte{}, consensusVotePrefix...), parentHash.Bytes()...)
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	var votes []*ConsensusVote
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+2 {
+			continue
+		}
+		votes = append(votes, &ConsensusVote{
+			Round:      key[len(prefix)],
+			PacketType: key[len(prefix)+1],
+			Data:       common.CopyBytes(it.Value()),
+		})
+	}
+	return votes
+}
+
+// DeleteConsensusVotes removes all journaled consensus packets of the local validator
+// for the given parent hash.
+func DeleteConsensusVotes(db ethdb.KeyValueStore, parentHash common.Hash) {
+	for _, vote := range ReadConsensusVotes(db, parentHash) {
+		if err := db.Delete(consensusVoteKey(parentHash, vote.Round, vote.PacketType)); err != nil {
+			log.Crit("Failed to delete consensus vote", "err", err)
+		}
+	}
+}
+
+// ReadConsensusVoteParents retrieves the parent hashes that the local validator has
+// journaled consensus packets for.
+func ReadConsensusVoteParents(db ethdb.Iteratee) []common.Hash {
+	it := db.NewIterator(consensusVotePrefix, nil)
+	defer it.Release()
+
+	var parents []common.Hash
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(consensusVotePrefix)+common.HashLength+2 {
+			continue
+		}
+		parentHash := common.BytesToHash(key[len(consensusVotePrefix) : len(consensusVotePrefix)+common.HashLength])
+		if len(parents) == 0 || parents[len(parents)-1] != parentHash {
+			parents = append(parents, parentHash)
+		}
+	}
+	return parents
+}
+
+// ReadValidatorPerformance retrieves the encoded validator performance of the given
+// chain index section, identified by the hash of its last block.
+func ReadValidatorPerformance(db ethdb.KeyValueReader, section uint64, head common.Hash) []byte {
+	data, _ := db.Get(validatorPerformanceKey(section, head))
+	return data
+}
+
+// WriteValidatorPerformance stores the encoded validator performance of the given
+// chain index section.
+func WriteValidatorPerformance(db ethdb.KeyValueWriter, section uint64, head common.Hash, data []byte) {
+	if err := db.Put(validatorPerformanceKey(section, head), data); err != nil {
+		log.Crit("Failed to store validator performance", "err", err)
+	}
+}
@@ -93,8 +93,12 @@ var (
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
 
+	consensusVotePrefix        = []byte("pos-vote-") // consensusVotePrefix + parent hash + round + packet type -> own consensus packet
+	validatorPerformancePrefix = []byte("pos-perf-") // validatorPerformancePrefix + section (uint64 big endian) + hash -> validator performance
+
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
-	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	BloomBitsIndexPrefix            = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	ValidatorPerformanceIndexPrefix = []byte("iV") // ValidatorPerformanceIndexPrefix is the data table of the validator performance indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -226,6 +230,19 @@ func IsCodeKey(key []byte) (bool, []byte) {
 	return false, nil
 }
 
+// consensusVoteKey = consensusVotePrefix + parent hash + round + packet type
+func consensusVoteKey(parentHash common.Hash, round byte, packetType byte) []byte {
+	return append(append(consensusVotePrefix, parentHash.Bytes()...), round, packetType)
+}
+
+// validatorPerformanceKey = validatorPerformancePrefix + section (uint64 big endian) + hash
+func validatorPerformanceKey(section uint64, hash common.Hash) []byte {
+	key := append(append(validatorPerformancePrefix, make([]byte, 8)...), hash.Bytes()...)
+
+	binary.BigEndian.PutUint64(key[len(validatorPerformancePrefix):], section)
+	return key
+}
+
 // configKey = configPrefix + hash
 func configKey(hash common.Hash) []byte {
 	return append(configPrefix, hash.Bytes()...)
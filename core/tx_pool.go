@@ -243,6 +243,7 @@ type TxPool struct {
 	currentState  *state.StateDB // Current state in the blockchain head
 	pendingNonces *txNoncer      // Pending state tracking virtual nonces
 	currentMaxGas uint64         // Current gas limit for transaction caps
+	pendingNumber *big.Int       // Number of the next block, the signature schemes are checked at
 
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
@@ -583,6 +584,11 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if err != nil {
 		return ErrInvalidSender
 	}
+	// The signer accepts the schemes valid at any block, the transaction has to be
+	// signed with a scheme valid in the next block
+	if err := types.CheckSignatureScheme(pool.chainconfig, tx, pool.pendingNumber); err != nil {
+		return err
+	}
 	// Drop non-local transactions under our own minimal accepted gas price or tip
 
 	// Ensure the transaction adheres to nonce ordering
@@ -1237,6 +1243,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.currentState = statedb
 	pool.pendingNonces = newTxNoncer(statedb)
 	pool.currentMaxGas = newHead.GasLimit
+	pool.pendingNumber = new(big.Int).Add(newHead.Number, big.NewInt(1))
 
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
@@ -1477,6 +1484,30 @@ func (pool *TxPool) demoteUnexecutables() {
 		}
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
+		// Drop all transactions signed with a scheme retired in the next block, and
+		// queue the ones after them back
+		for _, tx := range list.Flatten() {
+			if types.CheckSignatureScheme(pool.chainconfig, tx, pool.pendingNumber) == nil {
+				continue
+			}
+			removed, demoted := list.Remove(tx)
+			if !removed {
+				continue // Already dropped or queued back with an earlier one
+			}
+			hash := tx.Hash()
+			log.Trace("Removed pending transaction with a retired signature scheme", "hash", hash)
+			pool.all.Remove(hash)
+			drops = append(drops, tx)
+			for _, demotedTx := range demoted {
+				if types.CheckSignatureScheme(pool.chainconfig, demotedTx, pool.pendingNumber) == nil {
+					invalids = append(invalids, demotedTx)
+				} else {
+					pool.all.Remove(demotedTx.Hash())
+					drops = append(drops, demotedTx)
+				}
+			}
+		}
+
 		for _, tx := range invalids {
 			hash := tx.Hash()
 			log.Trace("Demoting pending transaction", "hash", hash)
@@ -19,6 +19,7 @@ package core
 import (
 	"errors"
 	"fmt"
+	"github.com/DogeProtocol/dp/crypto"
 	"github.com/DogeProtocol/dp/crypto/cryptobase"
 	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
 	"io/ioutil"
@@ -259,6 +260,183 @@ func testSetNonce(pool *TxPool, addr common.Address, nonce uint64) {
 	pool.mu.Unlock()
 }
 
+// Tests that transactions are only accepted if their signature scheme is valid in the
+// next block, and dropped once it is retired.
+func TestTransactionSignatureSchemes(t *testing.T) {
+	t.Parallel()
+
+	config := *params.TestChainConfig
+	config.SignatureSchemes = params.SignatureSchemes{
+		{Name: cryptobase.SigAlg.SignatureName(), Block: big.NewInt(0), RetireBlock: big.NewInt(2)},
+		{Name: "Falcon-512", Block: big.NewInt(0)},
+	}
+	pool, key := setupTxPoolWithConfig(&config)
+	defer pool.Stop()
+
+	tx := transaction(0, 100000, key)
+	from, _ := deriveSender(tx)
+	testAddBalance(pool, from, new(big.Int).Mul(tx.Cost(), big.NewInt(2)))
+	if err := pool.addRemoteSync(tx); err != nil {
+		t.Fatalf("failed to add a transaction signed with a valid scheme: %v", err)
+	}
+	if err := pool.addRemoteSync(transaction(1, 100000, key)); err != nil {
+		t.Fatalf("failed to add a transaction signed with a valid scheme: %v", err)
+	}
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	}
+
+	// Once the next block retires the scheme, the pending transactions are dropped and
+	// no new ones are accepted
+	pool.mu.Lock()
+	pool.pendingNumber = big.NewInt(2)
+	pool.demoteUnexecutables()
+	pool.mu.Unlock()
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("expected the transactions to be dropped, have %d pending and %d queued", pending, queued)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	if err := pool.AddRemote(tx); err != types.ErrInvalidSignatureScheme {
+		t.Fatalf("expected %v, got %v", types.ErrInvalidSignatureScheme, err)
+	}
+}
+
+// retiringSigAlg is the default signature algorithm under another name and first byte,
+// so that the transactions of an account can be signed with two schemes.
+type retiringSigAlg struct {
+	signaturealgorithm.SignatureAlgorithm
+}
+
+var testRetiringSigAlg = retiringSigAlg{cryptobase.SigAlg}
+
+func init() {
+	cryptobase.RegisterSigAlg(testRetiringSigAlg)
+}
+
+func (s retiringSigAlg) SignatureName() string {
+	return "test-retiring"
+}
+
+func (s retiringSigAlg) SignatureStartValue() byte {
+	return 0x72
+}
+
+// defaultSignature returns a copy of sig that starts with the first byte of the
+// default signature algorithm.
+func (s retiringSigAlg) defaultSignature(sig []byte) []byte {
+	sig = common.CopyBytes(sig)
+	sig[0] = crypto.DILITHIUM_ED25519_SPHINCS_COMPACT_ID
+	return sig
+}
+
+func (s retiringSigAlg) Sign(digestHash []byte, prv *signaturealgorithm.PrivateKey) ([]byte, error) {
+	combined, err := s.SignatureAlgorithm.Sign(digestHash, prv)
+	if err != nil {
+		return nil, err
+	}
+	sig, pubKey, err := common.ExtractTwoParts(combined)
+	if err != nil {
+		return nil, err
+	}
+	sig[0] = s.SignatureStartValue()
+	return common.CombineTwoParts(sig, pubKey), nil
+}
+
+func (s retiringSigAlg) PublicKeyAndSignatureFromCombinedSignature(digestHash []byte, combined []byte) ([]byte, []byte, error) {
+	sig, pubKey, err := common.ExtractTwoParts(combined)
+	if err != nil {
+		return nil, nil, err
+	}
+	sig, pubKey, err = s.SignatureAlgorithm.PublicKeyAndSignatureFromCombinedSignature(digestHash, common.CombineTwoParts(s.defaultSignature(sig), pubKey))
+	if err != nil {
+		return nil, nil, err
+	}
+	sig[0] = s.SignatureStartValue()
+	return sig, pubKey, nil
+}
+
+func (s retiringSigAlg) ValidateSignatureValues(digestHash []byte, v byte, r, sig *big.Int) bool {
+	return s.SignatureAlgorithm.ValidateSignatureValues(digestHash, v, r, new(big.Int).SetBytes(s.defaultSignature(sig.Bytes())))
+}
+
+func (s retiringSigAlg) CombinePublicKeySignature(sig []byte, pubKey []byte) ([]byte, error) {
+	return s.SignatureAlgorithm.CombinePublicKeySignature(s.defaultSignature(sig), pubKey)
+}
+
+// Tests that the pending transactions signed with a scheme retired in the next block
+// are dropped, and that the later transactions of the account that are signed with a
+// valid scheme are queued back.
+func TestTransactionSignatureSchemeRetired(t *testing.T) {
+	t.Parallel()
+
+	config := *params.TestChainConfig
+	config.SignatureSchemes = params.SignatureSchemes{
+		{Name: cryptobase.SigAlg.SignatureName(), Block: big.NewInt(0)},
+		{Name: testRetiringSigAlg.SignatureName(), Block: big.NewInt(0), RetireBlock: big.NewInt(2)},
+	}
+	pool, key := setupTxPoolWithConfig(&config)
+	defer pool.Stop()
+
+	txs := make([]*types.Transaction, 5)
+	for nonce := range txs {
+		sigAlg := signaturealgorithm.SignatureAlgorithm(cryptobase.SigAlg)
+		if nonce%2 == 1 {
+			sigAlg = testRetiringSigAlg
+		}
+		tx, err := types.SignTxWithSigAlg(types.NewTransaction(uint64(nonce), common.Address{}, big.NewInt(100), 100000, big.NewInt(1), nil),
+			types.LatestSigner(&config), sigAlg, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction %d: %v", nonce, err)
+		}
+		txs[nonce] = tx
+	}
+	from, _ := deriveSender(txs[0])
+	if sender, err := types.Sender(types.LatestSigner(&config), txs[1]); err != nil || sender != from {
+		t.Fatalf("expected the schemes to recover the same sender, have %x and %x: %v", sender, from, err)
+	}
+	testAddBalance(pool, from, new(big.Int).Mul(txs[0].Cost(), big.NewInt(10)))
+	for i, err := range pool.AddRemotesSync(txs) {
+		if err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 5 || queued != 0 {
+		t.Fatalf("expected all transactions to be pending, have %d pending and %d queued", pending, queued)
+	}
+
+	// Demote as a reorg to the block before the retirement does, which then moves the
+	// pending nonce to the last pending transaction
+	pool.mu.Lock()
+	pool.pendingNumber = big.NewInt(2)
+	pool.demoteUnexecutables()
+	pool.pendingNonces.set(from, pool.pending[from].LastElement().Nonce()+1)
+	pool.mu.Unlock()
+
+	// The first transaction is executable, the ones of the retired scheme are dropped and
+	// the ones after them are queued back
+	if pending, queued := pool.Stats(); pending != 1 || queued != 2 {
+		t.Fatalf("pending or queued transactions mismatched: have %d pending and %d queued, want 1 and 2", pending, queued)
+	}
+	if pool.pending[from].txs.Get(0) == nil {
+		t.Errorf("expected the first transaction to stay pending")
+	}
+	for _, nonce := range []uint64{2, 4} {
+		if pool.queue[from].txs.Get(nonce) == nil {
+			t.Errorf("expected transaction %d to be queued back", nonce)
+		}
+	}
+	for _, nonce := range []uint64{1, 3} {
+		if pool.all.Get(txs[nonce].Hash()) != nil {
+			t.Errorf("expected transaction %d of the retired scheme to be dropped", nonce)
+		}
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 func TestInvalidTransactions(t *testing.T) {
 	t.Parallel()
 
@@ -199,7 +199,11 @@ func sanityCheckSignature(digestHash []byte, v *big.Int, r *big.Int, s *big.Int,
 		// must already be equal to the recovery id.
 		plainV = byte(v.Uint64())
 	}
-	if !cryptobase.SigAlg.ValidateSignatureValues(digestHash, plainV, r, s) {
+	sigAlg, err := cryptobase.SigAlgFromSignature(s.Bytes())
+	if err != nil {
+		return ErrInvalidSig
+	}
+	if !sigAlg.ValidateSignatureValues(digestHash, plainV, r, s) {
 		return ErrInvalidSig
 	}
 
@@ -327,7 +331,11 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 
 func (tx *Transaction) Verify(digestHash []byte) bool {
 	_, r, s := tx.RawSignatureValues()
-	return cryptobase.SigAlg.ValidateSignatureValues(digestHash, 1, r, s)
+	sigAlg, err := cryptobase.SigAlgFromSignature(s.Bytes())
+	if err != nil {
+		return false
+	}
+	return sigAlg.ValidateSignatureValues(digestHash, 1, r, s)
 }
 
 // Transactions implements DerivableList for transactions.
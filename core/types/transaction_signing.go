@@ -26,7 +26,10 @@ import (
 	"math/big"
 )
 
-var ErrInvalidChainId = errors.New("invalid chain id for signer")
+var (
+	ErrInvalidChainId         = errors.New("invalid chain id for signer")
+	ErrInvalidSignatureScheme = errors.New("signature scheme not valid at the block")
+)
 
 // sigCache is used to cache the derived sender and contains
 // the signer used to derive it.
@@ -36,8 +39,13 @@ type sigCache struct {
 }
 
 // MakeSigner returns a Signer based on the given chain config and block number.
+// It only accepts signatures of the schemes valid at the block.
 func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
-	return NewLondonSigner(config.ChainID)
+	return &londonSigner{
+		chainId:          config.ChainID,
+		signatureSchemes: config.SignatureSchemes,
+		blockNumber:      blockNumber,
+	}
 }
 
 // LatestSigner returns the 'most permissive' Signer available for the given chain
@@ -46,9 +54,26 @@ func MakeSigner(config *params.ChainConfig, blockNumber *big.Int) Signer {
 // any block number in the chain config.
 //
 // Use this in transaction-handling code where the current block number is unknown. If you
-// have the current block number available, use MakeSigner instead.
+// have the current block number available, use MakeSigner instead. It accepts the
+// signatures of the schemes valid at any block.
 func LatestSigner(config *params.ChainConfig) Signer {
-	return NewLondonSigner(config.ChainID)
+	return &londonSigner{
+		chainId:          config.ChainID,
+		signatureSchemes: config.SignatureSchemes,
+	}
+}
+
+// CheckSignatureScheme returns ErrInvalidSignatureScheme if the signature of tx is
+// not of a scheme valid at blockNumber. It does not verify the signature.
+func CheckSignatureScheme(config *params.ChainConfig, tx *Transaction, blockNumber *big.Int) error {
+	_, _, S := tx.RawSignatureValues()
+	signer := londonSigner{
+		chainId:          config.ChainID,
+		signatureSchemes: config.SignatureSchemes,
+		blockNumber:      blockNumber,
+	}
+	_, err := signer.sigAlg(S.Bytes())
+	return err
 }
 
 // LatestSignerForChainID returns the 'most permissive' Signer available. Specifically,
@@ -64,11 +89,17 @@ func LatestSignerForChainID(chainID *big.Int) Signer {
 
 // SignTx signs the transaction using the given signer and private key.
 func SignTx(tx *Transaction, s Signer, prv *signaturealgorithm.PrivateKey) (*Transaction, error) {
+	return SignTxWithSigAlg(tx, s, cryptobase.SigAlg, prv)
+}
+
+// SignTxWithSigAlg signs the transaction using the given signer and private key of
+// the signature algorithm sigAlg.
+func SignTxWithSigAlg(tx *Transaction, s Signer, sigAlg signaturealgorithm.SignatureAlgorithm, prv *signaturealgorithm.PrivateKey) (*Transaction, error) {
 	h, err := s.Hash(tx)
 	if err != nil {
 		return nil, err
 	}
-	sig, err := cryptobase.SigAlg.Sign(h[:], prv)
+	sig, err := sigAlg.Sign(h[:], prv)
 	if err != nil {
 		return nil, err
 	}
@@ -77,16 +108,7 @@ func SignTx(tx *Transaction, s Signer, prv *signaturealgorithm.PrivateKey) (*Tra
 
 // SignNewTx creates a transaction and signs it.
 func SignNewTx(prv *signaturealgorithm.PrivateKey, s Signer, txdata TxData) (*Transaction, error) {
-	tx := NewTx(txdata)
-	h, err := s.Hash(tx)
-	if err != nil {
-		return nil, err
-	}
-	sig, err := cryptobase.SigAlg.Sign(h[:], prv)
-	if err != nil {
-		return nil, err
-	}
-	return tx.WithSignature(s, sig)
+	return SignTx(NewTx(txdata), s, prv)
 }
 
 // MustSignNewTx creates a transaction and signs it.
@@ -147,7 +169,11 @@ type Signer interface {
 	Equal(Signer) bool
 }
 
-type londonSigner struct{ chainId *big.Int }
+type londonSigner struct {
+	chainId          *big.Int
+	signatureSchemes params.SignatureSchemes
+	blockNumber      *big.Int // Block the signature schemes are checked at, nil for any block
+}
 
 // NewLondonSigner returns a signer that accepts
 // - EIP-1559 dynamic fee transactions
@@ -182,7 +208,24 @@ func (s londonSigner) Sender(tx *Transaction) (common.Address, error) {
 	if err != nil {
 		return common.ZERO_ADDRESS, err
 	}
-	return recoverPlain(hash, R, S, V)
+	sigAlg, err := s.sigAlg(S.Bytes())
+	if err != nil {
+		return common.ZERO_ADDRESS, err
+	}
+	return recoverPlain(sigAlg, hash, R, S, V)
+}
+
+// sigAlg returns the signature algorithm of signature, if its scheme is valid for
+// the signer.
+func (s londonSigner) sigAlg(signature []byte) (signaturealgorithm.SignatureAlgorithm, error) {
+	sigAlg, err := cryptobase.SigAlgFromSignature(signature)
+	if err != nil {
+		return nil, ErrInvalidSig
+	}
+	if s.signatureSchemes.IsValid(sigAlg.SignatureName(), s.blockNumber) == false {
+		return nil, ErrInvalidSignatureScheme
+	}
+	return sigAlg, nil
 }
 
 func (s londonSigner) Equal(s2 Signer) bool {
@@ -202,7 +245,11 @@ func (s londonSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big
 		if err != nil {
 			return nil, nil, nil, err
 		}
-		R, S, _, err = decodeSignature(sigHash.Bytes(), sig)
+		sigAlg, err := cryptobase.SigAlgFromCombinedSignature(sig)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		R, S, _, err = decodeSignature(sigAlg, sigHash.Bytes(), sig)
 		if err != nil {
 			return nil, nil, nil, err
 		}
@@ -241,9 +288,9 @@ func (s londonSigner) Hash(tx *Transaction) (common.Hash, error) {
 		}), nil
 }
 
-func decodeSignature(digestHash []byte, sig []byte) (r, s, v *big.Int, err error) {
+func decodeSignature(sigAlg signaturealgorithm.SignatureAlgorithm, digestHash []byte, sig []byte) (r, s, v *big.Int, err error) {
 
-	signature, publicKey, err := cryptobase.SigAlg.PublicKeyAndSignatureFromCombinedSignature(digestHash, sig)
+	signature, publicKey, err := sigAlg.PublicKeyAndSignatureFromCombinedSignature(digestHash, sig)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -255,28 +302,28 @@ func decodeSignature(digestHash []byte, sig []byte) (r, s, v *big.Int, err error
 	return r, s, v, nil
 }
 
-func recoverPlain(sighash common.Hash, R, S, Vb *big.Int) (common.Address, error) {
+func recoverPlain(sigAlg signaturealgorithm.SignatureAlgorithm, sighash common.Hash, R, S, Vb *big.Int) (common.Address, error) {
 	if Vb.BitLen() > 8 {
 		return common.Address{}, ErrInvalidSig
 	}
 	V := byte(Vb.Uint64() - 27)
-	if !cryptobase.SigAlg.ValidateSignatureValues(sighash[:], V, R, S) {
+	if !sigAlg.ValidateSignatureValues(sighash[:], V, R, S) {
 		return common.Address{}, ErrInvalidSig
 	}
 	// encode the signature in uncompressed format
 	r, s := R.Bytes(), S.Bytes()
 
-	combinedSignature, err := cryptobase.SigAlg.CombinePublicKeySignature(s, r)
+	combinedSignature, err := sigAlg.CombinePublicKeySignature(s, r)
 	if err != nil {
 		return common.Address{}, err
 	}
 
 	// recover the public key from the signature
-	pub, err := cryptobase.SigAlg.PublicKeyBytesFromSignature(sighash[:], combinedSignature)
+	pub, err := sigAlg.PublicKeyBytesFromSignature(sighash[:], combinedSignature)
 	if err != nil {
 		return common.Address{}, err
 	}
-	if len(pub) != 0 && len(pub) != cryptobase.SigAlg.PublicKeyLength() {
+	if len(pub) != 0 && len(pub) != sigAlg.PublicKeyLength() {
 		return common.Address{}, errors.New("invalid public key")
 	}
 	var addr common.Address
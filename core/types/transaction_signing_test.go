@@ -18,7 +18,9 @@ package types
 
 import (
 	"fmt"
+	"github.com/DogeProtocol/dp/crypto"
 	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/params"
 	"math/big"
 	"testing"
 
@@ -405,3 +407,99 @@ func TestHash(t *testing.T) {
 		t.Fatalf("failed")
 	}
 }
+
+func TestSignerSignatureSchemes(t *testing.T) {
+	key, addr := defaultTestKey()
+	chainId := big.NewInt(DEFAULT_CHAIN_ID)
+	config := &params.ChainConfig{
+		ChainID: chainId,
+		SignatureSchemes: params.SignatureSchemes{
+			{Name: cryptobase.SigAlg.SignatureName(), Block: big.NewInt(10), RetireBlock: big.NewInt(100)},
+			{Name: "Falcon-512", Block: big.NewInt(50)},
+		},
+	}
+	tx, err := SignTx(NewTransaction(0, common.Address{}, new(big.Int), 0, new(big.Int), nil), NewLondonSigner(chainId), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		signer Signer
+		number *big.Int
+		err    error
+	}{
+		{MakeSigner(config, big.NewInt(9)), big.NewInt(9), ErrInvalidSignatureScheme},
+		{MakeSigner(config, big.NewInt(10)), big.NewInt(10), nil},
+		{MakeSigner(config, big.NewInt(99)), big.NewInt(99), nil},
+		{MakeSigner(config, big.NewInt(100)), big.NewInt(100), ErrInvalidSignatureScheme},
+		{LatestSigner(config), nil, nil},
+	}
+	for i, test := range tests {
+		from, err := test.signer.Sender(tx)
+		if err != test.err {
+			t.Errorf("test %d: Sender error %v, want %v", i, err, test.err)
+		}
+		if err == nil && from != addr {
+			t.Errorf("test %d: Sender returned %x, want %x", i, from, addr)
+		}
+		if err := CheckSignatureScheme(config, tx, test.number); err != test.err {
+			t.Errorf("test %d: CheckSignatureScheme error %v, want %v", i, err, test.err)
+		}
+	}
+
+	// A chain that does not declare its schemes only accepts the default scheme
+	if err := CheckSignatureScheme(&params.ChainConfig{ChainID: chainId}, tx, big.NewInt(1000)); err != nil {
+		t.Errorf("default schemes: unexpected error %v", err)
+	}
+
+	// Signatures of an unknown algorithm are invalid
+	V, R, S := tx.RawSignatureValues()
+	sig := S.Bytes()
+	sig[0] = 0xff
+	unknown := NewTx(&DefaultFeeTx{ChainID: chainId, V: V, R: R, S: new(big.Int).SetBytes(sig)})
+	if _, err := LatestSigner(config).Sender(unknown); err != ErrInvalidSig {
+		t.Errorf("unknown algorithm: Sender error %v, want %v", err, ErrInvalidSig)
+	}
+	if err := CheckSignatureScheme(config, unknown, nil); err != ErrInvalidSig {
+		t.Errorf("unknown algorithm: CheckSignatureScheme error %v, want %v", err, ErrInvalidSig)
+	}
+}
+
+// Full signatures of the hybrid scheme start with the full id rather than with the
+// SignatureStartValue of the scheme, they must still be recovered as signatures of the
+// hybrid scheme.
+func TestSignerFullSignatureID(t *testing.T) {
+	key, _ := defaultTestKey()
+	chainId := big.NewInt(DEFAULT_CHAIN_ID)
+	config := &params.ChainConfig{
+		ChainID: chainId,
+		SignatureSchemes: params.SignatureSchemes{
+			{Name: cryptobase.SigAlg.SignatureName(), Block: big.NewInt(0), RetireBlock: big.NewInt(100)},
+			{Name: "Falcon-512", Block: big.NewInt(0)},
+		},
+	}
+	tx, err := SignTx(NewTransaction(0, common.Address{}, new(big.Int), 0, new(big.Int), nil), NewLondonSigner(chainId), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	V, R, S := tx.RawSignatureValues()
+	sig := S.Bytes()
+	sig[0] = crypto.DILITHIUM_ED25519_SPHINCS_FULL_ID
+	full := NewTx(&DefaultFeeTx{ChainID: chainId, V: V, R: R, S: new(big.Int).SetBytes(sig)})
+
+	sigAlg, err := cryptobase.SigAlgFromSignature(sig)
+	if err != nil || sigAlg.SignatureName() != cryptobase.SigAlg.SignatureName() {
+		t.Fatalf("full signature resolved to %v, %v", sigAlg, err)
+	}
+	if err := CheckSignatureScheme(config, full, big.NewInt(99)); err != nil {
+		t.Errorf("full signature: CheckSignatureScheme error %v", err)
+	}
+	// The scheme of the signature is checked, the signature is not taken for one of
+	// an unknown algorithm or of the algorithm of the SignatureStartValue
+	if _, err := MakeSigner(config, big.NewInt(100)).Sender(full); err != ErrInvalidSignatureScheme {
+		t.Errorf("full signature: Sender error %v, want %v", err, ErrInvalidSignatureScheme)
+	}
+	if err := CheckSignatureScheme(config, full, big.NewInt(100)); err != ErrInvalidSignatureScheme {
+		t.Errorf("full signature: CheckSignatureScheme error %v, want %v", err, ErrInvalidSignatureScheme)
+	}
+}
@@ -0,0 +1,104 @@
+package cryptobase
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+)
+
+var ErrUnknownSignatureAlgorithm = errors.New("unknown signature algorithm")
+
+// The registry of the signature algorithms, by the first byte of their signatures
+// and by name. Which of them are valid at a block is declared by the chain config.
+var (
+	sigAlgsLock   sync.RWMutex
+	sigAlgsByID   = make(map[byte]signaturealgorithm.SignatureAlgorithm)
+	sigAlgsByName = make(map[string]signaturealgorithm.SignatureAlgorithm)
+)
+
+func init() {
+	// The signatures of the hybrid scheme start with the compact or the full id, not
+	// with its SignatureStartValue, which is kept as it is for the callers that already
+	// use it. Full signatures are verified by the compact scheme with a signing context,
+	// they are part of the same scheme.
+	registerSigAlg(crypto.DILITHIUM_ED25519_SPHINCS_COMPACT_ID, SigAlg)
+	registerSigAlg(crypto.DILITHIUM_ED25519_SPHINCS_FULL_ID, SigAlg)
+}
+
+// RegisterSigAlg registers a signature algorithm whose signatures start with its
+// SignatureStartValue. It panics if another algorithm is registered with the same
+// first byte or name.
+func RegisterSigAlg(sigAlg signaturealgorithm.SignatureAlgorithm) {
+	registerSigAlg(sigAlg.SignatureStartValue(), sigAlg)
+}
+
+func registerSigAlg(id byte, sigAlg signaturealgorithm.SignatureAlgorithm) {
+	sigAlgsLock.Lock()
+	defer sigAlgsLock.Unlock()
+
+	if _, ok := sigAlgsByID[id]; ok {
+		panic(fmt.Sprintf("signature algorithm %d already registered", id))
+	}
+	if registered, ok := sigAlgsByName[sigAlg.SignatureName()]; ok && registered != sigAlg {
+		panic(fmt.Sprintf("signature algorithm %s already registered", sigAlg.SignatureName()))
+	}
+	sigAlgsByID[id] = sigAlg
+	sigAlgsByName[sigAlg.SignatureName()] = sigAlg
+}
+
+// SigAlgByName returns the signature algorithm registered with name.
+func SigAlgByName(name string) (signaturealgorithm.SignatureAlgorithm, error) {
+	sigAlgsLock.RLock()
+	defer sigAlgsLock.RUnlock()
+
+	sigAlg, ok := sigAlgsByName[name]
+	if ok == false {
+		return nil, ErrUnknownSignatureAlgorithm
+	}
+	return sigAlg, nil
+}
+
+// SigAlgFromSignature returns the signature algorithm of a signature, without its
+// public key.
+func SigAlgFromSignature(signature []byte) (signaturealgorithm.SignatureAlgorithm, error) {
+	if len(signature) == 0 {
+		return nil, ErrUnknownSignatureAlgorithm
+	}
+
+	sigAlgsLock.RLock()
+	defer sigAlgsLock.RUnlock()
+
+	sigAlg, ok := sigAlgsByID[signature[0]]
+	if ok == false {
+		return nil, ErrUnknownSignatureAlgorithm
+	}
+	return sigAlg, nil
+}
+
+// SigAlgFromCombinedSignature returns the signature algorithm of a signature combined
+// with its public key, as made by the Sign method of the algorithms.
+func SigAlgFromCombinedSignature(combinedSignature []byte) (signaturealgorithm.SignatureAlgorithm, error) {
+	signature, _, err := common.ExtractTwoParts(combinedSignature)
+	if err != nil {
+		return nil, err
+	}
+	return SigAlgFromSignature(signature)
+}
+
+// SignatureID returns the first byte of a signature combined with its public key,
+// which identifies the algorithm and, for the hybrid scheme, whether it is a compact
+// or a full signature.
+func SignatureID(combinedSignature []byte) (byte, error) {
+	signature, _, err := common.ExtractTwoParts(combinedSignature)
+	if err != nil {
+		return 0, err
+	}
+	if len(signature) == 0 {
+		return 0, ErrUnknownSignatureAlgorithm
+	}
+	return signature[0], nil
+}
@@ -0,0 +1,12 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package cryptobase
+
+import (
+	"github.com/DogeProtocol/dp/crypto/oqs"
+)
+
+func init() {
+	RegisterSigAlg(oqs.InitFalcon())
+}
@@ -0,0 +1,22 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package cryptobase
+
+import (
+	"testing"
+
+	"github.com/DogeProtocol/dp/crypto/oqs"
+)
+
+func TestFalconSigAlg(t *testing.T) {
+	falcon := oqs.InitFalcon()
+	sigAlg, err := SigAlgByName("Falcon-512")
+	if err != nil || sigAlg.SignatureName() != falcon.SignatureName() {
+		t.Fatalf("Falcon-512 not registered by name: %v", err)
+	}
+	sigAlg, err = SigAlgFromSignature([]byte{falcon.SignatureStartValue()})
+	if err != nil || sigAlg.SignatureName() != "Falcon-512" {
+		t.Fatalf("Falcon-512 not registered by signature id: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package cryptobase
+
+import (
+	"testing"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/hybrideds"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+)
+
+// testSigAlg is the default signature algorithm under another name and first byte.
+type testSigAlg struct {
+	signaturealgorithm.SignatureAlgorithm
+	name string
+	id   byte
+}
+
+func (s testSigAlg) SignatureName() string {
+	return s.name
+}
+
+func (s testSigAlg) SignatureStartValue() byte {
+	return s.id
+}
+
+func expectPanic(t *testing.T, name string, fn func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s: expected a panic", name)
+		}
+	}()
+	fn()
+}
+
+func TestDefaultSigAlgs(t *testing.T) {
+	sigAlg, err := SigAlgByName(hybrideds.SIG_NAME)
+	if err != nil || sigAlg != SigAlg {
+		t.Fatalf("default signature algorithm not registered by name: %v", err)
+	}
+	for _, id := range []byte{hybrideds.SIGNATURE_ID, crypto.DILITHIUM_ED25519_SPHINCS_FULL_ID} {
+		sigAlg, err := SigAlgFromSignature([]byte{id, 0})
+		if err != nil || sigAlg != SigAlg {
+			t.Errorf("signature id %d: expected the default signature algorithm, got %v", id, err)
+		}
+	}
+}
+
+func TestRegisterSigAlg(t *testing.T) {
+	sigAlg := testSigAlg{SignatureAlgorithm: SigAlg, name: "test-registry", id: 0x70}
+	RegisterSigAlg(sigAlg)
+
+	byName, err := SigAlgByName("test-registry")
+	if err != nil || byName != sigAlg {
+		t.Fatalf("SigAlgByName returned %v, %v", byName, err)
+	}
+	bySig, err := SigAlgFromSignature([]byte{0x70, 1, 2})
+	if err != nil || bySig != sigAlg {
+		t.Fatalf("SigAlgFromSignature returned %v, %v", bySig, err)
+	}
+	combined := common.CombineTwoParts([]byte{0x70, 1, 2}, []byte{3, 4})
+	byCombined, err := SigAlgFromCombinedSignature(combined)
+	if err != nil || byCombined != sigAlg {
+		t.Fatalf("SigAlgFromCombinedSignature returned %v, %v", byCombined, err)
+	}
+	if id, err := SignatureID(combined); err != nil || id != 0x70 {
+		t.Fatalf("SignatureID returned %d, %v", id, err)
+	}
+
+	// Neither the first byte nor the name can be registered twice
+	expectPanic(t, "same id", func() {
+		RegisterSigAlg(testSigAlg{SignatureAlgorithm: SigAlg, name: "test-registry-2", id: 0x70})
+	})
+	expectPanic(t, "same name", func() {
+		RegisterSigAlg(testSigAlg{SignatureAlgorithm: SigAlg, name: "test-registry", id: 0x71})
+	})
+	if _, err := SigAlgFromSignature([]byte{0x71}); err != ErrUnknownSignatureAlgorithm {
+		t.Fatalf("expected a rejected registration to be undone, got %v", err)
+	}
+}
+
+func TestUnknownSigAlg(t *testing.T) {
+	if _, err := SigAlgByName("unknown"); err != ErrUnknownSignatureAlgorithm {
+		t.Errorf("unknown name: got %v", err)
+	}
+	if _, err := SigAlgFromSignature(nil); err != ErrUnknownSignatureAlgorithm {
+		t.Errorf("empty signature: got %v", err)
+	}
+	if _, err := SigAlgFromSignature([]byte{0xff}); err != ErrUnknownSignatureAlgorithm {
+		t.Errorf("unknown signature id: got %v", err)
+	}
+	if _, err := SigAlgFromCombinedSignature([]byte{1, 2, 3}); err == nil {
+		t.Errorf("malformed combined signature: expected an error")
+	}
+}
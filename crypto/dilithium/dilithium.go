@@ -0,0 +1,165 @@
+// Package dilithium implements the verification of Dilithium2 (ML-DSA-44)
+// signatures in pure Go, compatible with the Dilithium2 signatures made by
+// libhybridpqc, so that signatures can be verified without cgo.
+package dilithium
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	ErrInvalidPublicKeyLen = errors.New("invalid public key length")
+	ErrInvalidSignatureLen = errors.New("invalid signature length")
+	ErrInvalidSignature    = errors.New("malformed signature")
+	ErrVerifyFailed        = errors.New("verify failed")
+)
+
+// Verify verifies the signature of message with publicKey, returning nil if the
+// signature is valid.
+func Verify(message []byte, signature []byte, publicKey []byte) error {
+	if len(publicKey) != CRYPTO_PUBLICKEY_BYTES {
+		return ErrInvalidPublicKeyLen
+	}
+	if len(signature) != CRYPTO_SIGNATURE_BYTES {
+		return ErrInvalidSignatureLen
+	}
+
+	rho := publicKey[:SEEDBYTES]
+	var t1 [K]poly
+	for i := range t1 {
+		t1[i].unpackT1(publicKey[SEEDBYTES+i*POLYT1_PACKEDBYTES:])
+	}
+
+	ctilde := signature[:CTILDEBYTES]
+	var z [L]poly
+	for i := range z {
+		z[i].unpackZ(signature[CTILDEBYTES+i*POLYZ_PACKEDBYTES:])
+		if z[i].exceedsNorm(GAMMA1 - BETA) {
+			return ErrVerifyFailed
+		}
+	}
+	var h [K][N]bool
+	if unpackHint(&h, signature[CTILDEBYTES+L*POLYZ_PACKEDBYTES:]) == false {
+		return ErrInvalidSignature
+	}
+
+	// mu = CRH(CRH(pk) || message)
+	tr := make([]byte, TRBYTES)
+	hasher := sha3.NewShake256()
+	hasher.Write(publicKey)
+	hasher.Read(tr)
+
+	mu := make([]byte, CRHBYTES)
+	hasher = sha3.NewShake256()
+	hasher.Write(tr)
+	hasher.Write(message)
+	hasher.Read(mu)
+
+	// w1 = UseHint(h, A*z - c*t1*2^d)
+	var c poly
+	c.challenge(ctilde)
+	c.ntt()
+
+	for i := range z {
+		z[i].ntt()
+	}
+
+	hasher = sha3.NewShake256()
+	hasher.Write(mu)
+	var a, w, ct1 poly
+	var w1 [POLYW1_PACKEDBYTES]byte
+	for i := 0; i < K; i++ {
+		for j := 0; j < L; j++ {
+			a.uniform(rho, uint8(i), uint8(j))
+			if j == 0 {
+				w.pointwiseMul(&a, &z[j])
+			} else {
+				w.pointwiseMulAdd(&a, &z[j])
+			}
+		}
+
+		for n := range t1[i] {
+			ct1[n] = t1[i][n] << D
+		}
+		ct1.ntt()
+		ct1.pointwiseMul(&ct1, &c)
+
+		w.sub(&w, &ct1)
+		w.invNTT()
+
+		for n := range w {
+			w[n] = useHint(w[n], h[i][n])
+		}
+		packW1(w1[:], &w)
+		hasher.Write(w1[:])
+	}
+
+	ctilde2 := make([]byte, CTILDEBYTES)
+	hasher.Read(ctilde2)
+	if bytes.Equal(ctilde, ctilde2) == false {
+		return ErrVerifyFailed
+	}
+
+	return nil
+}
+
+// unpackT1 unpacks the 10 bit coefficients of t1.
+func (p *poly) unpackT1(b []byte) {
+	for i := 0; i < N/4; i++ {
+		p[4*i+0] = (uint32(b[5*i+0]) | uint32(b[5*i+1])<<8) & 0x3FF
+		p[4*i+1] = (uint32(b[5*i+1])>>2 | uint32(b[5*i+2])<<6) & 0x3FF
+		p[4*i+2] = (uint32(b[5*i+2])>>4 | uint32(b[5*i+3])<<4) & 0x3FF
+		p[4*i+3] = (uint32(b[5*i+3])>>6 | uint32(b[5*i+4])<<2) & 0x3FF
+	}
+}
+
+// unpackZ unpacks the 18 bit coefficients of z, stored as GAMMA1 - z.
+func (p *poly) unpackZ(b []byte) {
+	for i := 0; i < N/4; i++ {
+		var c [4]uint32
+		c[0] = (uint32(b[9*i+0]) | uint32(b[9*i+1])<<8 | uint32(b[9*i+2])<<16) & 0x3FFFF
+		c[1] = (uint32(b[9*i+2])>>2 | uint32(b[9*i+3])<<6 | uint32(b[9*i+4])<<14) & 0x3FFFF
+		c[2] = (uint32(b[9*i+4])>>4 | uint32(b[9*i+5])<<4 | uint32(b[9*i+6])<<12) & 0x3FFFF
+		c[3] = (uint32(b[9*i+6])>>6 | uint32(b[9*i+7])<<2 | uint32(b[9*i+8])<<10) & 0x3FFFF
+		for j := range c {
+			p[4*i+j] = subMod(GAMMA1, c[j])
+		}
+	}
+}
+
+// unpackHint unpacks the hint bits, returning false if they are not encoded in
+// the unique valid way.
+func unpackHint(h *[K][N]bool, b []byte) bool {
+	k := 0
+	for i := 0; i < K; i++ {
+		end := int(b[OMEGA+i])
+		if end < k || end > OMEGA {
+			return false
+		}
+		for j := k; j < end; j++ {
+			if j > k && b[j] <= b[j-1] {
+				return false
+			}
+			h[i][b[j]] = true
+		}
+		k = end
+	}
+	for j := k; j < OMEGA; j++ {
+		if b[j] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// packW1 packs the 6 bit coefficients of w1.
+func packW1(b []byte, p *poly) {
+	for i := 0; i < N/4; i++ {
+		b[3*i+0] = byte(p[4*i+0] | p[4*i+1]<<6)
+		b[3*i+1] = byte(p[4*i+1]>>2 | p[4*i+2]<<4)
+		b[3*i+2] = byte(p[4*i+2]>>4 | p[4*i+3]<<2)
+	}
+}
@@ -0,0 +1,129 @@
+package dilithium
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+// The vectors are the Dilithium parts of the hybrid signatures in the genesis cross
+// sign file of consensus/proofofstake, made by libhybridpqc.
+type testVector struct {
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+	PublicKey string `json:"publicKey"`
+}
+
+func loadVectors(t *testing.T) (messages, signatures, publicKeys [][]byte) {
+	data, err := ioutil.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []testVector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectors {
+		message, _ := hex.DecodeString(v.Message)
+		signature, _ := hex.DecodeString(v.Signature)
+		publicKey, _ := hex.DecodeString(v.PublicKey)
+		messages = append(messages, message)
+		signatures = append(signatures, signature)
+		publicKeys = append(publicKeys, publicKey)
+	}
+	return messages, signatures, publicKeys
+}
+
+func TestVerify(t *testing.T) {
+	messages, signatures, publicKeys := loadVectors(t)
+	for i := range messages {
+		if err := Verify(messages[i], signatures[i], publicKeys[i]); err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+	}
+
+	if err := Verify(messages[0], signatures[0], publicKeys[1]); err == nil {
+		t.Fatal("verify succeeded with another public key")
+	}
+	if err := Verify(messages[0], signatures[1], publicKeys[1]); err == nil {
+		t.Fatal("verify succeeded with another message")
+	}
+}
+
+func TestVerifyTampered(t *testing.T) {
+	messages, signatures, publicKeys := loadVectors(t)
+
+	for _, pos := range []int{0, CTILDEBYTES - 1, CTILDEBYTES, CTILDEBYTES + 1000, CTILDEBYTES + L*POLYZ_PACKEDBYTES - 1, CRYPTO_SIGNATURE_BYTES - 1} {
+		signature := append([]byte{}, signatures[0]...)
+		signature[pos] ^= 0x01
+		if err := Verify(messages[0], signature, publicKeys[0]); err == nil {
+			t.Fatalf("verify succeeded with signature byte %d changed", pos)
+		}
+	}
+
+	for _, pos := range []int{0, SEEDBYTES, CRYPTO_PUBLICKEY_BYTES - 1} {
+		publicKey := append([]byte{}, publicKeys[0]...)
+		publicKey[pos] ^= 0x01
+		if err := Verify(messages[0], signatures[0], publicKey); err == nil {
+			t.Fatalf("verify succeeded with public key byte %d changed", pos)
+		}
+	}
+
+	message := append([]byte{}, messages[0]...)
+	message[len(message)-1] ^= 0x01
+	if err := Verify(message, signatures[0], publicKeys[0]); err == nil {
+		t.Fatal("verify succeeded with the message changed")
+	}
+}
+
+func TestVerifyMalformed(t *testing.T) {
+	messages, signatures, publicKeys := loadVectors(t)
+
+	if err := Verify(messages[0], signatures[0][1:], publicKeys[0]); err != ErrInvalidSignatureLen {
+		t.Fatalf("got %v, want %v", err, ErrInvalidSignatureLen)
+	}
+	if err := Verify(messages[0], signatures[0], publicKeys[0][1:]); err != ErrInvalidPublicKeyLen {
+		t.Fatalf("got %v, want %v", err, ErrInvalidPublicKeyLen)
+	}
+
+	// The hints have a single valid encoding, with increasing indexes and zero padding
+	signature := append([]byte{}, signatures[0]...)
+	hints := signature[CTILDEBYTES+L*POLYZ_PACKEDBYTES:]
+	if hints[OMEGA+K-1] == OMEGA {
+		t.Skip("no padding in the hints of the vector")
+	}
+	hints[OMEGA-1] = 1
+	if err := Verify(messages[0], signature, publicKeys[0]); err != ErrInvalidSignature {
+		t.Fatalf("got %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestNTT(t *testing.T) {
+	var a, b, c poly
+	for i := range a {
+		a[i] = uint32(i * 7919 % Q)
+		b[i] = uint32((i*104729 + 3) % Q)
+	}
+
+	// Multiplication in the NTT domain is multiplication in Z_q[X]/(X^256 + 1)
+	var want poly
+	for i := 0; i < N; i++ {
+		for j := 0; j < N; j++ {
+			p := mulMod(a[i], b[j])
+			if i+j < N {
+				want[i+j] = addMod(want[i+j], p)
+			} else {
+				want[i+j-N] = subMod(want[i+j-N], p)
+			}
+		}
+	}
+
+	a.ntt()
+	b.ntt()
+	c.pointwiseMul(&a, &b)
+	c.invNTT()
+	if c != want {
+		t.Fatal("NTT multiplication mismatch")
+	}
+}
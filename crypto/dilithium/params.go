@@ -0,0 +1,27 @@
+package dilithium
+
+const (
+	N = 256
+	Q = 8380417
+	D = 13
+
+	K      = 4
+	L      = 4
+	TAU    = 39
+	BETA   = 78
+	GAMMA1 = 1 << 17
+	GAMMA2 = (Q - 1) / 88
+	OMEGA  = 80
+
+	SEEDBYTES   = 32
+	TRBYTES     = 64
+	CRHBYTES    = 64
+	CTILDEBYTES = 32
+
+	POLYT1_PACKEDBYTES = 320
+	POLYZ_PACKEDBYTES  = 576
+	POLYW1_PACKEDBYTES = 192
+
+	CRYPTO_PUBLICKEY_BYTES = SEEDBYTES + K*POLYT1_PACKEDBYTES
+	CRYPTO_SIGNATURE_BYTES = CTILDEBYTES + L*POLYZ_PACKEDBYTES + OMEGA + K
+)
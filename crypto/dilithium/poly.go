@@ -0,0 +1,223 @@
+package dilithium
+
+import (
+	"golang.org/x/crypto/sha3"
+)
+
+// poly is a polynomial of R_q, with its coefficients reduced to [0, Q).
+type poly [N]uint32
+
+// zetas holds the powers of the 512th root of unity 1753 used by the NTT, in
+// bit-reversed order.
+var zetas [N]uint32
+
+// nInv is 256^-1 mod Q, the scaling of the inverse NTT.
+const nInv = 8347681
+
+func init() {
+	for m := 0; m < N; m++ {
+		zetas[m] = powMod(1753, uint32(bitRev8(uint8(m))))
+	}
+}
+
+func bitRev8(b uint8) uint8 {
+	var r uint8
+	for i := 0; i < 8; i++ {
+		r = (r << 1) | (b & 1)
+		b >>= 1
+	}
+	return r
+}
+
+func powMod(a uint32, e uint32) uint32 {
+	r := uint64(1)
+	x := uint64(a)
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			r = r * x % Q
+		}
+		x = x * x % Q
+	}
+	return uint32(r)
+}
+
+func mulMod(a, b uint32) uint32 {
+	return uint32(uint64(a) * uint64(b) % Q)
+}
+
+func addMod(a, b uint32) uint32 {
+	r := a + b
+	if r >= Q {
+		r -= Q
+	}
+	return r
+}
+
+func subMod(a, b uint32) uint32 {
+	if a >= b {
+		return a - b
+	}
+	return a + Q - b
+}
+
+// ntt transforms p in place into the NTT domain.
+func (p *poly) ntt() {
+	m := 0
+	for length := 128; length >= 1; length >>= 1 {
+		for start := 0; start < N; start += 2 * length {
+			m++
+			z := zetas[m]
+			for j := start; j < start+length; j++ {
+				t := mulMod(z, p[j+length])
+				p[j+length] = subMod(p[j], t)
+				p[j] = addMod(p[j], t)
+			}
+		}
+	}
+}
+
+// invNTT transforms p in place back from the NTT domain.
+func (p *poly) invNTT() {
+	m := N
+	for length := 1; length < N; length <<= 1 {
+		for start := 0; start < N; start += 2 * length {
+			m--
+			z := Q - zetas[m]
+			for j := start; j < start+length; j++ {
+				t := p[j]
+				p[j] = addMod(t, p[j+length])
+				p[j+length] = mulMod(z, subMod(t, p[j+length]))
+			}
+		}
+	}
+	for j := range p {
+		p[j] = mulMod(nInv, p[j])
+	}
+}
+
+// pointwiseMul sets p to the product of a and b, both in the NTT domain.
+func (p *poly) pointwiseMul(a, b *poly) {
+	for j := range p {
+		p[j] = mulMod(a[j], b[j])
+	}
+}
+
+// pointwiseMulAdd adds the product of a and b, both in the NTT domain, to p.
+func (p *poly) pointwiseMulAdd(a, b *poly) {
+	for j := range p {
+		p[j] = addMod(p[j], mulMod(a[j], b[j]))
+	}
+}
+
+func (p *poly) sub(a, b *poly) {
+	for j := range p {
+		p[j] = subMod(a[j], b[j])
+	}
+}
+
+// exceedsNorm reports whether a coefficient of p, taken in (-Q/2, Q/2], has an
+// absolute value of at least bound.
+func (p *poly) exceedsNorm(bound uint32) bool {
+	for _, c := range p {
+		abs := c
+		if c > (Q-1)/2 {
+			abs = Q - c
+		}
+		if abs >= bound {
+			return true
+		}
+	}
+	return false
+}
+
+// uniform samples the element (i, j) of the matrix A, in the NTT domain, from
+// the seed rho by rejection on SHAKE128.
+func (p *poly) uniform(rho []byte, i, j uint8) {
+	h := sha3.NewShake128()
+	h.Write(rho)
+	h.Write([]byte{j, i})
+
+	var buf [168]byte
+	n := 0
+	for n < N {
+		h.Read(buf[:])
+		for pos := 0; pos+3 <= len(buf) && n < N; pos += 3 {
+			t := (uint32(buf[pos]) | uint32(buf[pos+1])<<8 | uint32(buf[pos+2])<<16) & 0x7FFFFF
+			if t < Q {
+				p[n] = t
+				n++
+			}
+		}
+	}
+}
+
+// challenge samples the polynomial c with TAU coefficients in {-1, 1} from the
+// commitment hash ctilde.
+func (p *poly) challenge(ctilde []byte) {
+	h := sha3.NewShake256()
+	h.Write(ctilde)
+
+	var buf [136]byte
+	h.Read(buf[:])
+	var signs uint64
+	for i := 0; i < 8; i++ {
+		signs |= uint64(buf[i]) << (8 * i)
+	}
+	pos := 8
+
+	for j := range p {
+		p[j] = 0
+	}
+	for i := N - TAU; i < N; i++ {
+		var b int
+		for {
+			if pos >= len(buf) {
+				h.Read(buf[:])
+				pos = 0
+			}
+			b = int(buf[pos])
+			pos++
+			if b <= i {
+				break
+			}
+		}
+		p[i] = p[b]
+		if signs&1 == 1 {
+			p[b] = Q - 1
+		} else {
+			p[b] = 1
+		}
+		signs >>= 1
+	}
+}
+
+// decompose splits a into a1 and a0 with a = a1*2*GAMMA2 + a0 mod Q and a0 in
+// (-GAMMA2, GAMMA2], a0 being returned as a signed value.
+func decompose(a uint32) (uint32, int32) {
+	a0 := int32(a % (2 * GAMMA2))
+	if a0 > GAMMA2 {
+		a0 -= 2 * GAMMA2
+	}
+	if int32(a)-a0 == Q-1 {
+		return 0, a0 - 1
+	}
+	return uint32((int32(a) - a0) / (2 * GAMMA2)), a0
+}
+
+// useHint returns the high bits of a corrected by the hint bit h.
+func useHint(a uint32, h bool) uint32 {
+	a1, a0 := decompose(a)
+	if h == false {
+		return a1
+	}
+	if a0 > 0 {
+		if a1 == 43 {
+			return 0
+		}
+		return a1 + 1
+	}
+	if a1 == 0 {
+		return 43
+	}
+	return a1 - 1
+}
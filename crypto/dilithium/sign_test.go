@@ -0,0 +1,379 @@
+package dilithium
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"golang.org/x/crypto/sha3"
+)
+
+var update = flag.Bool("update", false, "regenerate testdata/generated_vectors.json")
+
+// This file signs with Dilithium2 in Go, the deterministic variant of the reference
+// implementation, to generate vectors for the verifier. It is only meant for tests:
+// it is not constant time and the secret key is not packed.
+
+type testKey struct {
+	publicKey []byte
+	rho       []byte
+	key       []byte
+	tr        []byte
+	s1        [L]poly // NTT domain
+	s2        [K]poly // NTT domain
+	t0        [K]poly // NTT domain
+}
+
+// centered returns a coefficient taken in (-Q/2, Q/2].
+func centered(a uint32) int32 {
+	if a > (Q-1)/2 {
+		return int32(a) - Q
+	}
+	return int32(a)
+}
+
+func shake256(out []byte, in ...[]byte) {
+	h := sha3.NewShake256()
+	for _, b := range in {
+		h.Write(b)
+	}
+	h.Read(out)
+}
+
+func nonceBytes(nonce uint16) []byte {
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, nonce)
+	return b
+}
+
+// uniformEta samples a polynomial with coefficients in [-2, 2] from seed and nonce.
+func (p *poly) uniformEta(seed []byte, nonce uint16) {
+	h := sha3.NewShake256()
+	h.Write(seed)
+	h.Write(nonceBytes(nonce))
+
+	var buf [136]byte
+	n := 0
+	for n < N {
+		h.Read(buf[:])
+		for _, b := range buf {
+			for _, t := range []uint32{uint32(b & 0x0F), uint32(b >> 4)} {
+				if t < 15 && n < N {
+					t = t - (205*t>>10)*5
+					p[n] = subMod(2, t)
+					n++
+				}
+			}
+		}
+	}
+}
+
+// uniformGamma1 samples the mask polynomial y from seed and nonce.
+func (p *poly) uniformGamma1(seed []byte, nonce uint16) {
+	buf := make([]byte, POLYZ_PACKEDBYTES)
+	shake256(buf, seed, nonceBytes(nonce))
+	p.unpackZ(buf)
+}
+
+func (p *poly) add(a, b *poly) {
+	for j := range p {
+		p[j] = addMod(a[j], b[j])
+	}
+}
+
+// mulNTT returns the product of a and b, given in the NTT domain, out of the NTT
+// domain.
+func mulNTT(a, b *poly) poly {
+	var r poly
+	r.pointwiseMul(a, b)
+	r.invNTT()
+	return r
+}
+
+// matrixMul returns A*v out of the NTT domain, v being in the NTT domain.
+func matrixMul(rho []byte, v *[L]poly) [K]poly {
+	var w [K]poly
+	var a poly
+	for i := 0; i < K; i++ {
+		for j := 0; j < L; j++ {
+			a.uniform(rho, uint8(i), uint8(j))
+			if j == 0 {
+				w[i].pointwiseMul(&a, &v[j])
+			} else {
+				w[i].pointwiseMulAdd(&a, &v[j])
+			}
+		}
+		w[i].invNTT()
+	}
+	return w
+}
+
+func packT1(b []byte, p *poly) {
+	for i := 0; i < N/4; i++ {
+		b[5*i+0] = byte(p[4*i+0])
+		b[5*i+1] = byte(p[4*i+0]>>8 | p[4*i+1]<<2)
+		b[5*i+2] = byte(p[4*i+1]>>6 | p[4*i+2]<<4)
+		b[5*i+3] = byte(p[4*i+2]>>4 | p[4*i+3]<<6)
+		b[5*i+4] = byte(p[4*i+3] >> 2)
+	}
+}
+
+func packZ(b []byte, p *poly) {
+	for i := 0; i < N/4; i++ {
+		var t [4]uint32
+		for j := range t {
+			t[j] = subMod(GAMMA1, p[4*i+j])
+		}
+		b[9*i+0] = byte(t[0])
+		b[9*i+1] = byte(t[0] >> 8)
+		b[9*i+2] = byte(t[0]>>16 | t[1]<<2)
+		b[9*i+3] = byte(t[1] >> 6)
+		b[9*i+4] = byte(t[1]>>14 | t[2]<<4)
+		b[9*i+5] = byte(t[2] >> 4)
+		b[9*i+6] = byte(t[2]>>12 | t[3]<<6)
+		b[9*i+7] = byte(t[3] >> 2)
+		b[9*i+8] = byte(t[3] >> 10)
+	}
+}
+
+// generateTestKey derives a key pair from a 32 byte seed.
+func generateTestKey(seed []byte) *testKey {
+	seeds := make([]byte, 2*SEEDBYTES+CRHBYTES)
+	shake256(seeds, seed)
+	k := &testKey{rho: seeds[:SEEDBYTES], key: seeds[SEEDBYTES+CRHBYTES:]}
+	rhoprime := seeds[SEEDBYTES : SEEDBYTES+CRHBYTES]
+
+	for i := range k.s1 {
+		k.s1[i].uniformEta(rhoprime, uint16(i))
+		k.s1[i].ntt()
+	}
+	for i := range k.s2 {
+		k.s2[i].uniformEta(rhoprime, uint16(L+i))
+	}
+
+	t := matrixMul(k.rho, &k.s1)
+	k.publicKey = make([]byte, CRYPTO_PUBLICKEY_BYTES)
+	copy(k.publicKey, k.rho)
+	for i := range t {
+		t[i].add(&t[i], &k.s2[i])
+		var t1 poly
+		for n, c := range t[i] {
+			t1[n] = (c + (1 << (D - 1)) - 1) >> D
+			k.t0[i][n] = subMod(c, t1[n]<<D)
+		}
+		packT1(k.publicKey[SEEDBYTES+i*POLYT1_PACKEDBYTES:], &t1)
+		k.s2[i].ntt()
+		k.t0[i].ntt()
+	}
+
+	k.tr = make([]byte, TRBYTES)
+	shake256(k.tr, k.publicKey)
+	return k
+}
+
+// sign signs message deterministically.
+func (k *testKey) sign(message []byte) []byte {
+	mu := make([]byte, CRHBYTES)
+	shake256(mu, k.tr, message)
+	rhoprime := make([]byte, CRHBYTES)
+	shake256(rhoprime, k.key, mu)
+
+	for kappa := uint16(0); ; kappa++ {
+		var y, yhat [L]poly
+		for i := range y {
+			y[i].uniformGamma1(rhoprime, L*kappa+uint16(i))
+			yhat[i] = y[i]
+			yhat[i].ntt()
+		}
+		w := matrixMul(k.rho, &yhat)
+
+		var w1 [K]poly
+		var w0 [K][N]int32
+		hasher := sha3.NewShake256()
+		hasher.Write(mu)
+		var packed [POLYW1_PACKEDBYTES]byte
+		for i := range w {
+			for n, c := range w[i] {
+				w1[i][n], w0[i][n] = decompose(c)
+			}
+			packW1(packed[:], &w1[i])
+			hasher.Write(packed[:])
+		}
+		ctilde := make([]byte, CTILDEBYTES)
+		hasher.Read(ctilde)
+
+		var c poly
+		c.challenge(ctilde)
+		c.ntt()
+
+		var z [L]poly
+		rejected := false
+		for i := range z {
+			cs1 := mulNTT(&c, &k.s1[i])
+			z[i].add(&y[i], &cs1)
+			if z[i].exceedsNorm(GAMMA1 - BETA) {
+				rejected = true
+			}
+		}
+		if rejected {
+			continue
+		}
+
+		var h [K][N]bool
+		hints := 0
+		for i := 0; i < K && rejected == false; i++ {
+			cs2 := mulNTT(&c, &k.s2[i])
+			ct0 := mulNTT(&c, &k.t0[i])
+			for n := 0; n < N; n++ {
+				r0 := w0[i][n] - centered(cs2[n])
+				if r0 >= GAMMA2-BETA || r0 <= -(GAMMA2-BETA) {
+					rejected = true
+					break
+				}
+				ct := centered(ct0[n])
+				if ct >= GAMMA2 || ct <= -GAMMA2 {
+					rejected = true
+					break
+				}
+				a0 := r0 + ct
+				if a0 > GAMMA2 || a0 < -GAMMA2 || (a0 == -GAMMA2 && w1[i][n] != 0) {
+					h[i][n] = true
+					hints++
+				}
+			}
+		}
+		if rejected || hints > OMEGA {
+			continue
+		}
+
+		signature := make([]byte, CRYPTO_SIGNATURE_BYTES)
+		copy(signature, ctilde)
+		for i := range z {
+			packZ(signature[CTILDEBYTES+i*POLYZ_PACKEDBYTES:], &z[i])
+		}
+		hintBytes := signature[CTILDEBYTES+L*POLYZ_PACKEDBYTES:]
+		n := 0
+		for i := range h {
+			for j := range h[i] {
+				if h[i][j] {
+					hintBytes[n] = byte(j)
+					n++
+				}
+			}
+			hintBytes[OMEGA+i] = byte(n)
+		}
+		return signature
+	}
+}
+
+type generatedVector struct {
+	Comment   string `json:"comment"`
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+	PublicKey string `json:"publicKey"`
+	Valid     bool   `json:"valid"`
+}
+
+// generateVectors signs messages with keys derived from fixed seeds, along with
+// tampered copies of the signatures, public keys and messages.
+func generateVectors() []generatedVector {
+	var vectors []generatedVector
+	add := func(comment string, message, signature, publicKey []byte, valid bool) {
+		vectors = append(vectors, generatedVector{
+			Comment:   comment,
+			Message:   hex.EncodeToString(message),
+			Signature: hex.EncodeToString(signature),
+			PublicKey: hex.EncodeToString(publicKey),
+			Valid:     valid,
+		})
+	}
+	flip := func(b []byte, pos int) []byte {
+		b = append([]byte{}, b...)
+		b[pos] ^= 0x01
+		return b
+	}
+
+	for i := 0; i < 4; i++ {
+		seed := sha256.Sum256([]byte{'k', 'e', 'y', byte(i)})
+		key := generateTestKey(seed[:])
+		message := sha3.Sum512([]byte{'m', 's', 'g', byte(i)})
+		signature := key.sign(message[:])
+
+		add("valid", message[:], signature, key.publicKey, true)
+		add("challenge changed", message[:], flip(signature, 0), key.publicKey, false)
+		add("z changed", message[:], flip(signature, CTILDEBYTES+POLYZ_PACKEDBYTES+7), key.publicKey, false)
+		add("last z changed", message[:], flip(signature, CTILDEBYTES+L*POLYZ_PACKEDBYTES-1), key.publicKey, false)
+		add("hint count changed", message[:], flip(signature, CRYPTO_SIGNATURE_BYTES-1), key.publicKey, false)
+		add("rho changed", message[:], signature, flip(key.publicKey, 0), false)
+		add("t1 changed", message[:], signature, flip(key.publicKey, CRYPTO_PUBLICKEY_BYTES-1), false)
+		add("message changed", flip(message[:], len(message)-1), signature, key.publicKey, false)
+	}
+	return vectors
+}
+
+func loadGeneratedVectors(t *testing.T) []generatedVector {
+	data, err := ioutil.ReadFile("testdata/generated_vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []generatedVector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	return vectors
+}
+
+func TestGeneratedVectors(t *testing.T) {
+	if *update {
+		data, err := json.MarshalIndent(generateVectors(), "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile("testdata/generated_vectors.json", append(data, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vectors := loadGeneratedVectors(t)
+	if len(vectors) == 0 {
+		t.Fatal("no vectors")
+	}
+	for i, v := range vectors {
+		message, _ := hex.DecodeString(v.Message)
+		signature, _ := hex.DecodeString(v.Signature)
+		publicKey, _ := hex.DecodeString(v.PublicKey)
+		if err := Verify(message, signature, publicKey); (err == nil) != v.Valid {
+			t.Errorf("vector %d (%s): verify returned %v, want valid %v", i, v.Comment, err, v.Valid)
+		}
+	}
+}
+
+// The vectors are regenerated the same, as signing is deterministic.
+func TestGeneratedVectorsReproducible(t *testing.T) {
+	vectors := loadGeneratedVectors(t)
+	generated := generateVectors()
+	if len(generated) != len(vectors) {
+		t.Fatalf("generated %d vectors, want %d", len(generated), len(vectors))
+	}
+	for i := range vectors {
+		if generated[i] != vectors[i] {
+			t.Fatalf("vector %d (%s) differs when generated again", i, vectors[i].Comment)
+		}
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		seed := sha256.Sum256([]byte{'s', byte(i)})
+		key := generateTestKey(seed[:])
+		message := sha256.Sum256(seed[:])
+		signature := key.sign(message[:])
+		if err := Verify(message[:], signature, key.publicKey); err != nil {
+			t.Fatalf("key %d: %v", i, err)
+		}
+	}
+}
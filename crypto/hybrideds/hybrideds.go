@@ -1,3 +1,6 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
 package hybrideds
 
 /*
@@ -13,13 +16,14 @@ import (
 )
 
 const (
-	OK                     = 0
-	CRYPTO_SECRETKEY_BYTES = 64 + 2560 + 1312 + 128
-	CRYPTO_PUBLICKEY_BYTES = 32 + 1312 + 64
-	CRYPTO_MESSAGE_LEN     = 32
-	CRYPTO_SIGNATURE_BYTES = 2 + 64 + 2420 + 40 + CRYPTO_MESSAGE_LEN //2558
-	HYBRID_DIGEST_LEN      = 64
-	SIG_NAME               = "dilithium-ed25519-sphincs"
+	OK                        = 0
+	CRYPTO_SECRETKEY_BYTES    = 64 + 2560 + 1312 + 128
+	CRYPTO_PUBLICKEY_BYTES    = 32 + 1312 + 64
+	CRYPTO_KEYPAIR_SEED_BYTES = 32 + 32 + 96 //ed25519, dilithium and sphincs seeds
+	CRYPTO_MESSAGE_LEN        = 32
+	CRYPTO_SIGNATURE_BYTES    = 2 + 64 + 2420 + 40 + CRYPTO_MESSAGE_LEN //2558
+	HYBRID_DIGEST_LEN         = 64
+	SIG_NAME                  = "dilithium-ed25519-sphincs"
 )
 
 var (
@@ -36,6 +40,8 @@ var (
 	ErrRecoverFailed          = errors.New("recovery failed")
 	ErrKeypairFailed          = errors.New("can not generate keypair")
 	ErrInvalidLen             = errors.New("invalid length")
+	ErrInvalidSeedLen         = errors.New("invalid seed length")
+	ErrSeedKeygenUnsupported  = errors.New("key generation from a seed requires libhybridpqc with crypto_sign_dilithium_ed25519_sphincs_keypair_seed, build with the hybridpqc_seed tag")
 	ErrVerifyFailed           = errors.New("verify failed")
 	ErrRecoverPublicKeyFailed = errors.New("recover public key length")
 )
@@ -0,0 +1,13 @@
+//go:build cgo && !purego && !hybridpqc_seed
+// +build cgo,!purego,!hybridpqc_seed
+
+package hybrideds
+
+// GenerateKeyFromSeed is not supported without the hybridpqc_seed build tag, since
+// the seeded keypair generation is not exported by every release of libhybridpqc.
+func GenerateKeyFromSeed(seed []byte) (publicKey []byte, secretKey []byte, err error) {
+	if len(seed) != CRYPTO_KEYPAIR_SEED_BYTES {
+		return nil, nil, ErrInvalidSeedLen
+	}
+	return nil, nil, ErrSeedKeygenUnsupported
+}
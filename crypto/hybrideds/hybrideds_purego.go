@@ -0,0 +1,81 @@
+//go:build !cgo || purego
+// +build !cgo purego
+
+package hybrideds
+
+import (
+	"errors"
+)
+
+// This file provides the verification half of the compact hybrid scheme in pure Go,
+// for builds without cgo or with the purego build tag. Keys can not be generated and
+// messages can not be signed without libhybridpqc.
+
+const (
+	OK                        = 0
+	CRYPTO_SECRETKEY_BYTES    = 64 + 2560 + 1312 + 128
+	CRYPTO_PUBLICKEY_BYTES    = 32 + 1312 + 64
+	CRYPTO_KEYPAIR_SEED_BYTES = 32 + 32 + 96 //ed25519, dilithium and sphincs seeds
+	CRYPTO_MESSAGE_LEN        = 32
+	CRYPTO_SIGNATURE_BYTES    = 2 + 64 + 2420 + 40 + CRYPTO_MESSAGE_LEN //2558
+	HYBRID_DIGEST_LEN         = 64
+	SIG_NAME                  = "dilithium-ed25519-sphincs"
+)
+
+var (
+	ErrSignatureInitial       = errors.New("signature mechanism is not supported by OQS")
+	ErrInvalidMsgLen          = errors.New("invalid message length, need 32 bytes")
+	ErrInvalidSignatureLen    = errors.New("invalid signature length")
+	ErrInvalidPublicKeyLen    = errors.New("invalid public key length")
+	ErrInvalidPrivateKeyLen   = errors.New("invalid private key length")
+	ErrInvalidRecoveryID      = errors.New("invalid signature recovery id")
+	ErrInvalidKey             = errors.New("invalid private key")
+	ErrInvalidPubkey          = errors.New("invalid public key")
+	ErrMismatchPublicKey      = errors.New("mismatch public key")
+	ErrSignFailed             = errors.New("signing failed")
+	ErrRecoverFailed          = errors.New("recovery failed")
+	ErrKeypairFailed          = errors.New("can not generate keypair")
+	ErrInvalidLen             = errors.New("invalid length")
+	ErrInvalidSeedLen         = errors.New("invalid seed length")
+	ErrSeedKeygenUnsupported  = errors.New("key generation from a seed requires libhybridpqc with crypto_sign_dilithium_ed25519_sphincs_keypair_seed, build with the hybridpqc_seed tag")
+	ErrVerifyFailed           = errors.New("verify failed")
+	ErrRecoverPublicKeyFailed = errors.New("recover public key length")
+	ErrCgoRequired            = errors.New("signing requires libhybridpqc, build with cgo and without the purego tag")
+)
+
+func GenerateKey() (publicKey []byte, secretKey []byte, err error) {
+	return nil, nil, ErrCgoRequired
+}
+
+func GenerateKeyFromSeed(seed []byte) (publicKey []byte, secretKey []byte, err error) {
+	return nil, nil, ErrCgoRequired
+}
+
+func Sign(secretKey []byte, message []byte) ([]byte, error) {
+	return nil, ErrCgoRequired
+}
+
+// Verify verifies the validity of a signed message, returning true if the
+// signature is valid, and false otherwise.
+func Verify(message []byte, signature []byte, publicKey []byte) error {
+	return verifyGo(message, signature, publicKey)
+}
+
+// Verifies only with Dilithium
+func VerifyDilithium(digestHash []byte, signature []byte, publicKey []byte) error {
+	return verifyDilithiumGo(digestHash, signature, publicKey)
+}
+
+func PrivateAndPublicFromPrivateKey(compositePrivateKey []byte) (privateBytes []byte, publicBytes []byte, err error) {
+
+	if len(compositePrivateKey) != CRYPTO_SECRETKEY_BYTES {
+		return nil, nil, ErrInvalidPrivateKeyLen
+	}
+
+	pubKeyBytes := make([]byte, 0, CRYPTO_PUBLICKEY_BYTES)
+	pubKeyBytes = append(pubKeyBytes, compositePrivateKey[32:64]...)
+	pubKeyBytes = append(pubKeyBytes, compositePrivateKey[64+2560:64+2560+1312]...)
+	pubKeyBytes = append(pubKeyBytes, compositePrivateKey[64+2560+1312+64:]...)
+
+	return compositePrivateKey, pubKeyBytes, nil
+}
@@ -0,0 +1,52 @@
+//go:build cgo && !purego && hybridpqc_seed
+// +build cgo,!purego,hybridpqc_seed
+
+package hybrideds
+
+/*
+#cgo pkg-config: libhybridpqc
+#include <dilithium/hybrid.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"errors"
+	"unsafe"
+)
+
+// GenerateKeyFromSeed generates the keypair deterministically from a seed of
+// CRYPTO_KEYPAIR_SEED_BYTES bytes, instead of from the system randomness. The
+// seeded keypair generation is not exported by every release of libhybridpqc, it
+// is only linked with the hybridpqc_seed build tag.
+func GenerateKeyFromSeed(seed []byte) (publicKey []byte, secretKey []byte, err error) {
+	if len(seed) != CRYPTO_KEYPAIR_SEED_BYTES {
+		return nil, nil, ErrInvalidSeedLen
+	}
+
+	publicKey = make([]byte, CRYPTO_PUBLICKEY_BYTES)
+	secretKey = make([]byte, CRYPTO_SECRETKEY_BYTES)
+
+	rv := C.crypto_sign_dilithium_ed25519_sphincs_keypair_seed(
+		(*C.uchar)(unsafe.Pointer(&publicKey[0])),
+		(*C.uchar)(unsafe.Pointer(&secretKey[0])),
+		(*C.uchar)(unsafe.Pointer(&seed[0])))
+
+	if rv != OK {
+		return nil, nil, errors.New("GenerateKeyFromSeed failed")
+	}
+
+	if bytes.Compare(publicKey[:32], secretKey[32:64]) != 0 {
+		return nil, nil, ErrKeypairFailed
+	}
+
+	if bytes.Compare(publicKey[32:32+1312], secretKey[64+2560:64+2560+1312]) != 0 {
+		return nil, nil, ErrKeypairFailed
+	}
+
+	if bytes.Compare(publicKey[32+1312:], secretKey[64+2560+1312+64:]) != 0 {
+		return nil, nil, ErrKeypairFailed
+	}
+
+	return publicKey[:], secretKey[:], nil
+}
@@ -0,0 +1,89 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
+package hybrideds
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+)
+
+// The pure Go verifier must accept and reject exactly the signatures libhybridpqc
+// accepts and rejects.
+func TestVerifyGo_CrossCheck(t *testing.T) {
+	messages, signatures, publicKeys := loadCompactVectors(t)
+	for i := range messages {
+		if err := Verify(messages[i], signatures[i], publicKeys[i]); err != nil {
+			t.Fatalf("vector %d: libhybridpqc verify failed: %v", i, err)
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		pubKey, priKey, err := GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		message := make([]byte, CRYPTO_MESSAGE_LEN)
+		rand.Read(message)
+		signature, err := Sign(priKey, message)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := verifyGo(message, signature, pubKey); err != nil {
+			t.Fatalf("iteration %d: pure Go verify failed: %v", i, err)
+		}
+
+		nonceStart := 2 + CRYPTO_ED25519_SIGNATURE_BYTES + CRYPTO_DILITHIUM_SIGNATURE_BYTES
+		hybridDigest := compactHybridDigest(signature[nonceStart:nonceStart+NONCE_SIZE], message, pubKey)
+		dilithiumSig := signature[2+CRYPTO_ED25519_SIGNATURE_BYTES : nonceStart]
+		dilithiumPubKey := pubKey[CRYPTO_ED25519_PUBLICKEY_BYTES : CRYPTO_ED25519_PUBLICKEY_BYTES+CRYPTO_DILITHIUM_PUBLICKEY_BYTES]
+		if (VerifyDilithium(hybridDigest, dilithiumSig, dilithiumPubKey) == nil) != (verifyDilithiumGo(hybridDigest, dilithiumSig, dilithiumPubKey) == nil) {
+			t.Fatalf("iteration %d: Dilithium verify mismatch", i)
+		}
+
+		for j, sig := range tamperedSignatures(signature) {
+			if (Verify(message, sig, pubKey) == nil) != (verifyGo(message, sig, pubKey) == nil) {
+				t.Fatalf("iteration %d: verify mismatch with tampered signature %d", i, j)
+			}
+		}
+
+		otherMessage := append([]byte{}, message...)
+		otherMessage[0] ^= 0x01
+		if (Verify(otherMessage, signature, pubKey) == nil) != (verifyGo(otherMessage, signature, pubKey) == nil) {
+			t.Fatalf("iteration %d: verify mismatch with another message", i)
+		}
+	}
+}
+
+// libhybridpqc must agree with the expected result of the generated vectors, the
+// tampered compact vectors and the Dilithium vectors signed in Go by the tests of
+// crypto/dilithium.
+func TestVerify_GeneratedVectors(t *testing.T) {
+	vectors, messages, signatures, publicKeys := loadGeneratedVectors(t)
+	for i, v := range vectors {
+		if err := Verify(messages[i], signatures[i], publicKeys[i]); (err == nil) != v.Valid {
+			t.Errorf("%s: libhybridpqc verify returned %v, want valid %v", v.Comment, err, v.Valid)
+		}
+	}
+
+	data, err := ioutil.ReadFile("../dilithium/testdata/generated_vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dilithiumVectors []generatedVector
+	if err := json.Unmarshal(data, &dilithiumVectors); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range dilithiumVectors {
+		message, _ := hex.DecodeString(v.Message)
+		signature, _ := hex.DecodeString(v.Signature)
+		publicKey, _ := hex.DecodeString(v.PublicKey)
+		if err := VerifyDilithium(message, signature, publicKey); (err == nil) != v.Valid {
+			t.Errorf("Dilithium %s: libhybridpqc verify returned %v, want valid %v", v.Comment, err, v.Valid)
+		}
+	}
+}
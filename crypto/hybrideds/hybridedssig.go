@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/drng/ChaCha20"
 	"github.com/DogeProtocol/dp/crypto/hybridedsfull"
 	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
 	"golang.org/x/crypto/sha3"
@@ -96,6 +97,56 @@ func (s HybridedsSig) GenerateKey() (*signaturealgorithm.PrivateKey, error) {
 	return privy, nil
 }
 
+// GenerateKeyFromSeed generates a key deterministically from a 32 byte seed, such
+// as the per-account seed of a hierarchical deterministic wallet. The seed
+// initializes the ChaCha20 DRNG that the keypair seed of the library is read from.
+func (s HybridedsSig) GenerateKeyFromSeed(seed [common.HashLength]byte) (*signaturealgorithm.PrivateKey, error) {
+	keypairSeed, err := expandKeypairSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroBytes(keypairSeed)
+
+	pubKey, priKey, err := GenerateKeyFromSeed(keypairSeed)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pubKey) != s.publicKeyLength || len(priKey) != s.privateKeyLength {
+		return nil, ErrKeypairFailed
+	}
+
+	privy := new(signaturealgorithm.PrivateKey)
+	privy.PriData = make([]byte, len(priKey))
+	copy(privy.PriData, priKey)
+
+	privy.PublicKey.PubData = make([]byte, len(pubKey))
+	copy(privy.PublicKey.PubData, pubKey)
+
+	return privy, nil
+}
+
+// expandKeypairSeed reads the keypair seed of the library from the ChaCha20 DRNG
+// initialized with seed.
+func expandKeypairSeed(seed [common.HashLength]byte) ([]byte, error) {
+	g := ChaCha20.ChaCha20DRNGInitializer{}
+	rng, err := g.InitializeWithSeed(seed)
+	if err != nil {
+		return nil, err
+	}
+	keypairSeed := make([]byte, CRYPTO_KEYPAIR_SEED_BYTES)
+	for i := range keypairSeed {
+		keypairSeed[i] = rng.NextByte()
+	}
+	return keypairSeed, nil
+}
+
+func zeroBytes(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
 func (s HybridedsSig) SerializePrivateKey(priv *signaturealgorithm.PrivateKey) ([]byte, error) {
 	priBytes, err := s.exportPrivateKey(priv)
 	if err != nil {
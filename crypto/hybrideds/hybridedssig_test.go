@@ -1,9 +1,14 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
 package hybrideds
 
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/crypto"
 	"github.com/DogeProtocol/dp/crypto/hybridedsfull"
 	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
@@ -177,3 +182,67 @@ func TestBase64(t *testing.T) {
 	testBase64(t, false)
 	testBase64(t, true)
 }
+
+func TestHybridedsSig_GenerateKeyFromSeed(t *testing.T) {
+	sig := CreateHybridedsSig(true)
+
+	var seed1, seed2 [32]byte
+	for i := range seed1 {
+		seed1[i] = byte(i)
+		seed2[i] = byte(i + 32)
+	}
+	key1, err := sig.GenerateKeyFromSeed(seed1)
+	if err == ErrSeedKeygenUnsupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	again, err := sig.GenerateKeyFromSeed(seed1)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if bytes.Equal(key1.PriData, again.PriData) == false || bytes.Equal(key1.PubData, again.PubData) == false {
+		t.Fatalf("key generation from a seed is not deterministic")
+	}
+	key2, err := sig.GenerateKeyFromSeed(seed2)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if bytes.Equal(key1.PubData, key2.PubData) {
+		t.Fatalf("keys of different seeds are equal")
+	}
+
+	digestHash := crypto.Keccak256([]byte("seeded key"))
+	signature, err := sig.Sign(digestHash, key1)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if sig.Verify(key1.PubData, digestHash, signature) == false {
+		t.Fatalf("failed to verify the signature of a seeded key")
+	}
+
+	if _, _, err := GenerateKeyFromSeed(seed1[:]); err != ErrInvalidSeedLen {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrInvalidSeedLen)
+	}
+}
+
+// Tests the keypair seed handed to the library for the account seed at the default
+// derivation path of the reference mnemonic, which fixes the keys of existing
+// wallets up to the seeded keypair generation of the library.
+func TestExpandKeypairSeed(t *testing.T) {
+	var seed [32]byte
+	copy(seed[:], common.FromHex("488128b78e04860a2e1d932df5f1ba5b662d49163940ccd41f19cd21242e841d"))
+	keypairSeed, err := expandKeypairSeed(seed)
+	if err != nil {
+		t.Fatalf("failed to expand seed: %v", err)
+	}
+	want := "c03c5ace47e63fda5d31a828a9e91d697a1bbdd5db49701cb612242cbbccfe46" +
+		"13d98ba939f60357f43e60e78864070b47aa91f38884e01b02efbb076e3ff68b" +
+		"45fc8e4691fc4061addc85c43a4488af370369491c5d6640a703a2b83336a8cd" +
+		"743c700e04cd764bafe8cd9e4b01021877d5caf13863164dc7bad87f32288810" +
+		"37fb06c3d521f9ffae1a3f1ef49aae7431aa0e6d2a42b1859919567c0b92690d"
+	if hex.EncodeToString(keypairSeed) != want {
+		t.Errorf("keypair seed mismatch: have %x, want %s", keypairSeed, want)
+	}
+}
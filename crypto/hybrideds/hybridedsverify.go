@@ -0,0 +1,71 @@
+package hybrideds
+
+import (
+	"bytes"
+	"crypto/ed25519"
+
+	"github.com/DogeProtocol/dp/crypto/dilithium"
+	"golang.org/x/crypto/sha3"
+)
+
+// verifyGo verifies a compact signature like Verify, with Go's ED25519 and the pure
+// Go Dilithium verifier instead of libhybridpqc.
+func verifyGo(message []byte, signature []byte, publicKey []byte) error {
+	if len(message) != CRYPTO_MESSAGE_LEN || len(signature) == 0 || len(publicKey) == 0 {
+		return ErrInvalidLen
+	}
+	if len(publicKey) != CRYPTO_PUBLICKEY_BYTES {
+		return ErrInvalidPublicKeyLen
+	}
+	if len(signature) != CRYPTO_SIGNATURE_BYTES {
+		return ErrInvalidSignatureLen
+	}
+
+	if signature[0] != SIGNATURE_ID || int(signature[1]) != len(message) {
+		return ErrVerifyFailed
+	}
+
+	nonceStart := 2 + CRYPTO_ED25519_SIGNATURE_BYTES + CRYPTO_DILITHIUM_SIGNATURE_BYTES
+	if bytes.Equal(signature[nonceStart+NONCE_SIZE:], message) == false {
+		return ErrVerifyFailed
+	}
+
+	hybridDigest := compactHybridDigest(signature[nonceStart:nonceStart+NONCE_SIZE], message, publicKey)
+
+	if ed25519.Verify(publicKey[:CRYPTO_ED25519_PUBLICKEY_BYTES], hybridDigest, signature[2:2+CRYPTO_ED25519_SIGNATURE_BYTES]) == false {
+		return ErrVerifyFailed
+	}
+
+	return verifyDilithiumGo(hybridDigest, signature[2+CRYPTO_ED25519_SIGNATURE_BYTES:nonceStart],
+		publicKey[CRYPTO_ED25519_PUBLICKEY_BYTES:CRYPTO_ED25519_PUBLICKEY_BYTES+CRYPTO_DILITHIUM_PUBLICKEY_BYTES])
+}
+
+// compactHybridDigest returns the digest signed by both ED25519 and Dilithium in a
+// compact signature, which binds the nonce, the message and the SPHINCS public key.
+func compactHybridDigest(nonce []byte, message []byte, publicKey []byte) []byte {
+	hasher := sha3.New512()
+	hasher.Write(nonce)
+	hasher.Write(message)
+	hasher.Write(publicKey[CRYPTO_ED25519_PUBLICKEY_BYTES+CRYPTO_DILITHIUM_PUBLICKEY_BYTES:])
+	return hasher.Sum(nil)
+}
+
+// verifyDilithiumGo verifies only with Dilithium like VerifyDilithium, with the pure
+// Go Dilithium verifier.
+func verifyDilithiumGo(digestHash []byte, signature []byte, publicKey []byte) error {
+	if len(digestHash) != HYBRID_DIGEST_LEN || len(signature) == 0 || len(publicKey) == 0 {
+		return ErrInvalidLen
+	}
+	if len(publicKey) != dilithium.CRYPTO_PUBLICKEY_BYTES {
+		return ErrInvalidPublicKeyLen
+	}
+	if len(signature) != dilithium.CRYPTO_SIGNATURE_BYTES {
+		return ErrInvalidSignatureLen
+	}
+
+	if dilithium.Verify(digestHash, signature, publicKey) != nil {
+		return ErrVerifyFailed
+	}
+
+	return nil
+}
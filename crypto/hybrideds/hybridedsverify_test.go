@@ -0,0 +1,172 @@
+package hybrideds
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "regenerate testdata/compact_generated_vectors.json")
+
+// The vectors are the hybrid signatures in the genesis cross sign file of
+// consensus/proofofstake, made by libhybridpqc.
+type compactVector struct {
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+	PublicKey string `json:"publicKey"`
+}
+
+func loadCompactVectors(t *testing.T) (messages, signatures, publicKeys [][]byte) {
+	data, err := ioutil.ReadFile("testdata/compact_vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []compactVector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectors {
+		message, _ := hex.DecodeString(v.Message)
+		signature, _ := hex.DecodeString(v.Signature)
+		publicKey, _ := hex.DecodeString(v.PublicKey)
+		messages = append(messages, message)
+		signatures = append(signatures, signature)
+		publicKeys = append(publicKeys, publicKey)
+	}
+	return messages, signatures, publicKeys
+}
+
+// tamperedSignatures returns copies of signature with a byte changed in each of its
+// parts: the header, the ED25519 and Dilithium signatures, the nonce and the message.
+func tamperedSignatures(signature []byte) [][]byte {
+	positions := []int{0, 1, 2, 2 + CRYPTO_ED25519_SIGNATURE_BYTES - 1, 2 + CRYPTO_ED25519_SIGNATURE_BYTES,
+		2 + CRYPTO_ED25519_SIGNATURE_BYTES + CRYPTO_DILITHIUM_SIGNATURE_BYTES - 1,
+		2 + CRYPTO_ED25519_SIGNATURE_BYTES + CRYPTO_DILITHIUM_SIGNATURE_BYTES, CRYPTO_SIGNATURE_BYTES - 1}
+	var tampered [][]byte
+	for _, pos := range positions {
+		sig := append([]byte{}, signature...)
+		sig[pos] ^= 0x01
+		tampered = append(tampered, sig)
+	}
+	return tampered
+}
+
+func TestVerifyGo_Vectors(t *testing.T) {
+	messages, signatures, publicKeys := loadCompactVectors(t)
+	for i := range messages {
+		if err := verifyGo(messages[i], signatures[i], publicKeys[i]); err != nil {
+			t.Fatalf("vector %d: %v", i, err)
+		}
+		for j, sig := range tamperedSignatures(signatures[i]) {
+			if verifyGo(messages[i], sig, publicKeys[i]) == nil {
+				t.Fatalf("vector %d: verify succeeded with tampered signature %d", i, j)
+			}
+		}
+	}
+
+	if verifyGo(messages[0], signatures[0], publicKeys[1]) == nil {
+		t.Fatal("verify succeeded with another public key")
+	}
+	if verifyGo(messages[0][1:], signatures[0], publicKeys[0]) != ErrInvalidLen {
+		t.Fatal("verify succeeded with a short message")
+	}
+}
+
+func TestHybridedsSig_VerifyGoVectors(t *testing.T) {
+	messages, signatures, publicKeys := loadCompactVectors(t)
+	sig := CreateHybridedsSig(true)
+	for i := range messages {
+		combined, err := sig.CombinePublicKeySignature(signatures[i], publicKeys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig.VerifyNative(publicKeys[i], messages[i], combined) == false {
+			t.Fatalf("vector %d: VerifyNative failed", i)
+		}
+	}
+}
+
+type generatedVector struct {
+	Comment   string `json:"comment"`
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+	PublicKey string `json:"publicKey"`
+	Valid     bool   `json:"valid"`
+}
+
+// generateCompactVectors returns the compact vectors along with tampered copies of
+// them, and the vectors checked with the public key or message of another one.
+func generateCompactVectors(t *testing.T) []generatedVector {
+	messages, signatures, publicKeys := loadCompactVectors(t)
+	var vectors []generatedVector
+	add := func(comment string, message, signature, publicKey []byte, valid bool) {
+		vectors = append(vectors, generatedVector{
+			Comment:   comment,
+			Message:   hex.EncodeToString(message),
+			Signature: hex.EncodeToString(signature),
+			PublicKey: hex.EncodeToString(publicKey),
+			Valid:     valid,
+		})
+	}
+	for i := range messages {
+		other := (i + 1) % len(messages)
+		add(fmt.Sprintf("vector %d", i), messages[i], signatures[i], publicKeys[i], true)
+		for j, sig := range tamperedSignatures(signatures[i]) {
+			add(fmt.Sprintf("vector %d tampered %d", i, j), messages[i], sig, publicKeys[i], false)
+		}
+		for _, pos := range []int{0, CRYPTO_ED25519_PUBLICKEY_BYTES, CRYPTO_PUBLICKEY_BYTES - 1} {
+			publicKey := append([]byte{}, publicKeys[i]...)
+			publicKey[pos] ^= 0x01
+			add(fmt.Sprintf("vector %d public key byte %d changed", i, pos), messages[i], signatures[i], publicKey, false)
+		}
+		add(fmt.Sprintf("vector %d with the public key of vector %d", i, other), messages[i], signatures[i], publicKeys[other], false)
+		message := append([]byte{}, messages[i]...)
+		message[len(message)-1] ^= 0x01
+		add(fmt.Sprintf("vector %d message changed", i), message, signatures[i], publicKeys[i], false)
+	}
+	return vectors
+}
+
+func loadGeneratedVectors(t *testing.T) (vectors []generatedVector, messages, signatures, publicKeys [][]byte) {
+	data, err := ioutil.ReadFile("testdata/compact_generated_vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectors {
+		message, _ := hex.DecodeString(v.Message)
+		signature, _ := hex.DecodeString(v.Signature)
+		publicKey, _ := hex.DecodeString(v.PublicKey)
+		messages = append(messages, message)
+		signatures = append(signatures, signature)
+		publicKeys = append(publicKeys, publicKey)
+	}
+	return vectors, messages, signatures, publicKeys
+}
+
+func TestVerifyGo_GeneratedVectors(t *testing.T) {
+	if *update {
+		data, err := json.MarshalIndent(generateCompactVectors(t), "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile("testdata/compact_generated_vectors.json", append(data, '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vectors, messages, signatures, publicKeys := loadGeneratedVectors(t)
+	if len(vectors) == 0 {
+		t.Fatal("no vectors")
+	}
+	for i, v := range vectors {
+		if err := verifyGo(messages[i], signatures[i], publicKeys[i]); (err == nil) != v.Valid {
+			t.Errorf("%s: verify returned %v, want valid %v", v.Comment, err, v.Valid)
+		}
+	}
+}
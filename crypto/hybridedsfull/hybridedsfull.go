@@ -1,3 +1,6 @@
+//go:build cgo && !purego
+// +build cgo,!purego
+
 package hybridedsfull
 
 /*
@@ -21,6 +24,9 @@ const (
 	SIG_NAME               = "dilithium-ed25519-sphincs-full"
 )
 
+// VerifySupported reports whether full signatures can be verified by this build.
+const VerifySupported = true
+
 var (
 	ErrSignatureInitial       = errors.New("signature mechanism is not supported by OQS")
 	ErrInvalidMsgLen          = errors.New("invalid message length, need 32 bytes")
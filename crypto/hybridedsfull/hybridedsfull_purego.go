@@ -0,0 +1,87 @@
+//go:build !cgo || purego
+// +build !cgo purego
+
+package hybridedsfull
+
+import (
+	"errors"
+)
+
+// This file lets the full hybrid scheme build without cgo or with the purego build
+// tag. Keys can not be generated and messages can not be signed without libhybridpqc.
+// Full signatures can not be verified either: only libhybridpqc defines how the
+// SPHINCS signature is combined with the ED25519 and Dilithium ones, and there are no
+// vectors of it yet to check a pure Go implementation against. Verify returns
+// ErrCgoRequired for every well formed signature, so that callers can tell a build
+// that can not verify apart from an invalid signature, see VerifySupported. The
+// purego build tag is for tools that only verify compact signatures; the consensus
+// engine, whose packets carry full signatures, does not build with it.
+
+// VerifySupported reports whether full signatures can be verified by this build.
+const VerifySupported = false
+
+const (
+	OK                     = 0
+	CRYPTO_SECRETKEY_BYTES = 64 + 2560 + 1312 + 128
+	CRYPTO_PUBLICKEY_BYTES = 32 + 1312 + 64
+	CRYPTO_MESSAGE_LEN     = 32
+	CRYPTO_SIGNATURE_BYTES = 2 + 64 + 2420 + 49856 + CRYPTO_MESSAGE_LEN
+	SIG_NAME               = "dilithium-ed25519-sphincs-full"
+)
+
+var (
+	ErrSignatureInitial       = errors.New("signature mechanism is not supported by OQS")
+	ErrInvalidMsgLen          = errors.New("invalid message length, need 32 bytes")
+	ErrInvalidSignatureLen    = errors.New("invalid signature length")
+	ErrInvalidPublicKeyLen    = errors.New("invalid public key length")
+	ErrInvalidPrivateKeyLen   = errors.New("invalid private key length")
+	ErrInvalidRecoveryID      = errors.New("invalid signature recovery id")
+	ErrInvalidKey             = errors.New("invalid private key")
+	ErrInvalidPubkey          = errors.New("invalid public key")
+	ErrMismatchPublicKey      = errors.New("mismatch public key")
+	ErrSignFailed             = errors.New("signing failed")
+	ErrRecoverFailed          = errors.New("recovery failed")
+	ErrKeypairFailed          = errors.New("can not generate keypair")
+	ErrInvalidLen             = errors.New("invalid length")
+	ErrVerifyFailed           = errors.New("verify failed")
+	ErrRecoverPublicKeyFailed = errors.New("recover public key length")
+	ErrCgoRequired            = errors.New("full hybrid signatures require libhybridpqc, build with cgo and without the purego tag")
+)
+
+func GenerateKey() (publicKey []byte, secretKey []byte, err error) {
+	return nil, nil, ErrCgoRequired
+}
+
+func Sign(secretKey []byte, message []byte) ([]byte, error) {
+	return nil, ErrCgoRequired
+}
+
+// Verify verifies the validity of a signed message, returning true if the
+// signature is valid, and false otherwise.
+func Verify(message []byte, signature []byte, publicKey []byte) error {
+	if len(message) != CRYPTO_MESSAGE_LEN || len(signature) == 0 || len(publicKey) == 0 {
+		return ErrInvalidLen
+	}
+	if len(publicKey) != CRYPTO_PUBLICKEY_BYTES {
+		return ErrInvalidPublicKeyLen
+	}
+	if len(signature) != CRYPTO_SIGNATURE_BYTES {
+		return ErrInvalidSignatureLen
+	}
+
+	return ErrCgoRequired
+}
+
+func PrivateAndPublicFromPrivateKey(compositePrivateKey []byte) (privateBytes []byte, publicBytes []byte, err error) {
+
+	if len(compositePrivateKey) != CRYPTO_SECRETKEY_BYTES {
+		return nil, nil, ErrInvalidPrivateKeyLen
+	}
+
+	pubKeyBytes := make([]byte, 0, CRYPTO_PUBLICKEY_BYTES)
+	pubKeyBytes = append(pubKeyBytes, compositePrivateKey[32:64]...)
+	pubKeyBytes = append(pubKeyBytes, compositePrivateKey[64+2560:64+2560+1312]...)
+	pubKeyBytes = append(pubKeyBytes, compositePrivateKey[64+2560+1312+64:]...)
+
+	return compositePrivateKey, pubKeyBytes, nil
+}
@@ -0,0 +1,32 @@
+//go:build !cgo || purego
+// +build !cgo purego
+
+package hybridedsfull
+
+import (
+	"testing"
+)
+
+// Full signatures can not be verified without libhybridpqc, which has to be told
+// apart from an invalid signature.
+func TestVerify_CgoRequired(t *testing.T) {
+	if VerifySupported {
+		t.Fatal("full verification reported as supported")
+	}
+	message := make([]byte, CRYPTO_MESSAGE_LEN)
+	signature := make([]byte, CRYPTO_SIGNATURE_BYTES)
+	publicKey := make([]byte, CRYPTO_PUBLICKEY_BYTES)
+	if err := Verify(message, signature, publicKey); err != ErrCgoRequired {
+		t.Fatalf("got %v, want %v", err, ErrCgoRequired)
+	}
+
+	if err := Verify(message[1:], signature, publicKey); err != ErrInvalidLen {
+		t.Fatalf("got %v, want %v", err, ErrInvalidLen)
+	}
+	if err := Verify(message, signature, publicKey[1:]); err != ErrInvalidPublicKeyLen {
+		t.Fatalf("got %v, want %v", err, ErrInvalidPublicKeyLen)
+	}
+	if err := Verify(message, signature[1:], publicKey); err != ErrInvalidSignatureLen {
+		t.Fatalf("got %v, want %v", err, ErrInvalidSignatureLen)
+	}
+}
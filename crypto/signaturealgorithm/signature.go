@@ -21,6 +21,8 @@ type SignatureAlgorithm interface {
 	SignatureLength() int
 	SignatureWithPublicKeyLength() int
 	PublicKeyStartValue() byte
+	// SignatureStartValue is the first byte of the signatures of the algorithm, which
+	// identifies the algorithm of a signature.
 	SignatureStartValue() byte
 
 	GenerateKey() (*PrivateKey, error)
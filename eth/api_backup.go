@@ -0,0 +1,203 @@
+package eth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DogeProtocol/dp/backupmanager"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/core"
+	"github.com/DogeProtocol/dp/core/rawdb"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/internal/ethapi"
+)
+
+// The statuses of a backed up transaction.
+const (
+	BackupTxStatusMined     = "mined"
+	BackupTxStatusPending   = "pending"
+	BackupTxStatusQueued    = "queued"
+	BackupTxStatusReplaced  = "replaced"
+	BackupTxStatusDiscarded = "discarded"
+)
+
+const (
+	// defaultBackupListLimit and maxBackupListLimit are the default and the maximum
+	// number of transactions returned by backup_listTransactionsByAddress.
+	defaultBackupListLimit = 100
+	maxBackupListLimit     = 1000
+)
+
+var errBackupsDisabled = errors.New("backups are not enabled, start the node with --enablebackup")
+
+// BackupTransaction is a backed up transaction, along with what happened to it.
+type BackupTransaction struct {
+	*ethapi.RPCTransaction
+
+	// Status is mined if the transaction is in the chain, pending or queued if it is in
+	// the pool, replaced if another transaction with its nonce was mined, or discarded if
+	// it was dropped from the pool and can still be executed.
+	Status string `json:"status"`
+
+	// FirstSeen is the time the transaction was first backed up, if it is known.
+	FirstSeen *hexutil.Uint64 `json:"firstSeen,omitempty"`
+
+	// ReplacedBy is the hash of the mined transaction that replaced a replaced
+	// transaction, if it was backed up.
+	ReplacedBy *common.Hash `json:"replacedBy,omitempty"`
+}
+
+// BackupTransactionList is a page of the backed up transactions of an address.
+type BackupTransactionList struct {
+	Transactions []*BackupTransaction `json:"transactions"`
+
+	// Cursor lists the next page, it is omitted on the last page.
+	Cursor *hexutil.Bytes `json:"cursor,omitempty"`
+}
+
+// PrivateBackupAPI provides an API to look up the transactions and blocks backed up by
+// the node, including the transactions that are no longer in the pool or the chain.
+type PrivateBackupAPI struct {
+	e *Ethereum
+}
+
+// NewPrivateBackupAPI creates a new backup API. The node must be started with backups
+// enabled for its methods to succeed.
+func NewPrivateBackupAPI(e *Ethereum) *PrivateBackupAPI {
+	return &PrivateBackupAPI{e: e}
+}
+
+func (api *PrivateBackupAPI) backup() (*backupmanager.BackupManager, error) {
+	bm := backupmanager.GetInstance()
+	if bm == nil {
+		return nil, errBackupsDisabled
+	}
+	return bm, nil
+}
+
+// GetTransaction returns the backed up transaction with the given hash and what happened
+// to it, or nil if it was not backed up.
+func (api *PrivateBackupAPI) GetTransaction(ctx context.Context, hash common.Hash) (*BackupTransaction, error) {
+	bm, err := api.backup()
+	if err != nil {
+		return nil, err
+	}
+	tx, firstSeen, err := bm.GetTransaction(hash)
+	if err == backupmanager.ErrTransactionNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return api.newBackupTransaction(bm, tx, firstSeen)
+}
+
+// ListTransactionsByAddress returns the backed up transactions sent from or to an
+// address and what happened to them, the most recently backed up first. limit defaults
+// to 100 and is at most 1000. The next page is listed by passing the returned cursor,
+// which is omitted on the last page.
+func (api *PrivateBackupAPI) ListTransactionsByAddress(ctx context.Context, address common.Address, limit *hexutil.Uint64, cursor *hexutil.Bytes) (*BackupTransactionList, error) {
+	bm, err := api.backup()
+	if err != nil {
+		return nil, err
+	}
+	n := uint64(defaultBackupListLimit)
+	if limit != nil {
+		n = uint64(*limit)
+	}
+	if n > maxBackupListLimit {
+		n = maxBackupListLimit
+	}
+	var start []byte
+	if cursor != nil {
+		start = *cursor
+	}
+
+	txs, next, err := bm.TransactionsByAddress(address, start, int(n))
+	if err != nil {
+		return nil, err
+	}
+	results := &BackupTransactionList{Transactions: make([]*BackupTransaction, 0, len(txs))}
+	for _, tx := range txs {
+		result, err := api.newBackupTransaction(bm, tx.Transaction, tx.FirstSeen)
+		if err != nil {
+			return nil, err
+		}
+		results.Transactions = append(results.Transactions, result)
+	}
+	if len(next) > 0 {
+		results.Cursor = (*hexutil.Bytes)(&next)
+	}
+	return results, nil
+}
+
+// GetBlockByNumber returns the backed up block with the given number, from the backup
+// stores or its segments, or nil if it was not backed up. If fullTx is true all
+// transactions in the block are returned, otherwise only their hashes.
+func (api *PrivateBackupAPI) GetBlockByNumber(ctx context.Context, number hexutil.Uint64, fullTx bool) (map[string]interface{}, error) {
+	bm, err := api.backup()
+	if err != nil {
+		return nil, err
+	}
+	block, err := bm.GetBlockByNumber(uint64(number))
+	if errors.Is(err, backupmanager.ErrBlockNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ethapi.RPCMarshalBlock(block, true, fullTx)
+}
+
+// newBackupTransaction finds out what happened to a backed up transaction: whether it is
+// in the chain or in the pool, or else whether another transaction with its nonce was mined.
+func (api *PrivateBackupAPI) newBackupTransaction(bm *backupmanager.BackupManager, tx *types.Transaction, firstSeen uint64) (*BackupTransaction, error) {
+	result := &BackupTransaction{}
+	if firstSeen != 0 {
+		result.FirstSeen = (*hexutil.Uint64)(&firstSeen)
+	}
+
+	if _, blockHash, blockNumber, index := rawdb.ReadTransaction(api.e.ChainDb(), tx.Hash()); blockHash != (common.Hash{}) {
+		result.RPCTransaction = ethapi.NewRPCTransaction(tx, blockHash, blockNumber, index)
+		result.Status = BackupTxStatusMined
+		return result, nil
+	}
+	result.RPCTransaction = ethapi.NewRPCTransaction(tx, common.Hash{}, 0, 0)
+
+	switch api.e.TxPool().Status([]common.Hash{tx.Hash()})[0] {
+	case core.TxStatusPending:
+		result.Status = BackupTxStatusPending
+		return result, nil
+	case core.TxStatusQueued:
+		result.Status = BackupTxStatusQueued
+		return result, nil
+	}
+
+	statedb, err := api.e.BlockChain().State()
+	if err != nil {
+		return nil, err
+	}
+	from := result.RPCTransaction.From
+	if statedb.GetNonce(from) <= tx.Nonce() {
+		result.Status = BackupTxStatusDiscarded
+		return result, nil
+	}
+
+	result.Status = BackupTxStatusReplaced
+	others, err := bm.TransactionsByNonce(from, tx.Nonce())
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range others {
+		if other.Hash() == tx.Hash() {
+			continue
+		}
+		if _, blockHash, _, _ := rawdb.ReadTransaction(api.e.ChainDb(), other.Hash()); blockHash != (common.Hash{}) {
+			hash := other.Hash()
+			result.ReplacedBy = &hash
+			break
+		}
+	}
+	return result, nil
+}
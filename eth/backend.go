@@ -87,6 +87,9 @@ type Ethereum struct {
 	gasPrice  *big.Int
 	etherbase common.Address
 
+	externalSigner string                     // Endpoint of the external signer of the validator, if any
+	remoteSigner   *proofofstake.RemoteSigner // Connection to the external signer, once mining started
+
 	networkID     uint64
 	netRPCService *ethapi.PublicNetAPI
 
@@ -139,6 +142,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		networkID:         config.NetworkId,
 		gasPrice:          config.Miner.GasPrice,
 		etherbase:         config.Miner.Etherbase,
+		externalSigner:    stack.Config().ExternalSigner,
 		bloomRequests:     make(chan chan *bloombits.Retrieval),
 		bloomIndexer:      core.NewBloomIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms),
 		p2pServer:         stack.Server(),
@@ -229,6 +233,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		eng.SetP2PHandler(eth.handler, eth.p2pServer.GetLocalPeerId())
 		var consensusHandler handler.ConsensusHandler = eng.GetConsensusPacketHandler()
 		eth.handler.SetConsensusHandler(consensusHandler)
+		eng.SetPacketSignatureCacheSize(config.ConsensusSignatureCache)
 		eng.SetBlockchain(eth.blockchain)
 	}
 
@@ -351,6 +356,10 @@ func (s *Ethereum) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   s.netRPCService,
 			Public:    true,
+		}, {
+			Namespace: "backup",
+			Version:   "1.0",
+			Service:   NewPrivateBackupAPI(s),
 		},
 	}...)
 }
@@ -475,15 +484,29 @@ func (s *Ethereum) StartMining(threads int) error {
 			return fmt.Errorf("etherbase missing: %v", err)
 		}
 
-		if proofofstake, ok := s.engine.(*proofofstake.ProofOfStake); ok {
+		if pos, ok := s.engine.(*proofofstake.ProofOfStake); ok {
 			account := accounts.Account{Address: eb}
-			wallet, err := s.accountManager.Find(account)
-			if wallet == nil || err != nil {
-				log.Error("Etherbase account unavailable locally", "err", err)
-				return fmt.Errorf("signer missing: %v", err)
+			if s.externalSigner != "" {
+				if s.remoteSigner == nil {
+					remoteSigner, err := proofofstake.NewRemoteSigner(s.externalSigner)
+					if err != nil {
+						log.Error("Cannot connect to external signer", "endpoint", s.externalSigner, "err", err)
+						return fmt.Errorf("signer missing: %v", err)
+					}
+					s.remoteSigner = remoteSigner
+				}
+				log.Info("Signing consensus packets with external signer", "endpoint", s.externalSigner, "validator", eb)
+				// The engine does not sign transactions
+				pos.Authorize(eb, s.remoteSigner.SignData, s.remoteSigner.SignDataWithContext, nil, account)
+			} else {
+				wallet, err := s.accountManager.Find(account)
+				if wallet == nil || err != nil {
+					log.Error("Etherbase account unavailable locally", "err", err)
+					return fmt.Errorf("signer missing: %v", err)
+				}
+
+				pos.Authorize(eb, wallet.SignData, wallet.SignDataWithContext, wallet.SignTx, account)
 			}
-
-			proofofstake.Authorize(eb, wallet.SignData, wallet.SignDataWithContext, wallet.SignTx, account)
 		}
 		// If mining is started, we can disable the transaction rejection mechanism
 		// introduced to speed sync times.
@@ -566,6 +589,9 @@ func (s *Ethereum) Stop() error {
 	s.miner.Stop()
 	s.blockchain.Stop()
 	s.engine.Close()
+	if s.remoteSigner != nil {
+		s.remoteSigner.Close()
+	}
 	rawdb.PopUncleanShutdownMarker(s.chainDb)
 	s.chainDb.Close()
 	s.eventMux.Stop()
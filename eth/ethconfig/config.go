@@ -71,6 +71,7 @@ var Defaults = Config{
 	TrieDirtyCache:          256,
 	TrieTimeout:             60 * time.Minute,
 	SnapshotCache:           102,
+	ConsensusSignatureCache: proofofstake.DefaultPacketSignatureCacheSize,
 	Miner: miner.Config{
 		GasFloor: 8000000,
 		GasCeil:  8000000,
@@ -157,6 +158,9 @@ type Config struct {
 	SnapshotCache           int
 	Preimages               bool
 
+	// Consensus options
+	ConsensusSignatureCache int // Number of consensus packet signature verifications kept in memory
+
 	// Mining options
 	Miner miner.Config
 
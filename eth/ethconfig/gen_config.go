@@ -47,6 +47,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TrieTimeout             time.Duration
 		SnapshotCache           int
 		Preimages               bool
+		ConsensusSignatureCache int
 		Miner                   miner.Config
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
@@ -89,6 +90,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TrieTimeout = c.TrieTimeout
 	enc.SnapshotCache = c.SnapshotCache
 	enc.Preimages = c.Preimages
+	enc.ConsensusSignatureCache = c.ConsensusSignatureCache
 	enc.Miner = c.Miner
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
@@ -135,6 +137,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TrieTimeout             *time.Duration
 		SnapshotCache           *int
 		Preimages               *bool
+		ConsensusSignatureCache *int
 		Miner                   *miner.Config
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
@@ -240,6 +243,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.Preimages != nil {
 		c.Preimages = *dec.Preimages
 	}
+	if dec.ConsensusSignatureCache != nil {
+		c.ConsensusSignatureCache = *dec.ConsensusSignatureCache
+	}
 	if dec.Miner != nil {
 		c.Miner = *dec.Miner
 	}
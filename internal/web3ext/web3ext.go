@@ -19,6 +19,7 @@ package web3ext
 
 var Modules = map[string]string{
 	"admin":        AdminJs,
+	"backup":       BackupJs,
 	"proofofstake": ProofOfStakeJs,
 	"debug":        DebugJs,
 	"eth":          EthJs,
@@ -31,6 +32,31 @@ var Modules = map[string]string{
 	"vflux":        VfluxJs,
 }
 
+const BackupJs = `
+web3._extend({
+	property: 'backup',
+	methods: [
+		new web3._extend.Method({
+			name: 'getTransaction',
+			call: 'backup_getTransaction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'listTransactionsByAddress',
+			call: 'backup_listTransactionsByAddress',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getBlockByNumber',
+			call: 'backup_getBlockByNumber',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, null]
+		}),
+	]
+});
+`
+
 const ProofOfStakeJs = `
 web3._extend({
 	property: 'proofofstake',
@@ -70,6 +96,22 @@ web3._extend({
 			call: 'proofofstake_getBlockConsensusContext',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getRewardSchedule',
+			call: 'proofofstake_getRewardSchedule',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getValidatorPerformance',
+			call: 'proofofstake_getValidatorPerformance',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getFinalityCertificate',
+			call: 'proofofstake_getFinalityCertificate',
+			params: 1
+		}),
 	]
 });
 `
@@ -26,6 +26,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/DogeProtocol/dp/accounts"
 	"github.com/DogeProtocol/dp/accounts/keystore"
@@ -194,6 +195,25 @@ type Config struct {
 
 	EnableBackups bool
 
+	// BackupRetentionBlocks is the number of most recent blocks kept in the backup
+	// stores, 0 keeps all of them.
+	BackupRetentionBlocks uint64 `toml:",omitempty"`
+
+	// BackupRetentionAge is the maximum age of the blocks kept in the backup stores, 0
+	// keeps all of them.
+	BackupRetentionAge time.Duration `toml:",omitempty"`
+
+	// BackupSegmentBlocks is the number of blocks per backup segment, 0 disables the
+	// sealing of backed up blocks into segments.
+	BackupSegmentBlocks uint64 `toml:",omitempty"`
+
+	// BackupSinkDir is a directory that backup segments are copied to.
+	BackupSinkDir string `toml:",omitempty"`
+
+	// BackupTrustedSigners are the addresses whose backup segments are trusted, besides
+	// the node key that the segments of the node are signed with.
+	BackupTrustedSigners []string `toml:",omitempty"`
+
 	RebroadcastCount int
 
 	ProfPort int `toml:",omitempty"`
@@ -8,8 +8,6 @@ import (
 	"errors"
 	"github.com/DogeProtocol/dp/crypto"
 	"github.com/DogeProtocol/dp/crypto/cryptobase"
-	"github.com/DogeProtocol/dp/crypto/keyestablishmentalgorithm"
-	"github.com/DogeProtocol/dp/crypto/oqs"
 	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
 	"github.com/DogeProtocol/dp/log"
 	"github.com/DogeProtocol/dp/rlp"
@@ -18,12 +16,20 @@ import (
 )
 
 type clientHelloMessage struct {
-	ClientKemPublicKey    []byte //kemPublicKeyLen
+	ClientKemPublicKey    []byte //Kyber512 public key, the key share of the legacy handshake
 	ClientHelloRandomData [shaLen]byte
 	Version               uint
+	KemKeyShares          []kemKeyShare  `rlp:"optional"` //Key shares of the other offered mechanisms, strongest first
 	Rest                  []rlp.RawValue `rlp:"tail"`
 }
 
+// kemKeyShare is the ephemeral public key of an offered key encapsulation mechanism.
+type kemKeyShare struct {
+	Kem       string
+	PublicKey []byte
+	Rest      []rlp.RawValue `rlp:"tail"`
+}
+
 type clientVerifyMessage struct {
 	Signature    []byte //SignPublicKeyLen
 	SignatureLen uint
@@ -31,8 +37,9 @@ type clientVerifyMessage struct {
 }
 
 type Client struct {
-	ephemeralKemPrivateKey  *keyestablishmentalgorithm.PrivateKey
-	kem                     *oqs.KeyEncapsulation
+	kems                    []string
+	kemShares               map[string]*handshakeKem
+	kem                     *handshakeKem
 	kemCipherText           []byte //kemCipherTextLength
 	kemSharedSecret         []byte //kemSecretLength
 	Nonce                   uint
@@ -79,6 +86,7 @@ func NewClient(conn io.ReadWriter, clientSigningPrivateKey *signaturealgorithm.P
 		conn:                    conn,
 		clientSigningPrivateKey: clientSigningPrivateKey,
 		serverSigningPublicKey:  serverSigningPublicKey,
+		kems:                    SupportedKems(),
 	}
 
 	client.serializer = NewRlpxSerializer()
@@ -101,6 +109,20 @@ func (c *Client) SetServerSigningPublicKey(serverSigningPublicKey *signaturealgo
 	c.serverSigningPublicKey = serverSigningPublicKey
 }
 
+// SetKems sets the key encapsulation mechanisms offered to the server. Offering
+// only Kyber512 makes for the legacy handshake.
+func (c *Client) SetKems(kems []string) {
+	c.kems = kems
+}
+
+// Kem returns the key encapsulation mechanism negotiated by the handshake.
+func (c *Client) Kem() string {
+	if c.kem == nil {
+		return ""
+	}
+	return c.kem.name
+}
+
 func (c *Client) PerformHandshake() error {
 
 	c.mutex.Lock()
@@ -110,17 +132,8 @@ func (c *Client) PerformHandshake() error {
 		return errors.New("Handshake already done")
 	}
 
-	//Initialize KEM
-	kem := oqs.KeyEncapsulation{}
-
-	err := kem.Init(oqs.KemName, nil)
-	if err != nil {
-		return err
-	}
-	c.kem = &kem
-
 	//Make client hello message
-	err = c.makeClientHello()
+	err := c.makeClientHello()
 	if err != nil {
 		return err
 	}
@@ -257,18 +270,35 @@ func (c *Client) makeClientHello() error {
 	clientHelloMessage := new(clientHelloMessage)
 	clientHelloMessage.Version = 1
 
-	//Generate an ephemeral kem keypair
-	kemPrivateKey, err := c.kem.GenerateKemKeyPair()
-	if err != nil {
-		return err
+	//Generate an ephemeral kem keypair for each offered mechanism
+	c.kemShares = make(map[string]*handshakeKem, len(c.kems))
+	for _, name := range kemPreference {
+		if containsKem(c.kems, name) == false {
+			continue
+		}
+		kem, err := newHandshakeKem(name)
+		if err != nil {
+			return err
+		}
+		c.kemShares[name] = kem
+
+		publicKey, err := kem.generateKeyPair()
+		if err != nil {
+			return err
+		}
+		if name == KemKyber512 {
+			clientHelloMessage.ClientKemPublicKey = publicKey
+		} else {
+			clientHelloMessage.KemKeyShares = append(clientHelloMessage.KemKeyShares, kemKeyShare{Kem: name, PublicKey: publicKey})
+		}
+	}
+	if len(c.kemShares) == 0 {
+		return ErrNoCommonKem
 	}
-	c.ephemeralKemPrivateKey = kemPrivateKey
-	clientHelloMessage.ClientKemPublicKey = make([]byte, c.kem.AlgDetails.LengthPublicKey)
-	copy(clientHelloMessage.ClientKemPublicKey[:], c.ephemeralKemPrivateKey.N.Bytes())
 
 	// Generate ClientRandomData
 	randomData := make([]byte, shaLength)
-	_, err = rand.Read(randomData)
+	_, err := rand.Read(randomData)
 	if err != nil {
 		return err
 	}
@@ -280,19 +310,39 @@ func (c *Client) makeClientHello() error {
 }
 
 func (c *Client) Cleanup() {
-	if c.kem != nil {
-		c.kem.Clean()
+	for name, kem := range c.kemShares {
+		kem.clean()
+		delete(c.kemShares, name)
 	}
 }
 
 func (c *Client) handleServerHello() error {
 
-	sharedSecret, err := c.kem.DecapsulateSecret(c.serverHelloMessage.CipherText[:])
+	//Servers of the legacy handshake do not name the mechanism, it is Kyber512
+	name := c.serverHelloMessage.Kem
+	if name == "" {
+		name = KemKyber512
+	}
+	kem, ok := c.kemShares[name]
+	if ok == false {
+		return ErrUnexpectedKem
+	}
+	c.kem = kem
+
+	//The key pairs of the other mechanisms are no longer needed
+	for other, kem := range c.kemShares {
+		if other != name {
+			kem.clean()
+			delete(c.kemShares, other)
+		}
+	}
+
+	sharedSecret, err := c.kem.decapsulate(c.serverHelloMessage.CipherText[:])
 	if err != nil {
 		return err
 	}
 
-	c.kemSharedSecret = make([]byte, c.kem.AlgDetails.LengthSharedSecret)
+	c.kemSharedSecret = make([]byte, len(sharedSecret))
 	copy(c.kemSharedSecret[:], sharedSecret[:])
 
 	return nil
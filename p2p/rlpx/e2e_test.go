@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"github.com/DogeProtocol/dp/crypto/cryptobase"
 	"github.com/DogeProtocol/dp/p2p/pipes"
+	"io"
 	"math/rand"
+	"net"
 	"testing"
 	"time"
 )
@@ -267,3 +269,165 @@ func Test_e2eHandshake(t *testing.T) {
 		}
 	}
 }
+
+// kemHandshake performs a handshake between a client and a server offering the
+// given key encapsulation mechanisms. If tamper is set, the client hello is passed
+// through it before reaching the server.
+func kemHandshake(t *testing.T, clientKems, serverKems []string, tamper func(*clientHelloMessage)) (*Client, *Server, error, error) {
+	waitTime := 5 * time.Second
+	clientConn, serverConn, err := pipes.TCPPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	var serverSide net.Conn = serverConn
+	if tamper != nil {
+		proxyConn, proxiedConn, err := pipes.TCPPipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer proxyConn.Close()
+		defer proxiedConn.Close()
+		serverSide = proxiedConn
+
+		go func() {
+			serializer := NewRlpxSerializer()
+			hello := new(clientHelloMessage)
+			if _, err := serializer.Deserialize(hello, serverConn); err != nil {
+				return
+			}
+			tamper(hello)
+			packet, err := serializer.Serialize(hello)
+			if err != nil {
+				return
+			}
+			if _, err := proxyConn.Write(packet); err != nil {
+				return
+			}
+			go io.Copy(serverConn, proxyConn)
+			io.Copy(proxyConn, serverConn)
+			proxyConn.Close()
+		}()
+	}
+
+	if err := clientConn.SetDeadline(time.Now().Add(waitTime)); err != nil {
+		t.Fatal(err)
+	}
+	if err := serverSide.SetDeadline(time.Now().Add(waitTime)); err != nil {
+		t.Fatal(err)
+	}
+
+	serverSigningKey, err := cryptobase.SigAlg.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(serverSide, serverSigningKey, "test")
+	server.SetKems(serverKems)
+
+	clientKey, err := cryptobase.SigAlg.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(clientConn, clientKey, &serverSigningKey.PublicKey, "test")
+	client.SetKems(clientKems)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		err := server.PerformHandshake()
+		if err != nil {
+			serverSide.Close()
+		}
+		serverErr <- err
+	}()
+
+	clientErr := client.PerformHandshake()
+	if clientErr != nil {
+		clientConn.Close()
+	}
+	return client, server, clientErr, <-serverErr
+}
+
+func Test_HandshakeKemNegotiation(t *testing.T) {
+	supported := SupportedKems()
+	legacy := []string{KemKyber512}
+
+	tests := []struct {
+		name       string
+		clientKems []string
+		serverKems []string
+		want       string
+	}{
+		{"both current", supported, supported, supported[0]},
+		{"legacy client", legacy, supported, KemKyber512},
+		{"legacy server", supported, legacy, KemKyber512},
+		{"both legacy", legacy, legacy, KemKyber512},
+	}
+	if containsKem(supported, KemKyber768) {
+		tests = append(tests, struct {
+			name       string
+			clientKems []string
+			serverKems []string
+			want       string
+		}{"kyber768 server", supported, []string{KemKyber768, KemKyber512}, KemKyber768})
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, server, clientErr, serverErr := kemHandshake(t, test.clientKems, test.serverKems, nil)
+			defer client.Cleanup()
+			defer server.Cleanup()
+			if clientErr != nil {
+				t.Fatal(clientErr)
+			}
+			if serverErr != nil {
+				t.Fatal(serverErr)
+			}
+			if client.Kem() != test.want || server.Kem() != test.want {
+				t.Fatalf("kem mismatch: client %q, server %q, want %q", client.Kem(), server.Kem(), test.want)
+			}
+			if !bytes.Equal(client.kemSharedSecret, server.kemSharedSecret) {
+				t.Fatal("shared secret mismatch")
+			}
+			if !bytes.Equal(client.secret.ClientApplicationKey, server.secret.ClientApplicationKey) {
+				t.Fatal("application key mismatch")
+			}
+		})
+	}
+}
+
+func Test_HandshakeNoCommonKem(t *testing.T) {
+	supported := SupportedKems()
+	if len(supported) < 2 {
+		t.Skip("only Kyber512 is enabled")
+	}
+	client, server, clientErr, serverErr := kemHandshake(t, []string{KemKyber512}, supported[:1], nil)
+	defer client.Cleanup()
+	defer server.Cleanup()
+	if serverErr != ErrNoCommonKem {
+		t.Fatalf("server error mismatch: got %v, want %v", serverErr, ErrNoCommonKem)
+	}
+	if clientErr == nil {
+		t.Fatal("client handshake succeeded")
+	}
+}
+
+func Test_HandshakeKemDowngrade(t *testing.T) {
+	if len(SupportedKems()) < 2 {
+		t.Skip("only Kyber512 is enabled")
+	}
+	// Stripping the key shares from the client hello makes the server fall back to
+	// Kyber512, the transcripts then differ and the client rejects the server.
+	client, server, clientErr, _ := kemHandshake(t, SupportedKems(), SupportedKems(), func(hello *clientHelloMessage) {
+		hello.KemKeyShares = nil
+	})
+	defer client.Cleanup()
+	defer server.Cleanup()
+	if server.Kem() != KemKyber512 {
+		t.Fatalf("server kem mismatch: got %q, want %q", server.Kem(), KemKyber512)
+	}
+	if clientErr == nil {
+		t.Fatal("client accepted the downgraded handshake")
+	}
+}
@@ -0,0 +1,226 @@
+package rlpx
+
+import (
+	"crypto/rand"
+	"errors"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/oqs"
+	"golang.org/x/crypto/curve25519"
+)
+
+// Key encapsulation mechanisms that the session secret can be negotiated with.
+const (
+	KemKyber512       = oqs.KemName
+	KemKyber768       = "Kyber768"
+	KemMLKEM768       = "ML-KEM-768"
+	KemX25519Kyber768 = "X25519-Kyber768"
+
+	x25519KeyLen = 32
+)
+
+// kemPreference lists the key encapsulation mechanisms from the strongest to the
+// weakest. Kyber512 is the mechanism of the legacy handshake, its key share is
+// carried in the ClientKemPublicKey field of the client hello.
+var kemPreference = []string{
+	KemX25519Kyber768,
+	KemMLKEM768,
+	KemKyber768,
+	KemKyber512,
+}
+
+// kemAlgorithms maps the mechanisms to their OQS algorithm, and whether it is
+// combined with an X25519 exchange.
+var kemAlgorithms = map[string]struct {
+	oqsName string
+	hybrid  bool
+}{
+	KemX25519Kyber768: {oqsName: KemKyber768, hybrid: true},
+	KemMLKEM768:       {oqsName: KemMLKEM768},
+	KemKyber768:       {oqsName: KemKyber768},
+	KemKyber512:       {oqsName: KemKyber512},
+}
+
+var (
+	ErrUnknownKem      = errors.New("unknown key encapsulation mechanism")
+	ErrNoCommonKem     = errors.New("no mutually supported key encapsulation mechanism")
+	ErrUnexpectedKem   = errors.New("server selected a key encapsulation mechanism that was not offered")
+	ErrInvalidKeyShare = errors.New("invalid key share")
+)
+
+// SupportedKems returns the key encapsulation mechanisms enabled in the OQS
+// library, from the strongest to the weakest.
+func SupportedKems() []string {
+	kems := make([]string, 0, len(kemPreference))
+	for _, name := range kemPreference {
+		if oqs.IsKEMEnabled(kemAlgorithms[name].oqsName) {
+			kems = append(kems, name)
+		}
+	}
+	return kems
+}
+
+// selectKem returns the strongest of the mechanisms in local that the peer
+// offered. The preference is the same for all nodes, so both sides of a
+// connection agree on the strongest mechanism.
+func selectKem(local []string, offered []string) (string, error) {
+	for _, name := range kemPreference {
+		if containsKem(local, name) && containsKem(offered, name) {
+			return name, nil
+		}
+	}
+	return "", ErrNoCommonKem
+}
+
+func containsKem(kems []string, name string) bool {
+	for _, kem := range kems {
+		if kem == name {
+			return true
+		}
+	}
+	return false
+}
+
+// handshakeKem is a key encapsulation mechanism of the handshake. The hybrid
+// mechanisms combine an X25519 exchange with the OQS one, so that the session
+// secret stays safe as long as either of them is unbroken.
+type handshakeKem struct {
+	name   string
+	kem    *oqs.KeyEncapsulation
+	hybrid bool
+
+	x25519PrivateKey []byte
+}
+
+func newHandshakeKem(name string) (*handshakeKem, error) {
+	alg, ok := kemAlgorithms[name]
+	if ok == false {
+		return nil, ErrUnknownKem
+	}
+
+	kem := oqs.KeyEncapsulation{}
+	err := kem.Init(alg.oqsName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &handshakeKem{
+		name:   name,
+		kem:    &kem,
+		hybrid: alg.hybrid,
+	}, nil
+}
+
+func (k *handshakeKem) publicKeyLength() int {
+	if k.hybrid {
+		return x25519KeyLen + k.kem.AlgDetails.LengthPublicKey
+	}
+	return k.kem.AlgDetails.LengthPublicKey
+}
+
+func (k *handshakeKem) ciphertextLength() int {
+	if k.hybrid {
+		return x25519KeyLen + k.kem.AlgDetails.LengthCiphertext
+	}
+	return k.kem.AlgDetails.LengthCiphertext
+}
+
+// generateKeyPair generates an ephemeral key pair and returns its public key.
+// The private key is kept to decapsulate the secret.
+func (k *handshakeKem) generateKeyPair() ([]byte, error) {
+	kemPrivateKey, err := k.kem.GenerateKemKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	kemPublicKey := kemPrivateKey.N.FillBytes(make([]byte, k.kem.AlgDetails.LengthPublicKey))
+	if k.hybrid == false {
+		return kemPublicKey, nil
+	}
+
+	k.x25519PrivateKey = make([]byte, x25519KeyLen)
+	if _, err = rand.Read(k.x25519PrivateKey); err != nil {
+		return nil, err
+	}
+	x25519PublicKey, err := curve25519.X25519(k.x25519PrivateKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	return append(x25519PublicKey, kemPublicKey...), nil
+}
+
+// encapsulate encapsulates a secret to the public key of the peer.
+func (k *handshakeKem) encapsulate(publicKey []byte) (ciphertext, sharedSecret []byte, err error) {
+	if len(publicKey) != k.publicKeyLength() {
+		return nil, nil, oqs.ErrInvalidKemPublicKeyLen
+	}
+	if k.hybrid == false {
+		return k.kem.EncapsulateSecret(publicKey)
+	}
+
+	x25519PublicKey := publicKey[:x25519KeyLen]
+	ephemeralKey := make([]byte, x25519KeyLen)
+	if _, err = rand.Read(ephemeralKey); err != nil {
+		return nil, nil, err
+	}
+	x25519Ciphertext, err := curve25519.X25519(ephemeralKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	x25519Secret, err := curve25519.X25519(ephemeralKey, x25519PublicKey)
+	oqs.MemCleanse(ephemeralKey)
+	if err != nil {
+		return nil, nil, oqs.ErrEncapsulate
+	}
+
+	kemCiphertext, kemSecret, err := k.kem.EncapsulateSecret(publicKey[x25519KeyLen:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sharedSecret = combineKemSecrets(kemSecret, x25519Secret, x25519Ciphertext, x25519PublicKey)
+	return append(x25519Ciphertext, kemCiphertext...), sharedSecret, nil
+}
+
+// decapsulate decapsulates the secret of a ciphertext with the private key
+// of generateKeyPair.
+func (k *handshakeKem) decapsulate(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) != k.ciphertextLength() {
+		return nil, oqs.ErrInvalidKemCiphertextLen
+	}
+	if k.hybrid == false {
+		return k.kem.DecapsulateSecret(ciphertext)
+	}
+
+	if len(k.x25519PrivateKey) != x25519KeyLen {
+		return nil, oqs.ErrInvalidKemPrivateKeyLen
+	}
+	x25519Ciphertext := ciphertext[:x25519KeyLen]
+	x25519Secret, err := curve25519.X25519(k.x25519PrivateKey, x25519Ciphertext)
+	if err != nil {
+		return nil, oqs.ErrDecapsulate
+	}
+	x25519PublicKey, err := curve25519.X25519(k.x25519PrivateKey, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+
+	kemSecret, err := k.kem.DecapsulateSecret(ciphertext[x25519KeyLen:])
+	if err != nil {
+		return nil, err
+	}
+
+	return combineKemSecrets(kemSecret, x25519Secret, x25519Ciphertext, x25519PublicKey), nil
+}
+
+func (k *handshakeKem) clean() {
+	if len(k.x25519PrivateKey) > 0 {
+		oqs.MemCleanse(k.x25519PrivateKey)
+	}
+	k.kem.Clean()
+}
+
+// combineKemSecrets combines the secrets of a hybrid exchange, in the manner of
+// X-Wing. The X25519 ciphertext and public key are hashed along, as the X25519
+// secret alone does not bind them.
+func combineKemSecrets(kemSecret, x25519Secret, x25519Ciphertext, x25519PublicKey []byte) []byte {
+	return crypto.Keccak256(kemSecret, x25519Secret, x25519Ciphertext, x25519PublicKey, []byte(KemX25519Kyber768))
+}
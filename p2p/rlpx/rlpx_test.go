@@ -15,3 +15,244 @@
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
 package rlpx
+
+import (
+	"bytes"
+	"github.com/DogeProtocol/dp/rlp"
+	"testing"
+)
+
+// legacyClientHelloMessage and legacyServerHelloMessage are the hello messages
+// of the handshake before the key encapsulation mechanism was negotiated.
+type legacyClientHelloMessage struct {
+	ClientKemPublicKey    []byte
+	ClientHelloRandomData [shaLen]byte
+	Version               uint
+	Rest                  []rlp.RawValue `rlp:"tail"`
+}
+
+type legacyServerHelloMessage struct {
+	CipherText            []byte
+	ServerHelloRandomData [shaLen]byte
+	Version               uint
+	Rest                  []rlp.RawValue `rlp:"tail"`
+}
+
+func TestSelectKem(t *testing.T) {
+	tests := []struct {
+		local   []string
+		offered []string
+		want    string
+		err     error
+	}{
+		{kemPreference, kemPreference, KemX25519Kyber768, nil},
+		{kemPreference, []string{KemKyber512, KemKyber768}, KemKyber768, nil},
+		{[]string{KemKyber512, KemKyber768}, kemPreference, KemKyber768, nil},
+		{kemPreference, []string{KemKyber512}, KemKyber512, nil},
+		{[]string{KemKyber512}, kemPreference, KemKyber512, nil},
+		{kemPreference, []string{KemMLKEM768, KemKyber768}, KemMLKEM768, nil},
+		{[]string{KemKyber768}, []string{KemKyber512}, "", ErrNoCommonKem},
+		{kemPreference, []string{"Unknown"}, "", ErrNoCommonKem},
+		{kemPreference, nil, "", ErrNoCommonKem},
+	}
+	for i, test := range tests {
+		got, err := selectKem(test.local, test.offered)
+		if err != test.err {
+			t.Errorf("test %d: error mismatch: got %v, want %v", i, err, test.err)
+		}
+		if got != test.want {
+			t.Errorf("test %d: kem mismatch: got %q, want %q", i, got, test.want)
+		}
+	}
+}
+
+func TestSupportedKems(t *testing.T) {
+	kems := SupportedKems()
+	if containsKem(kems, KemKyber512) == false {
+		t.Fatalf("Kyber512 is not supported: %v", kems)
+	}
+	for i := 1; i < len(kems); i++ {
+		prev, _ := selectKem(kemPreference, kems[i-1:i+1])
+		if prev != kems[i-1] {
+			t.Errorf("kems are not ordered from the strongest: %v", kems)
+		}
+	}
+}
+
+func TestHandshakeKem(t *testing.T) {
+	for _, name := range SupportedKems() {
+		t.Run(name, func(t *testing.T) {
+			client, err := newHandshakeKem(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.clean()
+			server, err := newHandshakeKem(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer server.clean()
+
+			publicKey, err := client.generateKeyPair()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(publicKey) != client.publicKeyLength() {
+				t.Fatalf("public key length mismatch: got %d, want %d", len(publicKey), client.publicKeyLength())
+			}
+			ciphertext, serverSecret, err := server.encapsulate(publicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ciphertext) != server.ciphertextLength() {
+				t.Fatalf("ciphertext length mismatch: got %d, want %d", len(ciphertext), server.ciphertextLength())
+			}
+			clientSecret, err := client.decapsulate(ciphertext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(clientSecret, serverSecret) {
+				t.Fatalf("shared secret mismatch")
+			}
+
+			if _, _, err := server.encapsulate(publicKey[1:]); err == nil {
+				t.Fatal("encapsulated to a truncated public key")
+			}
+			if _, err := client.decapsulate(ciphertext[1:]); err == nil {
+				t.Fatal("decapsulated a truncated ciphertext")
+			}
+		})
+	}
+}
+
+func TestHandshakeKemHybrid(t *testing.T) {
+	if containsKem(SupportedKems(), KemX25519Kyber768) == false {
+		t.Skip("Kyber768 is not enabled")
+	}
+	client, err := newHandshakeKem(KemX25519Kyber768)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.clean()
+	server, err := newHandshakeKem(KemX25519Kyber768)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.clean()
+
+	publicKey, err := client.generateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, serverSecret, err := server.encapsulate(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A tampered X25519 share must change the secret.
+	tampered := append([]byte{}, ciphertext...)
+	tampered[0] ^= 0x01
+	clientSecret, err := client.decapsulate(tampered)
+	if err == nil && bytes.Equal(clientSecret, serverSecret) {
+		t.Fatal("tampered X25519 ciphertext yields the same secret")
+	}
+}
+
+func TestClientHelloLegacyEncoding(t *testing.T) {
+	hello := clientHelloMessage{
+		ClientKemPublicKey: []byte{1, 2, 3},
+		Version:            1,
+	}
+	hello.ClientHelloRandomData[0] = 4
+	legacy := legacyClientHelloMessage{
+		ClientKemPublicKey:    hello.ClientKemPublicKey,
+		ClientHelloRandomData: hello.ClientHelloRandomData,
+		Version:               1,
+	}
+
+	// Clients that only offer Kyber512 send the legacy hello.
+	enc, err := rlp.EncodeToBytes(&hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyEnc, err := rlp.EncodeToBytes(&legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(enc, legacyEnc) {
+		t.Fatalf("encoding mismatch: got %x, want %x", enc, legacyEnc)
+	}
+
+	// The hello of legacy clients decodes without key shares.
+	var dec clientHelloMessage
+	if err := rlp.DecodeBytes(legacyEnc, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if len(dec.KemKeyShares) != 0 || !bytes.Equal(dec.ClientKemPublicKey, hello.ClientKemPublicKey) {
+		t.Fatalf("decoded legacy hello mismatch: %+v", dec)
+	}
+
+	// Legacy servers decode the hello with key shares, ignoring them.
+	hello.KemKeyShares = []kemKeyShare{{Kem: KemKyber768, PublicKey: []byte{5, 6}}}
+	enc, err = rlp.EncodeToBytes(&hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var legacyDec legacyClientHelloMessage
+	if err := rlp.DecodeBytes(enc, &legacyDec); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(legacyDec.ClientKemPublicKey, hello.ClientKemPublicKey) || legacyDec.Version != 1 {
+		t.Fatalf("decoded hello mismatch: %+v", legacyDec)
+	}
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if len(dec.KemKeyShares) != 1 || dec.KemKeyShares[0].Kem != KemKyber768 {
+		t.Fatalf("decoded key shares mismatch: %+v", dec.KemKeyShares)
+	}
+}
+
+func TestServerHelloLegacyEncoding(t *testing.T) {
+	hello := serverHelloMessage{
+		CipherText: []byte{1, 2, 3},
+		Version:    1,
+	}
+	legacy := legacyServerHelloMessage{
+		CipherText: hello.CipherText,
+		Version:    1,
+	}
+
+	// Servers answering legacy clients send the legacy hello.
+	enc, err := rlp.EncodeToBytes(&hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyEnc, err := rlp.EncodeToBytes(&legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(enc, legacyEnc) {
+		t.Fatalf("encoding mismatch: got %x, want %x", enc, legacyEnc)
+	}
+
+	var dec serverHelloMessage
+	if err := rlp.DecodeBytes(legacyEnc, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if dec.Kem != "" {
+		t.Fatalf("decoded legacy hello names a kem: %q", dec.Kem)
+	}
+
+	hello.Kem = KemKyber768
+	enc, err = rlp.EncodeToBytes(&hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rlp.DecodeBytes(enc, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if dec.Kem != KemKyber768 {
+		t.Fatalf("kem mismatch: got %q, want %q", dec.Kem, KemKyber768)
+	}
+}
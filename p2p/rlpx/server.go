@@ -7,8 +7,6 @@ import (
 	"errors"
 	"github.com/DogeProtocol/dp/crypto"
 	"github.com/DogeProtocol/dp/crypto/cryptobase"
-	"github.com/DogeProtocol/dp/crypto/keyestablishmentalgorithm"
-	"github.com/DogeProtocol/dp/crypto/oqs"
 	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
 	"github.com/DogeProtocol/dp/rlp"
 	"io"
@@ -19,6 +17,7 @@ type serverHelloMessage struct {
 	CipherText            []byte //kemCipherTextLength
 	ServerHelloRandomData [shaLen]byte
 	Version               uint
+	Kem                   string         `rlp:"optional"` //Selected mechanism, empty for clients of the legacy handshake
 	Rest                  []rlp.RawValue `rlp:"tail"`
 }
 
@@ -29,8 +28,8 @@ type serverVerifyMessage struct {
 }
 
 type Server struct {
-	ephemeralKemPrivateKey  *keyestablishmentalgorithm.PrivateKey
-	kem                     *oqs.KeyEncapsulation
+	kems                    []string
+	kem                     *handshakeKem
 	serverSigningPrivateKey *signaturealgorithm.PrivateKey
 	clientSigningPublicKey  *signaturealgorithm.PublicKey
 
@@ -72,6 +71,7 @@ func NewServer(conn io.ReadWriter, serverSigningPrivateKey *signaturealgorithm.P
 		conn:                    conn,
 		serverSigningPrivateKey: serverSigningPrivateKey,
 		context:                 context,
+		kems:                    SupportedKems(),
 	}
 
 	server.serializer = NewRlpxSerializer()
@@ -93,6 +93,19 @@ func (s *Server) SetServerSigningPrivateKey(serverSigningPrivateKey *signatureal
 	s.serverSigningPrivateKey = serverSigningPrivateKey
 }
 
+// SetKems sets the key encapsulation mechanisms accepted from clients.
+func (s *Server) SetKems(kems []string) {
+	s.kems = kems
+}
+
+// Kem returns the key encapsulation mechanism negotiated by the handshake.
+func (s *Server) Kem() string {
+	if s.kem == nil {
+		return ""
+	}
+	return s.kem.name
+}
+
 func (s *Server) PerformHandshake() error {
 
 	s.mutex.Lock()
@@ -102,18 +115,9 @@ func (s *Server) PerformHandshake() error {
 		return errors.New("Handshake already done")
 	}
 
-	//Initialize KEM
-	kem := oqs.KeyEncapsulation{}
-
-	err := kem.Init(oqs.KemName, nil)
-	if err != nil {
-		return err
-	}
-	s.kem = &kem
-
 	//Receive client hello message
 	clientHelloMessage := new(clientHelloMessage)
-	_, err = s.serializer.Deserialize(clientHelloMessage, s.conn)
+	_, err := s.serializer.Deserialize(clientHelloMessage, s.conn)
 	if err != nil {
 		return err
 	}
@@ -222,8 +226,13 @@ func (s *Server) makeServerHello() error {
 	}
 	copy(serverHelloMessage.ServerHelloRandomData[:], randomData)
 
-	serverHelloMessage.CipherText = make([]byte, s.kem.AlgDetails.LengthCiphertext)
+	serverHelloMessage.CipherText = make([]byte, s.kem.ciphertextLength())
 	copy(serverHelloMessage.CipherText[:], s.kemCipherText[:])
+
+	//Name the selected mechanism to clients that negotiate it, so that it is bound to the transcript signature
+	if len(s.clientHelloMessage.KemKeyShares) > 0 {
+		serverHelloMessage.Kem = s.kem.name
+	}
 	s.serverHelloMessage = serverHelloMessage
 
 	return nil
@@ -231,15 +240,44 @@ func (s *Server) makeServerHello() error {
 
 func (s *Server) handleClientHello() error {
 
-	ciphertext, sharedSecret, err := s.kem.EncapsulateSecret(s.clientHelloMessage.ClientKemPublicKey[:])
+	//Select the strongest mechanism the client has a key share of
+	publicKeys := make(map[string][]byte, len(s.clientHelloMessage.KemKeyShares)+1)
+	if len(s.clientHelloMessage.ClientKemPublicKey) > 0 {
+		publicKeys[KemKyber512] = s.clientHelloMessage.ClientKemPublicKey
+	}
+	for _, share := range s.clientHelloMessage.KemKeyShares {
+		if share.Kem == KemKyber512 {
+			return ErrInvalidKeyShare
+		}
+		if _, ok := publicKeys[share.Kem]; ok {
+			return ErrInvalidKeyShare
+		}
+		publicKeys[share.Kem] = share.PublicKey
+	}
+	offered := make([]string, 0, len(publicKeys))
+	for name := range publicKeys {
+		offered = append(offered, name)
+	}
+	name, err := selectKem(s.kems, offered)
+	if err != nil {
+		return err
+	}
+
+	kem, err := newHandshakeKem(name)
+	if err != nil {
+		return err
+	}
+	s.kem = kem
+
+	ciphertext, sharedSecret, err := s.kem.encapsulate(publicKeys[name])
 	if err != nil {
 		return err
 	}
 
-	s.kemCipherText = make([]byte, s.kem.AlgDetails.LengthCiphertext)
+	s.kemCipherText = make([]byte, len(ciphertext))
 	copy(s.kemCipherText[:], ciphertext[:])
 
-	s.kemSharedSecret = make([]byte, s.kem.AlgDetails.LengthSharedSecret)
+	s.kemSharedSecret = make([]byte, len(sharedSecret))
 	copy(s.kemSharedSecret[:], sharedSecret[:])
 
 	return nil
@@ -431,7 +469,7 @@ func (s *Server) ReadAndDecryptMessage(msg interface{}, packetType PacketType) e
 
 func (s *Server) Cleanup() {
 	if s.kem != nil {
-		s.kem.Clean()
+		s.kem.clean()
 	}
 }
 
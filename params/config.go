@@ -18,8 +18,10 @@ package params
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"github.com/DogeProtocol/dp/crypto/hashingalgorithm"
+	"math"
 	"math/big"
 
 	"github.com/DogeProtocol/dp/common"
@@ -252,6 +254,7 @@ var (
 		big.NewInt(0),
 		nil,
 		new(EthashConfig),
+		nil,
 		nil}
 
 	// AllProofOfStakeProtocolChanges contains every protocol change (EIPs) introduced
@@ -276,7 +279,8 @@ var (
 		big.NewInt(0),
 		nil,
 		nil,
-		&ProofOfStakeConfig{Period: 0, Epoch: 30000}}
+		&ProofOfStakeConfig{Period: 0, Epoch: 30000},
+		nil}
 
 	TestChainConfig = &ChainConfig{big.NewInt(123123),
 		big.NewInt(0),
@@ -295,6 +299,7 @@ var (
 		big.NewInt(0),
 		nil,
 		new(EthashConfig),
+		nil,
 		nil}
 	TestRules = TestChainConfig.Rules(new(big.Int))
 )
@@ -380,6 +385,8 @@ type ChainConfig struct {
 	// Various consensus engines
 	Ethash       *EthashConfig       `json:"ethash,omitempty"`
 	ProofOfStake *ProofOfStakeConfig `json:"proofofstake,omitempty"`
+
+	SignatureSchemes SignatureSchemes `json:"signatureSchemes,omitempty"` // Signature schemes valid by block (nil = only DefaultSignatureScheme)
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -391,16 +398,188 @@ func (c *EthashConfig) String() string {
 }
 
 // ProofOfStakeConfig is the consensus engine configs for proof-of-stake based sealing.
+//
+// The activation blocks are optional. Any block left unset in the genesis falls
+// back to the mainnet schedule, see ForkSchedule.
 type ProofOfStakeConfig struct {
 	Period uint64 `json:"period"` // Number of seconds between blocks to enforce
 	Epoch  uint64 `json:"epoch"`  // Epoch length to reset votes and checkpoint
-}
+
+	RewardStartBlock            *big.Int `json:"rewardStartBlock,omitempty"`            // Block proposers are rewarded from (nil = mainnet)
+	SlashStartBlock             *big.Int `json:"slashStartBlock,omitempty"`             // Offline block proposers are slashed from (nil = mainnet)
+	FullSignProposalCutoffBlock *big.Int `json:"fullSignProposalCutoffBlock,omitempty"` // Proposals are periodically full signed from (nil = mainnet)
+	StakingContractV2Block      *big.Int `json:"stakingContractV2Block,omitempty"`      // Staking contract code is switched to v2 at (nil = fullSignProposalCutoffBlock)
+	ConsensusContextStartBlock  *big.Int `json:"consensusContextStartBlock,omitempty"`  // Consensus context contract is deployed at (nil = fullSignProposalCutoffBlock)
+	ValidatorNilBlockStartBlock *big.Int `json:"validatorNilBlockStartBlock,omitempty"` // Nil blocks are tracked per validator from (nil = stakingContractV2Block + 1)
+	ContextBasedStartBlock      *big.Int `json:"contextBasedStartBlock,omitempty"`      // Block proposers are selected using the consensus context from (nil = mainnet)
+	PacketProtocolStartBlock    *big.Int `json:"packetProtocolStartBlock,omitempty"`    // Versioned consensus packets and peer relaying are used from (nil = contextBasedStartBlock + 33)
+	EquivocationSlashStartBlock *big.Int `json:"equivocationSlashStartBlock,omitempty"` // Equivocation evidence is accepted and slashed from (nil = disabled)
+	EquivocationSlashAmount     *big.Int `json:"equivocationSlashAmount,omitempty"`     // Wei slashed from the depositor of an equivocating validator (nil = 1000 coins)
+}
+
+// ProofOfStakeForkSchedule is the resolved set of proof-of-stake activation
+// blocks and slash amounts that the consensus engine runs with.
+type ProofOfStakeForkSchedule struct {
+	RewardStartBlock                uint64
+	SlashStartBlock                 uint64
+	FullSignProposalCutoffBlock     uint64
+	StakingContractV2Block          uint64
+	ConsensusContextStartBlock      uint64
+	ValidatorNilBlockStartBlock     uint64
+	BlockProposerNilBlockStartBlock uint64 // Always ValidatorNilBlockStartBlock + 16
+	ContextBasedStartBlock          uint64
+	BlockTimeOrigStartBlock         uint64 // Always ContextBasedStartBlock + 1
+	PacketProtocolStartBlock        uint64
+	EquivocationSlashStartBlock     uint64
+	EquivocationSlashAmount         *big.Int         // Wei
+	SignatureSchemes                SignatureSchemes // Set from the chain config, see ChainConfig.ProofOfStakeForkSchedule
+}
+
+const (
+	// ConsensusContextBasedBlockThreshold is how many blocks back the consensus
+	// context used for block proposer selection is taken from.
+	ConsensusContextBasedBlockThreshold = uint64(64000)
+
+	blockProposerNilBlockDelay    = uint64(16)
+	packetProtocolStartDelay      = uint64(32)
+	mainnetRewardStartBlock       = uint64(277204)
+	mainnetSlashStartBlock        = uint64(1497600)
+	mainnetFullSignCutoffBlock    = uint64(421888)
+	mainnetContextBasedStartBlock = uint64(536000)
+
+	defaultEquivocationSlashCoins = int64(1000)
+)
+
+// DefaultProofOfStakeForkSchedule is the fork schedule of the main network.
+var DefaultProofOfStakeForkSchedule = (&ProofOfStakeConfig{}).ForkSchedule()
 
 // String implements the stringer interface, returning the consensus engine details.
 func (c *ProofOfStakeConfig) String() string {
 	return "proofofstake"
 }
 
+// ForkSchedule resolves the activation blocks of the config. Unset blocks keep
+// the relationships they have on mainnet, so that for example setting only
+// fullSignProposalCutoffBlock also moves the staking v2 and consensus context
+// forks along with it.
+func (c *ProofOfStakeConfig) ForkSchedule() *ProofOfStakeForkSchedule {
+	s := &ProofOfStakeForkSchedule{
+		RewardStartBlock:            blockOrDefault(c.RewardStartBlock, mainnetRewardStartBlock),
+		SlashStartBlock:             blockOrDefault(c.SlashStartBlock, mainnetSlashStartBlock),
+		FullSignProposalCutoffBlock: blockOrDefault(c.FullSignProposalCutoffBlock, mainnetFullSignCutoffBlock),
+		ContextBasedStartBlock:      blockOrDefault(c.ContextBasedStartBlock, mainnetContextBasedStartBlock),
+	}
+	s.StakingContractV2Block = blockOrDefault(c.StakingContractV2Block, s.FullSignProposalCutoffBlock)
+	s.ConsensusContextStartBlock = blockOrDefault(c.ConsensusContextStartBlock, s.FullSignProposalCutoffBlock)
+	s.ValidatorNilBlockStartBlock = blockOrDefault(c.ValidatorNilBlockStartBlock, s.StakingContractV2Block+1)
+	s.BlockProposerNilBlockStartBlock = s.ValidatorNilBlockStartBlock + blockProposerNilBlockDelay
+	s.BlockTimeOrigStartBlock = s.ContextBasedStartBlock + 1
+	s.PacketProtocolStartBlock = blockOrDefault(c.PacketProtocolStartBlock, s.BlockTimeOrigStartBlock+packetProtocolStartDelay)
+	s.EquivocationSlashStartBlock = blockOrDefault(c.EquivocationSlashStartBlock, math.MaxUint64)
+	if c.EquivocationSlashAmount == nil {
+		s.EquivocationSlashAmount = EtherToWei(big.NewInt(defaultEquivocationSlashCoins))
+	} else {
+		s.EquivocationSlashAmount = new(big.Int).Set(c.EquivocationSlashAmount)
+	}
+	return s
+}
+
+// ProofOfStakeForkSchedule resolves the proof-of-stake activation blocks of the
+// config along with the signature schemes that consensus packets are checked against.
+func (c *ChainConfig) ProofOfStakeForkSchedule() *ProofOfStakeForkSchedule {
+	s := c.ProofOfStake.ForkSchedule()
+	s.SignatureSchemes = c.SignatureSchemes
+	return s
+}
+
+// CheckForkSchedule checks that the activation blocks are consistent with each
+// other. The contract upgrades happen while finalizing the fork block itself,
+// so they cannot be scheduled at the genesis block.
+func (c *ProofOfStakeConfig) CheckForkSchedule() error {
+	for _, b := range []struct {
+		name  string
+		block *big.Int
+	}{
+		{"rewardStartBlock", c.RewardStartBlock},
+		{"slashStartBlock", c.SlashStartBlock},
+		{"fullSignProposalCutoffBlock", c.FullSignProposalCutoffBlock},
+		{"stakingContractV2Block", c.StakingContractV2Block},
+		{"consensusContextStartBlock", c.ConsensusContextStartBlock},
+		{"validatorNilBlockStartBlock", c.ValidatorNilBlockStartBlock},
+		{"contextBasedStartBlock", c.ContextBasedStartBlock},
+		{"packetProtocolStartBlock", c.PacketProtocolStartBlock},
+		{"equivocationSlashStartBlock", c.EquivocationSlashStartBlock},
+	} {
+		if b.block != nil && (b.block.Sign() < 0 || !b.block.IsUint64()) {
+			return fmt.Errorf("invalid proofofstake %v: %v", b.name, b.block)
+		}
+	}
+
+	if c.EquivocationSlashAmount != nil && c.EquivocationSlashAmount.Sign() < 0 {
+		return fmt.Errorf("invalid proofofstake equivocationSlashAmount: %v", c.EquivocationSlashAmount)
+	}
+
+	s := c.ForkSchedule()
+	if s.StakingContractV2Block == 0 {
+		return errors.New("invalid proofofstake stakingContractV2Block: must be at least 1")
+	}
+	if s.ConsensusContextStartBlock == 0 {
+		return errors.New("invalid proofofstake consensusContextStartBlock: must be at least 1")
+	}
+	if s.ValidatorNilBlockStartBlock <= s.StakingContractV2Block {
+		return fmt.Errorf("unsupported proofofstake fork ordering: validatorNilBlockStartBlock %v must be after stakingContractV2Block %v",
+			s.ValidatorNilBlockStartBlock, s.StakingContractV2Block)
+	}
+	if s.ContextBasedStartBlock <= s.ConsensusContextStartBlock+ConsensusContextBasedBlockThreshold {
+		return fmt.Errorf("unsupported proofofstake fork ordering: contextBasedStartBlock %v must be more than %v blocks after consensusContextStartBlock %v",
+			s.ContextBasedStartBlock, ConsensusContextBasedBlockThreshold, s.ConsensusContextStartBlock)
+	}
+	if s.ContextBasedStartBlock < s.BlockProposerNilBlockStartBlock {
+		return fmt.Errorf("unsupported proofofstake fork ordering: contextBasedStartBlock %v before validatorNilBlockStartBlock + %v",
+			s.ContextBasedStartBlock, blockProposerNilBlockDelay)
+	}
+	return nil
+}
+
+// checkCompatible returns an error if the fork schedule was changed for a
+// block that the local chain has already processed.
+func (c *ProofOfStakeConfig) checkCompatible(newcfg *ProofOfStakeConfig, head *big.Int) *ConfigCompatError {
+	s1, s2 := c.ForkSchedule(), newcfg.ForkSchedule()
+	for _, f := range []struct {
+		name   string
+		s1, s2 uint64
+	}{
+		{"ProofOfStake reward start block", s1.RewardStartBlock, s2.RewardStartBlock},
+		{"ProofOfStake slash start block", s1.SlashStartBlock, s2.SlashStartBlock},
+		{"ProofOfStake full sign proposal cutoff block", s1.FullSignProposalCutoffBlock, s2.FullSignProposalCutoffBlock},
+		{"ProofOfStake staking contract v2 block", s1.StakingContractV2Block, s2.StakingContractV2Block},
+		{"ProofOfStake consensus context start block", s1.ConsensusContextStartBlock, s2.ConsensusContextStartBlock},
+		{"ProofOfStake validator nil block start block", s1.ValidatorNilBlockStartBlock, s2.ValidatorNilBlockStartBlock},
+		{"ProofOfStake context based start block", s1.ContextBasedStartBlock, s2.ContextBasedStartBlock},
+		{"ProofOfStake packet protocol start block", s1.PacketProtocolStartBlock, s2.PacketProtocolStartBlock},
+		{"ProofOfStake equivocation slash start block", s1.EquivocationSlashStartBlock, s2.EquivocationSlashStartBlock},
+	} {
+		b1, b2 := new(big.Int).SetUint64(f.s1), new(big.Int).SetUint64(f.s2)
+		if isForkIncompatible(b1, b2, head) {
+			return newCompatError(f.name, b1, b2)
+		}
+	}
+	// The slash amount applies from the equivocation slash fork, which is the same in
+	// both configs at this point
+	slashBlock := new(big.Int).SetUint64(s1.EquivocationSlashStartBlock)
+	if isForked(slashBlock, head) && s1.EquivocationSlashAmount.Cmp(s2.EquivocationSlashAmount) != 0 {
+		return newCompatError("ProofOfStake equivocation slash amount", slashBlock, slashBlock)
+	}
+	return nil
+}
+
+func blockOrDefault(block *big.Int, def uint64) uint64 {
+	if block == nil {
+		return def
+	}
+	return block.Uint64()
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -555,6 +734,12 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 			lastFork = cur
 		}
 	}
+	if err := c.SignatureSchemes.Check(); err != nil {
+		return err
+	}
+	if c.ProofOfStake != nil {
+		return c.ProofOfStake.CheckForkSchedule()
+	}
 	return nil
 }
 
@@ -605,6 +790,14 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.LondonBlock, newcfg.LondonBlock, head) {
 		return newCompatError("London fork block", c.LondonBlock, newcfg.LondonBlock)
 	}
+	if c.ProofOfStake != nil && newcfg.ProofOfStake != nil {
+		if err := c.ProofOfStake.checkCompatible(newcfg.ProofOfStake, head); err != nil {
+			return err
+		}
+	}
+	if err := c.SignatureSchemes.checkCompatible(newcfg.SignatureSchemes, head); err != nil {
+		return err
+	}
 	return nil
 }
 
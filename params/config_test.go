@@ -17,6 +17,7 @@
 package params
 
 import (
+	"math"
 	"math/big"
 	"reflect"
 	"testing"
@@ -96,3 +97,154 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestProofOfStakeForkSchedule(t *testing.T) {
+	want := &ProofOfStakeForkSchedule{
+		RewardStartBlock:                277204,
+		SlashStartBlock:                 1497600,
+		FullSignProposalCutoffBlock:     421888,
+		StakingContractV2Block:          421888,
+		ConsensusContextStartBlock:      421888,
+		ValidatorNilBlockStartBlock:     421889,
+		BlockProposerNilBlockStartBlock: 421905,
+		ContextBasedStartBlock:          536000,
+		BlockTimeOrigStartBlock:         536001,
+		PacketProtocolStartBlock:        536033,
+		EquivocationSlashStartBlock:     math.MaxUint64,
+		EquivocationSlashAmount:         new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18)),
+	}
+	if !reflect.DeepEqual(DefaultProofOfStakeForkSchedule, want) {
+		t.Fatalf("default schedule mismatch:\nhave %+v\nwant %+v", DefaultProofOfStakeForkSchedule, want)
+	}
+
+	// Derived blocks follow the blocks they depend on.
+	s := (&ProofOfStakeConfig{FullSignProposalCutoffBlock: big.NewInt(10), ContextBasedStartBlock: big.NewInt(64100)}).ForkSchedule()
+	if s.StakingContractV2Block != 10 || s.ConsensusContextStartBlock != 10 || s.ValidatorNilBlockStartBlock != 11 ||
+		s.BlockProposerNilBlockStartBlock != 27 || s.BlockTimeOrigStartBlock != 64101 || s.PacketProtocolStartBlock != 64133 {
+		t.Fatalf("derived schedule mismatch: %+v", s)
+	}
+}
+
+func TestCheckProofOfStakeForkSchedule(t *testing.T) {
+	tests := []struct {
+		config  *ProofOfStakeConfig
+		wantErr bool
+	}{
+		{&ProofOfStakeConfig{}, false},
+		{&ProofOfStakeConfig{FullSignProposalCutoffBlock: big.NewInt(10), ContextBasedStartBlock: big.NewInt(64100)}, false},
+		{&ProofOfStakeConfig{RewardStartBlock: big.NewInt(-1)}, true},
+		{&ProofOfStakeConfig{EquivocationSlashAmount: big.NewInt(0)}, false},
+		{&ProofOfStakeConfig{EquivocationSlashAmount: big.NewInt(-1)}, true},
+		{&ProofOfStakeConfig{StakingContractV2Block: big.NewInt(0)}, true},
+		{&ProofOfStakeConfig{ConsensusContextStartBlock: big.NewInt(0)}, true},
+		{&ProofOfStakeConfig{ValidatorNilBlockStartBlock: big.NewInt(421888)}, true},
+		{&ProofOfStakeConfig{ContextBasedStartBlock: big.NewInt(485888)}, true},
+		{&ProofOfStakeConfig{FullSignProposalCutoffBlock: big.NewInt(10), ConsensusContextStartBlock: big.NewInt(10), ValidatorNilBlockStartBlock: big.NewInt(64200), ContextBasedStartBlock: big.NewInt(64100)}, true},
+	}
+	for i, test := range tests {
+		err := test.config.CheckForkSchedule()
+		if (err != nil) != test.wantErr {
+			t.Errorf("test %d: err %v, wantErr %v", i, err, test.wantErr)
+		}
+	}
+}
+
+func TestCheckCompatibleProofOfStake(t *testing.T) {
+	stored := &ChainConfig{ProofOfStake: &ProofOfStakeConfig{}}
+	changed := &ChainConfig{ProofOfStake: &ProofOfStakeConfig{SlashStartBlock: big.NewInt(2000000)}}
+	if err := stored.CheckCompatible(changed, 1000000); err != nil {
+		t.Fatalf("unexpected error before the fork: %v", err)
+	}
+	if err := stored.CheckCompatible(changed, 1500000); err == nil || err.What != "ProofOfStake slash start block" {
+		t.Fatalf("expected slash start block incompatibility, got %v", err)
+	}
+
+	stored = &ChainConfig{ProofOfStake: &ProofOfStakeConfig{EquivocationSlashStartBlock: big.NewInt(100)}}
+	changed = &ChainConfig{ProofOfStake: &ProofOfStakeConfig{EquivocationSlashStartBlock: big.NewInt(100), EquivocationSlashAmount: big.NewInt(1)}}
+	if err := stored.CheckCompatible(changed, 99); err != nil {
+		t.Fatalf("unexpected error before the fork: %v", err)
+	}
+	if err := stored.CheckCompatible(changed, 100); err == nil || err.What != "ProofOfStake equivocation slash amount" || err.RewindTo != 99 {
+		t.Fatalf("expected equivocation slash amount incompatibility, got %v", err)
+	}
+}
+
+func TestSignatureSchemesIsValid(t *testing.T) {
+	var none SignatureSchemes
+	if none.IsValid(DefaultSignatureScheme, big.NewInt(100)) == false || none.IsValid("Falcon-512", nil) {
+		t.Fatalf("unexpected validity of the default schemes")
+	}
+
+	schemes := SignatureSchemes{
+		{Name: DefaultSignatureScheme, Block: big.NewInt(0), RetireBlock: big.NewInt(200)},
+		{Name: "Falcon-512", Block: big.NewInt(100)},
+	}
+	tests := []struct {
+		name  string
+		num   *big.Int
+		valid bool
+	}{
+		{DefaultSignatureScheme, big.NewInt(0), true},
+		{DefaultSignatureScheme, big.NewInt(199), true},
+		{DefaultSignatureScheme, big.NewInt(200), false},
+		{DefaultSignatureScheme, nil, true},
+		{"Falcon-512", big.NewInt(99), false},
+		{"Falcon-512", big.NewInt(100), true},
+		{"Falcon-512", big.NewInt(1000), true},
+		{"unknown", nil, false},
+	}
+	for i, test := range tests {
+		if valid := schemes.IsValid(test.name, test.num); valid != test.valid {
+			t.Errorf("test %d: %v at %v valid %v, want %v", i, test.name, test.num, valid, test.valid)
+		}
+	}
+}
+
+func TestCheckSignatureSchemes(t *testing.T) {
+	tests := []struct {
+		schemes SignatureSchemes
+		wantErr bool
+	}{
+		{nil, false},
+		{SignatureSchemes{{Name: DefaultSignatureScheme, Block: big.NewInt(0)}}, false},
+		{SignatureSchemes{{Name: DefaultSignatureScheme, Block: big.NewInt(0), RetireBlock: big.NewInt(200)}, {Name: "Falcon-512", Block: big.NewInt(100)}}, false},
+		{SignatureSchemes{{Name: DefaultSignatureScheme, Block: big.NewInt(0), RetireBlock: big.NewInt(100)}, {Name: "Falcon-512", Block: big.NewInt(100)}}, false},
+		{SignatureSchemes{{Name: "", Block: big.NewInt(0)}}, true},
+		{SignatureSchemes{{Name: DefaultSignatureScheme}}, true},
+		{SignatureSchemes{{Name: DefaultSignatureScheme, Block: big.NewInt(0)}, {Name: DefaultSignatureScheme, Block: big.NewInt(10)}}, true},
+		{SignatureSchemes{{Name: DefaultSignatureScheme, Block: big.NewInt(10), RetireBlock: big.NewInt(10)}}, true},
+		{SignatureSchemes{{Name: "Falcon-512", Block: big.NewInt(10)}}, true},
+		{SignatureSchemes{{Name: DefaultSignatureScheme, Block: big.NewInt(0), RetireBlock: big.NewInt(100)}}, true},
+		{SignatureSchemes{{Name: DefaultSignatureScheme, Block: big.NewInt(0), RetireBlock: big.NewInt(100)}, {Name: "Falcon-512", Block: big.NewInt(101)}}, true},
+	}
+	for i, test := range tests {
+		err := test.schemes.Check()
+		if (err != nil) != test.wantErr {
+			t.Errorf("test %d: err %v, wantErr %v", i, err, test.wantErr)
+		}
+	}
+}
+
+func TestCheckCompatibleSignatureSchemes(t *testing.T) {
+	stored := &ChainConfig{}
+	changed := &ChainConfig{SignatureSchemes: SignatureSchemes{
+		{Name: DefaultSignatureScheme, Block: big.NewInt(0)},
+		{Name: "Falcon-512", Block: big.NewInt(2000)},
+	}}
+	if err := stored.CheckCompatible(changed, 1000); err != nil {
+		t.Fatalf("unexpected error before the scheme block: %v", err)
+	}
+	if err := stored.CheckCompatible(changed, 3000); err == nil || err.What != "signature scheme Falcon-512 block" {
+		t.Fatalf("expected signature scheme block incompatibility, got %v", err)
+	}
+	retired := &ChainConfig{SignatureSchemes: SignatureSchemes{
+		{Name: DefaultSignatureScheme, Block: big.NewInt(0), RetireBlock: big.NewInt(4000)},
+		{Name: "Falcon-512", Block: big.NewInt(2000)},
+	}}
+	if err := changed.CheckCompatible(retired, 3000); err != nil {
+		t.Fatalf("unexpected error before the retire block: %v", err)
+	}
+	if err := changed.CheckCompatible(retired, 5000); err == nil || err.What != "signature scheme "+DefaultSignatureScheme+" retire block" {
+		t.Fatalf("expected signature scheme retire block incompatibility, got %v", err)
+	}
+}
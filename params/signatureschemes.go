@@ -0,0 +1,128 @@
+package params
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// DefaultSignatureScheme is the signature scheme of chains that do not declare
+// their signature schemes, the compact hybrid scheme of crypto/hybrideds.
+const DefaultSignatureScheme = "dilithium-ed25519-sphincs"
+
+// SignatureSchemeConfig declares a signature scheme valid for transactions and
+// consensus packets over a range of blocks. The name is the one the scheme is
+// registered with in crypto/cryptobase.
+type SignatureSchemeConfig struct {
+	Name        string   `json:"name"`
+	Block       *big.Int `json:"block"`                 // Block the scheme is valid from
+	RetireBlock *big.Int `json:"retireBlock,omitempty"` // Block the scheme is no longer valid from (nil = never retired)
+}
+
+// SignatureSchemes are the signature schemes of a chain. Schemes can be valid over
+// overlapping ranges of blocks, so that the network can migrate from one to the
+// next without every account and validator switching at the same block.
+type SignatureSchemes []SignatureSchemeConfig
+
+// defaultSignatureSchemes are the schemes of a chain that does not declare any.
+var defaultSignatureSchemes = SignatureSchemes{{Name: DefaultSignatureScheme, Block: big.NewInt(0)}}
+
+func (s SignatureSchemes) resolved() SignatureSchemes {
+	if len(s) == 0 {
+		return defaultSignatureSchemes
+	}
+	return s
+}
+
+// IsValid returns whether the scheme called name is valid at block num. A nil num
+// asks whether the scheme is valid at any block.
+func (s SignatureSchemes) IsValid(name string, num *big.Int) bool {
+	for _, scheme := range s.resolved() {
+		if scheme.Name != name {
+			continue
+		}
+		if num == nil {
+			return true
+		}
+		if isForked(scheme.Block, num) && isForked(scheme.RetireBlock, num) == false {
+			return true
+		}
+	}
+	return false
+}
+
+// Check checks that the schemes are well formed and that at every block from the
+// genesis on there is at least one valid scheme.
+func (s SignatureSchemes) Check() error {
+	names := make(map[string]bool)
+	for _, scheme := range s {
+		if scheme.Name == "" {
+			return errors.New("invalid signature scheme: missing name")
+		}
+		if names[scheme.Name] {
+			return fmt.Errorf("invalid signature scheme %v: declared twice", scheme.Name)
+		}
+		names[scheme.Name] = true
+		if scheme.Block == nil || scheme.Block.Sign() < 0 || !scheme.Block.IsUint64() {
+			return fmt.Errorf("invalid signature scheme %v block: %v", scheme.Name, scheme.Block)
+		}
+		if scheme.RetireBlock != nil && (!scheme.RetireBlock.IsUint64() || scheme.RetireBlock.Cmp(scheme.Block) <= 0) {
+			return fmt.Errorf("invalid signature scheme %v retire block: %v, must be after block %v", scheme.Name, scheme.RetireBlock, scheme.Block)
+		}
+	}
+
+	if len(s) == 0 {
+		return nil
+	}
+	sorted := make(SignatureSchemes, len(s))
+	copy(sorted, s)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Block.Cmp(sorted[j].Block) < 0
+	})
+	// covered is the block up to which a scheme is valid, nil once one never retires
+	covered := new(big.Int)
+	for _, scheme := range sorted {
+		if covered == nil {
+			break
+		}
+		if scheme.Block.Cmp(covered) > 0 {
+			return fmt.Errorf("no valid signature scheme from block %v to %v", covered, scheme.Block)
+		}
+		if scheme.RetireBlock == nil {
+			covered = nil
+		} else if scheme.RetireBlock.Cmp(covered) > 0 {
+			covered = scheme.RetireBlock
+		}
+	}
+	if covered != nil {
+		return fmt.Errorf("no valid signature scheme from block %v", covered)
+	}
+	return nil
+}
+
+// checkCompatible returns an error if a scheme was added, removed or rescheduled
+// at a block that the local chain has already processed.
+func (s SignatureSchemes) checkCompatible(newSchemes SignatureSchemes, head *big.Int) *ConfigCompatError {
+	s1, s2 := s.resolved(), newSchemes.resolved()
+	find := func(schemes SignatureSchemes, name string) SignatureSchemeConfig {
+		for _, scheme := range schemes {
+			if scheme.Name == name {
+				return scheme
+			}
+		}
+		return SignatureSchemeConfig{Name: name}
+	}
+	for _, schemes := range []SignatureSchemes{s1, s2} {
+		for _, scheme := range schemes {
+			c1, c2 := find(s1, scheme.Name), find(s2, scheme.Name)
+			if isForkIncompatible(c1.Block, c2.Block, head) {
+				return newCompatError("signature scheme "+scheme.Name+" block", c1.Block, c2.Block)
+			}
+			if isForkIncompatible(c1.RetireBlock, c2.RetireBlock, head) {
+				return newCompatError("signature scheme "+scheme.Name+" retire block", c1.RetireBlock, c2.RetireBlock)
+			}
+		}
+	}
+	return nil
+}
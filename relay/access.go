@@ -0,0 +1,573 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/metrics"
+	lru "github.com/hashicorp/golang-lru"
+	"golang.org/x/time/rate"
+)
+
+const API_KEY_HEADER_NAME = "X-Api-Key"
+const FORWARDED_FOR_HEADER_NAME = "X-Forwarded-For"
+const RETRY_AFTER_HEADER_NAME = "Retry-After"
+
+const (
+	// apiKeysFileReloadInterval is the interval at which the api keys file is checked for changes.
+	apiKeysFileReloadInterval = 10 * time.Second
+
+	// ipLimitersCacheSize is the number of client ip addresses whose limiters are kept.
+	ipLimitersCacheSize = 65536
+
+	// configApiKeyName is the name that the keys listed in the relay config are counted under.
+	configApiKeyName = "config"
+
+	// anonymousApiKeyName is the name that requests without a key are counted under, if
+	// authorization is disabled.
+	anonymousApiKeyName = "anonymous"
+)
+
+var (
+	ErrUnauthorized  = errors.New("Unauthorized")
+	ErrRateLimited   = errors.New("Too many requests")
+	ErrQuotaExceeded = errors.New("Quota exceeded")
+)
+
+// RateLimit is a token bucket: requests are allowed at Rate per second on average, with
+// bursts of up to Burst requests. A zero Rate is unlimited.
+type RateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+func (l RateLimit) newLimiter() *rate.Limiter {
+	if l.Rate <= 0 {
+		return nil
+	}
+	burst := l.Burst
+	if burst < 1 {
+		burst = int(math.Ceil(l.Rate))
+	}
+	return rate.NewLimiter(rate.Limit(l.Rate), burst)
+}
+
+// Quota is the number of requests allowed in each period, a duration such as "24h". The
+// period starts with the first request made after the previous one ended. Quotas are
+// counted in memory, so they start over when the relay restarts.
+type Quota struct {
+	Requests uint64 `json:"requests"`
+	Period   string `json:"period"`
+}
+
+func (q Quota) validate() error {
+	period, err := time.ParseDuration(q.Period)
+	if err != nil {
+		return err
+	}
+	if period <= 0 || q.Requests == 0 {
+		return errors.New("quota without requests or period")
+	}
+	return nil
+}
+
+func (q Quota) newCounter() *quotaCounter {
+	period, _ := time.ParseDuration(q.Period)
+	return &quotaCounter{
+		requests: q.Requests,
+		period:   period,
+	}
+}
+
+// quotaCounter counts the requests of a quota period.
+type quotaCounter struct {
+	requests uint64
+	period   time.Duration
+
+	lock  sync.Mutex
+	start time.Time
+	used  uint64
+}
+
+// take counts a request, and returns how long until the next period if the quota of the
+// current one is used up.
+func (c *quotaCounter) take(now time.Time) time.Duration {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if now.Sub(c.start) >= c.period {
+		c.start = now
+		c.used = 0
+	}
+	if c.used >= c.requests {
+		return c.start.Add(c.period).Sub(now)
+	}
+	c.used++
+	return 0
+}
+
+// release gives back a request counted by take, which was throttled by a rate limit.
+func (c *quotaCounter) release() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.used > 0 {
+		c.used--
+	}
+}
+
+// ApiKey is an entry of the api keys file. Its rate limit applies to all requests made
+// with the key, and its endpoint limits and quotas to the requests of each endpoint.
+type ApiKey struct {
+	RateLimit
+	Name      string               `json:"name"`
+	Key       string               `json:"key"`
+	Endpoints map[string]RateLimit `json:"endpoints"`
+	Quotas    map[string]Quota     `json:"quotas"`
+}
+
+// AccessConfig configures the authorization and rate limiting of an api.
+type AccessConfig struct {
+	EnableAuth bool
+	// ApiKeys is a comma separated list of keys, limited by KeyLimit.
+	ApiKeys string
+	// ApiKeysFile is a JSON file with a list of ApiKey, reloaded when it changes.
+	ApiKeysFile string
+	// KeyLimit is the rate limit of the keys that do not set their own.
+	KeyLimit RateLimit
+	// IpLimit is the rate limit of each client ip address, regardless of its key.
+	IpLimit RateLimit
+	// EndpointLimits are the rate limits of each endpoint, for each key and ip address.
+	EndpointLimits map[string]RateLimit
+	// EndpointQuotas are the quotas of each endpoint, for the keys that do not set their
+	// own, and for each ip address making requests without a key.
+	EndpointQuotas map[string]Quota
+	// TrustForwardedFor takes the client ip address from the X-Forwarded-For header,
+	// for relays behind a load balancer.
+	TrustForwardedFor bool
+	// TrustedProxies are the addresses or CIDR ranges of the load balancers in front of the
+	// relay. The client ip address is the last X-Forwarded-For entry that is not one of
+	// them, and the header is ignored unless the request comes from one of them. Without
+	// trusted proxies, the client ip address is the last X-Forwarded-For entry, which the
+	// load balancer in front of the relay appends.
+	TrustedProxies []string
+}
+
+// keyLimits holds the limiters and quotas of the requests made with a key, or made by an
+// ip address without one.
+type keyLimits struct {
+	limiter   *rate.Limiter
+	endpoints map[string]*rate.Limiter
+	quotas    map[string]*quotaCounter
+}
+
+func newKeyLimits(limit RateLimit, endpointLimits []map[string]RateLimit, endpointQuotas []map[string]Quota) keyLimits {
+	limits := keyLimits{
+		limiter:   limit.newLimiter(),
+		endpoints: make(map[string]*rate.Limiter),
+		quotas:    make(map[string]*quotaCounter),
+	}
+	for _, endpoints := range endpointLimits {
+		for endpoint, endpointLimit := range endpoints {
+			limits.endpoints[endpoint] = endpointLimit.newLimiter()
+		}
+	}
+	for _, quotas := range endpointQuotas {
+		for endpoint, quota := range quotas {
+			limits.quotas[endpoint] = quota.newCounter()
+		}
+	}
+	return limits
+}
+
+// apiKeyState holds the limiters of an api key.
+type apiKeyState struct {
+	keyLimits
+	key       ApiKey
+	name      string
+	requests  metrics.Counter
+	throttled metrics.Counter
+}
+
+func newApiKeyState(key ApiKey, config *AccessConfig) *apiKeyState {
+	limit := key.RateLimit
+	if limit.Rate <= 0 {
+		limit = config.KeyLimit
+	}
+	return &apiKeyState{
+		keyLimits: newKeyLimits(limit, []map[string]RateLimit{config.EndpointLimits, key.Endpoints},
+			[]map[string]Quota{config.EndpointQuotas, key.Quotas}),
+		key:       key,
+		name:      key.Name,
+		requests:  metrics.GetOrRegisterCounter("relay/apikey/"+key.Name+"/requests", nil),
+		throttled: metrics.GetOrRegisterCounter("relay/apikey/"+key.Name+"/throttled", nil),
+	}
+}
+
+// ipState holds the limiters of a client ip address.
+type ipState struct {
+	limiter   *rate.Limiter
+	lock      sync.Mutex
+	endpoints map[string]*rate.Limiter
+	anonymous *keyLimits
+}
+
+// AccessControl authorizes the requests of an api by their api key, and throttles them
+// per key, per client ip address and per endpoint.
+type AccessControl struct {
+	config AccessConfig
+
+	lock         sync.RWMutex
+	keys         map[string]*apiKeyState
+	fileModTime  time.Time
+	anonymous    *apiKeyState
+	proxies      []*net.IPNet
+	ips          *lru.Cache
+	quit         chan struct{}
+	reloadDoneCh chan struct{}
+}
+
+// NewAccessControl creates the access control of an api, loading the api keys file if one
+// is configured and reloading it whenever it changes.
+func NewAccessControl(config AccessConfig) (*AccessControl, error) {
+	for endpoint, quota := range config.EndpointQuotas {
+		if err := quota.validate(); err != nil {
+			return nil, errors.New("invalid quota of endpoint " + endpoint + ": " + err.Error())
+		}
+	}
+	proxies, err := parseTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	ips, _ := lru.New(ipLimitersCacheSize)
+	ac := &AccessControl{
+		config:    config,
+		keys:      make(map[string]*apiKeyState),
+		anonymous: newApiKeyState(ApiKey{Name: anonymousApiKeyName}, &config),
+		proxies:   proxies,
+		ips:       ips,
+		quit:      make(chan struct{}),
+	}
+	for _, key := range strings.Split(config.ApiKeys, ",") {
+		if len(key) == 0 {
+			continue
+		}
+		// Keys from the config are not named, so they are counted together
+		ac.keys[key] = newApiKeyState(ApiKey{Name: configApiKeyName, Key: key}, &config)
+	}
+	if len(config.ApiKeysFile) > 0 {
+		if err := ac.loadApiKeysFile(); err != nil {
+			return nil, err
+		}
+		ac.reloadDoneCh = make(chan struct{})
+		go ac.reloadLoop()
+	}
+	return ac, nil
+}
+
+// LoadApiKeysFile reads an api keys file.
+func LoadApiKeysFile(filename string) ([]ApiKey, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+
+	var keys []ApiKey
+	if err := json.Unmarshal(content, &keys); err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if len(key.Key) == 0 || len(key.Name) == 0 {
+			return nil, errors.New("api key entry without key or name in " + filename)
+		}
+		for endpoint, quota := range key.Quotas {
+			if err := quota.validate(); err != nil {
+				return nil, errors.New("invalid quota of endpoint " + endpoint + " of api key " + key.Name + " in " + filename + ": " + err.Error())
+			}
+		}
+	}
+	return keys, nil
+}
+
+func (ac *AccessControl) loadApiKeysFile() error {
+	info, err := os.Stat(ac.config.ApiKeysFile)
+	if err != nil {
+		return err
+	}
+	keys, err := LoadApiKeysFile(ac.config.ApiKeysFile)
+	if err != nil {
+		return err
+	}
+
+	ac.lock.Lock()
+	defer ac.lock.Unlock()
+
+	states := make(map[string]*apiKeyState)
+	for key, state := range ac.keys {
+		if state.name == configApiKeyName {
+			states[key] = state
+		}
+	}
+	for _, key := range keys {
+		// Keys that did not change keep their limiters, so that reloading does not refill
+		// their buckets
+		if state, ok := ac.keys[key.Key]; ok && reflect.DeepEqual(state.key, key) {
+			states[key.Key] = state
+			continue
+		}
+		states[key.Key] = newApiKeyState(key, &ac.config)
+	}
+	ac.keys = states
+	ac.fileModTime = info.ModTime()
+
+	log.Info("Loaded api keys", "file", ac.config.ApiKeysFile, "keys", len(keys))
+	return nil
+}
+
+func (ac *AccessControl) reloadLoop() {
+	defer close(ac.reloadDoneCh)
+
+	ticker := time.NewTicker(apiKeysFileReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			info, err := os.Stat(ac.config.ApiKeysFile)
+			if err != nil {
+				log.Warn("Failed to check api keys file", "file", ac.config.ApiKeysFile, "err", err)
+				continue
+			}
+			ac.lock.RLock()
+			changed := info.ModTime().Equal(ac.fileModTime) == false
+			ac.lock.RUnlock()
+			if changed == false {
+				continue
+			}
+			// The keys of a file that fails to load are kept until it is fixed
+			if err := ac.loadApiKeysFile(); err != nil {
+				log.Warn("Failed to reload api keys file", "file", ac.config.ApiKeysFile, "err", err)
+			}
+		case <-ac.quit:
+			return
+		}
+	}
+}
+
+// Close stops reloading the api keys file.
+func (ac *AccessControl) Close() {
+	close(ac.quit)
+	if ac.reloadDoneCh != nil {
+		<-ac.reloadDoneCh
+	}
+}
+
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if strings.Contains(proxy, "/") == false {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, errors.New("invalid trusted proxy " + proxy)
+			}
+			if ip.To4() != nil {
+				proxy = proxy + "/32"
+			} else {
+				proxy = proxy + "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, errors.New("invalid trusted proxy " + proxy)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+func (ac *AccessControl) isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range ac.proxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIp returns the ip address of the client of a request. The entries of the
+// X-Forwarded-For header are appended by each proxy, so only the ones added by the
+// trusted proxies can be relied on: the client can put anything before them.
+func (ac *AccessControl) clientIp(r *http.Request) string {
+	remoteIp, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteIp = r.RemoteAddr
+	}
+	if ac.config.TrustForwardedFor == false {
+		return remoteIp
+	}
+	if len(ac.proxies) > 0 && ac.isTrustedProxy(remoteIp) == false {
+		return remoteIp
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values(FORWARDED_FOR_HEADER_NAME) {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); len(hop) > 0 {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	if len(hops) == 0 {
+		return remoteIp
+	}
+	if len(ac.proxies) == 0 {
+		return hops[len(hops)-1]
+	}
+	for i := len(hops) - 1; i > 0; i-- {
+		if ac.isTrustedProxy(hops[i]) == false {
+			return hops[i]
+		}
+	}
+	return hops[0]
+}
+
+func (ac *AccessControl) ipState(ip string) *ipState {
+	if state, ok := ac.ips.Get(ip); ok {
+		return state.(*ipState)
+	}
+	state := &ipState{
+		limiter:   ac.config.IpLimit.newLimiter(),
+		endpoints: make(map[string]*rate.Limiter),
+	}
+	// Another request of the same ip may have added it in the meantime
+	if previous, ok, _ := ac.ips.PeekOrAdd(ip, state); ok {
+		return previous.(*ipState)
+	}
+	return state
+}
+
+func (s *ipState) endpointLimiter(endpoint string, limits map[string]RateLimit) *rate.Limiter {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	limiter, ok := s.endpoints[endpoint]
+	if !ok {
+		limiter = limits[endpoint].newLimiter()
+		s.endpoints[endpoint] = limiter
+	}
+	return limiter
+}
+
+// anonymousLimits returns the limits of the requests the ip address makes without a key,
+// which are the limits of a key, so that a client cannot use up the limits of the others.
+// The endpoint limits of the ip address already apply to them.
+func (s *ipState) anonymousLimits(config *AccessConfig) *keyLimits {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.anonymous == nil {
+		limits := newKeyLimits(config.KeyLimit, nil, []map[string]Quota{config.EndpointQuotas})
+		s.anonymous = &limits
+	}
+	return s.anonymous
+}
+
+// reserve takes a token from each of the limiters, and returns how long the request has
+// to wait if any of them is exhausted. No token is taken from any limiter in that case.
+func reserve(now time.Time, limiters ...*rate.Limiter) time.Duration {
+	reservations := make([]*rate.Reservation, 0, len(limiters))
+	var delay time.Duration
+	for _, limiter := range limiters {
+		if limiter == nil {
+			continue
+		}
+		r := limiter.ReserveN(now, 1)
+		reservations = append(reservations, r)
+		if r.OK() == false {
+			delay = time.Duration(math.MaxInt64)
+			continue
+		}
+		if d := r.DelayFrom(now); d > delay {
+			delay = d
+		}
+	}
+	if delay > 0 {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+	return delay
+}
+
+// Check authorizes a request of the given endpoint and applies the rate limits and quotas.
+// It returns ErrUnauthorized if the api key is missing or unknown, and ErrRateLimited or
+// ErrQuotaExceeded along with the time after which the request can be retried if it is
+// throttled. Requests without a key, if authorization is disabled, are limited per client
+// ip address like the requests of a key.
+func (ac *AccessControl) Check(r *http.Request, endpoint string) (time.Duration, error) {
+	return ac.check(r, endpoint, time.Now())
+}
+
+func (ac *AccessControl) check(r *http.Request, endpoint string, now time.Time) (time.Duration, error) {
+	apiKey := r.Header.Get(API_KEY_HEADER_NAME)
+
+	ac.lock.RLock()
+	key, ok := ac.keys[apiKey]
+	ac.lock.RUnlock()
+	if !ok && ac.config.EnableAuth {
+		return 0, ErrUnauthorized
+	}
+
+	ip := ac.ipState(ac.clientIp(r))
+	var limits *keyLimits
+	if ok {
+		limits = &key.keyLimits
+	} else {
+		key = ac.anonymous
+		limits = ip.anonymousLimits(&ac.config)
+	}
+	key.requests.Inc(1)
+
+	quota := limits.quotas[endpoint]
+	if quota != nil {
+		if delay := quota.take(now); delay > 0 {
+			key.throttled.Inc(1)
+			return delay, ErrQuotaExceeded
+		}
+	}
+	delay := reserve(now, ip.limiter, ip.endpointLimiter(endpoint, ac.config.EndpointLimits), limits.limiter, limits.endpoints[endpoint])
+	if delay > 0 {
+		if quota != nil {
+			quota.release()
+		}
+		key.throttled.Inc(1)
+		return delay, ErrRateLimited
+	}
+	return 0, nil
+}
+
+// RetryAfter formats a delay as the value of a Retry-After header, in whole seconds.
+func RetryAfter(delay time.Duration) string {
+	seconds := int64(math.Ceil(delay.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
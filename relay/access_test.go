@@ -0,0 +1,246 @@
+package relay
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func newTestAccessControl(t *testing.T, config AccessConfig) *AccessControl {
+	ac, err := NewAccessControl(config)
+	if err != nil {
+		t.Fatalf("NewAccessControl failed: %v", err)
+	}
+	t.Cleanup(ac.Close)
+	return ac
+}
+
+func TestReserve(t *testing.T) {
+	now := time.Now()
+	a := rate.NewLimiter(1, 1)
+	b := rate.NewLimiter(1, 2)
+
+	if delay := reserve(now, a, nil, b); delay != 0 {
+		t.Fatalf("expected the first request to be allowed, got a delay of %v", delay)
+	}
+	// a is exhausted, so no token is taken from b either
+	if delay := reserve(now, a, b); delay != time.Second {
+		t.Fatalf("expected a delay of 1s, got %v", delay)
+	}
+	if delay := reserve(now, b); delay != 0 {
+		t.Fatalf("expected the token taken from b to be given back, got a delay of %v", delay)
+	}
+	if delay := reserve(now.Add(time.Second), a, b); delay != 0 {
+		t.Fatalf("expected the request to be allowed after the delay, got %v", delay)
+	}
+	if delay := reserve(now, rate.NewLimiter(1, 0)); delay != time.Duration(1<<63-1) {
+		t.Fatalf("expected a request that can never be allowed to be delayed forever, got %v", delay)
+	}
+}
+
+func TestCheckApiKeys(t *testing.T) {
+	ac := newTestAccessControl(t, AccessConfig{
+		EnableAuth: true,
+		ApiKeys:    "key1,key2",
+		KeyLimit:   RateLimit{Rate: 1, Burst: 2},
+	})
+	now := time.Now()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	if _, err := ac.check(r, "GetLatestBlockDetails", now); err != ErrUnauthorized {
+		t.Fatalf("expected a request without key to be unauthorized, got %v", err)
+	}
+	r.Header.Set(API_KEY_HEADER_NAME, "unknown")
+	if _, err := ac.check(r, "GetLatestBlockDetails", now); err != ErrUnauthorized {
+		t.Fatalf("expected a request with an unknown key to be unauthorized, got %v", err)
+	}
+
+	r.Header.Set(API_KEY_HEADER_NAME, "key1")
+	for i := 0; i < 2; i++ {
+		if _, err := ac.check(r, "GetLatestBlockDetails", now); err != nil {
+			t.Fatalf("request %d: expected the burst to be allowed, got %v", i, err)
+		}
+	}
+	delay, err := ac.check(r, "GetLatestBlockDetails", now)
+	if err != ErrRateLimited || delay != time.Second {
+		t.Fatalf("expected the key to be limited for 1s, got %v %v", delay, err)
+	}
+
+	// Each key has its own bucket
+	r.Header.Set(API_KEY_HEADER_NAME, "key2")
+	if _, err := ac.check(r, "GetLatestBlockDetails", now); err != nil {
+		t.Fatalf("expected another key to be allowed, got %v", err)
+	}
+}
+
+func TestCheckAnonymousPerIp(t *testing.T) {
+	ac := newTestAccessControl(t, AccessConfig{
+		KeyLimit: RateLimit{Rate: 1, Burst: 1},
+	})
+	now := time.Now()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	if _, err := ac.check(r, "GetLatestBlockDetails", now); err != nil {
+		t.Fatalf("expected the first anonymous request to be allowed, got %v", err)
+	}
+	if _, err := ac.check(r, "GetLatestBlockDetails", now); err != ErrRateLimited {
+		t.Fatalf("expected the second anonymous request to be limited, got %v", err)
+	}
+
+	// Another client is not limited by the requests of the first one
+	r.RemoteAddr = "10.0.0.2:1234"
+	if _, err := ac.check(r, "GetLatestBlockDetails", now); err != nil {
+		t.Fatalf("expected another client to be allowed, got %v", err)
+	}
+}
+
+func TestCheckEndpointLimits(t *testing.T) {
+	ac := newTestAccessControl(t, AccessConfig{
+		ApiKeys:        "key1",
+		IpLimit:        RateLimit{Rate: 100, Burst: 3},
+		EndpointLimits: map[string]RateLimit{"SendTransaction": {Rate: 1, Burst: 1}},
+	})
+	now := time.Now()
+
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set(API_KEY_HEADER_NAME, "key1")
+	if _, err := ac.check(r, "SendTransaction", now); err != nil {
+		t.Fatalf("expected the first request to be allowed, got %v", err)
+	}
+	if _, err := ac.check(r, "SendTransaction", now); err != ErrRateLimited {
+		t.Fatalf("expected the endpoint to be limited, got %v", err)
+	}
+	if _, err := ac.check(r, "GetLatestBlockDetails", now); err != nil {
+		t.Fatalf("expected another endpoint to be allowed, got %v", err)
+	}
+	// The throttled request took no token from the ip limit
+	if _, err := ac.check(r, "GetLatestBlockDetails", now); err != nil {
+		t.Fatalf("expected the ip limit to allow a third request, got %v", err)
+	}
+	if _, err := ac.check(r, "GetLatestBlockDetails", now); err != ErrRateLimited {
+		t.Fatalf("expected the ip limit to be exhausted, got %v", err)
+	}
+}
+
+func TestCheckQuotas(t *testing.T) {
+	ac := newTestAccessControl(t, AccessConfig{
+		ApiKeys:        "key1",
+		EndpointQuotas: map[string]Quota{"SendTransaction": {Requests: 2, Period: "1h"}},
+	})
+	now := time.Now()
+
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set(API_KEY_HEADER_NAME, "key1")
+	for i := 0; i < 2; i++ {
+		if _, err := ac.check(r, "SendTransaction", now.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("request %d: expected the quota to allow it, got %v", i, err)
+		}
+	}
+	delay, err := ac.check(r, "SendTransaction", now.Add(10*time.Minute))
+	if err != ErrQuotaExceeded || delay != 50*time.Minute {
+		t.Fatalf("expected the quota to be exceeded for 50m, got %v %v", delay, err)
+	}
+	if _, err := ac.check(r, "GetLatestBlockDetails", now); err != nil {
+		t.Fatalf("expected an endpoint without quota to be allowed, got %v", err)
+	}
+	if _, err := ac.check(r, "SendTransaction", now.Add(time.Hour)); err != nil {
+		t.Fatalf("expected the quota of the next period to allow the request, got %v", err)
+	}
+
+	if _, err := NewAccessControl(AccessConfig{EndpointQuotas: map[string]Quota{"SendTransaction": {Requests: 1}}}); err == nil {
+		t.Fatal("expected a quota without period to be rejected")
+	}
+}
+
+func TestClientIp(t *testing.T) {
+	tests := []struct {
+		name         string
+		trust        bool
+		proxies      []string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{"not trusted", false, nil, "10.0.0.1:1234", []string{"1.1.1.1"}, "10.0.0.1"},
+		{"no header", true, nil, "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"last entry", true, nil, "10.0.0.1:1234", []string{"6.6.6.6, 1.1.1.1"}, "1.1.1.1"},
+		{"last header", true, nil, "10.0.0.1:1234", []string{"6.6.6.6", "1.1.1.1"}, "1.1.1.1"},
+		{"after trusted proxies", true, []string{"10.0.0.0/8", "192.168.1.1"}, "10.0.0.1:1234", []string{"6.6.6.6, 1.1.1.1, 192.168.1.1, 10.0.0.2"}, "1.1.1.1"},
+		{"request from an untrusted proxy", true, []string{"192.168.1.1"}, "10.0.0.1:1234", []string{"1.1.1.1"}, "10.0.0.1"},
+		{"only trusted proxies", true, []string{"10.0.0.0/8"}, "10.0.0.1:1234", []string{"10.0.0.3, 10.0.0.2"}, "10.0.0.3"},
+	}
+	for _, test := range tests {
+		ac := newTestAccessControl(t, AccessConfig{TrustForwardedFor: test.trust, TrustedProxies: test.proxies})
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = test.remoteAddr
+		for _, header := range test.forwardedFor {
+			r.Header.Add(FORWARDED_FOR_HEADER_NAME, header)
+		}
+		if ip := ac.clientIp(r); ip != test.want {
+			t.Errorf("%s: expected %s, got %s", test.name, test.want, ip)
+		}
+	}
+
+	if _, err := NewAccessControl(AccessConfig{TrustedProxies: []string{"not an ip"}}); err == nil {
+		t.Fatal("expected an invalid trusted proxy to be rejected")
+	}
+}
+
+func TestApiKeysFileReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay-access-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "keys.json")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`[{"name": "a", "key": "key-a", "rate": 1, "burst": 1}, {"name": "b", "key": "key-b", "rate": 1, "burst": 1}]`)
+	ac := newTestAccessControl(t, AccessConfig{EnableAuth: true, ApiKeys: "config-key", ApiKeysFile: file})
+	now := time.Now()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(API_KEY_HEADER_NAME, "key-a")
+	if _, err := ac.check(r, "GetLatestBlockDetails", now); err != nil {
+		t.Fatalf("expected key-a to be allowed, got %v", err)
+	}
+
+	// key-a is unchanged and keeps its empty bucket, key-b is removed and key-c added
+	write(`[{"name": "a", "key": "key-a", "rate": 1, "burst": 1}, {"name": "c", "key": "key-c"}]`)
+	if err := ac.loadApiKeysFile(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if _, err := ac.check(r, "GetLatestBlockDetails", now); err != ErrRateLimited {
+		t.Fatalf("expected key-a to keep its limiter across the reload, got %v", err)
+	}
+	for key, want := range map[string]error{"key-b": ErrUnauthorized, "key-c": nil, "config-key": nil} {
+		r.Header.Set(API_KEY_HEADER_NAME, key)
+		if _, err := ac.check(r, "GetLatestBlockDetails", now); err != want {
+			t.Errorf("%s: expected %v, got %v", key, want, err)
+		}
+	}
+
+	// A file that fails to load leaves the keys as they are
+	write(`[{"name": "d"}]`)
+	if err := ac.loadApiKeysFile(); err == nil {
+		t.Fatal("expected an entry without key to fail the reload")
+	}
+	r.Header.Set(API_KEY_HEADER_NAME, "key-c")
+	if _, err := ac.check(r, "GetLatestBlockDetails", now); err != nil {
+		t.Fatalf("expected key-c to be kept, got %v", err)
+	}
+	write(`[{"name": "e", "key": "key-e", "quotas": {"SendTransaction": {"requests": 1, "period": "forever"}}}]`)
+	if err := ac.loadApiKeysFile(); err == nil {
+		t.Fatal("expected an invalid quota to fail the reload")
+	}
+}
@@ -0,0 +1,500 @@
+package relay
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/ethdb"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/systemcontracts/conversion"
+	"github.com/DogeProtocol/dp/systemcontracts/staking"
+)
+
+const (
+	// addressIndexInterval is the interval at which the address index checks for new blocks.
+	addressIndexInterval = 2 * time.Second
+
+	// addressIndexReorgDepth is the number of recent blocks whose hash and addresses are kept,
+	// so that they can be unindexed if they are reorged out.
+	addressIndexReorgDepth = 128
+
+	// addressIndexCursorLength is the length of a pagination cursor, the inverted block
+	// number followed by the inverted transaction index.
+	addressIndexCursorLength = 12
+)
+
+const (
+	AddressTransactionTypeTransfer   = "transfer"
+	AddressTransactionTypeCall       = "call"
+	AddressTransactionTypeCreate     = "create"
+	AddressTransactionTypeStaking    = "staking"
+	AddressTransactionTypeConversion = "conversion"
+)
+
+var (
+	ErrAddressIndexDisabled     = errors.New("address index is not enabled")
+	ErrInvalidCursor            = errors.New("invalid cursor")
+	ErrAddressIndexReorgTooDeep = errors.New("address index reorg is deeper than the kept blocks, the index must be rebuilt")
+)
+
+var (
+	addressIndexHeadKey       = []byte("ih")
+	addressIndexBlockPrefix   = []byte("ib") // addressIndexBlockPrefix + num (uint64 big endian) -> indexed block
+	addressTransactionsPrefix = []byte("at") // addressTransactionsPrefix + address + ^num + ^txIndex -> AddressTransaction
+)
+
+// AddressTransaction is a transaction that an address took part in, either as the sender,
+// the recipient or an address in an event of the staking contract.
+type AddressTransaction struct {
+	Hash             common.Hash     `json:"hash"`
+	BlockHash        common.Hash     `json:"blockHash"`
+	BlockNumber      uint64          `json:"blockNumber"`
+	TransactionIndex uint64          `json:"transactionIndex"`
+	From             common.Address  `json:"from"`
+	To               *common.Address `json:"to"`
+	Value            *hexutil.Big    `json:"value"`
+	Type             string          `json:"type"`
+}
+
+// indexedBlock is kept for the recent blocks, to detect reorgs and unindex reorged blocks.
+type indexedBlock struct {
+	Hash      common.Hash      `json:"hash"`
+	Addresses []common.Address `json:"addresses"`
+}
+
+type rpcBlock struct {
+	Hash         common.Hash       `json:"hash"`
+	ParentHash   common.Hash       `json:"parentHash"`
+	Number       hexutil.Uint64    `json:"number"`
+	Timestamp    hexutil.Uint64    `json:"timestamp"`
+	Transactions []*rpcTransaction `json:"transactions"`
+}
+
+type rpcTransaction struct {
+	Hash             common.Hash     `json:"hash"`
+	From             common.Address  `json:"from"`
+	To               *common.Address `json:"to"`
+	Value            *hexutil.Big    `json:"value"`
+	Input            hexutil.Bytes   `json:"input"`
+	TransactionIndex hexutil.Uint64  `json:"transactionIndex"`
+}
+
+type rpcLog struct {
+	Address   common.Address `json:"address"`
+	Topics    []common.Hash  `json:"topics"`
+	TxHash    common.Hash    `json:"transactionHash"`
+	BlockHash common.Hash    `json:"blockHash"`
+	Removed   bool           `json:"removed"`
+}
+
+type rpcReceipt struct {
+	Logs []*rpcLog `json:"logs"`
+}
+
+// AddressIndex follows the head of the upstream nodes and indexes the transactions of
+// every address, so that the transaction history of an address can be listed.
+type AddressIndex struct {
+	db        ethdb.KeyValueStore
+	upstreams *UpstreamPool
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewAddressIndex creates an address index on top of the given store, that indexes the
+// blocks of the given upstream nodes once started.
+func NewAddressIndex(db ethdb.KeyValueStore, upstreams *UpstreamPool) *AddressIndex {
+	return &AddressIndex{
+		db:        db,
+		upstreams: upstreams,
+		quit:      make(chan struct{}),
+	}
+}
+
+// Start starts following the head of the upstream nodes.
+func (idx *AddressIndex) Start() {
+	idx.wg.Add(1)
+	go idx.loop()
+}
+
+// Close stops following the head and closes the underlying store.
+func (idx *AddressIndex) Close() error {
+	close(idx.quit)
+	idx.wg.Wait()
+	return idx.db.Close()
+}
+
+func (idx *AddressIndex) loop() {
+	defer idx.wg.Done()
+
+	ticker := time.NewTicker(addressIndexInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := idx.update(); err != nil {
+			log.Warn("Failed to update address index", "err", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-idx.quit:
+			return
+		}
+	}
+}
+
+// Head returns the number of the last indexed block, and false if no block is indexed.
+func (idx *AddressIndex) Head() (uint64, bool, error) {
+	has, err := idx.db.Has(addressIndexHeadKey)
+	if err != nil || has == false {
+		return 0, false, err
+	}
+	data, err := idx.db.Get(addressIndexHeadKey)
+	if err != nil {
+		return 0, false, err
+	}
+	return binary.BigEndian.Uint64(data), true, nil
+}
+
+// update indexes the blocks from the last indexed one up to the head of the upstream nodes.
+func (idx *AddressIndex) update() error {
+	ctx, cancel := context.WithTimeout(context.Background(), upstreamHealthCheckTimeout)
+	var latest hexutil.Uint64
+	err := idx.upstreams.CallContext(ctx, &latest, "eth_blockNumber")
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-idx.quit:
+			return nil
+		default:
+		}
+
+		head, ok, err := idx.Head()
+		if err != nil {
+			return err
+		}
+		next := uint64(0)
+		if ok {
+			next = head + 1
+		}
+		if next > uint64(latest) {
+			return nil
+		}
+
+		block, err := fetchBlock(idx.upstreams, next)
+		if err != nil {
+			return err
+		}
+		if block == nil {
+			// The upstream node that served the request is behind the others
+			return nil
+		}
+		if ok {
+			parent, err := idx.readIndexedBlock(head)
+			if err != nil {
+				return err
+			}
+			if parent == nil {
+				log.Error("Address index head block is not kept, reorgs can not be detected", "number", head)
+				return ErrAddressIndexReorgTooDeep
+			}
+			if parent.Hash != block.ParentHash {
+				if err := idx.rewind(head, parent); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if err := idx.indexBlock(block); err != nil {
+			return err
+		}
+	}
+}
+
+// rewind unindexes the blocks from head back to the last one that is in the chain of the
+// upstream nodes. The index can not be rewound past the recent blocks that are kept, in
+// which case ErrAddressIndexReorgTooDeep is returned and the index has to be rebuilt.
+func (idx *AddressIndex) rewind(head uint64, block *indexedBlock) error {
+	for {
+		log.Info("Address index reorg", "number", head, "hash", block.Hash)
+		if err := idx.unindexBlock(head, block); err != nil {
+			return err
+		}
+		if head == 0 {
+			return nil
+		}
+		head--
+
+		var err error
+		block, err = idx.readIndexedBlock(head)
+		if err != nil {
+			return err
+		}
+		if block == nil {
+			log.Error("Address index reorg is deeper than the kept blocks", "number", head, "depth", addressIndexReorgDepth)
+			return ErrAddressIndexReorgTooDeep
+		}
+		canonical, err := fetchBlock(idx.upstreams, head)
+		if err != nil {
+			return err
+		}
+		// The next update compares the parent of the next block if the upstream node that
+		// served the request is behind the others
+		if canonical == nil || canonical.Hash == block.Hash {
+			return nil
+		}
+	}
+}
+
+// fetchBlock fetches a block along with its transactions, and returns nil if the block
+// does not exist.
+func fetchBlock(upstreams *UpstreamPool, number uint64) (*rpcBlock, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), upstreamHealthCheckTimeout)
+	defer cancel()
+
+	var block *rpcBlock
+	err := upstreams.CallContext(ctx, &block, "eth_getBlockByNumber", hexutil.EncodeUint64(number), true)
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// transactionAddresses returns the addresses that took part in a transaction: the sender,
+// the recipient, and for staking transactions the addresses in the indexed topics of the
+// events that the staking contract emitted, such as the validator of a new deposit.
+func transactionAddresses(upstreams *UpstreamPool, tx *rpcTransaction) ([]common.Address, error) {
+	addresses := []common.Address{tx.From}
+	if tx.To == nil {
+		return addresses, nil
+	}
+	addresses = append(addresses, *tx.To)
+	if tx.To.IsEqualTo(staking.STAKING_CONTRACT_ADDRESS) == false {
+		return addresses, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), upstreamHealthCheckTimeout)
+	defer cancel()
+
+	var receipt *rpcReceipt
+	err := upstreams.CallContext(ctx, &receipt, "eth_getTransactionReceipt", tx.Hash)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	return append(addresses, stakingLogAddresses(receipt.Logs)...), nil
+}
+
+// stakingLogAddresses returns the addresses in the indexed topics of the events that the
+// staking contract emitted.
+func stakingLogAddresses(logs []*rpcLog) []common.Address {
+	var addresses []common.Address
+	for _, l := range logs {
+		if l.Address.IsEqualTo(staking.STAKING_CONTRACT_ADDRESS) == false || len(l.Topics) < 2 {
+			continue
+		}
+		// Addresses are as long as a topic, so indexed addresses are stored as is
+		for _, topic := range l.Topics[1:] {
+			addresses = append(addresses, common.BytesToAddress(topic.Bytes()))
+		}
+	}
+	return addresses
+}
+
+func addressTransactionType(tx *rpcTransaction) string {
+	switch {
+	case tx.To == nil:
+		return AddressTransactionTypeCreate
+	case tx.To.IsEqualTo(staking.STAKING_CONTRACT_ADDRESS):
+		return AddressTransactionTypeStaking
+	case tx.To.IsEqualTo(conversion.CONVERSION_CONTRACT_ADDRESS):
+		return AddressTransactionTypeConversion
+	case len(tx.Input) > 0:
+		return AddressTransactionTypeCall
+	default:
+		return AddressTransactionTypeTransfer
+	}
+}
+
+func newAddressTransaction(block *rpcBlock, tx *rpcTransaction) *AddressTransaction {
+	return &AddressTransaction{
+		Hash:             tx.Hash,
+		BlockHash:        block.Hash,
+		BlockNumber:      uint64(block.Number),
+		TransactionIndex: uint64(tx.TransactionIndex),
+		From:             tx.From,
+		To:               tx.To,
+		Value:            tx.Value,
+		Type:             addressTransactionType(tx),
+	}
+}
+
+func addressTransactionKey(address common.Address, blockNumber uint64, txIndex uint64) []byte {
+	key := make([]byte, 0, len(addressTransactionsPrefix)+common.AddressLength+addressIndexCursorLength)
+	key = append(key, addressTransactionsPrefix...)
+	key = append(key, address.Bytes()...)
+	return append(key, addressIndexCursor(blockNumber, txIndex)...)
+}
+
+// addressIndexCursor encodes the position of a transaction in the index. The block number
+// and transaction index are inverted, so that the most recent transactions come first.
+func addressIndexCursor(blockNumber uint64, txIndex uint64) []byte {
+	cursor := make([]byte, addressIndexCursorLength)
+	binary.BigEndian.PutUint64(cursor[:8], math.MaxUint64-blockNumber)
+	binary.BigEndian.PutUint32(cursor[8:], math.MaxUint32-uint32(txIndex))
+	return cursor
+}
+
+func addressIndexBlockKey(number uint64) []byte {
+	key := make([]byte, len(addressIndexBlockPrefix)+8)
+	copy(key, addressIndexBlockPrefix)
+	binary.BigEndian.PutUint64(key[len(addressIndexBlockPrefix):], number)
+	return key
+}
+
+func (idx *AddressIndex) readIndexedBlock(number uint64) (*indexedBlock, error) {
+	has, err := idx.db.Has(addressIndexBlockKey(number))
+	if err != nil || has == false {
+		return nil, err
+	}
+	data, err := idx.db.Get(addressIndexBlockKey(number))
+	if err != nil {
+		return nil, err
+	}
+	var block indexedBlock
+	if err := json.Unmarshal(data, &block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+func (idx *AddressIndex) indexBlock(block *rpcBlock) error {
+	number := uint64(block.Number)
+	batch := idx.db.NewBatch()
+	indexed := indexedBlock{Hash: block.Hash}
+	seen := make(map[common.Address]bool)
+
+	for _, tx := range block.Transactions {
+		entry := newAddressTransaction(block, tx)
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		addresses, err := transactionAddresses(idx.upstreams, tx)
+		if err != nil {
+			return err
+		}
+		for _, address := range addresses {
+			if err := batch.Put(addressTransactionKey(address, number, entry.TransactionIndex), data); err != nil {
+				return err
+			}
+			if seen[address] == false {
+				seen[address] = true
+				indexed.Addresses = append(indexed.Addresses, address)
+			}
+		}
+	}
+
+	data, err := json.Marshal(indexed)
+	if err != nil {
+		return err
+	}
+	if err := batch.Put(addressIndexBlockKey(number), data); err != nil {
+		return err
+	}
+	if number >= addressIndexReorgDepth {
+		if err := batch.Delete(addressIndexBlockKey(number - addressIndexReorgDepth)); err != nil {
+			return err
+		}
+	}
+	head := make([]byte, 8)
+	binary.BigEndian.PutUint64(head, number)
+	if err := batch.Put(addressIndexHeadKey, head); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// unindexBlock removes the transactions of a block that was reorged out, and sets the head
+// back to its parent.
+func (idx *AddressIndex) unindexBlock(number uint64, block *indexedBlock) error {
+	batch := idx.db.NewBatch()
+	for _, address := range block.Addresses {
+		prefix := append(append(common.CopyBytes(addressTransactionsPrefix), address.Bytes()...), addressIndexCursor(number, 0)[:8]...)
+		it := idx.db.NewIterator(prefix, nil)
+		for it.Next() {
+			if err := batch.Delete(common.CopyBytes(it.Key())); err != nil {
+				it.Release()
+				return err
+			}
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return err
+		}
+	}
+	if err := batch.Delete(addressIndexBlockKey(number)); err != nil {
+		return err
+	}
+	if number == 0 {
+		if err := batch.Delete(addressIndexHeadKey); err != nil {
+			return err
+		}
+	} else {
+		head := make([]byte, 8)
+		binary.BigEndian.PutUint64(head, number-1)
+		if err := batch.Put(addressIndexHeadKey, head); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// Transactions returns up to limit transactions of an address, most recent first, between
+// fromBlock and toBlock inclusive. Listing starts at the given cursor if it is not empty,
+// and the returned cursor is empty when there are no more transactions.
+func (idx *AddressIndex) Transactions(address common.Address, fromBlock uint64, toBlock uint64, cursor string, limit int) ([]*AddressTransaction, string, error) {
+	start := addressIndexCursor(toBlock, math.MaxUint32)
+	if cursor != "" {
+		decoded, err := hexutil.Decode(cursor)
+		if err != nil || len(decoded) != addressIndexCursorLength {
+			return nil, "", ErrInvalidCursor
+		}
+		start = decoded
+	}
+	prefix := append(common.CopyBytes(addressTransactionsPrefix), address.Bytes()...)
+
+	it := idx.db.NewIterator(prefix, start)
+	defer it.Release()
+
+	txs := make([]*AddressTransaction, 0, limit)
+	for it.Next() {
+		position := it.Key()[len(prefix):]
+		blockNumber := math.MaxUint64 - binary.BigEndian.Uint64(position[:8])
+		if blockNumber < fromBlock {
+			break
+		}
+		if blockNumber > toBlock {
+			continue
+		}
+		if len(txs) == limit {
+			return txs, hexutil.Encode(position), nil
+		}
+		var tx AddressTransaction
+		if err := json.Unmarshal(it.Value(), &tx); err != nil {
+			return nil, "", err
+		}
+		txs = append(txs, &tx)
+	}
+	return txs, "", it.Error()
+}
@@ -0,0 +1,189 @@
+package relay
+
+import (
+	"testing"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/ethdb/memorydb"
+)
+
+// testBlockHash returns the hash of a test block of the given fork.
+func testBlockHash(fork byte, number uint64) common.Hash {
+	return common.BytesToHash([]byte{fork, byte(number >> 8), byte(number)})
+}
+
+// setTestChain sets the blocks from first to last of the given fork, with a transaction
+// from the sender to the recipient in each block, and sets the head to last.
+func setTestChain(s *testEthService, fork byte, first uint64, last uint64, from common.Address, to common.Address) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for number := first; number <= last; number++ {
+		parentFork := fork
+		if number == first && first > 0 {
+			parentFork = s.blocks[first-1].Hash[common.HashLength-3]
+		}
+		s.blocks[number] = &rpcBlock{
+			Hash:       testBlockHash(fork, number),
+			ParentHash: testBlockHash(parentFork, number-1),
+			Number:     hexutil.Uint64(number),
+			Transactions: []*rpcTransaction{{
+				Hash:  common.BytesToHash([]byte{fork, byte(number >> 8), byte(number), 1}),
+				From:  from,
+				To:    &to,
+				Value: (*hexutil.Big)(common.Big1),
+			}},
+		}
+	}
+	s.head = last
+}
+
+func newTestAddressIndex(t *testing.T, service *testEthService) *AddressIndex {
+	return NewAddressIndex(memorydb.New(), newTestUpstreamPool(t, newTestUpstream(t, "inproc", service)))
+}
+
+// listAddressTransactions lists all the transactions of an address, limit at a time.
+func listAddressTransactions(t *testing.T, idx *AddressIndex, address common.Address, fromBlock uint64, toBlock uint64, limit int) []*AddressTransaction {
+	t.Helper()
+	var all []*AddressTransaction
+	cursor := ""
+	for {
+		txs, next, err := idx.Transactions(address, fromBlock, toBlock, cursor, limit)
+		if err != nil {
+			t.Fatalf("Transactions failed: %v", err)
+		}
+		if len(txs) > limit || (next != "" && len(txs) != limit) {
+			t.Fatalf("Transactions returned %d transactions with cursor %q, limit %d", len(txs), next, limit)
+		}
+		all = append(all, txs...)
+		if next == "" {
+			return all
+		}
+		cursor = next
+	}
+}
+
+func checkAddressTransactions(t *testing.T, txs []*AddressTransaction, fork byte, numbers ...uint64) {
+	t.Helper()
+	if len(txs) != len(numbers) {
+		t.Fatalf("got %d transactions, want %d", len(txs), len(numbers))
+	}
+	for i, number := range numbers {
+		if txs[i].BlockNumber != number || txs[i].BlockHash != testBlockHash(fork, number) {
+			t.Fatalf("transaction %d is in block %d %s, want %d %s", i, txs[i].BlockNumber, txs[i].BlockHash, number, testBlockHash(fork, number))
+		}
+	}
+}
+
+func TestAddressIndexTransactions(t *testing.T) {
+	service := newTestEthService()
+	from, to := common.BytesToAddress([]byte{1}), common.BytesToAddress([]byte{2})
+	setTestChain(service, 0, 0, 9, from, to)
+	idx := newTestAddressIndex(t, service)
+
+	if err := idx.update(); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if head, ok, err := idx.Head(); err != nil || ok == false || head != 9 {
+		t.Fatalf("head is %d %v %v, want 9", head, ok, err)
+	}
+
+	// Every page size lists the same transactions, the most recent first
+	for _, limit := range []int{1, 3, 10, 20} {
+		for _, address := range []common.Address{from, to} {
+			txs := listAddressTransactions(t, idx, address, 0, 9, limit)
+			checkAddressTransactions(t, txs, 0, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0)
+		}
+	}
+	checkAddressTransactions(t, listAddressTransactions(t, idx, from, 3, 5, 2), 0, 5, 4, 3)
+	if txs := listAddressTransactions(t, idx, common.BytesToAddress([]byte{3}), 0, 9, 2); len(txs) != 0 {
+		t.Fatalf("unknown address has %d transactions", len(txs))
+	}
+
+	// A cursor continues from the transaction it points to
+	page, cursor, err := idx.Transactions(from, 0, 9, "", 4)
+	if err != nil || len(page) != 4 || cursor == "" {
+		t.Fatalf("Transactions returned %d transactions, cursor %q, %v", len(page), cursor, err)
+	}
+	checkAddressTransactions(t, page, 0, 9, 8, 7, 6)
+	page, _, err = idx.Transactions(from, 0, 9, cursor, 2)
+	if err != nil {
+		t.Fatalf("Transactions failed: %v", err)
+	}
+	checkAddressTransactions(t, page, 0, 5, 4)
+
+	for _, cursor := range []string{"zz", "0x01", hexutil.Encode(make([]byte, addressIndexCursorLength+1))} {
+		if _, _, err := idx.Transactions(from, 0, 9, cursor, 2); err != ErrInvalidCursor {
+			t.Fatalf("cursor %q: expected ErrInvalidCursor, got %v", cursor, err)
+		}
+	}
+}
+
+func TestAddressIndexReorg(t *testing.T) {
+	service := newTestEthService()
+	from, to, other := common.BytesToAddress([]byte{1}), common.BytesToAddress([]byte{2}), common.BytesToAddress([]byte{3})
+	setTestChain(service, 0, 0, 5, from, to)
+	idx := newTestAddressIndex(t, service)
+	if err := idx.update(); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	// The blocks after 2 are replaced by a longer fork, whose transactions go to another
+	// address
+	setTestChain(service, 1, 3, 7, from, other)
+	if err := idx.update(); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if head, _, err := idx.Head(); err != nil || head != 7 {
+		t.Fatalf("head is %d %v, want 7", head, err)
+	}
+	checkAddressTransactions(t, listAddressTransactions(t, idx, to, 0, 7, 2), 0, 2, 1, 0)
+	checkAddressTransactions(t, listAddressTransactions(t, idx, other, 0, 7, 2), 1, 7, 6, 5, 4, 3)
+	txs := listAddressTransactions(t, idx, from, 0, 7, 3)
+	checkAddressTransactions(t, txs[:5], 1, 7, 6, 5, 4, 3)
+	checkAddressTransactions(t, txs[5:], 0, 2, 1, 0)
+
+	// The reorged blocks are no longer kept
+	for number := uint64(3); number <= 7; number++ {
+		block, err := idx.readIndexedBlock(number)
+		if err != nil || block == nil || block.Hash != testBlockHash(1, number) {
+			t.Fatalf("indexed block %d is %+v, %v", number, block, err)
+		}
+	}
+
+	// A fork at the same height replaces the head
+	setTestChain(service, 2, 7, 7, from, to)
+	service.lock.Lock()
+	service.blocks[8] = &rpcBlock{Hash: testBlockHash(2, 8), ParentHash: testBlockHash(2, 7), Number: 8}
+	service.head = 8
+	service.lock.Unlock()
+	if err := idx.update(); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	checkAddressTransactions(t, listAddressTransactions(t, idx, other, 0, 8, 2), 1, 6, 5, 4, 3)
+	checkAddressTransactions(t, listAddressTransactions(t, idx, to, 7, 8, 2), 2, 7)
+}
+
+func TestAddressIndexReorgTooDeep(t *testing.T) {
+	service := newTestEthService()
+	from, to := common.BytesToAddress([]byte{1}), common.BytesToAddress([]byte{2})
+	last := uint64(addressIndexReorgDepth + 10)
+	setTestChain(service, 0, 0, last, from, to)
+	idx := newTestAddressIndex(t, service)
+	if err := idx.update(); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	// A fork deeper than the kept blocks can not be followed
+	setTestChain(service, 1, 5, last+1, from, to)
+	if err := idx.update(); err != ErrAddressIndexReorgTooDeep {
+		t.Fatalf("expected ErrAddressIndexReorgTooDeep, got %v", err)
+	}
+	if err := idx.update(); err != ErrAddressIndexReorgTooDeep {
+		t.Fatalf("expected ErrAddressIndexReorgTooDeep on the next update, got %v", err)
+	}
+	head, _, err := idx.Head()
+	if err != nil || head != last-addressIndexReorgDepth {
+		t.Fatalf("head is %d %v, want %d", head, err, last-addressIndexReorgDepth)
+	}
+}
@@ -3,10 +3,18 @@ package relay
 import "errors"
 
 var (
-	InfoTitleLatestBlockDetails = "Get latest block details"
-	InfoTitleAccountDetails     = "Get account details"
-	InfoTitleTransaction        = "Get Transaction"
-	InfoTitleSendTransaction    = "Send Transaction"
+	InfoTitleLatestBlockDetails  = "Get latest block details"
+	InfoTitleAccountDetails      = "Get account details"
+	InfoTitleTransaction         = "Get Transaction"
+	InfoTitleAccountTransactions = "Get account transactions"
+	InfoTitleSendTransaction     = "Send Transaction"
+	InfoTitleDryRunTransaction   = "Dry run Transaction"
+	InfoTitleStreamEvents        = "Stream events"
+	InfoTitleStakingDetails      = "Get staking details"
+	InfoTitleValidatorDetails    = "Get validator staking details"
+	InfoTitleDepositorDetails    = "Get depositor staking details"
+	InfoTitleBlockConsensus      = "Get block consensus details"
+	InfoTitleConversionDetails   = "Get conversion details"
 )
 
 var (
@@ -17,19 +25,27 @@ var (
 	MsgBlockNumber        = "Block number"
 	MsgHash               = "Hash"
 	MsgTransaction        = "Transaction"
+	MsgTransactions       = "Transactions"
 	MsgTransactionReceipt = "Transaction receipt"
+	MsgStakingDetails     = "Staking details"
+	MsgConsensusData      = "Consensus data"
+	MsgConversionDetails  = "Conversion details"
 	MsgSend               = "Send"
 	MsgRawRawTxHex        = "Raw tx hex"
 	MsgRawTxData          = "Raw tx data"
+	MsgValidation         = "Validation"
 	MsgTimeDuration       = "Time Duration"
 	MsgStatus             = "Status"
 	MsgError              = "Error"
 )
 
 var (
-	ErrEmptyAddress   = errors.New("empty address")
-	ErrInvalidAddress = errors.New("invalid address")
-	ErrEmptyHash      = errors.New("empty hash")
-	ErrInvalidHash    = errors.New("invalid hash")
-	ErrEmptyRawTxHex  = errors.New("empty raw tx")
+	ErrEmptyAddress      = errors.New("empty address")
+	ErrInvalidAddress    = errors.New("invalid address")
+	ErrEmptyHash         = errors.New("empty hash")
+	ErrInvalidHash       = errors.New("invalid hash")
+	ErrInvalidBlockRange = errors.New("invalid block range")
+	ErrUnknownBlock      = errors.New("unknown block")
+	ErrInvalidEthAddress = errors.New("invalid ethereum address")
+	ErrEmptyRawTxHex     = errors.New("empty raw tx")
 )
@@ -12,6 +12,7 @@ package qcreadapi
 
 import (
 	"context"
+	"github.com/DogeProtocol/dp/relay"
 	"net/http"
 )
 
@@ -23,7 +24,15 @@ import (
 type ReadApiAPIRouter interface { 
 	GetLatestBlockDetails(http.ResponseWriter, *http.Request)
 	GetAccountDetails(http.ResponseWriter, *http.Request)
+	GetAccountTransactions(http.ResponseWriter, *http.Request)
+	StreamEvents(http.ResponseWriter, *http.Request)
+	StreamEventsWebSocket(http.ResponseWriter, *http.Request)
 	GetTransactionDetails(http.ResponseWriter, *http.Request)
+	GetStakingDetails(http.ResponseWriter, *http.Request)
+	GetValidatorDetails(http.ResponseWriter, *http.Request)
+	GetDepositorDetails(http.ResponseWriter, *http.Request)
+	GetBlockConsensusDetails(http.ResponseWriter, *http.Request)
+	GetConversionDetails(http.ResponseWriter, *http.Request)
 }
 
 
@@ -34,5 +43,12 @@ type ReadApiAPIRouter interface {
 type ReadApiAPIServicer interface { 
 	GetLatestBlockDetails(context.Context) (ImplResponse, error)
 	GetAccountDetails(context.Context, string) (ImplResponse, error)
+	GetAccountTransactions(context.Context, string, int64, int64, string, int32) (ImplResponse, error)
+	SubscribeEvents(context.Context, relay.StreamRequest) (ImplResponse, *relay.StreamSubscription, error)
 	GetTransactionDetails(context.Context, string) (ImplResponse, error)
+	GetStakingDetails(context.Context, int64) (ImplResponse, error)
+	GetValidatorDetails(context.Context, string, int64) (ImplResponse, error)
+	GetDepositorDetails(context.Context, string, int64) (ImplResponse, error)
+	GetBlockConsensusDetails(context.Context, int64) (ImplResponse, error)
+	GetConversionDetails(context.Context, string) (ImplResponse, error)
 }
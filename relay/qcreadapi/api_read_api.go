@@ -11,15 +11,15 @@
 package qcreadapi
 
 import (
-	"errors"
 	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/relay"
+	"math"
 	"net/http"
 	"strings"
 
 	"github.com/gorilla/mux"
 )
 
-const API_KEY_HEADER_NAME = "X-Api-Key"
 const REQUEST_ID_HEADER_NAME = "X-Request-Id"
 
 // ReadApiAPIController binds http requests to an api service and writes the service results to the http response
@@ -27,8 +27,7 @@ type ReadApiAPIController struct {
 	service ReadApiAPIServicer
 	errorHandler ErrorHandler
 	corsAllowedOrigins string
-	enableAuth bool
-	apiKeysMap map[string]bool
+	access *relay.AccessControl
 }
 
 // ReadApiAPIOption for how the controller is set up.
@@ -42,23 +41,12 @@ func WithReadApiAPIErrorHandler(h ErrorHandler) ReadApiAPIOption {
 }
 
 // NewReadApiAPIController creates a default api controller
-func NewReadApiAPIController(s ReadApiAPIServicer, corsAllowedOrigins string, enableAuth bool, apiKeys string, opts ...ReadApiAPIOption) *ReadApiAPIController {
+func NewReadApiAPIController(s ReadApiAPIServicer, corsAllowedOrigins string, access *relay.AccessControl, opts ...ReadApiAPIOption) *ReadApiAPIController {
 	controller := &ReadApiAPIController{
 		service:      s,
 		errorHandler: DefaultErrorHandler,
 		corsAllowedOrigins: corsAllowedOrigins,
-		enableAuth: enableAuth,
-		apiKeysMap: make(map[string]bool),
-	}
-
-	if len(apiKeys) > 0 {
-		apiKeyList := strings.Split(apiKeys, ",")
-		for _, key := range apiKeyList {
-			if len(key) == 0 {
-				continue
-			}
-			controller.apiKeysMap[key] = true
-		}
+		access: access,
 	}
 
 	for _, opt := range opts {
@@ -81,11 +69,51 @@ func (c *ReadApiAPIController) Routes() Routes {
 			"/account/{address}",
 			c.GetAccountDetails,
 		},
+		"GetAccountTransactions": Route{
+			strings.ToUpper("Get"),
+			"/account/{address}/transactions",
+			c.GetAccountTransactions,
+		},
+		"StreamEvents": Route{
+			strings.ToUpper("Get"),
+			"/stream",
+			c.StreamEvents,
+		},
+		"StreamEventsWebSocket": Route{
+			strings.ToUpper("Get"),
+			"/stream/ws",
+			c.StreamEventsWebSocket,
+		},
 		"GetTransactionDetails": Route{
 			strings.ToUpper("Get"),
 			"/transaction/{hash}",
 			c.GetTransactionDetails,
 		},
+		"GetStakingDetails": Route{
+			strings.ToUpper("Get"),
+			"/staking",
+			c.GetStakingDetails,
+		},
+		"GetValidatorDetails": Route{
+			strings.ToUpper("Get"),
+			"/staking/validator/{address}",
+			c.GetValidatorDetails,
+		},
+		"GetDepositorDetails": Route{
+			strings.ToUpper("Get"),
+			"/staking/depositor/{address}",
+			c.GetDepositorDetails,
+		},
+		"GetBlockConsensusDetails": Route{
+			strings.ToUpper("Get"),
+			"/block/{blockNumber}/consensus",
+			c.GetBlockConsensusDetails,
+		},
+		"GetConversionDetails": Route{
+			strings.ToUpper("Get"),
+			"/conversion/{ethAddress}",
+			c.GetConversionDetails,
+		},
 	}
 }
 
@@ -96,24 +124,18 @@ func (c *ReadApiAPIController) setupCORS(w *http.ResponseWriter, req *http.Reque
 	(*w).Header().Set("Access-Control-Allow-Headers", "*")
 }
 
-func (c *ReadApiAPIController) authorize(req *http.Request) bool {
-	if c.enableAuth == false {
-		return  true
-	}
-
-	if req.Header == nil {
-		return  false
-	}
-
-	apiKey := req.Header.Get(API_KEY_HEADER_NAME)
-
-	if len(apiKey) == 0 {
-		return false
+func (c *ReadApiAPIController) authorize(w http.ResponseWriter, r *http.Request, endpoint string) bool {
+	retryAfter, err := c.access.Check(r, endpoint)
+	if err == nil {
+		return true
 	}
 
-	if c.apiKeysMap[apiKey] == true {
-		return true
+	result := Response(http.StatusUnauthorized, nil)
+	if err == relay.ErrRateLimited || err == relay.ErrQuotaExceeded {
+		w.Header().Set(relay.RETRY_AFTER_HEADER_NAME, relay.RetryAfter(retryAfter))
+		result = Response(http.StatusTooManyRequests, nil)
 	}
+	c.errorHandler(w, r, err, &result)
 
 	return false
 }
@@ -133,12 +155,7 @@ func (c *ReadApiAPIController) GetLatestBlockDetails(w http.ResponseWriter, r *h
 		return
 	}
 
-	if c.authorize(r) == false {
-		result := Response(http.StatusUnauthorized, nil)
-		// If no error, encode the body and the result code
-		_ = EncodeJSONResponse(result.Body, &result.Code, w)
-
-		c.errorHandler(w, r, errors.New("Unauthorized"), &result)
+	if c.authorize(w, r, "GetLatestBlockDetails") == false {
 		return
 	}
 
@@ -167,12 +184,7 @@ func (c *ReadApiAPIController) GetAccountDetails(w http.ResponseWriter, r *http.
 		return
 	}
 
-	if c.authorize(r) == false {
-		result := Response(http.StatusUnauthorized, nil)
-		// If no error, encode the body and the result code
-		_ = EncodeJSONResponse(result.Body, &result.Code, w)
-
-		c.errorHandler(w, r, errors.New("Unauthorized"), &result)
+	if c.authorize(w, r, "GetAccountDetails") == false {
 		return
 	}
 
@@ -193,6 +205,76 @@ func (c *ReadApiAPIController) GetAccountDetails(w http.ResponseWriter, r *http.
 	_ = EncodeJSONResponse(result.Body, &result.Code, w)
 }
 
+// GetAccountTransactions - Get account transactions
+func (c *ReadApiAPIController) GetAccountTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Header != nil {
+		requestId := r.Header.Get(REQUEST_ID_HEADER_NAME)
+
+		if len(requestId) > 0 {
+			log.Info("GetAccountTransactions", "requestId", requestId)
+		}
+	}
+
+	c.setupCORS(&w, r)
+	if (*r).Method == "OPTIONS" {
+		return
+	}
+
+	if c.authorize(w, r, "GetAccountTransactions") == false {
+		return
+	}
+
+	params := mux.Vars(r)
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	addressParam := params["address"]
+	if addressParam == "" {
+		c.errorHandler(w, r, &RequiredError{"address"}, nil)
+		return
+	}
+	fromBlockParam, err := parseNumericParameter[int64](
+		query.Get("fromBlock"),
+		WithDefaultOrParse[int64](0, parseInt64),
+		WithMinimum[int64](0),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Param: "fromBlock", Err: err}, nil)
+		return
+	}
+	toBlockParam, err := parseNumericParameter[int64](
+		query.Get("toBlock"),
+		WithDefaultOrParse[int64](math.MaxInt64, parseInt64),
+		WithMinimum[int64](0),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Param: "toBlock", Err: err}, nil)
+		return
+	}
+	cursorParam := query.Get("cursor")
+	pageSizeParam, err := parseNumericParameter[int32](
+		query.Get("pageSize"),
+		WithDefaultOrParse[int32](25, parseInt32),
+		WithMinimum[int32](1),
+		WithMaximum[int32](100),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Param: "pageSize", Err: err}, nil)
+		return
+	}
+
+	result, err := c.service.GetAccountTransactions(r.Context(), addressParam, fromBlockParam, toBlockParam, cursorParam, pageSizeParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
 // GetTransaction - Get Transaction
 func (c *ReadApiAPIController) GetTransactionDetails(w http.ResponseWriter, r *http.Request) {
 	if r.Header != nil {
@@ -208,12 +290,7 @@ func (c *ReadApiAPIController) GetTransactionDetails(w http.ResponseWriter, r *h
 		return
 	}
 
-	if c.authorize(r) == false {
-		result := Response(http.StatusUnauthorized, nil)
-		// If no error, encode the body and the result code
-		_ = EncodeJSONResponse(result.Body, &result.Code, w)
-
-		c.errorHandler(w, r, errors.New("Unauthorized"), &result)
+	if c.authorize(w, r, "GetTransactionDetails") == false {
 		return
 	}
 
@@ -233,3 +310,226 @@ func (c *ReadApiAPIController) GetTransactionDetails(w http.ResponseWriter, r *h
 	// If no error, encode the body and the result code
 	_ = EncodeJSONResponse(result.Body, &result.Code, w)
 }
+
+// GetStakingDetails - Get staking details
+func (c *ReadApiAPIController) GetStakingDetails(w http.ResponseWriter, r *http.Request) {
+	if r.Header != nil {
+		requestId := r.Header.Get(REQUEST_ID_HEADER_NAME)
+
+		if len(requestId) > 0 {
+			log.Info("GetStakingDetails", "requestId", requestId)
+		}
+	}
+
+	c.setupCORS(&w, r)
+	if (*r).Method == "OPTIONS" {
+		return
+	}
+
+	if c.authorize(w, r, "GetStakingDetails") == false {
+		return
+	}
+
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	// Without a block number, the details are retrieved as of the latest block
+	blockNumberParam, err := parseNumericParameter[int64](
+		query.Get("blockNumber"),
+		WithDefaultOrParse[int64](-1, parseInt64),
+		WithMinimum[int64](0),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Param: "blockNumber", Err: err}, nil)
+		return
+	}
+
+	result, err := c.service.GetStakingDetails(r.Context(), blockNumberParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetValidatorDetails - Get the staking details of a validator
+func (c *ReadApiAPIController) GetValidatorDetails(w http.ResponseWriter, r *http.Request) {
+	if r.Header != nil {
+		requestId := r.Header.Get(REQUEST_ID_HEADER_NAME)
+
+		if len(requestId) > 0 {
+			log.Info("GetValidatorDetails", "requestId", requestId)
+		}
+	}
+
+	c.setupCORS(&w, r)
+	if (*r).Method == "OPTIONS" {
+		return
+	}
+
+	if c.authorize(w, r, "GetValidatorDetails") == false {
+		return
+	}
+
+	params := mux.Vars(r)
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	// Without a block number, the details are retrieved as of the latest block
+	blockNumberParam, err := parseNumericParameter[int64](
+		query.Get("blockNumber"),
+		WithDefaultOrParse[int64](-1, parseInt64),
+		WithMinimum[int64](0),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Param: "blockNumber", Err: err}, nil)
+		return
+	}
+	addressParam := params["address"]
+	if addressParam == "" {
+		c.errorHandler(w, r, &RequiredError{"address"}, nil)
+		return
+	}
+
+	result, err := c.service.GetValidatorDetails(r.Context(), addressParam, blockNumberParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetDepositorDetails - Get the staking details of the validator of a depositor
+func (c *ReadApiAPIController) GetDepositorDetails(w http.ResponseWriter, r *http.Request) {
+	if r.Header != nil {
+		requestId := r.Header.Get(REQUEST_ID_HEADER_NAME)
+
+		if len(requestId) > 0 {
+			log.Info("GetDepositorDetails", "requestId", requestId)
+		}
+	}
+
+	c.setupCORS(&w, r)
+	if (*r).Method == "OPTIONS" {
+		return
+	}
+
+	if c.authorize(w, r, "GetDepositorDetails") == false {
+		return
+	}
+
+	params := mux.Vars(r)
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	// Without a block number, the details are retrieved as of the latest block
+	blockNumberParam, err := parseNumericParameter[int64](
+		query.Get("blockNumber"),
+		WithDefaultOrParse[int64](-1, parseInt64),
+		WithMinimum[int64](0),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Param: "blockNumber", Err: err}, nil)
+		return
+	}
+	addressParam := params["address"]
+	if addressParam == "" {
+		c.errorHandler(w, r, &RequiredError{"address"}, nil)
+		return
+	}
+
+	result, err := c.service.GetDepositorDetails(r.Context(), addressParam, blockNumberParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetBlockConsensusDetails - Get the consensus details of a block
+func (c *ReadApiAPIController) GetBlockConsensusDetails(w http.ResponseWriter, r *http.Request) {
+	if r.Header != nil {
+		requestId := r.Header.Get(REQUEST_ID_HEADER_NAME)
+
+		if len(requestId) > 0 {
+			log.Info("GetBlockConsensusDetails", "requestId", requestId)
+		}
+	}
+
+	c.setupCORS(&w, r)
+	if (*r).Method == "OPTIONS" {
+		return
+	}
+
+	if c.authorize(w, r, "GetBlockConsensusDetails") == false {
+		return
+	}
+
+	params := mux.Vars(r)
+	blockNumberParam, err := parseNumericParameter[int64](
+		params["blockNumber"],
+		WithRequire[int64](parseInt64),
+		WithMinimum[int64](0),
+	)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Param: "blockNumber", Err: err}, nil)
+		return
+	}
+
+	result, err := c.service.GetBlockConsensusDetails(r.Context(), blockNumberParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
+
+// GetConversionDetails - Get the conversion status of an ethereum address
+func (c *ReadApiAPIController) GetConversionDetails(w http.ResponseWriter, r *http.Request) {
+	if r.Header != nil {
+		requestId := r.Header.Get(REQUEST_ID_HEADER_NAME)
+
+		if len(requestId) > 0 {
+			log.Info("GetConversionDetails", "requestId", requestId)
+		}
+	}
+
+	c.setupCORS(&w, r)
+	if (*r).Method == "OPTIONS" {
+		return
+	}
+
+	if c.authorize(w, r, "GetConversionDetails") == false {
+		return
+	}
+
+	params := mux.Vars(r)
+	ethAddressParam := params["ethAddress"]
+	if ethAddressParam == "" {
+		c.errorHandler(w, r, &RequiredError{"ethAddress"}, nil)
+		return
+	}
+
+	result, err := c.service.GetConversionDetails(r.Context(), ethAddressParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	_ = EncodeJSONResponse(result.Body, &result.Code, w)
+}
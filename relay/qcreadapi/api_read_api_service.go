@@ -18,10 +18,10 @@ import (
 	"github.com/DogeProtocol/dp/relay"
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/common/hexutil"
-	"github.com/DogeProtocol/dp/rpc"
 	"net/http"
 	"errors"
 	"github.com/mattn/go-colorable"
+	"math/big"
 	"time"
 )
 
@@ -29,7 +29,9 @@ import (
 // This service should implement the business logic for every endpoint for the ReadApiAPI API.
 // Include any external packages or services that will be required by this service.
 type ReadApiAPIService struct {
-  DpUrl string
+	Upstreams    *relay.UpstreamPool
+	AddressIndex *relay.AddressIndex
+	Streams      *relay.StreamHub
 }
 
 type RPCTransaction struct {
@@ -48,10 +50,11 @@ type RPCTransaction struct {
 	Type             hexutil.Uint64    `json:"type"`
 }
 
-// NewReadApiAPIService creates a default api service
-func NewReadApiAPIService(dpUrl string) *ReadApiAPIService {
+// NewReadApiAPIService creates a default api service. The address index is optional; without
+// it, the transactions of an account cannot be listed.
+func NewReadApiAPIService(upstreams *relay.UpstreamPool, addressIndex *relay.AddressIndex, streams *relay.StreamHub) *ReadApiAPIService {
 	log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(3), log.StreamHandler(colorable.NewColorableStderr(), log.TerminalFormat(true))))
-	return &ReadApiAPIService{DpUrl: dpUrl}
+	return &ReadApiAPIService{Upstreams: upstreams, AddressIndex: addressIndex, Streams: streams}
 }
 
 // GetLatestBlockDetails - Get latest block details
@@ -59,17 +62,8 @@ func (s *ReadApiAPIService) GetLatestBlockDetails(ctx context.Context) (ImplResp
 
 	startTime := time.Now()
 
-	log.Info(relay.InfoTitleLatestBlockDetails, relay.MsgDial, s.DpUrl)
-
-	client, err := rpc.Dial(s.DpUrl)
-	if err != nil {
-		log.Error(relay.MsgDial, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
-		return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
-	}
-	defer client.Close()
-
 	var blockNumber *hexutil.Uint64
-	err = client.CallContext(ctx, &blockNumber, "eth_blockNumber")
+	err := s.Upstreams.CallContext(ctx, &blockNumber, "eth_blockNumber")
 	if err != nil {
 		log.Error(relay.MsgBlockNumber, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
 		return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
@@ -93,36 +87,27 @@ func (s *ReadApiAPIService) GetAccountDetails(ctx context.Context, address strin
 
 	startTime := time.Now()
 
-	log.Info(relay.InfoTitleAccountDetails, relay.MsgDial, s.DpUrl)
-
-	client, err := rpc.Dial(s.DpUrl)
-	if err != nil {
-		log.Error(relay.MsgDial, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
-		return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
-	}
-	defer client.Close()
-
 	if !common.IsHexAddress(address) {
 		log.Error(relay.MsgAddress, relay.MsgAddress, address, relay.MsgError, relay.ErrInvalidAddress, relay.MsgStatus, http.StatusBadRequest)
 		return Response(http.StatusBadRequest, nil), relay.ErrInvalidAddress
 	}
 
 	var balance *hexutil.Big
-	err = client.CallContext(ctx, &balance, "eth_getBalance", common.HexToAddress(address), "latest")
+	err := s.Upstreams.CallContext(ctx, &balance, "eth_getBalance", common.HexToAddress(address), "latest")
 	if err != nil {
 		log.Error(relay.MsgBalance, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
 		return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
 	}
 
 	var nonce *hexutil.Big
-	err = client.CallContext(ctx, &nonce, "eth_getTransactionCount", common.HexToAddress(address), "latest")
+	err = s.Upstreams.CallContext(ctx, &nonce, "eth_getTransactionCount", common.HexToAddress(address), "latest")
 	if err != nil {
 		log.Error(relay.MsgNonce, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
 		return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
 	}
 
 	var blockNumber *hexutil.Uint64
-	err = client.CallContext(ctx, &blockNumber, "eth_blockNumber")
+	err = s.Upstreams.CallContext(ctx, &blockNumber, "eth_blockNumber")
 	if err != nil {
 		log.Error(relay.MsgBlockNumber, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
 		return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
@@ -158,28 +143,107 @@ func (s *ReadApiAPIService) GetAccountDetails(ctx context.Context, address strin
 		AccountDetails{&b,&n,&l}}), nil
 }
 
-// GetTransactionDetails - Get transaction Details
-func (s *ReadApiAPIService) GetTransactionDetails(ctx context.Context, hash string) (ImplResponse, error) {
+// GetAccountTransactions - Get account transactions
+func (s *ReadApiAPIService) GetAccountTransactions(ctx context.Context, address string, fromBlock int64, toBlock int64, cursor string, pageSize int32) (ImplResponse, error) {
 
 	startTime := time.Now()
-	isDiscarded := false
-	discardReason := ""
-	log.Info(relay.InfoTitleTransaction, relay.MsgDial, s.DpUrl)
 
-	client, err := rpc.Dial(s.DpUrl)
+	if s.AddressIndex == nil {
+		log.Error(relay.MsgTransactions, relay.MsgError, relay.ErrAddressIndexDisabled, relay.MsgStatus, http.StatusServiceUnavailable)
+		return Response(http.StatusServiceUnavailable, nil), relay.ErrAddressIndexDisabled
+	}
+
+	if !common.IsHexAddress(address) {
+		log.Error(relay.MsgAddress, relay.MsgAddress, address, relay.MsgError, relay.ErrInvalidAddress, relay.MsgStatus, http.StatusBadRequest)
+		return Response(http.StatusBadRequest, nil), relay.ErrInvalidAddress
+	}
+
+	if fromBlock > toBlock {
+		log.Error(relay.MsgBlockNumber, relay.MsgError, relay.ErrInvalidBlockRange, relay.MsgStatus, http.StatusBadRequest)
+		return Response(http.StatusBadRequest, nil), relay.ErrInvalidBlockRange
+	}
+
+	indexed, next, err := s.AddressIndex.Transactions(common.HexToAddress(address), uint64(fromBlock), uint64(toBlock), cursor, int(pageSize))
+	if err == relay.ErrInvalidCursor {
+		log.Error(relay.MsgTransactions, relay.MsgError, err, relay.MsgStatus, http.StatusBadRequest)
+		return Response(http.StatusBadRequest, nil), err
+	}
 	if err != nil {
-		log.Error(relay.MsgDial,relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
+		log.Error(relay.MsgTransactions, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
 		return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
 	}
-	defer client.Close()
 
+	head, ok, err := s.AddressIndex.Head()
+	if err != nil {
+		log.Error(relay.MsgBlockNumber, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
+		return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
+	}
+
+	txns := AccountTransactions{Items: make([]AccountTransaction, 0, len(indexed))}
+	for _, tx := range indexed {
+		txn := AccountTransaction{
+			Hash:             tx.Hash.String(),
+			BlockHash:        tx.BlockHash.String(),
+			BlockNumber:      int64(tx.BlockNumber),
+			TransactionIndex: int64(tx.TransactionIndex),
+			From:             tx.From.String(),
+			Type:             tx.Type,
+		}
+		if tx.To != nil {
+			to := tx.To.String()
+			txn.To = &to
+		}
+		if tx.Value != nil {
+			txn.Value = tx.Value.String()
+		}
+		txns.Items = append(txns.Items, txn)
+	}
+	if len(next) > 0 {
+		txns.NextCursor = &next
+	}
+	if ok {
+		h := int64(head)
+		txns.IndexedBlockNumber = &h
+	}
+
+	duration := time.Now().Sub(startTime)
+
+	log.Info(relay.InfoTitleAccountTransactions, relay.MsgAddress, address, relay.MsgTimeDuration, duration, relay.MsgStatus, http.StatusOK)
+
+	return Response(http.StatusOK, AccountTransactionsResponse{txns}), nil
+}
+
+// SubscribeEvents - Subscribe to new blocks, address activity and transaction status
+func (s *ReadApiAPIService) SubscribeEvents(ctx context.Context, req relay.StreamRequest) (ImplResponse, *relay.StreamSubscription, error) {
+
+	sub, err := s.Streams.Subscribe(req)
+	if err == relay.ErrTooManyStreams {
+		log.Error(relay.InfoTitleStreamEvents, relay.MsgError, err, relay.MsgStatus, http.StatusServiceUnavailable)
+		return Response(http.StatusServiceUnavailable, nil), nil, err
+	}
+	if err != nil {
+		log.Error(relay.InfoTitleStreamEvents, relay.MsgError, err, relay.MsgStatus, http.StatusBadRequest)
+		return Response(http.StatusBadRequest, nil), nil, err
+	}
+
+	log.Info(relay.InfoTitleStreamEvents, "blocks", req.Blocks, "addresses", len(req.Addresses), "transactions", len(req.Transactions))
+
+	return Response(http.StatusOK, nil), sub, nil
+}
+
+// GetTransactionDetails - Get transaction Details
+func (s *ReadApiAPIService) GetTransactionDetails(ctx context.Context, hash string) (ImplResponse, error) {
+
+	startTime := time.Now()
+	isDiscarded := false
+	discardReason := ""
 	if !common.IsHexAddress(hash)  {
 		log.Error(relay.MsgHash, relay.MsgHash, hash, relay.MsgError, relay.ErrInvalidHash, relay.MsgStatus, http.StatusBadRequest)
 		return  Response(http.StatusBadRequest, nil), relay.ErrInvalidHash
 	}
 
 	var raw json.RawMessage
-	err =  client.CallContext(ctx, &raw, "eth_getTransactionByHash", common.HexToHash(hash))
+	err :=  s.Upstreams.CallContext(ctx, &raw, "eth_getTransactionByHash", common.HexToHash(hash))
 	if err != nil {
 		log.Error(relay.MsgTransaction, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
 		return  Response(http.StatusInternalServerError, nil), errors.New(err.Error())
@@ -229,7 +293,7 @@ func (s *ReadApiAPIService) GetTransactionDetails(ctx context.Context, hash stri
 		value := rpcTxn.Value.String()
 
 		var receipt map[string]interface{}
-		err =  client.CallContext(ctx, &receipt, "eth_getTransactionReceipt", common.HexToHash(hash))
+		err =  s.Upstreams.CallContext(ctx, &receipt, "eth_getTransactionReceipt", common.HexToHash(hash))
 		if err != nil {
 			log.Error(relay.MsgTransactionReceipt, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusServiceUnavailable)
 			return  Response(http.StatusServiceUnavailable, nil), errors.New(err.Error())
@@ -281,6 +345,309 @@ func (s *ReadApiAPIService) GetTransactionDetails(ctx context.Context, hash stri
 	return  Response(http.StatusNotFound,nil), nil
 }
 
+// rpcValidatorDetails is a validator as returned by the proofofstake api of the node. The
+// amounts are hex encoded; the fields that the first version of the staking contract does
+// not have are empty before it was upgraded.
+type rpcValidatorDetails struct {
+	Depositor          common.Address `json:"depositor"`
+	Validator          common.Address `json:"validator"`
+	Balance            string         `json:"balance"`
+	NetBalance         string         `json:"netBalance"`
+	BlockRewards       string         `json:"blockRewards"`
+	Slashings          string         `json:"slashings"`
+	IsValidationPaused bool           `json:"isValidationPaused"`
+	WithdrawalBlock    string         `json:"withdrawalBlock"`
+	WithdrawalAmount   string         `json:"withdrawalAmount"`
+	LastNiLBlock       string         `json:"lastNiLBlock"`
+	NilBlockCount      string         `json:"nilBlockCount"`
+}
+
+type rpcStakingDetails struct {
+	TotalDepositedBalance string                 `json:"totalDepositedBalance"`
+	Validators            []*rpcValidatorDetails `json:"validators"`
+}
+
+type rpcConsensusData struct {
+	Data struct {
+		BlockProposer          common.Address   `json:"blockProposer"`
+		VoteType               byte             `json:"voteType"`
+		ProposalHash           common.Hash      `json:"proposalHash"`
+		PrecommitHash          common.Hash      `json:"precommitHash"`
+		NilVotedBlockProposers []common.Address `json:"nilvotedBlockProposers"`
+		Round                  byte             `json:"Round"`
+		SelectedTransactions   []common.Hash    `json:"selectedTransactions"`
+		BlockTime              uint64           `json:"blockTime"`
+	} `json:"data"`
+	AdditionalData struct {
+		InitTime uint64 `json:"initTime"`
+	} `json:"additionalData"`
+	ExtendedConsensusPackets []struct {
+		Signer     common.Address `json:"signer"`
+		PacketType byte           `json:"packetType"`
+		Round      byte           `json:"round"`
+	} `json:"extendedConsensusPackets"`
+	BlockProposerRewards string `json:"blockProposerRewards"`
+}
+
+type rpcConversionDetails struct {
+	QuantumAddress common.Address `json:"quantumAddress"`
+	IsConverted    bool           `json:"isConverted"`
+	Coins          *big.Int       `json:"coins"`
+}
+
+// The vote types of a block, as in proofofstake.VOTE_TYPE_OK and VOTE_TYPE_NIL
+var voteTypes = map[byte]string{
+	1: "ok",
+	2: "nil",
+}
+
+// decodeHexBig decodes an amount returned by the proofofstake api, which is empty for the
+// fields that the validator does not have.
+func decodeHexBig(s string) (*big.Int, error) {
+	if len(s) == 0 {
+		return new(big.Int), nil
+	}
+	return hexutil.DecodeBig(s)
+}
+
+func newValidatorDetails(v *rpcValidatorDetails) (ValidatorDetails, error) {
+	var amounts [8]*big.Int
+	for i, s := range []string{v.Balance, v.NetBalance, v.BlockRewards, v.Slashings, v.WithdrawalBlock,
+		v.WithdrawalAmount, v.LastNiLBlock, v.NilBlockCount} {
+		amount, err := decodeHexBig(s)
+		if err != nil {
+			return ValidatorDetails{}, err
+		}
+		amounts[i] = amount
+	}
+	return ValidatorDetails{
+		Depositor:          v.Depositor.String(),
+		Validator:          v.Validator.String(),
+		Balance:            amounts[0].String(),
+		NetBalance:         amounts[1].String(),
+		BlockRewards:       amounts[2].String(),
+		Slashings:          amounts[3].String(),
+		IsValidationPaused: v.IsValidationPaused,
+		WithdrawalBlock:    amounts[4].Int64(),
+		WithdrawalAmount:   amounts[5].String(),
+		LastNilBlock:       amounts[6].Int64(),
+		NilBlockCount:      amounts[7].Int64(),
+	}, nil
+}
+
+// blockNumberOrLatest returns the given block number, or the latest block number if it
+// is negative, so that the details of several calls are retrieved as of the same block.
+func (s *ReadApiAPIService) blockNumberOrLatest(ctx context.Context, blockNumber int64) (int64, error) {
+	if blockNumber >= 0 {
+		return blockNumber, nil
+	}
+	var latest hexutil.Uint64
+	if err := s.Upstreams.CallContext(ctx, &latest, "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+	return int64(latest), nil
+}
+
+// proofOfStakeErrorStatus returns the status of an error of a proofofstake call. The node
+// fails with an unknown block error for the blocks that it does not have yet.
+func proofOfStakeErrorStatus(err error) int {
+	if err.Error() == relay.ErrUnknownBlock.Error() {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// GetStakingDetails - Get staking details
+func (s *ReadApiAPIService) GetStakingDetails(ctx context.Context, blockNumber int64) (ImplResponse, error) {
+
+	startTime := time.Now()
+
+	blockNumber, err := s.blockNumberOrLatest(ctx, blockNumber)
+	if err != nil {
+		log.Error(relay.MsgBlockNumber, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
+		return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
+	}
+
+	var details *rpcStakingDetails
+	err = s.Upstreams.CallContext(ctx, &details, "proofofstake_getStakingDetails", hexutil.EncodeUint64(uint64(blockNumber)))
+	if err != nil {
+		status := proofOfStakeErrorStatus(err)
+		log.Error(relay.MsgStakingDetails, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, status)
+		return Response(status, nil), errors.New(err.Error())
+	}
+
+	totalDepositedBalance, err := decodeHexBig(details.TotalDepositedBalance)
+	if err != nil {
+		log.Error(relay.MsgStakingDetails, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
+		return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
+	}
+
+	stakingDetails := StakingDetails{
+		TotalDepositedBalance: totalDepositedBalance.String(),
+		Validators:            make([]ValidatorDetails, 0, len(details.Validators)),
+		BlockNumber:           &blockNumber,
+	}
+	for _, v := range details.Validators {
+		validator, err := newValidatorDetails(v)
+		if err != nil {
+			log.Error(relay.MsgStakingDetails, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
+			return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
+		}
+		stakingDetails.Validators = append(stakingDetails.Validators, validator)
+	}
+
+	duration := time.Now().Sub(startTime)
+
+	log.Info(relay.InfoTitleStakingDetails, relay.MsgBlockNumber, blockNumber, relay.MsgTimeDuration, duration, relay.MsgStatus, http.StatusOK)
+
+	return Response(http.StatusOK, StakingDetailsResponse{stakingDetails}), nil
+}
+
+// GetValidatorDetails - Get the staking details of a validator
+func (s *ReadApiAPIService) GetValidatorDetails(ctx context.Context, address string, blockNumber int64) (ImplResponse, error) {
+	return s.getValidatorDetails(ctx, relay.InfoTitleValidatorDetails, "proofofstake_getStakingDetailsByValidatorAddress", address, blockNumber)
+}
+
+// GetDepositorDetails - Get the staking details of the validator of a depositor
+func (s *ReadApiAPIService) GetDepositorDetails(ctx context.Context, address string, blockNumber int64) (ImplResponse, error) {
+	return s.getValidatorDetails(ctx, relay.InfoTitleDepositorDetails, "proofofstake_getStakingDetailsByDepositorAddress", address, blockNumber)
+}
+
+func (s *ReadApiAPIService) getValidatorDetails(ctx context.Context, title string, method string, address string, blockNumber int64) (ImplResponse, error) {
+
+	startTime := time.Now()
+
+	if !common.IsHexAddress(address) {
+		log.Error(relay.MsgAddress, relay.MsgAddress, address, relay.MsgError, relay.ErrInvalidAddress, relay.MsgStatus, http.StatusBadRequest)
+		return Response(http.StatusBadRequest, nil), relay.ErrInvalidAddress
+	}
+
+	blockNumber, err := s.blockNumberOrLatest(ctx, blockNumber)
+	if err != nil {
+		log.Error(relay.MsgBlockNumber, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
+		return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
+	}
+
+	var details *rpcValidatorDetails
+	err = s.Upstreams.CallContext(ctx, &details, method, common.HexToAddress(address), hexutil.EncodeUint64(uint64(blockNumber)))
+	if err != nil {
+		status := proofOfStakeErrorStatus(err)
+		log.Error(relay.MsgStakingDetails, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, status)
+		return Response(status, nil), errors.New(err.Error())
+	}
+	if details == nil {
+		log.Info(title, relay.MsgAddress, address, relay.MsgStatus, http.StatusNotFound)
+		return Response(http.StatusNotFound, nil), nil
+	}
+
+	validator, err := newValidatorDetails(details)
+	if err != nil {
+		log.Error(relay.MsgStakingDetails, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
+		return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
+	}
+	validator.BlockNumber = &blockNumber
+
+	duration := time.Now().Sub(startTime)
+
+	log.Info(title, relay.MsgAddress, address, relay.MsgTimeDuration, duration, relay.MsgStatus, http.StatusOK)
+
+	return Response(http.StatusOK, ValidatorDetailsResponse{validator}), nil
+}
+
+// GetBlockConsensusDetails - Get the consensus details of a block
+func (s *ReadApiAPIService) GetBlockConsensusDetails(ctx context.Context, blockNumber int64) (ImplResponse, error) {
+
+	startTime := time.Now()
+
+	var data *rpcConsensusData
+	err := s.Upstreams.CallContext(ctx, &data, "proofofstake_getBlockConsensusData", hexutil.EncodeUint64(uint64(blockNumber)))
+	if err != nil {
+		status := proofOfStakeErrorStatus(err)
+		log.Error(relay.MsgConsensusData, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, status)
+		return Response(status, nil), errors.New(err.Error())
+	}
+
+	rewards, err := decodeHexBig(data.BlockProposerRewards)
+	if err != nil {
+		log.Error(relay.MsgConsensusData, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
+		return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
+	}
+
+	details := BlockConsensusDetails{
+		BlockNumber:            blockNumber,
+		VoteType:               voteTypes[data.Data.VoteType],
+		Round:                  int32(data.Data.Round),
+		ProposalHash:           data.Data.ProposalHash.String(),
+		PrecommitHash:          data.Data.PrecommitHash.String(),
+		NilVotedBlockProposers: make([]string, 0, len(data.Data.NilVotedBlockProposers)),
+		SelectedTransactions:   make([]string, 0, len(data.Data.SelectedTransactions)),
+		BlockTime:              int64(data.Data.BlockTime),
+		InitTime:               int64(data.AdditionalData.InitTime),
+		BlockProposerRewards:   rewards.String(),
+		Packets:                make([]ConsensusPacket, 0, len(data.ExtendedConsensusPackets)),
+	}
+	if data.Data.BlockProposer != (common.Address{}) {
+		proposer := data.Data.BlockProposer.String()
+		details.BlockProposer = &proposer
+	}
+	for _, proposer := range data.Data.NilVotedBlockProposers {
+		details.NilVotedBlockProposers = append(details.NilVotedBlockProposers, proposer.String())
+	}
+	for _, hash := range data.Data.SelectedTransactions {
+		details.SelectedTransactions = append(details.SelectedTransactions, hash.String())
+	}
+	for _, packet := range data.ExtendedConsensusPackets {
+		details.Packets = append(details.Packets, ConsensusPacket{
+			Signer:     packet.Signer.String(),
+			PacketType: int32(packet.PacketType),
+			Round:      int32(packet.Round),
+		})
+	}
+
+	duration := time.Now().Sub(startTime)
+
+	log.Info(relay.InfoTitleBlockConsensus, relay.MsgBlockNumber, blockNumber, relay.MsgTimeDuration, duration, relay.MsgStatus, http.StatusOK)
+
+	return Response(http.StatusOK, BlockConsensusDetailsResponse{details}), nil
+}
+
+// GetConversionDetails - Get the conversion status of an ethereum address
+func (s *ReadApiAPIService) GetConversionDetails(ctx context.Context, ethAddress string) (ImplResponse, error) {
+
+	startTime := time.Now()
+
+	if !common.IsLegacyEthereumHexAddress(ethAddress) {
+		log.Error(relay.MsgAddress, relay.MsgAddress, ethAddress, relay.MsgError, relay.ErrInvalidEthAddress, relay.MsgStatus, http.StatusBadRequest)
+		return Response(http.StatusBadRequest, nil), relay.ErrInvalidEthAddress
+	}
+
+	var details *rpcConversionDetails
+	err := s.Upstreams.CallContext(ctx, &details, "proofofstake_getConversionDetails", ethAddress)
+	if err != nil {
+		log.Error(relay.MsgConversionDetails, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
+		return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
+	}
+
+	conversionDetails := ConversionDetails{
+		EthAddress:  hexutil.Encode(common.FromHex(ethAddress)),
+		IsConverted: details.IsConverted,
+		Coins:       "0",
+	}
+	if details.Coins != nil {
+		conversionDetails.Coins = details.Coins.String()
+	}
+	if details.IsConverted {
+		quantumAddress := details.QuantumAddress.String()
+		conversionDetails.QuantumAddress = &quantumAddress
+	}
+
+	duration := time.Now().Sub(startTime)
+
+	log.Info(relay.InfoTitleConversionDetails, relay.MsgAddress, ethAddress, relay.MsgTimeDuration, duration, relay.MsgStatus, http.StatusOK)
+
+	return Response(http.StatusOK, ConversionDetailsResponse{conversionDetails}), nil
+}
+
 func Dump(data interface{}){
 	b,_:=json.MarshalIndent(data, "", "  ")
 	fmt.Print(string(b))
@@ -0,0 +1,266 @@
+/*
+ * QC Read API
+ *
+ * Streaming endpoints. They are described by the OpenAPI document, but not generated from it.
+ *
+ * API version: v1
+ */
+
+package qcreadapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/relay"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// streamPingInterval is the interval at which idle streams are pinged, so that proxies
+	// do not close them.
+	streamPingInterval = 30 * time.Second
+
+	// streamWriteTimeout is the maximum time to write an event to a WebSocket client.
+	streamWriteTimeout = 10 * time.Second
+
+	// streamMaxMessageSize is the maximum size of a message from a WebSocket client.
+	streamMaxMessageSize = 64 * 1024
+)
+
+// parseStreamRequest parses the events selected by the query parameters of a stream request:
+// blocks=true, addresses and transactions as comma separated lists, and confirmations.
+func parseStreamRequest(r *http.Request) (relay.StreamRequest, error) {
+	var req relay.StreamRequest
+
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		return req, &ParsingError{Err: err}
+	}
+	req.Blocks, err = parseBoolParameter(query.Get("blocks"), WithParse[bool](parseBool))
+	if err != nil {
+		return req, &ParsingError{Param: "blocks", Err: err}
+	}
+	for _, address := range strings.Split(query.Get("addresses"), ",") {
+		if len(address) == 0 {
+			continue
+		}
+		if !common.IsHexAddress(address) {
+			return req, &ParsingError{Param: "addresses", Err: relay.ErrInvalidAddress}
+		}
+		req.Addresses = append(req.Addresses, common.HexToAddress(address))
+	}
+	for _, hash := range strings.Split(query.Get("transactions"), ",") {
+		if len(hash) == 0 {
+			continue
+		}
+		if !common.IsHexAddress(hash) {
+			return req, &ParsingError{Param: "transactions", Err: relay.ErrInvalidHash}
+		}
+		req.Transactions = append(req.Transactions, common.HexToHash(hash))
+	}
+	confirmations, err := parseNumericParameter[int64](
+		query.Get("confirmations"),
+		WithDefaultOrParse[int64](relay.DefaultStreamConfirmations, parseInt64),
+		WithMinimum[int64](1),
+		WithMaximum[int64](relay.MaxStreamConfirmations),
+	)
+	if err != nil {
+		return req, &ParsingError{Param: "confirmations", Err: err}
+	}
+	req.Confirmations = uint64(confirmations)
+
+	return req, nil
+}
+
+func (c *ReadApiAPIController) subscribe(w http.ResponseWriter, r *http.Request, endpoint string) *relay.StreamSubscription {
+	if r.Header != nil {
+		requestId := r.Header.Get(REQUEST_ID_HEADER_NAME)
+
+		if len(requestId) > 0 {
+			log.Info(endpoint, "requestId", requestId)
+		}
+	}
+
+	c.setupCORS(&w, r)
+	if (*r).Method == "OPTIONS" {
+		return nil
+	}
+
+	if c.authorize(w, r, endpoint) == false {
+		return nil
+	}
+
+	req, err := parseStreamRequest(r)
+	if err != nil {
+		c.errorHandler(w, r, err, nil)
+		return nil
+	}
+
+	result, sub, err := c.service.SubscribeEvents(r.Context(), req)
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return nil
+	}
+	return sub
+}
+
+// StreamEvents - Stream events as Server-Sent Events
+func (c *ReadApiAPIController) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		result := Response(http.StatusInternalServerError, nil)
+		c.errorHandler(w, r, fmt.Errorf("streaming is not supported"), &result)
+		return
+	}
+
+	sub := c.subscribe(w, r, "StreamEvents")
+	if sub == nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case event := <-sub.Events():
+			data, err := json.Marshal(event)
+			if err != nil {
+				log.Error("StreamEvents", relay.MsgError, err)
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", streamEventType(event), data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case err := <-sub.Err():
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", strconv.Quote(err.Error()))
+			flusher.Flush()
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// StreamEventsWebSocket - Stream events over a WebSocket. The client can watch more
+// addresses and transactions by sending StreamRequest messages.
+func (c *ReadApiAPIController) StreamEventsWebSocket(w http.ResponseWriter, r *http.Request) {
+	sub := c.subscribe(w, r, "StreamEventsWebSocket")
+	if sub == nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	upgrader := websocket.Upgrader{
+		CheckOrigin: c.checkOrigin,
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has written the error response
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(streamMaxMessageSize)
+
+	// Requests are read on their own goroutine; all writes are done below
+	requests := make(chan relay.StreamRequest)
+	closed := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(closed)
+		for {
+			var req relay.StreamRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			select {
+			case requests <- req:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	write := func(v interface{}) error {
+		conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		return conn.WriteJSON(v)
+	}
+	for {
+		select {
+		case event := <-sub.Events():
+			if err := write(event); err != nil {
+				return
+			}
+		case req := <-requests:
+			if err := sub.Add(req); err != nil {
+				if err := write(streamError(err, http.StatusBadRequest)); err != nil {
+					return
+				}
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		case err := <-sub.Err():
+			write(streamError(err, http.StatusServiceUnavailable))
+			return
+		case <-closed:
+			return
+		}
+	}
+}
+
+// checkOrigin allows WebSocket connections from the origins allowed by the CORS settings.
+func (c *ReadApiAPIController) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(origin) == 0 || c.corsAllowedOrigins == "*" {
+		return true
+	}
+	for _, allowed := range strings.Split(c.corsAllowedOrigins, ",") {
+		if strings.EqualFold(strings.TrimSpace(allowed), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func streamError(err error, status int32) ErrorResponseModel {
+	message := err.Error()
+	return ErrorResponseModel{Message: &message, Status: status}
+}
+
+func streamEventType(event interface{}) string {
+	switch event.(type) {
+	case *relay.BlockEvent:
+		return relay.StreamEventTypeBlock
+	case *relay.AddressEvent:
+		return relay.StreamEventTypeAddress
+	case *relay.TransactionEvent:
+		return relay.StreamEventTypeTransaction
+	}
+	return "message"
+}
@@ -13,13 +13,13 @@ package qcwriteapi
 import (
 	"encoding/json"
 	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/relay"
 	"io"
 	"net/http"
 	"strings"
 	"errors"
 )
 
-const API_KEY_HEADER_NAME = "X-Api-Key"
 const REQUEST_ID_HEADER_NAME = "X-Request-Id"
 
 // WriteApiAPIController binds http requests to an api service and writes the service results to the http response
@@ -27,8 +27,7 @@ type WriteApiAPIController struct {
 	service WriteApiAPIServicer
 	errorHandler ErrorHandler
 	corsAllowedOrigins string
-	enableAuth bool
-	apiKeysMap map[string]bool
+	access *relay.AccessControl
 }
 
 // WriteApiAPIOption for how the controller is set up.
@@ -42,23 +41,12 @@ func WithWriteApiAPIErrorHandler(h ErrorHandler) WriteApiAPIOption {
 }
 
 // NewWriteApiAPIController creates a default api controller
-func NewWriteApiAPIController(s WriteApiAPIServicer, corsAllowedOrigins string, enableAuth bool, apiKeys string, opts ...WriteApiAPIOption) *WriteApiAPIController {
+func NewWriteApiAPIController(s WriteApiAPIServicer, corsAllowedOrigins string, access *relay.AccessControl, opts ...WriteApiAPIOption) *WriteApiAPIController {
 	controller := &WriteApiAPIController{
 		service:      s,
 		errorHandler: DefaultErrorHandler,
 		corsAllowedOrigins: corsAllowedOrigins,
-		enableAuth: enableAuth,
-		apiKeysMap: make(map[string]bool),
-	}
-
-	if len(apiKeys) > 0 {
-		apiKeyList := strings.Split(apiKeys, ",")
-		for _, key := range apiKeyList {
-			if len(key) == 0 {
-				continue
-			}
-			controller.apiKeysMap[key] = true
-		}
+		access: access,
 	}
 
 	for _, opt := range opts {
@@ -85,24 +73,18 @@ func (c *WriteApiAPIController) setupCORS(w *http.ResponseWriter, req *http.Requ
 	(*w).Header().Set("Access-Control-Allow-Headers", "*")
 }
 
-func (c *WriteApiAPIController) authorize(w http.ResponseWriter, req *http.Request) bool {
-	if c.enableAuth == false {
-		return  true
-	}
-
-	if req.Header == nil {
-		return  false
-	}
-
-	apiKey := req.Header.Get(API_KEY_HEADER_NAME)
-
-	if len(apiKey) == 0 {
-		return false
+func (c *WriteApiAPIController) authorize(w http.ResponseWriter, r *http.Request, endpoint string) bool {
+	retryAfter, err := c.access.Check(r, endpoint)
+	if err == nil {
+		return true
 	}
 
-	if c.apiKeysMap[apiKey] == true {
-		return true
+	result := Response(http.StatusUnauthorized, nil)
+	if err == relay.ErrRateLimited || err == relay.ErrQuotaExceeded {
+		w.Header().Set(relay.RETRY_AFTER_HEADER_NAME, relay.RetryAfter(retryAfter))
+		result = Response(http.StatusTooManyRequests, nil)
 	}
+	c.errorHandler(w, r, err, &result)
 
 	return false
 }
@@ -122,12 +104,7 @@ func (c *WriteApiAPIController) SendTransaction(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	if c.authorize(w, r) == false {
-		result := Response(http.StatusUnauthorized, nil)
-		// If no error, encode the body and the result code
-		_ = EncodeJSONResponse(result.Body, &result.Code, w)
-
-		c.errorHandler(w, r, errors.New("Unauthorized"), &result)
+	if c.authorize(w, r, "SendTransaction") == false {
 		return
 	}
 
@@ -14,8 +14,8 @@ import (
 	"context"
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/params"
 	"github.com/DogeProtocol/dp/relay"
-	"github.com/DogeProtocol/dp/rpc"
 	"net/http"
 	"errors"
 	"github.com/mattn/go-colorable"
@@ -27,13 +27,59 @@ import (
 // This service should implement the business logic for every endpoint for the WriteApiAPI API.
 // Include any external packages or services that will be required by this service.
 type WriteApiAPIService struct {
-	DpUrl string
+	Upstreams *relay.UpstreamPool
+	Validator *relay.TransactionValidator
 }
 
 // NewWriteApiAPIService creates a default api service
-func NewWriteApiAPIService(dpUrl string) *WriteApiAPIService {
+func NewWriteApiAPIService(upstreams *relay.UpstreamPool, signatureSchemes params.SignatureSchemes) *WriteApiAPIService {
 	log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(3), log.StreamHandler(colorable.NewColorableStderr(), log.TerminalFormat(true))))
-	return &WriteApiAPIService{DpUrl: dpUrl}
+	return &WriteApiAPIService{Upstreams: upstreams, Validator: relay.NewTransactionValidator(upstreams, signatureSchemes)}
+}
+
+// newTransactionValidation converts the result of validating a transaction to its model.
+func newTransactionValidation(v *relay.TransactionValidation) TransactionValidation {
+	validation := TransactionValidation{
+		IsValid: v.Valid(),
+		Errors:  make([]ValidationError, 0, len(v.Errors)),
+	}
+	for _, e := range v.Errors {
+		validationError := ValidationError{Code: e.Code, Message: e.Message}
+		if len(e.Field) > 0 {
+			field := e.Field
+			validationError.Field = &field
+		}
+		validation.Errors = append(validation.Errors, validationError)
+	}
+	if tx := v.Transaction; tx != nil {
+		hash := tx.Hash().String()
+		nonce := int64(tx.Nonce())
+		gasLimit := int64(tx.Gas())
+		gasPrice := tx.GasPrice().String()
+		cost := tx.Cost().String()
+		validation.Hash, validation.Nonce, validation.GasLimit, validation.GasPrice, validation.Cost = &hash, &nonce, &gasLimit, &gasPrice, &cost
+	}
+	if v.From != nil {
+		from := v.From.String()
+		validation.From = &from
+	}
+	if v.Nonce != nil {
+		accountNonce := int64(*v.Nonce)
+		validation.AccountNonce = &accountNonce
+	}
+	if v.PendingNonce != nil {
+		pendingNonce := int64(*v.PendingNonce)
+		validation.PendingNonce = &pendingNonce
+	}
+	if v.Balance != nil {
+		balance := v.Balance.String()
+		validation.Balance = &balance
+	}
+	if v.GasEstimate != nil {
+		gasEstimate := int64(*v.GasEstimate)
+		validation.GasEstimate = &gasEstimate
+	}
+	return validation
 }
 
 // SendTransaction - Send Transaction
@@ -41,15 +87,6 @@ func (s *WriteApiAPIService) SendTransaction(ctx context.Context, sendTransactio
 
 	startTime := time.Now()
 
-	log.Info(relay.InfoTitleSendTransaction, relay.MsgDial, s.DpUrl)
-
-	client, err := rpc.Dial(s.DpUrl)
-	if err != nil {
-		log.Error(relay.MsgDial, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusInternalServerError)
-		return Response(http.StatusInternalServerError, nil), errors.New(err.Error())
-	}
-	defer client.Close()
-
 	rawTxHex := sendTransactionRequest.TxnData
 
 	if(len(strings.TrimSpace(rawTxHex)) == 0) {
@@ -57,8 +94,30 @@ func (s *WriteApiAPIService) SendTransaction(ctx context.Context, sendTransactio
 		return  Response(http.StatusBadRequest, nil), relay.ErrEmptyRawTxHex
 	}
 
+	// The transaction is validated first, so that all of its problems are returned at once
+	// and nothing is broadcast if the nodes would reject it
+	validation, err := s.Validator.Validate(ctx, rawTxHex)
+	if err != nil {
+		log.Error(relay.MsgValidation, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusServiceUnavailable)
+		return  Response(http.StatusServiceUnavailable, nil), errors.New(err.Error())
+	}
+	if validation.Valid() == false {
+		log.Info(relay.MsgValidation, relay.MsgError, validation.Errors[0].Message, "errors", len(validation.Errors), relay.MsgStatus, http.StatusBadRequest)
+		return Response(http.StatusBadRequest, TransactionValidationResponse{newTransactionValidation(validation)}), nil
+	}
+
+	if sendTransactionRequest.DryRun {
+		duration := time.Now().Sub(startTime)
+
+		log.Info(relay.InfoTitleDryRunTransaction, relay.MsgHash, validation.Transaction.Hash().String(), relay.MsgTimeDuration, duration, relay.MsgStatus, http.StatusOK)
+
+		return Response(http.StatusOK, TransactionValidationResponse{newTransactionValidation(validation)}), nil
+	}
+
 	var txHash *common.Hash
-	err = client.CallContext(ctx, &txHash, "eth_sendRawTransaction", rawTxHex)
+	// The transaction is sent to all healthy nodes, so that it propagates even if one of
+	// them is not connected to the rest of the network
+	err = s.Upstreams.BroadcastContext(ctx, &txHash, "eth_sendRawTransaction", rawTxHex)
 
 	if err != nil {
 		log.Error(relay.MsgSend + " " + relay.MsgTransaction, relay.MsgError, errors.New(err.Error()), relay.MsgStatus, http.StatusMethodNotAllowed)
@@ -16,6 +16,9 @@ package qcwriteapi
 type SendTransactionRequest struct {
 
 	TxnData string `json:"txnData,omitempty"`
+
+	// Validate the transaction without sending it
+	DryRun bool `json:"dryRun,omitempty"`
 }
 
 // AssertSendTransactionRequestRequired checks if the required fields are not zero-ed
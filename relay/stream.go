@@ -0,0 +1,648 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/rpc"
+	"github.com/DogeProtocol/dp/systemcontracts/staking"
+)
+
+const (
+	// streamEventsBuffer is the number of events that are buffered for a stream client,
+	// before it is dropped for being too slow.
+	streamEventsBuffer = 256
+
+	// streamMaxBlocksBehind is the number of blocks that are caught up with at most when
+	// the head moves ahead by more than one block, for example after a failover. If the head
+	// moves further ahead, the clients that watch blocks or addresses are dropped, rather
+	// than missing the events of the skipped blocks.
+	streamMaxBlocksBehind = 32
+
+	// streamLogsBuffer is the number of staking contract events that are buffered, before
+	// the logs subscription is dropped for being too slow.
+	streamLogsBuffer = 1024
+
+	// streamDiscardHeads is the number of heads that a watched transaction has to be unknown
+	// to the nodes for before it is reported as discarded. A transaction that was just sent
+	// may not have reached the node that serves the status request yet.
+	streamDiscardHeads = 3
+
+	// streamMaxSubscriptions is the number of stream clients that are served at most.
+	streamMaxSubscriptions = 10000
+
+	// streamMaxAddresses and streamMaxTransactions are the number of addresses and
+	// transactions that a stream client can watch at most.
+	streamMaxAddresses    = 1000
+	streamMaxTransactions = 100
+
+	DefaultStreamConfirmations = 12
+	MaxStreamConfirmations     = 1024
+)
+
+const (
+	StreamEventTypeBlock       = "block"
+	StreamEventTypeAddress     = "address"
+	StreamEventTypeTransaction = "transaction"
+)
+
+var (
+	ErrStreamTooSlow        = errors.New("stream client is too slow")
+	ErrStreamBehind         = errors.New("stream fell behind the chain, subscribe again")
+	ErrStreamClosed         = errors.New("stream is closed")
+	ErrTooManyStreams       = errors.New("too many stream clients")
+	ErrTooManyStreamWatches = errors.New("too many addresses or transactions watched")
+	ErrInvalidConfirmations = errors.New("invalid confirmations")
+	ErrEmptyStreamRequest   = errors.New("nothing to stream")
+)
+
+const discardReasonUnknownHash = "transaction is not known to the node"
+
+// StreamRequest selects the events that a stream client receives. The events of the
+// transactions are sent until they have the given number of confirmations.
+type StreamRequest struct {
+	Blocks        bool             `json:"blocks"`
+	Addresses     []common.Address `json:"addresses"`
+	Transactions  []common.Hash    `json:"transactions"`
+	Confirmations uint64           `json:"confirmations"`
+}
+
+// BlockEvent is sent for every new block.
+type BlockEvent struct {
+	Type             string      `json:"type"`
+	BlockNumber      uint64      `json:"blockNumber"`
+	BlockHash        common.Hash `json:"blockHash"`
+	ParentHash       common.Hash `json:"parentHash"`
+	Timestamp        uint64      `json:"timestamp"`
+	TransactionCount int         `json:"transactionCount"`
+}
+
+// AddressEvent is sent for every transaction in a new block that a watched address took
+// part in.
+type AddressEvent struct {
+	Type        string              `json:"type"`
+	Address     common.Address      `json:"address"`
+	Transaction *AddressTransaction `json:"transaction"`
+}
+
+// TransactionEvent is sent whenever the status of a watched transaction changes: when it is
+// included in a block, for every new confirmation, and when it is discarded.
+type TransactionEvent struct {
+	Type          string          `json:"type"`
+	Hash          common.Hash     `json:"hash"`
+	BlockNumber   *uint64         `json:"blockNumber"`
+	BlockHash     *common.Hash    `json:"blockHash"`
+	Confirmations uint64          `json:"confirmations"`
+	Status        *hexutil.Uint64 `json:"status"`
+	IsConfirmed   bool            `json:"isConfirmed"`
+	IsDiscarded   bool            `json:"isDiscarded"`
+	DiscardReason string          `json:"discardReason,omitempty"`
+
+	unknown bool // the transaction is not known to the node that served the status
+}
+
+type rpcHeader struct {
+	Number hexutil.Uint64 `json:"number"`
+}
+
+type rpcTransactionStatus struct {
+	BlockHash   *common.Hash    `json:"blockHash"`
+	BlockNumber *hexutil.Uint64 `json:"blockNumber"`
+}
+
+type rpcReceiptStatus struct {
+	Status hexutil.Uint64 `json:"status"`
+}
+
+// StreamHub follows the head of the upstream nodes and pushes new blocks, the activity of
+// addresses and the status of transactions to the stream clients that watch them.
+type StreamHub struct {
+	upstreams *UpstreamPool
+
+	lock sync.Mutex
+	subs map[*StreamSubscription]struct{}
+	head uint64
+
+	// The staking contract events received from the logs subscription, by block hash. They
+	// are only accessed by the loop.
+	logs      map[common.Hash][]*rpcLog
+	logBlocks []common.Hash
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewStreamHub creates a stream hub for the given upstream nodes.
+func NewStreamHub(upstreams *UpstreamPool) *StreamHub {
+	return &StreamHub{
+		upstreams: upstreams,
+		subs:      make(map[*StreamSubscription]struct{}),
+		quit:      make(chan struct{}),
+	}
+}
+
+// Start starts following the head of the upstream nodes.
+func (h *StreamHub) Start() {
+	h.wg.Add(1)
+	go h.loop()
+}
+
+// Close stops following the head and drops all stream clients.
+func (h *StreamHub) Close() {
+	close(h.quit)
+	h.wg.Wait()
+
+	for _, sub := range h.subscriptions() {
+		sub.drop(ErrStreamClosed)
+	}
+}
+
+func (h *StreamHub) loop() {
+	defer h.wg.Done()
+
+	for {
+		h.follow()
+		select {
+		case <-time.After(addressIndexInterval):
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// follow subscribes to the new heads and to the staking contract events of an upstream
+// node and processes them until a subscription fails. If no upstream node supports
+// subscriptions, the head is polled once.
+func (h *StreamHub) follow() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	heads := make(chan *rpcHeader, streamMaxBlocksBehind)
+	sub, err := h.upstreams.EthSubscribe(ctx, heads, "newHeads")
+	if err == rpc.ErrNotificationsUnsupported {
+		var latest hexutil.Uint64
+		if err := h.upstreams.CallContext(ctx, &latest, "eth_blockNumber"); err != nil {
+			log.Warn("Failed to get the head block", "err", err)
+			return
+		}
+		h.processHead(uint64(latest))
+		return
+	}
+	if err != nil {
+		log.Warn("Failed to subscribe to new heads", "err", err)
+		return
+	}
+	defer sub.Unsubscribe()
+
+	// The addresses in the staking contract events are taken from the logs subscription,
+	// rather than from the receipts of the staking transactions
+	logs := make(chan *rpcLog, streamLogsBuffer)
+	logsSub, err := h.upstreams.EthSubscribe(ctx, logs, "logs", map[string]interface{}{
+		"address": []common.Address{staking.STAKING_CONTRACT_ADDRESS},
+	})
+	if err != nil {
+		log.Warn("Failed to subscribe to staking logs", "err", err)
+		return
+	}
+	defer logsSub.Unsubscribe()
+
+	h.logs, h.logBlocks = make(map[common.Hash][]*rpcLog), nil
+	defer func() {
+		h.logs, h.logBlocks = nil, nil
+	}()
+
+	for {
+		select {
+		case head := <-heads:
+			// The node sends the events of a block before its head
+			h.drainLogs(logs)
+			h.processHead(uint64(head.Number))
+		case l := <-logs:
+			h.addLog(l)
+		case err := <-sub.Err():
+			log.Warn("New heads subscription failed", "err", err)
+			return
+		case err := <-logsSub.Err():
+			log.Warn("Logs subscription failed", "err", err)
+			return
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+func (h *StreamHub) drainLogs(logs chan *rpcLog) {
+	for {
+		select {
+		case l := <-logs:
+			h.addLog(l)
+		default:
+			return
+		}
+	}
+}
+
+// addLog keeps a staking contract event until the block that emitted it is processed. The
+// events of the last streamMaxBlocksBehind blocks are kept at most.
+func (h *StreamHub) addLog(l *rpcLog) {
+	if l.Removed {
+		delete(h.logs, l.BlockHash)
+		return
+	}
+	if _, ok := h.logs[l.BlockHash]; !ok {
+		h.logBlocks = append(h.logBlocks, l.BlockHash)
+		if len(h.logBlocks) > streamMaxBlocksBehind {
+			delete(h.logs, h.logBlocks[0])
+			h.logBlocks = h.logBlocks[1:]
+		}
+	}
+	h.logs[l.BlockHash] = append(h.logs[l.BlockHash], l)
+}
+
+// transactionAddresses returns the addresses that took part in a transaction of a block. The
+// addresses in the events of a staking transaction are taken from the logs subscription,
+// and from the receipt of the transaction if the subscription did not deliver them.
+func (h *StreamHub) transactionAddresses(block *rpcBlock, tx *rpcTransaction) ([]common.Address, error) {
+	if tx.To == nil || tx.To.IsEqualTo(staking.STAKING_CONTRACT_ADDRESS) == false {
+		return transactionAddresses(h.upstreams, tx)
+	}
+	var logs []*rpcLog
+	for _, l := range h.logs[block.Hash] {
+		if l.TxHash == tx.Hash {
+			logs = append(logs, l)
+		}
+	}
+	if len(logs) == 0 {
+		return transactionAddresses(h.upstreams, tx)
+	}
+	return append([]common.Address{tx.From, *tx.To}, stakingLogAddresses(logs)...), nil
+}
+
+func (h *StreamHub) subscriptions() []*StreamSubscription {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	subs := make([]*StreamSubscription, 0, len(h.subs))
+	for sub := range h.subs {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+func (h *StreamHub) currentHead() uint64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.head
+}
+
+// processHead processes the blocks from the last processed one up to the new head. A block
+// that cannot be fetched is retried with the next head.
+func (h *StreamHub) processHead(number uint64) {
+	h.lock.Lock()
+	from := h.head + 1
+	behind := h.head != 0 && number >= from+streamMaxBlocksBehind
+	if h.head == 0 || behind {
+		from = number
+	}
+	if number < from {
+		h.lock.Unlock()
+		return
+	}
+	// Blocks are only fetched while there are clients
+	if len(h.subs) == 0 {
+		h.head = number
+		h.lock.Unlock()
+		return
+	}
+	h.lock.Unlock()
+
+	if behind {
+		log.Warn("Stream fell behind the chain", "head", number, "behind", number-from)
+		h.dropBehind()
+	}
+	for n := from; n <= number; n++ {
+		block, err := fetchBlock(h.upstreams, n)
+		if err != nil {
+			log.Warn("Failed to fetch block", "number", n, "err", err)
+			break
+		}
+		if block == nil {
+			break
+		}
+		h.processBlock(block)
+		h.setHead(n)
+	}
+	h.checkTransactions(h.subscriptions(), h.currentHead())
+}
+
+func (h *StreamHub) setHead(number uint64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.head = number
+}
+
+// dropBehind drops the clients that would miss the events of the blocks that are skipped.
+// The clients that only watch transactions are not affected, as the status of those is
+// checked at every head.
+func (h *StreamHub) dropBehind() {
+	for _, sub := range h.subscriptions() {
+		if blocks, addresses := sub.watching(); blocks || addresses {
+			sub.drop(ErrStreamBehind)
+		}
+	}
+}
+
+func (h *StreamHub) processBlock(block *rpcBlock) {
+	subs := h.subscriptions()
+
+	blockEvent := &BlockEvent{
+		Type:             StreamEventTypeBlock,
+		BlockNumber:      uint64(block.Number),
+		BlockHash:        block.Hash,
+		ParentHash:       block.ParentHash,
+		Timestamp:        uint64(block.Timestamp),
+		TransactionCount: len(block.Transactions),
+	}
+	watchingAddresses := false
+	for _, sub := range subs {
+		blocks, addresses := sub.watching()
+		if blocks {
+			sub.send(blockEvent)
+		}
+		watchingAddresses = watchingAddresses || addresses
+	}
+	if watchingAddresses == false {
+		return
+	}
+
+	for _, tx := range block.Transactions {
+		addresses, err := h.transactionAddresses(block, tx)
+		if err != nil {
+			log.Warn("Failed to get the addresses of a transaction", "hash", tx.Hash, "err", err)
+			continue
+		}
+		entry := newAddressTransaction(block, tx)
+		seen := make(map[common.Address]bool)
+		for _, address := range addresses {
+			if seen[address] {
+				continue
+			}
+			seen[address] = true
+			for _, sub := range subs {
+				if sub.watchingAddress(address) {
+					sub.send(&AddressEvent{Type: StreamEventTypeAddress, Address: address, Transaction: entry})
+				}
+			}
+		}
+	}
+}
+
+// transactionStatus returns the status of a transaction as of the given head block.
+func (h *StreamHub) transactionStatus(hash common.Hash, head uint64) (*TransactionEvent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), upstreamHealthCheckTimeout)
+	defer cancel()
+
+	event := &TransactionEvent{Type: StreamEventTypeTransaction, Hash: hash}
+
+	var tx *rpcTransactionStatus
+	if err := h.upstreams.CallContext(ctx, &tx, "eth_getTransactionByHash", hash); err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		event.unknown = true
+		return event, nil
+	}
+	if tx.BlockNumber == nil {
+		// Pending
+		return event, nil
+	}
+
+	var receipt *rpcReceiptStatus
+	if err := h.upstreams.CallContext(ctx, &receipt, "eth_getTransactionReceipt", hash); err != nil {
+		return nil, err
+	}
+	blockNumber := uint64(*tx.BlockNumber)
+	event.BlockNumber = &blockNumber
+	event.BlockHash = tx.BlockHash
+	event.Confirmations = 1
+	if head > blockNumber {
+		event.Confirmations = head - blockNumber + 1
+	}
+	if receipt != nil {
+		event.Status = &receipt.Status
+	}
+	return event, nil
+}
+
+// checkTransactions sends the status of the transactions that the given subscriptions
+// watch, if it changed since it was last sent.
+func (h *StreamHub) checkTransactions(subs []*StreamSubscription, head uint64) {
+	statuses := make(map[common.Hash]*TransactionEvent)
+	for _, sub := range subs {
+		for _, hash := range sub.watchedTransactions() {
+			status, ok := statuses[hash]
+			if !ok {
+				var err error
+				status, err = h.transactionStatus(hash, head)
+				if err != nil {
+					log.Warn("Failed to get the status of a transaction", "hash", hash, "err", err)
+				}
+				statuses[hash] = status
+			}
+			if status != nil {
+				sub.updateTransaction(status, head)
+			}
+		}
+	}
+}
+
+// Subscribe creates a stream client that receives the events selected by the request.
+func (h *StreamHub) Subscribe(req StreamRequest) (*StreamSubscription, error) {
+	sub := &StreamSubscription{
+		hub:          h,
+		events:       make(chan interface{}, streamEventsBuffer),
+		err:          make(chan error, 1),
+		quit:         make(chan struct{}),
+		addresses:    make(map[common.Address]bool),
+		transactions: make(map[common.Hash]*watchedTransaction),
+	}
+
+	h.lock.Lock()
+	if len(h.subs) >= streamMaxSubscriptions {
+		h.lock.Unlock()
+		return nil, ErrTooManyStreams
+	}
+	h.subs[sub] = struct{}{}
+	h.lock.Unlock()
+
+	if err := sub.Add(req); err != nil {
+		sub.Unsubscribe()
+		return nil, err
+	}
+	return sub, nil
+}
+
+type watchedTransaction struct {
+	confirmations uint64
+	last          *TransactionEvent
+
+	unknown      bool   // whether the transaction was unknown at the last check
+	unknownSince uint64 // head at which the transaction was first found to be unknown
+}
+
+// StreamSubscription is a stream client of a StreamHub. The events are received from the
+// Events channel until the client unsubscribes, or until it is dropped by the hub, in
+// which case the reason is sent on the Err channel.
+type StreamSubscription struct {
+	hub    *StreamHub
+	events chan interface{}
+	err    chan error
+	quit   chan struct{}
+	once   sync.Once
+
+	lock         sync.Mutex
+	blocks       bool
+	addresses    map[common.Address]bool
+	transactions map[common.Hash]*watchedTransaction
+}
+
+// Events returns the channel that the events are sent on.
+func (s *StreamSubscription) Events() <-chan interface{} {
+	return s.events
+}
+
+// Err returns the channel that the reason is sent on if the client is dropped.
+func (s *StreamSubscription) Err() <-chan error {
+	return s.err
+}
+
+// Add adds the events selected by the request to the ones that the client receives. The
+// current status of the transactions in the request is sent right away.
+func (s *StreamSubscription) Add(req StreamRequest) error {
+	confirmations := req.Confirmations
+	if confirmations == 0 {
+		confirmations = DefaultStreamConfirmations
+	}
+	if confirmations > MaxStreamConfirmations {
+		return ErrInvalidConfirmations
+	}
+	if req.Blocks == false && len(req.Addresses) == 0 && len(req.Transactions) == 0 {
+		return ErrEmptyStreamRequest
+	}
+
+	s.lock.Lock()
+	if len(s.addresses)+len(req.Addresses) > streamMaxAddresses || len(s.transactions)+len(req.Transactions) > streamMaxTransactions {
+		s.lock.Unlock()
+		return ErrTooManyStreamWatches
+	}
+	s.blocks = s.blocks || req.Blocks
+	for _, address := range req.Addresses {
+		s.addresses[address] = true
+	}
+	for _, hash := range req.Transactions {
+		s.transactions[hash] = &watchedTransaction{confirmations: confirmations}
+	}
+	s.lock.Unlock()
+
+	if len(req.Transactions) > 0 {
+		go s.hub.checkTransactions([]*StreamSubscription{s}, s.hub.currentHead())
+	}
+	return nil
+}
+
+func (s *StreamSubscription) watching() (blocks bool, addresses bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.blocks, len(s.addresses) > 0
+}
+
+func (s *StreamSubscription) watchingAddress(address common.Address) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.addresses[address]
+}
+
+func (s *StreamSubscription) watchedTransactions() []common.Hash {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	hashes := make([]common.Hash, 0, len(s.transactions))
+	for hash := range s.transactions {
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+// updateTransaction sends the status of a watched transaction as of the given head if it
+// changed, and stops watching the transaction once it is confirmed or discarded. A
+// transaction is discarded once it is unknown for streamDiscardHeads heads in a row.
+func (s *StreamSubscription) updateTransaction(status *TransactionEvent, head uint64) {
+	s.lock.Lock()
+	watched, ok := s.transactions[status.Hash]
+	if !ok {
+		s.lock.Unlock()
+		return
+	}
+	event := *status
+	if event.unknown == false {
+		watched.unknown = false
+	} else if head > 0 {
+		if watched.unknown == false {
+			watched.unknown, watched.unknownSince = true, head
+		}
+		if head >= watched.unknownSince+streamDiscardHeads {
+			event.IsDiscarded = true
+			event.DiscardReason = discardReasonUnknownHash
+		}
+	}
+	event.IsConfirmed = event.Confirmations >= watched.confirmations
+	if watched.last != nil && watched.last.Confirmations == event.Confirmations &&
+		watched.last.IsDiscarded == event.IsDiscarded && (watched.last.BlockHash == nil) == (event.BlockHash == nil) &&
+		(event.BlockHash == nil || *watched.last.BlockHash == *event.BlockHash) {
+		s.lock.Unlock()
+		return
+	}
+	watched.last = &event
+	if event.IsConfirmed || event.IsDiscarded {
+		delete(s.transactions, status.Hash)
+	}
+	s.lock.Unlock()
+
+	s.send(&event)
+}
+
+func (s *StreamSubscription) send(event interface{}) {
+	select {
+	case <-s.quit:
+	case s.events <- event:
+	default:
+		s.drop(ErrStreamTooSlow)
+	}
+}
+
+func (s *StreamSubscription) drop(err error) {
+	s.once.Do(func() {
+		s.hub.lock.Lock()
+		delete(s.hub.subs, s)
+		s.hub.lock.Unlock()
+
+		s.err <- err
+		close(s.quit)
+	})
+}
+
+// Unsubscribe stops sending events to the client.
+func (s *StreamSubscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.hub.lock.Lock()
+		delete(s.hub.subs, s)
+		s.hub.lock.Unlock()
+
+		close(s.quit)
+	})
+}
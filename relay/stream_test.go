@@ -0,0 +1,320 @@
+package relay
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/rpc"
+	"github.com/DogeProtocol/dp/systemcontracts/staking"
+)
+
+type testReceipt struct {
+	Status hexutil.Uint64 `json:"status"`
+	Logs   []*rpcLog      `json:"logs"`
+}
+
+// testEthService serves the eth methods that the relay calls on its upstream nodes.
+type testEthService struct {
+	lock         sync.Mutex
+	head         uint64
+	blocks       map[uint64]*rpcBlock
+	transactions map[common.Hash]*rpcTransactionStatus
+	receipts     map[common.Hash]*testReceipt
+	calls        int
+}
+
+func newTestEthService() *testEthService {
+	return &testEthService{
+		blocks:       make(map[uint64]*rpcBlock),
+		transactions: make(map[common.Hash]*rpcTransactionStatus),
+		receipts:     make(map[common.Hash]*testReceipt),
+	}
+}
+
+func (s *testEthService) BlockNumber() hexutil.Uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls++
+	return hexutil.Uint64(s.head)
+}
+
+func (s *testEthService) Syncing() bool {
+	return false
+}
+
+func (s *testEthService) GetBlockByNumber(number hexutil.Uint64, full bool) *rpcBlock {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls++
+	return s.blocks[uint64(number)]
+}
+
+func (s *testEthService) GetTransactionByHash(hash common.Hash) *rpcTransactionStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls++
+	return s.transactions[hash]
+}
+
+func (s *testEthService) GetTransactionReceipt(hash common.Hash) *testReceipt {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls++
+	return s.receipts[hash]
+}
+
+func (s *testEthService) addBlock(number uint64, txs ...*rpcTransaction) *rpcBlock {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	block := &rpcBlock{
+		Hash:         common.BytesToHash([]byte{byte(number), 1}),
+		ParentHash:   common.BytesToHash([]byte{byte(number - 1), 1}),
+		Number:       hexutil.Uint64(number),
+		Transactions: txs,
+	}
+	s.blocks[number] = block
+	if number > s.head {
+		s.head = number
+	}
+	return block
+}
+
+func newTestUpstream(t *testing.T, url string, service interface{}) *upstream {
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", service); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	t.Cleanup(server.Stop)
+	return &upstream{url: url, client: client}
+}
+
+func newTestUpstreamPool(t *testing.T, upstreams ...*upstream) *UpstreamPool {
+	pool := &UpstreamPool{upstreams: upstreams, quit: make(chan struct{})}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func nextStreamEvent(t *testing.T, sub *StreamSubscription) interface{} {
+	t.Helper()
+	select {
+	case event := <-sub.Events():
+		return event
+	case err := <-sub.Err():
+		t.Fatalf("stream dropped: %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+	return nil
+}
+
+func noStreamEvent(t *testing.T, sub *StreamSubscription) {
+	t.Helper()
+	select {
+	case event := <-sub.Events():
+		t.Fatalf("unexpected event %+v", event)
+	default:
+	}
+}
+
+func TestStreamTransactionDiscard(t *testing.T) {
+	service := newTestEthService()
+	hub := NewStreamHub(newTestUpstreamPool(t, newTestUpstream(t, "inproc", service)))
+	hash := common.BytesToHash([]byte{1})
+
+	sub, err := hub.Subscribe(StreamRequest{Transactions: []common.Hash{hash}})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// The status is sent right away, but an unknown transaction is not discarded yet
+	event := nextStreamEvent(t, sub).(*TransactionEvent)
+	if event.IsDiscarded || event.BlockNumber != nil {
+		t.Fatalf("expected a pending status, got %+v", event)
+	}
+	for head := uint64(10); head < 10+streamDiscardHeads; head++ {
+		hub.checkTransactions(hub.subscriptions(), head)
+		noStreamEvent(t, sub)
+	}
+
+	// The transaction is seen by the node, and unknown again after a reorg
+	service.lock.Lock()
+	service.transactions[hash] = &rpcTransactionStatus{}
+	service.lock.Unlock()
+	hub.checkTransactions(hub.subscriptions(), 10+streamDiscardHeads)
+	noStreamEvent(t, sub)
+
+	service.lock.Lock()
+	delete(service.transactions, hash)
+	service.lock.Unlock()
+	for head := uint64(20); head < 20+streamDiscardHeads; head++ {
+		hub.checkTransactions(hub.subscriptions(), head)
+		noStreamEvent(t, sub)
+	}
+	hub.checkTransactions(hub.subscriptions(), 20+streamDiscardHeads)
+	event = nextStreamEvent(t, sub).(*TransactionEvent)
+	if event.IsDiscarded == false || event.DiscardReason != discardReasonUnknownHash {
+		t.Fatalf("expected the transaction to be discarded, got %+v", event)
+	}
+	if len(sub.watchedTransactions()) != 0 {
+		t.Fatal("expected a discarded transaction to no longer be watched")
+	}
+}
+
+func TestStreamTransactionConfirmations(t *testing.T) {
+	service := newTestEthService()
+	hub := NewStreamHub(newTestUpstreamPool(t, newTestUpstream(t, "inproc", service)))
+	hash := common.BytesToHash([]byte{1})
+	blockHash := common.BytesToHash([]byte{2})
+	blockNumber := hexutil.Uint64(10)
+	service.transactions[hash] = &rpcTransactionStatus{BlockHash: &blockHash, BlockNumber: &blockNumber}
+	service.receipts[hash] = &testReceipt{Status: 1}
+
+	sub, err := hub.Subscribe(StreamRequest{Transactions: []common.Hash{hash}, Confirmations: 2})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	event := nextStreamEvent(t, sub).(*TransactionEvent)
+	if event.Confirmations != 1 || event.IsConfirmed || *event.BlockHash != blockHash || event.Status == nil || *event.Status != 1 {
+		t.Fatalf("expected one confirmation, got %+v", event)
+	}
+	hub.checkTransactions(hub.subscriptions(), 10)
+	noStreamEvent(t, sub)
+
+	hub.checkTransactions(hub.subscriptions(), 11)
+	event = nextStreamEvent(t, sub).(*TransactionEvent)
+	if event.Confirmations != 2 || event.IsConfirmed == false {
+		t.Fatalf("expected the transaction to be confirmed, got %+v", event)
+	}
+	if len(sub.watchedTransactions()) != 0 {
+		t.Fatal("expected a confirmed transaction to no longer be watched")
+	}
+}
+
+func TestStreamProcessHead(t *testing.T) {
+	service := newTestEthService()
+	hub := NewStreamHub(newTestUpstreamPool(t, newTestUpstream(t, "inproc", service)))
+	for number := uint64(1); number <= 5; number++ {
+		service.addBlock(number)
+	}
+
+	// Blocks are not fetched without clients
+	hub.processHead(1)
+	if hub.currentHead() != 1 || service.calls != 0 {
+		t.Fatalf("expected an idle hub to only follow the head, head %d calls %d", hub.currentHead(), service.calls)
+	}
+
+	sub, err := hub.Subscribe(StreamRequest{Blocks: true})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	expectBlocks := func(numbers ...uint64) {
+		t.Helper()
+		for _, number := range numbers {
+			event := nextStreamEvent(t, sub).(*BlockEvent)
+			if event.BlockNumber != number {
+				t.Fatalf("expected block %d, got %d", number, event.BlockNumber)
+			}
+		}
+		noStreamEvent(t, sub)
+	}
+
+	// A block that is not available yet is retried with the next head
+	service.lock.Lock()
+	delete(service.blocks, 3)
+	service.lock.Unlock()
+	hub.processHead(4)
+	expectBlocks(2)
+	if hub.currentHead() != 2 {
+		t.Fatalf("expected the head to stay at the last processed block, got %d", hub.currentHead())
+	}
+	service.addBlock(3)
+	hub.processHead(5)
+	expectBlocks(3, 4, 5)
+
+	// An older head is ignored
+	hub.processHead(4)
+	noStreamEvent(t, sub)
+
+	// Clients that would miss blocks are dropped
+	txSub, err := hub.Subscribe(StreamRequest{Transactions: []common.Hash{common.BytesToHash([]byte{1})}})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer txSub.Unsubscribe()
+	nextStreamEvent(t, txSub)
+
+	head := 5 + streamMaxBlocksBehind + 1
+	service.addBlock(uint64(head))
+	hub.processHead(uint64(head))
+	select {
+	case err := <-sub.Err():
+		if err != ErrStreamBehind {
+			t.Fatalf("expected %v, got %v", ErrStreamBehind, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the block client to be dropped")
+	}
+	if len(hub.subscriptions()) != 1 {
+		t.Fatal("expected the transaction client to be kept")
+	}
+	if hub.currentHead() != uint64(head) {
+		t.Fatalf("expected the head to move to %d, got %d", head, hub.currentHead())
+	}
+}
+
+func TestStreamStakingLogs(t *testing.T) {
+	service := newTestEthService()
+	hub := NewStreamHub(newTestUpstreamPool(t, newTestUpstream(t, "inproc", service)))
+	hub.logs = make(map[common.Hash][]*rpcLog)
+
+	depositor := common.BytesToAddress([]byte{1})
+	validator := common.BytesToAddress([]byte{2})
+	to := staking.STAKING_CONTRACT_ADDRESS
+	tx := &rpcTransaction{Hash: common.BytesToHash([]byte{3}), From: depositor, To: &to}
+	block := service.addBlock(1, tx)
+
+	sub, err := hub.Subscribe(StreamRequest{Addresses: []common.Address{validator}})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	l := &rpcLog{
+		Address:   staking.STAKING_CONTRACT_ADDRESS,
+		Topics:    []common.Hash{common.BytesToHash([]byte{4}), common.BytesToHash(validator.Bytes())},
+		TxHash:    tx.Hash,
+		BlockHash: block.Hash,
+	}
+	hub.addLog(l)
+	hub.processBlock(block)
+	event := nextStreamEvent(t, sub).(*AddressEvent)
+	if event.Address != validator || event.Transaction.Hash != tx.Hash {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if service.calls != 0 {
+		t.Fatalf("expected the addresses to be taken from the logs, got %d calls", service.calls)
+	}
+
+	// The logs of a reorged block are dropped, and the receipt is read instead
+	hub.addLog(&rpcLog{BlockHash: block.Hash, Removed: true})
+	hub.processBlock(block)
+	noStreamEvent(t, sub)
+	if service.calls != 1 {
+		t.Fatalf("expected the receipt to be read, got %d calls", service.calls)
+	}
+
+	// Only the logs of the last blocks are kept
+	for i := 0; i <= streamMaxBlocksBehind; i++ {
+		hub.addLog(&rpcLog{BlockHash: common.BytesToHash([]byte{byte(i), 2})})
+	}
+	if len(hub.logs) != streamMaxBlocksBehind || len(hub.logBlocks) != streamMaxBlocksBehind {
+		t.Fatalf("expected the logs of %d blocks, got %d", streamMaxBlocksBehind, len(hub.logs))
+	}
+}
@@ -0,0 +1,216 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/conversionutil"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/params"
+)
+
+// The codes of the problems found by validating a transaction.
+const (
+	ValidationErrInvalidEncoding   = "invalidEncoding"
+	ValidationErrUnsupportedType   = "unsupportedType"
+	ValidationErrRemarksTooLong    = "remarksTooLong"
+	ValidationErrInvalidChainId    = "invalidChainId"
+	ValidationErrInvalidSignature  = "invalidSignature"
+	ValidationErrNonceTooLow       = "nonceTooLow"
+	ValidationErrNonceGap          = "nonceGap"
+	ValidationErrInsufficientFunds = "insufficientFunds"
+	ValidationErrExecutionFailed   = "executionFailed"
+	ValidationErrGasLimitTooLow    = "gasLimitTooLow"
+)
+
+// TransactionValidationError is a problem found by validating a transaction, which would
+// make the node reject it or make it fail once it is executed.
+type TransactionValidationError struct {
+	Code    string
+	Field   string
+	Message string
+}
+
+// TransactionValidation is the result of validating a raw transaction. The fields other
+// than Errors are set as far as the transaction could be checked.
+type TransactionValidation struct {
+	Transaction *types.Transaction
+	From        *common.Address
+	// Nonce and PendingNonce are the nonces of the sender as of the latest block and
+	// including its pending transactions.
+	Nonce        *uint64
+	PendingNonce *uint64
+	Balance      *big.Int
+	GasEstimate  *uint64
+	Errors       []*TransactionValidationError
+}
+
+// Valid returns whether no problem was found with the transaction.
+func (v *TransactionValidation) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+func (v *TransactionValidation) fail(code string, field string, format string, args ...interface{}) {
+	v.Errors = append(v.Errors, &TransactionValidationError{
+		Code:    code,
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// TransactionValidator checks raw transactions against the state of the upstream nodes
+// before they are broadcast, so that clients get all the problems of a transaction at once
+// instead of the first error of the node.
+type TransactionValidator struct {
+	upstreams        *UpstreamPool
+	signatureSchemes params.SignatureSchemes
+
+	lock    sync.Mutex
+	chainId *big.Int
+}
+
+// NewTransactionValidator creates a validator that checks transactions against the state
+// of the given upstream nodes. Signatures are accepted if their scheme is valid in the next
+// block according to signatureSchemes, the schemes of the chain of the nodes.
+func NewTransactionValidator(upstreams *UpstreamPool, signatureSchemes params.SignatureSchemes) *TransactionValidator {
+	return &TransactionValidator{upstreams: upstreams, signatureSchemes: signatureSchemes}
+}
+
+// ChainId returns the chain id of the upstream nodes, which is retrieved once.
+func (tv *TransactionValidator) ChainId(ctx context.Context) (*big.Int, error) {
+	tv.lock.Lock()
+	defer tv.lock.Unlock()
+
+	if tv.chainId != nil {
+		return tv.chainId, nil
+	}
+	var chainId hexutil.Big
+	if err := tv.upstreams.CallContext(ctx, &chainId, "eth_chainId"); err != nil {
+		return nil, err
+	}
+	tv.chainId = chainId.ToInt()
+	return tv.chainId, nil
+}
+
+// Validate decodes a hex encoded raw transaction and checks its encoding, signature, chain id, nonce
+// and the balance of its sender, then simulates it as of the pending block. Problems with
+// the transaction are returned in the result; an error is only returned if the upstream
+// nodes could not be queried.
+func (tv *TransactionValidator) Validate(ctx context.Context, rawTxHex string) (*TransactionValidation, error) {
+	result := &TransactionValidation{}
+
+	rawTx, err := hexutil.Decode(rawTxHex)
+	if err != nil {
+		result.fail(ValidationErrInvalidEncoding, "txnData", "transaction is not hex encoded: %v", err)
+		return result, nil
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(rawTx); err != nil {
+		result.fail(ValidationErrInvalidEncoding, "txnData", "transaction could not be decoded: %v", err)
+		return result, nil
+	}
+	result.Transaction = tx
+
+	if tx.Type() != types.DefaultFeeTxType {
+		result.fail(ValidationErrUnsupportedType, "type", "transaction type %d is not supported", tx.Type())
+		return result, nil
+	}
+	if len(tx.Remarks()) > types.MAX_REMARKS_LENGTH {
+		result.fail(ValidationErrRemarksTooLong, "remarks", "remarks are %d bytes, the maximum is %d", len(tx.Remarks()), types.MAX_REMARKS_LENGTH)
+		return result, nil
+	}
+
+	chainId, err := tv.ChainId(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tx.ChainId() == nil || tx.ChainId().Cmp(chainId) != 0 {
+		result.fail(ValidationErrInvalidChainId, "chainId", "chain id is %v, expected %v", tx.ChainId(), chainId)
+		return result, nil
+	}
+	var head hexutil.Uint64
+	if err := tv.upstreams.CallContext(ctx, &head, "eth_blockNumber"); err != nil {
+		return nil, err
+	}
+	config := &params.ChainConfig{ChainID: chainId, SignatureSchemes: tv.signatureSchemes}
+	signer := types.MakeSigner(config, new(big.Int).SetUint64(uint64(head)+1))
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		result.fail(ValidationErrInvalidSignature, "signature", "signature is invalid: %v", err)
+		return result, nil
+	}
+	result.From = &from
+
+	// The remaining checks are independent, so that all of their problems are reported
+	var nonce, pendingNonce hexutil.Uint64
+	if err := tv.upstreams.CallContext(ctx, &nonce, "eth_getTransactionCount", from, "latest"); err != nil {
+		return nil, err
+	}
+	if err := tv.upstreams.CallContext(ctx, &pendingNonce, "eth_getTransactionCount", from, "pending"); err != nil {
+		return nil, err
+	}
+	result.Nonce = (*uint64)(&nonce)
+	result.PendingNonce = (*uint64)(&pendingNonce)
+	if tx.Nonce() < uint64(nonce) {
+		result.fail(ValidationErrNonceTooLow, "nonce", "nonce is %d, the next nonce of the account is %d", tx.Nonce(), uint64(nonce))
+	} else if tx.Nonce() > uint64(pendingNonce) {
+		// Transactions with a nonce below the pending nonce replace a pending transaction
+		result.fail(ValidationErrNonceGap, "nonce", "nonce is %d, the next nonce of the account including its pending transactions is %d", tx.Nonce(), uint64(pendingNonce))
+	}
+
+	var balance hexutil.Big
+	if err := tv.upstreams.CallContext(ctx, &balance, "eth_getBalance", from, "latest"); err != nil {
+		return nil, err
+	}
+	result.Balance = balance.ToInt()
+	// The conversions of the addresses in the snapshot do not pay for gas, as in the pool
+	if result.Balance.Cmp(tx.Cost()) < 0 {
+		if isGasExempt, err := conversionutil.IsGasExemptTxn(tx, signer); err != nil || isGasExempt == false {
+			result.fail(ValidationErrInsufficientFunds, "value", "balance is %v, the value plus gas at the max gas tier costs %v", result.Balance, tx.Cost())
+		}
+	}
+
+	if err := tv.simulate(ctx, tx, from, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// callArgs are the arguments of eth_estimateGas.
+type callArgs struct {
+	From  common.Address  `json:"from"`
+	To    *common.Address `json:"to"`
+	Value *hexutil.Big    `json:"value"`
+	Data  hexutil.Bytes   `json:"data"`
+}
+
+// simulate executes the transaction as of the pending block to estimate the gas it needs,
+// without its gas limit so that a limit that is too low is told apart from a transaction
+// that fails regardless. Errors returned by the node, such as a revert, are problems with
+// the transaction.
+func (tv *TransactionValidator) simulate(ctx context.Context, tx *types.Transaction, from common.Address, result *TransactionValidation) error {
+	args := callArgs{
+		From:  from,
+		To:    tx.To(),
+		Value: (*hexutil.Big)(tx.Value()),
+		Data:  tx.Data(),
+	}
+	var estimate hexutil.Uint64
+	if err := tv.upstreams.CallContext(ctx, &estimate, "eth_estimateGas", args, "pending"); err != nil {
+		if isUpstreamErr(err) {
+			return err
+		}
+		result.fail(ValidationErrExecutionFailed, "", "%v", err)
+		return nil
+	}
+	result.GasEstimate = (*uint64)(&estimate)
+	if tx.Gas() < uint64(estimate) {
+		result.fail(ValidationErrGasLimitTooLow, "gas", "gas limit is %d, the transaction needs %d", tx.Gas(), uint64(estimate))
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package relay
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/DogeProtocol/dp/accounts"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/conversionutil"
+	"github.com/DogeProtocol/dp/core/types"
+	"github.com/DogeProtocol/dp/crypto"
+	"github.com/DogeProtocol/dp/crypto/crosssign"
+	"github.com/DogeProtocol/dp/crypto/cryptobase"
+	"github.com/DogeProtocol/dp/crypto/secp256k1"
+	"github.com/DogeProtocol/dp/crypto/signaturealgorithm"
+	"github.com/DogeProtocol/dp/systemcontracts/conversion"
+)
+
+// testValidationService serves the state that transactions are validated against.
+type testValidationService struct {
+	balance *big.Int
+}
+
+func (s *testValidationService) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(types.DEFAULT_CHAIN_ID))
+}
+
+func (s *testValidationService) BlockNumber() hexutil.Uint64 {
+	return 1
+}
+
+func (s *testValidationService) GetTransactionCount(address common.Address, block string) hexutil.Uint64 {
+	return 0
+}
+
+func (s *testValidationService) GetBalance(address common.Address, block string) *hexutil.Big {
+	return (*hexutil.Big)(s.balance)
+}
+
+func (s *testValidationService) EstimateGas(args callArgs, block string) hexutil.Uint64 {
+	return 21000
+}
+
+func newTestValidator(t *testing.T, balance *big.Int) *TransactionValidator {
+	service := &testValidationService{balance: balance}
+	return NewTransactionValidator(newTestUpstreamPool(t, newTestUpstream(t, "inproc", service)), nil)
+}
+
+// conversionData returns the data of a conversion to quantumAddress, cross signed by a new
+// Ethereum key, along with the address of the key.
+func conversionData(t *testing.T, quantumAddress common.Address) ([]byte, string) {
+	ethKey, err := ecdsa.GenerateKey(secp256k1.S256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed GenerateKey %v", err)
+	}
+	pub := elliptic.Marshal(secp256k1.S256(), ethKey.X, ethKey.Y)
+	ethAddress := hexutil.Encode(crypto.Keccak256(pub[1:])[12:])
+
+	message := strings.Replace(crosssign.ConversionMessageTemplate, "[ETH_ADDRESS]", ethAddress, 1)
+	message = strings.Replace(message, "[QUANTUM_ADDRESS]", strings.ToLower(quantumAddress.Hex()), 1)
+	digest, _ := accounts.TextAndHash([]byte(message))
+	sig, err := secp256k1.Sign(digest, common.LeftPadBytes(ethKey.D.Bytes(), 32))
+	if err != nil {
+		t.Fatalf("failed Sign %v", err)
+	}
+	sig[64] += 27
+
+	data := make([]byte, 356)
+	copy(data, conversionutil.FirstPart)
+	copy(data[100:], ethAddress)
+	copy(data[143:], conversionutil.SecondPart)
+	copy(data[196:], hexutil.Encode(sig))
+	return data, ethAddress
+}
+
+func signValidationTx(t *testing.T, key *signaturealgorithm.PrivateKey, to common.Address, data []byte) string {
+	tx := types.NewTx(types.NewDefaultFeeTransactionSimple(0, &to, big.NewInt(0), 21000, data))
+	signed, err := types.SignTx(tx, types.NewLondonSignerDefaultChain(), key)
+	if err != nil {
+		t.Fatalf("failed SignTx %v", err)
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed MarshalBinary %v", err)
+	}
+	return hexutil.Encode(raw)
+}
+
+func hasValidationErr(result *TransactionValidation, code string) bool {
+	for _, err := range result.Errors {
+		if err.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidateGasExemption(t *testing.T) {
+	key, err := cryptobase.SigAlg.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed GenerateKey %v", err)
+	}
+	from, err := cryptobase.SigAlg.PublicKeyToAddress(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed PublicKeyToAddress %v", err)
+	}
+	data, ethAddress := conversionData(t, from)
+	conversionutil.SnapshotMap[ethAddress] = true
+	defer delete(conversionutil.SnapshotMap, ethAddress)
+
+	unknownData, _ := conversionData(t, from)
+
+	tests := []struct {
+		name         string
+		to           common.Address
+		data         []byte
+		balance      *big.Int
+		insufficient bool
+	}{
+		{"conversion", conversion.CONVERSION_CONTRACT_ADDRESS, data, big.NewInt(0), false},
+		{"conversion without data", conversion.CONVERSION_CONTRACT_ADDRESS, nil, big.NewInt(0), true},
+		{"conversion not in snapshot", conversion.CONVERSION_CONTRACT_ADDRESS, unknownData, big.NewInt(0), true},
+		{"conversion data to another contract", common.BytesToAddress([]byte{1}), data, big.NewInt(0), true},
+		{"transfer", common.BytesToAddress([]byte{1}), nil, big.NewInt(0), true},
+		{"transfer with balance", common.BytesToAddress([]byte{1}), nil, new(big.Int).Lsh(big.NewInt(1), 128), false},
+	}
+	for _, test := range tests {
+		validator := newTestValidator(t, test.balance)
+		result, err := validator.Validate(context.Background(), signValidationTx(t, key, test.to, test.data))
+		if err != nil {
+			t.Fatalf("%s: failed Validate %v", test.name, err)
+		}
+		if hasValidationErr(result, ValidationErrInvalidSignature) {
+			t.Fatalf("%s: signature is invalid %v", test.name, result.Errors[0].Message)
+		}
+		if insufficient := hasValidationErr(result, ValidationErrInsufficientFunds); insufficient != test.insufficient {
+			t.Errorf("%s: insufficient funds is %v, want %v", test.name, insufficient, test.insufficient)
+		}
+	}
+}
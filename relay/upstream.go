@@ -0,0 +1,292 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/log"
+	"github.com/DogeProtocol/dp/rpc"
+)
+
+const (
+	// upstreamHealthCheckInterval is the interval at which the head block and the sync
+	// status of every upstream node are checked.
+	upstreamHealthCheckInterval = 5 * time.Second
+
+	// upstreamHealthCheckTimeout is the maximum time an upstream node has to answer a
+	// health check, before it is marked as unhealthy.
+	upstreamHealthCheckTimeout = 3 * time.Second
+)
+
+var ErrNoUpstream = errors.New("no healthy upstream node")
+
+// upstream is a node that the relay forwards requests to, along with the result of its
+// last health check.
+type upstream struct {
+	url    string
+	client *rpc.Client
+
+	lock    sync.RWMutex
+	healthy bool
+	syncing bool
+	head    uint64
+}
+
+func (u *upstream) status() (healthy bool, syncing bool, head uint64) {
+	u.lock.RLock()
+	defer u.lock.RUnlock()
+	return u.healthy, u.syncing, u.head
+}
+
+func (u *upstream) setHealthy(syncing bool, head uint64) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.healthy = true
+	u.syncing = syncing
+	u.head = head
+}
+
+func (u *upstream) setUnhealthy() {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.healthy = false
+}
+
+// UpstreamPool keeps a persistent connection to each of a set of upstream nodes and
+// checks their health periodically. Reads are routed to the most up to date healthy
+// node and fail over to the next one on errors, while transactions are sent to all
+// healthy nodes.
+type UpstreamPool struct {
+	upstreams []*upstream
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewUpstreamPool connects to the given upstream nodes, checks their health once and
+// starts checking it periodically.
+func NewUpstreamPool(urls []string) (*UpstreamPool, error) {
+	if len(urls) == 0 {
+		return nil, ErrNoUpstream
+	}
+	pool := &UpstreamPool{
+		quit: make(chan struct{}),
+	}
+	for _, url := range urls {
+		client, err := rpc.Dial(url)
+		if err != nil {
+			pool.closeClients()
+			return nil, err
+		}
+		pool.upstreams = append(pool.upstreams, &upstream{url: url, client: client})
+	}
+	pool.checkHealth()
+
+	pool.wg.Add(1)
+	go pool.loop()
+
+	return pool, nil
+}
+
+// Urls returns the urls of the upstream nodes.
+func (p *UpstreamPool) Urls() []string {
+	urls := make([]string, len(p.upstreams))
+	for i, u := range p.upstreams {
+		urls[i] = u.url
+	}
+	return urls
+}
+
+func (p *UpstreamPool) loop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(upstreamHealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.checkHealth()
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// checkHealth checks the head block and the sync status of all upstream nodes concurrently.
+func (p *UpstreamPool) checkHealth() {
+	var wg sync.WaitGroup
+	for _, u := range p.upstreams {
+		wg.Add(1)
+		go func(u *upstream) {
+			defer wg.Done()
+			p.checkUpstream(u)
+		}(u)
+	}
+	wg.Wait()
+}
+
+func (p *UpstreamPool) checkUpstream(u *upstream) {
+	ctx, cancel := context.WithTimeout(context.Background(), upstreamHealthCheckTimeout)
+	defer cancel()
+
+	wasHealthy, _, _ := u.status()
+
+	var head hexutil.Uint64
+	if err := u.client.CallContext(ctx, &head, "eth_blockNumber"); err != nil {
+		if wasHealthy {
+			log.Warn("Upstream node is unhealthy", "url", u.url, "err", err)
+		}
+		u.setUnhealthy()
+		return
+	}
+	// eth_syncing returns false, or an object with the sync progress
+	var syncing interface{}
+	if err := u.client.CallContext(ctx, &syncing, "eth_syncing"); err != nil {
+		if wasHealthy {
+			log.Warn("Upstream node is unhealthy", "url", u.url, "err", err)
+		}
+		u.setUnhealthy()
+		return
+	}
+	isSyncing := syncing != nil && syncing != false
+
+	if wasHealthy == false {
+		log.Info("Upstream node is healthy", "url", u.url, "head", uint64(head), "syncing", isSyncing)
+	}
+	u.setHealthy(isSyncing, uint64(head))
+}
+
+// candidates returns the healthy upstream nodes, the ones that are not syncing first and
+// the ones with the highest head block first among those. If no node is healthy, all of
+// them are returned, so that requests are still attempted until the next health check.
+func (p *UpstreamPool) candidates() []*upstream {
+	type candidate struct {
+		upstream *upstream
+		syncing  bool
+		head     uint64
+	}
+	var healthy []candidate
+	for _, u := range p.upstreams {
+		ok, syncing, head := u.status()
+		if ok {
+			healthy = append(healthy, candidate{u, syncing, head})
+		}
+	}
+	if len(healthy) == 0 {
+		return p.upstreams
+	}
+	sort.SliceStable(healthy, func(i, j int) bool {
+		if healthy[i].syncing != healthy[j].syncing {
+			return healthy[j].syncing
+		}
+		return healthy[i].head > healthy[j].head
+	})
+	upstreams := make([]*upstream, len(healthy))
+	for i, c := range healthy {
+		upstreams[i] = c.upstream
+	}
+	return upstreams
+}
+
+// isUpstreamErr returns whether the error is caused by the upstream node being unreachable
+// or failing, rather than by the request itself. Errors returned by the node in a JSON-RPC
+// response, such as an unknown transaction, are not retried on other nodes.
+func isUpstreamErr(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := err.(rpc.Error); ok {
+		return false
+	}
+	return true
+}
+
+// CallContext performs a JSON-RPC call on the most up to date healthy upstream node. If
+// the node cannot be reached, it is marked as unhealthy and the call is retried on the
+// next one.
+func (p *UpstreamPool) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	var err error
+	for _, u := range p.candidates() {
+		err = u.client.CallContext(ctx, result, method, args...)
+		if isUpstreamErr(err) == false || ctx.Err() != nil {
+			return err
+		}
+		log.Warn("Upstream node failed, failing over", "url", u.url, "method", method, "err", err)
+		u.setUnhealthy()
+	}
+	if err == nil {
+		return ErrNoUpstream
+	}
+	return err
+}
+
+// EthSubscribe subscribes to notifications in the eth namespace on the most up to date
+// healthy upstream node that supports subscriptions, which nodes reached over HTTP do not.
+func (p *UpstreamPool) EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (*rpc.ClientSubscription, error) {
+	err := rpc.ErrNotificationsUnsupported
+	for _, u := range p.candidates() {
+		var sub *rpc.ClientSubscription
+		sub, err = u.client.EthSubscribe(ctx, channel, args...)
+		if err == rpc.ErrNotificationsUnsupported {
+			continue
+		}
+		if isUpstreamErr(err) == false || ctx.Err() != nil {
+			return sub, err
+		}
+		log.Warn("Upstream node failed, failing over", "url", u.url, "method", "eth_subscribe", "err", err)
+		u.setUnhealthy()
+	}
+	return nil, err
+}
+
+// BroadcastContext performs a JSON-RPC call on all healthy upstream nodes concurrently,
+// and succeeds if any of them succeeds. The result is set from the first node that
+// succeeds; if all of them fail, the error of the most up to date node is returned.
+func (p *UpstreamPool) BroadcastContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	upstreams := p.candidates()
+	errs := make([]error, len(upstreams))
+	results := make([]json.RawMessage, len(upstreams))
+
+	var wg sync.WaitGroup
+	for i, u := range upstreams {
+		wg.Add(1)
+		go func(i int, u *upstream) {
+			defer wg.Done()
+			errs[i] = u.client.CallContext(ctx, &results[i], method, args...)
+			if isUpstreamErr(errs[i]) {
+				log.Warn("Upstream node failed", "url", u.url, "method", method, "err", errs[i])
+				u.setUnhealthy()
+			}
+		}(i, u)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			return json.Unmarshal(results[i], result)
+		}
+	}
+	if len(errs) == 0 {
+		return ErrNoUpstream
+	}
+	return errs[0]
+}
+
+// Close stops the health checks and closes the connections to the upstream nodes.
+func (p *UpstreamPool) Close() {
+	close(p.quit)
+	p.wg.Wait()
+	p.closeClients()
+}
+
+func (p *UpstreamPool) closeClients() {
+	for _, u := range p.upstreams {
+		u.client.Close()
+	}
+}
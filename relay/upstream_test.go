@@ -0,0 +1,201 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/rpc"
+)
+
+// testUpstreamService serves the methods that the upstream pool checks the health of a
+// node with, and records the transactions sent to it.
+type testUpstreamService struct {
+	lock    sync.Mutex
+	head    uint64
+	syncing bool
+	calls   int
+	sent    []hexutil.Bytes
+	sendErr error
+}
+
+func (s *testUpstreamService) BlockNumber() hexutil.Uint64 {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls++
+	return hexutil.Uint64(s.head)
+}
+
+func (s *testUpstreamService) Syncing() interface{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.syncing {
+		return map[string]hexutil.Uint64{"currentBlock": hexutil.Uint64(s.head)}
+	}
+	return false
+}
+
+func (s *testUpstreamService) SendRawTransaction(tx hexutil.Bytes) (hexutil.Bytes, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls++
+	if s.sendErr != nil {
+		return nil, s.sendErr
+	}
+	s.sent = append(s.sent, tx)
+	return tx, nil
+}
+
+func (s *testUpstreamService) callCount() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.calls
+}
+
+// newDownUpstream returns an upstream whose node can not be reached.
+func newDownUpstream(t *testing.T, url string) *upstream {
+	u := newTestUpstream(t, url, &testUpstreamService{})
+	u.client.Close()
+	return u
+}
+
+func upstreamUrls(upstreams []*upstream) []string {
+	urls := make([]string, len(upstreams))
+	for i, u := range upstreams {
+		urls[i] = u.url
+	}
+	return urls
+}
+
+func TestUpstreamPoolCandidates(t *testing.T) {
+	pool := newTestUpstreamPool(t,
+		newTestUpstream(t, "behind", &testUpstreamService{head: 10}),
+		newTestUpstream(t, "syncing", &testUpstreamService{head: 12, syncing: true}),
+		newDownUpstream(t, "down"),
+		newTestUpstream(t, "ahead", &testUpstreamService{head: 11}),
+	)
+
+	// Before the first health check all nodes are tried in the configured order
+	if urls := upstreamUrls(pool.candidates()); !reflect.DeepEqual(urls, []string{"behind", "syncing", "down", "ahead"}) {
+		t.Fatalf("candidates before the health check are %v", urls)
+	}
+
+	// Synced nodes come first, the most up to date first, and unreachable nodes are left out
+	pool.checkHealth()
+	if urls := upstreamUrls(pool.candidates()); !reflect.DeepEqual(urls, []string{"ahead", "behind", "syncing"}) {
+		t.Fatalf("candidates are %v", urls)
+	}
+	if healthy, syncing, head := pool.upstreams[1].status(); healthy == false || syncing == false || head != 12 {
+		t.Fatalf("syncing upstream status is %v %v %d", healthy, syncing, head)
+	}
+	if healthy, _, _ := pool.upstreams[2].status(); healthy {
+		t.Fatalf("expected the unreachable upstream to be unhealthy")
+	}
+}
+
+func TestUpstreamPoolFailover(t *testing.T) {
+	service := &testUpstreamService{head: 5}
+	down := newDownUpstream(t, "down")
+	pool := newTestUpstreamPool(t, down, newTestUpstream(t, "up", service))
+
+	// The unreachable node looked the most up to date at the last health check
+	down.setHealthy(false, 100)
+	pool.upstreams[1].setHealthy(false, 5)
+
+	var head hexutil.Uint64
+	if err := pool.CallContext(context.Background(), &head, "eth_blockNumber"); err != nil {
+		t.Fatalf("CallContext failed: %v", err)
+	}
+	if head != 5 {
+		t.Fatalf("head is %d, want 5", head)
+	}
+	if healthy, _, _ := down.status(); healthy {
+		t.Fatalf("expected the failed upstream to be marked unhealthy")
+	}
+	if urls := upstreamUrls(pool.candidates()); !reflect.DeepEqual(urls, []string{"up"}) {
+		t.Fatalf("candidates after the failover are %v", urls)
+	}
+
+	// Errors returned by the node are not failed over
+	other := &testUpstreamService{head: 4}
+	pool = newTestUpstreamPool(t, newTestUpstream(t, "failing", &testUpstreamService{head: 5, sendErr: errors.New("nonce too low")}),
+		newTestUpstream(t, "other", other))
+	pool.checkHealth()
+	calls := other.callCount()
+	var hash hexutil.Bytes
+	err := pool.CallContext(context.Background(), &hash, "eth_sendRawTransaction", hexutil.Bytes{1})
+	if _, ok := err.(rpc.Error); ok == false || err.Error() != "nonce too low" {
+		t.Fatalf("expected the error of the node, got %v", err)
+	}
+	if other.callCount() != calls {
+		t.Fatalf("expected the call not to be retried on another node")
+	}
+	if healthy, _, _ := pool.upstreams[0].status(); healthy == false {
+		t.Fatalf("expected the node that returned an error to stay healthy")
+	}
+
+	// All nodes down
+	pool = newTestUpstreamPool(t, newDownUpstream(t, "first"), newDownUpstream(t, "second"))
+	if err := pool.CallContext(context.Background(), &head, "eth_blockNumber"); err == nil || isUpstreamErr(err) == false {
+		t.Fatalf("expected the error of the unreachable nodes, got %v", err)
+	}
+
+	pool = newTestUpstreamPool(t)
+	if err := pool.CallContext(context.Background(), &head, "eth_blockNumber"); err != ErrNoUpstream {
+		t.Fatalf("expected ErrNoUpstream, got %v", err)
+	}
+	if _, err := NewUpstreamPool(nil); err != ErrNoUpstream {
+		t.Fatalf("expected ErrNoUpstream for no urls, got %v", err)
+	}
+}
+
+func TestUpstreamPoolBroadcast(t *testing.T) {
+	first, second := &testUpstreamService{head: 2}, &testUpstreamService{head: 1}
+	down := newDownUpstream(t, "down")
+	pool := newTestUpstreamPool(t, newTestUpstream(t, "first", first), newTestUpstream(t, "second", second), down)
+	pool.checkHealth()
+
+	// The transaction is sent to all healthy nodes
+	var result hexutil.Bytes
+	if err := pool.BroadcastContext(context.Background(), &result, "eth_sendRawTransaction", hexutil.Bytes{1, 2}); err != nil {
+		t.Fatalf("BroadcastContext failed: %v", err)
+	}
+	if !reflect.DeepEqual(result, hexutil.Bytes{1, 2}) {
+		t.Fatalf("result is %x", result)
+	}
+	for _, service := range []*testUpstreamService{first, second} {
+		if len(service.sent) != 1 || !reflect.DeepEqual(service.sent[0], hexutil.Bytes{1, 2}) {
+			t.Fatalf("expected the transaction to be sent to every healthy node, got %x", service.sent)
+		}
+	}
+
+	// One node accepting the transaction is enough
+	first.sendErr = errors.New("already known")
+	result = nil
+	if err := pool.BroadcastContext(context.Background(), &result, "eth_sendRawTransaction", hexutil.Bytes{3}); err != nil {
+		t.Fatalf("BroadcastContext failed: %v", err)
+	}
+	if !reflect.DeepEqual(result, hexutil.Bytes{3}) || len(second.sent) != 2 {
+		t.Fatalf("expected the result of the node that accepted the transaction, got %x", result)
+	}
+
+	// When all nodes fail, the error of the most up to date node is returned
+	second.sendErr = errors.New("nonce too low")
+	err := pool.BroadcastContext(context.Background(), &result, "eth_sendRawTransaction", hexutil.Bytes{4})
+	if err == nil || err.Error() != "already known" {
+		t.Fatalf("expected the error of the most up to date node, got %v", err)
+	}
+
+	// A node that can not be reached is marked unhealthy
+	down.setHealthy(false, 3)
+	first.sendErr, second.sendErr = nil, nil
+	if err := pool.BroadcastContext(context.Background(), &result, "eth_sendRawTransaction", hexutil.Bytes{5}); err != nil {
+		t.Fatalf("BroadcastContext failed: %v", err)
+	}
+	if healthy, _, _ := down.status(); healthy {
+		t.Fatalf("expected the unreachable upstream to be marked unhealthy")
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/DogeProtocol/dp/accounts/keystore"
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/consensus/proofofstake"
 	"github.com/DogeProtocol/dp/internal/ethapi"
 	"github.com/DogeProtocol/dp/log"
 	"github.com/DogeProtocol/dp/signer/core/apitypes"
@@ -38,7 +39,7 @@ const (
 	// numberOfAccountsToDerive For hardware wallets, the number of accounts to derive
 	numberOfAccountsToDerive = 10
 	// ExternalAPIVersion -- see extapi_changelog.md
-	ExternalAPIVersion = "6.1.0"
+	ExternalAPIVersion = "6.2.0"
 	// InternalAPIVersion -- see intapi_changelog.md
 	InternalAPIVersion = "7.0.1"
 )
@@ -61,6 +62,8 @@ type ExternalAPI interface {
 	Version(ctx context.Context) (string, error)
 	// SignGnosisSafeTransaction signs/confirms a gnosis-safe multisig transaction
 	SignGnosisSafeTx(ctx context.Context, signerAddress common.MixedcaseAddress, gnosisTx GnosisSafeTx, methodSelector *string) (*GnosisSafeTx, error)
+	// SignConsensusData - request to sign a proof-of-stake consensus packet, subject to slashing protection
+	SignConsensusData(ctx context.Context, addr common.MixedcaseAddress, data hexutil.Bytes, signContext *hexutil.Bytes) (hexutil.Bytes, error)
 }
 
 // UIClientAPI specifies what method a UI needs to implement to be able to be used as a
@@ -107,12 +110,13 @@ type Validator interface {
 
 // SignerAPI defines the actual implementation of ExternalAPI
 type SignerAPI struct {
-	chainID     *big.Int
-	am          *accounts.Manager
-	UI          UIClientAPI
-	validator   Validator
-	rejectMode  bool
-	credentials storage.Storage
+	chainID            *big.Int
+	am                 *accounts.Manager
+	UI                 UIClientAPI
+	validator          Validator
+	rejectMode         bool
+	credentials        storage.Storage
+	slashingProtection *proofofstake.SlashingProtectionDB
 }
 
 // Metadata about a request
@@ -237,7 +241,7 @@ func NewSignerAPI(am *accounts.Manager, chainID int64, noUSB bool, ui UIClientAP
 	if advancedMode {
 		log.Info("Clef is in advanced mode: will warn instead of reject")
 	}
-	signer := &SignerAPI{big.NewInt(chainID), am, ui, validator, !advancedMode, credentials}
+	signer := &SignerAPI{big.NewInt(chainID), am, ui, validator, !advancedMode, credentials, nil}
 	return signer
 }
 func (api *SignerAPI) openTrezor(url accounts.URL) {
@@ -89,6 +89,14 @@ func (l *AuditLogger) SignGnosisSafeTx(ctx context.Context, addr common.Mixedcas
 	return res, e
 }
 
+func (l *AuditLogger) SignConsensusData(ctx context.Context, addr common.MixedcaseAddress, data hexutil.Bytes, signContext *hexutil.Bytes) (hexutil.Bytes, error) {
+	l.log.Info("SignConsensusData", "type", "request", "metadata", MetadataFromContext(ctx).String(),
+		"addr", addr.String(), "data", common.Bytes2Hex(data), "context", signContext != nil)
+	b, e := l.api.SignConsensusData(ctx, addr, data, signContext)
+	l.log.Info("SignConsensusData", "type", "response", "data", common.Bytes2Hex(b), "error", e)
+	return b, e
+}
+
 func (l *AuditLogger) SignTypedData(ctx context.Context, addr common.MixedcaseAddress, data TypedData) (hexutil.Bytes, error) {
 	l.log.Info("SignTypedData", "type", "request", "metadata", MetadataFromContext(ctx).String(),
 		"addr", addr.String(), "data", data)
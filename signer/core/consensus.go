@@ -0,0 +1,121 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/DogeProtocol/dp/accounts"
+	"github.com/DogeProtocol/dp/accounts/keystore"
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/consensus/proofofstake"
+	"github.com/DogeProtocol/dp/crypto"
+)
+
+var ErrSlashingProtectionDisabled = errors.New("consensus signing requires a slashing protection database")
+
+// SetSlashingProtection enables signing of consensus packets, which are checked against
+// and recorded in the given slashing protection database before they are signed.
+func (api *SignerAPI) SetSlashingProtection(slashingProtection *proofofstake.SlashingProtectionDB) {
+	api.slashingProtection = slashingProtection
+}
+
+// SignConsensusData signs a proof-of-stake consensus packet of a validator. The data is the
+// parent hash of the packet followed by its consensus data, and signContext is the optional
+// signing context. The request is passed to the UI for approval like any other data, but a
+// packet that conflicts with one already signed for the same parent hash and round is
+// refused regardless, since it would get the validator slashed.
+func (api *SignerAPI) SignConsensusData(ctx context.Context, addr common.MixedcaseAddress, data hexutil.Bytes, signContext *hexutil.Bytes) (hexutil.Bytes, error) {
+	if api.slashingProtection == nil {
+		return nil, ErrSlashingProtectionDisabled
+	}
+	if len(data) <= common.HashLength {
+		return nil, proofofstake.InvalidPacketErr
+	}
+
+	messages := []*NameValueType{
+		{
+			Name:  "This is a request to sign a consensus packet of a validator",
+			Typ:   "description",
+			Value: "",
+		},
+		{
+			Name:  "Parent hash",
+			Typ:   "hash",
+			Value: common.BytesToHash(data[:common.HashLength]).Hex(),
+		},
+		{
+			Name:  "Consensus data",
+			Typ:   "bytes",
+			Value: hexutil.Encode(data[common.HashLength:]),
+		},
+	}
+	req := &SignDataRequest{
+		ContentType: accounts.MimetypeProofOfStake,
+		Rawdata:     data,
+		Messages:    messages,
+		Hash:        crypto.Keccak256(data),
+		Address:     addr,
+		Meta:        MetadataFromContext(ctx),
+	}
+	res, err := api.UI.ApproveSignData(req)
+	if err != nil {
+		return nil, err
+	}
+	if !res.Approved {
+		return nil, ErrRequestDenied
+	}
+
+	account := accounts.Account{Address: addr.Address()}
+	wallet, err := api.am.Find(account)
+	if err != nil {
+		return nil, err
+	}
+
+	// The packet is recorded before it is signed, so that a crash after signing cannot
+	// lead to a conflicting packet being signed after a restart
+	err = api.slashingProtection.CheckAndRecord(account.Address, data)
+	if err != nil {
+		api.UI.ShowError(err.Error())
+		return nil, err
+	}
+
+	signature, err := signConsensusData(wallet, account, data, signContext)
+	if err == keystore.ErrLocked {
+		// Consensus packets are signed every few seconds, so the account is unlocked once
+		// instead of decrypting the key for every packet
+		err = api.unlockConsensusAccount(account)
+		if err != nil {
+			return nil, err
+		}
+		signature, err = signConsensusData(wallet, account, data, signContext)
+	}
+	if err != nil {
+		api.UI.ShowError(err.Error())
+		return nil, err
+	}
+
+	return signature, nil
+}
+
+func signConsensusData(wallet accounts.Wallet, account accounts.Account, data []byte, signContext *hexutil.Bytes) ([]byte, error) {
+	if signContext == nil {
+		return wallet.SignData(account, accounts.MimetypeProofOfStake, data)
+	}
+	return wallet.SignDataWithContext(account, accounts.MimetypeProofOfStake, data, *signContext)
+}
+
+func (api *SignerAPI) unlockConsensusAccount(account accounts.Account) error {
+	backends := api.am.Backends(keystore.KeyStoreType)
+	if len(backends) == 0 {
+		return keystore.ErrLocked
+	}
+	pw, err := api.lookupOrQueryPassword(account.Address,
+		"Password for consensus signing",
+		fmt.Sprintf("Please enter password to unlock validator account %s for consensus signing", account.Address.Hex()))
+	if err != nil {
+		return err
+	}
+	return backends[0].(*keystore.KeyStore).Unlock(account, pw)
+}
@@ -0,0 +1,63 @@
+package core_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DogeProtocol/dp/common"
+	"github.com/DogeProtocol/dp/common/hexutil"
+	"github.com/DogeProtocol/dp/consensus/proofofstake"
+	"github.com/DogeProtocol/dp/ethdb/memorydb"
+	"github.com/DogeProtocol/dp/rlp"
+	"github.com/DogeProtocol/dp/signer/core"
+)
+
+func consensusAckData(t *testing.T, parentHash common.Hash, voteType proofofstake.VoteType, proposalHash common.Hash) hexutil.Bytes {
+	data, err := rlp.EncodeToBytes(&proofofstake.ProposalAckDetails{ProposalAckVoteType: voteType, Round: 1, ProposalHash: proposalHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(append(parentHash.Bytes(), byte(proofofstake.CONSENSUS_PACKET_TYPE_ACK_BLOCK_PROPOSAL)), data...)
+}
+
+func TestSignConsensusData(t *testing.T) {
+	api, control := setup(t)
+	createAccount(control, api, t)
+	control.approveCh <- "A"
+	list, err := api.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := common.NewMixedcaseAddress(list[0])
+	parentHash := common.BytesToHash([]byte{1})
+	okAck := consensusAckData(t, parentHash, proofofstake.VOTE_TYPE_OK, common.BytesToHash([]byte{2}))
+
+	if _, err = api.SignConsensusData(context.Background(), a, okAck, nil); err != core.ErrSlashingProtectionDisabled {
+		t.Fatalf("Expected ErrSlashingProtectionDisabled, got %v", err)
+	}
+	api.SetSlashingProtection(proofofstake.NewSlashingProtectionDB(memorydb.New()))
+
+	control.approveCh <- "No way"
+	if _, err = api.SignConsensusData(context.Background(), a, okAck, nil); err != core.ErrRequestDenied {
+		t.Fatalf("Expected ErrRequestDenied, got %v", err)
+	}
+	// The account is unlocked on the first request
+	control.approveCh <- "Y"
+	control.inputCh <- "a_long_password"
+	signature, err := api.SignConsensusData(context.Background(), a, okAck, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signature) == 0 {
+		t.Fatal("Expected a signature")
+	}
+	control.approveCh <- "Y"
+	if _, err = api.SignConsensusData(context.Background(), a, okAck, nil); err != nil {
+		t.Fatalf("Expected resending the packet to be signed, got %v", err)
+	}
+	control.approveCh <- "Y"
+	nilAck := consensusAckData(t, parentHash, proofofstake.VOTE_TYPE_NIL, common.Hash{})
+	if _, err = api.SignConsensusData(context.Background(), a, nilAck, nil); err != proofofstake.SlashingProtectionErr {
+		t.Fatalf("Expected SlashingProtectionErr, got %v", err)
+	}
+}
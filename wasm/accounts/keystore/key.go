@@ -6,7 +6,13 @@ import (
 )
 
 const (
-	version = 3
+	version   = 3
+	versionV4 = 4
+
+	// keySigAlgName and keySigAlgVersion are the signature algorithm of the keys
+	// that can be read, and the version of the serialization of their private keys.
+	keySigAlgName    = "dilithium-ed25519-sphincs"
+	keySigAlgVersion = 1
 )
 
 type encryptedKeyJSONV3 struct {
@@ -16,6 +22,22 @@ type encryptedKeyJSONV3 struct {
 	Version int        `json:"version"`
 }
 
+// encryptedKeyJSONV4 is a key file that records the signature algorithm of its
+// key, which is encrypted with an AEAD cipher that also authenticates the rest
+// of the key file.
+type encryptedKeyJSONV4 struct {
+	Address string     `json:"address"`
+	Crypto  CryptoJSON `json:"crypto"`
+	Id      string     `json:"id"`
+	Version int        `json:"version"`
+	SigAlg  sigAlgJSON `json:"sigalg"`
+}
+
+type sigAlgJSON struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
 type encryptedKeyJSONV1 struct {
 	Address string     `json:"address"`
 	Crypto  CryptoJSON `json:"crypto"`
@@ -3,6 +3,7 @@ package keystore
 import (
 	"bytes"
 	"crypto/aes"
+	"crypto/cipher"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
@@ -12,6 +13,7 @@ import (
 	"github.com/DogeProtocol/dp/common"
 	"github.com/DogeProtocol/dp/crypto"
 	"github.com/google/uuid"
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/pbkdf2"
 	"golang.org/x/crypto/scrypt"
 	"io"
@@ -19,7 +21,9 @@ import (
 )
 
 const (
-	keyHeaderKDF = "scrypt"
+	keyHeaderKDF         = "scrypt"
+	keyHeaderKDFArgon2id = "argon2id"
+	keyCipherAEAD        = "aes-256-gcm"
 
 	// StandardScryptN is the N parameter of Scrypt encryption algorithm, using 256MB
 	// memory and taking approximately 1s CPU time on a modern processor.
@@ -121,6 +125,15 @@ func DecryptKey(keyjson []byte, auth string) (*Key, error) {
 			return nil, err
 		}
 		keyBytes, keyId, err = decryptKeyV1(k, auth)
+	} else if version, ok := m["version"].(float64); ok && version == versionV4 {
+		k := new(encryptedKeyJSONV4)
+		if err := json.Unmarshal(keyjson, k); err != nil {
+			return nil, err
+		}
+		if k.SigAlg.Name != keySigAlgName {
+			return nil, fmt.Errorf("signature algorithm not supported: %v", k.SigAlg.Name)
+		}
+		keyBytes, keyId, err = decryptKeyV4(k, auth)
 	} else {
 		k := new(encryptedKeyJSONV3)
 		if err := json.Unmarshal(keyjson, k); err != nil {
@@ -214,6 +227,62 @@ func decryptKeyV3(keyProtected *encryptedKeyJSONV3, auth string) (keyBytes []byt
 	return plainText, keyId, err
 }
 
+func decryptKeyV4(keyProtected *encryptedKeyJSONV4, auth string) (keyBytes []byte, keyId []byte, err error) {
+	if keyProtected.SigAlg.Version != keySigAlgVersion {
+		return nil, nil, fmt.Errorf("signature algorithm version not supported: %v", keyProtected.SigAlg.Version)
+	}
+	if keyProtected.Crypto.Cipher != keyCipherAEAD {
+		return nil, nil, fmt.Errorf("cipher not supported: %v", keyProtected.Crypto.Cipher)
+	}
+	keyUUID, err := uuid.Parse(keyProtected.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyId = keyUUID[:]
+
+	nonce, err := hex.DecodeString(keyProtected.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, nil, err
+	}
+	cipherText, err := hex.DecodeString(keyProtected.Crypto.CipherText)
+	if err != nil {
+		return nil, nil, err
+	}
+	derivedKey, err := getKDFKey(keyProtected.Crypto, auth)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := newKeyAEAD(derivedKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, nil, fmt.Errorf("invalid nonce length: %d", len(nonce))
+	}
+	plainText, err := aead.Open(nil, nonce, cipherText, keyProtected.additionalData())
+	if err != nil {
+		return nil, nil, ErrDecrypt
+	}
+	return plainText, keyId, nil
+}
+
+// additionalData returns the fields of the key file that are authenticated along
+// with the encrypted key, so that they can not be swapped.
+func (k *encryptedKeyJSONV4) additionalData() []byte {
+	return []byte(fmt.Sprintf("%d:%s:%s:%s:%d", k.Version, k.Address, k.Id, k.SigAlg.Name, k.SigAlg.Version))
+}
+
+func newKeyAEAD(derivedKey []byte) (cipher.AEAD, error) {
+	if len(derivedKey) < 32 {
+		return nil, fmt.Errorf("invalid derived key length: %d", len(derivedKey))
+	}
+	block, err := aes.NewCipher(derivedKey[:32])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
 func decryptKeyV1(keyProtected *encryptedKeyJSONV1, auth string) (keyBytes []byte, keyId []byte, err error) {
 	keyUUID, err := uuid.Parse(keyProtected.Id)
 	if err != nil {
@@ -276,6 +345,10 @@ func getKDFKey(cryptoJSON CryptoJSON, auth string) ([]byte, error) {
 		return nil, err
 	}
 	dkLen := ensureInt(cryptoJSON.KDFParams["dklen"])
+	// All ciphers use the first 32 bytes of the derived key
+	if dkLen < 32 {
+		return nil, fmt.Errorf("invalid derived key length: %d", dkLen)
+	}
 
 	if cryptoJSON.KDF == keyHeaderKDF {
 		n := ensureInt(cryptoJSON.KDFParams["n"])
@@ -283,6 +356,15 @@ func getKDFKey(cryptoJSON CryptoJSON, auth string) ([]byte, error) {
 		p := ensureInt(cryptoJSON.KDFParams["p"])
 		return scrypt.Key(authArray, salt, n, r, p, dkLen)
 
+	} else if cryptoJSON.KDF == keyHeaderKDFArgon2id {
+		t := ensureInt(cryptoJSON.KDFParams["t"])
+		m := ensureInt(cryptoJSON.KDFParams["m"])
+		p := ensureInt(cryptoJSON.KDFParams["p"])
+		if t < 1 || m < 1 || p < 1 || p > 255 {
+			return nil, fmt.Errorf("invalid Argon2id parameters: t=%d m=%d p=%d dklen=%d", t, m, p, dkLen)
+		}
+		return argon2.IDKey(authArray, salt, uint32(t), uint32(m), uint8(p), uint32(dkLen)), nil
+
 	} else if cryptoJSON.KDF == "pbkdf2" {
 		c := ensureInt(cryptoJSON.KDFParams["c"])
 		prf := cryptoJSON.KDFParams["prf"].(string)